@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	certPrefixCN     = "cn:"
+	certPrefixSAN    = "san:"
+	certPrefixSHA256 = "sha256:"
+)
+
+// CertUser returns the user mapped to the given client certificate, which
+// must already have been verified. Certificates are identified in the
+// credentials file by their SHA-256 fingerprint, as "sha256:<hex>", by a
+// subject alternative name, as "san:<name>", or by their subject common
+// name, as "cn:<name>". A fingerprint takes precedence over a subject
+// alternative name, and both take precedence over the common name.
+func (c *CredentialsStore) CertUser(cert *x509.Certificate) (string, bool) {
+	if c == nil || cert == nil {
+		return "", false
+	}
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.certs) == 0 {
+		return "", false
+	}
+
+	fp := sha256.Sum256(cert.Raw)
+	if u, ok := c.certs[certPrefixSHA256+hex.EncodeToString(fp[:])]; ok {
+		return u, true
+	}
+	sans := append(append([]string{}, cert.DNSNames...), cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, san := range sans {
+		if u, ok := c.certs[certPrefixSAN+san]; ok {
+			return u, true
+		}
+	}
+	if cn := cert.Subject.CommonName; cn != "" {
+		if u, ok := c.certs[certPrefixCN+cn]; ok {
+			return u, true
+		}
+	}
+	return "", false
+}
+
+// AACert checks authorization for the given perm of the user mapped to the
+// given client certificate, which must already have been verified. If the
+// credential store is nil, then this function always returns true. If
+// AllUsers have the given perm, the certificate is not checked.
+func (c *CredentialsStore) AACert(cert *x509.Certificate, perm string) bool {
+	if c == nil {
+		return true
+	}
+	if c.HasAnyPerm(AllUsers, perm, PermAll) {
+		return true
+	}
+	username, ok := c.CertUser(cert)
+	if !ok {
+		return false
+	}
+	return c.AACertUser(username, perm)
+}
+
+// AACertUser checks authorization for the given perm of the user to which a
+// verified client certificate was mapped, such as by the node which received
+// the certificate. If the credential store is nil, then this function always
+// returns true.
+func (c *CredentialsStore) AACertUser(username, perm string) bool {
+	if c == nil {
+		return true
+	}
+	if c.HasAnyPerm(AllUsers, perm, PermAll) {
+		return true
+	}
+	if username == "" {
+		return false
+	}
+	return c.HasAnyPerm(username, perm, PermAll)
+}
+
+// normalizeCertIdentity checks the given certificate identity, and returns
+// it with its prefix, and any fingerprint, in lower case. Colons may
+// separate the bytes of a fingerprint.
+func normalizeCertIdentity(id string) (string, error) {
+	prefix, value, ok := strings.Cut(id, ":")
+	if !ok || value == "" {
+		return "", fmt.Errorf("invalid certificate identity %q", id)
+	}
+	prefix = strings.ToLower(prefix) + ":"
+	switch prefix {
+	case certPrefixCN, certPrefixSAN:
+		return prefix + value, nil
+	case certPrefixSHA256:
+		fp := strings.ToLower(strings.ReplaceAll(value, ":", ""))
+		if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("invalid SHA-256 fingerprint %q", value)
+		}
+		return prefix + fp, nil
+	}
+	return "", fmt.Errorf("invalid certificate identity %q, must start with cn:, san: or sha256:", id)
+}
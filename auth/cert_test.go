@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+)
+
+func Test_AuthCertUser(t *testing.T) {
+	fp := sha256.Sum256([]byte("cert3"))
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(`[
+		{"username": "*", "perms": ["status"]},
+		{"username": "svc", "perms": ["query"], "certificates": ["CN:svc.example.com", "san:10.0.0.1"]},
+		{"username": "admin", "password": "pw", "perms": ["all"], "certificates": ["sha256:` + strings.ToUpper(hex.EncodeToString(fp[:])) + `"]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+
+	for i, tt := range []struct {
+		cert *x509.Certificate
+		user string
+	}{
+		{
+			cert: &x509.Certificate{Raw: []byte("cert1"), Subject: pkix.Name{CommonName: "svc.example.com"}},
+			user: "svc",
+		},
+		{
+			cert: &x509.Certificate{Raw: []byte("cert2"), Subject: pkix.Name{CommonName: "other"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+			user: "svc",
+		},
+		{
+			cert: &x509.Certificate{Raw: []byte("cert3"), Subject: pkix.Name{CommonName: "svc.example.com"}},
+			user: "admin",
+		},
+		{
+			cert: &x509.Certificate{Raw: []byte("cert4"), Subject: pkix.Name{CommonName: "SVC.example.com"}},
+		},
+		{
+			cert: nil,
+		},
+	} {
+		u, ok := store.CertUser(tt.cert)
+		if ok != (tt.user != "") || u != tt.user {
+			t.Fatalf("test %d: wrong user, exp %q, got %q", i, tt.user, u)
+		}
+	}
+
+	svc := &x509.Certificate{Raw: []byte("cert1"), Subject: pkix.Name{CommonName: "svc.example.com"}}
+	if !store.AACert(svc, PermQuery) {
+		t.Fatalf("certificate user not granted query perm")
+	}
+	if store.AACert(svc, PermExecute) {
+		t.Fatalf("certificate user granted execute perm")
+	}
+	unknown := &x509.Certificate{Raw: []byte("cert4")}
+	if !store.AACert(unknown, PermStatus) {
+		t.Fatalf("perm of all users not granted")
+	}
+	if store.AACert(unknown, PermQuery) {
+		t.Fatalf("unknown certificate granted query perm")
+	}
+	if !store.AACert(&x509.Certificate{Raw: []byte("cert3")}, PermExecute) {
+		t.Fatalf("certificate of user with perm all not granted execute perm")
+	}
+
+	if !store.AACertUser("svc", PermQuery) || store.AACertUser("svc", PermExecute) {
+		t.Fatalf("wrong perms for mapped certificate user")
+	}
+	if !store.AACertUser("", PermStatus) || store.AACertUser("", PermQuery) {
+		t.Fatalf("wrong perms for unmapped certificate")
+	}
+
+	var nilStore *CredentialsStore
+	if !nilStore.AACert(unknown, PermExecute) {
+		t.Fatalf("nil store denied certificate")
+	}
+	if !nilStore.AACertUser("svc", PermExecute) {
+		t.Fatalf("nil store denied certificate user")
+	}
+}
+
+func Test_AuthCertInvalid(t *testing.T) {
+	for i, s := range []string{
+		`[{"username": "a", "certificates": ["svc.example.com"]}]`,
+		`[{"username": "a", "certificates": ["dn:svc"]}]`,
+		`[{"username": "a", "certificates": ["cn:"]}]`,
+		`[{"username": "a", "certificates": ["sha256:abcd"]}]`,
+		`[{"username": "a", "certificates": ["cn:svc"]}, {"username": "b", "certificates": ["CN:svc"]}]`,
+	} {
+		if err := NewCredentialsStore().Load(strings.NewReader(s)); err == nil {
+			t.Fatalf("test %d: expected error loading invalid certificates", i)
+		}
+	}
+}
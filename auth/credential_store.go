@@ -3,9 +3,19 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 const (
@@ -41,6 +51,27 @@ const (
 	PermLeaderOps = "leader-ops"
 	// PermUI means user can access the UI.
 	PermUI = "ui"
+	// PermStatements means user can manage the registry of named statements.
+	PermStatements = "statements"
+	// PermNamedStatements means user can execute and query using named statements only.
+	PermNamedStatements = "named-statements"
+	// PermUsers means user can manage the users stored in the database, granting
+	// them only perms the user holds itself.
+	PermUsers = "users"
+)
+
+const (
+	// OpSelect means reading from a table.
+	OpSelect = "select"
+	// OpInsert means inserting rows into a table.
+	OpInsert = "insert"
+	// OpUpdate means updating rows of a table.
+	OpUpdate = "update"
+	// OpDelete means deleting rows from a table.
+	OpDelete = "delete"
+	// OpDDL means creating, altering or dropping a table, or an index,
+	// trigger or view.
+	OpDDL = "ddl"
 )
 
 // BasicAuther is the interface an object must support to return basic auth information.
@@ -50,39 +81,88 @@ type BasicAuther interface {
 
 // Credential represents authentication and authorization configuration for a single user.
 type Credential struct {
-	Username string   `json:"username,omitempty"`
-	Password string   `json:"password,omitempty"`
-	Perms    []string `json:"perms,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Password string    `json:"password,omitempty"`
+	Perms    []string  `json:"perms,omitempty"`
+	ACL      []ACLRule `json:"acl,omitempty"`
+
+	// Certificates identify TLS client certificates which authenticate as
+	// the user. See CertUser.
+	Certificates []string `json:"certificates,omitempty"`
+
+	// RateLimit, if set, limits the rate at which the user makes requests.
+	// See RateLimiter.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// ACLRule permits operations on tables. Tables are names, or patterns such as
+// "orders_*" in the syntax of path.Match. Both are matched without regard to
+// case. The pattern "*" matches every table, and is also required for
+// statements which are not specific to a table, such as PRAGMA.
+type ACLRule struct {
+	Tables []string `json:"tables"`
+	Ops    []string `json:"ops"`
 }
 
 // CredentialsStore stores authentication and authorization information for all users.
+// Passwords may be stored in plaintext, or as bcrypt or Argon2id hashes. See
+// HashPassword. A store loaded from a file may be reloaded, in which case the
+// new credentials replace the old ones atomically.
 type CredentialsStore struct {
-	store map[string]string
-	perms map[string]map[string]bool
+	path string
+
+	mu      sync.RWMutex
+	store   map[string]string
+	perms   map[string]map[string]bool
+	acls    map[string][]ACLRule
+	certs   map[string]string // Certificate identities to usernames.
+	limits  map[string]RateLimit
+	modTime time.Time
+	size    int64
+
+	// fileCreds are the credentials loaded from the file, and userCreds
+	// those read from the UserSource, if any. Users in the file take
+	// precedence over users of the same name from the UserSource.
+	fileCreds    []Credential
+	userCreds    []Credential
+	userSource   UserSource
+	usersVersion uint64
+
+	// verified caches credentials which matched a hashed password, since
+	// checking a hash is deliberately slow. It is cleared on every load.
+	verifiedMu sync.Mutex
+	verified   map[[sha256.Size]byte]bool
+
+	tokenVerifier *TokenVerifier
+
+	logger *log.Logger
 }
 
 // NewCredentialsStore returns a new instance of a CredentialStore.
 func NewCredentialsStore() *CredentialsStore {
 	return &CredentialsStore{
-		store: make(map[string]string),
-		perms: make(map[string]map[string]bool),
+		store:    make(map[string]string),
+		perms:    make(map[string]map[string]bool),
+		acls:     make(map[string][]ACLRule),
+		certs:    make(map[string]string),
+		limits:   make(map[string]RateLimit),
+		verified: make(map[[sha256.Size]byte]bool),
+		logger:   logging.New("auth", os.Stderr),
 	}
 }
 
 // NewCredentialsStoreFromFile returns a new instance of a CredentialStore loaded from a file.
 func NewCredentialsStoreFromFile(path string) (*CredentialsStore, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
 	c := NewCredentialsStore()
-	return c, c.Load(f)
+	c.path = path
+	return c, c.Reload()
 }
 
-// Load loads credential information from a reader.
+// Load loads credential information from a reader, replacing any credentials
+// already loaded. If the information cannot be loaded, the existing
+// credentials are kept.
 func (c *CredentialsStore) Load(r io.Reader) error {
+	var creds []Credential
 	dec := json.NewDecoder(r)
 	// Read open bracket
 	_, err := dec.Token()
@@ -90,17 +170,13 @@ func (c *CredentialsStore) Load(r io.Reader) error {
 		return err
 	}
 
-	var cred Credential
 	for dec.More() {
+		var cred Credential
 		err := dec.Decode(&cred)
 		if err != nil {
 			return err
 		}
-		c.store[cred.Username] = cred.Password
-		c.perms[cred.Username] = make(map[string]bool, len(cred.Perms))
-		for _, p := range cred.Perms {
-			c.perms[cred.Username][p] = true
-		}
+		creds = append(creds, cred)
 	}
 
 	// Read closing bracket.
@@ -109,19 +185,130 @@ func (c *CredentialsStore) Load(r io.Reader) error {
 		return err
 	}
 
+	idx := newCredentialIndex()
+	for _, cred := range creds {
+		if err := idx.add(cred); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.fileCreds = creds
+	c.indexLocked()
+	c.mu.Unlock()
+
+	c.verifiedMu.Lock()
+	c.verified = make(map[[sha256.Size]byte]bool)
+	c.verifiedMu.Unlock()
 	return nil
 }
 
+// Reload reloads the credentials from the file the store was created from.
+// If the file cannot be loaded, the existing credentials are kept.
+func (c *CredentialsStore) Reload() error {
+	if c.path == "" {
+		return fmt.Errorf("credentials store was not loaded from a file")
+	}
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := c.Load(f); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.modTime, c.size = info.ModTime(), info.Size()
+	c.mu.Unlock()
+	return nil
+}
+
+// ReloadIfModified reloads the credentials if the file the store was created
+// from has changed since it was last loaded. It returns true if the
+// credentials were reloaded.
+func (c *CredentialsStore) ReloadIfModified() (bool, error) {
+	if c.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, err
+	}
+	c.mu.RLock()
+	modified := !info.ModTime().Equal(c.modTime) || info.Size() != c.size
+	c.mu.RUnlock()
+	if !modified {
+		return false, nil
+	}
+	return true, c.Reload()
+}
+
+// Watch checks, every interval until done is closed, whether the file the
+// store was created from has changed, and if so reloads the credentials.
+func (c *CredentialsStore) Watch(interval time.Duration, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				reloaded, err := c.ReloadIfModified()
+				if err != nil {
+					logging.Warnf(c.logger, "failed to reload credentials from %s, keeping prior credentials: %s", c.path, err)
+				} else if reloaded {
+					c.logger.Printf("reloaded credentials from %s", c.path)
+				}
+			}
+		}
+	}()
+}
+
 // Check returns true if the password is correct for the given username.
 func (c *CredentialsStore) Check(username, password string) bool {
-	pw, ok := c.store[username]
-	return ok && pw == password
+	c.refresh()
+	c.mu.RLock()
+	stored, ok := c.store[username]
+	c.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if !IsHashed(stored) {
+		return checkPassword(stored, password)
+	}
+
+	key := sha256.Sum256([]byte(username + "\x00" + stored + "\x00" + password))
+	c.verifiedMu.Lock()
+	verified := c.verified[key]
+	c.verifiedMu.Unlock()
+	if verified {
+		return true
+	}
+	if !checkPassword(stored, password) {
+		return false
+	}
+	c.verifiedMu.Lock()
+	c.verified[key] = true
+	c.verifiedMu.Unlock()
+	return true
 }
 
-// Password returns the password for the given user.
+// Password returns the password for the given user. A password which is
+// stored as a hash cannot be returned.
 func (c *CredentialsStore) Password(username string) (string, bool) {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	pw, ok := c.store[username]
-	return pw, ok
+	if !ok || IsHashed(pw) {
+		return "", false
+	}
+	return pw, true
 }
 
 // CheckRequest returns true if b contains a valid username and password.
@@ -136,6 +323,9 @@ func (c *CredentialsStore) CheckRequest(b BasicAuther) bool {
 // HasPerm returns true if username has the given perm, either directly or
 // via AllUsers. It does not perform any password checking.
 func (c *CredentialsStore) HasPerm(username string, perm string) bool {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if m, ok := c.perms[username]; ok {
 		if _, ok := m[perm]; ok {
 			return true
@@ -193,6 +383,52 @@ func (c *CredentialsStore) AA(username, password, perm string) bool {
 	return c.HasAnyPerm(username, perm, PermAll)
 }
 
+// SetTokenVerifier sets the verifier of bearer tokens. Without one, every
+// bearer token is rejected.
+func (c *CredentialsStore) SetTokenVerifier(v *TokenVerifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokenVerifier = v
+}
+
+// TokenUser returns the username asserted by the given bearer token, if the
+// token is verified. The perms of the user are those granted by the token,
+// but any ACL rules of a user with that name in the store apply to it.
+func (c *CredentialsStore) TokenUser(token string) (string, bool) {
+	id, err := c.verifyToken(token)
+	if err != nil {
+		return "", false
+	}
+	return id.Username, true
+}
+
+// AAToken authenticates the given bearer token, and checks it grants the
+// given perm. If the credential store is nil, then this function always
+// returns true. If AllUsers have the given perm, the token is not verified.
+func (c *CredentialsStore) AAToken(token, perm string) bool {
+	if c == nil {
+		return true
+	}
+	if c.HasAnyPerm(AllUsers, perm, PermAll) {
+		return true
+	}
+	id, err := c.verifyToken(token)
+	if err != nil {
+		return false
+	}
+	return id.Perms[perm] || id.Perms[PermAll]
+}
+
+func (c *CredentialsStore) verifyToken(token string) (*TokenIdentity, error) {
+	c.mu.RLock()
+	v := c.tokenVerifier
+	c.mu.RUnlock()
+	if v == nil {
+		return nil, ErrInvalidToken
+	}
+	return v.Verify(token)
+}
+
 // HasPermRequest returns true if the username returned by b has the given perm.
 // It does not perform any password checking, but if there is no username
 // in the request, it returns false.
@@ -200,3 +436,133 @@ func (c *CredentialsStore) HasPermRequest(b BasicAuther, perm string) bool {
 	username, _, ok := b.BasicAuth()
 	return ok && c.HasPerm(username, perm)
 }
+
+// HasTableACL returns true if the access of username to tables is restricted
+// by ACL rules, either its own or those of AllUsers. Users without any rules
+// may access every table.
+func (c *CredentialsStore) HasTableACL(username string) bool {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.acls[username]) > 0 || len(c.acls[AllUsers]) > 0
+}
+
+// TableAllowed returns true if the ACL rules of username, or of AllUsers,
+// permit op on the given table. It does not perform any password checking.
+// An empty table is only matched by the pattern "*".
+func (c *CredentialsStore) TableAllowed(username, table, op string) bool {
+	c.refresh()
+	table = strings.ToLower(table)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, u := range []string{username, AllUsers} {
+		for _, r := range c.acls[u] {
+			if !slices.Contains(r.Ops, op) {
+				continue
+			}
+			for _, t := range r.Tables {
+				// Patterns were validated when loaded.
+				if ok, _ := path.Match(t, table); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// credentialIndex indexes credentials for lookup.
+type credentialIndex struct {
+	store  map[string]string
+	perms  map[string]map[string]bool
+	acls   map[string][]ACLRule
+	certs  map[string]string
+	limits map[string]RateLimit
+}
+
+func newCredentialIndex() *credentialIndex {
+	return &credentialIndex{
+		store:  make(map[string]string),
+		perms:  make(map[string]map[string]bool),
+		acls:   make(map[string][]ACLRule),
+		certs:  make(map[string]string),
+		limits: make(map[string]RateLimit),
+	}
+}
+
+// add checks the given credential, and if it is valid adds it to the index,
+// replacing any credential of the same user.
+func (idx *credentialIndex) add(cred Credential) error {
+	if IsHashed(cred.Password) {
+		if err := ValidateHash(cred.Password); err != nil {
+			return fmt.Errorf("password of user %q: %s", cred.Username, err)
+		}
+	}
+	var rules []ACLRule
+	if len(cred.ACL) > 0 {
+		var err error
+		if rules, err = normalizeACL(cred.ACL); err != nil {
+			return fmt.Errorf("ACL of user %q: %s", cred.Username, err)
+		}
+	}
+	certs := make([]string, 0, len(cred.Certificates))
+	for _, ci := range cred.Certificates {
+		id, err := normalizeCertIdentity(ci)
+		if err != nil {
+			return fmt.Errorf("certificates of user %q: %s", cred.Username, err)
+		}
+		if u, ok := idx.certs[id]; ok && u != cred.Username {
+			return fmt.Errorf("certificate %q mapped to users %q and %q", ci, u, cred.Username)
+		}
+		certs = append(certs, id)
+	}
+	if cred.RateLimit != nil {
+		if err := cred.RateLimit.validate(); err != nil {
+			return fmt.Errorf("rate limit of user %q: %s", cred.Username, err)
+		}
+	}
+
+	idx.store[cred.Username] = cred.Password
+	idx.perms[cred.Username] = make(map[string]bool, len(cred.Perms))
+	for _, p := range cred.Perms {
+		idx.perms[cred.Username][p] = true
+	}
+	if rules != nil {
+		idx.acls[cred.Username] = rules
+	}
+	for _, id := range certs {
+		idx.certs[id] = cred.Username
+	}
+	if cred.RateLimit != nil {
+		idx.limits[cred.Username] = *cred.RateLimit
+	}
+	return nil
+}
+
+// normalizeACL checks the given rules, and returns them with table patterns
+// and operations in lower case.
+func normalizeACL(rules []ACLRule) ([]ACLRule, error) {
+	norm := make([]ACLRule, len(rules))
+	for i, r := range rules {
+		if len(r.Tables) == 0 || len(r.Ops) == 0 {
+			return nil, fmt.Errorf("rule %d must list tables and ops", i)
+		}
+		for _, t := range r.Tables {
+			t = strings.ToLower(t)
+			if _, err := path.Match(t, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid table pattern %q", i, t)
+			}
+			norm[i].Tables = append(norm[i].Tables, t)
+		}
+		for _, op := range r.Ops {
+			op = strings.ToLower(op)
+			switch op {
+			case OpSelect, OpInsert, OpUpdate, OpDelete, OpDDL:
+			default:
+				return nil, fmt.Errorf("rule %d: invalid op %q", i, op)
+			}
+			norm[i].Ops = append(norm[i].Ops, op)
+		}
+	}
+	return norm, nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 type testBasicAuther struct {
@@ -481,6 +482,223 @@ func Test_AuthPermsAllUsers(t *testing.T) {
 	}
 }
 
+func Test_AuthACL(t *testing.T) {
+	const jsonStream = `
+		[
+			{
+				"username": "username1",
+				"password": "password1",
+				"perms": ["execute", "query"],
+				"acl": [
+					{"tables": ["Orders", "items_*"], "ops": ["SELECT", "insert"]},
+					{"tables": ["*"], "ops": ["ddl"]}
+				]
+			},
+			{
+				"username": "username2",
+				"password": "password2",
+				"perms": ["all"]
+			}
+		]
+	`
+
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(jsonStream)); err != nil {
+		t.Fatalf("failed to load credentials with ACL: %s", err.Error())
+	}
+
+	if !store.HasTableACL("username1") {
+		t.Fatalf("username1 should have a table ACL")
+	}
+	if store.HasTableACL("username2") {
+		t.Fatalf("username2 should not have a table ACL")
+	}
+	for _, tt := range []struct {
+		table string
+		op    string
+		exp   bool
+	}{
+		{"orders", OpSelect, true},
+		{"ORDERS", OpInsert, true},
+		{"orders", OpDelete, false},
+		{"items_2024", OpSelect, true},
+		{"items", OpSelect, false},
+		{"secrets", OpSelect, false},
+		{"secrets", OpDDL, true},
+		{"", OpDDL, true},
+		{"", OpSelect, false},
+	} {
+		if got := store.TableAllowed("username1", tt.table, tt.op); got != tt.exp {
+			t.Fatalf("wrong result for %s on table %q, exp %v, got %v", tt.op, tt.table, tt.exp, got)
+		}
+	}
+}
+
+func Test_AuthACLAllUsers(t *testing.T) {
+	const jsonStream = `
+		[
+			{"username": "username1", "password": "password1"},
+			{"username": "*", "acl": [{"tables": ["public"], "ops": ["select"]}]}
+		]
+	`
+
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(jsonStream)); err != nil {
+		t.Fatalf("failed to load credentials with ACL: %s", err.Error())
+	}
+	if !store.HasTableACL("username1") || !store.HasTableACL("") {
+		t.Fatalf("all users should have a table ACL")
+	}
+	if !store.TableAllowed("username1", "public", OpSelect) {
+		t.Fatalf("username1 should be allowed to select from public via *")
+	}
+	if store.TableAllowed("", "public", OpDelete) {
+		t.Fatalf("anonymous user should not be allowed to delete from public")
+	}
+}
+
+func Test_AuthACLInvalid(t *testing.T) {
+	for _, acl := range []string{
+		`[{"tables": ["foo"], "ops": ["truncate"]}]`,
+		`[{"tables": ["foo["], "ops": ["select"]}]`,
+		`[{"tables": ["foo"]}]`,
+		`[{"ops": ["select"]}]`,
+	} {
+		store := NewCredentialsStore()
+		err := store.Load(strings.NewReader(`[{"username": "username1", "password": "password1", "acl": ` + acl + `}]`))
+		if err == nil {
+			t.Fatalf("expected error loading ACL %s", acl)
+		}
+	}
+}
+
+func Test_AuthHashedPasswords(t *testing.T) {
+	jsonStream := `
+		[
+			{"username": "username1", "password": "` + mustHashPassword(t, "password1", HashBcrypt) + `", "perms": ["foo"]},
+			{"username": "username2", "password": "` + mustHashPassword(t, "password2", HashArgon2id) + `", "perms": ["foo"]},
+			{"username": "username3", "password": "password3", "perms": ["foo"]}
+		]
+	`
+
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(jsonStream)); err != nil {
+		t.Fatalf("failed to load hashed credentials: %s", err.Error())
+	}
+	for i := 0; i < 2; i++ {
+		// Check twice, so cached verifications are checked too.
+		for _, u := range []string{"username1", "username2", "username3"} {
+			pw := "password" + u[len(u)-1:]
+			if !store.Check(u, pw) {
+				t.Fatalf("correct password for %s not accepted", u)
+			}
+			if store.Check(u, pw+"x") {
+				t.Fatalf("wrong password for %s accepted", u)
+			}
+			if !store.AA(u, pw, "foo") {
+				t.Fatalf("%s not authorized for foo", u)
+			}
+		}
+	}
+
+	if _, ok := store.Password("username1"); ok {
+		t.Fatalf("hashed password returned for username1")
+	}
+	if pw, ok := store.Password("username3"); !ok || pw != "password3" {
+		t.Fatalf("wrong plaintext password returned for username3")
+	}
+}
+
+func Test_AuthHashedPasswordsMalformed(t *testing.T) {
+	for _, pw := range []string{
+		"$2a$10$short",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$",
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5",
+	} {
+		store := NewCredentialsStore()
+		err := store.Load(strings.NewReader(`[{"username": "username1", "password": "` + pw + `"}]`))
+		if err == nil {
+			t.Fatalf("expected error loading malformed hash %s", pw)
+		}
+	}
+}
+
+func Test_AuthReload(t *testing.T) {
+	path := mustWriteTempFile(t, `[{"username": "username1", "password": "password1", "perms": ["foo"]}]`)
+	store, err := NewCredentialsStoreFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to load credential store from file: %s", err.Error())
+	}
+	if reloaded, err := store.ReloadIfModified(); err != nil || reloaded {
+		t.Fatalf("unmodified file reloaded, err: %v", err)
+	}
+
+	// Rotate the password, and remove the perm.
+	if err := os.WriteFile(path, []byte(`[{"username": "username1", "password": "password2", "perms": ["bar"], "extra": true}]`), 0600); err != nil {
+		t.Fatalf("failed to write credentials file: %s", err)
+	}
+	if reloaded, err := store.ReloadIfModified(); err != nil || !reloaded {
+		t.Fatalf("modified file not reloaded, err: %v", err)
+	}
+	if store.Check("username1", "password1") {
+		t.Fatalf("old password still accepted after reload")
+	}
+	if !store.Check("username1", "password2") {
+		t.Fatalf("new password not accepted after reload")
+	}
+	if store.HasPerm("username1", "foo") || !store.HasPerm("username1", "bar") {
+		t.Fatalf("perms not replaced on reload")
+	}
+
+	// A malformed file must not change the credentials.
+	if err := os.WriteFile(path, []byte(`[{"username": "username1", "password": "password3"`), 0600); err != nil {
+		t.Fatalf("failed to write credentials file: %s", err)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatalf("expected error reloading malformed file")
+	}
+	if !store.Check("username1", "password2") || store.Check("username1", "password3") {
+		t.Fatalf("credentials changed by malformed file")
+	}
+
+	// Nor must a file with a malformed hash.
+	if err := os.WriteFile(path, []byte(`[{"username": "username1", "password": "$2a$10$short"}]`), 0600); err != nil {
+		t.Fatalf("failed to write credentials file: %s", err)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatalf("expected error reloading file with malformed hash")
+	}
+	if !store.Check("username1", "password2") {
+		t.Fatalf("credentials changed by file with malformed hash")
+	}
+
+	if err := NewCredentialsStore().Reload(); err == nil {
+		t.Fatalf("expected error reloading store not loaded from file")
+	}
+}
+
+func Test_AuthWatch(t *testing.T) {
+	path := mustWriteTempFile(t, `[{"username": "username1", "password": "password1"}]`)
+	store, err := NewCredentialsStoreFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to load credential store from file: %s", err.Error())
+	}
+	done := make(chan struct{})
+	defer close(done)
+	store.Watch(10*time.Millisecond, done)
+
+	if err := os.WriteFile(path, []byte(`[{"username": "username1", "password": "password2"}]`), 0600); err != nil {
+		t.Fatalf("failed to write credentials file: %s", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !store.Check("username1", "password2") {
+		if time.Now().After(deadline) {
+			t.Fatalf("credentials not reloaded after file changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func mustWriteTempFile(t *testing.T, s string) string {
 	f, err := os.CreateTemp(t.TempDir(), "rqlite-test")
 	if err != nil {
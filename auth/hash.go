@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// HashBcrypt is the name of the bcrypt password hashing algorithm.
+	HashBcrypt = "bcrypt"
+
+	// HashArgon2id is the name of the Argon2id password hashing algorithm.
+	HashArgon2id = "argon2id"
+
+	argon2idPrefix = "$argon2id$"
+	argon2Time     = 3
+	argon2Memory   = 64 * 1024
+	argon2Threads  = 4
+	argon2SaltLen  = 16
+	argon2KeyLen   = 32
+
+	// Upper bounds on the parameters accepted from a stored Argon2id hash,
+	// so that a single hash cannot make every login exhaust memory or CPU.
+	argon2MaxMemory = 1024 * 1024 // KiB, 1 GiB.
+	argon2MaxTime   = 64
+	argon2MaxKeyLen = 1024
+)
+
+var (
+	// ErrUnknownHash is returned when asked to use an unsupported hashing algorithm.
+	ErrUnknownHash = errors.New("unknown password hashing algorithm")
+
+	// ErrInvalidHash is returned when a stored password hash is malformed, or
+	// has parameters outside the accepted bounds.
+	ErrInvalidHash = errors.New("invalid password hash")
+)
+
+// HashPassword returns a hash of password, using the given algorithm, which
+// may be stored in the password field of a credentials file. cost is the
+// bcrypt cost, and is ignored by Argon2id. If cost is zero, the default cost
+// is used.
+func HashPassword(password, algorithm string, cost int) (string, error) {
+	switch algorithm {
+	case HashBcrypt:
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		b, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case HashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+			argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", ErrUnknownHash
+	}
+}
+
+// IsHashed returns true if the given password from a credentials file is a
+// hash, rather than the password itself.
+func IsHashed(stored string) bool {
+	return isBcrypt(stored) || strings.HasPrefix(stored, argon2idPrefix)
+}
+
+// checkPassword returns true if password matches stored, which is either a
+// hash, or the password itself. Plaintext passwords are compared in constant
+// time. A user without a stored password never matches.
+func checkPassword(stored, password string) bool {
+	switch {
+	case stored == "":
+		return false
+	case isBcrypt(stored):
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	case strings.HasPrefix(stored, argon2idPrefix):
+		return checkArgon2id(stored, password)
+	default:
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	}
+}
+
+// ValidateHash returns nil if stored, which must be a hash as reported by
+// IsHashed, is well-formed and may safely be checked against a password.
+func ValidateHash(stored string) error {
+	switch {
+	case isBcrypt(stored):
+		if _, err := bcrypt.Cost([]byte(stored)); err != nil {
+			return ErrInvalidHash
+		}
+		return nil
+	case strings.HasPrefix(stored, argon2idPrefix):
+		_, err := parseArgon2id(stored)
+		return err
+	default:
+		return ErrInvalidHash
+	}
+}
+
+func isBcrypt(s string) bool {
+	return strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+// argon2idHash holds the parameters, salt and key of an Argon2id hash.
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id parses a hash in the PHC string format, for example
+// $argon2id$v=19$m=65536,t=3,p=4$salt$key, rejecting parameters which
+// argon2.IDKey does not accept or which exceed the accepted bounds.
+func parseArgon2id(stored string) (*argon2idHash, error) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidHash
+	}
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if h.time == 0 || h.time > argon2MaxTime || h.threads == 0 || h.memory > argon2MaxMemory {
+		return nil, ErrInvalidHash
+	}
+	var err error
+	h.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(h.salt) == 0 {
+		return nil, ErrInvalidHash
+	}
+	h.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(h.key) == 0 || len(h.key) > argon2MaxKeyLen {
+		return nil, ErrInvalidHash
+	}
+	return &h, nil
+}
+
+// checkArgon2id checks password against an Argon2id hash. A malformed hash
+// never matches.
+func checkArgon2id(stored, password string) bool {
+	h, err := parseArgon2id(stored)
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(got, h.key) == 1
+}
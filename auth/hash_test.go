@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_HashPassword(t *testing.T) {
+	for _, alg := range []string{HashBcrypt, HashArgon2id} {
+		h, err := HashPassword("secret", alg, bcrypt.MinCost)
+		if err != nil {
+			t.Fatalf("failed to hash password with %s: %s", alg, err)
+		}
+		if !IsHashed(h) {
+			t.Fatalf("%s hash %s not detected as hashed", alg, h)
+		}
+		if !checkPassword(h, "secret") {
+			t.Fatalf("%s hash does not match password", alg)
+		}
+		if checkPassword(h, "wrong") {
+			t.Fatalf("%s hash matches wrong password", alg)
+		}
+		h2, err := HashPassword("secret", alg, bcrypt.MinCost)
+		if err != nil {
+			t.Fatalf("failed to hash password with %s: %s", alg, err)
+		}
+		if h == h2 {
+			t.Fatalf("%s hashes of the same password are not salted", alg)
+		}
+	}
+	if !strings.HasPrefix(mustHashPassword(t, "secret", HashArgon2id), "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Fatalf("wrong Argon2id hash format")
+	}
+
+	if _, err := HashPassword("secret", "md5", 0); err != ErrUnknownHash {
+		t.Fatalf("expected ErrUnknownHash, got %v", err)
+	}
+}
+
+func Test_CheckPassword(t *testing.T) {
+	for _, tt := range []struct {
+		stored   string
+		password string
+		exp      bool
+	}{
+		{"secret", "secret", true},
+		{"secret", "Secret", false},
+		{"secret", "", false},
+		{"", "", false},
+		{"", "secret", false},
+		{"$argon2id$v=19$m=65536,t=3,p=4$c2FsdA", "secret", false},
+		{"$argon2id$v=18$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5", "secret", false},
+		{"$argon2id$v=19$m=65536,t=3,p=4$!!!$a2V5", "secret", false},
+		{"$2a$10$notavalidhash", "secret", false},
+	} {
+		if got := checkPassword(tt.stored, tt.password); got != tt.exp {
+			t.Fatalf("wrong result checking %q against %q, exp %v, got %v", tt.password, tt.stored, tt.exp, got)
+		}
+	}
+}
+
+func Test_CheckPassword_MalformedArgon2id(t *testing.T) {
+	for _, stored := range []string{
+		"$argon2id$v=19$m=65536,t=0,p=4$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=65536,t=65,p=4$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=0$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=256$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=4294967295,t=3,p=4$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=1048577,t=3,p=4$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=-1,t=3,p=4$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=4$$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=65536,t=3$c2FsdHNhbHQ$a2V5",
+	} {
+		if checkPassword(stored, "secret") {
+			t.Fatalf("malformed hash %q matched password", stored)
+		}
+		if err := ValidateHash(stored); err != ErrInvalidHash {
+			t.Fatalf("expected ErrInvalidHash for %q, got %v", stored, err)
+		}
+	}
+}
+
+func Test_ValidateHash(t *testing.T) {
+	for _, alg := range []string{HashBcrypt, HashArgon2id} {
+		if err := ValidateHash(mustHashPassword(t, "secret", alg)); err != nil {
+			t.Fatalf("valid %s hash rejected: %s", alg, err)
+		}
+	}
+	for _, stored := range []string{"secret", "$2a$10$notavalidhash", "$2a$99$abc"} {
+		if err := ValidateHash(stored); err != ErrInvalidHash {
+			t.Fatalf("expected ErrInvalidHash for %q, got %v", stored, err)
+		}
+	}
+}
+
+func Test_IsHashed(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		exp bool
+	}{
+		{"secret", false},
+		{"$2a$10$abc", true},
+		{"$2b$10$abc", true},
+		{"$2y$10$abc", true},
+		{"$argon2id$v=19$abc", true},
+		{"$argon2i$v=19$abc", false},
+	} {
+		if got := IsHashed(tt.s); got != tt.exp {
+			t.Fatalf("wrong result for %q, exp %v, got %v", tt.s, tt.exp, got)
+		}
+	}
+}
+
+func mustHashPassword(t *testing.T, password, algorithm string) string {
+	t.Helper()
+	h, err := HashPassword(password, algorithm, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %s", err)
+	}
+	return h
+}
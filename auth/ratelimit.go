@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit limits the rate at which a user makes requests. Each limit is
+// enforced with a token bucket, which fills at the given rate, and holds at
+// most Burst seconds of unused allowance. A zero limit is no limit.
+type RateLimit struct {
+	RequestsPerSecond   float64 `json:"requests_per_second,omitempty"`
+	StatementsPerSecond float64 `json:"statements_per_second,omitempty"`
+	BytesPerSecond      float64 `json:"bytes_per_second,omitempty"`
+
+	// Burst is the number of seconds of allowance which may be used at
+	// once. If zero, it is one second.
+	Burst float64 `json:"burst,omitempty"`
+}
+
+func (rl RateLimit) validate() error {
+	for _, v := range []float64{rl.RequestsPerSecond, rl.StatementsPerSecond, rl.BytesPerSecond, rl.Burst} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("limits must be non-negative numbers")
+		}
+	}
+	return nil
+}
+
+// RateLimit returns the rate limit of the given user, or of AllUsers if the
+// user has none. It does not perform any password checking.
+func (c *CredentialsStore) RateLimit(username string) (RateLimit, bool) {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if rl, ok := c.limits[username]; ok {
+		return rl, true
+	}
+	rl, ok := c.limits[AllUsers]
+	return rl, ok
+}
+
+// Client is the credentials presented by a client, which identify the user
+// making a request.
+type Client struct {
+	Username string
+	Password string
+	Token    string
+	Cert     *x509.Certificate
+}
+
+// RateLimitError is returned when a request exceeds the rate limit of the
+// user making it.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e *RateLimitError) Error() string {
+	return "rate limit exceeded, retry after " + e.RetryAfter.String()
+}
+
+// RateLimiter enforces the rate limits of the users in a CredentialsStore.
+// Clients whose credentials are not verified are limited together, as
+// anonymous clients. The rate limit of AllUsers applies to anonymous clients,
+// and separately to each user without a rate limit of their own.
+//
+// A RateLimiter is safe for concurrent use.
+type RateLimiter struct {
+	cs *CredentialsStore
+
+	mu    sync.Mutex
+	users map[string]*userLimiter
+
+	now func() time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter, which enforces the rate limits in
+// the given store.
+func NewRateLimiter(cs *CredentialsStore) *RateLimiter {
+	return &RateLimiter{
+		cs:    cs,
+		users: make(map[string]*userLimiter),
+		now:   time.Now,
+	}
+}
+
+// Allow returns nil if the client may make a request of the given number of
+// statements and bytes, and charges the request to the user. Otherwise it
+// returns a *RateLimitError, saying when the request would be allowed. A
+// request larger than a bucket may hold is allowed once the bucket is full,
+// and the bucket is then in debt. A nil RateLimiter allows everything.
+func (l *RateLimiter) Allow(client Client, statements, bytes int) error {
+	if l == nil {
+		return nil
+	}
+	username := l.user(client)
+	rl, ok := l.cs.RateLimit(username)
+	if !ok {
+		return nil
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ul, ok := l.users[username]
+	if !ok || ul.limit != rl {
+		ul = newUserLimiter(rl, now)
+		l.users[username] = ul
+	}
+
+	costs := [...]float64{1, float64(statements), float64(bytes)}
+	var wait time.Duration
+	for i := range ul.buckets {
+		if w := ul.buckets[i].wait(now, costs[i]); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		ul.limited++
+		return &RateLimitError{RetryAfter: wait}
+	}
+	for i := range ul.buckets {
+		ul.buckets[i].take(costs[i])
+	}
+	ul.allowed++
+	return nil
+}
+
+// Stats returns the rate limit of each user which has made requests, and
+// how many of their requests have been allowed and limited.
+func (l *RateLimiter) Stats() (map[string]any, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	users := make(map[string]any, len(l.users))
+	for username, ul := range l.users {
+		if username == "" {
+			username = "(anonymous)"
+		}
+		users[username] = map[string]any{
+			"limit":   ul.limit,
+			"allowed": ul.allowed,
+			"limited": ul.limited,
+		}
+	}
+	return map[string]any{"users": users}, nil
+}
+
+// user returns the user the client authenticates as, or the empty string if
+// the credentials of the client are not verified.
+func (l *RateLimiter) user(client Client) string {
+	var username string
+	var ok bool
+	switch {
+	case client.Token != "":
+		username, ok = l.cs.TokenUser(client.Token)
+	case client.Cert != nil:
+		username, ok = l.cs.CertUser(client.Cert)
+	case client.Username != "":
+		username, ok = client.Username, l.cs.Check(client.Username, client.Password)
+	}
+	if !ok {
+		return ""
+	}
+	return username
+}
+
+// userLimiter holds the token buckets of a user, for requests, statements
+// and bytes.
+type userLimiter struct {
+	limit   RateLimit
+	buckets [3]bucket
+	allowed uint64
+	limited uint64
+}
+
+func newUserLimiter(rl RateLimit, now time.Time) *userLimiter {
+	burst := rl.Burst
+	if burst == 0 {
+		burst = 1
+	}
+	ul := &userLimiter{limit: rl}
+	for i, rate := range []float64{rl.RequestsPerSecond, rl.StatementsPerSecond, rl.BytesPerSecond} {
+		ul.buckets[i] = bucket{
+			rate:     rate,
+			capacity: math.Max(rate*burst, 1),
+			tokens:   math.Max(rate*burst, 1),
+			last:     now,
+		}
+	}
+	return ul
+}
+
+// bucket is a token bucket. A zero rate is no limit.
+type bucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// wait fills the bucket, and returns how long until n tokens may be taken.
+func (b *bucket) wait(now time.Time, n float64) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	if now.After(b.last) {
+		b.tokens = math.Min(b.capacity, b.tokens+b.rate*now.Sub(b.last).Seconds())
+		b.last = now
+	}
+	need := math.Min(n, b.capacity)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration(math.Ceil((need - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// take takes n tokens from the bucket, which may leave it in debt.
+func (b *bucket) take(n float64) {
+	if b.rate == 0 {
+		return
+	}
+	b.tokens -= n
+}
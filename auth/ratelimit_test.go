@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_RateLimiter(t *testing.T) {
+	c := NewCredentialsStore()
+	if err := c.Load(strings.NewReader(`[
+		{"username": "alice", "password": "pw", "rate_limit": {"requests_per_second": 2, "statements_per_second": 5}},
+		{"username": "bob", "password": "pw"},
+		{"username": "*", "rate_limit": {"bytes_per_second": 100, "burst": 2}}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+
+	now := time.Unix(1000, 0)
+	l := NewRateLimiter(c)
+	l.now = func() time.Time { return now }
+	alice := Client{Username: "alice", Password: "pw"}
+
+	for i := range 2 {
+		if err := l.Allow(alice, 1, 1000); err != nil {
+			t.Fatalf("request %d denied: %s", i, err)
+		}
+	}
+	err := l.Allow(alice, 1, 0)
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if exp, got := 500*time.Millisecond, rlErr.RetryAfter; exp != got {
+		t.Fatalf("wrong retry after, exp %s, got %s", exp, got)
+	}
+
+	// A request larger than the bucket is allowed once the bucket is full,
+	// and must then be paid for.
+	now = now.Add(time.Second)
+	if err := l.Allow(alice, 20, 0); err != nil {
+		t.Fatalf("large request denied: %s", err)
+	}
+	now = now.Add(time.Second)
+	if err := l.Allow(alice, 1, 0); err == nil {
+		t.Fatalf("request permitted while in debt")
+	}
+	now = now.Add(3 * time.Second)
+	if err := l.Allow(alice, 1, 0); err != nil {
+		t.Fatalf("request denied after debt repaid: %s", err)
+	}
+
+	// The wrong password is anonymous, limited by the limit of all users,
+	// separately from bob, who has no limit of their own.
+	if err := l.Allow(Client{Username: "alice", Password: "wrong"}, 1, 200); err != nil {
+		t.Fatalf("anonymous request denied: %s", err)
+	}
+	if err := l.Allow(Client{}, 1, 1); err == nil {
+		t.Fatalf("anonymous request permitted beyond limit")
+	}
+	if err := l.Allow(Client{Username: "bob", Password: "pw"}, 1, 200); err != nil {
+		t.Fatalf("bob denied: %s", err)
+	}
+
+	stats, err := l.Stats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %s", err)
+	}
+	users := stats["users"].(map[string]any)
+	if got := users["alice"].(map[string]any)["limited"]; got != uint64(2) {
+		t.Fatalf("wrong number of limited requests for alice, got %v", got)
+	}
+	if _, ok := users["(anonymous)"]; !ok {
+		t.Fatalf("no stats for anonymous clients: %v", users)
+	}
+
+	var nl *RateLimiter
+	if err := nl.Allow(alice, 100, 100); err != nil {
+		t.Fatalf("nil rate limiter denied request: %s", err)
+	}
+}
+
+func Test_RateLimiterReload(t *testing.T) {
+	c := NewCredentialsStore()
+	if err := c.Load(strings.NewReader(`[{"username": "alice", "password": "pw", "rate_limit": {"requests_per_second": 1}}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	l := NewRateLimiter(c)
+	alice := Client{Username: "alice", Password: "pw"}
+	if err := l.Allow(alice, 0, 0); err != nil {
+		t.Fatalf("request denied: %s", err)
+	}
+	if err := l.Allow(alice, 0, 0); err == nil {
+		t.Fatalf("request permitted beyond limit")
+	}
+	if err := c.Load(strings.NewReader(`[{"username": "alice", "password": "pw"}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	if err := l.Allow(alice, 0, 0); err != nil {
+		t.Fatalf("request denied after limit removed: %s", err)
+	}
+
+	if err := c.Load(strings.NewReader(`[{"username": "alice", "rate_limit": {"requests_per_second": -1}}]`)); err == nil {
+		t.Fatalf("expected error loading negative rate limit")
+	}
+}
@@ -0,0 +1,456 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // Register SHA-256 for crypto.Hash.
+	_ "crypto/sha512" // Register SHA-384 and SHA-512 for crypto.Hash.
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed, or its
+	// signature cannot be verified.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrTokenExpired is returned when a token has expired, or is not yet
+	// valid.
+	ErrTokenExpired = errors.New("token expired or not yet valid")
+
+	// ErrTokenClaims is returned when the issuer or audience of a token is
+	// not accepted, or it does not identify a user.
+	ErrTokenClaims = errors.New("token claims not accepted")
+)
+
+const (
+	defaultUsernameClaim = "sub"
+	defaultPermsClaim    = "perms"
+	defaultGroupsClaim   = "groups"
+)
+
+// TokenConfig is the configuration of a TokenVerifier.
+type TokenConfig struct {
+	// Issuer is the required value of the iss claim.
+	Issuer string `json:"issuer"`
+
+	// Audience is the value the aud claim must be, or contain.
+	Audience string `json:"audience"`
+
+	// JWKSFile is the path of a JSON Web Key Set file containing the keys
+	// tokens are signed with. A relative path is relative to the directory
+	// of the configuration file.
+	JWKSFile string `json:"jwks_file,omitempty"`
+
+	// Keys are static keys tokens are signed with, in addition to those in
+	// JWKSFile. Secrets for HMAC are keys of type "oct".
+	Keys []JSONWebKey `json:"keys,omitempty"`
+
+	// UsernameClaim is the claim which names the user. Defaults to "sub".
+	UsernameClaim string `json:"username_claim,omitempty"`
+
+	// PermsClaim is the claim listing the perms of the user. Defaults to
+	// "perms".
+	PermsClaim string `json:"perms_claim,omitempty"`
+
+	// GroupsClaim is the claim listing the groups of the user. Defaults to
+	// "groups".
+	GroupsClaim string `json:"groups_claim,omitempty"`
+
+	// Groups maps groups to the perms granted to their members.
+	Groups map[string][]string `json:"groups,omitempty"`
+
+	// Leeway is the allowed clock skew when checking the exp and nbf claims,
+	// for example "30s".
+	Leeway string `json:"leeway,omitempty"`
+}
+
+// JSONWebKey is a public key, or a secret, in the JSON Web Key format of
+// RFC 7517.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// TokenIdentity is the identity asserted by a verified token.
+type TokenIdentity struct {
+	Username string
+	Perms    map[string]bool
+}
+
+// verificationKey is a key parsed from a JSONWebKey.
+type verificationKey struct {
+	kid string
+	alg string
+	key any // *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or []byte.
+}
+
+// TokenVerifier verifies JSON Web Tokens presented as bearer tokens, and
+// maps their claims to a user and perms. Tokens must be signed by one of the
+// configured keys, must have been issued by the configured issuer for the
+// configured audience, and must not have expired. The perms of the user are
+// those listed in the perms claim, plus those granted to the groups listed
+// in the groups claim.
+//
+// A TokenVerifier is safe for concurrent use.
+type TokenVerifier struct {
+	path string
+
+	mu     sync.RWMutex
+	cfg    TokenConfig
+	keys   []verificationKey
+	leeway time.Duration
+
+	now func() time.Time
+}
+
+// NewTokenVerifier returns a new TokenVerifier with the given configuration.
+func NewTokenVerifier(cfg TokenConfig) (*TokenVerifier, error) {
+	v := &TokenVerifier{now: time.Now}
+	return v, v.configure(cfg, "")
+}
+
+// NewTokenVerifierFromFile returns a new TokenVerifier configured by the
+// JSON TokenConfig in the given file.
+func NewTokenVerifierFromFile(path string) (*TokenVerifier, error) {
+	v := &TokenVerifier{path: path, now: time.Now}
+	return v, v.Reload()
+}
+
+// Reload reloads the configuration, and keys, from the file the verifier
+// was created from. If the configuration cannot be loaded, the existing
+// configuration is kept.
+func (v *TokenVerifier) Reload() error {
+	if v.path == "" {
+		return fmt.Errorf("token verifier was not loaded from a file")
+	}
+	b, err := os.ReadFile(v.path)
+	if err != nil {
+		return err
+	}
+	var cfg TokenConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", v.path, err)
+	}
+	return v.configure(cfg, filepath.Dir(v.path))
+}
+
+func (v *TokenVerifier) configure(cfg TokenConfig, dir string) error {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return fmt.Errorf("issuer and audience must be set")
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = defaultUsernameClaim
+	}
+	if cfg.PermsClaim == "" {
+		cfg.PermsClaim = defaultPermsClaim
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = defaultGroupsClaim
+	}
+	var leeway time.Duration
+	if cfg.Leeway != "" {
+		var err error
+		if leeway, err = time.ParseDuration(cfg.Leeway); err != nil || leeway < 0 {
+			return fmt.Errorf("invalid leeway %q", cfg.Leeway)
+		}
+	}
+
+	jwks := cfg.Keys
+	if cfg.JWKSFile != "" {
+		p := cfg.JWKSFile
+		if dir != "" && !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileKeys, err := ReadJWKS(f)
+		if err != nil {
+			return fmt.Errorf("failed to read JWKS file %s: %s", p, err)
+		}
+		jwks = append(jwks[:len(jwks):len(jwks)], fileKeys...)
+	}
+	if len(jwks) == 0 {
+		return fmt.Errorf("no keys configured")
+	}
+	keys := make([]verificationKey, 0, len(jwks))
+	for i, jwk := range jwks {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		k, err := parseJSONWebKey(jwk)
+		if err != nil {
+			return fmt.Errorf("key %d (kid %q): %s", i, jwk.Kid, err)
+		}
+		keys = append(keys, k)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cfg, v.keys, v.leeway = cfg, keys, leeway
+	return nil
+}
+
+// ReadJWKS reads a JSON Web Key Set from r.
+func ReadJWKS(r io.Reader) ([]JSONWebKey, error) {
+	var set struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, err
+	}
+	return set.Keys, nil
+}
+
+// Verify verifies the given token, and returns the identity it asserts.
+func (v *TokenVerifier) Verify(token string) (*TokenIdentity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	v.mu.RLock()
+	cfg, keys, leeway := v.cfg, v.keys, v.leeway
+	v.mu.RUnlock()
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range keys {
+		if header.Kid != "" && k.kid != "" && k.kid != header.Kid {
+			continue
+		}
+		if k.alg != "" && k.alg != header.Alg {
+			continue
+		}
+		if verifySignature(header.Alg, k.key, signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidToken
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, ErrTokenExpired
+	}
+	if iss, _ := claims["iss"].(string); iss != cfg.Issuer {
+		return nil, ErrTokenClaims
+	}
+	if !containsString(claims["aud"], cfg.Audience) {
+		return nil, ErrTokenClaims
+	}
+	username, _ := claims[cfg.UsernameClaim].(string)
+	if username == "" {
+		return nil, ErrTokenClaims
+	}
+
+	id := &TokenIdentity{Username: username, Perms: make(map[string]bool)}
+	for _, p := range stringList(claims[cfg.PermsClaim]) {
+		id.Perms[p] = true
+	}
+	for _, g := range stringList(claims[cfg.GroupsClaim]) {
+		for _, p := range cfg.Groups[g] {
+			id.Perms[p] = true
+		}
+	}
+	return id, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// containsString returns whether claim is s, or is a list containing s.
+func containsString(claim any, s string) bool {
+	for _, c := range stringList(claim) {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList returns the strings of a claim which is a list of strings, or
+// a string of space-separated values, such as the scope claim.
+func stringList(claim any) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []any:
+		l := make([]string, 0, len(c))
+		for _, e := range c {
+			if s, ok := e.(string); ok {
+				l = append(l, s)
+			}
+		}
+		return l
+	}
+	return nil
+}
+
+// verifySignature returns whether sig is a valid signature of signed, made
+// with alg by the given key. The algorithm must suit the type of the key,
+// so that, for example, a public key cannot be used as an HMAC secret.
+func verifySignature(alg string, key any, signed, sig []byte) bool {
+	var hash crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+
+	switch k := key.(type) {
+	case []byte:
+		if !strings.HasPrefix(alg, "HS") || hash == 0 {
+			return false
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write(signed)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case *rsa.PublicKey:
+		if hash == 0 {
+			return false
+		}
+		h := hash.New()
+		h.Write(signed)
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hash, h.Sum(nil), sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(k, hash, h.Sum(nil), sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		// The curve determines the algorithm, for example P-256 with ES256.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg != ecdsaAlgs[k.Curve.Params().Name] || len(sig) != 2*size {
+			return false
+		}
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, h.Sum(nil), r, s)
+	case ed25519.PublicKey:
+		return alg == "EdDSA" && ed25519.Verify(k, signed, sig)
+	}
+	return false
+}
+
+var ecdsaAlgs = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+func parseJSONWebKey(jwk JSONWebKey) (verificationKey, error) {
+	vk := verificationKey{kid: jwk.Kid, alg: jwk.Alg}
+	b64 := base64.RawURLEncoding
+	switch jwk.Kty {
+	case "oct":
+		k, err := b64.DecodeString(jwk.K)
+		if err != nil || len(k) == 0 {
+			return vk, fmt.Errorf("invalid secret")
+		}
+		vk.key = k
+	case "RSA":
+		n, err := b64.DecodeString(jwk.N)
+		if err != nil || len(n) == 0 {
+			return vk, fmt.Errorf("invalid modulus")
+		}
+		e, err := b64.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return vk, fmt.Errorf("invalid exponent")
+		}
+		vk.key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return vk, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, errX := b64.DecodeString(jwk.X)
+		y, errY := b64.DecodeString(jwk.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return vk, fmt.Errorf("invalid point")
+		}
+		k, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return vk, fmt.Errorf("invalid point: %s", err)
+		}
+		vk.key = k
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return vk, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := b64.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return vk, fmt.Errorf("invalid public key")
+		}
+		vk.key = ed25519.PublicKey(x)
+	default:
+		return vk, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+	return vk, nil
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TokenVerifier_Algorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %s", err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %s", err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	ecPub, err := ecKey.PublicKey.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode ECDSA key: %s", err)
+	}
+	b64 := base64.RawURLEncoding
+	v := mustNewTokenVerifier(t, TokenConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "rqlite",
+		Keys: []JSONWebKey{
+			{Kty: "RSA", Kid: "rsa", N: b64.EncodeToString(rsaKey.N.Bytes()), E: "AQAB"},
+			{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64.EncodeToString(ecPub[1:33]), Y: b64.EncodeToString(ecPub[33:])},
+			{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: b64.EncodeToString(edPub)},
+			{Kty: "oct", Kid: "hs", K: b64.EncodeToString(secret)},
+		},
+	})
+	claims := validClaims("svc")
+
+	for _, tt := range []struct {
+		alg string
+		kid string
+		key any
+	}{
+		{"RS256", "rsa", rsaKey},
+		{"PS256", "rsa", rsaKey},
+		{"ES256", "ec", ecKey},
+		{"EdDSA", "ed", edKey},
+		{"HS256", "hs", secret},
+		{"HS256", "", secret},
+	} {
+		id, err := v.Verify(signToken(t, tt.alg, tt.kid, tt.key, claims))
+		if err != nil {
+			t.Fatalf("failed to verify %s token: %s", tt.alg, err)
+		}
+		if id.Username != "svc" {
+			t.Fatalf("wrong username for %s token, got %s", tt.alg, id.Username)
+		}
+	}
+
+	// A public key must not be accepted as an HMAC secret.
+	rsaPub := b64.EncodeToString(rsaKey.N.Bytes())
+	if _, err := v.Verify(signToken(t, "HS256", "rsa", []byte(rsaPub), claims)); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for HS256 token with RSA key, got %v", err)
+	}
+	unsigned := strings.Join(strings.Split(signToken(t, "HS256", "hs", secret, claims), ".")[:2], ".") + "."
+	if _, err := v.Verify(unsigned); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for unsigned token, got %v", err)
+	}
+	if _, err := v.Verify(signToken(t, "none", "", nil, claims)); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for alg none, got %v", err)
+	}
+	if _, err := v.Verify(signToken(t, "HS256", "hs", []byte("wrong secret"), claims)); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for wrong secret, got %v", err)
+	}
+	if _, err := v.Verify("not.a.token"); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for garbage, got %v", err)
+	}
+}
+
+func Test_TokenVerifier_Claims(t *testing.T) {
+	secret := []byte("secret")
+	v := mustNewTokenVerifier(t, TokenConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "rqlite",
+		Keys:     []JSONWebKey{{Kty: "oct", K: base64.RawURLEncoding.EncodeToString(secret)}},
+		Groups:   map[string][]string{"writers": {PermExecute, PermQuery}},
+		Leeway:   "30s",
+	})
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	for i, tt := range []struct {
+		mod func(map[string]any)
+		err error
+	}{
+		{mod: func(c map[string]any) {}},
+		{mod: func(c map[string]any) { c["aud"] = []string{"other", "rqlite"} }},
+		{mod: func(c map[string]any) { c["exp"] = now.Add(-10 * time.Second).Unix() }},
+		{mod: func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() }, err: ErrTokenExpired},
+		{mod: func(c map[string]any) { delete(c, "exp") }, err: ErrTokenExpired},
+		{mod: func(c map[string]any) { c["nbf"] = now.Add(time.Minute).Unix() }, err: ErrTokenExpired},
+		{mod: func(c map[string]any) { c["iss"] = "https://evil.example.com" }, err: ErrTokenClaims},
+		{mod: func(c map[string]any) { c["aud"] = "other" }, err: ErrTokenClaims},
+		{mod: func(c map[string]any) { delete(c, "aud") }, err: ErrTokenClaims},
+		{mod: func(c map[string]any) { delete(c, "sub") }, err: ErrTokenClaims},
+	} {
+		claims := validClaims("svc")
+		tt.mod(claims)
+		_, err := v.Verify(signToken(t, "HS256", "", secret, claims))
+		if err != tt.err {
+			t.Fatalf("test %d: exp error %v, got %v", i, tt.err, err)
+		}
+	}
+
+	claims := validClaims("svc")
+	claims["perms"] = []string{PermStatus}
+	claims["groups"] = []string{"writers", "unknown"}
+	id, err := v.Verify(signToken(t, "HS256", "", secret, claims))
+	if err != nil {
+		t.Fatalf("failed to verify token: %s", err)
+	}
+	for _, p := range []string{PermStatus, PermExecute, PermQuery} {
+		if !id.Perms[p] {
+			t.Fatalf("token identity does not have perm %s", p)
+		}
+	}
+	if id.Perms[PermBackup] {
+		t.Fatalf("token identity has perm %s", PermBackup)
+	}
+
+	claims["perms"] = "ready backup"
+	if id, err = v.Verify(signToken(t, "HS256", "", secret, claims)); err != nil {
+		t.Fatalf("failed to verify token: %s", err)
+	}
+	if !id.Perms[PermReady] || !id.Perms[PermBackup] {
+		t.Fatalf("space-separated perms not granted")
+	}
+}
+
+func Test_TokenVerifier_Config(t *testing.T) {
+	for i, cfg := range []TokenConfig{
+		{Audience: "rqlite", Keys: []JSONWebKey{{Kty: "oct", K: "c2VjcmV0"}}},
+		{Issuer: "idp", Keys: []JSONWebKey{{Kty: "oct", K: "c2VjcmV0"}}},
+		{Issuer: "idp", Audience: "rqlite"},
+		{Issuer: "idp", Audience: "rqlite", Keys: []JSONWebKey{{Kty: "EC", Crv: "P-256", X: "AA", Y: "AA"}}},
+		{Issuer: "idp", Audience: "rqlite", Keys: []JSONWebKey{{Kty: "DSA"}}},
+		{Issuer: "idp", Audience: "rqlite", Keys: []JSONWebKey{{Kty: "oct", K: "c2VjcmV0"}}, Leeway: "soon"},
+	} {
+		if _, err := NewTokenVerifier(cfg); err == nil {
+			t.Fatalf("test %d: expected error for invalid config", i)
+		}
+	}
+}
+
+func Test_TokenVerifier_File(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	writeFile("jwks.json", `{"keys": [{"kty": "oct", "kid": "k1", "k": "c2VjcmV0MQ"}]}`)
+	writeFile("jwt.json", `{"issuer": "idp", "audience": "rqlite", "jwks_file": "jwks.json"}`)
+
+	v, err := NewTokenVerifierFromFile(filepath.Join(dir, "jwt.json"))
+	if err != nil {
+		t.Fatalf("failed to create token verifier: %s", err)
+	}
+	claims := validClaims("svc")
+	claims["iss"] = "idp"
+	if _, err := v.Verify(signToken(t, "HS256", "k1", []byte("secret1"), claims)); err != nil {
+		t.Fatalf("failed to verify token: %s", err)
+	}
+
+	// Rotate the keys.
+	writeFile("jwks.json", `{"keys": [{"kty": "oct", "kid": "k2", "k": "c2VjcmV0Mg"}]}`)
+	if err := v.Reload(); err != nil {
+		t.Fatalf("failed to reload token verifier: %s", err)
+	}
+	if _, err := v.Verify(signToken(t, "HS256", "k1", []byte("secret1"), claims)); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for rotated key, got %v", err)
+	}
+	if _, err := v.Verify(signToken(t, "HS256", "k2", []byte("secret2"), claims)); err != nil {
+		t.Fatalf("failed to verify token: %s", err)
+	}
+
+	// A bad configuration is rejected, and the existing keys kept.
+	writeFile("jwks.json", `{"keys": [`)
+	if err := v.Reload(); err == nil {
+		t.Fatalf("expected error reloading bad JWKS file")
+	}
+	if _, err := v.Verify(signToken(t, "HS256", "k2", []byte("secret2"), claims)); err != nil {
+		t.Fatalf("failed to verify token after failed reload: %s", err)
+	}
+}
+
+func Test_AuthToken(t *testing.T) {
+	secret := []byte("secret")
+	v := mustNewTokenVerifier(t, TokenConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "rqlite",
+		Keys:     []JSONWebKey{{Kty: "oct", K: base64.RawURLEncoding.EncodeToString(secret)}},
+	})
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(`[
+		{"username": "*", "perms": ["status"]},
+		{"username": "svc", "acl": [{"tables": ["orders"], "ops": ["select"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+
+	claims := validClaims("svc")
+	claims["perms"] = []string{PermQuery}
+	token := signToken(t, "HS256", "", secret, claims)
+	if store.AAToken(token, PermQuery) {
+		t.Fatalf("token accepted without a token verifier")
+	}
+
+	store.SetTokenVerifier(v)
+	if !store.AAToken(token, PermQuery) {
+		t.Fatalf("token not granted query perm")
+	}
+	if store.AAToken(token, PermExecute) {
+		t.Fatalf("token granted execute perm")
+	}
+	if !store.AAToken("garbage", PermStatus) {
+		t.Fatalf("perm of all users not granted")
+	}
+	if store.AAToken("garbage", PermQuery) {
+		t.Fatalf("invalid token granted query perm")
+	}
+	if u, ok := store.TokenUser(token); !ok || u != "svc" {
+		t.Fatalf("wrong token user, got %q, %v", u, ok)
+	}
+	if _, ok := store.TokenUser("garbage"); ok {
+		t.Fatalf("invalid token has a user")
+	}
+
+	// The ACL of the user named by the token applies.
+	if !store.HasTableACL("svc") || store.TableAllowed("svc", "secrets", OpSelect) {
+		t.Fatalf("ACL of token user not applied")
+	}
+
+	var nilStore *CredentialsStore
+	if !nilStore.AAToken(token, PermExecute) {
+		t.Fatalf("nil store denied token")
+	}
+}
+
+func mustNewTokenVerifier(t *testing.T, cfg TokenConfig) *TokenVerifier {
+	t.Helper()
+	v, err := NewTokenVerifier(cfg)
+	if err != nil {
+		t.Fatalf("failed to create token verifier: %s", err)
+	}
+	return v
+}
+
+func validClaims(sub string) map[string]any {
+	return map[string]any{
+		"iss": "https://idp.example.com",
+		"aud": "rqlite",
+		"sub": sub,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// signToken returns a token containing claims, signed with key using alg.
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	enc := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal: %s", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(header) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case nil:
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if alg == "PS256" {
+			sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}
+	case *ecdsa.PrivateKey:
+		var r, s []byte
+		rInt, sInt, e := ecdsa.Sign(rand.Reader, k, digest[:])
+		r, s, err = rInt.FillBytes(make([]byte, 32)), sInt.FillBytes(make([]byte, 32)), e
+		sig = append(r, s...)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
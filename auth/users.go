@@ -0,0 +1,93 @@
+package auth
+
+import "github.com/rqlite/rqlite/v10/internal/logging"
+
+// UsersTable is the table which holds the users managed through the database,
+// rather than a credentials file. Since it is stored in the database, it is
+// replicated to every node. It holds password hashes, so statements may not
+// access it directly.
+const UsersTable = "_rqlite_users"
+
+// UserSource is a source of users in addition to those loaded from a file,
+// such as UsersTable.
+type UserSource interface {
+	// UsersVersion returns a value which changes whenever the users may
+	// have changed.
+	UsersVersion() uint64
+
+	// Users returns the users.
+	Users() ([]Credential, error)
+}
+
+// Validate returns an error if the credential could not be loaded.
+func (cred Credential) Validate() error {
+	return newCredentialIndex().add(cred)
+}
+
+// SetUserSource sets a source of users in addition to those loaded from a
+// file. Users are read from the source whenever its version changes, so
+// changes take effect immediately. A user in the file takes precedence over a
+// user of the same name from the source, so that a bootstrap administrator
+// can always be defined in the file.
+func (c *CredentialsStore) SetUserSource(src UserSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userSource = src
+	c.userCreds = nil
+	c.usersVersion = 0
+	c.indexLocked()
+}
+
+// refresh rereads the users from the UserSource, if they may have changed.
+func (c *CredentialsStore) refresh() {
+	c.mu.RLock()
+	src, version := c.userSource, c.usersVersion
+	c.mu.RUnlock()
+	if src == nil {
+		return
+	}
+	v := src.UsersVersion()
+	if v == version {
+		return
+	}
+	creds, err := src.Users()
+	if err != nil {
+		logging.Warnf(c.logger, "failed to read users, keeping prior users: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userSource != src {
+		return
+	}
+	// The version is updated even on error, so that the error is not
+	// logged on every check. The users are read again on the next change.
+	c.usersVersion = v
+	if err == nil {
+		c.userCreds = creds
+		c.indexLocked()
+	}
+}
+
+// indexLocked indexes the credentials from the file and the UserSource. It
+// must be called with mu held for writing.
+func (c *CredentialsStore) indexLocked() {
+	idx := newCredentialIndex()
+	for _, cred := range c.fileCreds {
+		// Checked when loaded.
+		_ = idx.add(cred)
+	}
+	fileUsers := make(map[string]bool, len(c.fileCreds))
+	for _, cred := range c.fileCreds {
+		fileUsers[cred.Username] = true
+	}
+	for _, cred := range c.userCreds {
+		if fileUsers[cred.Username] {
+			continue
+		}
+		if err := idx.add(cred); err != nil {
+			c.logger.Printf("ignoring user %q: %s", cred.Username, err)
+		}
+	}
+	c.store, c.perms, c.acls, c.certs, c.limits = idx.store, idx.perms, idx.acls, idx.certs, idx.limits
+}
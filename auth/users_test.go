@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type mockUserSource struct {
+	version uint64
+	users   []Credential
+	err     error
+	reads   int
+}
+
+func (m *mockUserSource) UsersVersion() uint64 {
+	return m.version
+}
+
+func (m *mockUserSource) Users() ([]Credential, error) {
+	m.reads++
+	return m.users, m.err
+}
+
+func Test_AuthUserSource(t *testing.T) {
+	store := NewCredentialsStore()
+	if err := store.Load(strings.NewReader(`[
+		{"username": "admin", "password": "pw", "perms": ["all"]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	src := &mockUserSource{}
+	store.SetUserSource(src)
+
+	if store.Check("alice", "secret") {
+		t.Fatalf("unknown user authenticated")
+	}
+	if src.reads != 0 {
+		t.Fatalf("users read before version changed")
+	}
+
+	src.version, src.users = 1, []Credential{
+		{Username: "alice", Password: mustHashPassword(t, "secret", HashBcrypt), Perms: []string{PermQuery}},
+		{Username: "admin", Password: "other", Perms: []string{PermQuery}},
+	}
+	if !store.AA("alice", "secret", PermQuery) {
+		t.Fatalf("user from source not authorized")
+	}
+	if store.AA("alice", "secret", PermExecute) {
+		t.Fatalf("user from source granted execute perm")
+	}
+	if !store.AA("admin", "pw", PermExecute) || store.Check("admin", "other") {
+		t.Fatalf("user from file did not take precedence")
+	}
+	reads := src.reads
+	store.HasPerm("alice", PermQuery)
+	if src.reads != reads {
+		t.Fatalf("users reread without version change")
+	}
+
+	// Users are removed, and a failure to read keeps the prior users.
+	src.version, src.err = 2, errors.New("read failed")
+	if !store.Check("alice", "secret") {
+		t.Fatalf("prior users not kept after failure to read")
+	}
+	src.version, src.users, src.err = 3, nil, nil
+	if store.Check("alice", "secret") {
+		t.Fatalf("removed user authenticated")
+	}
+
+	// Invalid users are ignored.
+	src.version, src.users = 4, []Credential{
+		{Username: "bob", Password: "pw", ACL: []ACLRule{{Tables: []string{"t"}, Ops: []string{"drop"}}}},
+		{Username: "carol", Password: "pw"},
+	}
+	if store.Check("bob", "pw") || !store.Check("carol", "pw") {
+		t.Fatalf("invalid users not ignored")
+	}
+
+	// Reloading the file keeps the users from the source.
+	if err := store.Load(strings.NewReader(`[{"username": "root", "password": "pw"}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	if !store.Check("carol", "pw") || !store.Check("root", "pw") || store.Check("admin", "pw") {
+		t.Fatalf("wrong users after reloading file")
+	}
+}
+
+func Test_CredentialValidate(t *testing.T) {
+	if err := (Credential{Username: "a", ACL: []ACLRule{{Tables: []string{"t"}, Ops: []string{"select"}}}}).Validate(); err != nil {
+		t.Fatalf("valid credential failed validation: %s", err)
+	}
+	if err := (Credential{Username: "a", Certificates: []string{"x"}}).Validate(); err == nil {
+		t.Fatalf("invalid credential passed validation")
+	}
+}
@@ -2,8 +2,10 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/rqlite/rqlite/v10/auto"
 )
 
 var (
@@ -44,6 +48,7 @@ type S3Client struct {
 	// These fields are used for testing via dependency injection.
 	uploader   uploader
 	downloader downloader
+	lister     lister
 	now        func() time.Time
 }
 
@@ -93,6 +98,7 @@ func NewS3Client(endpoint, region, accessKey, secretKey, bucket, key string, opt
 		s3:         s3,
 		uploader:   manager.NewUploader(s3),
 		downloader: manager.NewDownloader(s3),
+		lister:     s3,
 	}
 	if opts != nil {
 		client.timestamp = opts.Timestamp
@@ -120,17 +126,26 @@ func (s *S3Client) EnsureBucket(ctx context.Context) error {
 	return nil
 }
 
-// Upload uploads data to S3.
-func (s *S3Client) Upload(ctx context.Context, reader io.Reader, id string) error {
-	key := s.key
-	if s.timestamp {
-		if s.now == nil {
-			s.now = func() time.Time {
-				return time.Now().UTC()
-			}
+// NextKey returns the key the next call to Upload will write to.
+func (s *S3Client) NextKey() string {
+	if !s.timestamp {
+		return s.key
+	}
+	if s.now == nil {
+		s.now = func() time.Time {
+			return time.Now().UTC()
 		}
-		key = TimestampedPath(key, s.now())
 	}
+	return TimestampedPath(s.key, s.now())
+}
+
+// Upload uploads data to S3.
+func (s *S3Client) Upload(ctx context.Context, reader io.Reader, id string) error {
+	return s.UploadKey(ctx, s.NextKey(), reader, id)
+}
+
+// UploadKey uploads data to the given key, in the configured bucket, in S3.
+func (s *S3Client) UploadKey(ctx context.Context, key string, reader io.Reader, id string) error {
 	input := &s3.PutObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
@@ -170,16 +185,54 @@ func (s *S3Client) CurrentID(ctx context.Context) (string, error) {
 
 // Download downloads data from S3.
 func (s *S3Client) Download(ctx context.Context, writer io.WriterAt) error {
+	return s.DownloadKey(ctx, s.key, writer)
+}
+
+// DownloadKey downloads the object with the given key, in the configured
+// bucket, from S3.
+func (s *S3Client) DownloadKey(ctx context.Context, key string, writer io.WriterAt) error {
 	_, err := s.downloader.Download(ctx, writer, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(s.key),
+		Key:    aws.String(key),
 	})
-	if err != nil {
-		return fmt.Errorf("failed to download from %v: %w", s, err)
+	var re interface{ HTTPStatusCode() int }
+	if errors.As(err, &re) && re.HTTPStatusCode() == http.StatusNotFound {
+		return fmt.Errorf("failed to download %s from %v: %w", key, s, auto.ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to download %s from %v: %w", key, s, err)
 	}
 	return nil
 }
 
+// ListTimestamped returns all timestamped versions of the configured key
+// present in the bucket.
+func (s *S3Client) ListTimestamped(ctx context.Context) ([]auto.TimestampedObject, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	}
+	if i := strings.LastIndex(s.key, "/"); i >= 0 {
+		input.Prefix = aws.String(s.key[:i+1])
+	}
+
+	var objs []auto.TimestampedObject
+	for {
+		out, err := s.lister.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects for %v: %w", s, err)
+		}
+		for _, o := range out.Contents {
+			key := aws.ToString(o.Key)
+			if t, ok := auto.ParseTimestampedPath(s.key, key); ok {
+				objs = append(objs, auto.TimestampedObject{Key: key, Time: t})
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			return objs, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
+
 // Delete deletes object from S3.
 func (s *S3Client) Delete(ctx context.Context) error {
 	_, err := s.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
@@ -196,7 +249,7 @@ func (s *S3Client) Delete(ctx context.Context) error {
 // If path contains /, the timestamp is prepended to the last segment.
 func TimestampedPath(path string, t time.Time) string {
 	parts := strings.Split(path, "/")
-	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format("20060102150405"), parts[len(parts)-1])
+	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format(auto.TimestampFormat), parts[len(parts)-1])
 	return strings.Join(parts, "/")
 }
 
@@ -215,3 +268,7 @@ type uploader interface {
 type downloader interface {
 	Download(ctx context.Context, w io.WriterAt, input *s3.GetObjectInput, opts ...func(*manager.Downloader)) (n int64, err error)
 }
+
+type lister interface {
+	ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
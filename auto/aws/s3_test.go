@@ -480,6 +480,67 @@ func Test_MetadataSet(t *testing.T) {
 	}
 }
 
+func Test_S3ClientListTimestamped(t *testing.T) {
+	bucket := "your-bucket"
+	key := "backups/db.sqlite"
+
+	pages := []*s3.ListObjectsV2Output{
+		{
+			Contents: []types.Object{
+				{Key: aws.String("backups/20260101100000_db.sqlite")},
+				{Key: aws.String("backups/db.sqlite")},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("next"),
+		},
+		{
+			Contents: []types.Object{
+				{Key: aws.String("backups/20260101110000_db.sqlite")},
+				{Key: aws.String("backups/20260101110000_other.sqlite")},
+			},
+		},
+	}
+	calls := 0
+	mockLister := &mockLister{
+		listFn: func(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if *input.Bucket != bucket {
+				t.Errorf("expected bucket to be %q, got %q", bucket, *input.Bucket)
+			}
+			if *input.Prefix != "backups/" {
+				t.Errorf("expected prefix to be %q, got %q", "backups/", *input.Prefix)
+			}
+			if calls == 1 && aws.ToString(input.ContinuationToken) != "next" {
+				t.Errorf("expected continuation token to be set on second call")
+			}
+			out := pages[calls]
+			calls++
+			return out, nil
+		},
+	}
+
+	client := &S3Client{
+		bucket: bucket,
+		key:    key,
+		lister: mockLister,
+	}
+	objs, err := client.ListTimestamped(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 list calls, got %d", calls)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(objs), objs)
+	}
+	if objs[0].Key != "backups/20260101100000_db.sqlite" || !objs[0].Time.Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first object: %v", objs[0])
+	}
+	if objs[1].Key != "backups/20260101110000_db.sqlite" || !objs[1].Time.Equal(time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected second object: %v", objs[1])
+	}
+}
+
 func Test_TimestampedPath(t *testing.T) {
 	ts, err := time.Parse(time.RFC3339, "2021-07-01T15:04:05Z")
 	if err != nil {
@@ -520,6 +581,17 @@ func (m *mockDownloader) Download(ctx context.Context, w io.WriterAt, input *s3.
 	return 0, nil
 }
 
+type mockLister struct {
+	listFn func(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+func (m *mockLister) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, input, opts...)
+	}
+	return &s3.ListObjectsV2Output{}, nil
+}
+
 type mockUploader struct {
 	uploadFn func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error)
 }
@@ -0,0 +1,420 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/auto"
+)
+
+var (
+	defaultDownloadBufferSz = 32 * 1024        // 32 KiB
+	defaultBlockSize        = 16 * 1024 * 1024 // 16 MiB
+	apiVersion              = "2021-08-06"
+
+	// AzureBlobIDKey is the metadata key under which the upload ID is stored.
+	// Azure metadata names must be valid C# identifiers, so no dashes.
+	AzureBlobIDKey = "rqliteautobackupid"
+)
+
+// ErrNoCredentials is returned when neither an account key nor a SAS token
+// is configured.
+var ErrNoCredentials = errors.New("either account_key or sas_token must be set")
+
+// TimestampedPath returns a new path with the given timestamp prepended.
+// If path contains /, the timestamp is prepended to the last segment.
+func TimestampedPath(path string, t time.Time) string {
+	parts := strings.Split(path, "/")
+	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format(auto.TimestampFormat), parts[len(parts)-1])
+	return strings.Join(parts, "/")
+}
+
+// BlobConfig is the subconfig for the Azure Blob storage type.
+type BlobConfig struct {
+	// Endpoint is the Blob service endpoint. If not set, it defaults to
+	// https://<account_name>.blob.core.windows.net.
+	Endpoint    string `json:"endpoint,omitempty"`
+	AccountName string `json:"account_name"`
+	AccountKey  string `json:"account_key,omitempty"`
+	SASToken    string `json:"sas_token,omitempty"`
+	Container   string `json:"container"`
+	Name        string `json:"name"`
+}
+
+// BlobClient is a client for uploading data to Azure Blob Storage.
+type BlobClient struct {
+	cfg *BlobConfig
+	key []byte
+
+	http         *http.Client
+	containerURL string
+
+	timestamp bool
+	blockSize int
+	now       func() time.Time
+}
+
+// BlobClientOpts are options for creating a BlobClient.
+type BlobClientOpts struct {
+	Timestamp bool
+}
+
+// NewBlobClient returns an instance of a BlobClient. opts can be nil.
+func NewBlobClient(cfg *BlobConfig, opts *BlobClientOpts) (*BlobClient, error) {
+	if cfg.AccountKey == "" && cfg.SASToken == "" {
+		return nil, ErrNoCredentials
+	}
+	var key []byte
+	if cfg.AccountKey != "" {
+		var err error
+		key, err = base64.StdEncoding.DecodeString(cfg.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account key: %w", err)
+		}
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.AccountName)
+	}
+	cfg.SASToken = strings.TrimPrefix(cfg.SASToken, "?")
+	base := strings.TrimRight(cfg.Endpoint, "/")
+
+	return &BlobClient{
+		cfg:          cfg,
+		key:          key,
+		http:         &http.Client{},
+		containerURL: fmt.Sprintf("%s/%s", base, url.PathEscape(cfg.Container)),
+		timestamp:    opts != nil && opts.Timestamp,
+		blockSize:    defaultBlockSize,
+	}, nil
+}
+
+// String returns a string representation of the BlobClient.
+func (b *BlobClient) String() string {
+	return fmt.Sprintf("azure://%s/%s/%s", b.cfg.AccountName, b.cfg.Container, b.cfg.Name)
+}
+
+// EnsureContainer ensures the container actually exists in Azure.
+func (b *BlobClient) EnsureContainer(ctx context.Context) error {
+	res, err := b.do(ctx, http.MethodPut, b.containerURL, url.Values{"restype": {"container"}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusCreated, http.StatusConflict:
+		return nil
+	default:
+		return responseError("container creation", res)
+	}
+}
+
+// NextKey returns the blob name the next call to Upload will write to.
+func (b *BlobClient) NextKey() string {
+	if !b.timestamp {
+		return b.cfg.Name
+	}
+	if b.now == nil {
+		b.now = func() time.Time {
+			return time.Now().UTC()
+		}
+	}
+	return TimestampedPath(b.cfg.Name, b.now())
+}
+
+// Upload uploads data to Azure.
+func (b *BlobClient) Upload(ctx context.Context, r io.Reader, id string) error {
+	return b.UploadKey(ctx, b.NextKey(), r, id)
+}
+
+// UploadKey uploads data to the blob with the given name, in the configured
+// container. Data larger than the block size is uploaded as a series of
+// blocks, which are then committed as a single block blob.
+func (b *BlobClient) UploadKey(ctx context.Context, name string, r io.Reader, id string) error {
+	hdr := http.Header{}
+	if id != "" {
+		hdr.Set("x-ms-meta-"+AzureBlobIDKey, id)
+	}
+
+	buf := make([]byte, b.blockSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// All the data fits in a single request.
+		hdr.Set("x-ms-blob-type", "BlockBlob")
+		return b.put(ctx, b.blobURL(name), nil, hdr, buf[:n], "upload")
+	} else if err != nil {
+		return err
+	}
+
+	var blockIDs []string
+	for n > 0 {
+		blockID := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%010d", len(blockIDs)))
+		q := url.Values{"comp": {"block"}, "blockid": {blockID}}
+		if err := b.put(ctx, b.blobURL(name), q, nil, buf[:n], "block upload"); err != nil {
+			return err
+		}
+		blockIDs = append(blockIDs, blockID)
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+
+	var list bytes.Buffer
+	list.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range blockIDs {
+		fmt.Fprintf(&list, "<Latest>%s</Latest>", id)
+	}
+	list.WriteString(`</BlockList>`)
+	hdr.Set("Content-Type", "application/xml")
+	return b.put(ctx, b.blobURL(name), url.Values{"comp": {"blocklist"}}, hdr, list.Bytes(), "block list commit")
+}
+
+// CurrentID returns the last ID uploaded to Azure.
+func (b *BlobClient) CurrentID(ctx context.Context) (string, error) {
+	res, err := b.do(ctx, http.MethodHead, b.blobURL(b.cfg.Name), nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata fetch failed: %s", res.Status)
+	}
+	id := res.Header.Get("x-ms-meta-" + AzureBlobIDKey)
+	if id == "" {
+		return "", fmt.Errorf("ID key (%s) not found in metadata %v", AzureBlobIDKey, b)
+	}
+	return id, nil
+}
+
+// Download downloads data from Azure.
+func (b *BlobClient) Download(ctx context.Context, w io.WriterAt) error {
+	return b.DownloadKey(ctx, b.cfg.Name, w)
+}
+
+// DownloadKey downloads the blob with the given name, in the configured
+// container, from Azure.
+func (b *BlobClient) DownloadKey(ctx context.Context, name string, w io.WriterAt) error {
+	res, err := b.do(ctx, http.MethodGet, b.blobURL(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("download of %s failed: %w", name, auto.ErrNotFound)
+	} else if res.StatusCode != http.StatusOK {
+		return responseError("download", res)
+	}
+
+	buf := make([]byte, defaultDownloadBufferSz)
+	var off int64
+	for {
+		n, err := res.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.WriteAt(buf[:n], off); werr != nil {
+				return werr
+			}
+			off += int64(n)
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTimestamped returns all timestamped versions of the configured blob
+// present in the container.
+func (b *BlobClient) ListTimestamped(ctx context.Context) ([]auto.TimestampedObject, error) {
+	q := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if i := strings.LastIndex(b.cfg.Name, "/"); i >= 0 {
+		q.Set("prefix", b.cfg.Name[:i+1])
+	}
+
+	var objs []auto.TimestampedObject
+	for {
+		res, err := b.do(ctx, http.MethodGet, b.containerURL, q, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = func() error {
+			defer res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				return responseError("list", res)
+			}
+			return xml.NewDecoder(res.Body).Decode(&list)
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob := range list.Blobs {
+			if t, ok := auto.ParseTimestampedPath(b.cfg.Name, blob.Name); ok {
+				objs = append(objs, auto.TimestampedObject{Key: blob.Name, Time: t})
+			}
+		}
+		if list.NextMarker == "" {
+			return objs, nil
+		}
+		q.Set("marker", list.NextMarker)
+	}
+}
+
+// Delete deletes the configured blob from Azure.
+func (b *BlobClient) Delete(ctx context.Context) error {
+	res, err := b.do(ctx, http.MethodDelete, b.blobURL(b.cfg.Name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusAccepted, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return responseError("delete", res)
+	}
+}
+
+func (b *BlobClient) blobURL(name string) string {
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return b.containerURL + "/" + strings.Join(parts, "/")
+}
+
+func (b *BlobClient) put(ctx context.Context, u string, q url.Values, hdr http.Header, body []byte, op string) error {
+	res, err := b.do(ctx, http.MethodPut, u, q, hdr, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return responseError(op, res)
+	}
+	return nil
+}
+
+// do performs an authenticated request against the Blob service.
+func (b *BlobClient) do(ctx context.Context, method, u string, q url.Values, hdr http.Header, body []byte) (*http.Response, error) {
+	query := q.Encode()
+	if b.key == nil && b.cfg.SASToken != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += b.cfg.SASToken
+	}
+	if query != "" {
+		u += "?" + query
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", apiVersion)
+	if b.key != nil {
+		req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", b.cfg.AccountName,
+			sign(b.key, stringToSign(req, b.cfg.AccountName))))
+	}
+	return b.http.Do(req)
+}
+
+// stringToSign returns the string which must be signed to authorize req
+// using Shared Key authorization.
+// See https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key.
+func stringToSign(req *http.Request, account string) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var sb strings.Builder
+	for _, v := range []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead.
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	} {
+		sb.WriteString(v)
+		sb.WriteByte('\n')
+	}
+
+	// Canonicalized headers.
+	var msHeaders []string
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			msHeaders = append(msHeaders, lk)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, k := range msHeaders {
+		fmt.Fprintf(&sb, "%s:%s\n", k, strings.TrimSpace(req.Header.Get(k)))
+	}
+
+	// Canonicalized resource.
+	fmt.Fprintf(&sb, "/%s%s", account, req.URL.EscapedPath())
+	q := req.URL.Query()
+	params := make([]string, 0, len(q))
+	for k := range q {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		fmt.Fprintf(&sb, "\n%s:%s", strings.ToLower(k), strings.Join(vals, ","))
+	}
+	return sb.String()
+}
+
+func sign(key []byte, s string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func responseError(op string, res *http.Response) error {
+	b, _ := io.ReadAll(res.Body)
+	return fmt.Errorf("%s failed: %s: %s", op, res.Status, b)
+}
@@ -0,0 +1,425 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testAccountKey = base64.StdEncoding.EncodeToString([]byte("test-account-key"))
+
+func Test_NewBlobClient(t *testing.T) {
+	cli, err := NewBlobClient(&BlobConfig{
+		AccountName: "acct",
+		AccountKey:  testAccountKey,
+		Container:   "mycontainer",
+		Name:        "backups/db.sqlite",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewBlobClient: %v", err)
+	}
+	if exp, got := "https://acct.blob.core.windows.net/mycontainer", cli.containerURL; exp != got {
+		t.Errorf("containerURL = %s, want %s", got, exp)
+	}
+	if exp, got := "azure://acct/mycontainer/backups/db.sqlite", cli.String(); exp != got {
+		t.Errorf("String() = %s, want %s", got, exp)
+	}
+}
+
+func Test_NewBlobClient_Credentials(t *testing.T) {
+	if _, err := NewBlobClient(&BlobConfig{AccountName: "acct"}, nil); err != ErrNoCredentials {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+	if _, err := NewBlobClient(&BlobConfig{AccountName: "acct", AccountKey: "not base64!"}, nil); err == nil {
+		t.Fatalf("expected error for invalid account key")
+	}
+	if _, err := NewBlobClient(&BlobConfig{AccountName: "acct", SASToken: "?sv=x&sig=y"}, nil); err != nil {
+		t.Fatalf("unexpected error for SAS token: %v", err)
+	}
+}
+
+func Test_StringToSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut,
+		"https://acct.blob.core.windows.net/mycontainer/db.sqlite?comp=block&blockid=QUJD", strings.NewReader("hello"))
+	req.ContentLength = 5
+	req.Header.Set("x-ms-date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	req.Header.Set("x-ms-version", apiVersion)
+	req.Header.Set("x-ms-meta-"+AzureBlobIDKey, "42")
+
+	exp := "PUT\n\n\n5\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT\n" +
+		"x-ms-meta-rqliteautobackupid:42\n" +
+		"x-ms-version:" + apiVersion + "\n" +
+		"/acct/mycontainer/db.sqlite\nblockid:QUJD\ncomp:block"
+	if got := stringToSign(req, "acct"); got != exp {
+		t.Fatalf("unexpected string to sign\nexp: %q\ngot: %q", exp, got)
+	}
+}
+
+func Test_EnsureContainer(t *testing.T) {
+	fs := newFakeServer(t)
+	cli, shutdown := newTestClient(t, fs, nil)
+	defer shutdown()
+
+	if err := cli.EnsureContainer(context.Background()); err != nil {
+		t.Fatalf("EnsureContainer: %v", err)
+	}
+	// Second call finds the container already exists.
+	if err := cli.EnsureContainer(context.Background()); err != nil {
+		t.Fatalf("EnsureContainer: %v", err)
+	}
+}
+
+func Test_UploadDownload(t *testing.T) {
+	fs := newFakeServer(t)
+	cli, shutdown := newTestClient(t, fs, nil)
+	defer shutdown()
+
+	if err := cli.Upload(context.Background(), strings.NewReader("hello"), "v123"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if fs.numBlockUploads != 0 {
+		t.Fatalf("small upload should not use blocks")
+	}
+
+	id, err := cli.CurrentID(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentID: %v", err)
+	}
+	if id != "v123" {
+		t.Fatalf("id = %s, want v123", id)
+	}
+
+	var buf writerAtBuffer
+	if err := cli.Download(context.Background(), &buf); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if buf.String() != "hello" {
+		t.Fatalf("got %q, want %q", buf.String(), "hello")
+	}
+}
+
+func Test_UploadChunked(t *testing.T) {
+	fs := newFakeServer(t)
+	cli, shutdown := newTestClient(t, fs, nil)
+	defer shutdown()
+	cli.blockSize = 4
+
+	data := "0123456789abcdef01"
+	if err := cli.Upload(context.Background(), strings.NewReader(data), "v1"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if exp, got := 5, fs.numBlockUploads; exp != got {
+		t.Fatalf("expected %d block uploads, got %d", exp, got)
+	}
+
+	var buf writerAtBuffer
+	if err := cli.Download(context.Background(), &buf); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if buf.String() != data {
+		t.Fatalf("got %q, want %q", buf.String(), data)
+	}
+	id, err := cli.CurrentID(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentID: %v", err)
+	}
+	if id != "v1" {
+		t.Fatalf("id = %s, want v1", id)
+	}
+}
+
+func Test_UploadSAS(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.sas = "sv=2021-08-06&sig=abc"
+	cli, shutdown := newTestClient(t, fs, func(cfg *BlobConfig) {
+		cfg.AccountKey = ""
+		cfg.SASToken = "?" + fs.sas
+	})
+	defer shutdown()
+
+	if err := cli.Upload(context.Background(), strings.NewReader("hello"), "v1"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	var buf writerAtBuffer
+	if err := cli.Download(context.Background(), &buf); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if buf.String() != "hello" {
+		t.Fatalf("got %q, want %q", buf.String(), "hello")
+	}
+}
+
+func Test_UploadTimestamped_ListTimestamped(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.pageSize = 1
+	cli, shutdown := newTestClient(t, fs, func(cfg *BlobConfig) {
+		cfg.Name = "backups/db.sqlite"
+	})
+	defer shutdown()
+	cli.timestamp = true
+
+	t1 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	for _, ts := range []time.Time{t1, t2} {
+		cli.now = func() time.Time { return ts }
+		if err := cli.Upload(context.Background(), strings.NewReader(ts.String()), "id"); err != nil {
+			t.Fatalf("Upload: %v", err)
+		}
+	}
+	fs.blobs["backups/db.sqlite"] = &fakeBlob{data: []byte("x")}
+	fs.blobs["backups/20260101100000_other.sqlite"] = &fakeBlob{data: []byte("x")}
+	fs.blobs["20260101100000_db.sqlite"] = &fakeBlob{data: []byte("x")}
+
+	objs, err := cli.ListTimestamped(context.Background())
+	if err != nil {
+		t.Fatalf("ListTimestamped: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects, want 2: %v", len(objs), objs)
+	}
+	if objs[0].Key != "backups/20260101100000_db.sqlite" || !objs[0].Time.Equal(t1) {
+		t.Fatalf("unexpected first object: %v", objs[0])
+	}
+	if objs[1].Key != "backups/20260101110000_db.sqlite" || !objs[1].Time.Equal(t2) {
+		t.Fatalf("unexpected second object: %v", objs[1])
+	}
+
+	var buf writerAtBuffer
+	if err := cli.DownloadKey(context.Background(), objs[0].Key, &buf); err != nil {
+		t.Fatalf("DownloadKey: %v", err)
+	}
+	if buf.String() != t1.String() {
+		t.Fatalf("got %q, want %q", buf.String(), t1.String())
+	}
+}
+
+func Test_Delete(t *testing.T) {
+	fs := newFakeServer(t)
+	cli, shutdown := newTestClient(t, fs, nil)
+	defer shutdown()
+
+	if err := cli.Upload(context.Background(), strings.NewReader("hello"), "v1"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if err := cli.Delete(context.Background()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fs.blobs["db.sqlite"]; ok {
+		t.Fatalf("blob not deleted")
+	}
+	if err := cli.Download(context.Background(), &writerAtBuffer{}); err == nil {
+		t.Fatalf("expected error downloading deleted blob")
+	}
+}
+
+type writerAtBuffer struct {
+	buf bytes.Buffer
+}
+
+func (w *writerAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if off != int64(w.buf.Len()) {
+		return 0, fmt.Errorf("unexpected offset %d", off)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *writerAtBuffer) String() string { return w.buf.String() }
+
+// newTestClient spins up a fake Blob service, and returns a client configured
+// to use it. mod, if non-nil, may modify the client config.
+func newTestClient(t *testing.T, fs *fakeServer, mod func(cfg *BlobConfig)) (*BlobClient, func()) {
+	t.Helper()
+	ts := httptest.NewServer(fs)
+	cfg := &BlobConfig{
+		Endpoint:    ts.URL + "/acct",
+		AccountName: "acct",
+		AccountKey:  testAccountKey,
+		Container:   "mycontainer",
+		Name:        "db.sqlite",
+	}
+	if mod != nil {
+		mod(cfg)
+	}
+	cli, err := NewBlobClient(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewBlobClient: %v", err)
+	}
+	cli.http = ts.Client()
+	return cli, ts.Close
+}
+
+type fakeBlob struct {
+	data []byte
+	meta http.Header
+}
+
+// fakeServer is a minimal in-memory implementation of the Azure Blob
+// service, supporting a single account and container.
+type fakeServer struct {
+	t        *testing.T
+	sas      string
+	pageSize int
+
+	mu              sync.Mutex
+	container       bool
+	blobs           map[string]*fakeBlob
+	blocks          map[string][]byte
+	numBlockUploads int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	return &fakeServer{
+		t:      t,
+		blobs:  make(map[string]*fakeBlob),
+		blocks: make(map[string][]byte),
+	}
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.authorized(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("x-ms-version") == "" {
+		f.t.Errorf("x-ms-version header missing")
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/acct/mycontainer")
+	name := strings.TrimPrefix(path, "/")
+	q := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case name == "" && q.Get("restype") == "container" && r.Method == http.MethodPut:
+		if f.container {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.container = true
+		w.WriteHeader(http.StatusCreated)
+	case name == "" && q.Get("comp") == "list" && r.Method == http.MethodGet:
+		f.list(w, q)
+	case q.Get("comp") == "block" && r.Method == http.MethodPut:
+		f.numBlockUploads++
+		f.blocks[name+"/"+q.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case q.Get("comp") == "blocklist" && r.Method == http.MethodPut:
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var data []byte
+		for _, id := range list.Latest {
+			b, ok := f.blocks[name+"/"+id]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data = append(data, b...)
+		}
+		f.blobs[name] = &fakeBlob{data: data, meta: metaHeaders(r)}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[name] = &fakeBlob{data: body, meta: metaHeaders(r)}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		blob, ok := f.blobs[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range blob.meta {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(blob.data)
+		}
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeServer) authorized(r *http.Request) bool {
+	if f.sas != "" {
+		sas, _ := url.ParseQuery(f.sas)
+		for k := range sas {
+			if r.URL.Query().Get(k) != sas.Get(k) {
+				return false
+			}
+		}
+		return true
+	}
+	key, _ := base64.StdEncoding.DecodeString(testAccountKey)
+	exp := "SharedKey acct:" + sign(key, stringToSign(r, "acct"))
+	return r.Header.Get("Authorization") == exp
+}
+
+func (f *fakeServer) list(w http.ResponseWriter, q url.Values) {
+	var names []string
+	for n := range f.blobs {
+		if strings.HasPrefix(n, q.Get("prefix")) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	start := 0
+	if m := q.Get("marker"); m != "" {
+		start = sort.SearchStrings(names, m)
+	}
+	end := len(names)
+	next := ""
+	if f.pageSize > 0 && start+f.pageSize < end {
+		end = start + f.pageSize
+		next = names[end]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+	for _, n := range names[start:end] {
+		fmt.Fprintf(&sb, "<Blob><Name>%s</Name></Blob>", n)
+	}
+	fmt.Fprintf(&sb, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", next)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(sb.String()))
+}
+
+func metaHeaders(r *http.Request) http.Header {
+	h := http.Header{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") {
+			h[k] = v
+		}
+	}
+	return h
+}
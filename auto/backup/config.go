@@ -7,19 +7,24 @@ import (
 
 	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/aws"
+	"github.com/rqlite/rqlite/v10/auto/azure"
 	"github.com/rqlite/rqlite/v10/auto/file"
 	"github.com/rqlite/rqlite/v10/auto/gcp"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 // Config is the config file format for the upload service
 type Config struct {
-	Version    int              `json:"version"`
-	Type       auto.StorageType `json:"type"`
-	NoCompress bool             `json:"no_compress,omitempty"`
-	Timestamp  bool             `json:"timestamp"`
-	Vacuum     bool             `json:"vacuum,omitempty"`
-	Interval   auto.Duration    `json:"interval"`
-	Sub        json.RawMessage  `json:"sub"`
+	Version     int              `json:"version"`
+	Type        auto.StorageType `json:"type"`
+	NoCompress  bool             `json:"no_compress,omitempty"`
+	Compression string           `json:"compression,omitempty"`
+	Timestamp   bool             `json:"timestamp"`
+	Vacuum      bool             `json:"vacuum,omitempty"`
+	Manifest    bool             `json:"manifest,omitempty"`
+	Verify      bool             `json:"verify,omitempty"`
+	Interval    auto.Duration    `json:"interval"`
+	Sub         json.RawMessage  `json:"sub"`
 }
 
 // NewStorageClient unmarshals the config data and returns the Config and StorageClient.
@@ -34,6 +39,12 @@ func NewStorageClient(data []byte) (*Config, StorageClient, error) {
 		return nil, nil, auto.ErrInvalidVersion
 	}
 
+	switch cfg.Compression {
+	case "", rarchive.CompressionNone, rarchive.CompressionGzip, rarchive.CompressionZstd:
+	default:
+		return nil, nil, auto.ErrInvalidCompression
+	}
+
 	var sc StorageClient
 	switch cfg.Type {
 	case auto.StorageTypeS3:
@@ -68,6 +79,16 @@ func NewStorageClient(data []byte) (*Config, StorageClient, error) {
 			Timestamp: cfg.Timestamp,
 		}
 		sc, err = file.NewClient(fileCfg.Dir, fileCfg.Name, opts)
+	case auto.StorageTypeAzure:
+		azureCfg := &azure.BlobConfig{}
+		err = json.Unmarshal(cfg.Sub, azureCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts := &azure.BlobClientOpts{
+			Timestamp: cfg.Timestamp,
+		}
+		sc, err = azure.NewBlobClient(azureCfg, opts)
 	default:
 		return nil, nil, auto.ErrUnsupportedStorageType
 	}
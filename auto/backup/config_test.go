@@ -11,6 +11,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/aws"
+	"github.com/rqlite/rqlite/v10/auto/azure"
 	"github.com/rqlite/rqlite/v10/auto/file"
 	"github.com/rqlite/rqlite/v10/auto/gcp"
 )
@@ -227,6 +228,94 @@ func Test_NewStorageClient(t *testing.T) {
 			expectedClient: mustNewFileClient(t, tempDir, "backup.sqlite"),
 			expectedErr:    nil,
 		},
+		{
+			name: "ValidAzureConfig",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "azure",
+				"timestamp": true,
+				"interval": "1h",
+				"sub": {
+					"account_name": "acct",
+					"account_key": "dGVzdA==",
+					"container": "backups",
+					"name": "backup.sqlite"
+				}
+			}`),
+			expectedCfg: &Config{
+				Version:   1,
+				Type:      "azure",
+				Timestamp: true,
+				Interval:  1 * auto.Duration(time.Hour),
+			},
+			expectedClient: mustNewBlobClient(t, "acct", "dGVzdA==", "backups", "backup.sqlite"),
+			expectedErr:    nil,
+		},
+		{
+			name: "ValidFileConfigManifestVerify",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "file",
+				"timestamp": true,
+				"manifest": true,
+				"verify": true,
+				"interval": "1h",
+				"sub": {
+					"dir": "` + tempDir + `",
+					"name": "backup.sqlite"
+				}
+			}`),
+			expectedCfg: &Config{
+				Version:   1,
+				Type:      "file",
+				Timestamp: true,
+				Manifest:  true,
+				Verify:    true,
+				Interval:  1 * auto.Duration(time.Hour),
+			},
+			expectedClient: mustNewFileClient(t, tempDir, "backup.sqlite"),
+			expectedErr:    nil,
+		},
+		{
+			name: "ValidFileConfigZstd",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "file",
+				"compression": "zstd",
+				"interval": "1h",
+				"sub": {
+					"dir": "` + tempDir + `",
+					"name": "backup.sqlite"
+				}
+			}`),
+			expectedCfg: &Config{
+				Version:     1,
+				Type:        "file",
+				Compression: "zstd",
+				Interval:    1 * auto.Duration(time.Hour),
+			},
+			expectedClient: mustNewFileClient(t, tempDir, "backup.sqlite"),
+			expectedErr:    nil,
+		},
+		{
+			name: "InvalidCompression",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "file",
+				"compression": "lz4",
+				"interval": "1h",
+				"sub": {
+					"dir": "` + tempDir + `",
+					"name": "backup.sqlite"
+				}
+			}`),
+			expectedCfg: nil,
+			expectedErr: auto.ErrInvalidCompression,
+		},
 		{
 			name: "ValidFileConfigTimestampFalse",
 			input: []byte(`
@@ -339,6 +428,11 @@ func Test_NewStorageClient(t *testing.T) {
 					if !ok {
 						t.Fatalf("Test case %s failed, expected GCSClient, got %T", tc.name, sc)
 					}
+				case *azure.BlobClient:
+					_, ok := sc.(*azure.BlobClient)
+					if !ok {
+						t.Fatalf("Test case %s failed, expected azure.BlobClient, got %T", tc.name, sc)
+					}
 				case *file.Client:
 					_, ok := sc.(*file.Client)
 					if !ok {
@@ -363,6 +457,9 @@ func compareConfig(a, b *Config) bool {
 	return a.Version == b.Version &&
 		a.Type == b.Type &&
 		a.NoCompress == b.NoCompress &&
+		a.Compression == b.Compression &&
+		a.Manifest == b.Manifest &&
+		a.Verify == b.Verify &&
 		a.Interval == b.Interval
 }
 
@@ -389,6 +486,20 @@ func mustNewGCSClient(t *testing.T, bucket, name, projectID, credentialsFile str
 	return client
 }
 
+func mustNewBlobClient(t *testing.T, account, key, container, name string) *azure.BlobClient {
+	t.Helper()
+	client, err := azure.NewBlobClient(&azure.BlobConfig{
+		AccountName: account,
+		AccountKey:  key,
+		Container:   container,
+		Name:        name,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create Azure Blob client: %v", err)
+	}
+	return client
+}
+
 func mustNewFileClient(t *testing.T, dir, filename string) *file.Client {
 	t.Helper()
 	client, err := file.NewClient(dir, filename, nil)
@@ -1,17 +1,27 @@
 package backup
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"expvar"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auto"
+	"github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/db/humanize"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/progress"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 // StorageClient is an interface for uploading data to a storage service.
@@ -29,6 +39,21 @@ type StorageClient interface {
 	fmt.Stringer
 }
 
+// KeyedStorageClient is an interface for uploading data to, and downloading
+// data from, explicit keys in a storage service. A StorageClient must also
+// implement KeyedStorageClient to support manifests and verification.
+type KeyedStorageClient interface {
+	// NextKey returns the key the next call to Upload would write to.
+	NextKey() string
+
+	// UploadKey uploads the data from the given reader to the given key.
+	// If id is empty, no identifier is stored with the data.
+	UploadKey(ctx context.Context, key string, reader io.Reader, id string) error
+
+	// DownloadKey downloads the data stored at the given key.
+	DownloadKey(ctx context.Context, key string, writer io.WriterAt) error
+}
+
 // DataProvider is an interface for providing data to be uploaded. The Uploader
 // service will call Provide() to have the data-for-upload to be written to the
 // to the file specified by path.
@@ -41,6 +66,23 @@ type DataProvider interface {
 	Provide(w io.WriteSeeker) error
 }
 
+// ManifestProvider is an optional interface a DataProvider may implement, to
+// supply details of the most recently provided data for backup manifests.
+type ManifestProvider interface {
+	// ManifestDetails sets the Term, NodeID, and SchemaHash fields of m, so
+	// they describe the data most recently written by Provide.
+	ManifestDetails(m *auto.Manifest) error
+}
+
+var (
+	// ErrUploadDisabled is returned when an upload is requested on a node
+	// where uploads are not currently enabled, for example a follower.
+	ErrUploadDisabled = errors.New("upload not enabled on this node")
+
+	// ErrInvalidName is returned when an object name or suffix is invalid.
+	ErrInvalidName = errors.New("invalid object name or suffix")
+)
+
 // stats captures stats for the Uploader service.
 var stats *expvar.Map
 
@@ -49,7 +91,11 @@ const (
 	numUploadsFail      = "num_uploads_fail"
 	numUploadsSkipped   = "num_uploads_skipped"
 	numUploadsSkippedID = "num_uploads_skipped_id"
+	numUploadsOnDemand  = "num_uploads_on_demand"
 	numSumGetFail       = "num_sum_get_fail"
+	numManifestsFail    = "num_manifests_fail"
+	numVerifyOK         = "num_verify_ok"
+	numVerifyFail       = "num_verify_fail"
 	totalUploadBytes    = "total_upload_bytes"
 	lastUploadBytes     = "last_upload_bytes"
 )
@@ -66,7 +112,11 @@ func ResetStats() {
 	stats.Add(numUploadsFail, 0)
 	stats.Add(numUploadsSkipped, 0)
 	stats.Add(numUploadsSkippedID, 0)
+	stats.Add(numUploadsOnDemand, 0)
 	stats.Add(numSumGetFail, 0)
+	stats.Add(numManifestsFail, 0)
+	stats.Add(numVerifyOK, 0)
+	stats.Add(numVerifyFail, 0)
 	stats.Add(totalUploadBytes, 0)
 	stats.Add(lastUploadBytes, 0)
 }
@@ -77,11 +127,48 @@ type Uploader struct {
 	dataProvider  DataProvider
 	interval      time.Duration
 
-	logger             *log.Logger
+	// Manifest enables uploading of a manifest alongside each upload. The
+	// StorageClient must implement KeyedStorageClient.
+	Manifest bool
+
+	// Verify enables re-downloading each upload, and checking its checksum
+	// and integrity. The StorageClient must implement KeyedStorageClient.
+	Verify bool
+
+	// Version is the version of rqlite recorded in manifests.
+	Version string
+
+	logger *log.Logger
+
+	isUploadEnabled func() bool
+	uploadMu        sync.Mutex // Serializes uploads.
+
+	// mu protects the results of the last upload, which are read by Stats.
+	mu                 sync.Mutex
 	lastUploadTime     time.Time
 	lastUploadDuration time.Duration
+	lastIndex          uint64 // The last index of the data most-recently uploaded.
+	lastKey            string
+	lastManifest       *auto.Manifest
+	lastVerify         *VerifyResult
+}
 
-	lastIndex uint64 // The last index of the data most-recently uploaded.
+// UploadResult is the result of an upload.
+type UploadResult struct {
+	Key      string         `json:"key"`
+	Index    uint64         `json:"index"`
+	SHA256   string         `json:"sha256"`
+	Size     int64          `json:"size"`
+	Manifest *auto.Manifest `json:"manifest,omitempty"`
+	Verify   *VerifyResult  `json:"verify,omitempty"`
+}
+
+// VerifyResult is the result of verifying an upload.
+type VerifyResult struct {
+	Key   string    `json:"key"`
+	Time  time.Time `json:"time"`
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
 }
 
 // NewUploader creates a new Uploader service.
@@ -90,7 +177,7 @@ func NewUploader(storageClient StorageClient, dataProvider DataProvider, interva
 		storageClient: storageClient,
 		dataProvider:  dataProvider,
 		interval:      interval,
-		logger:        log.New(os.Stderr, "[uploader] ", log.LstdFlags),
+		logger:        logging.New("uploader", os.Stderr),
 	}
 }
 
@@ -100,6 +187,7 @@ func (u *Uploader) Start(ctx context.Context, isUploadEnabled func() bool) chan
 	if isUploadEnabled == nil {
 		isUploadEnabled = func() bool { return true }
 	}
+	u.isUploadEnabled = isUploadEnabled
 
 	u.logger.Printf("starting upload to %s every %s", u.storageClient, u.interval)
 	ticker := time.NewTicker(u.interval)
@@ -116,7 +204,7 @@ func (u *Uploader) Start(ctx context.Context, isUploadEnabled func() bool) chan
 					continue
 				}
 				if err := u.upload(ctx); err != nil {
-					u.logger.Printf("failed to upload to %s: %v", u.storageClient, err)
+					logging.Errorf(u.logger, "failed to upload to %s: %v", u.storageClient, err)
 				}
 			}
 		}
@@ -126,6 +214,8 @@ func (u *Uploader) Start(ctx context.Context, isUploadEnabled func() bool) chan
 
 // Stats returns the stats for the Uploader service.
 func (u *Uploader) Stats() (map[string]any, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	status := map[string]any{
 		"upload_destination":   u.storageClient.String(),
 		"upload_interval":      u.interval.String(),
@@ -133,10 +223,22 @@ func (u *Uploader) Stats() (map[string]any, error) {
 		"last_upload_duration": u.lastUploadDuration.String(),
 		"last_index":           strconv.FormatUint(u.lastIndex, 10),
 	}
+	if u.lastKey != "" {
+		status["last_upload_key"] = u.lastKey
+	}
+	if u.lastManifest != nil {
+		status["last_manifest"] = u.lastManifest
+	}
+	if u.lastVerify != nil {
+		status["last_verify"] = u.lastVerify
+	}
 	return status, nil
 }
 
 func (u *Uploader) upload(ctx context.Context) error {
+	u.uploadMu.Lock()
+	defer u.uploadMu.Unlock()
+
 	var err error
 	var li uint64
 
@@ -144,7 +246,10 @@ func (u *Uploader) upload(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if li <= u.lastIndex {
+	u.mu.Lock()
+	lastIndex := u.lastIndex
+	u.mu.Unlock()
+	if li <= lastIndex {
 		stats.Add(numUploadsSkipped, 1)
 		return nil
 	}
@@ -161,41 +266,279 @@ func (u *Uploader) upload(ctx context.Context) error {
 		return err
 	}
 
-	if u.lastIndex == 0 {
+	if lastIndex == 0 {
 		// No last index, so this must be the first upload since this
 		// uploader started. Double-check that we really need to upload.
 		currID, err := u.storageClient.CurrentID(ctx)
 		if err != nil {
 			stats.Add(numSumGetFail, 1)
-			u.logger.Printf("failed to get current ID from %s: %v", u.storageClient, err)
+			logging.Errorf(u.logger, "failed to get current ID from %s: %v", u.storageClient, err)
 		} else if currID == strconv.FormatUint(li, 10) {
 			stats.Add(numUploadsSkippedID, 1)
 			return nil
 		}
 	}
 
-	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+	var ksc KeyedStorageClient
+	if u.Manifest || u.Verify {
+		var ok bool
+		if ksc, ok = u.storageClient.(KeyedStorageClient); !ok {
+			return fmt.Errorf("storage client %s does not support manifests or verification", u.storageClient)
+		}
+	}
+	key := ""
+	if ksc != nil {
+		key = ksc.NextKey()
+	}
+	if _, err := u.uploadData(ctx, ksc, key, li, fd, false); err != nil {
 		return err
 	}
-	cr := progress.NewCountingReader(fd)
+	u.mu.Lock()
+	u.lastIndex = li
+	u.mu.Unlock()
+	return nil
+}
+
+// UploadNow uploads the data to the storage service immediately, even if it
+// has not changed since the last upload. If name is non-empty it replaces the
+// last path segment of the key the upload would otherwise be written to, and
+// if suffix is non-empty it is appended to that key. The StorageClient must
+// implement KeyedStorageClient. The periodic upload schedule is unaffected.
+func (u *Uploader) UploadNow(ctx context.Context, name, suffix string) (*UploadResult, error) {
+	ksc, ok := u.storageClient.(KeyedStorageClient)
+	if !ok {
+		return nil, fmt.Errorf("storage client %s does not support on-demand uploads", u.storageClient)
+	}
+	if u.isUploadEnabled != nil && !u.isUploadEnabled() {
+		return nil, ErrUploadDisabled
+	}
+	key, err := OverrideKey(ksc.NextKey(), name, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	u.uploadMu.Lock()
+	defer u.uploadMu.Unlock()
+
+	li, err := u.dataProvider.LastIndex()
+	if err != nil {
+		return nil, err
+	}
+	fd, err := tempFD()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fd.Name())
+	defer fd.Close()
+
+	if err := u.dataProvider.Provide(fd); err != nil {
+		return nil, err
+	}
+	stats.Add(numUploadsOnDemand, 1)
+	return u.uploadData(ctx, ksc, key, li, fd, true)
+}
+
+// uploadData uploads the data in fd, which reflects index li, to the storage
+// service. If ksc is non-nil the data is uploaded to key, and a manifest is
+// uploaded and the upload verified, if so configured. On-demand uploads
+// always return a manifest, even if it is not uploaded.
+func (u *Uploader) uploadData(ctx context.Context, ksc KeyedStorageClient, key string,
+	li uint64, fd *os.File, onDemand bool) (*UploadResult, error) {
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var err error
+	h := sha256.New()
+	cr := progress.NewCountingReader(io.TeeReader(fd, h))
 	startTime := time.Now()
-	err = u.storageClient.Upload(ctx, cr, strconv.FormatUint(li, 10))
+	if ksc != nil {
+		err = ksc.UploadKey(ctx, key, cr, strconv.FormatUint(li, 10))
+	} else {
+		err = u.storageClient.Upload(ctx, cr, strconv.FormatUint(li, 10))
+	}
 	if err != nil {
 		stats.Add(numUploadsFail, 1)
-		return err
+		return nil, err
 	}
 
 	// Successful upload!
-	u.lastIndex = li
 	stats.Add(numUploadsOK, 1)
 	stats.Add(totalUploadBytes, cr.Count())
 	stats.Get(lastUploadBytes).(*expvar.Int).Set(cr.Count())
+	duration := time.Since(startTime)
+	u.mu.Lock()
 	u.lastUploadTime = time.Now()
-	u.lastUploadDuration = time.Since(startTime)
+	u.lastUploadDuration = duration
+	u.mu.Unlock()
 	u.logger.Printf("completed auto upload of %s to %s in %s",
 		humanize.Bytes(uint64(stats.Get(lastUploadBytes).(*expvar.Int).Value())),
-		u.storageClient, u.lastUploadDuration)
-	return nil
+		u.storageClient, duration)
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	var manifest, uploaded *auto.Manifest
+	if u.Manifest || onDemand {
+		manifest, err = u.buildManifest(key, li, sum, cr.Count(), fd)
+		if err == nil && u.Manifest {
+			if err = uploadManifest(ctx, ksc, manifest); err == nil {
+				uploaded = manifest
+			}
+		}
+		if err != nil {
+			stats.Add(numManifestsFail, 1)
+			logging.Errorf(u.logger, "failed to upload manifest for %s: %v", key, err)
+		}
+	}
+
+	var vr *VerifyResult
+	if u.Verify {
+		vr = &VerifyResult{Key: key, Time: time.Now()}
+		if err := verify(ctx, ksc, key, sum); err != nil {
+			stats.Add(numVerifyFail, 1)
+			vr.Error = err.Error()
+			u.logger.Printf("verification of %s failed: %v", key, err)
+		} else {
+			stats.Add(numVerifyOK, 1)
+			vr.OK = true
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lastKey = key
+	u.lastManifest = uploaded
+	if vr != nil {
+		u.lastVerify = vr
+	}
+	return &UploadResult{
+		Key:      key,
+		Index:    li,
+		SHA256:   sum,
+		Size:     cr.Count(),
+		Manifest: manifest,
+		Verify:   vr,
+	}, nil
+}
+
+// OverrideKey returns key with its last path segment replaced by name, if
+// name is non-empty, and with suffix appended, if suffix is non-empty.
+func OverrideKey(key, name, suffix string) (string, error) {
+	if strings.Contains(name, "/") || name == "." || name == ".." {
+		return "", ErrInvalidName
+	}
+	if strings.Contains(suffix, "/") {
+		return "", ErrInvalidName
+	}
+	if name != "" {
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			key = key[:i+1] + name
+		} else {
+			key = name
+		}
+	}
+	return key + suffix, nil
+}
+
+// buildManifest builds the manifest for the data in fd, which was uploaded to
+// key.
+func (u *Uploader) buildManifest(key string, li uint64, sum string, size int64,
+	fd io.ReadSeeker) (*auto.Manifest, error) {
+	compression, err := detectCompression(fd)
+	if err != nil {
+		return nil, err
+	}
+	m := &auto.Manifest{
+		Key:         key,
+		Index:       li,
+		Version:     u.Version,
+		SHA256:      sum,
+		Compression: compression,
+		Size:        size,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if mp, ok := u.dataProvider.(ManifestProvider); ok {
+		if err := mp.ManifestDetails(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// uploadManifest uploads m alongside the data it describes.
+func uploadManifest(ctx context.Context, ksc KeyedStorageClient, m *auto.Manifest) error {
+	b, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return ksc.UploadKey(ctx, auto.ManifestKey(m.Key), bytes.NewReader(b), "")
+}
+
+// verify downloads the data at key, and checks that its SHA-256 checksum is
+// sum, and that it is a valid SQLite database.
+func verify(ctx context.Context, ksc KeyedStorageClient, key, sum string) error {
+	fd, err := tempFD()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fd.Name())
+	defer fd.Close()
+
+	if err := ksc.DownloadKey(ctx, key, fd); err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, fd); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", sum, got)
+	}
+
+	compression, err := detectCompression(fd)
+	if err != nil {
+		return err
+	}
+	dbPath := fd.Name()
+	if compression != rarchive.CompressionNone {
+		dbFD, err := tempFD()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(dbFD.Name())
+		defer dbFD.Close()
+
+		rc, _, err := rarchive.NewDecompressReader(fd)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if _, err := io.Copy(dbFD, rc); err != nil {
+			return fmt.Errorf("failed to decompress data: %w", err)
+		}
+		if err := dbFD.Close(); err != nil {
+			return err
+		}
+		dbPath = dbFD.Name()
+	}
+	return db.VerifyFileIntegrity(dbPath)
+}
+
+// detectCompression returns the compression algorithm used for the data in
+// the reader. When this function returns f will be positioned at the start
+// of the reader.
+func detectCompression(f io.ReadSeeker) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	b := make([]byte, 4)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return rarchive.DetectCompression(b[:n]), nil
 }
 
 func tempFD() (*os.File, error) {
@@ -1,14 +1,22 @@
 package backup
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/file"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 func Test_Uploader_FileStorage_Timestamped(t *testing.T) {
@@ -153,3 +161,279 @@ func Test_Uploader_FileStorage(t *testing.T) {
 		t.Fatalf("data mismatch: got %q want %q", string(data), "my upload data")
 	}
 }
+
+func Test_Uploader_FileStorage_ManifestVerify(t *testing.T) {
+	ResetStats()
+
+	dir := t.TempDir()
+	storageClient, err := file.NewClient(dir, "backup.sqlite", &file.Options{Timestamp: true})
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	data := mustCreateDatabase(t, rarchive.CompressionGzip)
+	dp := &mockManifestDataProvider{mockDataProvider: mockDataProvider{data: string(data)}}
+	uploader := NewUploader(storageClient, dp, time.Hour)
+	uploader.Manifest = true
+	uploader.Verify = true
+	uploader.Version = "v1.2.3"
+
+	if err := uploader.upload(context.Background()); err != nil {
+		t.Fatalf("upload failed: %s", err.Error())
+	}
+
+	objs, err := storageClient.ListTimestamped(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list uploads: %s", err.Error())
+	}
+	if len(objs) != 1 {
+		t.Fatalf("expected 1 upload, got %d", len(objs))
+	}
+	b, err := os.ReadFile(filepath.Join(dir, auto.ManifestKey(objs[0].Key)))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %s", err.Error())
+	}
+	m, err := auto.UnmarshalManifest(b)
+	if err != nil {
+		t.Fatalf("failed to parse manifest: %s", err.Error())
+	}
+	sum := sha256.Sum256(data)
+	exp := auto.Manifest{
+		Key:         objs[0].Key,
+		Index:       1,
+		Term:        2,
+		NodeID:      "node1",
+		Version:     "v1.2.3",
+		SHA256:      hex.EncodeToString(sum[:]),
+		Compression: rarchive.CompressionGzip,
+		Size:        int64(len(data)),
+		SchemaHash:  "abc",
+		CreatedAt:   m.CreatedAt,
+	}
+	if *m != exp {
+		t.Fatalf("unexpected manifest\nexp: %+v\ngot: %+v", exp, *m)
+	}
+
+	// The manifest must not be treated as the latest upload.
+	if md, err := storageClient.CurrentMetadata(context.Background()); err != nil || md.ID != "1" {
+		t.Fatalf("unexpected metadata after manifest upload: %v, %v", md, err)
+	}
+
+	st, err := uploader.Stats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %s", err.Error())
+	}
+	vr, ok := st["last_verify"].(*VerifyResult)
+	if !ok {
+		t.Fatalf("expected last_verify in stats, got %v", st)
+	}
+	if !vr.OK || vr.Key != objs[0].Key {
+		t.Fatalf("unexpected verify result: %+v", vr)
+	}
+	if _, ok := st["last_manifest"].(*auto.Manifest); !ok {
+		t.Fatalf("expected last_manifest in stats, got %v", st)
+	}
+	if exp, got := int64(1), stats.Get(numVerifyOK).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d successful verifications, got %d", exp, got)
+	}
+}
+
+func Test_Uploader_FileStorage_VerifyFail(t *testing.T) {
+	ResetStats()
+
+	dir := t.TempDir()
+	storageClient, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	dp := &mockDataProvider{data: "not a database"}
+	uploader := NewUploader(storageClient, dp, time.Hour)
+	uploader.Verify = true
+
+	if err := uploader.upload(context.Background()); err != nil {
+		t.Fatalf("upload failed: %s", err.Error())
+	}
+	st, err := uploader.Stats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %s", err.Error())
+	}
+	vr, ok := st["last_verify"].(*VerifyResult)
+	if !ok {
+		t.Fatalf("expected last_verify in stats, got %v", st)
+	}
+	if vr.OK || vr.Error == "" {
+		t.Fatalf("expected failed verification, got %+v", vr)
+	}
+	if _, ok := st["last_manifest"]; ok {
+		t.Fatalf("manifest should not be reported when disabled")
+	}
+	if exp, got := int64(1), stats.Get(numVerifyFail).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d failed verifications, got %d", exp, got)
+	}
+}
+
+func Test_Uploader_FileStorage_UploadNow(t *testing.T) {
+	ResetStats()
+
+	dir := t.TempDir()
+	storageClient, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	data := mustCreateDatabase(t, rarchive.CompressionNone)
+	dp := &mockManifestDataProvider{mockDataProvider: mockDataProvider{data: string(data)}}
+	uploader := NewUploader(storageClient, dp, time.Hour)
+	uploader.Manifest = true
+
+	// Upload twice, since on-demand uploads are never skipped.
+	for range 2 {
+		res, err := uploader.UploadNow(context.Background(), "", "")
+		if err != nil {
+			t.Fatalf("on-demand upload failed: %s", err.Error())
+		}
+		if res.Key != "backup.sqlite" {
+			t.Fatalf("unexpected key: %s", res.Key)
+		}
+		if res.Manifest == nil || res.Manifest.Key != res.Key || res.Manifest.SHA256 != res.SHA256 {
+			t.Fatalf("unexpected manifest: %+v", res.Manifest)
+		}
+	}
+	if exp, got := int64(2), stats.Get(numUploadsOnDemand).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d on-demand uploads, got %d", exp, got)
+	}
+
+	res, err := uploader.UploadNow(context.Background(), "pre-migration.sqlite", ".bak")
+	if err != nil {
+		t.Fatalf("on-demand upload failed: %s", err.Error())
+	}
+	if res.Key != "pre-migration.sqlite.bak" {
+		t.Fatalf("unexpected key: %s", res.Key)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, res.Key))
+	if err != nil {
+		t.Fatalf("failed to read upload: %s", err.Error())
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatalf("uploaded data does not match provided data")
+	}
+	if _, err := os.Stat(filepath.Join(dir, auto.ManifestKey(res.Key))); err != nil {
+		t.Fatalf("expected manifest for %s: %s", res.Key, err.Error())
+	}
+
+	if _, err := uploader.UploadNow(context.Background(), "../escape", ""); err != ErrInvalidName {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+}
+
+func Test_Uploader_FileStorage_UploadNow_NoManifest(t *testing.T) {
+	dir := t.TempDir()
+	storageClient, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	data := mustCreateDatabase(t, rarchive.CompressionNone)
+	dp := &mockManifestDataProvider{mockDataProvider: mockDataProvider{data: string(data)}}
+	uploader := NewUploader(storageClient, dp, time.Hour)
+
+	// The manifest is returned even if manifests are not uploaded.
+	res, err := uploader.UploadNow(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("on-demand upload failed: %s", err.Error())
+	}
+	if res.Manifest == nil || res.Manifest.Key != res.Key || res.Manifest.SHA256 != res.SHA256 {
+		t.Fatalf("unexpected manifest: %+v", res.Manifest)
+	}
+	if _, err := os.Stat(filepath.Join(dir, auto.ManifestKey(res.Key))); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest uploaded for %s, got %v", res.Key, err)
+	}
+	st, err := uploader.Stats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %s", err.Error())
+	}
+	if _, ok := st["last_manifest"]; ok {
+		t.Fatalf("last manifest set though no manifest was uploaded")
+	}
+}
+
+func Test_Uploader_UploadNow_Disabled(t *testing.T) {
+	storageClient, err := file.NewClient(t.TempDir(), "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	uploader := NewUploader(storageClient, &mockDataProvider{data: "data"}, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uploader.Start(ctx, func() bool { return false })
+
+	if _, err := uploader.UploadNow(context.Background(), "", ""); err != ErrUploadDisabled {
+		t.Fatalf("expected ErrUploadDisabled, got %v", err)
+	}
+}
+
+func Test_Uploader_FileStorage_VerifyZstd(t *testing.T) {
+	ResetStats()
+
+	storageClient, err := file.NewClient(t.TempDir(), "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file storage client: %s", err.Error())
+	}
+	data := mustCreateDatabase(t, rarchive.CompressionZstd)
+	dp := &mockManifestDataProvider{mockDataProvider: mockDataProvider{data: string(data)}}
+	uploader := NewUploader(storageClient, dp, time.Hour)
+	uploader.Manifest = true
+	uploader.Verify = true
+
+	res, err := uploader.UploadNow(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("upload failed: %s", err.Error())
+	}
+	if res.Manifest == nil || res.Manifest.Compression != rarchive.CompressionZstd {
+		t.Fatalf("expected zstd compression in manifest, got %+v", res.Manifest)
+	}
+	if res.Verify == nil || !res.Verify.OK {
+		t.Fatalf("expected successful verification, got %+v", res.Verify)
+	}
+}
+
+type mockManifestDataProvider struct {
+	mockDataProvider
+}
+
+func (mp *mockManifestDataProvider) ManifestDetails(m *auto.Manifest) error {
+	m.Term = 2
+	m.NodeID = "node1"
+	m.SchemaHash = "abc"
+	return nil
+}
+
+// mustCreateDatabase returns the bytes of a small SQLite database, compressed
+// with the given algorithm.
+func mustCreateDatabase(t *testing.T, compression string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	sdb, err := db.Open(path, false, false)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err.Error())
+	}
+	if _, err := sdb.ExecuteStringStmt("CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %s", err.Error())
+	}
+	if err := sdb.Close(); err != nil {
+		t.Fatalf("failed to close database: %s", err.Error())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read database: %s", err.Error())
+	}
+	var buf bytes.Buffer
+	cw, err := rarchive.NewCompressWriter(&buf, compression)
+	if err != nil {
+		t.Fatalf("failed to create compress writer: %s", err.Error())
+	}
+	if _, err := cw.Write(b); err != nil {
+		t.Fatalf("failed to compress database: %s", err.Error())
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("failed to compress database: %s", err.Error())
+	}
+	return buf.Bytes()
+}
@@ -310,6 +310,79 @@ func Test_UploaderStats(t *testing.T) {
 	}
 }
 
+// Test_UploaderStatsDuringUpload tests that stats may be read while uploads
+// are in progress. Run with -race to check for data races.
+func Test_UploaderStatsDuringUpload(t *testing.T) {
+	ResetStats()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	var nUploads atomic.Int32
+	sc := &mockStorageClient{
+		uploadFn: func(ctx context.Context, reader io.Reader, id string) error {
+			if nUploads.Add(1) <= 3 {
+				wg.Done()
+			}
+			return nil
+		},
+	}
+	dp := &mockDataProvider{data: "my upload data"}
+	var li atomic.Uint64
+	dp.lastIndexFn = func() (uint64, error) { return li.Add(1), nil }
+	uploader := NewUploader(sc, dp, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := uploader.Start(ctx, nil)
+	uploaded := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(uploaded)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-uploaded:
+			finished = true
+		default:
+		}
+		if _, err := uploader.Stats(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	cancel()
+	<-done
+
+	stats, err := uploader.Stats()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats["last_index"] == "0" {
+		t.Errorf("expected last_index to be set, got %s", stats["last_index"])
+	}
+}
+
+func Test_OverrideKey(t *testing.T) {
+	for _, tc := range []struct {
+		key, name, suffix string
+		exp               string
+		err               error
+	}{
+		{key: "backup.sqlite", exp: "backup.sqlite"},
+		{key: "dir/backup.sqlite", name: "other.sqlite", exp: "dir/other.sqlite"},
+		{key: "dir/20250101000000_backup.sqlite", suffix: "-pre", exp: "dir/20250101000000_backup.sqlite-pre"},
+		{key: "backup.sqlite", name: "other", suffix: ".gz", exp: "other.gz"},
+		{key: "backup.sqlite", name: "a/b", err: ErrInvalidName},
+		{key: "backup.sqlite", name: "..", err: ErrInvalidName},
+		{key: "backup.sqlite", suffix: "/x", err: ErrInvalidName},
+	} {
+		got, err := OverrideKey(tc.key, tc.name, tc.suffix)
+		if err != tc.err {
+			t.Fatalf("OverrideKey(%q, %q, %q): expected error %v, got %v", tc.key, tc.name, tc.suffix, tc.err, err)
+		}
+		if got != tc.exp {
+			t.Fatalf("OverrideKey(%q, %q, %q): expected %q, got %q", tc.key, tc.name, tc.suffix, tc.exp, got)
+		}
+	}
+}
+
 // mockStorageClient implements StorageClient and in its default configuration
 // always returns an error for CurrentSum.
 type mockStorageClient struct {
@@ -3,12 +3,16 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/rqlite/rqlite/v10/auto"
 )
 
 // Config represents configuration for the file storage client.
@@ -113,18 +117,29 @@ func (c *Client) String() string {
 	return fmt.Sprintf("dir:%s, file:%s", c.dir, c.name)
 }
 
-// Upload uploads data from the reader to the file storage.
-func (c *Client) Upload(ctx context.Context, reader io.Reader, id string) (retErr error) {
-	filename := c.name
-	if c.timestamp {
-		if c.now == nil {
-			c.now = func() time.Time {
-				return time.Now().UTC()
-			}
+// NextKey returns the name of the file the next call to Upload will write to.
+func (c *Client) NextKey() string {
+	if !c.timestamp {
+		return c.name
+	}
+	if c.now == nil {
+		c.now = func() time.Time {
+			return time.Now().UTC()
 		}
-		filename = timestampedPath(filename, c.now())
 	}
+	return timestampedPath(c.name, c.now())
+}
+
+// Upload uploads data from the reader to the file storage.
+func (c *Client) Upload(ctx context.Context, reader io.Reader, id string) error {
+	return c.UploadKey(ctx, c.NextKey(), reader, id)
+}
 
+// UploadKey uploads data from the reader to the file with the given name,
+// within the configured directory. If id is empty the metadata file is not
+// updated, which allows ancillary files to be stored alongside uploads.
+func (c *Client) UploadKey(ctx context.Context, key string, reader io.Reader, id string) (retErr error) {
+	filename := filepath.Base(key)
 	finalPath := filepath.Join(c.dir, filename)
 
 	tmpFile, err := os.CreateTemp(c.dir, ".upload-*")
@@ -155,6 +170,13 @@ func (c *Client) Upload(ctx context.Context, reader io.Reader, id string) (retEr
 		return fmt.Errorf("failed to close temporary file %s: %w", tmpPath, err)
 	}
 
+	if id == "" {
+		if err := os.Rename(tmpPath, finalPath); err != nil {
+			return fmt.Errorf("failed to rename temporary file %s to %s: %w", tmpPath, finalPath, err)
+		}
+		return nil
+	}
+
 	// Write metadata to temporary metadata file
 	metadata := Metadata{
 		ID:        id,
@@ -194,11 +216,52 @@ func (c *Client) CurrentID(ctx context.Context) (string, error) {
 	return md.ID, nil
 }
 
+// Download copies the configured file to the writer.
+func (c *Client) Download(ctx context.Context, writer io.WriterAt) error {
+	return c.DownloadKey(ctx, c.name, writer)
+}
+
+// DownloadKey copies the file with the given name, within the configured
+// directory, to the writer.
+func (c *Client) DownloadKey(ctx context.Context, key string, writer io.WriterAt) error {
+	path := filepath.Join(c.dir, filepath.Base(key))
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("file %s: %w", path, auto.ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(io.NewOffsetWriter(writer, 0), f); err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ListTimestamped returns all timestamped versions of the configured file
+// present in the directory.
+func (c *Client) ListTimestamped(ctx context.Context) ([]auto.TimestampedObject, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", c.dir, err)
+	}
+	var objs []auto.TimestampedObject
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if t, ok := auto.ParseTimestampedPath(c.name, e.Name()); ok {
+			objs = append(objs, auto.TimestampedObject{Key: e.Name(), Time: t})
+		}
+	}
+	return objs, nil
+}
+
 // timestampedPath returns a new path with the given timestamp prepended.
 // If path contains /, the timestamp is prepended to the last segment.
 func timestampedPath(path string, t time.Time) string {
 	parts := strings.Split(path, "/")
-	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format("20060102150405"), parts[len(parts)-1])
+	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format(auto.TimestampFormat), parts[len(parts)-1])
 	return strings.Join(parts, "/")
 }
 
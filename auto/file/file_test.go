@@ -232,3 +232,57 @@ func Test_NewClient_PathValidation(t *testing.T) {
 		}
 	}
 }
+
+func Test_ListTimestamped_DownloadKey(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := NewClient(dir, "backup.sqlite", &Options{Timestamp: true})
+
+	t1 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	for _, ts := range []time.Time{t1, t2} {
+		c.now = func() time.Time { return ts }
+		if err := c.Upload(context.Background(), strings.NewReader(ts.String()), "id"); err != nil {
+			t.Fatalf("Upload error: %v", err)
+		}
+	}
+
+	// Files which are not timestamped versions of the configured name must be ignored.
+	for _, n := range []string{"backup.sqlite", "20260101100000_other.sqlite", "bad_backup.sqlite"} {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+	}
+
+	objs, err := c.ListTimestamped(context.Background())
+	if err != nil {
+		t.Fatalf("ListTimestamped error: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(objs), objs)
+	}
+	for _, o := range objs {
+		if !o.Time.Equal(t1) && !o.Time.Equal(t2) {
+			t.Fatalf("unexpected time %s for key %s", o.Time, o.Key)
+		}
+
+		var buf bytes.Buffer
+		w := &writerAt{buf: &buf}
+		if err := c.DownloadKey(context.Background(), o.Key, w); err != nil {
+			t.Fatalf("DownloadKey error: %v", err)
+		}
+		if buf.String() != o.Time.String() {
+			t.Fatalf("downloaded data mismatch: got %q want %q", buf.String(), o.Time.String())
+		}
+	}
+}
+
+type writerAt struct {
+	buf *bytes.Buffer
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	if int(off) != w.buf.Len() {
+		return 0, os.ErrInvalid
+	}
+	return w.buf.Write(p)
+}
@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/gcp/jws"
 )
 
@@ -34,7 +35,7 @@ var (
 // If path contains /, the timestamp is prepended to the last segment.
 func TimestampedPath(path string, t time.Time) string {
 	parts := strings.Split(path, "/")
-	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format("20060102150405"), parts[len(parts)-1])
+	parts[len(parts)-1] = fmt.Sprintf("%s_%s", t.Format(auto.TimestampFormat), parts[len(parts)-1])
 	return strings.Join(parts, "/")
 }
 
@@ -56,10 +57,11 @@ type GCSClient struct {
 	expiry      time.Time
 	tokenMu     sync.Mutex
 
-	http      *http.Client
-	uploadURL string
-	objectURL string
-	bucketURL string
+	http       *http.Client
+	uploadURL  string
+	objectURL  string
+	objectsURL string
+	bucketURL  string
 
 	timestamp bool
 }
@@ -87,8 +89,9 @@ func NewGCSClient(cfg *GCSConfig, opts *GCSClientOpts) (*GCSClient, error) {
 		uploadURL: fmt.Sprintf("%s/upload/storage/v1/b/%s/o", base, url.PathEscape(cfg.Bucket)),
 		objectURL: fmt.Sprintf("%s/storage/v1/b/%s/o/%s",
 			base, url.PathEscape(cfg.Bucket), url.PathEscape(cfg.Name)),
-		bucketURL: fmt.Sprintf("%s/storage/v1/b/%s", base, url.PathEscape(cfg.Bucket)),
-		timestamp: opts != nil && opts.Timestamp,
+		objectsURL: fmt.Sprintf("%s/storage/v1/b/%s/o", base, url.PathEscape(cfg.Bucket)),
+		bucketURL:  fmt.Sprintf("%s/storage/v1/b/%s", base, url.PathEscape(cfg.Bucket)),
+		timestamp:  opts != nil && opts.Timestamp,
 	}, nil
 }
 
@@ -139,15 +142,25 @@ func (g *GCSClient) EnsureBucket(ctx context.Context) error {
 	}
 }
 
+// NextKey returns the object name the next call to Upload will write to.
+func (g *GCSClient) NextKey() string {
+	if !g.timestamp {
+		return g.cfg.Name
+	}
+	return TimestampedPath(g.cfg.Name, time.Now().UTC())
+}
+
 // Upload uploads data to GCS.
 func (g *GCSClient) Upload(ctx context.Context, r io.Reader, id string) error {
+	return g.UploadKey(ctx, g.NextKey(), r, id)
+}
+
+// UploadKey uploads data to the object with the given name, in the configured
+// bucket, in GCS.
+func (g *GCSClient) UploadKey(ctx context.Context, name string, r io.Reader, id string) error {
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
 
-	name := g.cfg.Name
-	if g.timestamp {
-		name = TimestampedPath(name, time.Now().UTC())
-	}
 	metaData := struct {
 		Name     string `json:"name"`
 		Metadata struct {
@@ -205,7 +218,65 @@ func (g *GCSClient) Upload(ctx context.Context, r io.Reader, id string) error {
 
 // Download downloads data from GCS.
 func (g *GCSClient) Download(ctx context.Context, w io.WriterAt) error {
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, g.objectURL+"?alt=media", nil)
+	return g.download(ctx, g.objectURL, w)
+}
+
+// DownloadKey downloads the object with the given name, in the configured
+// bucket, from GCS.
+func (g *GCSClient) DownloadKey(ctx context.Context, key string, w io.WriterAt) error {
+	return g.download(ctx, g.objectsURL+"/"+url.PathEscape(key), w)
+}
+
+// ListTimestamped returns all timestamped versions of the configured object
+// present in the bucket.
+func (g *GCSClient) ListTimestamped(ctx context.Context) ([]auto.TimestampedObject, error) {
+	q := url.Values{}
+	if i := strings.LastIndex(g.cfg.Name, "/"); i >= 0 {
+		q.Set("prefix", g.cfg.Name[:i+1])
+	}
+
+	var objs []auto.TimestampedObject
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, g.objectsURL+"?"+q.Encode(), nil)
+		if err := g.addAuth(req); err != nil {
+			return nil, err
+		}
+		res, err := g.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = func() error {
+			defer res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				b, _ := io.ReadAll(res.Body)
+				return fmt.Errorf("list failed: %s", b)
+			}
+			return json.NewDecoder(res.Body).Decode(&list)
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			if t, ok := auto.ParseTimestampedPath(g.cfg.Name, item.Name); ok {
+				objs = append(objs, auto.TimestampedObject{Key: item.Name, Time: t})
+			}
+		}
+		if list.NextPageToken == "" {
+			return objs, nil
+		}
+		q.Set("pageToken", list.NextPageToken)
+	}
+}
+
+func (g *GCSClient) download(ctx context.Context, objectURL string, w io.WriterAt) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, objectURL+"?alt=media", nil)
 	if err := g.addAuth(req); err != nil {
 		return err
 	}
@@ -214,7 +285,9 @@ func (g *GCSClient) Download(ctx context.Context, w io.WriterAt) error {
 		return err
 	}
 	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("download failed: %w", auto.ErrNotFound)
+	} else if res.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(res.Body)
 		return fmt.Errorf("download failed: %s", b)
 	}
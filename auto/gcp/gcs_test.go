@@ -180,6 +180,84 @@ func Test_Download(t *testing.T) {
 	}
 }
 
+func Test_ListTimestamped(t *testing.T) {
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Fatalf("method %s", r.Method)
+		}
+		if r.URL.Path != "/storage/v1/b/mybucket/o" {
+			t.Fatalf("path %s", r.URL.Path)
+		}
+		var resp map[string]any
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			if r.URL.Query().Get("pageToken") != "" {
+				t.Fatalf("unexpected page token on first request")
+			}
+			resp = map[string]any{
+				"items": []map[string]string{
+					{"name": "20260101100000_object.txt"},
+					{"name": "object.txt"},
+				},
+				"nextPageToken": "page2",
+			}
+		case 2:
+			if r.URL.Query().Get("pageToken") != "page2" {
+				t.Fatalf("missing page token on second request")
+			}
+			resp = map[string]any{
+				"items": []map[string]string{
+					{"name": "20260101110000_object.txt"},
+					{"name": "20260101110000_other.txt"},
+				},
+			}
+		default:
+			t.Fatalf("unexpected extra request")
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+	cli, shutdown := newTestClient(t, handler)
+	defer shutdown()
+
+	objs, err := cli.ListTimestamped(context.Background())
+	if err != nil {
+		t.Fatalf("ListTimestamped: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects, want 2: %v", len(objs), objs)
+	}
+	if objs[0].Key != "20260101100000_object.txt" || !objs[0].Time.Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first object: %v", objs[0])
+	}
+	if objs[1].Key != "20260101110000_object.txt" || !objs[1].Time.Equal(time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected second object: %v", objs[1])
+	}
+}
+
+func Test_DownloadKey(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.RawQuery, "alt=media") {
+			t.Fatalf("missing alt=media")
+		}
+		if r.URL.Path != "/storage/v1/b/mybucket/o/20260101100000_object.txt" {
+			t.Fatalf("path %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("older"))
+	}
+	cli, shutdown := newTestClient(t, handler)
+	defer shutdown()
+
+	var buf writerAtBuffer
+	if err := cli.DownloadKey(context.Background(), "20260101100000_object.txt", &buf); err != nil {
+		t.Fatalf("DownloadKey: %v", err)
+	}
+	if buf.String() != "older" {
+		t.Fatalf("got %q, want %q", buf.String(), "older")
+	}
+}
+
 func Test_Delete(t *testing.T) {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
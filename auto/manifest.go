@@ -0,0 +1,64 @@
+package auto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ManifestSuffix is appended to the key of a backup to form the key of
+// the backup's manifest.
+const ManifestSuffix = ".manifest.json"
+
+// Manifest describes a backup. It is stored alongside the backup, in the
+// same storage service.
+type Manifest struct {
+	// Key is the key of the backup in the storage service.
+	Key string `json:"key"`
+
+	// Index is the Raft index the backup reflects.
+	Index uint64 `json:"index"`
+
+	// Term is the Raft term of the log entry at Index.
+	Term uint64 `json:"term"`
+
+	// NodeID is the ID of the node which created the backup.
+	NodeID string `json:"node_id"`
+
+	// Version is the version of rqlite which created the backup.
+	Version string `json:"version"`
+
+	// SHA256 is the hex-encoded SHA-256 checksum of the backup, as stored.
+	SHA256 string `json:"sha256"`
+
+	// Compression is the compression applied to the backup, one of the
+	// algorithms named in package rarchive.
+	Compression string `json:"compression"`
+
+	// Size is the size of the backup in bytes, as stored.
+	Size int64 `json:"size"`
+
+	// SchemaHash is the hex-encoded SHA-256 checksum of the database schema.
+	SchemaHash string `json:"schema_hash"`
+
+	// CreatedAt is the time the backup was uploaded.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ManifestKey returns the key of the manifest for the backup at key.
+func ManifestKey(key string) string {
+	return key + ManifestSuffix
+}
+
+// Marshal returns the JSON encoding of the manifest.
+func (m *Manifest) Marshal() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// UnmarshalManifest parses a JSON-encoded manifest.
+func UnmarshalManifest(b []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -2,19 +2,51 @@ package restore
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"time"
 
 	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/aws"
+	"github.com/rqlite/rqlite/v10/auto/azure"
+	"github.com/rqlite/rqlite/v10/auto/file"
 	"github.com/rqlite/rqlite/v10/auto/gcp"
 )
 
+var (
+	// ErrInvalidMode is returned when the restore mode is not supported.
+	ErrInvalidMode = errors.New("invalid restore mode")
+
+	// ErrPointInTimeRequired is returned when point-in-time restore is requested
+	// without a point in time.
+	ErrPointInTimeRequired = errors.New("point_in_time must be set for point-in-time restore")
+)
+
+// Mode selects which backup is restored.
+type Mode string
+
+const (
+	// ModeFixed restores the object at the configured path. This is the default.
+	// The object is checked against its manifest, if the storage service
+	// supports manifests and one exists, and is checked to be a valid SQLite
+	// database. There is no fallback if either check fails.
+	ModeFixed Mode = "fixed"
+
+	// ModeLatest restores the most recent timestamped backup of the configured path.
+	ModeLatest Mode = "latest"
+
+	// ModePointInTime restores the most recent timestamped backup of the configured
+	// path which was created at or before the configured point in time.
+	ModePointInTime Mode = "point_in_time"
+)
+
 // Config is the config file format for the upload service
 type Config struct {
 	Version           int              `json:"version"`
 	Type              auto.StorageType `json:"type"`
+	Mode              Mode             `json:"mode,omitempty"`
+	PointInTime       time.Time        `json:"point_in_time,omitzero"`
 	Timeout           auto.Duration    `json:"timeout,omitempty"`
 	ContinueOnFailure bool             `json:"continue_on_failure,omitempty"`
 	Sub               json.RawMessage  `json:"sub"`
@@ -50,6 +82,18 @@ func NewStorageClient(data []byte) (*Config, StorageClient, error) {
 		return nil, nil, auto.ErrInvalidVersion
 	}
 
+	switch dCfg.Mode {
+	case "":
+		dCfg.Mode = ModeFixed
+	case ModeFixed, ModeLatest:
+	case ModePointInTime:
+		if dCfg.PointInTime.IsZero() {
+			return nil, nil, ErrPointInTimeRequired
+		}
+	default:
+		return nil, nil, ErrInvalidMode
+	}
+
 	if dCfg.Timeout == 0 {
 		dCfg.Timeout = auto.Duration(30 * time.Second)
 	}
@@ -74,6 +118,20 @@ func NewStorageClient(data []byte) (*Config, StorageClient, error) {
 			return nil, nil, err
 		}
 		sc, err = gcp.NewGCSClient(gcsCfg, nil)
+	case auto.StorageTypeFile:
+		fileCfg := &file.Config{}
+		err = json.Unmarshal(dCfg.Sub, fileCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		sc, err = file.NewClient(fileCfg.Dir, fileCfg.Name, nil)
+	case auto.StorageTypeAzure:
+		azureCfg := &azure.BlobConfig{}
+		err = json.Unmarshal(dCfg.Sub, azureCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		sc, err = azure.NewBlobClient(azureCfg, nil)
 	default:
 		return nil, nil, auto.ErrUnsupportedStorageType
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/auto/aws"
+	"github.com/rqlite/rqlite/v10/auto/azure"
 	"github.com/rqlite/rqlite/v10/auto/gcp"
 )
 
@@ -179,6 +180,42 @@ func TestNewStorageClient(t *testing.T) {
 			expectedCfg: nil,
 			expectedErr: auto.ErrInvalidVersion,
 		},
+		{
+			name: "PointInTimeMissingTime",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "s3",
+				"mode": "point_in_time",
+				"sub": {
+					"access_key_id": "test_id",
+					"secret_access_key": "test_secret",
+					"region": "us-west-2",
+					"bucket": "test_bucket",
+					"path": "test/path"
+				}
+			}			`),
+			expectedCfg: nil,
+			expectedErr: ErrPointInTimeRequired,
+		},
+		{
+			name: "InvalidMode",
+			input: []byte(`
+			{
+				"version": 1,
+				"type": "s3",
+				"mode": "oldest",
+				"sub": {
+					"access_key_id": "test_id",
+					"secret_access_key": "test_secret",
+					"region": "us-west-2",
+					"bucket": "test_bucket",
+					"path": "test/path"
+				}
+			}			`),
+			expectedCfg: nil,
+			expectedErr: ErrInvalidMode,
+		},
 		{
 			name: "UnsupportedType",
 			input: []byte(`
@@ -228,6 +265,76 @@ func TestNewStorageClient(t *testing.T) {
 	}
 }
 
+func TestNewStorageClient_Azure(t *testing.T) {
+	cfg, sc, err := NewStorageClient([]byte(`
+	{
+		"version": 1,
+		"type": "azure",
+		"mode": "latest",
+		"sub": {
+			"account_name": "acct",
+			"sas_token": "sv=2021-08-06&sig=abc",
+			"container": "backups",
+			"name": "backup.sqlite"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Mode != ModeLatest {
+		t.Fatalf("expected mode %s, got %s", ModeLatest, cfg.Mode)
+	}
+	if _, ok := sc.(*azure.BlobClient); !ok {
+		t.Fatalf("expected Azure Blob client, got %T", sc)
+	}
+	if _, ok := sc.(Lister); !ok {
+		t.Fatalf("expected Azure Blob client to implement Lister")
+	}
+}
+
+func TestNewStorageClient_Modes(t *testing.T) {
+	dir := t.TempDir()
+	cfg, sc, err := NewStorageClient([]byte(`
+	{
+		"version": 1,
+		"type": "file",
+		"sub": {
+			"dir": "` + dir + `",
+			"name": "backup.sqlite"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Mode != ModeFixed {
+		t.Fatalf("expected default mode %s, got %s", ModeFixed, cfg.Mode)
+	}
+	if _, ok := sc.(Lister); !ok {
+		t.Fatalf("expected file client to implement Lister, got %T", sc)
+	}
+
+	cfg, _, err = NewStorageClient([]byte(`
+	{
+		"version": 1,
+		"type": "file",
+		"mode": "point_in_time",
+		"point_in_time": "2026-01-02T15:04:05Z",
+		"sub": {
+			"dir": "` + dir + `",
+			"name": "backup.sqlite"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Mode != ModePointInTime {
+		t.Fatalf("expected mode %s, got %s", ModePointInTime, cfg.Mode)
+	}
+	if exp := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC); !cfg.PointInTime.Equal(exp) {
+		t.Fatalf("expected point in time %s, got %s", exp, cfg.PointInTime)
+	}
+}
+
 func compareConfig(a, b *Config) bool {
 	if a == nil || b == nil {
 		return a == b
@@ -1,28 +1,33 @@
 package restore
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"expvar"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"time"
+
+	"github.com/rqlite/rqlite/v10/auto"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 // stats captures stats for the Uploader service.
 var stats *expvar.Map
 
-var (
-	gzipMagic = []byte{0x1f, 0x8b, 0x08}
-)
-
 const (
-	numDownloadsOK   = "num_downloads_ok"
-	numDownloadsFail = "num_downloads_fail"
-	numDownloadBytes = "download_bytes"
+	numDownloadsOK    = "num_downloads_ok"
+	numDownloadsFail  = "num_downloads_fail"
+	numDownloadBytes  = "download_bytes"
+	numVerifyFail     = "num_verify_fail"
+	numBackupFallback = "num_backup_fallback"
 )
 
 func init() {
@@ -36,6 +41,8 @@ func ResetStats() {
 	stats.Add(numDownloadsOK, 0)
 	stats.Add(numDownloadsFail, 0)
 	stats.Add(numDownloadBytes, 0)
+	stats.Add(numVerifyFail, 0)
+	stats.Add(numBackupFallback, 0)
 }
 
 // DownloadFile downloads the auto-restore file from the given URL, and returns the path to
@@ -66,6 +73,18 @@ func DownloadFile(ctx context.Context, cfgPath string) (path string, errOK bool,
 	}
 	d := NewDownloader(sc)
 
+	if dCfg.Mode != ModeFixed {
+		path, err = d.DoSelected(ctx, dCfg.Mode, dCfg.PointInTime, time.Duration(dCfg.Timeout))
+		if err != nil {
+			return "", dCfg.ContinueOnFailure, fmt.Errorf("failed to download auto-restore file: %s", err.Error())
+		}
+		return path, false, nil
+	}
+
+	if err := d.useFixedManifest(ctx, time.Duration(dCfg.Timeout)); err != nil {
+		return "", dCfg.ContinueOnFailure, err
+	}
+
 	// Create a temporary file to download to.
 	f, err = os.CreateTemp("", "rqlite-auto-restore")
 	if err != nil {
@@ -76,6 +95,10 @@ func DownloadFile(ctx context.Context, cfgPath string) (path string, errOK bool,
 	if err := d.Do(ctx, f, time.Duration(dCfg.Timeout)); err != nil {
 		return "", dCfg.ContinueOnFailure, fmt.Errorf("failed to download auto-restore file: %s", err.Error())
 	}
+	if err := db.VerifyFileIntegrity(f.Name()); err != nil {
+		stats.Add(numVerifyFail, 1)
+		return "", dCfg.ContinueOnFailure, fmt.Errorf("auto-restore file failed verification: %s", err.Error())
+	}
 	return f.Name(), false, nil
 }
 
@@ -85,17 +108,60 @@ type StorageClient interface {
 	fmt.Stringer
 }
 
+// Lister is an interface for listing and downloading timestamped backups. A
+// StorageClient must also implement Lister to support the latest and
+// point-in-time restore modes.
+type Lister interface {
+	// ListTimestamped returns all timestamped backups of the configured object.
+	ListTimestamped(ctx context.Context) ([]auto.TimestampedObject, error)
+
+	// DownloadKey downloads the object with the given key.
+	DownloadKey(ctx context.Context, key string, writer io.WriterAt) error
+}
+
+// keyedClient is implemented by storage clients which can report the key
+// they download from, so the manifest of the data can be found.
+type keyedClient interface {
+	Lister
+	NextKey() string
+}
+
+// SelectBackups returns the backups eligible for restore under the given mode,
+// ordered newest first. For ModePointInTime only backups created at or before
+// pit are eligible. ModeFixed selects no backups.
+func SelectBackups(objs []auto.TimestampedObject, mode Mode, pit time.Time) []auto.TimestampedObject {
+	var sel []auto.TimestampedObject
+	for _, o := range objs {
+		switch mode {
+		case ModeLatest:
+			sel = append(sel, o)
+		case ModePointInTime:
+			if !o.Time.After(pit) {
+				sel = append(sel, o)
+			}
+		}
+	}
+	sort.SliceStable(sel, func(i, j int) bool {
+		return sel[i].Time.After(sel[j].Time)
+	})
+	return sel
+}
+
 // Downloader is a struct that handles downloading data from a storage service.
 type Downloader struct {
 	storageClient StorageClient
 	logger        *log.Logger
+
+	// sum, if set, is the expected hex-encoded SHA-256 checksum of the
+	// downloaded data, prior to any decompression.
+	sum string
 }
 
 // NewDownloader creates a new Downloader instance with the given StorageClient.
 func NewDownloader(storageClient StorageClient) *Downloader {
 	return &Downloader{
 		storageClient: storageClient,
-		logger:        log.New(os.Stderr, "[downloader] ", log.LstdFlags),
+		logger:        logging.New("downloader", os.Stderr),
 	}
 }
 
@@ -130,65 +196,186 @@ func (d *Downloader) Do(ctx context.Context, w io.Writer, timeout time.Duration)
 		return err
 	}
 
-	// Check if the download data is gzip compressed.
-	compressed, err := isGzip(f)
+	if d.sum != "" {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != d.sum {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", d.sum, got)
+		}
+	}
+
+	// Decompress the downloaded data, if it is compressed.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	rc, compression, err := rarchive.NewDecompressReader(f)
 	if err != nil {
 		return err
 	}
+	defer rc.Close()
 
-	if compressed {
-		gzr, err := gzip.NewReader(f)
-		if err != nil {
-			return err
+	_, err = io.Copy(w, rc)
+	if err != nil {
+		if compression != rarchive.CompressionNone {
+			return fmt.Errorf("failed to decompress data: %s", err)
 		}
-		defer gzr.Close()
+		return fmt.Errorf("failed to write data: %s", err)
+	}
+	return nil
+}
+
+// DoSelected lists the timestamped backups in the storage service, and downloads
+// the most recent one selected by mode and pit to a temporary file, returning the
+// path to that file. The downloaded database is checked for integrity, and if
+// the check fails, or the manifest of the backup cannot be downloaded or
+// parsed, the next most recent backup is tried instead. The caller is
+// responsible for removing the file.
+func (d *Downloader) DoSelected(ctx context.Context, mode Mode, pit time.Time, timeout time.Duration) (string, error) {
+	l, ok := d.storageClient.(Lister)
+	if !ok {
+		return "", fmt.Errorf("storage client %s does not support listing backups", d.storageClient)
+	}
 
-		_, err = io.Copy(w, gzr)
+	listCtx, cancel := context.WithTimeout(ctx, timeout)
+	objs, err := l.ListTimestamped(listCtx)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	cands := SelectBackups(objs, mode, pit)
+	if len(cands) == 0 {
+		return "", fmt.Errorf("no eligible backups found at %s", d.storageClient)
+	}
+
+	for i, o := range cands {
+		if i > 0 {
+			stats.Add(numBackupFallback, 1)
+		}
+		m, err := d.manifest(ctx, l, o.Key, timeout)
 		if err != nil {
-			return fmt.Errorf("failed to decompress data: %s", err)
+			logging.Warnf(d.logger, "backup %s not usable: %s", o.Key, err.Error())
+			continue
 		}
-	} else {
-		_, err = io.Copy(w, f)
+		path, err := d.downloadVerified(ctx, l, o.Key, m, timeout)
 		if err != nil {
-			return fmt.Errorf("failed to write data: %s", err)
+			logging.Warnf(d.logger, "backup %s not usable: %s", o.Key, err.Error())
+			continue
 		}
+		d.logger.Printf("selected backup %s, created at %s", o.Key, o.Time.Format(time.RFC3339))
+		return path, nil
 	}
-	return nil
+	return "", fmt.Errorf("none of %d eligible backups at %s are usable", len(cands), d.storageClient)
 }
 
-type countingWriterAt struct {
-	writerAt io.WriterAt
-	count    int64
-}
+// downloadVerified downloads the data at key to a temporary file, and checks
+// that the file is a valid SQLite database. If m is not nil, the checksum
+// recorded in it is also checked.
+func (d *Downloader) downloadVerified(ctx context.Context, l Lister, key string, m *auto.Manifest, timeout time.Duration) (path string, retErr error) {
+	f, err := os.CreateTemp("", "rqlite-auto-restore")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		if retErr != nil {
+			os.Remove(f.Name())
+		}
+	}()
 
-func (c *countingWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
-	n, err = c.writerAt.WriteAt(p, off)
-	c.count += int64(n)
-	return
+	kd := NewDownloader(&keyStorageClient{l: l, key: key})
+	if m != nil {
+		kd.sum = m.SHA256
+	}
+	if err := kd.Do(ctx, f, timeout); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := db.VerifyFileIntegrity(f.Name()); err != nil {
+		stats.Add(numVerifyFail, 1)
+		return "", err
+	}
+	return f.Name(), nil
 }
 
-// isGzip returns true if the data in the reader is gzip compressed.
-// It does this by reading the first three bytes of the reader, and checking
-// if they match the gzip magic number. When f is returned it will be
-// positioned at the start of the reader.
-func isGzip(f io.ReadSeeker) (bool, error) {
-	_, err := f.Seek(0, io.SeekStart)
-	if err != nil {
-		return false, err
+// manifest downloads and parses the manifest for the data at key. If no
+// manifest exists nil is returned, and the checksum of the data cannot be
+// checked. Any other failure is returned as an error, since the manifest
+// may exist but be unavailable or corrupt, and the data must not then be
+// restored unchecked.
+func (d *Downloader) manifest(ctx context.Context, l Lister, key string, timeout time.Duration) (*auto.Manifest, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var buf bufferWriterAt
+	if err := l.DownloadKey(ctx, auto.ManifestKey(key), &buf); err != nil {
+		if errors.Is(err, auto.ErrNotFound) {
+			d.logger.Printf("no manifest available for %s, checksum will not be checked", key)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to download manifest for %s: %s", key, err.Error())
 	}
-	data := make([]byte, len(gzipMagic))
-	n, err := f.Read(data)
+	m, err := auto.UnmarshalManifest(buf.b)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("invalid manifest for %s: %s", key, err.Error())
 	}
-	if n != len(gzipMagic) {
-		return false, nil
+	return m, nil
+}
+
+// useFixedManifest sets the checksum checked by Do from the manifest of the
+// configured object, if one exists. Storage clients which cannot download
+// by key do not support manifests, and the checksum of their data is not
+// checked.
+func (d *Downloader) useFixedManifest(ctx context.Context, timeout time.Duration) error {
+	kc, ok := d.storageClient.(keyedClient)
+	if !ok {
+		d.logger.Printf("storage client %s does not support manifests, checksum will not be checked", d.storageClient)
+		return nil
 	}
+	m, err := d.manifest(ctx, kc, kc.NextKey(), timeout)
+	if err != nil || m == nil {
+		return err
+	}
+	d.sum = m.SHA256
+	return nil
+}
 
-	_, err = f.Seek(0, io.SeekStart)
-	if err != nil {
-		return false, err
+// bufferWriterAt is an in-memory io.WriterAt.
+type bufferWriterAt struct {
+	b []byte
+}
+
+func (w *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(w.b) {
+		w.b = append(w.b, make([]byte, end-len(w.b))...)
 	}
+	return copy(w.b[off:], p), nil
+}
+
+// keyStorageClient adapts a Lister to a StorageClient which downloads
+// a specific key.
+type keyStorageClient struct {
+	l   Lister
+	key string
+}
+
+func (k *keyStorageClient) Download(ctx context.Context, writer io.WriterAt) error {
+	return k.l.DownloadKey(ctx, k.key, writer)
+}
 
-	return bytes.Equal(gzipMagic, data[0:len(gzipMagic)]), nil
+func (k *keyStorageClient) String() string {
+	return k.key
+}
+
+type countingWriterAt struct {
+	writerAt io.WriterAt
+	count    int64
+}
+
+func (c *countingWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	n, err = c.writerAt.WriteAt(p, off)
+	c.count += int64(n)
+	return
 }
@@ -2,20 +2,30 @@ package restore
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"expvar"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/rqlite/rqlite/v10/auto"
+	"github.com/rqlite/rqlite/v10/auto/file"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 func TestDownloader_Do(t *testing.T) {
 	tests := []struct {
 		name           string
 		mockClientData []byte
-		compress       bool
+		compression    string
 		expectError    error
 	}{
 		{
@@ -26,7 +36,13 @@ func TestDownloader_Do(t *testing.T) {
 		{
 			name:           "Successful download of compressed data",
 			mockClientData: []byte("test data"),
-			compress:       true,
+			compression:    rarchive.CompressionGzip,
+			expectError:    nil,
+		},
+		{
+			name:           "Successful download of zstd-compressed data",
+			mockClientData: []byte("test data"),
+			compression:    rarchive.CompressionZstd,
 			expectError:    nil,
 		},
 		{
@@ -41,8 +57,10 @@ func TestDownloader_Do(t *testing.T) {
 				data:  tt.mockClientData,
 				error: tt.expectError,
 			}
-			if tt.compress {
-				mockClient.Compress()
+			if tt.compression != "" {
+				if err := mockClient.Compress(tt.compression); err != nil {
+					t.Fatalf("failed to compress data: %s", err)
+				}
 			}
 			downloader := NewDownloader(mockClient)
 
@@ -67,6 +85,284 @@ func TestDownloader_Do(t *testing.T) {
 	}
 }
 
+func Test_SelectBackups(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	objs := []auto.TimestampedObject{
+		{Key: "b", Time: t0.Add(time.Hour)},
+		{Key: "a", Time: t0},
+		{Key: "c", Time: t0.Add(2 * time.Hour)},
+	}
+
+	sel := SelectBackups(objs, ModeLatest, time.Time{})
+	if got := keys(sel); got != "c,b,a" {
+		t.Fatalf("latest: got %s, want c,b,a", got)
+	}
+
+	sel = SelectBackups(objs, ModePointInTime, t0.Add(90*time.Minute))
+	if got := keys(sel); got != "b,a" {
+		t.Fatalf("point in time: got %s, want b,a", got)
+	}
+
+	sel = SelectBackups(objs, ModePointInTime, t0.Add(time.Hour))
+	if got := keys(sel); got != "b,a" {
+		t.Fatalf("point in time, exact match: got %s, want b,a", got)
+	}
+
+	sel = SelectBackups(objs, ModePointInTime, t0.Add(-time.Second))
+	if len(sel) != 0 {
+		t.Fatalf("point in time before all backups: got %s, want none", keys(sel))
+	}
+
+	if sel := SelectBackups(objs, ModeFixed, time.Time{}); len(sel) != 0 {
+		t.Fatalf("fixed: got %s, want none", keys(sel))
+	}
+}
+
+func Test_Downloader_DoSelected(t *testing.T) {
+	ResetStats()
+	dir := t.TempDir()
+	sc, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file client: %s", err.Error())
+	}
+
+	writeBackup := func(name string, data []byte) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("failed to write backup %s: %s", name, err.Error())
+		}
+	}
+	writeBackup("20260101100000_backup.sqlite", mustCreateDatabase(t, "CREATE TABLE foo (id INTEGER)", false))
+	writeBackup("20260101110000_backup.sqlite", mustCreateDatabase(t, "CREATE TABLE bar (id INTEGER)", true))
+	writeBackup("20260101120000_backup.sqlite", []byte("not a database"))
+
+	d := NewDownloader(sc)
+
+	// Latest backup is corrupt, so the one before it should be selected.
+	path, err := d.DoSelected(context.Background(), ModeLatest, time.Time{}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("DoSelected failed: %s", err.Error())
+	}
+	defer os.Remove(path)
+	if !hasTable(t, path, "bar") {
+		t.Fatalf("expected backup with table bar to be selected")
+	}
+	if exp, got := int64(1), stats.Get(numBackupFallback).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d fallbacks, got %d", exp, got)
+	}
+
+	// Point in time before the second backup.
+	path, err = d.DoSelected(context.Background(), ModePointInTime,
+		time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC), 5*time.Second)
+	if err != nil {
+		t.Fatalf("DoSelected failed: %s", err.Error())
+	}
+	defer os.Remove(path)
+	if !hasTable(t, path, "foo") {
+		t.Fatalf("expected backup with table foo to be selected")
+	}
+
+	// Point in time before all backups.
+	_, err = d.DoSelected(context.Background(), ModePointInTime,
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 5*time.Second)
+	if err == nil {
+		t.Fatalf("expected error when no backups are eligible")
+	}
+}
+
+func Test_Downloader_DoSelected_ManifestChecksum(t *testing.T) {
+	ResetStats()
+	dir := t.TempDir()
+	sc, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file client: %s", err.Error())
+	}
+
+	writeBackup := func(name string, data []byte, sum []byte) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("failed to write backup %s: %s", name, err.Error())
+		}
+		m := &auto.Manifest{Key: name, SHA256: hex.EncodeToString(sum)}
+		b, err := m.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal manifest: %s", err.Error())
+		}
+		if err := os.WriteFile(filepath.Join(dir, auto.ManifestKey(name)), b, 0644); err != nil {
+			t.Fatalf("failed to write manifest %s: %s", name, err.Error())
+		}
+	}
+	older := mustCreateDatabase(t, "CREATE TABLE foo (id INTEGER)", true)
+	olderSum := sha256.Sum256(older)
+	writeBackup("20260101100000_backup.sqlite", older, olderSum[:])
+	newer := mustCreateDatabase(t, "CREATE TABLE bar (id INTEGER)", true)
+	writeBackup("20260101110000_backup.sqlite", newer, []byte("wrong checksum"))
+
+	// Latest backup does not match its manifest, so the one before it should be selected.
+	path, err := NewDownloader(sc).DoSelected(context.Background(), ModeLatest, time.Time{}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("DoSelected failed: %s", err.Error())
+	}
+	defer os.Remove(path)
+	if !hasTable(t, path, "foo") {
+		t.Fatalf("expected backup with table foo to be selected")
+	}
+	if exp, got := int64(1), stats.Get(numDownloadsFail).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d failed downloads, got %d", exp, got)
+	}
+}
+
+func Test_Downloader_DoSelected_BadManifest(t *testing.T) {
+	ResetStats()
+	dir := t.TempDir()
+	sc, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file client: %s", err.Error())
+	}
+	name := "20260101100000_backup.sqlite"
+	if err := os.WriteFile(filepath.Join(dir, name), mustCreateDatabase(t, "CREATE TABLE foo (id INTEGER)", false), 0644); err != nil {
+		t.Fatalf("failed to write backup: %s", err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(dir, auto.ManifestKey(name)), []byte("not a manifest"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err.Error())
+	}
+
+	// A backup with a manifest which cannot be parsed must not be restored
+	// unchecked, so with no other backups the restore fails.
+	if _, err := NewDownloader(sc).DoSelected(context.Background(), ModeLatest, time.Time{}, 5*time.Second); err == nil {
+		t.Fatalf("expected error for unparsable manifest")
+	}
+
+	// Make the backup older than a new one with an unparsable manifest, so
+	// the older backup should be selected.
+	older := mustCreateDatabase(t, "CREATE TABLE bar (id INTEGER)", false)
+	if err := os.WriteFile(filepath.Join(dir, name), older, 0644); err != nil {
+		t.Fatalf("failed to write backup: %s", err.Error())
+	}
+	if err := os.Remove(filepath.Join(dir, auto.ManifestKey(name))); err != nil {
+		t.Fatalf("failed to remove manifest: %s", err.Error())
+	}
+	newer := "20260101110000_backup.sqlite"
+	if err := os.WriteFile(filepath.Join(dir, newer), mustCreateDatabase(t, "CREATE TABLE foo (id INTEGER)", false), 0644); err != nil {
+		t.Fatalf("failed to write backup: %s", err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(dir, auto.ManifestKey(newer)), []byte("not a manifest"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err.Error())
+	}
+	ResetStats()
+	path, err := NewDownloader(sc).DoSelected(context.Background(), ModeLatest, time.Time{}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("DoSelected failed: %s", err.Error())
+	}
+	defer os.Remove(path)
+	if !hasTable(t, path, "bar") {
+		t.Fatalf("expected backup with table bar to be selected")
+	}
+	if exp, got := int64(1), stats.Get(numBackupFallback).(*expvar.Int).Value(); exp != got {
+		t.Fatalf("expected %d fallbacks, got %d", exp, got)
+	}
+}
+
+func Test_Downloader_FixedManifest(t *testing.T) {
+	dir := t.TempDir()
+	sc, err := file.NewClient(dir, "backup.sqlite", nil)
+	if err != nil {
+		t.Fatalf("failed to create file client: %s", err.Error())
+	}
+	data := mustCreateDatabase(t, "CREATE TABLE foo (id INTEGER)", false)
+	if err := os.WriteFile(filepath.Join(dir, "backup.sqlite"), data, 0644); err != nil {
+		t.Fatalf("failed to write backup: %s", err.Error())
+	}
+
+	// No manifest, so the data is downloaded unchecked.
+	d := NewDownloader(sc)
+	if err := d.useFixedManifest(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("useFixedManifest failed without manifest: %s", err.Error())
+	}
+	if err := d.Do(context.Background(), io.Discard, 5*time.Second); err != nil {
+		t.Fatalf("Do failed: %s", err.Error())
+	}
+
+	m := &auto.Manifest{Key: "backup.sqlite", SHA256: hex.EncodeToString([]byte("wrong checksum"))}
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %s", err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(dir, auto.ManifestKey("backup.sqlite")), b, 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err.Error())
+	}
+	d = NewDownloader(sc)
+	if err := d.useFixedManifest(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("useFixedManifest failed: %s", err.Error())
+	}
+	if err := d.Do(context.Background(), io.Discard, 5*time.Second); err == nil {
+		t.Fatalf("expected checksum mismatch")
+	}
+}
+
+func Test_Downloader_DoSelected_NotLister(t *testing.T) {
+	d := NewDownloader(&mockStorageClient{})
+	if _, err := d.DoSelected(context.Background(), ModeLatest, time.Time{}, time.Second); err == nil {
+		t.Fatalf("expected error for storage client which cannot list")
+	}
+}
+
+func keys(objs []auto.TimestampedObject) string {
+	k := make([]string, len(objs))
+	for i := range objs {
+		k[i] = objs[i].Key
+	}
+	return strings.Join(k, ",")
+}
+
+// mustCreateDatabase returns the bytes of a SQLite database created by
+// executing stmt, optionally gzip-compressed.
+func mustCreateDatabase(t *testing.T, stmt string, compress bool) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	sdb, err := db.Open(path, false, false)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err.Error())
+	}
+	if _, err := sdb.ExecuteStringStmt(stmt); err != nil {
+		t.Fatalf("failed to execute statement: %s", err.Error())
+	}
+	if err := sdb.Close(); err != nil {
+		t.Fatalf("failed to close database: %s", err.Error())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read database: %s", err.Error())
+	}
+	if !compress {
+		return b
+	}
+	m := &mockStorageClient{data: b}
+	if err := m.Compress(rarchive.CompressionGzip); err != nil {
+		t.Fatalf("failed to compress database: %s", err.Error())
+	}
+	return m.data
+}
+
+func hasTable(t *testing.T, path, table string) bool {
+	t.Helper()
+	sdb, err := db.Open(path, false, false)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err.Error())
+	}
+	defer sdb.Close()
+	rows, err := sdb.QueryStringStmt("SELECT name FROM sqlite_master WHERE type='table'")
+	if err != nil {
+		t.Fatalf("failed to query database: %s", err.Error())
+	}
+	for _, v := range rows[0].Values {
+		if v.Parameters[0].GetS() == table {
+			return true
+		}
+	}
+	return false
+}
+
 type mockStorageClient struct {
 	data  []byte
 	error error
@@ -83,16 +379,19 @@ func (m *mockStorageClient) Download(ctx context.Context, writer io.WriterAt) er
 	return nil
 }
 
-func (m *mockStorageClient) Compress() error {
+func (m *mockStorageClient) Compress(algo string) error {
 	var compressedData bytes.Buffer
-	gzipWriter := gzip.NewWriter(&compressedData)
+	cw, err := rarchive.NewCompressWriter(&compressedData, algo)
+	if err != nil {
+		return err
+	}
 
-	_, err := gzipWriter.Write(m.data)
+	_, err = cw.Write(m.data)
 	if err != nil {
 		return err
 	}
 
-	err = gzipWriter.Close()
+	err = cw.Close()
 	if err != nil {
 		return err
 	}
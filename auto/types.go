@@ -3,6 +3,7 @@ package auto
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -20,6 +21,12 @@ var (
 
 	// ErrInvalidInterval is returned when the interval is invalid.
 	ErrInvalidInterval = errors.New("invalid interval")
+
+	// ErrInvalidCompression is returned when the compression algorithm is not supported.
+	ErrInvalidCompression = errors.New("invalid compression")
+
+	// ErrNotFound is returned when an object does not exist in a storage service.
+	ErrNotFound = errors.New("not found")
 )
 
 // Duration is a wrapper around time.Duration that allows us to unmarshal
@@ -66,6 +73,9 @@ const (
 
 	// StorageTypeFile is the storage type for local file storage
 	StorageTypeFile StorageType = "file"
+
+	// StorageTypeAzure is the storage type for Azure Blob Storage
+	StorageTypeAzure StorageType = "azure"
 )
 
 // UnmarshalJSON unmarshals the storage type from a string and validates it
@@ -77,7 +87,7 @@ func (s *StorageType) UnmarshalJSON(b []byte) error {
 	switch value := v.(type) {
 	case string:
 		*s = StorageType(value)
-		if *s != StorageTypeS3 && *s != StorageTypeGCS && *s != StorageTypeFile {
+		if *s != StorageTypeS3 && *s != StorageTypeGCS && *s != StorageTypeFile && *s != StorageTypeAzure {
 			return ErrUnsupportedStorageType
 		}
 		return nil
@@ -85,3 +95,40 @@ func (s *StorageType) UnmarshalJSON(b []byte) error {
 		return ErrUnsupportedStorageType
 	}
 }
+
+// TimestampFormat is the layout of the timestamp prepended to object names
+// when timestamped backups are enabled.
+const TimestampFormat = "20060102150405"
+
+// TimestampedObject is a timestamped backup, as found in a storage service.
+type TimestampedObject struct {
+	Key  string
+	Time time.Time
+}
+
+// ParseTimestampedPath checks if key is a timestamped version of path, as
+// created by TimestampedPath in the storage packages. If it is, the time
+// encoded in key is returned along with true. The timestamp is of fixed
+// width, so underscores elsewhere in path are allowed.
+func ParseTimestampedPath(path, key string) (time.Time, bool) {
+	dir, name := splitPath(path)
+	kDir, kName := splitPath(key)
+	if dir != kDir {
+		return time.Time{}, false
+	}
+	n := len(TimestampFormat)
+	if len(kName) != n+1+len(name) || kName[n] != '_' || kName[n+1:] != name {
+		return time.Time{}, false
+	}
+	ts := kName[:n]
+	t, err := time.Parse(TimestampFormat, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func splitPath(path string) (string, string) {
+	i := strings.LastIndex(path, "/")
+	return path[:i+1], path[i+1:]
+}
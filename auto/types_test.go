@@ -0,0 +1,33 @@
+package auto
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseTimestampedPath(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		path string
+		key  string
+		ok   bool
+	}{
+		{"backup.sqlite", "20260102030405_backup.sqlite", true},
+		{"dir/backup.sqlite", "dir/20260102030405_backup.sqlite", true},
+		{"my_dir/my_backup.sqlite", "my_dir/20260102030405_my_backup.sqlite", true},
+		{"backup.sqlite", "backup.sqlite", false},
+		{"backup.sqlite", "20260102030405_other.sqlite", false},
+		{"backup.sqlite", "2026010203040_backup.sqlite", false},
+		{"backup.sqlite", "2026010203040x_backup.sqlite", false},
+		{"dir/backup.sqlite", "20260102030405_backup.sqlite", false},
+		{"my_backup.sqlite", "20260102030405_my_backup.sqlite.manifest.json", false},
+	} {
+		got, ok := ParseTimestampedPath(tt.path, tt.key)
+		if ok != tt.ok {
+			t.Fatalf("ParseTimestampedPath(%s, %s) ok = %v, want %v", tt.path, tt.key, ok, tt.ok)
+		}
+		if ok && !got.Equal(ts) {
+			t.Fatalf("ParseTimestampedPath(%s, %s) = %s, want %s", tt.path, tt.key, got, ts)
+		}
+	}
+}
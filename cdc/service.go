@@ -12,6 +12,7 @@ import (
 
 	cdcjson "github.com/rqlite/rqlite/v10/cdc/json"
 	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rarchive/flate"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
 	"github.com/rqlite/rqlite/v10/queue"
@@ -203,7 +204,7 @@ func NewService(nodeID, dir string, clstr Cluster, cfg *Config) (*Service, error
 		snapshotCh:            make(chan chan struct{}),
 		hwmObCh:               make(chan uint64, leaderChanLen),
 		done:                  make(chan struct{}),
-		logger:                log.New(os.Stderr, "[cdc-service] ", log.LstdFlags),
+		logger:                logging.New("cdc-service", os.Stderr),
 	}
 
 	if cfg.TransmitMaxRetries == nil {
@@ -414,19 +415,19 @@ func (s *Service) mainLoop() {
 
 			b, err := cdcjson.MarshalToEnvelopeJSON(s.serviceID, s.nodeID, true, req.Objects)
 			if err != nil {
-				s.logger.Printf("error marshalling batch for FIFO: %v", err)
+				logging.Errorf(s.logger, "error marshalling batch for FIFO: %v", err)
 				continue
 			}
 
 			// Compress the marshalled data before enqueuing to FIFO
 			compressedData, err := flate.Compress(b)
 			if err != nil {
-				s.logger.Printf("error compressing batch for FIFO: %v", err)
+				logging.Errorf(s.logger, "error compressing batch for FIFO: %v", err)
 				continue
 			}
 
 			if err := s.fifo.Enqueue(&Event{Index: hiIdx, Data: compressedData}); err != nil {
-				s.logger.Printf("error writing batch to FIFO: %v", err)
+				logging.Errorf(s.logger, "error writing batch to FIFO: %v", err)
 			}
 			req.Close()
 
@@ -457,7 +458,7 @@ func (s *Service) writeToBatcher() {
 				continue
 			}
 			if _, err := s.batcher.WriteOne(o, nil); err != nil {
-				s.logger.Printf("error writing CDC events to batcher: %v", err)
+				logging.Errorf(s.logger, "error writing CDC events to batcher: %v", err)
 			} else {
 				s.writesToBatcher.Add(1)
 				stats.Add(numBatcherWrites, 1)
@@ -471,7 +472,7 @@ func (s *Service) writeToBatcher() {
 			}
 			fc := make(queue.FlushChannel)
 			if _, err := s.batcher.WriteOne(evg, fc); err != nil {
-				s.logger.Printf("error writing CDC flush event to batcher: %v", err)
+				logging.Errorf(s.logger, "error writing CDC flush event to batcher: %v", err)
 				continue
 			}
 
@@ -527,7 +528,7 @@ func (s *Service) leaderLoop() (chan struct{}, chan struct{}) {
 				// so the sink can handle the request properly.
 				decompressed, err := flate.Decompress(ev.Data)
 				if err != nil {
-					s.logger.Printf("error decompressing data for batch from FIFO: %v", err)
+					logging.Errorf(s.logger, "error decompressing data for batch from FIFO: %v", err)
 					continue
 				}
 
@@ -546,7 +547,7 @@ func (s *Service) leaderLoop() (chan struct{}, chan struct{}) {
 					stats.Add(numEventTxFailed, 1)
 
 					if s.transmitMaxRetries != retryForever && nAttempts == s.transmitMaxRetries {
-						s.logger.Printf("failed to send request to endpoint after %d retries, last error: %v", nAttempts, err)
+						logging.Errorf(s.logger, "failed to send request to endpoint after %d retries, last error: %v", nAttempts, err)
 						stats.Add(numDroppedFailedToSend, 1)
 						break
 					}
@@ -608,7 +609,7 @@ func (s *Service) leaderHWMLoop() (chan struct{}, chan struct{}) {
 				// followers get the update even if there are no new events,
 				// or nodes that join the cluster get the current HWM.
 				if err := s.clstr.BroadcastHighWatermark(hwm); err != nil {
-					s.logger.Printf("error broadcasting high watermark to Cluster: %v", err)
+					logging.Warnf(s.logger, "error broadcasting high watermark to Cluster: %v", err)
 				}
 				// While we always broadcast the high watermark, we only prune the
 				// FIFO if it has advanced since the last time we did so. There
@@ -617,7 +618,7 @@ func (s *Service) leaderHWMLoop() (chan struct{}, chan struct{}) {
 					continue
 				}
 				if err := s.fifo.DeleteRange(hwm); err != nil {
-					s.logger.Printf("error deleting events up to high watermark from FIFO: %v", err)
+					logging.Errorf(s.logger, "error deleting events up to high watermark from FIFO: %v", err)
 				}
 				s.hwmLeaderUpdated.Add(1)
 				hwmPersisted = hwm
@@ -649,7 +650,7 @@ func (s *Service) followerLoop() (chan struct{}, chan struct{}) {
 				// successfully sent to the webhook by the cluster. We can
 				// delete all events up and including that point from our FIFO.
 				if err := s.fifo.DeleteRange(hwm); err != nil {
-					s.logger.Printf("error deleting events up to high watermark from FIFO: %v", err)
+					logging.Errorf(s.logger, "error deleting events up to high watermark from FIFO: %v", err)
 				}
 				hwmPersisted = hwm
 				s.highWatermark.Store(hwm)
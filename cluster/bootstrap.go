@@ -11,6 +11,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/random"
 )
 
@@ -96,7 +97,7 @@ func NewBootstrapper(p AddressProvider, client *Client) *Bootstrapper {
 	bs := &Bootstrapper{
 		provider: p,
 		client:   client,
-		logger:   log.New(os.Stderr, "[cluster-bootstrap] ", log.LstdFlags),
+		logger:   logging.New("cluster-bootstrap", os.Stderr),
 		Interval: bootCheckInterval,
 	}
 	return bs
@@ -158,7 +159,7 @@ func (b *Bootstrapper) Boot(ctx context.Context, id, raftAddr string, suf comman
 
 			targets, err := b.provider.Lookup()
 			if err != nil {
-				b.logger.Printf("provider lookup failed %s", err.Error())
+				logging.Warnf(b.logger, "provider lookup failed %s", err.Error())
 			}
 			if len(targets) == 0 {
 				continue
@@ -181,7 +182,7 @@ func (b *Bootstrapper) Boot(ctx context.Context, id, raftAddr string, suf comman
 				// If this is a new cluster, some node will then reach the bootstrap-expect value
 				// first, form the cluster, beating all other nodes to it.
 				if err := b.notify(ctx, targets, id, raftAddr); err != nil {
-					b.logger.Printf("failed to notify all targets: %s (%s, will retry)", targets,
+					logging.Warnf(b.logger, "failed to notify all targets: %s (%s, will retry)", targets,
 						err.Error())
 				} else {
 					b.logger.Printf("succeeded notifying all targets: %s", targets)
@@ -16,10 +16,15 @@ import (
 
 	"github.com/rqlite/rqlite/v10/auth"
 	"github.com/rqlite/rqlite/v10/cluster/proto"
+	rcommand "github.com/rqlite/rqlite/v10/command"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
+	"github.com/rqlite/rqlite/v10/internal/tracing"
 	"github.com/rqlite/rqlite/v10/tcp"
 	"github.com/rqlite/rqlite/v10/tcp/pool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	pb "google.golang.org/protobuf/proto"
 )
 
@@ -204,7 +209,9 @@ func (c *Client) Execute(ctx context.Context, er *command.ExecuteRequest, nodeAd
 		},
 		Credentials: creds,
 	}
+	startT := time.Now()
 	p, nr, err := c.retry(ctx, command, nodeAddr, timeout, retries)
+	clientExecuteHist.RecordSince(startT)
 	stats.Add(numClientExecuteRetries, int64(nr))
 	if err != nil {
 		return nil, 0, err
@@ -236,7 +243,9 @@ func (c *Client) Query(ctx context.Context, qr *command.QueryRequest, nodeAddr s
 		},
 		Credentials: creds,
 	}
+	startT := time.Now()
 	p, nr, err := c.retry(ctx, command, nodeAddr, timeout, retries)
+	clientQueryHist.RecordSince(startT)
 	stats.Add(numClientQueryRetries, int64(nr))
 	if err != nil {
 		return nil, 0, err
@@ -268,7 +277,9 @@ func (c *Client) Request(ctx context.Context, r *command.ExecuteQueryRequest, no
 		},
 		Credentials: creds,
 	}
+	startT := time.Now()
 	p, nr, err := c.retry(ctx, command, nodeAddr, timeout, retries)
+	clientRequestHist.RecordSince(startT)
 	stats.Add(numClientRequestRetries, int64(nr))
 	if err != nil {
 		return nil, 0, 0, err
@@ -327,21 +338,30 @@ func (c *Client) Backup(ctx context.Context, br *command.BackupRequest, nodeAddr
 		return errors.New(a.Error)
 	}
 
-	// The backup stream is unconditionally compressed, so depending on whether
-	// the user requested compression, we may need to decompress the response.
-	var rc io.ReadCloser
-	rc = conn
-	if !br.Compress {
-		gzr, err := gzip.NewReader(conn)
-		if err != nil {
-			return err
-		}
-		gzr.Multistream(false)
-		rc = gzr
-		defer rc.Close()
+	// The backup stream is unconditionally gzip-compressed, so depending on
+	// the compression the user requested, we may need to decompress the
+	// response, and then recompress it with the requested algorithm.
+	compression := rcommand.BackupCompressionToString(rcommand.BackupCompression(br))
+	if compression == rarchive.CompressionGzip {
+		_, err = io.Copy(w, conn)
+		return err
+	}
+	gzr, err := gzip.NewReader(conn)
+	if err != nil {
+		return err
+	}
+	gzr.Multistream(false)
+	defer gzr.Close()
+
+	cw, err := rarchive.NewCompressWriter(w, compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, gzr); err != nil {
+		cw.Close()
+		return err
 	}
-	_, err = io.Copy(w, rc)
-	return err
+	return cw.Close()
 }
 
 // Load loads a SQLite file into the database. If creds is nil, then no
@@ -375,6 +395,37 @@ func (c *Client) Load(ctx context.Context, lr *command.LoadRequest, nodeAddr str
 	return nil
 }
 
+// LoadChunk loads one chunk of a SQLite file into the database at the
+// remote node.
+func (c *Client) LoadChunk(ctx context.Context, lcr *command.LoadChunkRequest, nodeAddr string, creds *proto.Credentials, timeout time.Duration, retries int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	command := &proto.Command{
+		Type: proto.Command_COMMAND_TYPE_LOAD_CHUNK,
+		Request: &proto.Command_LoadChunkRequest{
+			LoadChunkRequest: lcr,
+		},
+		Credentials: creds,
+	}
+	p, nr, err := c.retry(ctx, command, nodeAddr, timeout, retries)
+	stats.Add(numClientLoadRetries, int64(nr))
+	if err != nil {
+		return err
+	}
+
+	a := &proto.CommandLoadChunkResponse{}
+	err = pb.Unmarshal(p, a)
+	if err != nil {
+		return err
+	}
+
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
 // RemoveNode removes a node from the cluster. If creds is nil, then no
 // credential information will be included in the RemoveNode request to the
 // remote node.
@@ -463,6 +514,66 @@ func (c *Client) Stepdown(ctx context.Context, sr *command.StepdownRequest, node
 	return nil
 }
 
+// Checksum requests that the remote node, which must be the leader, checksums
+// the database on every node. The checksums computed by the remote node are
+// returned. If creds is nil, then no credential information will be included
+// in the Checksum request to the remote node.
+func (c *Client) Checksum(ctx context.Context, cr *command.ChecksumRequest, nodeAddr string, creds *proto.Credentials, timeout time.Duration) (*command.ChecksumResult, error) {
+	return c.checksum(ctx, &proto.Command{
+		Type: proto.Command_COMMAND_TYPE_CHECKSUM,
+		Request: &proto.Command_ChecksumRequest{
+			ChecksumRequest: cr,
+		},
+		Credentials: creds,
+	}, nodeAddr, timeout)
+}
+
+// GetChecksum returns the checksums the remote node computed at the given
+// log index. If creds is nil, then no credential information will be included
+// in the GetChecksum request to the remote node.
+func (c *Client) GetChecksum(ctx context.Context, gr *command.ChecksumResultRequest, nodeAddr string, creds *proto.Credentials, timeout time.Duration) (*command.ChecksumResult, error) {
+	return c.checksum(ctx, &proto.Command{
+		Type: proto.Command_COMMAND_TYPE_GET_CHECKSUM,
+		Request: &proto.Command_ChecksumResultRequest{
+			ChecksumResultRequest: gr,
+		},
+		Credentials: creds,
+	}, nodeAddr, timeout)
+}
+
+func (c *Client) checksum(ctx context.Context, command *proto.Command, nodeAddr string, timeout time.Duration) (*command.ChecksumResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := c.dial(nodeAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := writeCommand(conn, command, timeout); err != nil {
+		handleConnError(conn)
+		return nil, err
+	}
+
+	p, err := readResponse(conn, timeout)
+	if err != nil {
+		handleConnError(conn)
+		return nil, err
+	}
+
+	a := &proto.CommandChecksumResponse{}
+	err = pb.Unmarshal(p, a)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Error != "" {
+		return nil, errors.New(a.Error)
+	}
+	return a.Result, nil
+}
+
 // Notify notifies a remote node that this node is ready to bootstrap.
 // If creds is nil, then no credential information will be included in
 // the Notify request to the remote node.
@@ -763,11 +874,19 @@ func (c *Client) dialWithOption(nodeAddr string, forceNew bool) (net.Conn, error
 // retry retries a command on a remote node. It does this so we churn through connections
 // in the pool if we hit an error, as the remote node may have restarted and the pool's
 // connections are now stale.
-func (c *Client) retry(ctx context.Context, command *proto.Command, nodeAddr string, timeout time.Duration, maxRetries int) ([]byte, int, error) {
+func (c *Client) retry(ctx context.Context, command *proto.Command, nodeAddr string, timeout time.Duration, maxRetries int) (_ []byte, nRetries int, retErr error) {
+	ctx, span := tracing.StartChild(ctx, tracer, "cluster.Client "+command.Type.String(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rqlite.node_addr", nodeAddr)))
+	defer func() {
+		span.SetAttributes(attribute.Int("rqlite.retries", nRetries))
+		tracing.End(span, retErr)
+	}()
+	command.TraceContext = tracing.Inject(ctx)
+
 	var p []byte
 	var errOuter error
 	effectiveRetries := max(1, maxRetries)
-	nRetries := 0
 
 	for {
 		if err := ctx.Err(); err != nil {
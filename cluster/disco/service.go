@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/random"
 )
 
@@ -87,7 +88,7 @@ func NewService(c Client, s Store, suf Suffrage) *Service {
 		suf:              suf,
 		RegisterInterval: 3 * time.Second,
 		ReportInterval:   10 * time.Second,
-		logger:           log.New(os.Stderr, "[disco] ", log.LstdFlags),
+		logger:           logging.New("disco", os.Stderr),
 	}
 }
 
@@ -98,7 +99,7 @@ func (s *Service) Register(id, apiAddr, addr string) (bool, string, error) {
 	for {
 		_, _, cRaftAddr, ok, err := s.c.GetLeader()
 		if err != nil {
-			s.logger.Printf("failed to get leader: %s", err.Error())
+			logging.Warnf(s.logger, "failed to get leader: %s", err.Error())
 		}
 		if ok {
 			return false, cRaftAddr, nil
@@ -107,7 +108,7 @@ func (s *Service) Register(id, apiAddr, addr string) (bool, string, error) {
 		if s.suf.IsVoter() {
 			ok, err = s.c.InitializeLeader(id, apiAddr, addr)
 			if err != nil {
-				s.logger.Printf("failed to initialize as Leader: %s", err.Error())
+				logging.Errorf(s.logger, "failed to initialize as Leader: %s", err.Error())
 			}
 			if ok {
 				s.updateContact(time.Now())
@@ -147,7 +148,7 @@ func (s *Service) StartReporting(id, apiAddr, addr string) chan struct{} {
 			select {
 			case <-reportCh:
 				if err := s.c.SetLeader(id, apiAddr, addr); err != nil {
-					s.logger.Printf("failed to update discovery service with Leader details: %s",
+					logging.Warnf(s.logger, "failed to update discovery service with Leader details: %s",
 						err.Error())
 					continue
 				}
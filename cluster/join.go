@@ -9,6 +9,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 var (
@@ -41,7 +42,7 @@ func NewJoiner(client *Client, numAttempts int, attemptInterval time.Duration) *
 		client:          client,
 		numAttempts:     numAttempts,
 		attemptInterval: attemptInterval,
-		logger:          log.New(os.Stderr, "[cluster-join] ", log.LstdFlags),
+		logger:          logging.New("cluster-join", os.Stderr),
 	}
 }
 
@@ -73,12 +74,12 @@ func (j *Joiner) Do(ctx context.Context, targetAddrs []string, id, addr string,
 				if j.creds == nil {
 					helpMsg = " (did you forget to set -join-as?)"
 				}
-				j.logger.Printf("failed to join via node at %s: %s%s", ta, err, helpMsg)
+				logging.Warnf(j.logger, "failed to join via node at %s: %s%s", ta, err, helpMsg)
 			}
 		}
 		if i+1 < j.numAttempts {
 			// This logic message only make sense if performing more than 1 join-attempt.
-			j.logger.Printf("failed to join cluster at %s, sleeping %s before retry", targetAddrs, j.attemptInterval)
+			logging.Warnf(j.logger, "failed to join cluster at %s, sleeping %s before retry", targetAddrs, j.attemptInterval)
 			select {
 			case <-ctx.Done():
 				return "", ErrJoinCanceled
@@ -87,7 +88,7 @@ func (j *Joiner) Do(ctx context.Context, targetAddrs []string, id, addr string,
 			}
 		}
 	}
-	j.logger.Printf("failed to join cluster at %s, after %d attempt(s)", targetAddrs, j.numAttempts)
+	logging.Errorf(j.logger, "failed to join cluster at %s, after %d attempt(s)", targetAddrs, j.numAttempts)
 	return "", ErrJoinFailed
 }
 
@@ -39,6 +39,8 @@ const (
 	Command_COMMAND_TYPE_BACKUP_STREAM         Command_Type = 11
 	Command_COMMAND_TYPE_STEPDOWN              Command_Type = 12
 	Command_COMMAND_TYPE_HIGHWATER_MARK_UPDATE Command_Type = 13
+	Command_COMMAND_TYPE_CHECKSUM              Command_Type = 14
+	Command_COMMAND_TYPE_GET_CHECKSUM          Command_Type = 15
 )
 
 // Enum value maps for Command_Type.
@@ -58,6 +60,8 @@ var (
 		11: "COMMAND_TYPE_BACKUP_STREAM",
 		12: "COMMAND_TYPE_STEPDOWN",
 		13: "COMMAND_TYPE_HIGHWATER_MARK_UPDATE",
+		14: "COMMAND_TYPE_CHECKSUM",
+		15: "COMMAND_TYPE_GET_CHECKSUM",
 	}
 	Command_Type_value = map[string]int32{
 		"COMMAND_TYPE_UNKNOWN":               0,
@@ -74,6 +78,8 @@ var (
 		"COMMAND_TYPE_BACKUP_STREAM":         11,
 		"COMMAND_TYPE_STEPDOWN":              12,
 		"COMMAND_TYPE_HIGHWATER_MARK_UPDATE": 13,
+		"COMMAND_TYPE_CHECKSUM":              14,
+		"COMMAND_TYPE_GET_CHECKSUM":          15,
 	}
 )
 
@@ -108,6 +114,8 @@ type Credentials struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
 	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	CertUser      string                 `protobuf:"bytes,4,opt,name=cert_user,json=certUser,proto3" json:"cert_user,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -156,13 +164,29 @@ func (x *Credentials) GetPassword() string {
 	return ""
 }
 
+func (x *Credentials) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *Credentials) GetCertUser() string {
+	if x != nil {
+		return x.CertUser
+	}
+	return ""
+}
+
 type NodeMeta struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
-	CommitIndex   uint64                 `protobuf:"varint,2,opt,name=commit_index,json=commitIndex,proto3" json:"commit_index,omitempty"`
-	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Url                string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	CommitIndex        uint64                 `protobuf:"varint,2,opt,name=commit_index,json=commitIndex,proto3" json:"commit_index,omitempty"`
+	Version            string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	WriteThrottleLevel uint32                 `protobuf:"varint,4,opt,name=write_throttle_level,json=writeThrottleLevel,proto3" json:"write_throttle_level,omitempty"`
+	WriteRetryAfter    int64                  `protobuf:"varint,5,opt,name=write_retry_after,json=writeRetryAfter,proto3" json:"write_retry_after,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *NodeMeta) Reset() {
@@ -216,6 +240,20 @@ func (x *NodeMeta) GetVersion() string {
 	return ""
 }
 
+func (x *NodeMeta) GetWriteThrottleLevel() uint32 {
+	if x != nil {
+		return x.WriteThrottleLevel
+	}
+	return 0
+}
+
+func (x *NodeMeta) GetWriteRetryAfter() int64 {
+	if x != nil {
+		return x.WriteRetryAfter
+	}
+	return 0
+}
+
 type Command struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	Type  Command_Type           `protobuf:"varint,1,opt,name=type,proto3,enum=cluster.Command_Type" json:"type,omitempty"`
@@ -232,8 +270,13 @@ type Command struct {
 	//	*Command_LoadChunkRequest
 	//	*Command_StepdownRequest
 	//	*Command_HighwaterMarkUpdateRequest
-	Request       isCommand_Request `protobuf_oneof:"request"`
-	Credentials   *Credentials      `protobuf:"bytes,4,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	//	*Command_ChecksumRequest
+	//	*Command_ChecksumResultRequest
+	Request     isCommand_Request `protobuf_oneof:"request"`
+	Credentials *Credentials      `protobuf:"bytes,4,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	// trace_context is the OpenTelemetry trace context of the request, if
+	// it is part of a sampled trace.
+	TraceContext  map[string]string `protobuf:"bytes,16,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -381,6 +424,24 @@ func (x *Command) GetHighwaterMarkUpdateRequest() *HighwaterMarkUpdateRequest {
 	return nil
 }
 
+func (x *Command) GetChecksumRequest() *proto.ChecksumRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Command_ChecksumRequest); ok {
+			return x.ChecksumRequest
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetChecksumResultRequest() *proto.ChecksumResultRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Command_ChecksumResultRequest); ok {
+			return x.ChecksumResultRequest
+		}
+	}
+	return nil
+}
+
 func (x *Command) GetCredentials() *Credentials {
 	if x != nil {
 		return x.Credentials
@@ -388,6 +449,13 @@ func (x *Command) GetCredentials() *Credentials {
 	return nil
 }
 
+func (x *Command) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
 type isCommand_Request interface {
 	isCommand_Request()
 }
@@ -436,6 +504,14 @@ type Command_HighwaterMarkUpdateRequest struct {
 	HighwaterMarkUpdateRequest *HighwaterMarkUpdateRequest `protobuf:"bytes,13,opt,name=highwater_mark_update_request,json=highwaterMarkUpdateRequest,proto3,oneof"`
 }
 
+type Command_ChecksumRequest struct {
+	ChecksumRequest *proto.ChecksumRequest `protobuf:"bytes,14,opt,name=checksum_request,json=checksumRequest,proto3,oneof"`
+}
+
+type Command_ChecksumResultRequest struct {
+	ChecksumResultRequest *proto.ChecksumResultRequest `protobuf:"bytes,15,opt,name=checksum_result_request,json=checksumResultRequest,proto3,oneof"`
+}
+
 func (*Command_ExecuteRequest) isCommand_Request() {}
 
 func (*Command_QueryRequest) isCommand_Request() {}
@@ -458,6 +534,10 @@ func (*Command_StepdownRequest) isCommand_Request() {}
 
 func (*Command_HighwaterMarkUpdateRequest) isCommand_Request() {}
 
+func (*Command_ChecksumRequest) isCommand_Request() {}
+
+func (*Command_ChecksumResultRequest) isCommand_Request() {}
+
 type CommandExecuteResponse struct {
 	state         protoimpl.MessageState        `protogen:"open.v1"`
 	Error         string                        `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
@@ -970,6 +1050,58 @@ func (x *CommandStepdownResponse) GetError() string {
 	return ""
 }
 
+type CommandChecksumResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	Result        *proto.ChecksumResult  `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandChecksumResponse) Reset() {
+	*x = CommandChecksumResponse{}
+	mi := &file_message_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandChecksumResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandChecksumResponse) ProtoMessage() {}
+
+func (x *CommandChecksumResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandChecksumResponse.ProtoReflect.Descriptor instead.
+func (*CommandChecksumResponse) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CommandChecksumResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CommandChecksumResponse) GetResult() *proto.ChecksumResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
 type HighwaterMarkUpdateRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -980,7 +1112,7 @@ type HighwaterMarkUpdateRequest struct {
 
 func (x *HighwaterMarkUpdateRequest) Reset() {
 	*x = HighwaterMarkUpdateRequest{}
-	mi := &file_message_proto_msgTypes[13]
+	mi := &file_message_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -992,7 +1124,7 @@ func (x *HighwaterMarkUpdateRequest) String() string {
 func (*HighwaterMarkUpdateRequest) ProtoMessage() {}
 
 func (x *HighwaterMarkUpdateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[13]
+	mi := &file_message_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1005,7 +1137,7 @@ func (x *HighwaterMarkUpdateRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HighwaterMarkUpdateRequest.ProtoReflect.Descriptor instead.
 func (*HighwaterMarkUpdateRequest) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{13}
+	return file_message_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *HighwaterMarkUpdateRequest) GetNodeId() string {
@@ -1031,7 +1163,7 @@ type HighwaterMarkUpdateResponse struct {
 
 func (x *HighwaterMarkUpdateResponse) Reset() {
 	*x = HighwaterMarkUpdateResponse{}
-	mi := &file_message_proto_msgTypes[14]
+	mi := &file_message_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1043,7 +1175,7 @@ func (x *HighwaterMarkUpdateResponse) String() string {
 func (*HighwaterMarkUpdateResponse) ProtoMessage() {}
 
 func (x *HighwaterMarkUpdateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[14]
+	mi := &file_message_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1056,7 +1188,7 @@ func (x *HighwaterMarkUpdateResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HighwaterMarkUpdateResponse.ProtoReflect.Descriptor instead.
 func (*HighwaterMarkUpdateResponse) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{14}
+	return file_message_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *HighwaterMarkUpdateResponse) GetError() string {
@@ -1070,15 +1202,18 @@ var File_message_proto protoreflect.FileDescriptor
 
 const file_message_proto_rawDesc = "" +
 	"\n" +
-	"\rmessage.proto\x12\acluster\x1a\x1bcommand/proto/command.proto\"E\n" +
+	"\rmessage.proto\x12\acluster\x1a\x1bcommand/proto/command.proto\"x\n" +
 	"\vCredentials\x12\x1a\n" +
 	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
-	"\bpassword\x18\x02 \x01(\tR\bpassword\"Y\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\x12\x1b\n" +
+	"\tcert_user\x18\x04 \x01(\tR\bcertUser\"\xb7\x01\n" +
 	"\bNodeMeta\x12\x10\n" +
 	"\x03url\x18\x01 \x01(\tR\x03url\x12!\n" +
 	"\fcommit_index\x18\x02 \x01(\x04R\vcommitIndex\x12\x18\n" +
-	"\aversion\x18\x03 \x01(\tR\aversion\"\x9c\n" +
-	"\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x120\n" +
+	"\x14write_throttle_level\x18\x04 \x01(\rR\x12writeThrottleLevel\x12*\n" +
+	"\x11write_retry_after\x18\x05 \x01(\x03R\x0fwriteRetryAfter\"\x81\r\n" +
 	"\aCommand\x12)\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x15.cluster.Command.TypeR\x04type\x12B\n" +
 	"\x0fexecute_request\x18\x02 \x01(\v2\x17.command.ExecuteRequestH\x00R\x0eexecuteRequest\x12<\n" +
@@ -1092,8 +1227,14 @@ const file_message_proto_rawDesc = "" +
 	" \x01(\v2\x1c.command.ExecuteQueryRequestH\x00R\x13executeQueryRequest\x12I\n" +
 	"\x12load_chunk_request\x18\v \x01(\v2\x19.command.LoadChunkRequestH\x00R\x10loadChunkRequest\x12E\n" +
 	"\x10stepdown_request\x18\f \x01(\v2\x18.command.StepdownRequestH\x00R\x0fstepdownRequest\x12h\n" +
-	"\x1dhighwater_mark_update_request\x18\r \x01(\v2#.cluster.HighwaterMarkUpdateRequestH\x00R\x1ahighwaterMarkUpdateRequest\x126\n" +
-	"\vcredentials\x18\x04 \x01(\v2\x14.cluster.CredentialsR\vcredentials\"\x8a\x03\n" +
+	"\x1dhighwater_mark_update_request\x18\r \x01(\v2#.cluster.HighwaterMarkUpdateRequestH\x00R\x1ahighwaterMarkUpdateRequest\x12E\n" +
+	"\x10checksum_request\x18\x0e \x01(\v2\x18.command.ChecksumRequestH\x00R\x0fchecksumRequest\x12X\n" +
+	"\x17checksum_result_request\x18\x0f \x01(\v2\x1e.command.ChecksumResultRequestH\x00R\x15checksumResultRequest\x126\n" +
+	"\vcredentials\x18\x04 \x01(\v2\x14.cluster.CredentialsR\vcredentials\x12G\n" +
+	"\rtrace_context\x18\x10 \x03(\v2\".cluster.Command.TraceContextEntryR\ftraceContext\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc4\x03\n" +
 	"\x04Type\x12\x18\n" +
 	"\x14COMMAND_TYPE_UNKNOWN\x10\x00\x12\x1e\n" +
 	"\x1aCOMMAND_TYPE_GET_NODE_META\x10\x01\x12\x18\n" +
@@ -1109,7 +1250,9 @@ const file_message_proto_rawDesc = "" +
 	"\x12\x1e\n" +
 	"\x1aCOMMAND_TYPE_BACKUP_STREAM\x10\v\x12\x19\n" +
 	"\x15COMMAND_TYPE_STEPDOWN\x10\f\x12&\n" +
-	"\"COMMAND_TYPE_HIGHWATER_MARK_UPDATE\x10\rB\t\n" +
+	"\"COMMAND_TYPE_HIGHWATER_MARK_UPDATE\x10\r\x12\x19\n" +
+	"\x15COMMAND_TYPE_CHECKSUM\x10\x0e\x12\x1d\n" +
+	"\x19COMMAND_TYPE_GET_CHECKSUM\x10\x0fB\t\n" +
 	"\arequest\"\x87\x01\n" +
 	"\x16CommandExecuteResponse\x12\x14\n" +
 	"\x05error\x18\x01 \x01(\tR\x05error\x129\n" +
@@ -1139,7 +1282,10 @@ const file_message_proto_rawDesc = "" +
 	"\x05error\x18\x01 \x01(\tR\x05error\x12\x16\n" +
 	"\x06leader\x18\x02 \x01(\tR\x06leader\"/\n" +
 	"\x17CommandStepdownResponse\x12\x14\n" +
-	"\x05error\x18\x01 \x01(\tR\x05error\"\\\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\"`\n" +
+	"\x17CommandChecksumResponse\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\x12/\n" +
+	"\x06result\x18\x02 \x01(\v2\x17.command.ChecksumResultR\x06result\"\\\n" +
 	"\x1aHighwaterMarkUpdateRequest\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12%\n" +
 	"\x0ehighwater_mark\x18\x02 \x01(\x04R\rhighwaterMark\"3\n" +
@@ -1159,7 +1305,7 @@ func file_message_proto_rawDescGZIP() []byte {
 }
 
 var file_message_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_message_proto_goTypes = []any{
 	(Command_Type)(0),                   // 0: cluster.Command.Type
 	(*Credentials)(nil),                 // 1: cluster.Credentials
@@ -1175,43 +1321,52 @@ var file_message_proto_goTypes = []any{
 	(*CommandNotifyResponse)(nil),       // 11: cluster.CommandNotifyResponse
 	(*CommandJoinResponse)(nil),         // 12: cluster.CommandJoinResponse
 	(*CommandStepdownResponse)(nil),     // 13: cluster.CommandStepdownResponse
-	(*HighwaterMarkUpdateRequest)(nil),  // 14: cluster.HighwaterMarkUpdateRequest
-	(*HighwaterMarkUpdateResponse)(nil), // 15: cluster.HighwaterMarkUpdateResponse
-	(*proto.ExecuteRequest)(nil),        // 16: command.ExecuteRequest
-	(*proto.QueryRequest)(nil),          // 17: command.QueryRequest
-	(*proto.BackupRequest)(nil),         // 18: command.BackupRequest
-	(*proto.LoadRequest)(nil),           // 19: command.LoadRequest
-	(*proto.RemoveNodeRequest)(nil),     // 20: command.RemoveNodeRequest
-	(*proto.NotifyRequest)(nil),         // 21: command.NotifyRequest
-	(*proto.JoinRequest)(nil),           // 22: command.JoinRequest
-	(*proto.ExecuteQueryRequest)(nil),   // 23: command.ExecuteQueryRequest
-	(*proto.LoadChunkRequest)(nil),      // 24: command.LoadChunkRequest
-	(*proto.StepdownRequest)(nil),       // 25: command.StepdownRequest
-	(*proto.ExecuteQueryResponse)(nil),  // 26: command.ExecuteQueryResponse
-	(*proto.QueryRows)(nil),             // 27: command.QueryRows
+	(*CommandChecksumResponse)(nil),     // 14: cluster.CommandChecksumResponse
+	(*HighwaterMarkUpdateRequest)(nil),  // 15: cluster.HighwaterMarkUpdateRequest
+	(*HighwaterMarkUpdateResponse)(nil), // 16: cluster.HighwaterMarkUpdateResponse
+	nil,                                 // 17: cluster.Command.TraceContextEntry
+	(*proto.ExecuteRequest)(nil),        // 18: command.ExecuteRequest
+	(*proto.QueryRequest)(nil),          // 19: command.QueryRequest
+	(*proto.BackupRequest)(nil),         // 20: command.BackupRequest
+	(*proto.LoadRequest)(nil),           // 21: command.LoadRequest
+	(*proto.RemoveNodeRequest)(nil),     // 22: command.RemoveNodeRequest
+	(*proto.NotifyRequest)(nil),         // 23: command.NotifyRequest
+	(*proto.JoinRequest)(nil),           // 24: command.JoinRequest
+	(*proto.ExecuteQueryRequest)(nil),   // 25: command.ExecuteQueryRequest
+	(*proto.LoadChunkRequest)(nil),      // 26: command.LoadChunkRequest
+	(*proto.StepdownRequest)(nil),       // 27: command.StepdownRequest
+	(*proto.ChecksumRequest)(nil),       // 28: command.ChecksumRequest
+	(*proto.ChecksumResultRequest)(nil), // 29: command.ChecksumResultRequest
+	(*proto.ExecuteQueryResponse)(nil),  // 30: command.ExecuteQueryResponse
+	(*proto.QueryRows)(nil),             // 31: command.QueryRows
+	(*proto.ChecksumResult)(nil),        // 32: command.ChecksumResult
 }
 var file_message_proto_depIdxs = []int32{
 	0,  // 0: cluster.Command.type:type_name -> cluster.Command.Type
-	16, // 1: cluster.Command.execute_request:type_name -> command.ExecuteRequest
-	17, // 2: cluster.Command.query_request:type_name -> command.QueryRequest
-	18, // 3: cluster.Command.backup_request:type_name -> command.BackupRequest
-	19, // 4: cluster.Command.load_request:type_name -> command.LoadRequest
-	20, // 5: cluster.Command.remove_node_request:type_name -> command.RemoveNodeRequest
-	21, // 6: cluster.Command.notify_request:type_name -> command.NotifyRequest
-	22, // 7: cluster.Command.join_request:type_name -> command.JoinRequest
-	23, // 8: cluster.Command.execute_query_request:type_name -> command.ExecuteQueryRequest
-	24, // 9: cluster.Command.load_chunk_request:type_name -> command.LoadChunkRequest
-	25, // 10: cluster.Command.stepdown_request:type_name -> command.StepdownRequest
-	14, // 11: cluster.Command.highwater_mark_update_request:type_name -> cluster.HighwaterMarkUpdateRequest
-	1,  // 12: cluster.Command.credentials:type_name -> cluster.Credentials
-	26, // 13: cluster.CommandExecuteResponse.response:type_name -> command.ExecuteQueryResponse
-	27, // 14: cluster.CommandQueryResponse.rows:type_name -> command.QueryRows
-	26, // 15: cluster.CommandRequestResponse.response:type_name -> command.ExecuteQueryResponse
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	18, // 1: cluster.Command.execute_request:type_name -> command.ExecuteRequest
+	19, // 2: cluster.Command.query_request:type_name -> command.QueryRequest
+	20, // 3: cluster.Command.backup_request:type_name -> command.BackupRequest
+	21, // 4: cluster.Command.load_request:type_name -> command.LoadRequest
+	22, // 5: cluster.Command.remove_node_request:type_name -> command.RemoveNodeRequest
+	23, // 6: cluster.Command.notify_request:type_name -> command.NotifyRequest
+	24, // 7: cluster.Command.join_request:type_name -> command.JoinRequest
+	25, // 8: cluster.Command.execute_query_request:type_name -> command.ExecuteQueryRequest
+	26, // 9: cluster.Command.load_chunk_request:type_name -> command.LoadChunkRequest
+	27, // 10: cluster.Command.stepdown_request:type_name -> command.StepdownRequest
+	15, // 11: cluster.Command.highwater_mark_update_request:type_name -> cluster.HighwaterMarkUpdateRequest
+	28, // 12: cluster.Command.checksum_request:type_name -> command.ChecksumRequest
+	29, // 13: cluster.Command.checksum_result_request:type_name -> command.ChecksumResultRequest
+	1,  // 14: cluster.Command.credentials:type_name -> cluster.Credentials
+	17, // 15: cluster.Command.trace_context:type_name -> cluster.Command.TraceContextEntry
+	30, // 16: cluster.CommandExecuteResponse.response:type_name -> command.ExecuteQueryResponse
+	31, // 17: cluster.CommandQueryResponse.rows:type_name -> command.QueryRows
+	30, // 18: cluster.CommandRequestResponse.response:type_name -> command.ExecuteQueryResponse
+	32, // 19: cluster.CommandChecksumResponse.result:type_name -> command.ChecksumResult
+	20, // [20:20] is the sub-list for method output_type
+	20, // [20:20] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_message_proto_init() }
@@ -1231,6 +1386,8 @@ func file_message_proto_init() {
 		(*Command_LoadChunkRequest)(nil),
 		(*Command_StepdownRequest)(nil),
 		(*Command_HighwaterMarkUpdateRequest)(nil),
+		(*Command_ChecksumRequest)(nil),
+		(*Command_ChecksumResultRequest)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1238,7 +1395,7 @@ func file_message_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_message_proto_rawDesc), len(file_message_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   15,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -8,6 +8,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 const (
@@ -37,7 +38,7 @@ func NewRemover(client *Client, timeout time.Duration, control Control) *Remover
 		client:  client,
 		timeout: timeout,
 		control: control,
-		log:     log.New(os.Stderr, "[cluster-remove] ", log.LstdFlags),
+		log:     logging.New("cluster-remove", os.Stderr),
 	}
 }
 
@@ -65,7 +66,7 @@ func (r *Remover) Do(ctx context.Context, id string, confirm bool) error {
 
 			r.log.Printf("removing node %s from cluster via leader at %s", id, laddr)
 			if innerErr = r.client.RemoveNode(ctx, rn, laddr, r.creds, r.timeout); innerErr != nil {
-				r.log.Printf("failed to remove node %s from cluster via leader at %s: %s", id, laddr, innerErr)
+				logging.Warnf(r.log, "failed to remove node %s from cluster via leader at %s: %s", id, laddr, innerErr)
 				return innerErr
 			}
 			return nil
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
@@ -20,7 +23,12 @@ import (
 	"github.com/rqlite/rqlite/v10/auth"
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/internal/latency"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
+	"github.com/rqlite/rqlite/v10/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 	pb "google.golang.org/protobuf/proto"
 )
 
@@ -28,6 +36,15 @@ var (
 	// stats captures stats for the Cluster service.
 	stats *expvar.Map
 
+	// tracer traces the requests sent to, and handled by, the Cluster service.
+	tracer = tracing.Tracer("cluster")
+
+	// Latency histograms of requests forwarded by the Client, served as part
+	// of stats.
+	clientExecuteHist = latency.NewHistogram(0)
+	clientQueryHist   = latency.NewHistogram(0)
+	clientRequestHist = latency.NewHistogram(0)
+
 	// ErrServiceOpen is returned when the service is already open.
 	ErrServiceOpen = errors.New("service already open")
 )
@@ -40,10 +57,13 @@ const (
 	numRequestRequest      = "num_request_req"
 	numBackupRequest       = "num_backup_req"
 	numLoadRequest         = "num_load_req"
+	numLoadChunkRequest    = "num_load_chunk_req"
 	numRemoveNodeRequest   = "num_remove_node_req"
 	numNotifyRequest       = "num_notify_req"
 	numJoinRequest         = "num_join_req"
 	numStepdownRequest     = "num_stepdown_req"
+	numChecksumRequest     = "num_checksum_req"
+	numGetChecksumRequest  = "num_get_checksum_req"
 	numBroadcastHWMRequest = "num_broadcast_hwm_req"
 	numHWMUpdateDropped    = "num_hwm_update_dropped"
 	numConnRejected        = "num_conn_rejected"
@@ -57,6 +77,9 @@ const (
 	numClientReadTimeouts       = "num_client_read_timeouts"
 	numClientWriteTimeouts      = "num_client_write_timeouts"
 	numClientForceNewConn       = "num_client_force_new_conn"
+	clientExecuteLatency        = "client_execute_latency"
+	clientQueryLatency          = "client_query_latency"
+	clientRequestLatency        = "client_request_latency"
 
 	// Client stats for this package.
 	numGetNodeAPIRequestLocal = "num_get_node_api_req_local"
@@ -78,6 +101,14 @@ const (
 	// maxConcurrentConns bounds the number of connections the service handles
 	// concurrently, preventing connection floods from spawning unbounded goroutines.
 	maxConcurrentConns = 512
+
+	// maxAnalysisCacheSize is the maximum number of named statements whose
+	// analysis is cached.
+	maxAnalysisCacheSize = 1024
+
+	// checksumWaitTimeout is the maximum time to wait for this node to
+	// apply a checksum command, when its checksums are requested.
+	checksumWaitTimeout = 10 * time.Second
 )
 
 func init() {
@@ -93,11 +124,14 @@ func ResetStats() {
 	stats.Add(numRequestRequest, 0)
 	stats.Add(numBackupRequest, 0)
 	stats.Add(numLoadRequest, 0)
+	stats.Add(numLoadChunkRequest, 0)
 	stats.Add(numRemoveNodeRequest, 0)
 	stats.Add(numGetNodeAPIRequestLocal, 0)
 	stats.Add(numNotifyRequest, 0)
 	stats.Add(numJoinRequest, 0)
 	stats.Add(numStepdownRequest, 0)
+	stats.Add(numChecksumRequest, 0)
+	stats.Add(numGetChecksumRequest, 0)
 	stats.Add(numBroadcastHWMRequest, 0)
 	stats.Add(numHWMUpdateDropped, 0)
 	stats.Add(numClientRetries, 0)
@@ -110,6 +144,12 @@ func ResetStats() {
 	stats.Add(numClientWriteTimeouts, 0)
 	stats.Add(numClientForceNewConn, 0)
 	stats.Add(numConnRejected, 0)
+	clientExecuteHist.Reset()
+	stats.Set(clientExecuteLatency, clientExecuteHist)
+	clientQueryHist.Reset()
+	stats.Set(clientQueryLatency, clientQueryHist)
+	clientRequestHist.Reset()
+	stats.Set(clientRequestLatency, clientRequestHist)
 }
 
 // Dialer is the interface dialers must implement.
@@ -135,6 +175,16 @@ type Database interface {
 
 	// Load an entire SQLite file into the database
 	Load(ctx context.Context, lr *command.LoadRequest) error
+
+	// LoadChunk loads one chunk of a SQLite file into the database.
+	LoadChunk(ctx context.Context, lcr *command.LoadChunkRequest) error
+
+	// Checksum checksums the database on every node, at the same log index.
+	Checksum(ctx context.Context, cr *command.ChecksumRequest) (*command.ChecksumResult, error)
+
+	// GetChecksum returns the checksums computed by this node at the given
+	// log index.
+	GetChecksum(ctx context.Context, gr *command.ChecksumResultRequest) (*command.ChecksumResult, error)
 }
 
 // Manager is the interface node-management systems must implement
@@ -145,6 +195,10 @@ type Manager interface {
 	// CommitIndex returns the Raft commit index of the cluster.
 	CommitIndex() (uint64, error)
 
+	// WriteThrottle returns the current throttle level of writes, and if
+	// writes are being refused, how long clients should wait before retrying.
+	WriteThrottle() (level int, retryAfter time.Duration)
+
 	// Remove removes the node, given by id, from the cluster
 	Remove(ctx context.Context, rn *command.RemoveNodeRequest) error
 
@@ -165,6 +219,18 @@ type Manager interface {
 type CredentialStore interface {
 	// AA authenticates and checks authorization for the given perm.
 	AA(username, password, perm string) bool
+
+	// AAToken authenticates the bearer token and checks authorization for
+	// the given perm.
+	AAToken(token, perm string) bool
+
+	// AACertUser checks authorization for the given perm of the user to
+	// which a verified client certificate was mapped.
+	AACertUser(username, perm string) bool
+
+	// CertUser returns the user to which the given verified client
+	// certificate maps, if any.
+	CertUser(cert *x509.Certificate) (string, bool)
 }
 
 // Service provides information about the node and cluster.
@@ -176,6 +242,11 @@ type Service struct {
 	mgr Manager  // The cluster management system.
 
 	credentialStore CredentialStore
+	nodeCN          string // Common Name identifying the certificates of nodes.
+
+	authorizer    *sql.Authorizer
+	lookupFn      func(name string) (string, error) // Returns the SQL of named statements.
+	analysisCache *sql.AnalysisCache
 
 	mu      sync.RWMutex
 	https   bool              // Serving HTTPS?
@@ -206,11 +277,12 @@ func New(ln net.Listener, db Database, m Manager, credentialStore CredentialStor
 		addr:            ln.Addr(),
 		db:              db,
 		mgr:             m,
-		logger:          log.New(os.Stderr, "[cluster] ", log.LstdFlags),
+		logger:          logging.New("cluster", os.Stderr),
 		credentialStore: credentialStore,
 		connTimeout:     connReadTimeout,
 		connLimit:       maxConcurrentConns,
 		open:            rsync.NewAtomicBool(),
+		analysisCache:   sql.NewAnalysisCache(maxAnalysisCacheSize),
 	}
 }
 
@@ -263,6 +335,23 @@ func (s *Service) SetAPIAddr(addr string) {
 	s.apiAddr = addr
 }
 
+// SetAuthorizer sets the Authorizer which checks that the statements of
+// requests forwarded from other nodes only access the tables the user may.
+// lookupFn returns the SQL of named statements. It must be called before the
+// service is opened.
+func (s *Service) SetAuthorizer(a *sql.Authorizer, lookupFn func(name string) (string, error)) {
+	s.authorizer = a
+	s.lookupFn = lookupFn
+}
+
+// SetNodeCommonName sets the Common Name which identifies the certificates
+// of other nodes. Only peers verified by mutual TLS, presenting a certificate
+// with this Common Name, may forward the users of the client certificates
+// they verified. It must be called before the service is opened.
+func (s *Service) SetNodeCommonName(cn string) {
+	s.nodeCN = cn
+}
+
 // GetAPIAddr returns the previously-set API address
 func (s *Service) GetAPIAddr() string {
 	s.mu.RLock()
@@ -342,11 +431,29 @@ func (s *Service) serve() error {
 	}
 }
 
+// startSpan starts a span for the handling of c, continuing the trace of
+// the node which sent it.
+func startSpan(c *proto.Command) (context.Context, trace.Span) {
+	ctx := tracing.Extract(context.Background(), c.TraceContext)
+	return tracing.StartChild(ctx, tracer, "cluster.Service "+c.Type.String(),
+		trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// endSpan ends a span started by startSpan, recording the error, if any,
+// returned to the node which sent the request.
+func endSpan(span trace.Span, errMsg string) {
+	var err error
+	if errMsg != "" {
+		err = errors.New(errMsg)
+	}
+	tracing.End(span, err)
+}
+
 func (s *Service) checkCommandPerm(c *proto.Command, perm string) bool {
 	if s.credentialStore == nil {
 		return true
 	}
-	return s.credentialStore.AA(c.Credentials.GetUsername(), c.Credentials.GetPassword(), perm)
+	return s.aa(c.Credentials, perm)
 }
 
 func (s *Service) checkCommandPermAll(c *proto.Command, perms ...string) bool {
@@ -355,13 +462,176 @@ func (s *Service) checkCommandPermAll(c *proto.Command, perms ...string) bool {
 	}
 
 	for _, perm := range perms {
-		if !s.credentialStore.AA(c.Credentials.GetUsername(), c.Credentials.GetPassword(), perm) {
+		if !s.aa(c.Credentials, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCommandPermOrNamed checks the credentials of c have all the given
+// perms. If they do not, but the user may run named statements and every
+// statement is named, the statements are accepted, but are first replaced by
+// those registered under their names, so that the SQL of any statement the
+// forwarding node expanded cannot have been changed.
+func (s *Service) checkCommandPermOrNamed(c *proto.Command, stmts []*command.Statement, perms ...string) (bool, error) {
+	if s.checkCommandPermAll(c, perms...) {
+		return true, nil
+	}
+	if s.lookupFn == nil || !allNamed(stmts) || !s.checkCommandPerm(c, auth.PermNamedStatements) {
+		return false, nil
+	}
+	for _, stmt := range stmts {
+		stmt.Sql = ""
+		stmt.ForceQuery = false
+		stmt.SqlExplain = false
+		stmt.DefaultTime = 0
+		stmt.DefaultSeed = 0
+	}
+	if err := sql.ExpandNamed(stmts, s.lookupFn, s.analysisCache); err != nil {
+		return false, err
+	}
+	if err := sql.Process(stmts, true, true); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// allNamed returns whether every statement in stmts is a named statement.
+func allNamed(stmts []*command.Statement) bool {
+	for _, stmt := range stmts {
+		if stmt.Name == "" {
 			return false
 		}
 	}
 	return true
 }
 
+// aa authenticates and authorizes the given credentials, which carry a
+// bearer token, the user mapped to a client certificate, or a username and
+// password.
+func (s *Service) aa(creds *proto.Credentials, perm string) bool {
+	if token := creds.GetToken(); token != "" {
+		return s.credentialStore.AAToken(token, perm)
+	}
+	if username := creds.GetCertUser(); username != "" {
+		return s.credentialStore.AACertUser(username, perm)
+	}
+	return s.credentialStore.AA(creds.GetUsername(), creds.GetPassword(), perm)
+}
+
+// systemTablePerms are the perms required to change each table managed by
+// rqlite, those of the endpoints which manage them.
+var systemTablePerms = map[string]string{
+	auth.UsersTable:     auth.PermUsers,
+	sql.StatementsTable: auth.PermStatements,
+}
+
+// authorize checks the statements of a forwarded request with the Authorizer,
+// for the user whose credentials the request carries, as the node which
+// received the request did. Statements which refer to a table managed by
+// rqlite are refused, unless write is true, the statement is one with which
+// the endpoint managing the table changes it, and the user has the perm of
+// that endpoint, since that endpoint forwards its changes to the Leader.
+func (s *Service) authorize(c *proto.Command, stmts []*command.Statement, write bool) error {
+	checked := make([]*command.Statement, len(stmts))
+	for i, stmt := range stmts {
+		checked[i] = stmt
+		q := stmt.Sql
+		if q == "" && stmt.Name != "" && s.lookupFn != nil {
+			q, _ = s.lookupFn(stmt.Name)
+		}
+		t := sql.ReferencesSystemTable(q)
+		if t == "" {
+			continue
+		}
+		if !write || sql.SystemTableChange(stmt) != t ||
+			!s.checkCommandPerm(c, systemTablePerms[t]) || !s.grantable(c, stmt) {
+			return &sql.ACLError{Denials: []string{fmt.Sprintf("statement %d: access to table %s not permitted", i, t)}}
+		}
+		// Leave a placeholder, so the Authorizer reports denials by position.
+		checked[i] = &command.Statement{}
+	}
+
+	creds := c.Credentials
+	if token := creds.GetToken(); token != "" {
+		return s.authorizer.CheckToken(token, checked, s.lookupFn)
+	}
+	if username := creds.GetCertUser(); username != "" {
+		return s.authorizer.CheckCertUser(username, checked, s.lookupFn)
+	}
+	return s.authorizer.Check(creds.GetUsername(), creds.GetPassword(), checked, s.lookupFn)
+}
+
+// grantable returns whether the user whose credentials c carries may store the
+// user set by stmt, as the endpoint which manages users checks. The user must
+// be valid, with a well-formed password hash, and have only perms held by the
+// user making the change. Any other statement is grantable.
+func (s *Service) grantable(c *proto.Command, stmt *command.Statement) bool {
+	if stmt.Sql != sql.PutUserSQL && stmt.Sql != sql.UpdateUserSQL {
+		return true
+	}
+	var cred auth.Credential
+	if err := json.Unmarshal([]byte(stmt.Parameters[1].GetS()), &cred); err != nil {
+		return false
+	}
+	cred.Username = stmt.Parameters[0].GetS()
+	if cred.Validate() != nil {
+		return false
+	}
+	if stmt.Sql == sql.PutUserSQL && (!auth.IsHashed(cred.Password) || auth.ValidateHash(cred.Password) != nil) {
+		return false
+	}
+	if stmt.Sql == sql.UpdateUserSQL && cred.Password != "" {
+		return false
+	}
+	for _, perm := range cred.Perms {
+		if !s.checkCommandPerm(c, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifiedPeerCert returns the certificate of the peer, if conn is a TLS
+// connection and the certificate was verified. conn may wrap the TLS
+// connection, as those accepted by a tcp.Mux do, if it exposes the TLS state.
+func verifiedPeerCert(conn net.Conn) *x509.Certificate {
+	tc, ok := conn.(interface{ ConnectionState() tls.ConnectionState })
+	if !ok {
+		return nil
+	}
+	chains := tc.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+	return chains[0][0]
+}
+
+// setCertUser sets the certificate user of the command. Only other nodes may
+// vouch for the users of the client certificates they verified, so for any
+// other peer the forwarded user is replaced by the user to which the peer's
+// own verified certificate maps, if the command carries no other credentials.
+func (s *Service) setCertUser(conn net.Conn, c *proto.Command) {
+	cert := verifiedPeerCert(conn)
+	if cert != nil && s.nodeCN != "" && cert.Subject.CommonName == s.nodeCN {
+		return
+	}
+	if c.Credentials != nil {
+		c.Credentials.CertUser = ""
+	}
+	if cert == nil || s.credentialStore == nil ||
+		c.Credentials.GetToken() != "" || c.Credentials.GetUsername() != "" {
+		return
+	}
+	if user, ok := s.credentialStore.CertUser(cert); ok {
+		if c.Credentials == nil {
+			c.Credentials = &proto.Credentials{}
+		}
+		c.Credentials.CertUser = user
+	}
+}
+
 func (s *Service) handleConn(conn net.Conn) {
 	defer conn.Close()
 
@@ -395,6 +665,7 @@ func (s *Service) handleConn(conn net.Conn) {
 			conn.Close()
 			return
 		}
+		s.setCertUser(conn, c)
 
 		switch c.Type {
 		case proto.Command_COMMAND_TYPE_GET_NODE_META:
@@ -404,10 +675,13 @@ func (s *Service) handleConn(conn net.Conn) {
 				conn.Close()
 				return
 			}
+			level, retryAfter := s.mgr.WriteThrottle()
 			p, err = pb.Marshal(&proto.NodeMeta{
-				Url:         s.GetNodeAPIURL(),
-				CommitIndex: ci,
-				Version:     s.GetVersion(),
+				Url:                s.GetNodeAPIURL(),
+				CommitIndex:        ci,
+				Version:            s.GetVersion(),
+				WriteThrottleLevel: uint32(level),
+				WriteRetryAfter:    retryAfter.Nanoseconds(),
 			})
 			if err != nil {
 				conn.Close()
@@ -421,14 +695,19 @@ func (s *Service) handleConn(conn net.Conn) {
 		case proto.Command_COMMAND_TYPE_EXECUTE:
 			stats.Add(numExecuteRequest, 1)
 			resp := &proto.CommandExecuteResponse{}
+			ctx, span := startSpan(c)
 
 			er := c.GetExecuteRequest()
 			if er == nil {
 				resp.Error = "ExecuteRequest is nil"
-			} else if !s.checkCommandPerm(c, auth.PermExecute) {
+			} else if ok, err := s.checkCommandPermOrNamed(c, er.GetRequest().GetStatements(), auth.PermExecute); err != nil {
+				resp.Error = err.Error()
+			} else if !ok {
 				resp.Error = "unauthorized"
+			} else if err := s.authorize(c, er.GetRequest().GetStatements(), true); err != nil {
+				resp.Error = err.Error()
 			} else {
-				res, idx, err := s.db.Execute(context.Background(), er)
+				res, idx, err := s.db.Execute(ctx, er)
 				if err != nil {
 					resp.Error = err.Error()
 				} else {
@@ -436,6 +715,7 @@ func (s *Service) handleConn(conn net.Conn) {
 					resp.RaftIndex = idx
 				}
 			}
+			endSpan(span, resp.Error)
 			if err := marshalAndWrite(conn, resp); err != nil {
 				return
 			}
@@ -443,14 +723,19 @@ func (s *Service) handleConn(conn net.Conn) {
 		case proto.Command_COMMAND_TYPE_QUERY:
 			stats.Add(numQueryRequest, 1)
 			resp := &proto.CommandQueryResponse{}
+			ctx, span := startSpan(c)
 
 			qr := c.GetQueryRequest()
 			if qr == nil {
 				resp.Error = "QueryRequest is nil"
-			} else if !s.checkCommandPerm(c, auth.PermQuery) {
+			} else if ok, err := s.checkCommandPermOrNamed(c, qr.GetRequest().GetStatements(), auth.PermQuery); err != nil {
+				resp.Error = err.Error()
+			} else if !ok {
 				resp.Error = "unauthorized"
+			} else if err := s.authorize(c, qr.GetRequest().GetStatements(), false); err != nil {
+				resp.Error = err.Error()
 			} else {
-				res, _, idx, err := s.db.Query(context.Background(), qr)
+				res, _, idx, err := s.db.Query(ctx, qr)
 				if err != nil {
 					resp.Error = err.Error()
 				} else {
@@ -458,6 +743,7 @@ func (s *Service) handleConn(conn net.Conn) {
 					resp.RaftIndex = idx
 				}
 			}
+			endSpan(span, resp.Error)
 			if err := marshalAndWrite(conn, resp); err != nil {
 				return
 			}
@@ -465,14 +751,19 @@ func (s *Service) handleConn(conn net.Conn) {
 		case proto.Command_COMMAND_TYPE_REQUEST:
 			stats.Add(numRequestRequest, 1)
 			resp := &proto.CommandRequestResponse{}
+			ctx, span := startSpan(c)
 
 			rr := c.GetExecuteQueryRequest()
 			if rr == nil {
 				resp.Error = "RequestRequest is nil"
-			} else if !s.checkCommandPermAll(c, auth.PermQuery, auth.PermExecute) {
+			} else if ok, err := s.checkCommandPermOrNamed(c, rr.GetRequest().GetStatements(), auth.PermQuery, auth.PermExecute); err != nil {
+				resp.Error = err.Error()
+			} else if !ok {
 				resp.Error = "unauthorized"
+			} else if err := s.authorize(c, rr.GetRequest().GetStatements(), false); err != nil {
+				resp.Error = err.Error()
 			} else {
-				res, numRW, idx, err := s.db.Request(context.Background(), rr)
+				res, numRW, idx, err := s.db.Request(ctx, rr)
 				if err != nil {
 					resp.Error = err.Error()
 				} else {
@@ -481,6 +772,7 @@ func (s *Service) handleConn(conn net.Conn) {
 					resp.RaftIndex = idx
 				}
 			}
+			endSpan(span, resp.Error)
 			if err := marshalAndWrite(conn, resp); err != nil {
 				return
 			}
@@ -542,8 +834,9 @@ func (s *Service) handleConn(conn net.Conn) {
 			// can easily detect the end of the stream, as well as saving
 			// space on the wire.
 			br.Compress = true
+			br.Compression = command.BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP
 			if err := s.db.Backup(context.Background(), br, conn); err != nil {
-				s.logger.Printf("failed to stream backup: %s", err.Error())
+				logging.Errorf(s.logger, "failed to stream backup: %s", err.Error())
 				return
 			}
 
@@ -567,8 +860,30 @@ func (s *Service) handleConn(conn net.Conn) {
 			}
 
 		case proto.Command_COMMAND_TYPE_LOAD_CHUNK:
-			resp := &proto.CommandLoadChunkResponse{
-				Error: "unsupported",
+			stats.Add(numLoadChunkRequest, 1)
+			resp := &proto.CommandLoadChunkResponse{}
+
+			lcr := c.GetLoadChunkRequest()
+			if lcr == nil {
+				resp.Error = "LoadChunkRequest is nil"
+			} else if lcr.ImportAccess != "" {
+				// The chunk carries a batch of an import, which may only
+				// access tables as the import's access statement does. No
+				// import may refer to a table managed by rqlite.
+				access := []*command.Statement{{Sql: lcr.ImportAccess}}
+				if !s.checkCommandPermAll(c, auth.PermExecute, auth.PermQuery) {
+					resp.Error = "unauthorized"
+				} else if err := s.authorize(c, access, false); err != nil {
+					resp.Error = err.Error()
+				} else if err := s.db.LoadChunk(context.Background(), lcr); err != nil {
+					resp.Error = fmt.Sprintf("remote node failed to import chunk: %s", err.Error())
+				}
+			} else if !s.checkCommandPerm(c, auth.PermLoad) {
+				resp.Error = "unauthorized"
+			} else {
+				if err := s.db.LoadChunk(context.Background(), lcr); err != nil {
+					resp.Error = fmt.Sprintf("remote node failed to load chunk: %s", err.Error())
+				}
 			}
 			if err := marshalAndWrite(conn, resp); err != nil {
 				return
@@ -658,6 +973,50 @@ func (s *Service) handleConn(conn net.Conn) {
 				return
 			}
 
+		case proto.Command_COMMAND_TYPE_CHECKSUM:
+			stats.Add(numChecksumRequest, 1)
+			resp := &proto.CommandChecksumResponse{}
+
+			cr := c.GetChecksumRequest()
+			if cr == nil {
+				resp.Error = "ChecksumRequest is nil"
+			} else if !s.checkCommandPerm(c, auth.PermStatus) {
+				resp.Error = "unauthorized"
+			} else {
+				res, err := s.db.Checksum(context.Background(), cr)
+				if err != nil {
+					resp.Error = err.Error()
+				} else {
+					resp.Result = res
+				}
+			}
+			if err := marshalAndWrite(conn, resp); err != nil {
+				return
+			}
+
+		case proto.Command_COMMAND_TYPE_GET_CHECKSUM:
+			stats.Add(numGetChecksumRequest, 1)
+			resp := &proto.CommandChecksumResponse{}
+
+			gr := c.GetChecksumResultRequest()
+			if gr == nil {
+				resp.Error = "ChecksumResultRequest is nil"
+			} else if !s.checkCommandPerm(c, auth.PermStatus) {
+				resp.Error = "unauthorized"
+			} else {
+				ctx, cancel := context.WithTimeout(context.Background(), checksumWaitTimeout)
+				res, err := s.db.GetChecksum(ctx, gr)
+				cancel()
+				if err != nil {
+					resp.Error = err.Error()
+				} else {
+					resp.Result = res
+				}
+			}
+			if err := marshalAndWrite(conn, resp); err != nil {
+				return
+			}
+
 		case proto.Command_COMMAND_TYPE_HIGHWATER_MARK_UPDATE:
 			stats.Add(numBroadcastHWMRequest, 1)
 			resp := &proto.HighwaterMarkUpdateResponse{}
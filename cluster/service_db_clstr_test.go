@@ -15,6 +15,7 @@ import (
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	"github.com/rqlite/rqlite/v10/command/encoding"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
 
 const shortWait = 1 * time.Second
@@ -313,6 +314,25 @@ func Test_ServiceBackup(t *testing.T) {
 		t.Fatalf("backup data is not as expected, exp: %s, got: %s", testData, buf.Bytes())
 	}
 
+	// Request zstd compression, which the client applies after receiving the
+	// gzip-compressed stream.
+	buf.Reset()
+	br := backupRequestBinary(true)
+	br.Compression = command.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD
+	if err := c.Backup(context.Background(), br, s.Addr(), NO_CREDS, longWait, buf); err != nil {
+		t.Fatalf("failed to backup database: %s", err.Error())
+	}
+	rc, compression, err := rarchive.NewDecompressReader(buf)
+	if err != nil {
+		t.Fatalf("failed to create decompress reader: %s", err.Error())
+	}
+	if compression != rarchive.CompressionZstd {
+		t.Fatalf("expected zstd compression, got %s", compression)
+	}
+	if b, err := io.ReadAll(rc); err != nil || !bytes.Equal(b, testData) {
+		t.Fatalf("zstd backup data is not as expected, got: %s, err: %v", b, err)
+	}
+
 	// Clean up resources.
 	if err := ln.Close(); err != nil {
 		t.Fatalf("failed to close Mux's listener: %s", err)
@@ -370,6 +390,119 @@ func Test_ServiceLoad(t *testing.T) {
 	}
 }
 
+func Test_ServiceLoadChunk(t *testing.T) {
+	ln, mux := mustNewMux()
+	defer mux.Close()
+	go mux.Serve()
+	tn := mux.Listen(1) // Could be any byte value.
+	db := mustNewMockDatabase()
+	mgr := mustNewMockManager()
+	cred := mustNewMockCredentialStore()
+	s := New(tn, db, mgr, cred)
+	if s == nil {
+		t.Fatalf("failed to create cluster service")
+	}
+
+	c := NewClient(mustNewDialer(1, false, false), 30*time.Second)
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service: %s", err.Error())
+	}
+
+	called := false
+	testData := []byte("this is a chunk of SQLite data")
+	db.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		called = true
+		if lcr.StreamId != "stream" || lcr.SequenceNum != 1 || !lcr.IsLast || !bytes.Equal(lcr.Data, testData) {
+			t.Fatalf("load chunk is not as expected, got: %v", lcr)
+		}
+		return nil
+	}
+	lcr := &command.LoadChunkRequest{StreamId: "stream", SequenceNum: 1, IsLast: true, Data: testData}
+	if err := c.LoadChunk(context.Background(), lcr, s.Addr(), NO_CREDS, longWait, defaultMaxRetries); err != nil {
+		t.Fatalf("failed to load chunk: %s", err.Error())
+	}
+	if !called {
+		t.Fatal("load chunk not called on database")
+	}
+
+	db.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		return errors.New("chunk failed")
+	}
+	if err := c.LoadChunk(context.Background(), lcr, s.Addr(), NO_CREDS, longWait, defaultMaxRetries); err == nil {
+		t.Fatal("expected error loading chunk")
+	}
+
+	// Clean up resources.
+	if err := ln.Close(); err != nil {
+		t.Fatalf("failed to close Mux's listener: %s", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close cluster service")
+	}
+}
+
+func Test_ServiceChecksum(t *testing.T) {
+	ln, mux := mustNewMux()
+	defer mux.Close()
+	go mux.Serve()
+	tn := mux.Listen(1) // Could be any byte value.
+	db := mustNewMockDatabase()
+	mgr := mustNewMockManager()
+	cred := mustNewMockCredentialStore()
+	s := New(tn, db, mgr, cred)
+	if s == nil {
+		t.Fatalf("failed to create cluster service")
+	}
+
+	c := NewClient(mustNewDialer(1, false, false), 30*time.Second)
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service: %s", err.Error())
+	}
+
+	sums := []*command.TableChecksum{{Table: "foo", Rows: 2, Sum: "abc"}}
+	db.checksumFn = func(cr *command.ChecksumRequest) (*command.ChecksumResult, error) {
+		if cr.ChunkSize != 100 {
+			t.Fatalf("wrong chunk size, exp 100, got %d", cr.ChunkSize)
+		}
+		return &command.ChecksumResult{Index: 5, ChunkSize: cr.ChunkSize, Checksums: sums}, nil
+	}
+	res, err := c.Checksum(context.Background(), &command.ChecksumRequest{ChunkSize: 100}, s.Addr(), NO_CREDS, longWait)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	if res.Index != 5 || len(res.Checksums) != 1 || res.Checksums[0].Sum != "abc" {
+		t.Fatalf("unexpected checksum result: %v", res)
+	}
+
+	db.getChecksumFn = func(gr *command.ChecksumResultRequest) (*command.ChecksumResult, error) {
+		if gr.Index != 5 {
+			return nil, fmt.Errorf("no checksum at index %d", gr.Index)
+		}
+		return &command.ChecksumResult{Index: 5, Checksums: sums}, nil
+	}
+	res, err = c.GetChecksum(context.Background(), &command.ChecksumResultRequest{Index: 5}, s.Addr(), NO_CREDS, longWait)
+	if err != nil {
+		t.Fatalf("failed to get checksum: %s", err.Error())
+	}
+	if res.Index != 5 || len(res.Checksums) != 1 {
+		t.Fatalf("unexpected checksum result: %v", res)
+	}
+	_, err = c.GetChecksum(context.Background(), &command.ChecksumResultRequest{Index: 6}, s.Addr(), NO_CREDS, longWait)
+	if err == nil || err.Error() != "no checksum at index 6" {
+		t.Fatalf("expected error getting missing checksum, got %v", err)
+	}
+
+	// Clean up resources.
+	if err := ln.Close(); err != nil {
+		t.Fatalf("failed to close Mux's listener: %s", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close cluster service")
+	}
+}
+
 func Test_ServiceRemoveNode(t *testing.T) {
 	ln, mux := mustNewMux()
 	defer mux.Close()
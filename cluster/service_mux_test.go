@@ -3,11 +3,18 @@ package cluster
 import (
 	"context"
 	"crypto/tls"
+	cryptox509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/cluster/proto"
+	command "github.com/rqlite/rqlite/v10/command/proto"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
 	"github.com/rqlite/rqlite/v10/tcp"
 	"github.com/rqlite/rqlite/v10/testdata/x509"
@@ -83,6 +90,51 @@ func Test_NewServiceSetGetNodeAPIAddrMuxedTLS(t *testing.T) {
 	}
 }
 
+func Test_NewServiceExecuteAuthCertUserMuxedMutualTLS(t *testing.T) {
+	db := mustNewMockDatabase()
+	db.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
+	}
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return false
+		},
+		aaCertFunc: func(username, perm string) bool {
+			return username == "svc" && perm == "execute"
+		},
+	}
+	execute := func(mux *tcp.Mux, dialer *tcp.Dialer) error {
+		go mux.Serve()
+		s := New(mux.Listen(1), db, mustNewMockManager(), c)
+		s.SetNodeCommonName("node")
+		if err := s.Open(); err != nil {
+			t.Fatalf("failed to open cluster service")
+		}
+		defer s.Close()
+		cl := NewClient(dialer, 30*time.Second)
+		_, _, err := cl.Execute(context.Background(), &command.ExecuteRequest{}, s.Addr(), &proto.Credentials{CertUser: "svc"}, 5*time.Second, noRetries)
+		return err
+	}
+
+	ln, mux, certFile, keyFile := mustNewMutualTLSMux(t)
+	defer ln.Close()
+	defer mux.Close()
+	dialer, err := rtls.CreateClientConfig(certFile, keyFile, rtls.NoCACert, rtls.NoServerName, true)
+	if err != nil {
+		t.Fatalf("failed to create client TLS config: %s", err)
+	}
+	if err := execute(mux, tcp.NewDialer(1, dialer)); err != nil {
+		t.Fatalf("certificate user forwarded by authenticated node improperly unauthorized: %s", err)
+	}
+
+	ln, mux = mustNewTLSMux(t)
+	defer ln.Close()
+	defer mux.Close()
+	if err := execute(mux, mustNewDialer(1, true, true)); err == nil {
+		t.Fatalf("certificate user forwarded by unauthenticated node improperly authorized")
+	}
+}
+
 func mustNewMux() (net.Listener, *tcp.Mux) {
 	ln, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
@@ -125,3 +177,44 @@ func mustNewDialer(header byte, remoteEncrypted, skipVerify bool) *tcp.Dialer {
 	}
 	return tcp.NewDialer(header, tlsConfig)
 }
+
+// mustNewMutualTLSMux returns a Mux which requires a client certificate signed
+// by its CA, and the files of a certificate and key which are.
+func mustNewMutualTLSMux(t *testing.T) (net.Listener, *tcp.Mux, string, string) {
+	t.Helper()
+	caCertPEM, caKeyPEM, err := rtls.GenerateCACert(pkix.Name{CommonName: "ca"}, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate CA cert: %s", err)
+	}
+	caBlock, _ := pem.Decode(caCertPEM)
+	keyBlock, _ := pem.Decode(caKeyPEM)
+	caCert, err := cryptox509.ParseCertificate(caBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA cert: %s", err)
+	}
+	caKey, err := cryptox509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA key: %s", err)
+	}
+	certPEM, keyPEM, err := rtls.GenerateCert(pkix.Name{CommonName: "node"}, time.Hour, 2048, caCert, caKey)
+	if err != nil {
+		t.Fatalf("failed to generate cert: %s", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	for f, b := range map[string][]byte{certFile: certPEM, keyFile: keyPEM, caFile: caCertPEM} {
+		if err := os.WriteFile(f, b, 0600); err != nil {
+			t.Fatalf("failed to write %s: %s", f, err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to create listener: %s", err)
+	}
+	mux, err := tcp.NewMutualTLSMux(ln, nil, certFile, keyFile, caFile, rtls.NoVerifyCN)
+	if err != nil {
+		t.Fatalf("failed to create mutual TLS mux: %s", err)
+	}
+	return ln, mux, certFile, keyFile
+}
@@ -3,18 +3,30 @@ package cluster
 import (
 	"context"
 	"crypto/tls"
+	cryptox509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
 	"github.com/rqlite/rqlite/v10/cluster/proto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/internal/rtls"
 	"github.com/rqlite/rqlite/v10/testdata/x509"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func Test_NewServiceOpenClose(t *testing.T) {
@@ -306,6 +318,370 @@ func Test_NewServiceTestExecuteQueryAuth(t *testing.T) {
 	}
 }
 
+func Test_NewServiceTestExecuteAuthToken(t *testing.T) {
+	ml := mustNewMockTransport()
+	db := mustNewMockDatabase()
+	clstr := mustNewMockManager()
+
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return false
+		},
+		aaTokenFunc: func(token, perm string) bool {
+			return token == "token1" && perm == "execute"
+		},
+	}
+	s := New(ml, db, clstr, c)
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service")
+	}
+	defer s.Close()
+
+	cl := NewClient(ml, 30*time.Second)
+	if err := cl.SetLocal(s.Addr(), s); err != nil {
+		t.Fatalf("failed to set cluster client local parameters: %s", err)
+	}
+	er := &command.ExecuteRequest{}
+	if _, _, err := cl.Execute(context.Background(), er, s.Addr(), &proto.Credentials{Token: "token1"}, 5*time.Second, defaultMaxRetries); err != nil {
+		t.Fatalf("token improperly unauthorized to execute: %s", err)
+	}
+	if _, _, err := cl.Execute(context.Background(), er, s.Addr(), &proto.Credentials{Token: "token2"}, 5*time.Second, defaultMaxRetries); err == nil {
+		t.Fatal("bad token improperly authorized to execute")
+	}
+}
+
+func Test_NewServiceTestExecuteTraced(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	ml := mustNewMockTransport()
+	db := mustNewMockDatabase()
+	db.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
+	}
+	s := New(ml, db, mustNewMockManager(), mustNewMockCredentialStore())
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service")
+	}
+	defer s.Close()
+
+	// Requests outside a trace are not traced.
+	cl := NewClient(ml, 30*time.Second)
+	if _, _, err := cl.Execute(context.Background(), &command.ExecuteRequest{}, s.Addr(), nil, 5*time.Second, noRetries); err != nil {
+		t.Fatalf("failed to execute: %s", err)
+	}
+	if n := len(sr.Ended()); n != 0 {
+		t.Fatalf("expected no spans outside a trace, got %d", n)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if _, _, err := cl.Execute(ctx, &command.ExecuteRequest{}, s.Addr(), nil, 5*time.Second, noRetries); err != nil {
+		t.Fatalf("failed to execute: %s", err)
+	}
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		spans[span.Name()] = span
+	}
+	client, ok := spans["cluster.Client COMMAND_TYPE_EXECUTE"]
+	if !ok {
+		t.Fatalf("client span not recorded, got %v", spans)
+	}
+	server, ok := spans["cluster.Service COMMAND_TYPE_EXECUTE"]
+	if !ok {
+		t.Fatalf("service span not recorded, got %v", spans)
+	}
+	if client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("client span is not a child of the parent span")
+	}
+	if server.Parent().SpanID() != client.SpanContext().SpanID() || !server.Parent().IsRemote() {
+		t.Fatalf("service span does not continue the trace of the client")
+	}
+}
+
+func Test_NewServiceTestExecuteAuthCert(t *testing.T) {
+	db := mustNewMockDatabase()
+	db.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
+	}
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return false
+		},
+		aaCertFunc: func(username, perm string) bool {
+			return username == "svc" && perm == "execute"
+		},
+	}
+	execute := func(ml *mockTransport, nodeCN, username string) error {
+		s := New(ml, db, mustNewMockManager(), c)
+		s.SetNodeCommonName(nodeCN)
+		if err := s.Open(); err != nil {
+			t.Fatalf("failed to open cluster service")
+		}
+		defer s.Close()
+		cl := NewClient(ml, 30*time.Second)
+		_, _, err := cl.Execute(context.Background(), &command.ExecuteRequest{}, s.Addr(), &proto.Credentials{CertUser: username}, 5*time.Second, noRetries)
+		return err
+	}
+
+	mutualTLS := func() *mockTransport {
+		ml, _ := mustNewMockMutualTLSTransport(t)
+		return ml
+	}
+	if err := execute(mutualTLS(), "node", "svc"); err != nil {
+		t.Fatalf("forwarded certificate user improperly unauthorized to execute: %s", err)
+	}
+
+	// Certificate users are ignored unless forwarded by an authenticated node.
+	if err := execute(mutualTLS(), "", "svc"); err == nil {
+		t.Fatalf("certificate user without node Common Name improperly authorized to execute")
+	}
+	if err := execute(mutualTLS(), "other", "svc"); err == nil {
+		t.Fatalf("certificate user from non-node peer improperly authorized to execute")
+	}
+	if err := execute(mustNewMockTLSTransport(), "node", "svc"); err == nil {
+		t.Fatalf("certificate user from unauthenticated node improperly authorized to execute")
+	}
+	if err := execute(mustNewMockTransport(), "node", "svc"); err == nil {
+		t.Fatalf("certificate user over plaintext improperly authorized to execute")
+	}
+
+	// Other peers authenticate as the user of their own certificate.
+	c.certUser = "svc"
+	if err := execute(mutualTLS(), "other", ""); err != nil {
+		t.Fatalf("user of peer certificate improperly unauthorized to execute: %s", err)
+	}
+	c.certUser = "client"
+	if err := execute(mutualTLS(), "other", "svc"); err == nil {
+		t.Fatalf("certificate user forwarded by non-node peer improperly authorized to execute")
+	}
+}
+
+func Test_NewServiceAuthorizer(t *testing.T) {
+	ml := mustNewMockTransport()
+	db := mustNewMockDatabase()
+	db.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
+	}
+	db.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return nil, 0, nil
+	}
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return perm != auth.PermUsers || username == "admin"
+		},
+	}
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "bob", "password": "secret1", "acl": [{"tables": ["orders"], "ops": ["select"]}]},
+		{"username": "admin", "password": "secret1"}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+
+	s := New(ml, db, mustNewMockManager(), c)
+	s.SetAuthorizer(sql.NewAuthorizer(cs), nil)
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service")
+	}
+	defer s.Close()
+	cl := NewClient(ml, 30*time.Second)
+
+	if _, _, err := cl.Query(context.Background(), queryRequestFromString("SELECT * FROM orders"), s.Addr(),
+		makeCredentials("bob", "secret1"), 5*time.Second, noRetries); err != nil {
+		t.Fatalf("bob improperly denied query of orders: %s", err)
+	}
+	if _, _, err := cl.Execute(context.Background(), executeRequestFromString("DELETE FROM orders"), s.Addr(),
+		makeCredentials("bob", "secret1"), 5*time.Second, noRetries); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("bob improperly permitted to delete from orders: %v", err)
+	}
+
+	// Changes to the table of users are only accepted from users who may
+	// manage users, and only those the endpoint managing users makes.
+	users := &command.ExecuteRequest{Request: &command.Request{Statements: []*command.Statement{
+		{Sql: sql.CreateUsersTableSQL},
+		{Sql: sql.DeleteUserSQL, Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "bob"}}}},
+	}}}
+	if _, _, err := cl.Execute(context.Background(), users, s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err != nil {
+		t.Fatalf("admin improperly denied change to users table: %s", err)
+	}
+	if _, _, err := cl.Execute(context.Background(), users, s.Addr(),
+		makeCredentials("bob", "secret1"), 5*time.Second, noRetries); err == nil {
+		t.Fatalf("bob improperly permitted to change users table")
+	}
+	h, err := auth.HashPassword("secret2", auth.HashBcrypt, 0)
+	if err != nil {
+		t.Fatalf("failed to hash password: %s", err)
+	}
+	putUser := func(q, cred string) *command.ExecuteRequest {
+		return &command.ExecuteRequest{Request: &command.Request{Statements: []*command.Statement{
+			{Sql: q, Parameters: []*command.Parameter{
+				{Value: &command.Parameter_S{S: "carol"}},
+				{Value: &command.Parameter_S{S: cred}},
+			}},
+		}}}
+	}
+	for _, er := range []*command.ExecuteRequest{
+		putUser(sql.PutUserSQL, `{"password": "`+h+`", "perms": ["all"]}`),
+		putUser(sql.UpdateUserSQL, `{"perms": ["query"]}`),
+	} {
+		if _, _, err := cl.Execute(context.Background(), er, s.Addr(),
+			makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err != nil {
+			t.Fatalf("admin improperly denied change to users table: %s", err)
+		}
+	}
+	for _, er := range []*command.ExecuteRequest{
+		executeRequestFromString(fmt.Sprintf("DELETE FROM %s WHERE username = 'bob'", auth.UsersTable)),
+		executeRequestFromString(fmt.Sprintf("UPDATE %s SET credential = '{}'", auth.UsersTable)),
+		putUser(sql.PutUserSQL, `{"password": "secret2"}`),
+		putUser(sql.PutUserSQL, `{"password": "$2a$10$notavalidhash"}`),
+		putUser(sql.UpdateUserSQL, `{"password": "secret2"}`),
+		putUser(sql.UpdateUserSQL, `not json`),
+		{Request: &command.Request{Statements: []*command.Statement{
+			{Sql: sql.DeleteUserSQL, Parameters: []*command.Parameter{{Value: &command.Parameter_I{I: 1}}}},
+		}}},
+	} {
+		if _, _, err := cl.Execute(context.Background(), er, s.Addr(),
+			makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err == nil {
+			t.Fatalf("admin improperly permitted arbitrary change to users table: %v", er.Request.Statements)
+		}
+	}
+	if _, _, err := cl.Query(context.Background(), queryRequestFromString("SELECT * FROM "+auth.UsersTable), s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err == nil {
+		t.Fatalf("admin improperly permitted to query users table")
+	}
+
+	// Users may only grant the perms they hold.
+	c.aaFunc = func(username, password, perm string) bool {
+		return perm == auth.PermUsers || perm == auth.PermExecute || perm == auth.PermQuery
+	}
+	if _, _, err := cl.Execute(context.Background(), putUser(sql.PutUserSQL, `{"password": "`+h+`", "perms": ["query"]}`), s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err != nil {
+		t.Fatalf("admin improperly denied grant of held perm: %s", err)
+	}
+	if _, _, err := cl.Execute(context.Background(), putUser(sql.PutUserSQL, `{"password": "`+h+`", "perms": ["all"]}`), s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err == nil {
+		t.Fatalf("admin improperly permitted to grant perm not held")
+	}
+	c.aaFunc = func(username, password, perm string) bool {
+		return perm != auth.PermUsers || username == "admin"
+	}
+
+	// Chunks of an import are checked against the import's access statement.
+	db.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		return nil
+	}
+	importChunk := func(access string) *command.LoadChunkRequest {
+		return &command.LoadChunkRequest{StreamId: "stream", SequenceNum: 1, IsLast: true, ImportAccess: access}
+	}
+	if err := cl.LoadChunk(context.Background(), importChunk("INSERT INTO orders DEFAULT VALUES"), s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err != nil {
+		t.Fatalf("admin improperly denied import into orders: %s", err)
+	}
+	if err := cl.LoadChunk(context.Background(), importChunk("INSERT INTO orders DEFAULT VALUES"), s.Addr(),
+		makeCredentials("bob", "secret1"), 5*time.Second, noRetries); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("bob improperly permitted to import into orders: %v", err)
+	}
+	if err := cl.LoadChunk(context.Background(), importChunk("INSERT INTO "+auth.UsersTable+" DEFAULT VALUES"), s.Addr(),
+		makeCredentials("admin", "secret1"), 5*time.Second, noRetries); err == nil {
+		t.Fatalf("admin improperly permitted to import into users table")
+	}
+}
+
+func Test_NewServiceNamedOnly(t *testing.T) {
+	ml := mustNewMockTransport()
+	db := mustNewMockDatabase()
+	var executed []*command.Statement
+	db.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = er.Request.Statements
+		return nil, 0, nil
+	}
+	db.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return nil, 0, nil
+	}
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return perm == auth.PermNamedStatements
+		},
+	}
+	registry := map[string]string{
+		"add":     "INSERT INTO foo(name) VALUES(?)",
+		"add_now": "INSERT INTO foo(t) VALUES(datetime('now'))",
+		"all":     "SELECT * FROM foo",
+	}
+	lookupFn := func(name string) (string, error) {
+		if q, ok := registry[name]; ok {
+			return q, nil
+		}
+		return "", fmt.Errorf("no such statement: %s", name)
+	}
+
+	s := New(ml, db, mustNewMockManager(), c)
+	s.SetAuthorizer(sql.NewAuthorizer(auth.NewCredentialsStore()), lookupFn)
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open cluster service")
+	}
+	defer s.Close()
+	cl := NewClient(ml, 30*time.Second)
+	creds := makeCredentials("bob", "secret1")
+
+	// Expand the statements as a follower does before forwarding them.
+	er := &command.ExecuteRequest{Request: &command.Request{Statements: []*command.Statement{
+		{Name: "add", Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "fiona"}}}},
+		{Name: "add_now"},
+	}}}
+	if err := sql.ExpandNamed(er.Request.Statements, lookupFn, sql.NewAnalysisCache(10)); err != nil {
+		t.Fatalf("failed to expand named statements: %s", err)
+	}
+	if _, _, err := cl.Execute(context.Background(), er, s.Addr(), creds, 5*time.Second, noRetries); err != nil {
+		t.Fatalf("forwarded named statements improperly denied: %s", err)
+	}
+	if len(executed) != 2 || executed[1].Name != "add_now" || !strings.HasPrefix(executed[1].Sql, "INSERT INTO") {
+		t.Fatalf("unexpected statements executed: %v", executed)
+	}
+
+	// Inlined SQL must be replaced by that registered under the name.
+	er = &command.ExecuteRequest{Request: &command.Request{Statements: []*command.Statement{
+		{Name: "add", Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "fiona"}}}},
+		{Name: "add_now", Sql: "DELETE FROM foo"},
+	}}}
+	if _, _, err := cl.Execute(context.Background(), er, s.Addr(), creds, 5*time.Second, noRetries); err != nil {
+		t.Fatalf("named statements improperly denied: %s", err)
+	}
+	if len(executed) != 2 || executed[0].Name != "add" || executed[0].Sql != "" {
+		t.Fatalf("unexpected statements executed: %v", executed)
+	}
+	if !strings.HasPrefix(executed[1].Sql, "INSERT INTO") {
+		t.Fatalf("SQL of named statement not replaced: %v", executed[1])
+	}
+
+	if _, _, err := cl.Query(context.Background(), &command.QueryRequest{Request: &command.Request{
+		Statements: []*command.Statement{{Name: "all"}}}}, s.Addr(), creds, 5*time.Second, noRetries); err != nil {
+		t.Fatalf("named query improperly denied: %s", err)
+	}
+
+	executed = nil
+	er = &command.ExecuteRequest{Request: &command.Request{Statements: []*command.Statement{
+		{Name: "add"},
+		{Sql: "DELETE FROM foo"},
+	}}}
+	if _, _, err := cl.Execute(context.Background(), er, s.Addr(), creds, 5*time.Second, noRetries); err == nil {
+		t.Fatalf("statement without a name improperly permitted")
+	}
+	if _, _, err := cl.Execute(context.Background(), &command.ExecuteRequest{Request: &command.Request{
+		Statements: []*command.Statement{{Name: "missing"}}}}, s.Addr(), creds, 5*time.Second, noRetries); err == nil {
+		t.Fatalf("missing named statement improperly permitted")
+	}
+	if executed != nil {
+		t.Fatalf("statements executed without permission: %v", executed)
+	}
+}
+
 func Test_NewServiceNotify(t *testing.T) {
 	ml := mustNewMockTransport()
 	mm := mustNewMockManager()
@@ -637,6 +1013,7 @@ func (d *mockDialer) CloseAll() {
 type mockTransport struct {
 	tn              net.Listener
 	remoteEncrypted bool
+	clientCerts     []tls.Certificate
 }
 
 func (ml *mockTransport) Accept() (c net.Conn, err error) {
@@ -659,6 +1036,7 @@ func (ml *mockTransport) Dial(addr string, timeout time.Duration) (net.Conn, err
 	if ml.remoteEncrypted {
 		conf := &tls.Config{
 			InsecureSkipVerify: true,
+			Certificates:       ml.clientCerts,
 		}
 		conn, err = tls.DialWithDialer(dialer, "tcp", addr, conf)
 	} else {
@@ -686,12 +1064,62 @@ func mustNewMockTLSTransport() *mockTransport {
 	}
 }
 
+// mustNewMockMutualTLSTransport returns a transport which requires, and
+// presents, a client certificate signed by a CA. It also returns the client
+// certificate.
+func mustNewMockMutualTLSTransport(t *testing.T) (*mockTransport, *cryptox509.Certificate) {
+	t.Helper()
+	caCertPEM, caKeyPEM, err := rtls.GenerateCACert(pkix.Name{CommonName: "ca"}, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate CA cert: %s", err)
+	}
+	caBlock, _ := pem.Decode(caCertPEM)
+	keyBlock, _ := pem.Decode(caKeyPEM)
+	caCert, err := cryptox509.ParseCertificate(caBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA cert: %s", err)
+	}
+	caKey, err := cryptox509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA key: %s", err)
+	}
+	certPEM, keyPEM, err := rtls.GenerateCert(pkix.Name{CommonName: "node"}, time.Hour, 2048, caCert, caKey)
+	if err != nil {
+		t.Fatalf("failed to generate cert: %s", err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load key pair: %s", err)
+	}
+	cert, err := cryptox509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse cert: %s", err)
+	}
+
+	pool := cryptox509.NewCertPool()
+	pool.AddCert(caCert)
+	tn := mustNewMockTransport()
+	return &mockTransport{
+		tn: tls.NewListener(tn, &tls.Config{
+			Certificates: []tls.Certificate{pair},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		}),
+		remoteEncrypted: true,
+		clientCerts:     []tls.Certificate{pair},
+	}, cert
+}
+
 type mockDatabase struct {
-	executeFn func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error)
-	queryFn   func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error)
-	requestFn func(rr *command.ExecuteQueryRequest) ([]*command.ExecuteQueryResponse, uint64, uint64, error)
-	backupFn  func(br *command.BackupRequest, dst io.Writer) error
-	loadFn    func(lr *command.LoadRequest) error
+	executeFn   func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error)
+	queryFn     func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error)
+	requestFn   func(rr *command.ExecuteQueryRequest) ([]*command.ExecuteQueryResponse, uint64, uint64, error)
+	backupFn    func(br *command.BackupRequest, dst io.Writer) error
+	loadFn      func(lr *command.LoadRequest) error
+	loadChunkFn func(lcr *command.LoadChunkRequest) error
+
+	checksumFn    func(cr *command.ChecksumRequest) (*command.ChecksumResult, error)
+	getChecksumFn func(gr *command.ChecksumResultRequest) (*command.ChecksumResult, error)
 }
 
 func (m *mockDatabase) Execute(ctx context.Context, er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
@@ -724,6 +1152,27 @@ func (m *mockDatabase) Load(ctx context.Context, lr *command.LoadRequest) error
 	return m.loadFn(lr)
 }
 
+func (m *mockDatabase) LoadChunk(ctx context.Context, lcr *command.LoadChunkRequest) error {
+	if m.loadChunkFn == nil {
+		return nil
+	}
+	return m.loadChunkFn(lcr)
+}
+
+func (m *mockDatabase) Checksum(ctx context.Context, cr *command.ChecksumRequest) (*command.ChecksumResult, error) {
+	if m.checksumFn == nil {
+		return &command.ChecksumResult{}, nil
+	}
+	return m.checksumFn(cr)
+}
+
+func (m *mockDatabase) GetChecksum(ctx context.Context, gr *command.ChecksumResultRequest) (*command.ChecksumResult, error) {
+	if m.getChecksumFn == nil {
+		return &command.ChecksumResult{}, nil
+	}
+	return m.getChecksumFn(gr)
+}
+
 func mustNewMockDatabase() *mockDatabase {
 	e := func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
 		return []*command.ExecuteQueryResponse{}, 0, nil
@@ -778,6 +1227,10 @@ func (m *MockManager) LeaderAddr() (string, error) {
 	return m.leaderAddrFn()
 }
 
+func (m *MockManager) WriteThrottle() (int, time.Duration) {
+	return 0, 0
+}
+
 func (m *MockManager) CommitIndex() (uint64, error) {
 	return m.commitIndex, nil
 }
@@ -807,8 +1260,11 @@ func mustCreateTLSConfig() *tls.Config {
 }
 
 type mockCredentialStore struct {
-	HasPermOK bool
-	aaFunc    func(username, password, perm string) bool
+	HasPermOK   bool
+	aaFunc      func(username, password, perm string) bool
+	aaTokenFunc func(token, perm string) bool
+	aaCertFunc  func(username, perm string) bool
+	certUser    string
 }
 
 func (m *mockCredentialStore) AA(username, password, perm string) bool {
@@ -822,6 +1278,35 @@ func (m *mockCredentialStore) AA(username, password, perm string) bool {
 	return m.HasPermOK
 }
 
+func (m *mockCredentialStore) AAToken(token, perm string) bool {
+	if m == nil {
+		return true
+	}
+
+	if m.aaTokenFunc != nil {
+		return m.aaTokenFunc(token, perm)
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) AACertUser(username, perm string) bool {
+	if m == nil {
+		return true
+	}
+
+	if m.aaCertFunc != nil {
+		return m.aaCertFunc(username, perm)
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) CertUser(cert *cryptox509.Certificate) (string, bool) {
+	if m == nil || m.certUser == "" {
+		return "", false
+	}
+	return m.certUser, true
+}
+
 func mustNewMockCredentialStore() *mockCredentialStore {
 	return &mockCredentialStore{HasPermOK: true}
 }
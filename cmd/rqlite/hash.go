@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkideal/cli"
+	"github.com/peterh/liner"
+	"github.com/rqlite/rqlite/v10/auth"
+)
+
+const hashPasswordCmd = "hash-password"
+
+type hashPasswordArgT struct {
+	cli.Helper
+	Algorithm string `cli:"a,algorithm" usage:"hashing algorithm (bcrypt or argon2id)" dft:"bcrypt"`
+	Cost      int    `cli:"c,cost" usage:"bcrypt cost, or 0 for the default" dft:"0"`
+}
+
+// hashPassword reads a password, and prints a hash of it which may be used
+// in the password field of an rqlited credentials file.
+func hashPassword(ctx *cli.Context) error {
+	argv := ctx.Argv().(*hashPasswordArgT)
+	if argv.Help {
+		ctx.WriteUsage()
+		return nil
+	}
+
+	pw, err := readPassword()
+	if err != nil {
+		return err
+	}
+	if pw == "" {
+		return errors.New("password must not be empty")
+	}
+	hash, err := auth.HashPassword(pw, strings.ToLower(argv.Algorithm), argv.Cost)
+	if err != nil {
+		return err
+	}
+	ctx.String("%s\n", hash)
+	return nil
+}
+
+// readPassword prompts for a password, without echoing it, if attached to a
+// terminal. Otherwise it reads the password from the first line of stdin.
+func readPassword() (string, error) {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		line := liner.NewLiner()
+		defer line.Close()
+		return line.PasswordPrompt("Password: ")
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("no password provided")
+	}
+	return strings.TrimRight(scanner.Text(), "\r"), nil
+}
@@ -93,12 +93,17 @@ func init() {
 		`.sysdump FILE                                 Dump system diagnostics to FILE`,
 		`.tables                                       List names of tables`,
 		`.timer [on|off]                               Show setting, or set query timings on or off`,
+		`.verify [CHUNKSIZE]                           Check all nodes hold identical data (reads every row), optionally comparing CHUNKSIZE rowids at a time`,
 	}
 	sort.Strings(cliHelp)
 }
 
 func main() {
 	cli.SetUsageStyle(cli.ManualStyle)
+	if len(os.Args) > 1 && os.Args[1] == hashPasswordCmd {
+		os.Exit(cli.RunWithArgs(new(hashPasswordArgT), os.Args[1:], hashPassword,
+			"Print a hash of a password, for use in an rqlited credentials file."))
+	}
 	cli.Run(new(argT), func(ctx *cli.Context) error {
 		argv := ctx.Argv().(*argT)
 		if argv.Help {
@@ -359,6 +364,19 @@ func main() {
 					nodeID = strings.TrimSpace(input[index+1:])
 				}
 				err = stepdown(client, nodeID)
+			case ".VERIFY":
+				chunkSize := 0
+				if index != -1 && index < len(input)-1 {
+					chunkSizeStr := strings.TrimSpace(input[index+1:])
+					if chunkSizeStr != "" {
+						chunkSize, err = strconv.Atoi(chunkSizeStr)
+						if err != nil || chunkSize < 0 {
+							err = fmt.Errorf("invalid chunk size: %s", chunkSizeStr)
+							break
+						}
+					}
+				}
+				err = verify(ctx, client, chunkSize)
 			default:
 				err = requestWithClient(output, client, timer, forceWrites, changes, mode, input)
 			}
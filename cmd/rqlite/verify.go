@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mkideal/cli"
+	httpcl "github.com/rqlite/rqlite/v10/cmd/rqlite/http"
+)
+
+type verifyNode struct {
+	ID    string `json:"id"`
+	Addr  string `json:"addr"`
+	Rows  int64  `json:"rows"`
+	Error string `json:"error"`
+}
+
+type verifyDifference struct {
+	Table     string            `json:"table"`
+	Start     *int64            `json:"start"`
+	End       *int64            `json:"end"`
+	Rows      map[string]int64  `json:"rows"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+type verifyResponse struct {
+	Index       uint64              `json:"index"`
+	Consistent  bool                `json:"consistent"`
+	Nodes       []*verifyNode       `json:"nodes"`
+	Differences []*verifyDifference `json:"differences"`
+}
+
+// verify checksums the database on every node at the same log index, and
+// prints any differences between the nodes. Every row is read on every node,
+// so this is expensive on a large database.
+func verify(ctx *cli.Context, client *httpcl.Client, chunkSize int) error {
+	u := fmt.Sprintf("%sdb/verify", client.Prefix)
+	if chunkSize > 0 {
+		u = fmt.Sprintf("%s?chunk_size=%d", u, chunkSize)
+	}
+	resp, err := client.Post(u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("unauthorized")
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var vr verifyResponse
+	if err := json.Unmarshal(body, &vr); err != nil {
+		return fmt.Errorf("failed to decode response: %s", err)
+	}
+	if vr.Consistent {
+		ctx.String("%d nodes are consistent at index %d\n", len(vr.Nodes), vr.Index)
+		return nil
+	}
+
+	ctx.String("%s nodes are not consistent at index %d\n", ctx.Color().Red("FAIL"), vr.Index)
+	for _, n := range vr.Nodes {
+		if n.Error != "" {
+			ctx.String("node %s (%s) could not be checked: %s\n", n.ID, n.Addr, n.Error)
+		}
+	}
+	for _, d := range vr.Differences {
+		if d.Start != nil && d.End != nil {
+			ctx.String("table %s, rowids %d to %d:\n", d.Table, *d.Start, *d.End)
+		} else {
+			ctx.String("table %s:\n", d.Table)
+		}
+		for _, id := range slices.Sorted(maps.Keys(d.Rows)) {
+			sum := d.Checksums[id]
+			if sum == "" {
+				sum = "missing"
+			} else if len(sum) > 16 {
+				sum = sum[:16]
+			}
+			ctx.String("  node %s: %d rows, checksum %s\n", id, d.Rows[id], sum)
+		}
+	}
+	return nil
+}
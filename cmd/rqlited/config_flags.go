@@ -24,6 +24,8 @@ type Config struct {
 	HTTPAdv string
 	// Value to set for Access-Control-Allow-Origin HTTP header
 	HTTPAllowOrigin string
+	// Throttle level at which writes are refused with 429, rather than delayed. If 0, writes are always delayed
+	WriteThrottleRejectLevel int
 	// Path to HTTPS X.509 certificate
 	HTTPx509Cert string
 	// Path to HTTPS X.509 private key
@@ -34,8 +36,26 @@ type Config struct {
 	HTTPVerifyClient bool
 	// Required Common Name on client certificates. If not set, no Common Name required
 	HTTPVerifyCommonName string
+	// PostgreSQL wire protocol bind address. If not set, not enabled
+	PGAddr string
+	// Path to X.509 certificate for PostgreSQL wire protocol TLS
+	PGx509Cert string
+	// Path to X.509 private key for PostgreSQL wire protocol TLS
+	PGx509Key string
+	// Allow PostgreSQL wire protocol clients to send passwords without TLS
+	PGInsecureAuth bool
+	// gRPC API bind address. If not set, not enabled
+	GRPCAddr string
+	// Path to X.509 certificate for gRPC API
+	GRPCx509Cert string
+	// Path to X.509 private key for gRPC API
+	GRPCx509Key string
 	// Path to authentication and authorization file. If not set, not enabled
 	AuthFile string
+	// Path to JWT bearer token configuration file. Requires -auth
+	AuthJWTFile string
+	// Enable management of users stored in the database. Requires -auth
+	AuthUsers bool
 	// Path to X.509 certificate for node-to-node mutual authentication and encryption
 	NodeX509Cert string
 	// Path to X.509 private key for node-to-node mutual authentication and encryption
@@ -116,6 +136,8 @@ type Config struct {
 	AutoOptimizeInterval time.Duration
 	// Comma-delimited list of paths to directories, zipfiles, or tar.gz files containing SQLite extensions
 	ExtensionPaths []string
+	// Check writes for non-determinism. One of 'off', 'warn' or 'reject'
+	SQLLint string
 	// Queued Writes queue capacity
 	WriteQueueCap int
 	// Queued Writes queue batch size
@@ -130,10 +152,18 @@ type Config struct {
 	AutoRestoreFile string
 	// Set CDC HTTP endpoint, or path to CDC config file. If not set, CDC not enabled
 	CDCConfig string
+	// Log format. One of 'text' or 'json'
+	LogFormat string
+	// Minimum log level for subsystems without a level set by -log-subsystem-levels
+	LogLevel string
+	// Comma-delimited list of minimum log levels by subsystem, e.g. store=debug,http=warn
+	LogSubsystemLevels string
 	// Address of OpenTelemetry Collector for metrics. If not set, OTLP reporting not enabled
 	OTLPEndpoint string
 	// Period between OTLP metric exports
 	OTLPMetricsInterval time.Duration
+	// Fraction of requests traced, and exported to the OpenTelemetry Collector. If 0, tracing is disabled
+	OTLPTraceSampleRatio float64
 	// Use plaintext gRPC when communicating with the OpenTelemetry Collector
 	OTLPInsecure bool
 	// Skip verification of the OpenTelemetry Collector certificate
@@ -164,12 +194,22 @@ func Forge(arguments []string) (*flag.FlagSet, *Config, error) {
 	fs.StringVar(&config.HTTPAddr, "http-addr", "localhost:4001", "HTTP server bind address. To enable HTTPS, set X.509 certificate and key")
 	fs.StringVar(&config.HTTPAdv, "http-adv-addr", "", "Advertised HTTP address. If not set, same as HTTP server bind address")
 	fs.StringVar(&config.HTTPAllowOrigin, "http-allow-origin", "", "Value to set for Access-Control-Allow-Origin HTTP header")
+	fs.IntVar(&config.WriteThrottleRejectLevel, "write-throttle-reject-level", 0, "Throttle level at which writes are refused with 429, rather than delayed. If 0, writes are always delayed")
 	fs.StringVar(&config.HTTPx509Cert, "http-cert", "", "Path to HTTPS X.509 certificate")
 	fs.StringVar(&config.HTTPx509Key, "http-key", "", "Path to HTTPS X.509 private key")
 	fs.StringVar(&config.HTTPx509CACert, "http-ca-cert", "", "Path to X.509 CA certificate for HTTPS")
 	fs.BoolVar(&config.HTTPVerifyClient, "http-verify-client", false, "Enable mutual TLS for HTTPS")
 	fs.StringVar(&config.HTTPVerifyCommonName, "http-verify-common-name", "", "Required Common Name on client certificates. If not set, no Common Name required")
+	fs.StringVar(&config.PGAddr, "pg-addr", "", "PostgreSQL wire protocol bind address. If not set, not enabled")
+	fs.StringVar(&config.PGx509Cert, "pg-cert", "", "Path to X.509 certificate for PostgreSQL wire protocol TLS")
+	fs.StringVar(&config.PGx509Key, "pg-key", "", "Path to X.509 private key for PostgreSQL wire protocol TLS")
+	fs.BoolVar(&config.PGInsecureAuth, "pg-insecure-auth", false, "Allow PostgreSQL wire protocol clients to send passwords without TLS")
+	fs.StringVar(&config.GRPCAddr, "grpc-addr", "", "gRPC API bind address. If not set, not enabled")
+	fs.StringVar(&config.GRPCx509Cert, "grpc-cert", "", "Path to X.509 certificate for gRPC API")
+	fs.StringVar(&config.GRPCx509Key, "grpc-key", "", "Path to X.509 private key for gRPC API")
 	fs.StringVar(&config.AuthFile, "auth", "", "Path to authentication and authorization file. If not set, not enabled")
+	fs.StringVar(&config.AuthJWTFile, "auth-jwt", "", "Path to JWT bearer token configuration file. Requires -auth")
+	fs.BoolVar(&config.AuthUsers, "auth-users", false, "Enable management of users stored in the database. Requires -auth")
 	fs.StringVar(&config.NodeX509Cert, "node-cert", "", "Path to X.509 certificate for node-to-node mutual authentication and encryption")
 	fs.StringVar(&config.NodeX509Key, "node-key", "", "Path to X.509 private key for node-to-node mutual authentication and encryption")
 	fs.StringVar(&config.NodeX509CACert, "node-ca-cert", "", "Path to X.509 CA certificate for node-to-node encryption")
@@ -211,6 +251,7 @@ func Forge(arguments []string) (*flag.FlagSet, *Config, error) {
 	fs.DurationVar(&config.AutoOptimizeInterval, "auto-optimize-int", mustParseDuration("24h"), "Period between automatic 'PRAGMA optimize'. Set to 0h to disable")
 	var tmpExtensionPaths string
 	fs.StringVar(&tmpExtensionPaths, "extensions-path", "", "Comma-delimited list of paths to directories, zipfiles, or tar.gz files containing SQLite extensions")
+	fs.StringVar(&config.SQLLint, "sql-lint", "off", "Check writes for non-determinism. One of 'off', 'warn' or 'reject'")
 	fs.IntVar(&config.WriteQueueCap, "write-queue-capacity", 1024, "Queued Writes queue capacity")
 	fs.IntVar(&config.WriteQueueBatchSz, "write-queue-batch-size", 128, "Queued Writes queue batch size")
 	fs.DurationVar(&config.WriteQueueTimeout, "write-queue-timeout", mustParseDuration("50ms"), "Queued Writes queue timeout")
@@ -218,8 +259,12 @@ func Forge(arguments []string) (*flag.FlagSet, *Config, error) {
 	fs.StringVar(&config.AutoBackupFile, "auto-backup", "", "Path to automatic backup configuration file. If not set, not enabled")
 	fs.StringVar(&config.AutoRestoreFile, "auto-restore", "", "Path to automatic restore configuration file. If not set, not enabled")
 	fs.StringVar(&config.CDCConfig, "cdc-config", "", "Set CDC HTTP endpoint, or path to CDC config file. If not set, CDC not enabled")
+	fs.StringVar(&config.LogFormat, "log-format", "text", "Log format. One of 'text' or 'json'")
+	fs.StringVar(&config.LogLevel, "log-level", "INFO", "Minimum log level for subsystems without a level set by -log-subsystem-levels")
+	fs.StringVar(&config.LogSubsystemLevels, "log-subsystem-levels", "", "Comma-delimited list of minimum log levels by subsystem, e.g. store=debug,http=warn")
 	fs.StringVar(&config.OTLPEndpoint, "otlp-endpoint", "", "Address of OpenTelemetry Collector for metrics. If not set, OTLP reporting not enabled")
 	fs.DurationVar(&config.OTLPMetricsInterval, "otlp-metrics-interval", mustParseDuration("30s"), "Period between OTLP metric exports")
+	fs.Float64Var(&config.OTLPTraceSampleRatio, "otlp-trace-sample-ratio", 0.0, "Fraction of requests traced, and exported to the OpenTelemetry Collector. If 0, tracing is disabled")
 	fs.BoolVar(&config.OTLPInsecure, "otlp-insecure", false, "Use plaintext gRPC when communicating with the OpenTelemetry Collector")
 	fs.BoolVar(&config.OTLPNoVerify, "otlp-no-verify", false, "Skip verification of the OpenTelemetry Collector certificate")
 	fs.StringVar(&config.OTLPCACert, "otlp-ca-cert", "", "Path to X.509 CA certificate for verifying the OpenTelemetry Collector")
@@ -14,6 +14,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 const (
@@ -32,9 +35,26 @@ const (
 	HTTPx509KeyFlag  = "http-key"
 	NodeX509CertFlag = "node-cert"
 	NodeX509KeyFlag  = "node-key"
+	PGAddrFlag       = "pg-addr"
+	PGx509CertFlag   = "pg-cert"
+	PGx509KeyFlag    = "pg-key"
+	GRPCAddrFlag     = "grpc-addr"
+	GRPCx509CertFlag = "grpc-cert"
+	GRPCx509KeyFlag  = "grpc-key"
+	SQLLintFlag      = "sql-lint"
+	AuthFlag         = "auth"
+	AuthJWTFlag      = "auth-jwt"
+	AuthUsersFlag    = "auth-users"
+
+	WriteThrottleRejectLevelFlag = "write-throttle-reject-level"
+
+	LogFormatFlag          = "log-format"
+	LogLevelFlag           = "log-level"
+	LogSubsystemLevelsFlag = "log-subsystem-levels"
 
 	OTLPEndpointFlag = "otlp-endpoint"
 	OTLPIntervalFlag = "otlp-interval"
+	OTLPSampleFlag   = "otlp-trace-sample-ratio"
 	OTLPInsecureFlag = "otlp-insecure"
 	OTLPNoVerifyFlag = "otlp-no-verify"
 	OTLPCACertFlag   = "otlp-ca-cert"
@@ -75,6 +95,10 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if _, err := sql.ParseLintMode(c.SQLLint); err != nil {
+		return fmt.Errorf("invalid -%s: %s", SQLLintFlag, err.Error())
+	}
+
 	if !bothUnsetSet(c.HTTPx509Cert, c.HTTPx509Key) {
 		return fmt.Errorf("either both -%s and -%s must be set, or neither", HTTPx509CertFlag, HTTPx509KeyFlag)
 	}
@@ -82,6 +106,33 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("either both -%s and -%s must be set, or neither", NodeX509CertFlag, NodeX509KeyFlag)
 
 	}
+	if !bothUnsetSet(c.PGx509Cert, c.PGx509Key) {
+		return fmt.Errorf("either both -%s and -%s must be set, or neither", PGx509CertFlag, PGx509KeyFlag)
+	}
+	if c.PGx509Cert != "" && c.PGAddr == "" {
+		return fmt.Errorf("-%s requires -%s", PGx509CertFlag, PGAddrFlag)
+	}
+	if c.PGAddr != "" && (c.PGAddr == c.HTTPAddr || c.PGAddr == c.RaftAddr) {
+		return errors.New("PostgreSQL wire protocol address must differ from HTTP and Raft addresses")
+	}
+	if !bothUnsetSet(c.GRPCx509Cert, c.GRPCx509Key) {
+		return fmt.Errorf("either both -%s and -%s must be set, or neither", GRPCx509CertFlag, GRPCx509KeyFlag)
+	}
+	if c.GRPCx509Cert != "" && c.GRPCAddr == "" {
+		return fmt.Errorf("-%s requires -%s", GRPCx509CertFlag, GRPCAddrFlag)
+	}
+	if c.GRPCAddr != "" && (c.GRPCAddr == c.HTTPAddr || c.GRPCAddr == c.RaftAddr || c.GRPCAddr == c.PGAddr) {
+		return errors.New("gRPC API address must differ from HTTP, Raft and PostgreSQL wire protocol addresses")
+	}
+	if c.AuthJWTFile != "" && c.AuthFile == "" {
+		return fmt.Errorf("-%s requires -%s", AuthJWTFlag, AuthFlag)
+	}
+	if c.AuthUsers && c.AuthFile == "" {
+		return fmt.Errorf("-%s requires -%s", AuthUsersFlag, AuthFlag)
+	}
+	if c.WriteThrottleRejectLevel < 0 {
+		return fmt.Errorf("-%s must not be negative", WriteThrottleRejectLevelFlag)
+	}
 	if c.HTTPVerifyCommonName != "" && !c.HTTPVerifyClient {
 		return errors.New("-http-verify-common-name requires -http-verify-client")
 	}
@@ -179,12 +230,23 @@ func (c *Config) Validate() error {
 		return errors.New("CDC cannot be enabled on non-voting nodes")
 	}
 
+	// Logging options OK?
+	if c.LogFormat != logging.FormatText && c.LogFormat != logging.FormatJSON {
+		return fmt.Errorf("-%s must be %s or %s", LogFormatFlag, logging.FormatText, logging.FormatJSON)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("-%s is not a valid log level", LogLevelFlag)
+	}
+	if _, err := logging.ParseLevels(c.LogSubsystemLevels); err != nil {
+		return fmt.Errorf("-%s is invalid: %s", LogSubsystemLevelsFlag, err)
+	}
+
 	// OTLP metrics reporting OK?
 	if !bothUnsetSet(c.OTLPCert, c.OTLPKey) {
 		return fmt.Errorf("either both -%s and -%s must be set, or neither", OTLPCertFlag, OTLPKeyFlag)
 	}
 	if c.OTLPEndpoint == "" {
-		if c.OTLPInsecure || c.OTLPNoVerify || c.OTLPCACert != "" || c.OTLPCert != "" {
+		if c.OTLPInsecure || c.OTLPNoVerify || c.OTLPCACert != "" || c.OTLPCert != "" || c.OTLPTraceSampleRatio != 0 {
 			return fmt.Errorf("OTLP options require -%s", OTLPEndpointFlag)
 		}
 	} else {
@@ -197,6 +259,9 @@ func (c *Config) Validate() error {
 		if c.OTLPMetricsInterval <= 0 {
 			return fmt.Errorf("-%s must be greater than zero", OTLPIntervalFlag)
 		}
+		if c.OTLPTraceSampleRatio < 0 || c.OTLPTraceSampleRatio > 1 {
+			return fmt.Errorf("-%s must be between 0 and 1", OTLPSampleFlag)
+		}
 		if c.OTLPInsecure && (c.OTLPNoVerify || c.OTLPCACert != "" || c.OTLPCert != "") {
 			return fmt.Errorf("-%s cannot be used with other OTLP TLS options", OTLPInsecureFlag)
 		}
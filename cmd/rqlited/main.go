@@ -27,12 +27,16 @@ import (
 	"github.com/rqlite/rqlite/v10/cluster/disco"
 	"github.com/rqlite/rqlite/v10/cmd"
 	"github.com/rqlite/rqlite/v10/command"
+	"github.com/rqlite/rqlite/v10/command/sql"
 	"github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/db/extensions"
+	"github.com/rqlite/rqlite/v10/grpc"
 	httpd "github.com/rqlite/rqlite/v10/http"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rarchive"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
 	"github.com/rqlite/rqlite/v10/otlp"
+	"github.com/rqlite/rqlite/v10/pgwire"
 	"github.com/rqlite/rqlite/v10/proxy"
 	"github.com/rqlite/rqlite/v10/store"
 	"github.com/rqlite/rqlite/v10/tcp"
@@ -58,6 +62,10 @@ Visit https://www.rqlite.io to learn more.`
 
 const (
 	shutdownTimeout = 10 * time.Second
+
+	// credentialsReloadInterval is how often the credentials file is checked
+	// for changes.
+	credentialsReloadInterval = 5 * time.Second
 )
 
 func init() {
@@ -66,6 +74,35 @@ func init() {
 	log.SetPrefix(fmt.Sprintf("[%s] ", name))
 }
 
+// configureLogging sets the format of logs, and the levels of each
+// subsystem. The Raft log level applies unless the raft subsystem is given
+// a level of its own.
+func configureLogging(cfg *Config) error {
+	if err := logging.Configure(cfg.LogFormat, os.Stderr, cfg.NodeID); err != nil {
+		return err
+	}
+	lvl, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	logging.SetLevel(logging.DefaultSubsystem, lvl)
+	if lvl, err := logging.ParseLevel(cfg.RaftLogLevel); err == nil {
+		logging.SetLevel("raft", lvl)
+	}
+	levels, err := logging.ParseLevels(cfg.LogSubsystemLevels)
+	if err != nil {
+		return err
+	}
+	for sub, lvl := range levels {
+		logging.SetLevel(sub, lvl)
+	}
+
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(logging.New(name, os.Stderr).Writer())
+	return nil
+}
+
 func main() {
 	// Handle signals first, so signal handling is established before anything else.
 	sigCh := HandleSignals(syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
@@ -77,11 +114,16 @@ func main() {
 		SQLiteVersion: db.DBVersion,
 	})
 	if err != nil {
-		log.Fatalf("failed to parse command-line flags: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to parse command-line flags: %s", err.Error())
+	}
+	if cfg.LogFormat != logging.FormatJSON {
+		fmt.Print(logo)
 	}
-	fmt.Print(logo)
 
 	// Configure logging and pump out initial message.
+	if err := configureLogging(cfg); err != nil {
+		logging.Fatalf(log.Default(), "failed to configure logging: %s", err.Error())
+	}
 	log.Printf("%s starting, version %s, SQLite %s, commit %s, compiler (toolchain) %s, compiler (command) %s",
 		name, cmd.Version, db.DBVersion, cmd.Commit, runtime.Compiler, cmd.CompilerCommand)
 	log.Printf("%s, target architecture is %s, operating system target is %s", runtime.Version(),
@@ -94,11 +136,11 @@ func main() {
 	// Create internode network mux and configure.
 	muxLn, err := net.Listen("tcp", cfg.RaftAddr)
 	if err != nil {
-		log.Fatalf("failed to listen on %s: %s", cfg.RaftAddr, err.Error())
+		logging.Fatalf(log.Default(), "failed to listen on %s: %s", cfg.RaftAddr, err.Error())
 	}
 	mux, err := startNodeMux(cfg, muxLn)
 	if err != nil {
-		log.Fatalf("failed to start node mux: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to start node mux: %s", err.Error())
 	}
 
 	// Create the Raft internode layer. All Raft-related traffic is sent over this layer.
@@ -109,31 +151,31 @@ func main() {
 	raftDialer, err := cluster.CreateRaftDialer(cfg.NodeX509Cert, cfg.NodeX509Key, cfg.NodeX509CACert,
 		cfg.NodeVerifyServerName, cfg.NoNodeVerify)
 	if err != nil {
-		log.Fatalf("failed to create Raft dialer: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create Raft dialer: %s", err.Error())
 	}
 	raftTn := tcp.NewLayer(raftLn, raftDialer)
 
 	// Create extension store.
 	extensionsStore, err := createExtensionsStore(cfg)
 	if err != nil {
-		log.Fatalf("failed to create extensions store: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create extensions store: %s", err.Error())
 	}
 	extensionsPaths, err := extensionsStore.List()
 	if err != nil {
-		log.Fatalf("failed to list extensions: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to list extensions: %s", err.Error())
 	}
 
 	// Create the store.
 	str, err := createStore(cfg, raftTn, extensionsPaths)
 	if err != nil {
-		log.Fatalf("failed to create store: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create store: %s", err.Error())
 	}
 
 	// Install the auto-restore data, if necessary.
 	if cfg.AutoRestoreFile != "" {
 		hd, err := store.HasData(str.Path())
 		if err != nil {
-			log.Fatalf("failed to check for existing data: %s", err.Error())
+			logging.Fatalf(log.Default(), "failed to check for existing data: %s", err.Error())
 		}
 		if hd {
 			log.Printf("auto-restore requested, but data already exists in %s, skipping", str.Path())
@@ -153,7 +195,7 @@ func main() {
 			} else {
 				log.Printf("auto-restore file downloaded in %s", time.Since(start))
 				if err := str.SetRestorePath(path); err != nil {
-					log.Fatalf("failed to preload auto-restore data: %s", err.Error())
+					logging.Fatalf(log.Default(), "failed to preload auto-restore data: %s", err.Error())
 				}
 			}
 		}
@@ -162,19 +204,34 @@ func main() {
 	// Get any credential store.
 	credStr, err := credentialStore(cfg)
 	if err != nil {
-		log.Fatalf("failed to get credential store: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to get credential store: %s", err.Error())
+	}
+	if cfg.AuthUsers {
+		credStr.SetUserSource(str)
+	}
+
+	// Create the rate limiter, which enforces any per-user rate limits in the auth file.
+	var rateLimiter *auth.RateLimiter
+	if credStr != nil {
+		rateLimiter = auth.NewRateLimiter(credStr)
+	}
+
+	// Create the authorizer, which enforces any table ACLs in the auth file.
+	var authorizer *sql.Authorizer
+	if credStr != nil {
+		authorizer = sql.NewAuthorizer(credStr)
 	}
 
 	// Create cluster service now, so nodes will be able to learn information about each other.
-	clstrServ, err := clusterService(cfg, mux.Listen(cluster.MuxClusterHeader), str, str, credStr)
+	clstrServ, err := clusterService(cfg, mux.Listen(cluster.MuxClusterHeader), str, credStr, authorizer)
 	if err != nil {
-		log.Fatalf("failed to create cluster service: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create cluster service: %s", err.Error())
 	}
 
 	// Create cluster client.
 	clstrClient, err := createClusterClient(cfg, clstrServ)
 	if err != nil {
-		log.Fatalf("failed to create cluster client: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create cluster client: %s", err.Error())
 	}
 
 	// Create the CDC service, if requested. Do this before opening the Store so the CDC
@@ -182,7 +239,7 @@ func main() {
 	var cdcServ *cdc.Service
 	if cfg.CDCConfig != "" {
 		if cdcServ, err = createCDC(cfg, str, clstrServ, clstrClient); err != nil {
-			log.Fatalf("failed to create CDC Service: %s", err.Error())
+			logging.Fatalf(log.Default(), "failed to create CDC Service: %s", err.Error())
 		}
 	}
 
@@ -190,25 +247,32 @@ func main() {
 	pxy := proxy.New(str, clstrClient)
 	pxy.SetAPIAddr(cfg.HTTPAdv)
 
+	// Create the linter, which checks writes against this node's database.
+	lintMode, err := sql.ParseLintMode(cfg.SQLLint)
+	if err != nil {
+		logging.Fatalf(log.Default(), "failed to parse lint mode: %s", err.Error())
+	}
+	linter := sql.NewLinter(lintMode, str)
+
 	// Create the HTTP service.
 	//
 	// We want to start the HTTP server as soon as possible, so the node is responsive and external
 	// systems can see that it's running. We still have to open the Store though, so the node won't
 	// be able to do much until that happens however.
-	httpServ, err := startHTTPService(cfg, str, clstrClient, credStr, pxy)
+	httpServ, err := startHTTPService(cfg, str, clstrClient, credStr, pxy, linter, authorizer, rateLimiter)
 	if err != nil {
-		log.Fatalf("failed to start HTTP server: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to start HTTP server: %s", err.Error())
 	}
 
 	// Now, open store. How long this takes does depend on how much data is being stored by rqlite.
 	if err := str.Open(); err != nil {
-		log.Fatalf("failed to open store: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to open store: %s", err.Error())
 	}
 
 	// Register remaining status providers.
 	if cdcServ != nil {
 		if err := httpServ.RegisterStatus("cdc", cdcServ); err != nil {
-			log.Fatalf("failed to register cdc status provider: %s", err.Error())
+			logging.Fatalf(log.Default(), "failed to register cdc status provider: %s", err.Error())
 		}
 	}
 	for n, r := range map[string]httpd.StatusReporter{
@@ -218,17 +282,20 @@ func main() {
 		"extensions": extensionsStore,
 	} {
 		if err := httpServ.RegisterStatus(n, r); err != nil {
-			log.Fatalf("failed to register %s status provider: %s", n, err.Error())
+			logging.Fatalf(log.Default(), "failed to register %s status provider: %s", n, err.Error())
 		}
 	}
+	if rateLimiter != nil {
+		httpServ.RegisterStatus("rate_limits", rateLimiter)
+	}
 
 	// Create the cluster!
 	nodes, err := str.Nodes()
 	if err != nil {
-		log.Fatalf("failed to get nodes %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to get nodes %s", err.Error())
 	}
 	if err := createCluster(mainCtx, cfg, len(nodes) > 0, clstrClient, str, httpServ, credStr); err != nil {
-		log.Fatalf("clustering failure: %s", err.Error())
+		logging.Fatalf(log.Default(), "clustering failure: %s", err.Error())
 	}
 
 	// Tell the user the node is ready for HTTP, giving some advice on how to connect.
@@ -240,26 +307,60 @@ func main() {
 	// Start any requested auto-backups
 	backupSrv, err := startAutoBackups(mainCtx, cfg, str)
 	if err != nil {
-		log.Fatalf("failed to start auto-backups: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to start auto-backups: %s", err.Error())
 	}
 	if backupSrv != nil {
 		httpServ.RegisterStatus("auto_backups", backupSrv)
+		httpServ.SetBackupUploader(backupSrv)
 	}
 
 	// Start any requested OTLP metrics reporting.
 	otlpSrv, err := startOTLPMetrics(cfg)
 	if err != nil {
-		log.Fatalf("failed to start OTLP metrics reporting: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to start OTLP metrics reporting: %s", err.Error())
 	}
 	if otlpSrv != nil {
 		httpServ.RegisterStatus("otlp_metrics", otlpSrv)
 	}
 
+	// Start any requested OTLP trace export.
+	otlpTraceSrv, err := startOTLPTraces(cfg)
+	if err != nil {
+		logging.Fatalf(log.Default(), "failed to start OTLP trace export: %s", err.Error())
+	}
+	if otlpTraceSrv != nil {
+		httpServ.RegisterStatus("otlp_traces", otlpTraceSrv)
+	}
+
+	// Start the PostgreSQL wire protocol service, if requested.
+	pgServ, err := startPGService(cfg, credStr, pxy, str, linter, authorizer)
+	if err != nil {
+		logging.Fatalf(log.Default(), "failed to start PostgreSQL wire protocol service: %s", err.Error())
+	}
+	if pgServ != nil {
+		httpServ.RegisterStatus("pgwire", pgServ)
+	}
+
+	// Start the gRPC API service, if requested.
+	grpcServ, err := startGRPCService(cfg, credStr, pxy, str, httpServ, linter, authorizer)
+	if err != nil {
+		logging.Fatalf(log.Default(), "failed to start gRPC API service: %s", err.Error())
+	}
+	if grpcServ != nil {
+		httpServ.RegisterStatus("grpc", grpcServ)
+	}
+
 	// Block until done.
 	<-mainCtx.Done()
 
 	// Stop the HTTP server and other network access first so clients get notification as soon as
 	// possible that the node is going away.
+	if pgServ != nil {
+		pgServ.Close()
+	}
+	if grpcServ != nil {
+		grpcServ.Close()
+	}
 	httpServ.Close()
 	clstrServ.Close()
 
@@ -270,7 +371,7 @@ func main() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 		if err := remover.Do(shutdownCtx, cfg.NodeID, true); err != nil {
-			log.Fatalf("failed to remove this node from cluster before shutdown: %s", err.Error())
+			logging.Fatalf(log.Default(), "failed to remove this node from cluster before shutdown: %s", err.Error())
 		}
 		log.Printf("removed this node successfully from cluster before shutdown")
 	}
@@ -287,17 +388,21 @@ func main() {
 	// str.Close below, otherwise the pre-close snapshot and Raft shutdown could
 	// block indefinitely on the Raft run goroutine.
 	if err := mux.Close(); err != nil {
-		log.Printf("failed to close mux during shutdown: %s", err.Error())
+		logging.Warnf(log.Default(), "failed to close mux during shutdown: %s", err.Error())
 	}
 
 	if err := str.Close(true); err != nil {
-		log.Printf("failed to close store: %s", err.Error())
+		logging.Warnf(log.Default(), "failed to close store: %s", err.Error())
 	}
 
-	// Stop OTLP metrics reporting, flushing any remaining metrics.
+	// Stop OTLP metrics reporting and trace export, flushing any remaining
+	// metrics and spans.
 	if otlpSrv != nil {
 		otlpSrv.Stop()
 	}
+	if otlpTraceSrv != nil {
+		otlpTraceSrv.Stop()
+	}
 
 	stopProfile()
 	log.Println("rqlite server stopped")
@@ -318,7 +423,11 @@ func startAutoBackups(ctx context.Context, cfg *Config, str *store.Store) (*back
 		return nil, fmt.Errorf("failed to parse auto-backup file: %s", err.Error())
 	}
 	provider := store.NewProvider(str, uCfg.Vacuum, !uCfg.NoCompress)
+	provider.Compression = command.BackupCompressionFromString(uCfg.Compression)
 	u := backup.NewUploader(sc, provider, time.Duration(uCfg.Interval))
+	u.Manifest = uCfg.Manifest
+	u.Verify = uCfg.Verify
+	u.Version = cmd.Version
 	u.Start(ctx, str.IsLeader)
 	return u, nil
 }
@@ -328,7 +437,27 @@ func startOTLPMetrics(cfg *Config) (*otlp.Service, error) {
 		return nil, nil
 	}
 
-	srv := otlp.NewService(otlp.Config{
+	srv := otlp.NewService(otlpConfig(cfg))
+	if err := srv.Start(); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+func startOTLPTraces(cfg *Config) (*otlp.TraceService, error) {
+	if cfg.OTLPEndpoint == "" || cfg.OTLPTraceSampleRatio == 0 {
+		return nil, nil
+	}
+
+	srv := otlp.NewTraceService(otlpConfig(cfg))
+	if err := srv.Start(); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+func otlpConfig(cfg *Config) otlp.Config {
+	return otlp.Config{
 		Endpoint:           cfg.OTLPEndpoint,
 		Interval:           cfg.OTLPMetricsInterval,
 		Insecure:           cfg.OTLPInsecure,
@@ -338,36 +467,33 @@ func startOTLPMetrics(cfg *Config) (*otlp.Service, error) {
 		KeyFile:            cfg.OTLPKey,
 		NodeID:             cfg.NodeID,
 		Version:            cmd.Version,
-	})
-	if err := srv.Start(); err != nil {
-		return nil, err
+		TraceSampleRatio:   cfg.OTLPTraceSampleRatio,
 	}
-	return srv, nil
 }
 
 func createExtensionsStore(cfg *Config) (*extensions.Store, error) {
 	str, err := extensions.NewStore(filepath.Join(cfg.DataPath, "extensions"))
 	if err != nil {
-		log.Fatalf("failed to create extension store: %s", err.Error())
+		logging.Fatalf(log.Default(), "failed to create extension store: %s", err.Error())
 	}
 
 	if len(cfg.ExtensionPaths) > 0 {
 		for _, path := range cfg.ExtensionPaths {
 			if isDir(path) {
 				if err := str.LoadFromDir(path); err != nil {
-					log.Fatalf("failed to load extensions from directory: %s", err.Error())
+					logging.Fatalf(log.Default(), "failed to load extensions from directory: %s", err.Error())
 				}
 			} else if rarchive.IsZipFile(path) {
 				if err := str.LoadFromZip(path); err != nil {
-					log.Fatalf("failed to load extensions from zip file: %s", err.Error())
+					logging.Fatalf(log.Default(), "failed to load extensions from zip file: %s", err.Error())
 				}
 			} else if rarchive.IsTarGzipFile(path) {
 				if err := str.LoadFromTarGzip(path); err != nil {
-					log.Fatalf("failed to load extensions from tar.gz file: %s", err.Error())
+					logging.Fatalf(log.Default(), "failed to load extensions from tar.gz file: %s", err.Error())
 				}
 			} else {
 				if err := str.LoadFromFile(path); err != nil {
-					log.Fatalf("failed to load extension from file: %s", err.Error())
+					logging.Fatalf(log.Default(), "failed to load extension from file: %s", err.Error())
 				}
 			}
 		}
@@ -428,6 +554,7 @@ func createStore(cfg *Config, ln *tcp.Layer, extensions []string) (*store.Store,
 	str.BootstrapExpect = cfg.BootstrapExpect
 	str.ReapTimeout = cfg.RaftReapNodeTimeout
 	str.ReapReadOnlyTimeout = cfg.RaftReapReadOnlyNodeTimeout
+	str.WriteThrottleRejectLevel = cfg.WriteThrottleRejectLevel
 	str.AutoVacInterval = cfg.AutoVacInterval
 	str.AutoOptimizeInterval = cfg.AutoOptimizeInterval
 	str.CompressSnapTransport = cfg.CompressSnapTransport
@@ -481,7 +608,7 @@ func createDiscoService(cfg *Config, str *store.Store) (*disco.Service, error) {
 	return disco.NewService(c, str, disco.VoterSuffrage(!cfg.RaftNonVoter)), nil
 }
 
-func startHTTPService(cfg *Config, str *store.Store, cltr *cluster.Client, credStr *auth.CredentialsStore, pxy *proxy.Proxy) (*httpd.Service, error) {
+func startHTTPService(cfg *Config, str *store.Store, cltr *cluster.Client, credStr *auth.CredentialsStore, pxy *proxy.Proxy, linter *sql.Linter, authorizer *sql.Authorizer, rateLimiter *auth.RateLimiter) (*httpd.Service, error) {
 	// Create HTTP server and load authentication information.
 	var cs httpd.CredentialStore
 	if credStr != nil {
@@ -498,6 +625,12 @@ func startHTTPService(cfg *Config, str *store.Store, cltr *cluster.Client, credS
 	s.DefaultQueueBatchSz = cfg.WriteQueueBatchSz
 	s.DefaultQueueTimeout = cfg.WriteQueueTimeout
 	s.DefaultQueueTx = cfg.WriteQueueTx
+	s.Linter = linter
+	s.Catalog = str
+	s.Authorizer = authorizer
+	s.UserManagement = cfg.AuthUsers
+	s.RateLimiter = rateLimiter
+	s.Metrics = otlp.NewPrometheus(map[string]string{"node_id": cfg.NodeID})
 	s.BuildInfo = map[string]any{
 		"commit":             cmd.Commit,
 		"version":            cmd.Version,
@@ -509,6 +642,71 @@ func startHTTPService(cfg *Config, str *store.Store, cltr *cluster.Client, credS
 	return s, s.Start()
 }
 
+// startPGService starts the PostgreSQL wire protocol service, if an address
+// for it is configured.
+func startPGService(cfg *Config, credStr *auth.CredentialsStore, pxy *proxy.Proxy, catalog sql.Catalog, linter *sql.Linter, authorizer *sql.Authorizer) (*pgwire.Service, error) {
+	if cfg.PGAddr == "" {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", cfg.PGAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %s", cfg.PGAddr, err.Error())
+	}
+	var cs pgwire.CredentialStore
+	if credStr != nil {
+		cs = credStr
+	}
+	s := pgwire.New(ln, pxy, cs)
+	s.Version = cmd.Version
+	s.Linter = linter
+	s.Catalog = catalog
+	s.Authorizer = authorizer
+	s.InsecureAuth = cfg.PGInsecureAuth
+	if cfg.PGx509Cert != "" {
+		cr, err := rtls.NewCertReloader(cfg.PGx509Cert, cfg.PGx509Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load PostgreSQL wire protocol certificate: %s", err.Error())
+		}
+		s.TLSConfig, err = rtls.CreateServerConfigWithFunc(cr.GetCertificate, "", rtls.MTLSStateDisabled, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PostgreSQL wire protocol TLS config: %s", err.Error())
+		}
+	}
+	return s, s.Start()
+}
+
+// startGRPCService starts the gRPC API service, if an address for it is
+// configured.
+func startGRPCService(cfg *Config, credStr *auth.CredentialsStore, pxy *proxy.Proxy, str *store.Store, httpServ *httpd.Service, linter *sql.Linter, authorizer *sql.Authorizer) (*grpc.Service, error) {
+	if cfg.GRPCAddr == "" {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", cfg.GRPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %s", cfg.GRPCAddr, err.Error())
+	}
+	var cs grpc.CredentialStore
+	if credStr != nil {
+		cs = credStr
+	}
+	s := grpc.New(ln, pxy, httpServ, cs)
+	s.Linter = linter
+	s.Catalog = str
+	s.Authorizer = authorizer
+	s.NamedStatement = str.NamedStatement
+	if cfg.GRPCx509Cert != "" {
+		cr, err := rtls.NewCertReloader(cfg.GRPCx509Cert, cfg.GRPCx509Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gRPC API certificate: %s", err.Error())
+		}
+		s.TLSConfig, err = rtls.CreateServerConfigWithFunc(cr.GetCertificate, "", rtls.MTLSStateDisabled, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gRPC API TLS config: %s", err.Error())
+		}
+	}
+	return s, s.Start()
+}
+
 // startNodeMux starts the TCP mux on the given listener, which should be already
 // bound to the relevant interface.
 func startNodeMux(cfg *Config, ln net.Listener) (*tcp.Mux, error) {
@@ -550,11 +748,53 @@ func credentialStore(cfg *Config) (*auth.CredentialsStore, error) {
 	if cfg.AuthFile == "" {
 		return nil, nil
 	}
-	return auth.NewCredentialsStoreFromFile(cfg.AuthFile)
+	cs, err := auth.NewCredentialsStoreFromFile(cfg.AuthFile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JoinAs != "" {
+		if _, ok := cs.Password(cfg.JoinAs); !ok {
+			log.Printf("no plaintext password for join-as user %s in %s, requests to other nodes will not be authenticated",
+				cfg.JoinAs, cfg.AuthFile)
+		}
+	}
+
+	var tv *auth.TokenVerifier
+	if cfg.AuthJWTFile != "" {
+		tv, err = auth.NewTokenVerifierFromFile(cfg.AuthJWTFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT configuration from %s: %s", cfg.AuthJWTFile, err.Error())
+		}
+		cs.SetTokenVerifier(tv)
+	}
+
+	// Reload the credentials whenever the file changes, or on SIGHUP.
+	cs.Watch(credentialsReloadInterval, nil)
+	hupCh := HandleSignals(syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			if err := cs.Reload(); err != nil {
+				logging.Warnf(log.Default(), "failed to reload credentials from %s, keeping prior credentials: %s", cfg.AuthFile, err.Error())
+			} else {
+				log.Printf("reloaded credentials from %s", cfg.AuthFile)
+			}
+			if tv == nil {
+				continue
+			}
+			if err := tv.Reload(); err != nil {
+				logging.Warnf(log.Default(), "failed to reload JWT configuration from %s, keeping prior configuration: %s", cfg.AuthJWTFile, err.Error())
+			} else {
+				log.Printf("reloaded JWT configuration from %s", cfg.AuthJWTFile)
+			}
+		}
+	}()
+	return cs, nil
 }
 
-func clusterService(cfg *Config, ln net.Listener, db cluster.Database, mgr cluster.Manager, credStr *auth.CredentialsStore) (*cluster.Service, error) {
-	c := cluster.New(ln, db, mgr, credStr)
+func clusterService(cfg *Config, ln net.Listener, str *store.Store, credStr *auth.CredentialsStore, authorizer *sql.Authorizer) (*cluster.Service, error) {
+	c := cluster.New(ln, str, str, credStr)
+	c.SetAuthorizer(authorizer, str.NamedStatement)
+	c.SetNodeCommonName(cfg.NodeVerifyCommonName)
 	c.SetAPIAddr(cfg.HTTPAdv)
 	c.SetVersion(cmd.Version)
 	c.EnableHTTPS(cfg.HTTPx509Cert != "" && cfg.HTTPx509Key != "") // Conditions met for an HTTPS API
@@ -719,12 +959,12 @@ func createCluster(ctx context.Context, cfg *Config, hasPeers bool, client *clus
 			for {
 				log.Printf("discovery service returned %s as join address", addr)
 				if j, err := joiner.Do(ctx, []string{addr}, str.ID(), cfg.RaftAdv, clusterSuf); err != nil {
-					log.Printf("failed to join cluster at %s: %s", addr, err.Error())
+					logging.Warnf(log.Default(), "failed to join cluster at %s: %s", addr, err.Error())
 
 					time.Sleep(time.Second)
 					_, addr, err = discoService.Register(str.ID(), cfg.HTTPURL(), cfg.RaftAdv)
 					if err != nil {
-						log.Printf("failed to get updated leader: %s", err.Error())
+						logging.Warnf(log.Default(), "failed to get updated leader: %s", err.Error())
 					}
 					continue
 				} else {
@@ -0,0 +1,265 @@
+package encoding
+
+import (
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// arrowBatchRows is the maximum number of rows in each Arrow record batch.
+const arrowBatchRows = 64 * 1024
+
+// arrowKind is the Arrow representation chosen for a column.
+type arrowKind int
+
+const (
+	arrowUnknown arrowKind = iota
+	arrowBool
+	arrowInt
+	arrowFloat
+	arrowBinary
+	arrowString
+)
+
+// WriteArrow writes the given QueryRows to w as an Arrow IPC stream. The
+// Arrow type of each column is determined by its declared type, as reported
+// in the types field of the QueryRows. If the declared type says nothing
+// useful, or the values of the column don't all fit it, the Arrow type is
+// inferred from the values instead. BLOB columns are Arrow binary columns,
+// or lists of uint8 if bytesAsArray is set.
+func WriteArrow(w io.Writer, q *proto.QueryRows, bytesAsArray bool) error {
+	if len(q.Columns) != len(q.Types) {
+		return ErrTypesColumnsLengthViolation
+	}
+
+	kinds := make([]arrowKind, len(q.Columns))
+	fields := make([]arrow.Field, len(q.Columns))
+	for i := range q.Columns {
+		kinds[i] = arrowColumnKind(q.Types[i], q.Values, i)
+		fields[i] = arrow.Field{
+			Name:     q.Columns[i],
+			Type:     arrowDataType(kinds[i], bytesAsArray),
+			Nullable: true,
+		}
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	mem := memory.DefaultAllocator
+	wr := ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+
+	for start := 0; start < len(q.Values); start += arrowBatchRows {
+		end := min(start+arrowBatchRows, len(q.Values))
+		for _, v := range q.Values[start:end] {
+			params := v.GetParameters()
+			for i := range fields {
+				var p *proto.Parameter
+				if i < len(params) {
+					p = params[i]
+				}
+				if err := appendArrowValue(rb.Field(i), kinds[i], p, bytesAsArray); err != nil {
+					return err
+				}
+			}
+		}
+		rec := rb.NewRecordBatch()
+		err := wr.Write(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return wr.Close()
+}
+
+// arrowColumnKind returns the Arrow representation for column i, which has
+// the given declared type.
+func arrowColumnKind(declType string, values []*proto.Values, i int) arrowKind {
+	k := arrowKindOfDeclType(declType)
+	if k != arrowUnknown {
+		fits := true
+		for _, v := range values {
+			if p := v.GetParameters(); i < len(p) && !arrowFits(k, p[i]) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return k
+		}
+	}
+
+	// Infer the kind from the values themselves.
+	k = arrowUnknown
+	for _, v := range values {
+		p := v.GetParameters()
+		if i >= len(p) {
+			continue
+		}
+		k = arrowMerge(k, arrowKindOfValue(p[i]))
+		if k == arrowString {
+			break
+		}
+	}
+	if k == arrowUnknown {
+		return arrowString
+	}
+	return k
+}
+
+// arrowKindOfDeclType returns the Arrow representation implied by the given
+// declared column type, or arrowUnknown if there is none.
+func arrowKindOfDeclType(declType string) arrowKind {
+	t := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(t, "BOOL"):
+		return arrowBool
+	case strings.Contains(t, "INT"):
+		return arrowInt
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return arrowString
+	case strings.Contains(t, "BLOB"):
+		return arrowBinary
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return arrowFloat
+	default:
+		return arrowUnknown
+	}
+}
+
+// arrowKindOfValue returns the Arrow representation of the given value.
+func arrowKindOfValue(p *proto.Parameter) arrowKind {
+	switch p.GetValue().(type) {
+	case *proto.Parameter_B:
+		return arrowBool
+	case *proto.Parameter_I:
+		return arrowInt
+	case *proto.Parameter_D:
+		return arrowFloat
+	case *proto.Parameter_Y:
+		return arrowBinary
+	case *proto.Parameter_S:
+		return arrowString
+	default:
+		return arrowUnknown
+	}
+}
+
+// arrowMerge returns the Arrow representation able to hold values of both
+// kinds a and b.
+func arrowMerge(a, b arrowKind) arrowKind {
+	if a == arrowUnknown || a == b {
+		return b
+	}
+	if b == arrowUnknown {
+		return a
+	}
+	lo, hi := min(a, b), max(a, b)
+	if hi <= arrowFloat && lo >= arrowBool {
+		// Booleans, integers and floats widen to the larger kind.
+		return hi
+	}
+	return arrowString
+}
+
+// arrowFits returns whether the given value can be stored in a column of
+// kind k without loss.
+func arrowFits(k arrowKind, p *proto.Parameter) bool {
+	switch p.GetValue().(type) {
+	case nil:
+		return true
+	case *proto.Parameter_B:
+		return k == arrowBool || k == arrowInt || k == arrowString
+	case *proto.Parameter_I:
+		return k == arrowInt || k == arrowFloat || k == arrowString
+	case *proto.Parameter_D:
+		return k == arrowFloat || k == arrowString
+	case *proto.Parameter_Y:
+		return k == arrowBinary || k == arrowString
+	default:
+		return k == arrowString
+	}
+}
+
+// arrowDataType returns the Arrow data type for columns of kind k.
+func arrowDataType(k arrowKind, bytesAsArray bool) arrow.DataType {
+	switch k {
+	case arrowBool:
+		return arrow.FixedWidthTypes.Boolean
+	case arrowInt:
+		return arrow.PrimitiveTypes.Int64
+	case arrowFloat:
+		return arrow.PrimitiveTypes.Float64
+	case arrowBinary:
+		if bytesAsArray {
+			return arrow.ListOf(arrow.PrimitiveTypes.Uint8)
+		}
+		return arrow.BinaryTypes.Binary
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// appendArrowValue appends the value p to the builder b, which holds values
+// of kind k. A nil p is appended as NULL.
+func appendArrowValue(b array.Builder, k arrowKind, p *proto.Parameter, bytesAsArray bool) error {
+	if p.GetValue() == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch k {
+	case arrowBool:
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_B:
+			b.(*array.BooleanBuilder).Append(v.B)
+		case *proto.Parameter_I:
+			b.(*array.BooleanBuilder).Append(v.I != 0)
+		}
+	case arrowInt:
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_I:
+			b.(*array.Int64Builder).Append(v.I)
+		case *proto.Parameter_B:
+			var i int64
+			if v.B {
+				i = 1
+			}
+			b.(*array.Int64Builder).Append(i)
+		}
+	case arrowFloat:
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_D:
+			b.(*array.Float64Builder).Append(v.D)
+		case *proto.Parameter_I:
+			b.(*array.Float64Builder).Append(float64(v.I))
+		case *proto.Parameter_B:
+			var f float64
+			if v.B {
+				f = 1
+			}
+			b.(*array.Float64Builder).Append(f)
+		}
+	case arrowBinary:
+		y := p.GetY()
+		if bytesAsArray {
+			lb := b.(*array.ListBuilder)
+			lb.Append(true)
+			lb.ValueBuilder().(*array.Uint8Builder).AppendValues(y, nil)
+		} else {
+			b.(*array.BinaryBuilder).Append(y)
+		}
+	default:
+		s, err := textValue(p, bytesAsArray)
+		if err != nil {
+			return err
+		}
+		b.(*array.StringBuilder).Append(s)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// WriteCSV writes the given QueryRows to w as CSV, with a header row naming
+// the columns. NULL values are written as empty fields. BLOB values are
+// written as base64, or as JSON arrays of integers if bytesAsArray is set.
+func WriteCSV(w io.Writer, q *proto.QueryRows, bytesAsArray bool) error {
+	if len(q.Columns) != len(q.Types) {
+		return ErrTypesColumnsLengthViolation
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(q.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(q.Columns))
+	for _, v := range q.Values {
+		params := v.GetParameters()
+		for i := range record {
+			record[i] = ""
+			if i < len(params) {
+				s, err := textValue(params[i], bytesAsArray)
+				if err != nil {
+					return err
+				}
+				record[i] = s
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// textValue returns the text representation of the given parameter. NULL is
+// represented by the empty string.
+func textValue(p *proto.Parameter, bytesAsArray bool) (string, error) {
+	switch w := p.GetValue().(type) {
+	case *proto.Parameter_I:
+		return strconv.FormatInt(w.I, 10), nil
+	case *proto.Parameter_D:
+		return strconv.FormatFloat(w.D, 'g', -1, 64), nil
+	case *proto.Parameter_B:
+		return strconv.FormatBool(w.B), nil
+	case *proto.Parameter_Y:
+		if bytesAsArray {
+			b, err := ByteSliceAsArray(w.Y).MarshalJSON()
+			if err != nil {
+				return "", err
+			}
+			return string(b), nil
+		}
+		return base64.StdEncoding.EncodeToString(w.Y), nil
+	case *proto.Parameter_S:
+		return w.S, nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported parameter type: %T", w)
+	}
+}
@@ -0,0 +1,208 @@
+package encoding
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func testQueryRows() *proto.QueryRows {
+	return &proto.QueryRows{
+		Columns: []string{"id", "name", "score", "data", "expr"},
+		Types:   []string{"integer", "text", "real", "blob", "numeric"},
+		Values: []*proto.Values{
+			{
+				Parameters: []*proto.Parameter{
+					{Value: &proto.Parameter_I{I: 1}},
+					{Value: &proto.Parameter_S{S: "fiona, \"the\" first"}},
+					{Value: &proto.Parameter_D{D: 1.5}},
+					{Value: &proto.Parameter_Y{Y: []byte{1, 2}}},
+					{Value: &proto.Parameter_I{I: 7}},
+				},
+			},
+			{
+				Parameters: []*proto.Parameter{
+					{Value: &proto.Parameter_I{I: 2}},
+					{},
+					{Value: &proto.Parameter_I{I: 3}},
+					{},
+					{Value: &proto.Parameter_D{D: 2.5}},
+				},
+			},
+		},
+	}
+}
+
+func Test_WriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testQueryRows(), false); err != nil {
+		t.Fatalf("failed to write CSV: %s", err)
+	}
+	exp := "id,name,score,data,expr\n1,\"fiona, \"\"the\"\" first\",1.5,AQI=,7\n2,,3,,2.5\n"
+	if got := buf.String(); got != exp {
+		t.Fatalf("wrong CSV, exp %q, got %q", exp, got)
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, testQueryRows(), true); err != nil {
+		t.Fatalf("failed to write CSV: %s", err)
+	}
+	exp = "id,name,score,data,expr\n1,\"fiona, \"\"the\"\" first\",1.5,\"[1,2]\",7\n2,,3,,2.5\n"
+	if got := buf.String(); got != exp {
+		t.Fatalf("wrong CSV with blob arrays, exp %q, got %q", exp, got)
+	}
+}
+
+func Test_WriteCSV_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	q := &proto.QueryRows{Columns: []string{"a"}, Types: []string{"integer"}}
+	if err := WriteCSV(&buf, q, false); err != nil {
+		t.Fatalf("failed to write CSV: %s", err)
+	}
+	if exp, got := "a\n", buf.String(); exp != got {
+		t.Fatalf("wrong CSV, exp %q, got %q", exp, got)
+	}
+
+	q.Types = nil
+	if err := WriteCSV(&buf, q, false); err != ErrTypesColumnsLengthViolation {
+		t.Fatalf("expected ErrTypesColumnsLengthViolation, got %v", err)
+	}
+}
+
+func Test_WriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, testQueryRows(), false); err != nil {
+		t.Fatalf("failed to write NDJSON: %s", err)
+	}
+	exp := `{"id":1,"name":"fiona, \"the\" first","score":1.5,"data":"AQI=","expr":7}
+{"id":2,"name":null,"score":3,"data":null,"expr":2.5}
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("wrong NDJSON, exp %q, got %q", exp, got)
+	}
+
+	buf.Reset()
+	if err := WriteNDJSON(&buf, testQueryRows(), true); err != nil {
+		t.Fatalf("failed to write NDJSON: %s", err)
+	}
+	exp = `{"id":1,"name":"fiona, \"the\" first","score":1.5,"data":[1,2],"expr":7}
+{"id":2,"name":null,"score":3,"data":null,"expr":2.5}
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("wrong NDJSON with blob arrays, exp %q, got %q", exp, got)
+	}
+}
+
+func Test_WriteArrow(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, testQueryRows(), false); err != nil {
+		t.Fatalf("failed to write Arrow: %s", err)
+	}
+
+	rdr, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to create Arrow reader: %s", err)
+	}
+	defer rdr.Release()
+
+	expTypes := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		arrow.BinaryTypes.String,
+		arrow.PrimitiveTypes.Float64,
+		arrow.BinaryTypes.Binary,
+		arrow.PrimitiveTypes.Float64,
+	}
+	for i, f := range rdr.Schema().Fields() {
+		if !arrow.TypeEqual(f.Type, expTypes[i]) {
+			t.Fatalf("column %s: exp type %s, got %s", f.Name, expTypes[i], f.Type)
+		}
+	}
+
+	if !rdr.Next() {
+		t.Fatalf("no record batch in Arrow stream: %v", rdr.Err())
+	}
+	rec := rdr.RecordBatch()
+	if rec.NumRows() != 2 {
+		t.Fatalf("expected 2 rows, got %d", rec.NumRows())
+	}
+	if got := rec.Column(0).(*array.Int64).Value(1); got != 2 {
+		t.Fatalf("wrong id, exp 2, got %d", got)
+	}
+	if !rec.Column(1).IsNull(1) {
+		t.Fatalf("expected NULL name")
+	}
+	if got := rec.Column(2).(*array.Float64).Value(1); got != 3 {
+		t.Fatalf("wrong score, exp 3, got %v", got)
+	}
+	if got := rec.Column(3).(*array.Binary).Value(0); !bytes.Equal(got, []byte{1, 2}) {
+		t.Fatalf("wrong data, exp [1 2], got %v", got)
+	}
+	if rdr.Next() {
+		t.Fatalf("unexpected second record batch")
+	}
+}
+
+func Test_WriteArrow_BlobArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, testQueryRows(), true); err != nil {
+		t.Fatalf("failed to write Arrow: %s", err)
+	}
+	rdr, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to create Arrow reader: %s", err)
+	}
+	defer rdr.Release()
+
+	if exp, got := arrow.ListOf(arrow.PrimitiveTypes.Uint8), rdr.Schema().Field(3).Type; !arrow.TypeEqual(exp, got) {
+		t.Fatalf("exp type %s, got %s", exp, got)
+	}
+	if !rdr.Next() {
+		t.Fatalf("no record batch in Arrow stream: %v", rdr.Err())
+	}
+	l := rdr.RecordBatch().Column(3).(*array.List)
+	start, end := l.ValueOffsets(0)
+	if end-start != 2 || !l.IsNull(1) {
+		t.Fatalf("unexpected list column: %v", l)
+	}
+}
+
+func Test_ArrowColumnKind(t *testing.T) {
+	i := func(v int64) *proto.Values {
+		return &proto.Values{Parameters: []*proto.Parameter{{Value: &proto.Parameter_I{I: v}}}}
+	}
+	s := func(v string) *proto.Values {
+		return &proto.Values{Parameters: []*proto.Parameter{{Value: &proto.Parameter_S{S: v}}}}
+	}
+	b := func(v bool) *proto.Values {
+		return &proto.Values{Parameters: []*proto.Parameter{{Value: &proto.Parameter_B{B: v}}}}
+	}
+	null := &proto.Values{Parameters: []*proto.Parameter{{}}}
+
+	for _, tc := range []struct {
+		name     string
+		declType string
+		values   []*proto.Values
+		exp      arrowKind
+	}{
+		{"integer", "integer", []*proto.Values{i(1), null}, arrowInt},
+		{"bigint", "bigint", []*proto.Values{i(1)}, arrowInt},
+		{"varchar", "varchar(10)", []*proto.Values{s("a")}, arrowString},
+		{"boolean", "boolean", []*proto.Values{b(true), null}, arrowBool},
+		{"text in integer column", "integer", []*proto.Values{i(1), s("a")}, arrowString},
+		{"real holds integers", "real", []*proto.Values{i(1)}, arrowFloat},
+		{"empty type inferred", "", []*proto.Values{i(1), i(2)}, arrowInt},
+		{"datetime inferred", "datetime", []*proto.Values{s("2024-01-01T00:00:00Z")}, arrowString},
+		{"all null", "", []*proto.Values{null}, arrowString},
+		{"no rows", "integer", nil, arrowInt},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := arrowColumnKind(tc.declType, tc.values, 0); got != tc.exp {
+				t.Fatalf("exp %v, got %v", tc.exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// WriteNDJSON writes the given QueryRows to w as newline-delimited JSON, one
+// object per row, with members in column order. BLOB values are encoded as
+// base64 strings, or as arrays of integers if bytesAsArray is set.
+func WriteNDJSON(w io.Writer, q *proto.QueryRows, bytesAsArray bool) error {
+	if len(q.Columns) != len(q.Types) {
+		return ErrTypesColumnsLengthViolation
+	}
+
+	keys := make([][]byte, len(q.Columns))
+	for i, c := range q.Columns {
+		b, err := noEscapeEncode(c)
+		if err != nil {
+			return err
+		}
+		keys[i] = b
+	}
+
+	bw := bufio.NewWriter(w)
+	values := make([][]any, 1)
+	var line bytes.Buffer
+	for _, v := range q.Values {
+		if err := NewValuesFromQueryValues(values, []*proto.Values{v}, bytesAsArray); err != nil {
+			return err
+		}
+		line.Reset()
+		line.WriteByte('{')
+		for i := range keys {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.Write(keys[i])
+			line.WriteByte(':')
+			var val any
+			if i < len(values[0]) {
+				val = values[0][i]
+			}
+			b, err := noEscapeEncode(val)
+			if err != nil {
+				return err
+			}
+			line.Write(b)
+		}
+		line.WriteString("}\n")
+		if _, err := bw.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,464 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+const (
+	// FormatCSV is comma-separated values, with a header row naming the fields.
+	FormatCSV = "csv"
+
+	// FormatNDJSON is newline-delimited JSON, one object per line.
+	FormatNDJSON = "ndjson"
+)
+
+const (
+	// OnConflictAbort causes a constraint violation to fail the import.
+	OnConflictAbort = "abort"
+
+	// OnConflictIgnore causes rows violating a constraint to be skipped.
+	OnConflictIgnore = "ignore"
+
+	// OnConflictReplace causes rows violating a constraint to replace the
+	// existing rows.
+	OnConflictReplace = "replace"
+)
+
+const (
+	// DefaultBatchRows is the default maximum number of rows in a batch.
+	DefaultBatchRows = 1000
+
+	// DefaultBatchBytes is the default approximate maximum size of a batch.
+	// Batches are sent through the Raft log in chunks, using command/chunking,
+	// so need not fit in a single log entry, but each batch is executed as its
+	// own transaction, and is held in memory when it is. An import is
+	// therefore not atomic.
+	DefaultBatchBytes = 4 * 1024 * 1024
+
+	// sampleSize is the number of records examined to infer column types.
+	sampleSize = 100
+)
+
+var (
+	// ErrUnsupportedFormat is returned when an unknown import format is requested.
+	ErrUnsupportedFormat = errors.New("unsupported import format")
+
+	// ErrInvalidOnConflict is returned when an unknown conflict behavior is requested.
+	ErrInvalidOnConflict = errors.New("invalid on_conflict value")
+
+	// ErrNoTable is returned when no destination table is specified.
+	ErrNoTable = errors.New("no table specified")
+
+	// ErrNoColumns is returned when no import field maps to a column of
+	// the destination table.
+	ErrNoColumns = errors.New("no fields map to table columns")
+)
+
+// Config is the configuration of an import.
+type Config struct {
+	// Table is the name of the destination table.
+	Table string
+
+	// Format is the format of the data, FormatCSV or FormatNDJSON.
+	Format string
+
+	// Mapping maps source field names to column names. Fields not present
+	// in Mapping are imported into the column with the same name.
+	Mapping map[string]string
+
+	// OnConflict is the behavior on constraint violations. Defaults to
+	// OnConflictAbort.
+	OnConflict string
+
+	// BatchRows is the maximum number of rows in a batch. Defaults to
+	// DefaultBatchRows.
+	BatchRows int
+
+	// BatchBytes is the approximate maximum size of a batch, in bytes.
+	// Defaults to DefaultBatchBytes.
+	BatchBytes int
+}
+
+// Column is a column of the destination table.
+type Column struct {
+	Name string
+	Type string
+}
+
+// csvField is the value of a field read from CSV data. Unlike values read
+// from NDJSON data, it carries no type information.
+type csvField string
+
+// field is a single field of a record.
+type field struct {
+	name  string
+	value any
+}
+
+// record is a single row of import data, with its fields in source order.
+type record struct {
+	n      int64 // 1-based record number, for error reporting.
+	fields []field
+}
+
+// recordReader reads records from import data.
+type recordReader interface {
+	Read() (*record, error)
+}
+
+// Importer reads CSV or NDJSON data, and converts it into batches of INSERT
+// statements, each batch an ExecuteRequest executed as a single transaction.
+// Batches are sent to the Leader in chunks, using command/chunking.
+type Importer struct {
+	cfg     Config
+	rr      recordReader
+	verb    string
+	pending []*record // Records read for sampling, not yet batched.
+	columns map[string]Column
+	nRows   int64
+}
+
+// New returns an Importer reading data from r.
+func New(r io.Reader, cfg *Config) (*Importer, error) {
+	if cfg.Table == "" {
+		return nil, ErrNoTable
+	}
+	i := &Importer{cfg: *cfg}
+	if i.cfg.BatchRows <= 0 {
+		i.cfg.BatchRows = DefaultBatchRows
+	}
+	if i.cfg.BatchBytes <= 0 {
+		i.cfg.BatchBytes = DefaultBatchBytes
+	}
+
+	switch strings.ToLower(i.cfg.OnConflict) {
+	case "", OnConflictAbort:
+		i.verb = "INSERT"
+	case OnConflictIgnore:
+		i.verb = "INSERT OR IGNORE"
+	case OnConflictReplace:
+		i.verb = "INSERT OR REPLACE"
+	default:
+		return nil, ErrInvalidOnConflict
+	}
+
+	switch strings.ToLower(i.cfg.Format) {
+	case FormatCSV:
+		cr, err := newCSVReader(r)
+		if err != nil {
+			return nil, err
+		}
+		i.rr = cr
+	case FormatNDJSON:
+		i.rr = newNDJSONReader(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	return i, nil
+}
+
+// Rows returns the number of rows placed in batches so far.
+func (i *Importer) Rows() int64 {
+	return i.nRows
+}
+
+// InferColumns examines the first records of the data, and returns the
+// destination columns, with types inferred from the values of those records.
+func (i *Importer) InferColumns() ([]Column, error) {
+	for len(i.pending) < sampleSize {
+		rec, err := i.rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		i.pending = append(i.pending, rec)
+	}
+
+	var names []string
+	kinds := make(map[string]kind)
+	for _, rec := range i.pending {
+		for _, f := range rec.fields {
+			name := i.columnName(f.name)
+			if name == "" {
+				continue
+			}
+			k, ok := kinds[name]
+			if !ok {
+				names = append(names, name)
+			}
+			kinds[name] = k.merge(kindOf(f.value))
+		}
+	}
+	if len(names) == 0 {
+		return nil, ErrNoColumns
+	}
+
+	cols := make([]Column, len(names))
+	for j, name := range names {
+		cols[j] = Column{Name: name, Type: kinds[name].declType()}
+	}
+	return cols, nil
+}
+
+// CreateTableStatement returns the statement which creates the destination
+// table with the given columns, if it does not exist.
+func (i *Importer) CreateTableStatement(cols []Column) *proto.Statement {
+	defs := make([]string, len(cols))
+	for j, c := range cols {
+		defs[j] = fmt.Sprintf("%s %s", quoteIdent(c.Name), c.Type)
+	}
+	return &proto.Statement{
+		Sql: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdent(i.cfg.Table), strings.Join(defs, ", ")),
+	}
+}
+
+// AccessStatement returns a statement which inserts a row into the
+// destination table as the batches do, so that a client's permission to do so
+// can be checked before the table is read or created. Every statement of every
+// batch accesses tables only as this statement does.
+func (i *Importer) AccessStatement() *proto.Statement {
+	return &proto.Statement{
+		Sql: fmt.Sprintf("%s INTO %s DEFAULT VALUES", i.verb, quoteIdent(i.cfg.Table)),
+	}
+}
+
+// SetColumns sets the columns of the destination table. Values are coerced
+// to the declared types of these columns, and fields which do not map to
+// any of these columns are ignored.
+func (i *Importer) SetColumns(cols []Column) error {
+	i.columns = make(map[string]Column, len(cols))
+	for _, c := range cols {
+		i.columns[strings.ToLower(c.Name)] = c
+	}
+	if len(i.cfg.Mapping) == 0 {
+		return nil
+	}
+	for src, dst := range i.cfg.Mapping {
+		if dst == "" {
+			continue
+		}
+		if _, ok := i.columns[strings.ToLower(dst)]; !ok {
+			return fmt.Errorf("field %s maps to unknown column %s", src, dst)
+		}
+	}
+	return nil
+}
+
+// Next returns the next batch of INSERT statements, or io.EOF if all data
+// has been read. SetColumns must be called before Next.
+func (i *Importer) Next() (*proto.ExecuteRequest, error) {
+	if i.columns == nil {
+		return nil, ErrNoColumns
+	}
+
+	var stmts []*proto.Statement
+	sz := 0
+	for len(stmts) < i.cfg.BatchRows && sz < i.cfg.BatchBytes {
+		var rec *record
+		if len(i.pending) > 0 {
+			rec, i.pending = i.pending[0], i.pending[1:]
+		} else {
+			var err error
+			rec, err = i.rr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stmt, err := i.insert(rec)
+		if err != nil {
+			return nil, err
+		}
+		if stmt == nil {
+			continue
+		}
+		stmts = append(stmts, stmt)
+		sz += statementSize(stmt)
+	}
+	if len(stmts) == 0 {
+		return nil, io.EOF
+	}
+
+	i.nRows += int64(len(stmts))
+	return &proto.ExecuteRequest{
+		Request: &proto.Request{
+			Transaction: true,
+			Statements:  stmts,
+		},
+	}, nil
+}
+
+// insert returns the INSERT statement for rec, or nil if none of its fields
+// map to a column.
+func (i *Importer) insert(rec *record) (*proto.Statement, error) {
+	var names []string
+	var params []*proto.Parameter
+	for _, f := range rec.fields {
+		name := i.columnName(f.name)
+		if name == "" {
+			continue
+		}
+		col, ok := i.columns[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		p, err := coerce(f.value, col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: column %s: %w", rec.n, col.Name, err)
+		}
+		names = append(names, quoteIdent(col.Name))
+		params = append(params, p)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	return &proto.Statement{
+		Sql: fmt.Sprintf("%s INTO %s (%s) VALUES (%s)", i.verb, quoteIdent(i.cfg.Table),
+			strings.Join(names, ", "), placeholders),
+		Parameters: params,
+	}, nil
+}
+
+// columnName returns the name of the column the given source field maps to,
+// or the empty string if the field is not to be imported.
+func (i *Importer) columnName(src string) string {
+	if dst, ok := i.cfg.Mapping[src]; ok {
+		return dst
+	}
+	return src
+}
+
+// csvReader reads records from CSV data. The first row is the header.
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+	n      int64
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV data has no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	// The header fixes the number of fields expected in every record.
+	cr.FieldsPerRecord = len(header)
+	return &csvReader{r: cr, header: header}, nil
+}
+
+func (c *csvReader) Read() (*record, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	c.n++
+	rec := &record{n: c.n, fields: make([]field, len(row))}
+	for j, v := range row {
+		rec.fields[j] = field{name: c.header[j], value: csvField(v)}
+	}
+	return rec, nil
+}
+
+// ndjsonReader reads records from NDJSON data. Blank lines are skipped.
+type ndjsonReader struct {
+	s *bufio.Scanner
+	n int64
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), DefaultBatchBytes)
+	return &ndjsonReader{s: s}
+}
+
+func (nr *ndjsonReader) Read() (*record, error) {
+	for nr.s.Scan() {
+		line := strings.TrimSpace(nr.s.Text())
+		if line == "" {
+			continue
+		}
+		nr.n++
+		fields, err := decodeObject(line)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", nr.n, err)
+		}
+		return &record{n: nr.n, fields: fields}, nil
+	}
+	if err := nr.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// decodeObject decodes a single JSON object, returning its members in the
+// order they appear.
+func decodeObject(s string) ([]field, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, errors.New("line is not a JSON object")
+	}
+
+	var fields []field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{name: tok.(string), value: v})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// statementSize returns the approximate size of stmt, in bytes.
+func statementSize(stmt *proto.Statement) int {
+	sz := len(stmt.Sql)
+	for _, p := range stmt.Parameters {
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_S:
+			sz += len(v.S)
+		case *proto.Parameter_Y:
+			sz += len(v.Y)
+		default:
+			sz += 8
+		}
+	}
+	return sz
+}
+
+// quoteIdent quotes the given SQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
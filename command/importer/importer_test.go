@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_New_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		exp  error
+	}{
+		{"no table", Config{Format: FormatCSV}, ErrNoTable},
+		{"bad format", Config{Table: "foo", Format: "xml"}, ErrUnsupportedFormat},
+		{"bad on_conflict", Config{Table: "foo", Format: FormatCSV, OnConflict: "update"}, ErrInvalidOnConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(strings.NewReader("a\n1\n"), &tc.cfg)
+			if !errors.Is(err, tc.exp) {
+				t.Fatalf("expected %v, got %v", tc.exp, err)
+			}
+		})
+	}
+}
+
+func Test_CSV(t *testing.T) {
+	data := "\ufeffid,name,age,skip\n1,fiona,20,x\n2,declan,,y\n"
+	imp, err := New(strings.NewReader(data), &Config{
+		Table:      "foo",
+		Format:     FormatCSV,
+		Mapping:    map[string]string{"skip": ""},
+		OnConflict: OnConflictReplace,
+	})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"id", "INTEGER"}, {"name", "TEXT"}, {"age", "INT"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+
+	er, err := imp.Next()
+	if err != nil {
+		t.Fatalf("failed to get batch: %s", err)
+	}
+	if !er.Request.Transaction {
+		t.Fatalf("batch is not a transaction")
+	}
+	stmts := er.Request.Statements
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(stmts))
+	}
+	if exp, got := `INSERT OR REPLACE INTO "foo" ("id", "name", "age") VALUES (?, ?, ?)`, stmts[0].Sql; exp != got {
+		t.Fatalf("wrong SQL, exp %s, got %s", exp, got)
+	}
+	if exp, got := `INSERT OR REPLACE INTO "foo" DEFAULT VALUES`, imp.AccessStatement().Sql; exp != got {
+		t.Fatalf("wrong access statement, exp %s, got %s", exp, got)
+	}
+	if exp, got := int64(1), stmts[0].Parameters[0].GetI(); exp != got {
+		t.Fatalf("wrong id, exp %d, got %d", exp, got)
+	}
+	if exp, got := "fiona", stmts[0].Parameters[1].GetS(); exp != got {
+		t.Fatalf("wrong name, exp %s, got %s", exp, got)
+	}
+	if stmts[1].Parameters[2].GetValue() != nil {
+		t.Fatalf("expected NULL for empty age, got %v", stmts[1].Parameters[2])
+	}
+
+	if _, err := imp.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if imp.Rows() != 2 {
+		t.Fatalf("expected 2 rows, got %d", imp.Rows())
+	}
+}
+
+func Test_CSV_BadRecord(t *testing.T) {
+	imp, err := New(strings.NewReader("id,name\n1\n"), &Config{Table: "foo", Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"id", "INTEGER"}, {"name", "TEXT"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+	if _, err := imp.Next(); err == nil {
+		t.Fatalf("expected error for record with wrong number of fields")
+	}
+}
+
+func Test_NDJSON(t *testing.T) {
+	data := `{"id":1,"name":"fiona","tags":["a","b"],"active":true,"extra":"x"}
+
+{"name":"declan","id":"2","active":false}
+`
+	imp, err := New(strings.NewReader(data), &Config{Table: "foo", Format: FormatNDJSON})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"ID", "INTEGER"}, {"name", "TEXT"}, {"tags", "TEXT"}, {"active", "BOOLEAN"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+
+	er, err := imp.Next()
+	if err != nil {
+		t.Fatalf("failed to get batch: %s", err)
+	}
+	stmts := er.Request.Statements
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(stmts))
+	}
+	if exp, got := `INSERT INTO "foo" ("ID", "name", "tags", "active") VALUES (?, ?, ?, ?)`, stmts[0].Sql; exp != got {
+		t.Fatalf("wrong SQL, exp %s, got %s", exp, got)
+	}
+	if exp, got := `["a","b"]`, stmts[0].Parameters[2].GetS(); exp != got {
+		t.Fatalf("wrong tags, exp %s, got %s", exp, got)
+	}
+	if exp, got := int64(1), stmts[0].Parameters[3].GetI(); exp != got {
+		t.Fatalf("wrong active, exp %d, got %d", exp, got)
+	}
+	if exp, got := `INSERT INTO "foo" ("name", "ID", "active") VALUES (?, ?, ?)`, stmts[1].Sql; exp != got {
+		t.Fatalf("wrong SQL, exp %s, got %s", exp, got)
+	}
+	if exp, got := int64(2), stmts[1].Parameters[1].GetI(); exp != got {
+		t.Fatalf("wrong id, exp %d, got %d", exp, got)
+	}
+}
+
+func Test_NDJSON_BadLine(t *testing.T) {
+	imp, err := New(strings.NewReader("{\"id\":1}\n[1,2]\n"), &Config{Table: "foo", Format: FormatNDJSON})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"id", "INTEGER"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+	if _, err := imp.Next(); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Fatalf("expected error for record 2, got %v", err)
+	}
+}
+
+func Test_Coercion_Error(t *testing.T) {
+	imp, err := New(strings.NewReader("id\nabc\n"), &Config{Table: "foo", Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"id", "INTEGER"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+	if _, err := imp.Next(); err == nil {
+		t.Fatalf("expected error coercing text to INTEGER")
+	}
+}
+
+func Test_InferColumns(t *testing.T) {
+	data := "a,b,c,d\n1,1.5,x,\n2,3,4,\n"
+	imp, err := New(strings.NewReader(data), &Config{
+		Table:   "foo",
+		Format:  FormatCSV,
+		Mapping: map[string]string{"a": "id"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	cols, err := imp.InferColumns()
+	if err != nil {
+		t.Fatalf("failed to infer columns: %s", err)
+	}
+	exp := []Column{{"id", "INTEGER"}, {"b", "REAL"}, {"c", "TEXT"}, {"d", "TEXT"}}
+	if len(cols) != len(exp) {
+		t.Fatalf("expected %d columns, got %d", len(exp), len(cols))
+	}
+	for i := range exp {
+		if cols[i] != exp[i] {
+			t.Fatalf("column %d: exp %v, got %v", i, exp[i], cols[i])
+		}
+	}
+	if exp, got := `CREATE TABLE IF NOT EXISTS "foo" ("id" INTEGER, "b" REAL, "c" TEXT, "d" TEXT)`, imp.CreateTableStatement(cols).Sql; exp != got {
+		t.Fatalf("wrong create statement, exp %s, got %s", exp, got)
+	}
+	if exp, got := `INSERT INTO "foo" DEFAULT VALUES`, imp.AccessStatement().Sql; exp != got {
+		t.Fatalf("wrong access statement, exp %s, got %s", exp, got)
+	}
+
+	// Records read while sampling must still be imported.
+	if err := imp.SetColumns(cols); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+	er, err := imp.Next()
+	if err != nil {
+		t.Fatalf("failed to get batch: %s", err)
+	}
+	if len(er.Request.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(er.Request.Statements))
+	}
+	if exp, got := float64(3), er.Request.Statements[1].Parameters[1].GetD(); exp != got {
+		t.Fatalf("wrong REAL value, exp %v, got %v", exp, got)
+	}
+}
+
+func Test_Batching(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id\n")
+	for i := 0; i < 25; i++ {
+		sb.WriteString("1\n")
+	}
+	imp, err := New(strings.NewReader(sb.String()), &Config{Table: "foo", Format: FormatCSV, BatchRows: 10})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"id", "INTEGER"}}); err != nil {
+		t.Fatalf("failed to set columns: %s", err)
+	}
+
+	var sizes []int
+	for {
+		er, err := imp.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get batch: %s", err)
+		}
+		sizes = append(sizes, len(er.Request.Statements))
+	}
+	if len(sizes) != 3 || sizes[0] != 10 || sizes[1] != 10 || sizes[2] != 5 {
+		t.Fatalf("unexpected batch sizes: %v", sizes)
+	}
+}
+
+func Test_SetColumns_UnknownMapping(t *testing.T) {
+	imp, err := New(strings.NewReader("a\n1\n"), &Config{
+		Table:   "foo",
+		Format:  FormatCSV,
+		Mapping: map[string]string{"a": "b"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create importer: %s", err)
+	}
+	if err := imp.SetColumns([]Column{{"a", "INTEGER"}}); err == nil {
+		t.Fatalf("expected error for mapping to unknown column")
+	}
+}
+
+func Test_Coerce(t *testing.T) {
+	for _, tc := range []struct {
+		v        any
+		declType string
+		exp      *proto.Parameter
+	}{
+		{csvField("5"), "INTEGER", intParam(5)},
+		{csvField("5.0"), "INTEGER", intParam(5)},
+		{csvField("5"), "REAL", &proto.Parameter{Value: &proto.Parameter_D{D: 5}}},
+		{csvField("5"), "TEXT", stringParam("5")},
+		{csvField("yes"), "BOOLEAN", intParam(1)},
+		{csvField("2024-01-01"), "DATETIME", stringParam("2024-01-01")},
+		{csvField(""), "INTEGER", &proto.Parameter{}},
+		{csvField(""), "TEXT", stringParam("")},
+		{nil, "TEXT", &proto.Parameter{}},
+		{true, "TEXT", stringParam("true")},
+		{map[string]any{"a": "b"}, "", stringParam(`{"a":"b"}`)},
+	} {
+		p, err := coerce(tc.v, tc.declType)
+		if err != nil {
+			t.Fatalf("failed to coerce %v to %s: %s", tc.v, tc.declType, err)
+		}
+		if p.String() != tc.exp.String() {
+			t.Fatalf("coerce %v to %s: exp %v, got %v", tc.v, tc.declType, tc.exp, p)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// affinity is a SQLite column type affinity.
+type affinity int
+
+const (
+	affinityBlob affinity = iota
+	affinityText
+	affinityNumeric
+	affinityInteger
+	affinityReal
+)
+
+// affinityOf returns the affinity of the given declared column type, as
+// determined by the rules SQLite itself uses.
+func affinityOf(declType string) affinity {
+	t := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return affinityInteger
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return affinityText
+	case t == "", strings.Contains(t, "BLOB"):
+		return affinityBlob
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return affinityReal
+	default:
+		return affinityNumeric
+	}
+}
+
+// coerce converts v, as decoded from the import data, into a parameter
+// suitable for a column of the given declared type.
+func coerce(v any, declType string) (*proto.Parameter, error) {
+	aff := affinityOf(declType)
+	switch val := v.(type) {
+	case nil:
+		return &proto.Parameter{}, nil
+	case bool:
+		if aff == affinityText {
+			return stringParam(strconv.FormatBool(val)), nil
+		}
+		if val {
+			return intParam(1), nil
+		}
+		return intParam(0), nil
+	case json.Number:
+		if aff == affinityText || aff == affinityBlob {
+			return stringParam(val.String()), nil
+		}
+		return numberParam(val.String(), aff)
+	case csvField:
+		return coerceString(string(val), declType, aff)
+	case string:
+		return coerceString(val, declType, aff)
+	default:
+		// Arrays and objects are stored as their JSON text.
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		return stringParam(string(b)), nil
+	}
+}
+
+// coerceString converts the string s into a parameter suitable for a column
+// with the given declared type and affinity. Empty strings are NULL in any
+// column which does not have TEXT or BLOB affinity.
+func coerceString(s, declType string, aff affinity) (*proto.Parameter, error) {
+	switch aff {
+	case affinityText, affinityBlob:
+		return stringParam(s), nil
+	}
+	if s == "" {
+		return &proto.Parameter{}, nil
+	}
+	if strings.Contains(strings.ToUpper(declType), "BOOL") {
+		switch strings.ToLower(s) {
+		case "true", "t", "yes", "y":
+			return intParam(1), nil
+		case "false", "f", "no", "n":
+			return intParam(0), nil
+		}
+	}
+	p, err := numberParam(s, aff)
+	if err != nil && aff == affinityNumeric {
+		// Values such as dates are stored as text in NUMERIC columns.
+		return stringParam(s), nil
+	}
+	return p, err
+}
+
+// numberParam parses s as a number for a column with the given affinity.
+func numberParam(s string, aff affinity) (*proto.Parameter, error) {
+	s = strings.TrimSpace(s)
+	if aff != affinityReal {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return intParam(i), nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q to a number", s)
+	}
+	if aff == affinityInteger && f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return intParam(int64(f)), nil
+	}
+	return &proto.Parameter{Value: &proto.Parameter_D{D: f}}, nil
+}
+
+func intParam(i int64) *proto.Parameter {
+	return &proto.Parameter{Value: &proto.Parameter_I{I: i}}
+}
+
+func stringParam(s string) *proto.Parameter {
+	return &proto.Parameter{Value: &proto.Parameter_S{S: s}}
+}
+
+// kind is the inferred type of the values of a field.
+type kind int
+
+const (
+	kindNull kind = iota
+	kindInteger
+	kindReal
+	kindText
+)
+
+// kindOf returns the kind of the given value. CSV fields, which are untyped,
+// are examined to see if they hold numbers.
+func kindOf(v any) kind {
+	switch val := v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindInteger
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return kindInteger
+		}
+		return kindReal
+	case csvField:
+		if val == "" {
+			return kindNull
+		}
+		if _, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+			return kindInteger
+		}
+		if _, err := strconv.ParseFloat(string(val), 64); err == nil {
+			return kindReal
+		}
+		return kindText
+	default:
+		return kindText
+	}
+}
+
+// merge returns the kind able to represent values of both k and o.
+func (k kind) merge(o kind) kind {
+	return max(k, o)
+}
+
+// declType returns the declared column type for values of kind k.
+func (k kind) declType() string {
+	switch k {
+	case kindInteger:
+		return "INTEGER"
+	case kindReal:
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
@@ -172,6 +172,26 @@ func UnmarshalLoadChunkRequest(b []byte, lr *proto.LoadChunkRequest) error {
 	return pb.Unmarshal(b, lr)
 }
 
+// MarshalImportBatch marshals the Request carrying a batch of an import.
+func MarshalImportBatch(r *proto.Request) ([]byte, error) {
+	return pb.Marshal(r)
+}
+
+// UnmarshalImportBatch unmarshals the Request carrying a batch of an import.
+func UnmarshalImportBatch(b []byte, r *proto.Request) error {
+	return pb.Unmarshal(b, r)
+}
+
+// MarshalChecksumRequest marshals a ChecksumRequest command
+func MarshalChecksumRequest(cr *proto.ChecksumRequest) ([]byte, error) {
+	return pb.Marshal(cr)
+}
+
+// UnmarshalChecksumRequest unmarshals a ChecksumRequest command
+func UnmarshalChecksumRequest(b []byte, cr *proto.ChecksumRequest) error {
+	return pb.Unmarshal(b, cr)
+}
+
 // MarshalAppendEntriesExtension marshals an AppendEntriesExtension
 // object into a byte slice.
 func MarshalAppendEntriesExtension(ext *proto.AppendEntriesExtension) ([]byte, error) {
@@ -177,6 +177,58 @@ func (BackupRequest_Format) EnumDescriptor() ([]byte, []int) {
 	return file_command_proto_rawDescGZIP(), []int{10, 0}
 }
 
+type BackupRequest_Compression int32
+
+const (
+	BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED BackupRequest_Compression = 0
+	BackupRequest_BACKUP_REQUEST_COMPRESSION_NONE        BackupRequest_Compression = 1
+	BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP        BackupRequest_Compression = 2
+	BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD        BackupRequest_Compression = 3
+)
+
+// Enum value maps for BackupRequest_Compression.
+var (
+	BackupRequest_Compression_name = map[int32]string{
+		0: "BACKUP_REQUEST_COMPRESSION_UNSPECIFIED",
+		1: "BACKUP_REQUEST_COMPRESSION_NONE",
+		2: "BACKUP_REQUEST_COMPRESSION_GZIP",
+		3: "BACKUP_REQUEST_COMPRESSION_ZSTD",
+	}
+	BackupRequest_Compression_value = map[string]int32{
+		"BACKUP_REQUEST_COMPRESSION_UNSPECIFIED": 0,
+		"BACKUP_REQUEST_COMPRESSION_NONE":        1,
+		"BACKUP_REQUEST_COMPRESSION_GZIP":        2,
+		"BACKUP_REQUEST_COMPRESSION_ZSTD":        3,
+	}
+)
+
+func (x BackupRequest_Compression) Enum() *BackupRequest_Compression {
+	p := new(BackupRequest_Compression)
+	*p = x
+	return p
+}
+
+func (x BackupRequest_Compression) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BackupRequest_Compression) Descriptor() protoreflect.EnumDescriptor {
+	return file_command_proto_enumTypes[3].Descriptor()
+}
+
+func (BackupRequest_Compression) Type() protoreflect.EnumType {
+	return &file_command_proto_enumTypes[3]
+}
+
+func (x BackupRequest_Compression) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BackupRequest_Compression.Descriptor instead.
+func (BackupRequest_Compression) EnumDescriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{10, 1}
+}
+
 type Command_Type int32
 
 const (
@@ -188,6 +240,7 @@ const (
 	Command_COMMAND_TYPE_JOIN          Command_Type = 5
 	Command_COMMAND_TYPE_EXECUTE_QUERY Command_Type = 6
 	Command_COMMAND_TYPE_LOAD_CHUNK    Command_Type = 7
+	Command_COMMAND_TYPE_CHECKSUM      Command_Type = 8
 )
 
 // Enum value maps for Command_Type.
@@ -201,6 +254,7 @@ var (
 		5: "COMMAND_TYPE_JOIN",
 		6: "COMMAND_TYPE_EXECUTE_QUERY",
 		7: "COMMAND_TYPE_LOAD_CHUNK",
+		8: "COMMAND_TYPE_CHECKSUM",
 	}
 	Command_Type_value = map[string]int32{
 		"COMMAND_TYPE_UNKNOWN":       0,
@@ -211,6 +265,7 @@ var (
 		"COMMAND_TYPE_JOIN":          5,
 		"COMMAND_TYPE_EXECUTE_QUERY": 6,
 		"COMMAND_TYPE_LOAD_CHUNK":    7,
+		"COMMAND_TYPE_CHECKSUM":      8,
 	}
 )
 
@@ -225,11 +280,11 @@ func (x Command_Type) String() string {
 }
 
 func (Command_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_command_proto_enumTypes[3].Descriptor()
+	return file_command_proto_enumTypes[4].Descriptor()
 }
 
 func (Command_Type) Type() protoreflect.EnumType {
-	return &file_command_proto_enumTypes[3]
+	return &file_command_proto_enumTypes[4]
 }
 
 func (x Command_Type) Number() protoreflect.EnumNumber {
@@ -238,7 +293,7 @@ func (x Command_Type) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Command_Type.Descriptor instead.
 func (Command_Type) EnumDescriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{18, 0}
+	return file_command_proto_rawDescGZIP(), []int{22, 0}
 }
 
 type CDCEvent_Operation int32
@@ -277,11 +332,11 @@ func (x CDCEvent_Operation) String() string {
 }
 
 func (CDCEvent_Operation) Descriptor() protoreflect.EnumDescriptor {
-	return file_command_proto_enumTypes[4].Descriptor()
+	return file_command_proto_enumTypes[5].Descriptor()
 }
 
 func (CDCEvent_Operation) Type() protoreflect.EnumType {
-	return &file_command_proto_enumTypes[4]
+	return &file_command_proto_enumTypes[5]
 }
 
 func (x CDCEvent_Operation) Number() protoreflect.EnumNumber {
@@ -290,7 +345,7 @@ func (x CDCEvent_Operation) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use CDCEvent_Operation.Descriptor instead.
 func (CDCEvent_Operation) EnumDescriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{21, 0}
+	return file_command_proto_rawDescGZIP(), []int{25, 0}
 }
 
 type UpdateHookEvent_Operation int32
@@ -329,11 +384,11 @@ func (x UpdateHookEvent_Operation) String() string {
 }
 
 func (UpdateHookEvent_Operation) Descriptor() protoreflect.EnumDescriptor {
-	return file_command_proto_enumTypes[5].Descriptor()
+	return file_command_proto_enumTypes[6].Descriptor()
 }
 
 func (UpdateHookEvent_Operation) Type() protoreflect.EnumType {
-	return &file_command_proto_enumTypes[5]
+	return &file_command_proto_enumTypes[6]
 }
 
 func (x UpdateHookEvent_Operation) Number() protoreflect.EnumNumber {
@@ -342,7 +397,7 @@ func (x UpdateHookEvent_Operation) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use UpdateHookEvent_Operation.Descriptor instead.
 func (UpdateHookEvent_Operation) EnumDescriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{24, 0}
+	return file_command_proto_rawDescGZIP(), []int{28, 0}
 }
 
 type Parameter struct {
@@ -490,6 +545,9 @@ type Statement struct {
 	ForceQuery    bool                   `protobuf:"varint,3,opt,name=forceQuery,proto3" json:"forceQuery,omitempty"`
 	ForceStall    bool                   `protobuf:"varint,4,opt,name=forceStall,proto3" json:"forceStall,omitempty"`
 	SqlExplain    bool                   `protobuf:"varint,5,opt,name=sql_explain,json=sqlExplain,proto3" json:"sql_explain,omitempty"`
+	Name          string                 `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	DefaultTime   int64                  `protobuf:"varint,7,opt,name=default_time,json=defaultTime,proto3" json:"default_time,omitempty"`
+	DefaultSeed   int64                  `protobuf:"varint,8,opt,name=default_seed,json=defaultSeed,proto3" json:"default_seed,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -559,6 +617,27 @@ func (x *Statement) GetSqlExplain() bool {
 	return false
 }
 
+func (x *Statement) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Statement) GetDefaultTime() int64 {
+	if x != nil {
+		return x.DefaultTime
+	}
+	return 0
+}
+
+func (x *Statement) GetDefaultSeed() int64 {
+	if x != nil {
+		return x.DefaultSeed
+	}
+	return 0
+}
+
 type Request struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	Transaction     bool                   `protobuf:"varint,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
@@ -1142,12 +1221,13 @@ func (*ExecuteQueryResponse_E) isExecuteQueryResponse_Result() {}
 func (*ExecuteQueryResponse_Error) isExecuteQueryResponse_Result() {}
 
 type BackupRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Format        BackupRequest_Format   `protobuf:"varint,1,opt,name=format,proto3,enum=command.BackupRequest_Format" json:"format,omitempty"`
-	Leader        bool                   `protobuf:"varint,2,opt,name=Leader,proto3" json:"Leader,omitempty"`
-	Vacuum        bool                   `protobuf:"varint,3,opt,name=Vacuum,proto3" json:"Vacuum,omitempty"`
-	Compress      bool                   `protobuf:"varint,4,opt,name=Compress,proto3" json:"Compress,omitempty"`
-	Tables        []string               `protobuf:"bytes,5,rep,name=tables,proto3" json:"tables,omitempty"`
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Format        BackupRequest_Format      `protobuf:"varint,1,opt,name=format,proto3,enum=command.BackupRequest_Format" json:"format,omitempty"`
+	Leader        bool                      `protobuf:"varint,2,opt,name=Leader,proto3" json:"Leader,omitempty"`
+	Vacuum        bool                      `protobuf:"varint,3,opt,name=Vacuum,proto3" json:"Vacuum,omitempty"`
+	Compress      bool                      `protobuf:"varint,4,opt,name=Compress,proto3" json:"Compress,omitempty"`
+	Tables        []string                  `protobuf:"bytes,5,rep,name=tables,proto3" json:"tables,omitempty"`
+	Compression   BackupRequest_Compression `protobuf:"varint,6,opt,name=compression,proto3,enum=command.BackupRequest_Compression" json:"compression,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -1217,6 +1297,13 @@ func (x *BackupRequest) GetTables() []string {
 	return nil
 }
 
+func (x *BackupRequest) GetCompression() BackupRequest_Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED
+}
+
 type LoadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
@@ -1268,6 +1355,7 @@ type LoadChunkRequest struct {
 	IsLast        bool                   `protobuf:"varint,3,opt,name=is_last,json=isLast,proto3" json:"is_last,omitempty"`
 	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
 	Abort         bool                   `protobuf:"varint,5,opt,name=abort,proto3" json:"abort,omitempty"`
+	ImportAccess  string                 `protobuf:"bytes,6,opt,name=import_access,json=importAccess,proto3" json:"import_access,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -1337,6 +1425,13 @@ func (x *LoadChunkRequest) GetAbort() bool {
 	return false
 }
 
+func (x *LoadChunkRequest) GetImportAccess() string {
+	if x != nil {
+		return x.ImportAccess
+	}
+	return ""
+}
+
 type JoinRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -1589,6 +1684,230 @@ func (x *Noop) GetId() string {
 	return ""
 }
 
+type ChecksumRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkSize     int64                  `protobuf:"varint,1,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChecksumRequest) Reset() {
+	*x = ChecksumRequest{}
+	mi := &file_command_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChecksumRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChecksumRequest) ProtoMessage() {}
+
+func (x *ChecksumRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChecksumRequest.ProtoReflect.Descriptor instead.
+func (*ChecksumRequest) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ChecksumRequest) GetChunkSize() int64 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+type TableChecksum struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Table         string                 `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	Start         int64                  `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           int64                  `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	Rows          int64                  `protobuf:"varint,4,opt,name=rows,proto3" json:"rows,omitempty"`
+	Sum           string                 `protobuf:"bytes,5,opt,name=sum,proto3" json:"sum,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TableChecksum) Reset() {
+	*x = TableChecksum{}
+	mi := &file_command_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TableChecksum) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TableChecksum) ProtoMessage() {}
+
+func (x *TableChecksum) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TableChecksum.ProtoReflect.Descriptor instead.
+func (*TableChecksum) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *TableChecksum) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *TableChecksum) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *TableChecksum) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *TableChecksum) GetRows() int64 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *TableChecksum) GetSum() string {
+	if x != nil {
+		return x.Sum
+	}
+	return ""
+}
+
+type ChecksumResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	ChunkSize     int64                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	Checksums     []*TableChecksum       `protobuf:"bytes,3,rep,name=checksums,proto3" json:"checksums,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChecksumResult) Reset() {
+	*x = ChecksumResult{}
+	mi := &file_command_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChecksumResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChecksumResult) ProtoMessage() {}
+
+func (x *ChecksumResult) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChecksumResult.ProtoReflect.Descriptor instead.
+func (*ChecksumResult) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ChecksumResult) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ChecksumResult) GetChunkSize() int64 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *ChecksumResult) GetChecksums() []*TableChecksum {
+	if x != nil {
+		return x.Checksums
+	}
+	return nil
+}
+
+type ChecksumResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChecksumResultRequest) Reset() {
+	*x = ChecksumResultRequest{}
+	mi := &file_command_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChecksumResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChecksumResultRequest) ProtoMessage() {}
+
+func (x *ChecksumResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChecksumResultRequest.ProtoReflect.Descriptor instead.
+func (*ChecksumResultRequest) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ChecksumResultRequest) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
 type Command struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Type          Command_Type           `protobuf:"varint,1,opt,name=type,proto3,enum=command.Command_Type" json:"type,omitempty"`
@@ -1600,7 +1919,7 @@ type Command struct {
 
 func (x *Command) Reset() {
 	*x = Command{}
-	mi := &file_command_proto_msgTypes[18]
+	mi := &file_command_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1612,7 +1931,7 @@ func (x *Command) String() string {
 func (*Command) ProtoMessage() {}
 
 func (x *Command) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[18]
+	mi := &file_command_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1625,7 +1944,7 @@ func (x *Command) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Command.ProtoReflect.Descriptor instead.
 func (*Command) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{18}
+	return file_command_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *Command) GetType() Command_Type {
@@ -1665,7 +1984,7 @@ type CDCValue struct {
 
 func (x *CDCValue) Reset() {
 	*x = CDCValue{}
-	mi := &file_command_proto_msgTypes[19]
+	mi := &file_command_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1677,7 +1996,7 @@ func (x *CDCValue) String() string {
 func (*CDCValue) ProtoMessage() {}
 
 func (x *CDCValue) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[19]
+	mi := &file_command_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1690,7 +2009,7 @@ func (x *CDCValue) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CDCValue.ProtoReflect.Descriptor instead.
 func (*CDCValue) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{19}
+	return file_command_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *CDCValue) GetValue() isCDCValue_Value {
@@ -1788,7 +2107,7 @@ type CDCRow struct {
 
 func (x *CDCRow) Reset() {
 	*x = CDCRow{}
-	mi := &file_command_proto_msgTypes[20]
+	mi := &file_command_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1800,7 +2119,7 @@ func (x *CDCRow) String() string {
 func (*CDCRow) ProtoMessage() {}
 
 func (x *CDCRow) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[20]
+	mi := &file_command_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1813,7 +2132,7 @@ func (x *CDCRow) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CDCRow.ProtoReflect.Descriptor instead.
 func (*CDCRow) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{20}
+	return file_command_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *CDCRow) GetValues() []*CDCValue {
@@ -1839,7 +2158,7 @@ type CDCEvent struct {
 
 func (x *CDCEvent) Reset() {
 	*x = CDCEvent{}
-	mi := &file_command_proto_msgTypes[21]
+	mi := &file_command_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1851,7 +2170,7 @@ func (x *CDCEvent) String() string {
 func (*CDCEvent) ProtoMessage() {}
 
 func (x *CDCEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[21]
+	mi := &file_command_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1864,7 +2183,7 @@ func (x *CDCEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CDCEvent.ProtoReflect.Descriptor instead.
 func (*CDCEvent) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{21}
+	return file_command_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *CDCEvent) GetError() string {
@@ -1935,7 +2254,7 @@ type CDCIndexedEventGroup struct {
 
 func (x *CDCIndexedEventGroup) Reset() {
 	*x = CDCIndexedEventGroup{}
-	mi := &file_command_proto_msgTypes[22]
+	mi := &file_command_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1947,7 +2266,7 @@ func (x *CDCIndexedEventGroup) String() string {
 func (*CDCIndexedEventGroup) ProtoMessage() {}
 
 func (x *CDCIndexedEventGroup) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[22]
+	mi := &file_command_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1960,7 +2279,7 @@ func (x *CDCIndexedEventGroup) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CDCIndexedEventGroup.ProtoReflect.Descriptor instead.
 func (*CDCIndexedEventGroup) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{22}
+	return file_command_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *CDCIndexedEventGroup) GetIndex() uint64 {
@@ -2000,7 +2319,7 @@ type CDCIndexedEventGroupBatch struct {
 
 func (x *CDCIndexedEventGroupBatch) Reset() {
 	*x = CDCIndexedEventGroupBatch{}
-	mi := &file_command_proto_msgTypes[23]
+	mi := &file_command_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2012,7 +2331,7 @@ func (x *CDCIndexedEventGroupBatch) String() string {
 func (*CDCIndexedEventGroupBatch) ProtoMessage() {}
 
 func (x *CDCIndexedEventGroupBatch) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[23]
+	mi := &file_command_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2025,7 +2344,7 @@ func (x *CDCIndexedEventGroupBatch) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CDCIndexedEventGroupBatch.ProtoReflect.Descriptor instead.
 func (*CDCIndexedEventGroupBatch) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{23}
+	return file_command_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *CDCIndexedEventGroupBatch) GetPayload() []*CDCIndexedEventGroup {
@@ -2047,7 +2366,7 @@ type UpdateHookEvent struct {
 
 func (x *UpdateHookEvent) Reset() {
 	*x = UpdateHookEvent{}
-	mi := &file_command_proto_msgTypes[24]
+	mi := &file_command_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2059,7 +2378,7 @@ func (x *UpdateHookEvent) String() string {
 func (*UpdateHookEvent) ProtoMessage() {}
 
 func (x *UpdateHookEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[24]
+	mi := &file_command_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2072,7 +2391,7 @@ func (x *UpdateHookEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateHookEvent.ProtoReflect.Descriptor instead.
 func (*UpdateHookEvent) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{24}
+	return file_command_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *UpdateHookEvent) GetError() string {
@@ -2112,7 +2431,7 @@ type AppendEntriesExtension struct {
 
 func (x *AppendEntriesExtension) Reset() {
 	*x = AppendEntriesExtension{}
-	mi := &file_command_proto_msgTypes[25]
+	mi := &file_command_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2124,7 +2443,7 @@ func (x *AppendEntriesExtension) String() string {
 func (*AppendEntriesExtension) ProtoMessage() {}
 
 func (x *AppendEntriesExtension) ProtoReflect() protoreflect.Message {
-	mi := &file_command_proto_msgTypes[25]
+	mi := &file_command_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2137,7 +2456,7 @@ func (x *AppendEntriesExtension) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AppendEntriesExtension.ProtoReflect.Descriptor instead.
 func (*AppendEntriesExtension) Descriptor() ([]byte, []int) {
-	return file_command_proto_rawDescGZIP(), []int{25}
+	return file_command_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *AppendEntriesExtension) GetCdcHWM() uint64 {
@@ -2159,7 +2478,7 @@ const file_command_proto_rawDesc = "" +
 	"\x01y\x18\x04 \x01(\fH\x00R\x01y\x12\x0e\n" +
 	"\x01s\x18\x05 \x01(\tH\x00R\x01s\x12\x12\n" +
 	"\x04name\x18\x06 \x01(\tR\x04nameB\a\n" +
-	"\x05value\"\xb2\x01\n" +
+	"\x05value\"\x8c\x02\n" +
 	"\tStatement\x12\x10\n" +
 	"\x03sql\x18\x01 \x01(\tR\x03sql\x122\n" +
 	"\n" +
@@ -2172,7 +2491,10 @@ const file_command_proto_rawDesc = "" +
 	"forceStall\x18\x04 \x01(\bR\n" +
 	"forceStall\x12\x1f\n" +
 	"\vsql_explain\x18\x05 \x01(\bR\n" +
-	"sqlExplain\"\xcf\x01\n" +
+	"sqlExplain\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12!\n" +
+	"\fdefault_time\x18\a \x01(\x03R\vdefaultTime\x12!\n" +
+	"\fdefault_seed\x18\b \x01(\x03R\vdefaultSeed\"\xcf\x01\n" +
 	"\aRequest\x12 \n" +
 	"\vtransaction\x18\x01 \x01(\bR\vtransaction\x122\n" +
 	"\n" +
@@ -2217,26 +2539,33 @@ const file_command_proto_rawDesc = "" +
 	"\x01q\x18\x01 \x01(\v2\x12.command.QueryRowsH\x00R\x01q\x12&\n" +
 	"\x01e\x18\x02 \x01(\v2\x16.command.ExecuteResultH\x00R\x01e\x12\x16\n" +
 	"\x05error\x18\x03 \x01(\tH\x00R\x05errorB\b\n" +
-	"\x06result\"\xb8\x02\n" +
+	"\x06result\"\xa9\x04\n" +
 	"\rBackupRequest\x125\n" +
 	"\x06format\x18\x01 \x01(\x0e2\x1d.command.BackupRequest.FormatR\x06format\x12\x16\n" +
 	"\x06Leader\x18\x02 \x01(\bR\x06Leader\x12\x16\n" +
 	"\x06Vacuum\x18\x03 \x01(\bR\x06Vacuum\x12\x1a\n" +
 	"\bCompress\x18\x04 \x01(\bR\bCompress\x12\x16\n" +
-	"\x06tables\x18\x05 \x03(\tR\x06tables\"\x8b\x01\n" +
+	"\x06tables\x18\x05 \x03(\tR\x06tables\x12D\n" +
+	"\vcompression\x18\x06 \x01(\x0e2\".command.BackupRequest.CompressionR\vcompression\"\x8b\x01\n" +
 	"\x06Format\x12\x1e\n" +
 	"\x1aBACKUP_REQUEST_FORMAT_NONE\x10\x00\x12\x1d\n" +
 	"\x19BACKUP_REQUEST_FORMAT_SQL\x10\x01\x12 \n" +
 	"\x1cBACKUP_REQUEST_FORMAT_BINARY\x10\x02\x12 \n" +
-	"\x1cBACKUP_REQUEST_FORMAT_DELETE\x10\x03\"!\n" +
+	"\x1cBACKUP_REQUEST_FORMAT_DELETE\x10\x03\"\xa8\x01\n" +
+	"\vCompression\x12*\n" +
+	"&BACKUP_REQUEST_COMPRESSION_UNSPECIFIED\x10\x00\x12#\n" +
+	"\x1fBACKUP_REQUEST_COMPRESSION_NONE\x10\x01\x12#\n" +
+	"\x1fBACKUP_REQUEST_COMPRESSION_GZIP\x10\x02\x12#\n" +
+	"\x1fBACKUP_REQUEST_COMPRESSION_ZSTD\x10\x03\"!\n" +
 	"\vLoadRequest\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data\"\x95\x01\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\xba\x01\n" +
 	"\x10LoadChunkRequest\x12\x1b\n" +
 	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x12!\n" +
 	"\fsequence_num\x18\x02 \x01(\x03R\vsequenceNum\x12\x17\n" +
 	"\ais_last\x18\x03 \x01(\bR\x06isLast\x12\x12\n" +
 	"\x04data\x18\x04 \x01(\fR\x04data\x12\x14\n" +
-	"\x05abort\x18\x05 \x01(\bR\x05abort\"M\n" +
+	"\x05abort\x18\x05 \x01(\bR\x05abort\x12#\n" +
+	"\rimport_access\x18\x06 \x01(\tR\fimportAccess\"M\n" +
 	"\vJoinRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
 	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x14\n" +
@@ -2250,14 +2579,30 @@ const file_command_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04wait\x18\x02 \x01(\bR\x04wait\"\x16\n" +
 	"\x04Noop\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\xcc\x02\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"0\n" +
+	"\x0fChecksumRequest\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x01 \x01(\x03R\tchunkSize\"s\n" +
+	"\rTableChecksum\x12\x14\n" +
+	"\x05table\x18\x01 \x01(\tR\x05table\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x03R\x03end\x12\x12\n" +
+	"\x04rows\x18\x04 \x01(\x03R\x04rows\x12\x10\n" +
+	"\x03sum\x18\x05 \x01(\tR\x03sum\"{\n" +
+	"\x0eChecksumResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x02 \x01(\x03R\tchunkSize\x124\n" +
+	"\tchecksums\x18\x03 \x03(\v2\x16.command.TableChecksumR\tchecksums\"-\n" +
+	"\x15ChecksumResultRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\"\xe7\x02\n" +
 	"\aCommand\x12)\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x15.command.Command.TypeR\x04type\x12\x1f\n" +
 	"\vsub_command\x18\x02 \x01(\fR\n" +
 	"subCommand\x12\x1e\n" +
 	"\n" +
 	"compressed\x18\x03 \x01(\bR\n" +
-	"compressed\"\xd4\x01\n" +
+	"compressed\"\xef\x01\n" +
 	"\x04Type\x12\x18\n" +
 	"\x14COMMAND_TYPE_UNKNOWN\x10\x00\x12\x16\n" +
 	"\x12COMMAND_TYPE_QUERY\x10\x01\x12\x18\n" +
@@ -2266,7 +2611,8 @@ const file_command_proto_rawDesc = "" +
 	"\x11COMMAND_TYPE_LOAD\x10\x04\x12\x15\n" +
 	"\x11COMMAND_TYPE_JOIN\x10\x05\x12\x1e\n" +
 	"\x1aCOMMAND_TYPE_EXECUTE_QUERY\x10\x06\x12\x1b\n" +
-	"\x17COMMAND_TYPE_LOAD_CHUNK\x10\a\"c\n" +
+	"\x17COMMAND_TYPE_LOAD_CHUNK\x10\a\x12\x19\n" +
+	"\x15COMMAND_TYPE_CHECKSUM\x10\b\"c\n" +
 	"\bCDCValue\x12\x0e\n" +
 	"\x01i\x18\x01 \x01(\x12H\x00R\x01i\x12\x0e\n" +
 	"\x01d\x18\x02 \x01(\x01H\x00R\x01d\x12\x0e\n" +
@@ -2341,68 +2687,75 @@ func file_command_proto_rawDescGZIP() []byte {
 	return file_command_proto_rawDescData
 }
 
-var file_command_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
-var file_command_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_command_proto_enumTypes = make([]protoimpl.EnumInfo, 7)
+var file_command_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
 var file_command_proto_goTypes = []any{
 	(Suffrage)(0),                     // 0: command.Suffrage
 	(ConsistencyLevel)(0),             // 1: command.ConsistencyLevel
 	(BackupRequest_Format)(0),         // 2: command.BackupRequest.Format
-	(Command_Type)(0),                 // 3: command.Command.Type
-	(CDCEvent_Operation)(0),           // 4: command.CDCEvent.Operation
-	(UpdateHookEvent_Operation)(0),    // 5: command.UpdateHookEvent.Operation
-	(*Parameter)(nil),                 // 6: command.Parameter
-	(*Statement)(nil),                 // 7: command.Statement
-	(*Request)(nil),                   // 8: command.Request
-	(*QueryRequest)(nil),              // 9: command.QueryRequest
-	(*Values)(nil),                    // 10: command.Values
-	(*QueryRows)(nil),                 // 11: command.QueryRows
-	(*ExecuteRequest)(nil),            // 12: command.ExecuteRequest
-	(*ExecuteResult)(nil),             // 13: command.ExecuteResult
-	(*ExecuteQueryRequest)(nil),       // 14: command.ExecuteQueryRequest
-	(*ExecuteQueryResponse)(nil),      // 15: command.ExecuteQueryResponse
-	(*BackupRequest)(nil),             // 16: command.BackupRequest
-	(*LoadRequest)(nil),               // 17: command.LoadRequest
-	(*LoadChunkRequest)(nil),          // 18: command.LoadChunkRequest
-	(*JoinRequest)(nil),               // 19: command.JoinRequest
-	(*NotifyRequest)(nil),             // 20: command.NotifyRequest
-	(*RemoveNodeRequest)(nil),         // 21: command.RemoveNodeRequest
-	(*StepdownRequest)(nil),           // 22: command.StepdownRequest
-	(*Noop)(nil),                      // 23: command.Noop
-	(*Command)(nil),                   // 24: command.Command
-	(*CDCValue)(nil),                  // 25: command.CDCValue
-	(*CDCRow)(nil),                    // 26: command.CDCRow
-	(*CDCEvent)(nil),                  // 27: command.CDCEvent
-	(*CDCIndexedEventGroup)(nil),      // 28: command.CDCIndexedEventGroup
-	(*CDCIndexedEventGroupBatch)(nil), // 29: command.CDCIndexedEventGroupBatch
-	(*UpdateHookEvent)(nil),           // 30: command.UpdateHookEvent
-	(*AppendEntriesExtension)(nil),    // 31: command.AppendEntriesExtension
+	(BackupRequest_Compression)(0),    // 3: command.BackupRequest.Compression
+	(Command_Type)(0),                 // 4: command.Command.Type
+	(CDCEvent_Operation)(0),           // 5: command.CDCEvent.Operation
+	(UpdateHookEvent_Operation)(0),    // 6: command.UpdateHookEvent.Operation
+	(*Parameter)(nil),                 // 7: command.Parameter
+	(*Statement)(nil),                 // 8: command.Statement
+	(*Request)(nil),                   // 9: command.Request
+	(*QueryRequest)(nil),              // 10: command.QueryRequest
+	(*Values)(nil),                    // 11: command.Values
+	(*QueryRows)(nil),                 // 12: command.QueryRows
+	(*ExecuteRequest)(nil),            // 13: command.ExecuteRequest
+	(*ExecuteResult)(nil),             // 14: command.ExecuteResult
+	(*ExecuteQueryRequest)(nil),       // 15: command.ExecuteQueryRequest
+	(*ExecuteQueryResponse)(nil),      // 16: command.ExecuteQueryResponse
+	(*BackupRequest)(nil),             // 17: command.BackupRequest
+	(*LoadRequest)(nil),               // 18: command.LoadRequest
+	(*LoadChunkRequest)(nil),          // 19: command.LoadChunkRequest
+	(*JoinRequest)(nil),               // 20: command.JoinRequest
+	(*NotifyRequest)(nil),             // 21: command.NotifyRequest
+	(*RemoveNodeRequest)(nil),         // 22: command.RemoveNodeRequest
+	(*StepdownRequest)(nil),           // 23: command.StepdownRequest
+	(*Noop)(nil),                      // 24: command.Noop
+	(*ChecksumRequest)(nil),           // 25: command.ChecksumRequest
+	(*TableChecksum)(nil),             // 26: command.TableChecksum
+	(*ChecksumResult)(nil),            // 27: command.ChecksumResult
+	(*ChecksumResultRequest)(nil),     // 28: command.ChecksumResultRequest
+	(*Command)(nil),                   // 29: command.Command
+	(*CDCValue)(nil),                  // 30: command.CDCValue
+	(*CDCRow)(nil),                    // 31: command.CDCRow
+	(*CDCEvent)(nil),                  // 32: command.CDCEvent
+	(*CDCIndexedEventGroup)(nil),      // 33: command.CDCIndexedEventGroup
+	(*CDCIndexedEventGroupBatch)(nil), // 34: command.CDCIndexedEventGroupBatch
+	(*UpdateHookEvent)(nil),           // 35: command.UpdateHookEvent
+	(*AppendEntriesExtension)(nil),    // 36: command.AppendEntriesExtension
 }
 var file_command_proto_depIdxs = []int32{
-	6,  // 0: command.Statement.parameters:type_name -> command.Parameter
-	7,  // 1: command.Request.statements:type_name -> command.Statement
-	8,  // 2: command.QueryRequest.request:type_name -> command.Request
+	7,  // 0: command.Statement.parameters:type_name -> command.Parameter
+	8,  // 1: command.Request.statements:type_name -> command.Statement
+	9,  // 2: command.QueryRequest.request:type_name -> command.Request
 	1,  // 3: command.QueryRequest.level:type_name -> command.ConsistencyLevel
-	6,  // 4: command.Values.parameters:type_name -> command.Parameter
-	10, // 5: command.QueryRows.values:type_name -> command.Values
-	8,  // 6: command.ExecuteRequest.request:type_name -> command.Request
-	8,  // 7: command.ExecuteQueryRequest.request:type_name -> command.Request
+	7,  // 4: command.Values.parameters:type_name -> command.Parameter
+	11, // 5: command.QueryRows.values:type_name -> command.Values
+	9,  // 6: command.ExecuteRequest.request:type_name -> command.Request
+	9,  // 7: command.ExecuteQueryRequest.request:type_name -> command.Request
 	1,  // 8: command.ExecuteQueryRequest.level:type_name -> command.ConsistencyLevel
-	11, // 9: command.ExecuteQueryResponse.q:type_name -> command.QueryRows
-	13, // 10: command.ExecuteQueryResponse.e:type_name -> command.ExecuteResult
+	12, // 9: command.ExecuteQueryResponse.q:type_name -> command.QueryRows
+	14, // 10: command.ExecuteQueryResponse.e:type_name -> command.ExecuteResult
 	2,  // 11: command.BackupRequest.format:type_name -> command.BackupRequest.Format
-	3,  // 12: command.Command.type:type_name -> command.Command.Type
-	25, // 13: command.CDCRow.values:type_name -> command.CDCValue
-	4,  // 14: command.CDCEvent.op:type_name -> command.CDCEvent.Operation
-	26, // 15: command.CDCEvent.old_row:type_name -> command.CDCRow
-	26, // 16: command.CDCEvent.new_row:type_name -> command.CDCRow
-	27, // 17: command.CDCIndexedEventGroup.events:type_name -> command.CDCEvent
-	28, // 18: command.CDCIndexedEventGroupBatch.payload:type_name -> command.CDCIndexedEventGroup
-	5,  // 19: command.UpdateHookEvent.op:type_name -> command.UpdateHookEvent.Operation
-	20, // [20:20] is the sub-list for method output_type
-	20, // [20:20] is the sub-list for method input_type
-	20, // [20:20] is the sub-list for extension type_name
-	20, // [20:20] is the sub-list for extension extendee
-	0,  // [0:20] is the sub-list for field type_name
+	3,  // 12: command.BackupRequest.compression:type_name -> command.BackupRequest.Compression
+	26, // 13: command.ChecksumResult.checksums:type_name -> command.TableChecksum
+	4,  // 14: command.Command.type:type_name -> command.Command.Type
+	30, // 15: command.CDCRow.values:type_name -> command.CDCValue
+	5,  // 16: command.CDCEvent.op:type_name -> command.CDCEvent.Operation
+	31, // 17: command.CDCEvent.old_row:type_name -> command.CDCRow
+	31, // 18: command.CDCEvent.new_row:type_name -> command.CDCRow
+	32, // 19: command.CDCIndexedEventGroup.events:type_name -> command.CDCEvent
+	33, // 20: command.CDCIndexedEventGroupBatch.payload:type_name -> command.CDCIndexedEventGroup
+	6,  // 21: command.UpdateHookEvent.op:type_name -> command.UpdateHookEvent.Operation
+	22, // [22:22] is the sub-list for method output_type
+	22, // [22:22] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
 }
 
 func init() { file_command_proto_init() }
@@ -2422,7 +2775,7 @@ func file_command_proto_init() {
 		(*ExecuteQueryResponse_E)(nil),
 		(*ExecuteQueryResponse_Error)(nil),
 	}
-	file_command_proto_msgTypes[19].OneofWrappers = []any{
+	file_command_proto_msgTypes[23].OneofWrappers = []any{
 		(*CDCValue_I)(nil),
 		(*CDCValue_D)(nil),
 		(*CDCValue_B)(nil),
@@ -2434,8 +2787,8 @@ func file_command_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_command_proto_rawDesc), len(file_command_proto_rawDesc)),
-			NumEnums:      6,
-			NumMessages:   26,
+			NumEnums:      7,
+			NumMessages:   30,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
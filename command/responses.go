@@ -0,0 +1,22 @@
+package command
+
+import (
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// ExecuteQueryResponsesError returns the first error contained in the given
+// responses, or the empty string if there is none.
+func ExecuteQueryResponsesError(results []*proto.ExecuteQueryResponse) string {
+	for _, r := range results {
+		if e := r.GetError(); e != "" {
+			return e
+		}
+		if e := r.GetE().GetError(); e != "" {
+			return e
+		}
+		if e := r.GetQ().GetError(); e != "" {
+			return e
+		}
+	}
+	return ""
+}
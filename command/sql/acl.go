@@ -0,0 +1,380 @@
+package sql
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/sql"
+)
+
+// TableACL decides which operations users may perform on which tables.
+type TableACL interface {
+	// Check returns whether the password is correct for the user.
+	Check(username, password string) bool
+
+	// TokenUser returns the username asserted by the bearer token, if the
+	// token is verified.
+	TokenUser(token string) (string, bool)
+
+	// CertUser returns the username mapped to the verified TLS client
+	// certificate.
+	CertUser(cert *x509.Certificate) (string, bool)
+
+	// HasTableACL returns whether the access of the user to tables is
+	// restricted.
+	HasTableACL(username string) bool
+
+	// TableAllowed returns whether the user may perform op on the table.
+	TableAllowed(username, table, op string) bool
+}
+
+// TableAccess is an operation a statement performs on a table. Table is empty
+// for statements which are not specific to a table, such as PRAGMA.
+type TableAccess struct {
+	Table string
+	Op    string
+}
+
+// ACLError is returned when an Authorizer denies statements.
+type ACLError struct {
+	Denials []string
+}
+
+// Error returns the string representation of the error.
+func (e *ACLError) Error() string {
+	return "not authorized: " + strings.Join(e.Denials, "; ")
+}
+
+// Authorizer checks that users only access the tables their ACL rules permit.
+// Statements are parsed to find every table they read, write or change the
+// schema of, including tables read by subqueries. Statements which cannot be
+// parsed are denied to users whose access is restricted. Statements which
+// refer to the table of users are denied to every user.
+//
+// An Authorizer is safe for concurrent use.
+type Authorizer struct {
+	acl TableACL
+}
+
+// NewAuthorizer returns a new Authorizer, which checks statements against the
+// given ACL.
+func NewAuthorizer(acl TableACL) *Authorizer {
+	return &Authorizer{acl: acl}
+}
+
+// Check returns an *ACLError listing every operation in the given statements
+// which the user is not permitted to perform. Users whose password is not
+// correct are treated as anonymous. lookupFn, if not nil, returns the SQL of
+// named statements. A nil Authorizer permits everything.
+func (a *Authorizer) Check(username, password string, stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	if a == nil {
+		return nil
+	}
+	if username != "" && !a.acl.Check(username, password) {
+		// Endpoint permissions granted to all users are checked without
+		// authenticating, so the username may not be genuine.
+		username = ""
+	}
+	return a.check(username, stmts, lookupFn)
+}
+
+// CheckToken is like Check, but for the user asserted by the given bearer
+// token. Users whose token is not verified are treated as anonymous.
+func (a *Authorizer) CheckToken(token string, stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	if a == nil {
+		return nil
+	}
+	username, ok := a.acl.TokenUser(token)
+	if !ok {
+		username = ""
+	}
+	return a.check(username, stmts, lookupFn)
+}
+
+// CheckCert is like Check, but for the user mapped to the given verified TLS
+// client certificate. Users whose certificate is not mapped are treated as
+// anonymous.
+func (a *Authorizer) CheckCert(cert *x509.Certificate, stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	if a == nil {
+		return nil
+	}
+	username, ok := a.acl.CertUser(cert)
+	if !ok {
+		username = ""
+	}
+	return a.check(username, stmts, lookupFn)
+}
+
+// CheckCertUser is like Check, but for the user to which a verified TLS client
+// certificate was mapped, such as by the node which forwarded a request.
+func (a *Authorizer) CheckCertUser(username string, stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	if a == nil {
+		return nil
+	}
+	return a.check(username, stmts, lookupFn)
+}
+
+func (a *Authorizer) check(username string, stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	restricted := a.acl.HasTableACL(username)
+	var denials []string
+	for i, stmt := range stmts {
+		s := stmt.Sql
+		if s == "" && stmt.Name != "" && lookupFn != nil {
+			var err error
+			if s, err = lookupFn(stmt.Name); err != nil {
+				// Missing statements are reported when executed.
+				continue
+			}
+		}
+		if t := ReferencesSystemTable(s); t != "" {
+			denials = append(denials, fmt.Sprintf("statement %d: access to table %s not permitted", i, t))
+			continue
+		}
+		if !restricted {
+			continue
+		}
+		accesses, err := TableAccesses(s)
+		if err != nil {
+			denials = append(denials, fmt.Sprintf("statement %d cannot be checked: %s", i, err))
+			continue
+		}
+		for _, ta := range accesses {
+			if a.acl.TableAllowed(username, ta.Table, ta.Op) {
+				continue
+			}
+			if ta.Table == "" {
+				denials = append(denials, fmt.Sprintf("statement %d: %s not permitted", i, ta.Op))
+			} else {
+				denials = append(denials, fmt.Sprintf("statement %d: %s on table %s not permitted", i, ta.Op, ta.Table))
+			}
+		}
+	}
+	if len(denials) == 0 {
+		return nil
+	}
+	stats.Add(numACLRejections, 1)
+	return &ACLError{Denials: denials}
+}
+
+// systemTables are the tables managed by rqlite itself. The table of users
+// holds password hashes, and the registry of named statements is an allow-list,
+// so each is only changed via its own endpoints, and no user may access either
+// with SQL.
+var systemTables = []string{auth.UsersTable, StatementsTable}
+
+// ReferencesSystemTable returns the name of the system table the statement may
+// refer to, or the empty string if it refers to none. The check is deliberately
+// coarse, so that no quoting of a table name escapes it.
+func ReferencesSystemTable(stmt string) string {
+	lower := strings.ToLower(stmt)
+	for _, t := range systemTables {
+		if strings.Contains(lower, t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// TableAccesses returns the operations the given SQL performs on tables. Each
+// table and operation is returned once, in the order first found. Table
+// names are returned in lower case.
+func TableAccesses(stmt string) (accesses []TableAccess, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stats.Add(numParserPanics, 1)
+			accesses, retErr = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	masked, _, err := maskReferences(stmt)
+	if err != nil {
+		return nil, err
+	}
+	c := &accessCollector{seen: make(map[TableAccess]bool)}
+	parser := sql.NewParser(strings.NewReader(masked))
+	for {
+		parsed, err := parser.ParseStatement()
+		if errors.Is(err, io.EOF) {
+			return c.accesses, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := c.statement(parsed); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// accessCollector records the operations statements perform on tables.
+type accessCollector struct {
+	seen     map[TableAccess]bool
+	accesses []TableAccess
+}
+
+func (c *accessCollector) add(table *sql.Ident, ops ...string) {
+	var name string
+	if table != nil {
+		name = strings.ToLower(table.Name)
+	}
+	for _, op := range ops {
+		ta := TableAccess{Table: name, Op: op}
+		if !c.seen[ta] {
+			c.seen[ta] = true
+			c.accesses = append(c.accesses, ta)
+		}
+	}
+}
+
+func (c *accessCollector) statement(parsed sql.Statement) error {
+	var target *sql.QualifiedTableName
+	switch n := parsed.(type) {
+	case *sql.ExplainStatement:
+		return c.statement(n.Stmt)
+	case *sql.BeginStatement, *sql.CommitStatement, *sql.RollbackStatement,
+		*sql.SavepointStatement, *sql.ReleaseStatement:
+		return nil
+	case *sql.SelectStatement:
+	case *sql.InsertStatement:
+		c.add(n.Table, auth.OpInsert)
+		if n.Replace.IsValid() || n.InsertOrReplace.IsValid() {
+			// Conflicting rows are deleted.
+			c.add(n.Table, auth.OpDelete)
+		}
+		if n.UpsertClause != nil && n.UpsertClause.DoUpdate.IsValid() {
+			c.add(n.Table, auth.OpUpdate)
+		}
+	case *sql.UpdateStatement:
+		target = n.Table
+		c.add(n.Table.Name, auth.OpUpdate)
+	case *sql.DeleteStatement:
+		target = n.Table
+		c.add(n.Table.Name, auth.OpDelete)
+	case *sql.CreateTableStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.CreateVirtualTableStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.AlterTableStatement:
+		c.add(n.Name, auth.OpDDL)
+		if n.NewName != nil {
+			c.add(n.NewName, auth.OpDDL)
+		}
+	case *sql.DropTableStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.CreateViewStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.DropViewStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.CreateIndexStatement:
+		c.add(n.Table, auth.OpDDL)
+	case *sql.DropIndexStatement:
+		// The table of the index is not known, so the index is named.
+		c.add(n.Name, auth.OpDDL)
+	case *sql.CreateTriggerStatement:
+		c.add(n.Table, auth.OpDDL)
+		// The body runs whenever the trigger fires, whoever fires it, so its
+		// creator must be permitted everything it does.
+		for _, s := range n.Body {
+			if err := c.statement(s); err != nil {
+				return err
+			}
+		}
+		if n.WhenExpr != nil {
+			return c.reads(n.WhenExpr, nil)
+		}
+		return nil
+	case *sql.DropTriggerStatement:
+		c.add(n.Name, auth.OpDDL)
+	case *sql.AnalyzeStatement:
+		c.add(n.Name, auth.OpDDL)
+	default:
+		// For example PRAGMA and REINDEX.
+		c.add(nil, auth.OpDDL)
+		return nil
+	}
+	return c.reads(parsed, target)
+}
+
+// reads records every table read by the given node, other than target,
+// which is the table written by an UPDATE or DELETE statement.
+func (c *accessCollector) reads(node sql.Node, target *sql.QualifiedTableName) error {
+	v := &readVisitor{c: c, target: target}
+	_, err := sql.Walk(v, node)
+	return err
+}
+
+// readVisitor records the tables read by the nodes it visits. Walk does not
+// visit common table expressions or subquery expressions, so the visitor
+// walks those itself.
+type readVisitor struct {
+	c      *accessCollector
+	target *sql.QualifiedTableName
+	ctes   map[string]bool // Names of the common table expressions in scope.
+}
+
+func (v *readVisitor) Visit(node sql.Node) (sql.Visitor, sql.Node, error) {
+	switch n := node.(type) {
+	case *sql.SelectStatement:
+		w, err := v.scope(n.WithClause)
+		return w, node, err
+	case *sql.InsertStatement:
+		w, err := v.scope(n.WithClause)
+		return w, node, err
+	case *sql.UpdateStatement:
+		w, err := v.scope(n.WithClause)
+		return w, node, err
+	case *sql.DeleteStatement:
+		w, err := v.scope(n.WithClause)
+		return w, node, err
+	case sql.SelectExpr:
+		if n.SelectStatement != nil {
+			if _, err := sql.Walk(v, n.SelectStatement); err != nil {
+				return nil, nil, err
+			}
+		}
+	case *sql.QualifiedTableName:
+		if n == v.target {
+			break
+		}
+		// Unqualified names may refer to common table expressions.
+		if n.Schema == nil && v.ctes[strings.ToLower(n.Name.Name)] {
+			break
+		}
+		v.c.add(n.Name, auth.OpSelect)
+	case *sql.QualifiedTableFunctionName:
+		// Table-valued functions, such as pragma_table_info, are read like
+		// tables.
+		v.c.add(n.Name, auth.OpSelect)
+	}
+	return v, node, nil
+}
+
+func (v *readVisitor) VisitEnd(node sql.Node) (sql.Node, error) {
+	return node, nil
+}
+
+// scope walks the common table expressions of wc, if any, and returns the
+// visitor for the statement they belong to. Each expression may refer to
+// itself, and to those before it.
+func (v *readVisitor) scope(wc *sql.WithClause) (*readVisitor, error) {
+	if wc == nil {
+		return v, nil
+	}
+	w := &readVisitor{c: v.c, target: v.target, ctes: make(map[string]bool, len(v.ctes)+len(wc.CTEs))}
+	maps.Copy(w.ctes, v.ctes)
+	for _, cte := range wc.CTEs {
+		w.ctes[strings.ToLower(cte.TableName.Name)] = true
+		if cte.Select == nil {
+			continue
+		}
+		cv := &readVisitor{c: v.c, ctes: maps.Clone(w.ctes)}
+		if _, err := sql.Walk(cv, cte.Select); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
@@ -0,0 +1,382 @@
+package sql
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_TableAccesses(t *testing.T) {
+	for i, tt := range []struct {
+		sql string
+		exp string
+	}{
+		{
+			sql: `SELECT * FROM foo`,
+			exp: "select:foo",
+		},
+		{
+			sql: `SELECT * FROM Foo AS f JOIN main.bar ON f.id = bar.id`,
+			exp: "select:foo,select:bar",
+		},
+		{
+			sql: `SELECT id FROM foo WHERE id IN (SELECT id FROM bar) AND EXISTS (SELECT 1 FROM baz)`,
+			exp: "select:foo,select:bar,select:baz",
+		},
+		{
+			sql: `SELECT (SELECT max(id) FROM bar) FROM foo`,
+			exp: "select:bar,select:foo",
+		},
+		{
+			sql: `SELECT * FROM (SELECT * FROM foo) UNION SELECT * FROM bar`,
+			exp: "select:foo,select:bar",
+		},
+		{
+			sql: `WITH t AS (SELECT * FROM foo) SELECT * FROM t`,
+			exp: "select:foo",
+		},
+		{
+			sql: `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c WHERE x < 5) SELECT x FROM c`,
+			exp: "",
+		},
+		{
+			sql: `WITH t AS (SELECT * FROM foo) SELECT * FROM main.t`,
+			exp: "select:foo,select:t",
+		},
+		{
+			sql: `WITH a AS (SELECT * FROM b), b AS (SELECT 1) SELECT * FROM a, b`,
+			exp: "select:b",
+		},
+		{
+			sql: `SELECT * FROM pragma_table_info('foo')`,
+			exp: "select:pragma_table_info",
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(1)`,
+			exp: "insert:foo",
+		},
+		{
+			sql: `INSERT INTO foo(id) SELECT id FROM bar`,
+			exp: "insert:foo,select:bar",
+		},
+		{
+			sql: `REPLACE INTO foo(id) VALUES(1)`,
+			exp: "insert:foo,delete:foo",
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(1) ON CONFLICT(id) DO UPDATE SET n = n + 1`,
+			exp: "insert:foo,update:foo",
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(1) ON CONFLICT DO NOTHING`,
+			exp: "insert:foo",
+		},
+		{
+			sql: `UPDATE foo SET n = (SELECT n FROM bar WHERE bar.id = foo.id)`,
+			exp: "update:foo,select:bar",
+		},
+		{
+			sql: `UPDATE foo SET n = 1 FROM bar WHERE bar.id = foo.id`,
+			exp: "update:foo,select:bar",
+		},
+		{
+			sql: `UPDATE foo SET n = 1 WHERE id IN (SELECT id FROM foo WHERE n = 0)`,
+			exp: "update:foo,select:foo",
+		},
+		{
+			sql: `DELETE FROM foo WHERE id IN (SELECT id FROM bar)`,
+			exp: "delete:foo,select:bar",
+		},
+		{
+			sql: `WITH t AS (SELECT id FROM bar) DELETE FROM foo WHERE id IN t`,
+			exp: "delete:foo,select:bar",
+		},
+		{
+			sql: `CREATE TABLE foo (id INTEGER PRIMARY KEY)`,
+			exp: "ddl:foo",
+		},
+		{
+			sql: `CREATE TABLE foo AS SELECT * FROM bar`,
+			exp: "ddl:foo,select:bar",
+		},
+		{
+			sql: `ALTER TABLE foo RENAME TO bar`,
+			exp: "ddl:foo,ddl:bar",
+		},
+		{
+			sql: `DROP TABLE IF EXISTS foo`,
+			exp: "ddl:foo",
+		},
+		{
+			sql: `CREATE VIEW v AS SELECT * FROM foo`,
+			exp: "ddl:v,select:foo",
+		},
+		{
+			sql: `CREATE INDEX foo_n ON foo(n)`,
+			exp: "ddl:foo",
+		},
+		{
+			sql: `DROP INDEX foo_n`,
+			exp: "ddl:foo_n",
+		},
+		{
+			sql: `CREATE TRIGGER t AFTER INSERT ON foo BEGIN DELETE FROM bar; END`,
+			exp: "ddl:foo,delete:bar",
+		},
+		{
+			sql: `PRAGMA foreign_keys`,
+			exp: "ddl:",
+		},
+		{
+			sql: `EXPLAIN QUERY PLAN SELECT * FROM foo`,
+			exp: "select:foo",
+		},
+		{
+			sql: `BEGIN; INSERT INTO foo(id) VALUES(1); DELETE FROM bar; COMMIT`,
+			exp: "insert:foo,delete:bar",
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(:$0.last_insert_id)`,
+			exp: "insert:foo",
+		},
+	} {
+		accesses, err := TableAccesses(tt.sql)
+		if err != nil {
+			t.Fatalf("test %d: failed to get accesses of %q: %s", i, tt.sql, err)
+		}
+		got := make([]string, len(accesses))
+		for j, a := range accesses {
+			got[j] = a.Op + ":" + a.Table
+		}
+		if strings.Join(got, ",") != tt.exp {
+			t.Fatalf("test %d: wrong accesses for %q, exp %q, got %q", i, tt.sql, tt.exp, strings.Join(got, ","))
+		}
+	}
+}
+
+func Test_TableAccesses_Unparsable(t *testing.T) {
+	if _, err := TableAccesses(`ATTACH DATABASE 'x.db' AS x`); err == nil {
+		t.Fatalf("expected error for unparsable statement")
+	}
+}
+
+func Test_Authorizer_Check(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "alice", "password": "pw", "perms": ["all"]},
+		{"username": "bob", "password": "pw", "perms": ["all"], "acl": [
+			{"tables": ["orders", "items_*"], "ops": ["SELECT", "INSERT", "UPDATE", "DELETE"]},
+			{"tables": ["reports"], "ops": ["select"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	lookupFn := func(name string) (string, error) {
+		if name == "purge" {
+			return `DELETE FROM reports`, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	var a *Authorizer
+	if err := a.Check("bob", "pw", []*proto.Statement{{Sql: `DROP TABLE orders`}}, nil); err != nil {
+		t.Fatalf("nil authorizer returned error: %s", err)
+	}
+
+	a = NewAuthorizer(cs)
+	for i, tt := range []struct {
+		user  string
+		stmts []*proto.Statement
+		exp   string
+	}{
+		{
+			user:  "alice",
+			stmts: []*proto.Statement{{Sql: `DROP TABLE orders`}},
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Sql: `INSERT INTO orders(id) SELECT id FROM reports`}, {Sql: `UPDATE Items_2024 SET n = 1`}},
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Sql: `DROP TABLE orders`}},
+			exp:   "not authorized: statement 0: ddl on table orders not permitted",
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Sql: `SELECT * FROM orders`}, {Sql: `DELETE FROM orders WHERE id IN (SELECT id FROM secrets)`}},
+			exp:   "not authorized: statement 1: select on table secrets not permitted",
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Sql: `PRAGMA foreign_keys = off`}},
+			exp:   "not authorized: statement 0: ddl not permitted",
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Name: "purge"}, {Name: "missing"}},
+			exp:   "not authorized: statement 0: delete on table reports not permitted",
+		},
+		{
+			user:  "bob",
+			stmts: []*proto.Statement{{Sql: `VACUUM`}},
+			exp:   "not authorized: statement 0 cannot be checked",
+		},
+	} {
+		err := a.Check(tt.user, "pw", tt.stmts, lookupFn)
+		if tt.exp == "" {
+			if err != nil {
+				t.Fatalf("test %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		var aclErr *ACLError
+		if !errors.As(err, &aclErr) {
+			t.Fatalf("test %d: expected ACLError, got %v", i, err)
+		}
+		if !strings.HasPrefix(err.Error(), tt.exp) {
+			t.Fatalf("test %d: wrong error, exp %q, got %q", i, tt.exp, err.Error())
+		}
+	}
+}
+
+func Test_Authorizer_AllUsers(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "*", "perms": ["query"], "acl": [{"tables": ["public"], "ops": ["select"]}]},
+		{"username": "admin", "password": "pw", "perms": ["all"], "acl": [{"tables": ["*"], "ops": ["select", "insert", "update", "delete", "ddl"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	a := NewAuthorizer(cs)
+	for _, user := range []string{"", "mary"} {
+		if err := a.Check(user, "pw", []*proto.Statement{{Sql: `SELECT * FROM public`}}, nil); err != nil {
+			t.Fatalf("user %q denied access to public table: %s", user, err)
+		}
+		if err := a.Check(user, "pw", []*proto.Statement{{Sql: `SELECT * FROM private`}}, nil); err == nil {
+			t.Fatalf("user %q permitted access to private table", user)
+		}
+	}
+	if err := a.Check("admin", "wrong", []*proto.Statement{{Sql: `SELECT * FROM private`}}, nil); err == nil {
+		t.Fatalf("admin with wrong password permitted access to private table")
+	}
+	for i, s := range []string{`SELECT * FROM private`, `DROP TABLE public`, `PRAGMA optimize`} {
+		if err := a.Check("admin", "pw", []*proto.Statement{{Sql: s}}, nil); err != nil {
+			t.Fatalf("test %d: admin denied: %s", i, err)
+		}
+	}
+}
+
+func Test_Authorizer_SystemTables(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[{"username": "admin", "password": "pw", "perms": ["all"]}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	lookupFn := func(name string) (string, error) {
+		return `SELECT * FROM _rqlite_users`, nil
+	}
+	a := NewAuthorizer(cs)
+	for i, s := range []string{
+		`SELECT * FROM _rqlite_users`,
+		`SELECT * FROM "_RQLITE_USERS"`,
+		`INSERT INTO foo SELECT credential FROM [_rqlite_users]`,
+		`DROP TABLE _rqlite_users`,
+		`UPDATE _rqlite_statements SET sql = 'DELETE FROM foo' WHERE name = 'get'`,
+		`INSERT OR REPLACE INTO "_RQLITE_STATEMENTS"(name, sql) VALUES('x', 'DROP TABLE foo')`,
+	} {
+		err := a.Check("admin", "pw", []*proto.Statement{{Sql: s}}, nil)
+		var aclErr *ACLError
+		if !errors.As(err, &aclErr) {
+			t.Fatalf("test %d: expected ACLError for %q, got %v", i, s, err)
+		}
+	}
+	if err := a.Check("admin", "pw", []*proto.Statement{{Name: "users"}}, lookupFn); err == nil {
+		t.Fatalf("named statement accessing users table permitted")
+	}
+	if err := a.Check("admin", "pw", []*proto.Statement{{Sql: `SELECT * FROM users`}}, nil); err != nil {
+		t.Fatalf("admin denied access to other table: %s", err)
+	}
+}
+
+func Test_Authorizer_Stats(t *testing.T) {
+	ResetStats()
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[{"username": "*", "acl": [{"tables": ["public"], "ops": ["select"]}]}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	if err := NewAuthorizer(cs).Check("", "", []*proto.Statement{{Sql: `DELETE FROM public`}}, nil); err == nil {
+		t.Fatalf("expected delete to be denied")
+	}
+	if got := stats.Get(numACLRejections).String(); got != fmt.Sprint(1) {
+		t.Fatalf("wrong number of ACL rejections, got %s", got)
+	}
+}
+
+// tokenACL is a TableACL which accepts a single bearer token, for bob.
+type tokenACL struct {
+	*auth.CredentialsStore
+}
+
+func (t tokenACL) TokenUser(token string) (string, bool) {
+	return "bob", token == "bob-token"
+}
+
+func Test_Authorizer_CheckToken(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "*", "acl": [{"tables": ["public"], "ops": ["select"]}]},
+		{"username": "bob", "acl": [{"tables": ["orders"], "ops": ["select"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	a := NewAuthorizer(tokenACL{cs})
+	stmts := []*proto.Statement{{Sql: `SELECT * FROM orders`}}
+	if err := a.CheckToken("bob-token", stmts, nil); err != nil {
+		t.Fatalf("token user denied access to orders: %s", err)
+	}
+	if err := a.CheckToken("other-token", stmts, nil); err == nil {
+		t.Fatalf("unverified token permitted access to orders")
+	}
+	if err := a.CheckToken("other-token", []*proto.Statement{{Sql: `SELECT * FROM public`}}, nil); err != nil {
+		t.Fatalf("unverified token denied access to public: %s", err)
+	}
+}
+
+func Test_Authorizer_CheckCert(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "bob", "certificates": ["cn:bob"], "acl": [{"tables": ["orders"], "ops": ["select"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	a := NewAuthorizer(cs)
+	stmts := []*proto.Statement{{Sql: `SELECT * FROM orders`}}
+	if err := a.CheckCert(&x509.Certificate{Subject: pkix.Name{CommonName: "bob"}}, stmts, nil); err != nil {
+		t.Fatalf("certificate user denied access to orders: %s", err)
+	}
+	if err := a.CheckCert(&x509.Certificate{Subject: pkix.Name{CommonName: "bob"}}, []*proto.Statement{{Sql: `DELETE FROM orders`}}, nil); err == nil {
+		t.Fatalf("certificate user permitted to delete from orders")
+	}
+}
+
+func Test_Authorizer_CheckCertUser(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "bob", "certificates": ["cn:bob"], "acl": [{"tables": ["orders"], "ops": ["select"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	a := NewAuthorizer(cs)
+	if err := a.CheckCertUser("bob", []*proto.Statement{{Sql: `SELECT * FROM orders`}}, nil); err != nil {
+		t.Fatalf("certificate user denied access to orders: %s", err)
+	}
+	if err := a.CheckCertUser("bob", []*proto.Statement{{Sql: `DELETE FROM orders`}}, nil); err == nil {
+		t.Fatalf("certificate user permitted to delete from orders")
+	}
+}
@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/sql"
+)
+
+// ColumnDefault is the default value of a table column, as declared when the
+// table was created.
+type ColumnDefault struct {
+	Column string
+	Expr   string
+}
+
+// ErrNoSuchTable is returned by a ColumnDefaultsFn if the table does not exist.
+var ErrNoSuchTable = errors.New("no such table")
+
+// ColumnDefaultsFn returns the defaults of the columns of the given table, in
+// the order the columns are declared. schema is empty unless the statement
+// names one. It returns ErrNoSuchTable if the table does not exist.
+type ColumnDefaultsFn func(schema, table string) ([]ColumnDefault, error)
+
+// InjectDefaults returns the SQL of an INSERT statement with every column
+// which would take a non-deterministic default, such as CURRENT_TIMESTAMP or
+// random(), given an explicit value instead. Values are computed using the time
+// and random seed which Process recorded in the statement, so the statement
+// inserts the same values no matter which node executes it, or when. The SQL
+// is returned unchanged if nothing needs to be injected.
+//
+// Values cannot be injected into INSERT INTO ... SELECT statements, which are
+// also returned unchanged.
+func InjectDefaults(stmt *proto.Statement, defaultsFn ColumnDefaultsFn) (retSQL string, retErr error) {
+	if stmt.DefaultTime == 0 && stmt.DefaultSeed == 0 {
+		return stmt.Sql, nil
+	}
+	if !ContainsInsert(strings.ToLower(stmt.Sql)) {
+		return stmt.Sql, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			stats.Add(numParserPanics, 1)
+			retSQL, retErr = "", fmt.Errorf("panic during SQL processing: %v", r)
+		}
+	}()
+
+	parsed, err := sql.NewParser(strings.NewReader(stmt.Sql)).ParseStatement()
+	if err != nil {
+		// Let the database report the error.
+		return stmt.Sql, nil
+	}
+	ins, ok := parsed.(*sql.InsertStatement)
+	if !ok || ins.Select != nil {
+		return stmt.Sql, nil
+	}
+	if len(ins.Columns) == 0 && !ins.DefaultValues.IsValid() {
+		// Every column is given a value.
+		return stmt.Sql, nil
+	}
+
+	var schema string
+	if ins.Schema != nil {
+		schema = ins.Schema.Name
+	}
+	defaults, err := defaultsFn(schema, ins.Table.Name)
+	if errors.Is(err, ErrNoSuchTable) {
+		// Let the database report the error.
+		return stmt.Sql, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	given := make(map[string]bool, len(ins.Columns))
+	for _, c := range ins.Columns {
+		given[strings.ToLower(c.Name)] = true
+	}
+	var inject []ColumnDefault
+	for _, d := range defaults {
+		lowered := strings.ToLower(d.Expr)
+		if given[strings.ToLower(d.Column)] {
+			continue
+		}
+		if (stmt.DefaultTime != 0 && ContainsTime(lowered)) ||
+			(stmt.DefaultSeed != 0 && ContainsRandom(lowered)) {
+			inject = append(inject, d)
+		}
+	}
+	if len(inject) == 0 {
+		return stmt.Sql, nil
+	}
+
+	if ins.DefaultValues.IsValid() {
+		ins.Default, ins.DefaultValues = sql.Pos{}, sql.Pos{}
+		ins.ValueLists = []*sql.ExprList{{}}
+	}
+
+	rw := NewRewriter()
+	rw.RewriteTime = stmt.DefaultTime != 0
+	rw.RewriteRand = stmt.DefaultSeed != 0
+	rw.nowFn = func() time.Time {
+		return time.Unix(0, stmt.DefaultTime)
+	}
+	rng := rand.New(rand.NewPCG(uint64(stmt.DefaultSeed), uint64(stmt.DefaultTime)))
+	rw.randFn = rng.Int64
+	rw.bytesFn = func(n int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(rng.Uint32())
+		}
+		return b
+	}
+
+	for _, d := range inject {
+		expr, err := sql.NewParser(strings.NewReader(d.Expr)).ParseExpr()
+		if err != nil {
+			return stmt.Sql, nil
+		}
+		ins.Columns = append(ins.Columns, &sql.Ident{Name: d.Column, Quoted: true})
+		for _, vl := range ins.ValueLists {
+			// Rewrite a copy for each row, so that each row is given its own
+			// random values.
+			node, err := sql.Walk(rw, sql.CloneExpr(expr))
+			if err != nil {
+				return "", err
+			}
+			vl.Exprs = append(vl.Exprs, node.(sql.Expr))
+		}
+	}
+	stats.Add(numInjectedStmts, 1)
+	return ins.String(), nil
+}
@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_InjectDefaults(t *testing.T) {
+	defaultTime := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC).UnixNano()
+	defaultsFn := func(schema, table string) ([]ColumnDefault, error) {
+		if table != "foo" {
+			return nil, nil
+		}
+		return []ColumnDefault{
+			{Column: "name", Expr: "'unknown'"},
+			{Column: "ts", Expr: "CURRENT_TIMESTAMP"},
+			{Column: "d", Expr: "date('now')"},
+			{Column: "uuid", Expr: "lower(hex(randomblob(16)))"},
+		}, nil
+	}
+
+	for i, tt := range []struct {
+		sql  string
+		time int64
+		seed int64
+		exp  string
+	}{
+		{
+			sql:  `INSERT INTO foo(name) VALUES('fiona')`,
+			time: defaultTime,
+			exp:  `^INSERT INTO "foo" \("name", "ts", "d"\) VALUES \('fiona', '2024-02-29 13:14:15', date\(2460370\.[0-9]+\)\)$`,
+		},
+		{
+			sql:  `INSERT INTO foo(name, ts) VALUES('fiona', NULL)`,
+			time: defaultTime,
+			seed: 1,
+			exp:  `^INSERT INTO "foo" \("name", "ts", "d", "uuid"\) VALUES \('fiona', NULL, date\([0-9.]+\), lower\(hex\(x'[0-9A-F]{32}'\)\)\)$`,
+		},
+		{
+			sql:  `INSERT INTO foo DEFAULT VALUES`,
+			time: defaultTime,
+			exp:  `^INSERT INTO "foo" \("ts", "d"\) VALUES \('2024-02-29 13:14:15', date\([0-9.]+\)\)$`,
+		},
+		{
+			sql:  `INSERT INTO foo(name, ts, d, uuid) VALUES('fiona', 1, 2, 3)`,
+			time: defaultTime,
+			seed: 1,
+			exp:  `^INSERT INTO foo\(name, ts, d, uuid\) VALUES\('fiona', 1, 2, 3\)$`,
+		},
+		{
+			sql:  `INSERT INTO foo VALUES('fiona', 1, 2, 3)`,
+			time: defaultTime,
+			exp:  `^INSERT INTO foo VALUES\('fiona', 1, 2, 3\)$`,
+		},
+		{
+			sql:  `INSERT INTO foo(name) SELECT name FROM bar`,
+			time: defaultTime,
+			exp:  `^INSERT INTO foo\(name\) SELECT name FROM bar$`,
+		},
+		{
+			sql:  `INSERT INTO bar(name) VALUES('fiona')`,
+			time: defaultTime,
+			exp:  `^INSERT INTO bar\(name\) VALUES\('fiona'\)$`,
+		},
+		{
+			sql: `INSERT INTO foo(name) VALUES('fiona')`,
+			exp: `^INSERT INTO foo\(name\) VALUES\('fiona'\)$`,
+		},
+		{
+			sql:  `UPDATE foo SET name = 'fiona'`,
+			time: defaultTime,
+			exp:  `^UPDATE foo SET name = 'fiona'$`,
+		},
+	} {
+		stmt := &proto.Statement{Sql: tt.sql, DefaultTime: tt.time, DefaultSeed: tt.seed}
+		got, err := InjectDefaults(stmt, defaultsFn)
+		if err != nil {
+			t.Fatalf("test %d: failed to inject defaults: %s", i, err)
+		}
+		if !regexp.MustCompile(tt.exp).MatchString(got) {
+			t.Fatalf("test %d: exp match with %s, got %s", i, tt.exp, got)
+		}
+	}
+}
+
+func Test_InjectDefaults_Deterministic(t *testing.T) {
+	defaultsFn := func(schema, table string) ([]ColumnDefault, error) {
+		return []ColumnDefault{{Column: "r", Expr: "random()"}}, nil
+	}
+	stmt := &proto.Statement{
+		Sql:         `INSERT INTO foo(name) VALUES('fiona'), ('declan')`,
+		DefaultSeed: 1234,
+	}
+	first, err := InjectDefaults(stmt, defaultsFn)
+	if err != nil {
+		t.Fatalf("failed to inject defaults: %s", err)
+	}
+	second, err := InjectDefaults(stmt, defaultsFn)
+	if err != nil {
+		t.Fatalf("failed to inject defaults: %s", err)
+	}
+	if first != second {
+		t.Fatalf("injected values differ: %s, %s", first, second)
+	}
+	m := regexp.MustCompile(`VALUES \('fiona', (-?[0-9]+)\), \('declan', (-?[0-9]+)\)$`).FindStringSubmatch(first)
+	if m == nil {
+		t.Fatalf("unexpected statement: %s", first)
+	}
+	if m[1] == m[2] {
+		t.Fatalf("rows given the same random value: %s", first)
+	}
+}
+
+func Test_InjectDefaults_Error(t *testing.T) {
+	stmt := &proto.Statement{Sql: `INSERT INTO foo(name) VALUES('fiona')`, DefaultTime: 1}
+	_, err := InjectDefaults(stmt, func(schema, table string) ([]ColumnDefault, error) {
+		return nil, errors.New("no defaults")
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,441 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/sql"
+)
+
+// LintMode controls what is done with write statements which may not execute
+// identically on every node.
+type LintMode int
+
+const (
+	// LintOff disables linting.
+	LintOff LintMode = iota
+
+	// LintWarn logs any problems found, but allows the statements to be
+	// executed.
+	LintWarn
+
+	// LintReject refuses to execute statements with problems.
+	LintReject
+)
+
+// String returns the string representation of the mode.
+func (m LintMode) String() string {
+	switch m {
+	case LintWarn:
+		return "warn"
+	case LintReject:
+		return "reject"
+	default:
+		return "off"
+	}
+}
+
+// ParseLintMode parses the string representation of a LintMode.
+func ParseLintMode(s string) (LintMode, error) {
+	switch strings.ToLower(s) {
+	case "", "off":
+		return LintOff, nil
+	case "warn":
+		return LintWarn, nil
+	case "reject":
+		return LintReject, nil
+	}
+	return LintOff, fmt.Errorf("invalid lint mode %q", s)
+}
+
+// FunctionInfo describes a function provided by the database.
+type FunctionInfo struct {
+	Name string
+
+	// Builtin is whether the function is built into SQLite, rather than
+	// provided by an extension.
+	Builtin bool
+
+	// Aggregate is whether the function is an aggregate or window function.
+	Aggregate bool
+
+	// Deterministic is whether the function is declared to always return
+	// the same result given the same arguments.
+	Deterministic bool
+}
+
+// Catalog describes the database against which statements are linted.
+type Catalog interface {
+	// Functions returns the functions the database provides.
+	Functions() ([]FunctionInfo, error)
+
+	// Triggers returns the SQL of each trigger on the given table.
+	Triggers(schema, table string) ([]string, error)
+
+	// ColumnDefaults returns the defaults of the columns of the given
+	// table, in column order. It returns ErrNoSuchTable if the table does
+	// not exist.
+	ColumnDefaults(schema, table string) ([]ColumnDefault, error)
+}
+
+// LintError is returned when a Linter rejects statements.
+type LintError struct {
+	Problems []string
+}
+
+// Error returns the string representation of the error.
+func (e *LintError) Error() string {
+	return "non-deterministic write: " + strings.Join(e.Problems, "; ")
+}
+
+// consistentFunctions are functions which SQLite does not declare
+// deterministic, but which return the same result on every node, since every
+// node applies the same changes to the same database.
+var consistentFunctions = map[string]bool{
+	"changes":           true,
+	"total_changes":     true,
+	"last_insert_rowid": true,
+}
+
+// timeFunctions are the functions which use the current time if called with
+// 'now', or without a time value.
+var timeFunctions = map[string]int{
+	"date":      1,
+	"time":      1,
+	"datetime":  1,
+	"julianday": 1,
+	"unixepoch": 1,
+	"strftime":  2,
+	"timediff":  2,
+}
+
+// Linter checks that write statements will execute identically on every node.
+// Statements are checked for functions which are not deterministic, including
+// any called by triggers or column defaults the statements would use. Time and
+// random functions are only permitted where they are rewritten, or where
+// values are injected for them. See Process and InjectDefaults.
+//
+// A Linter is safe for concurrent use.
+type Linter struct {
+	mode    LintMode
+	catalog Catalog
+
+	mu        sync.Mutex
+	functions map[string]bool // Keyed by lower-case name, true if deterministic.
+
+	logger *log.Logger
+}
+
+// NewLinter returns a new Linter, which checks statements against the given
+// catalog.
+func NewLinter(mode LintMode, catalog Catalog) *Linter {
+	return &Linter{
+		mode:    mode,
+		catalog: catalog,
+		logger:  logging.New("sql", os.Stderr),
+	}
+}
+
+// Mode returns the mode of the Linter.
+func (l *Linter) Mode() LintMode {
+	if l == nil {
+		return LintOff
+	}
+	return l.mode
+}
+
+// Check lints the given statements, which must already have been processed.
+// In LintWarn mode any problems are logged. In LintReject mode a *LintError
+// listing the problems is returned. lookupFn, if not nil, returns the SQL of
+// named statements. A nil Linter checks nothing.
+func (l *Linter) Check(stmts []*proto.Statement, lookupFn func(name string) (string, error)) error {
+	if l.Mode() == LintOff {
+		return nil
+	}
+	var problems []string
+	for i, stmt := range stmts {
+		if stmt.Sql == "" && stmt.Name != "" && lookupFn != nil {
+			s, err := lookupFn(stmt.Name)
+			if err != nil {
+				// Missing statements are reported when executed.
+				continue
+			}
+			stmt = &proto.Statement{Sql: s, DefaultTime: stmt.DefaultTime, DefaultSeed: stmt.DefaultSeed}
+		}
+		ps, err := l.Lint(stmt)
+		if err != nil {
+			return err
+		}
+		for _, p := range ps {
+			problems = append(problems, fmt.Sprintf("statement %d: %s", i, p))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	stats.Add(numLintProblems, int64(len(problems)))
+	if l.mode == LintWarn {
+		for _, p := range problems {
+			l.logger.Printf("non-deterministic write: %s", p)
+		}
+		return nil
+	}
+	stats.Add(numLintRejections, 1)
+	return &LintError{Problems: problems}
+}
+
+// Lint returns the problems which could cause the given statement to execute
+// differently on different nodes. The statement must already have been
+// processed. Statements which do not write to the database have no problems.
+func (l *Linter) Lint(stmt *proto.Statement) (problems []string, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stats.Add(numParserPanics, 1)
+			problems, retErr = []string{fmt.Sprintf("cannot be checked: %v", r)}, nil
+		}
+	}()
+	if strings.TrimSpace(stmt.Sql) == "" {
+		return nil, nil
+	}
+	fns, err := l.loadFunctions()
+	if err != nil {
+		return nil, err
+	}
+	masked, _, err := maskReferences(stmt.Sql)
+	if err != nil {
+		// Malformed references are reported when executed.
+		return nil, nil
+	}
+
+	parser := sql.NewParser(strings.NewReader(masked))
+	for {
+		parsed, err := parser.ParseStatement()
+		if errors.Is(err, io.EOF) {
+			return problems, nil
+		} else if err != nil {
+			return append(problems, fmt.Sprintf("cannot be checked: %s", err)), nil
+		}
+		ps, err := l.lintStatement(parsed, stmt, fns)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, ps...)
+	}
+}
+
+func (l *Linter) lintStatement(parsed sql.Statement, stmt *proto.Statement, fns map[string]bool) ([]string, error) {
+	v := &lintVisitor{functions: fns}
+	switch n := parsed.(type) {
+	case *sql.InsertStatement:
+		if err := l.lintTable(v, n.Schema, n.Table); err != nil {
+			return nil, err
+		}
+		if err := l.lintDefaults(v, n, stmt); err != nil {
+			return nil, err
+		}
+	case *sql.UpdateStatement:
+		if err := l.lintTable(v, n.Table.Schema, n.Table.Name); err != nil {
+			return nil, err
+		}
+	case *sql.DeleteStatement:
+		if err := l.lintTable(v, n.Table.Schema, n.Table.Name); err != nil {
+			return nil, err
+		}
+	case *sql.CreateTriggerStatement:
+		// Trigger bodies are never rewritten.
+	case *sql.CreateTableStatement:
+		if n.Select == nil {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	if _, err := sql.Walk(v, parsed); err != nil {
+		return nil, err
+	}
+	return v.problems, nil
+}
+
+// lintTable checks the triggers on the given table.
+func (l *Linter) lintTable(v *lintVisitor, schema, table *sql.Ident) error {
+	var s string
+	if schema != nil {
+		s = schema.Name
+	}
+	triggers, err := l.catalog.Triggers(s, table.Name)
+	if err != nil {
+		return err
+	}
+	for _, t := range triggers {
+		parsed, err := sql.NewParser(strings.NewReader(t)).ParseStatement()
+		if err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("trigger on %s cannot be checked: %s", table.Name, err))
+			continue
+		}
+		tv := &lintVisitor{functions: v.functions}
+		if _, err := sql.Walk(tv, parsed); err != nil {
+			return err
+		}
+		name := table.Name
+		if ct, ok := parsed.(*sql.CreateTriggerStatement); ok {
+			name = ct.Name.Name
+		}
+		for _, p := range tv.problems {
+			v.problems = append(v.problems, fmt.Sprintf("trigger %s: %s", name, p))
+		}
+	}
+	return nil
+}
+
+// lintDefaults checks the defaults of the columns which the INSERT statement
+// does not give values.
+func (l *Linter) lintDefaults(v *lintVisitor, ins *sql.InsertStatement, stmt *proto.Statement) error {
+	if len(ins.Columns) == 0 && !ins.DefaultValues.IsValid() {
+		// Every column is given a value.
+		return nil
+	}
+	var schema string
+	if ins.Schema != nil {
+		schema = ins.Schema.Name
+	}
+	defaults, err := l.catalog.ColumnDefaults(schema, ins.Table.Name)
+	if errors.Is(err, ErrNoSuchTable) {
+		// The table may be created earlier in the same request.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	given := make(map[string]bool, len(ins.Columns))
+	for _, c := range ins.Columns {
+		given[strings.ToLower(c.Name)] = true
+	}
+	injectable := ins.Select == nil
+	for _, d := range defaults {
+		if given[strings.ToLower(d.Column)] {
+			continue
+		}
+		expr, err := sql.NewParser(strings.NewReader(d.Expr)).ParseExpr()
+		if err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("default of column %s cannot be checked: %s", d.Column, err))
+			continue
+		}
+		dv := &lintVisitor{
+			functions:   v.functions,
+			allowTime:   injectable && stmt.DefaultTime != 0,
+			allowRandom: injectable && stmt.DefaultSeed != 0,
+		}
+		if _, err := sql.Walk(dv, expr); err != nil {
+			return err
+		}
+		for _, p := range dv.problems {
+			v.problems = append(v.problems, fmt.Sprintf("default of column %s: %s", d.Column, p))
+		}
+	}
+	return nil
+}
+
+// loadFunctions returns whether each function provided by the database is
+// deterministic. The functions are read from the catalog once, since they do
+// not change once the database is open.
+func (l *Linter) loadFunctions() (map[string]bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.functions != nil {
+		return l.functions, nil
+	}
+	infos, err := l.catalog.Functions()
+	if err != nil {
+		return nil, err
+	}
+	fns := make(map[string]bool, len(infos))
+	for _, fi := range infos {
+		name := strings.ToLower(fi.Name)
+		det := fi.Deterministic || (fi.Builtin && fi.Aggregate)
+		if d, ok := fns[name]; ok {
+			// A function is only deterministic if every variant is.
+			det = det && d
+		}
+		fns[name] = det
+	}
+	l.functions = fns
+	return fns, nil
+}
+
+// lintVisitor records the non-deterministic function calls and keywords in
+// the nodes it visits.
+type lintVisitor struct {
+	functions map[string]bool
+
+	// allowTime and allowRandom are whether the current time and random
+	// values may be used, because values will be injected for them.
+	allowTime   bool
+	allowRandom bool
+
+	problems []string
+}
+
+func (v *lintVisitor) Visit(node sql.Node) (sql.Visitor, sql.Node, error) {
+	switch n := node.(type) {
+	case *sql.Call:
+		if p := v.checkCall(n); p != "" {
+			v.problems = append(v.problems, p)
+		}
+	case *sql.Ident:
+		if _, ok := timeKeyword(n.Name, time.Time{}); ok && !n.Quoted && !v.allowTime {
+			v.problems = append(v.problems, fmt.Sprintf("%s uses the current time", strings.ToUpper(n.Name)))
+		}
+	case *sql.TimestampLit:
+		if !v.allowTime {
+			v.problems = append(v.problems, fmt.Sprintf("%s uses the current time", strings.ToUpper(n.Value)))
+		}
+	}
+	return v, node, nil
+}
+
+func (v *lintVisitor) VisitEnd(node sql.Node) (sql.Node, error) {
+	return node, nil
+}
+
+func (v *lintVisitor) checkCall(c *sql.Call) string {
+	name := strings.ToLower(c.Name.Name)
+	if minArgs, ok := timeFunctions[name]; ok {
+		if v.allowTime {
+			return ""
+		}
+		if len(c.Args) < minArgs {
+			return fmt.Sprintf("%s() uses the current time", c.Name.Name)
+		}
+		for _, a := range c.Args {
+			if isNow(a) {
+				return fmt.Sprintf("%s() uses the current time", c.Name.Name)
+			}
+		}
+		return ""
+	}
+	if name == "random" || name == "randomblob" {
+		if v.allowRandom {
+			return ""
+		}
+		return fmt.Sprintf("%s() is not deterministic", c.Name.Name)
+	}
+	if consistentFunctions[name] {
+		return ""
+	}
+	det, ok := v.functions[name]
+	if !ok {
+		return fmt.Sprintf("%s() is not provided by this node", c.Name.Name)
+	}
+	if !det {
+		return fmt.Sprintf("%s() is not deterministic", c.Name.Name)
+	}
+	return ""
+}
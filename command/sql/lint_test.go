@@ -0,0 +1,257 @@
+package sql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+type mockCatalog struct {
+	functions []FunctionInfo
+	triggers  map[string][]string
+	defaults  map[string][]ColumnDefault
+	err       error
+}
+
+func (m *mockCatalog) Functions() ([]FunctionInfo, error) {
+	return m.functions, m.err
+}
+
+func (m *mockCatalog) Triggers(schema, table string) ([]string, error) {
+	return m.triggers[table], m.err
+}
+
+func (m *mockCatalog) ColumnDefaults(schema, table string) ([]ColumnDefault, error) {
+	return m.defaults[table], m.err
+}
+
+func newMockCatalog() *mockCatalog {
+	return &mockCatalog{
+		functions: []FunctionInfo{
+			{Name: "abs", Builtin: true, Deterministic: true},
+			{Name: "lower", Builtin: true, Deterministic: true},
+			{Name: "hex", Builtin: true, Deterministic: true},
+			{Name: "date", Builtin: true, Deterministic: true},
+			{Name: "datetime", Builtin: true, Deterministic: true},
+			{Name: "strftime", Builtin: true, Deterministic: true},
+			{Name: "random", Builtin: true},
+			{Name: "randomblob", Builtin: true},
+			{Name: "changes", Builtin: true},
+			{Name: "sqlite_version", Builtin: true},
+			{Name: "count", Builtin: true, Aggregate: true},
+			{Name: "ext_hash", Deterministic: true},
+			{Name: "ext_uuid"},
+			{Name: "ext_mixed", Deterministic: true},
+			{Name: "ext_mixed"},
+		},
+		triggers: map[string][]string{
+			"audited": {`CREATE TRIGGER audit AFTER INSERT ON audited BEGIN INSERT INTO log VALUES(NEW.id, CURRENT_TIMESTAMP); END`},
+			"counted": {`CREATE TRIGGER count AFTER INSERT ON counted BEGIN UPDATE totals SET n = n + changes(); END`},
+		},
+		defaults: map[string][]ColumnDefault{
+			"stamped": {
+				{Column: "name", Expr: "'unknown'"},
+				{Column: "ts", Expr: "CURRENT_TIMESTAMP"},
+			},
+			"keyed": {
+				{Column: "uuid", Expr: "lower(hex(randomblob(16)))"},
+			},
+		},
+	}
+}
+
+func Test_ParseLintMode(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		exp  LintMode
+		fail bool
+	}{
+		{s: "", exp: LintOff},
+		{s: "off", exp: LintOff},
+		{s: "warn", exp: LintWarn},
+		{s: "REJECT", exp: LintReject},
+		{s: "maybe", fail: true},
+	} {
+		m, err := ParseLintMode(tt.s)
+		if tt.fail {
+			if err == nil {
+				t.Fatalf("expected error parsing %q", tt.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", tt.s, err)
+		}
+		if m != tt.exp {
+			t.Fatalf("wrong mode for %q, exp %s, got %s", tt.s, tt.exp, m)
+		}
+		if tt.s != "" && m.String() != strings.ToLower(tt.s) {
+			t.Fatalf("wrong string for %q, got %s", tt.s, m)
+		}
+	}
+}
+
+func Test_Linter_Lint(t *testing.T) {
+	l := NewLinter(LintReject, newMockCatalog())
+	for i, tt := range []struct {
+		sql   string
+		time  int64
+		seed  int64
+		probs []string
+	}{
+		{
+			sql: `SELECT random(), datetime('now')`,
+		},
+		{
+			sql: `CREATE TABLE foo (id INTEGER PRIMARY KEY, ts TEXT DEFAULT CURRENT_TIMESTAMP)`,
+		},
+		{
+			sql: `INSERT INTO foo(id, name) VALUES(1, abs(-1))`,
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(date('2024-02-29'))`,
+		},
+		{
+			sql: `INSERT INTO foo(n) SELECT count(*) FROM bar`,
+		},
+		{
+			sql: `UPDATE foo SET n = changes()`,
+		},
+		{
+			sql:   `INSERT INTO foo(id) VALUES(date())`,
+			probs: []string{"date() uses the current time"},
+		},
+		{
+			sql:   `UPDATE foo SET ts = strftime('%s', 'now')`,
+			probs: []string{"strftime() uses the current time"},
+		},
+		{
+			sql:   `INSERT INTO foo(id) SELECT id FROM bar ORDER BY random() LIMIT 1`,
+			probs: []string{"random() is not deterministic"},
+		},
+		{
+			sql:   `DELETE FROM foo WHERE id = ext_uuid()`,
+			probs: []string{"ext_uuid() is not deterministic"},
+		},
+		{
+			sql:   `UPDATE foo SET id = ext_mixed(id)`,
+			probs: []string{"ext_mixed() is not deterministic"},
+		},
+		{
+			sql: `UPDATE foo SET id = ext_hash(id)`,
+		},
+		{
+			sql:   `INSERT INTO foo(v) VALUES(sqlite_version())`,
+			probs: []string{"sqlite_version() is not deterministic"},
+		},
+		{
+			sql:   `INSERT INTO foo(v) VALUES(missing(1))`,
+			probs: []string{"missing() is not provided by this node"},
+		},
+		{
+			sql:   `CREATE TABLE foo AS SELECT random() AS r`,
+			probs: []string{"random() is not deterministic"},
+		},
+		{
+			sql:   `CREATE TRIGGER t AFTER INSERT ON foo BEGIN UPDATE bar SET ts = CURRENT_TIME; END`,
+			probs: []string{"CURRENT_TIME uses the current time"},
+		},
+		{
+			sql:   `INSERT INTO audited(id) VALUES(1)`,
+			probs: []string{"trigger audit: CURRENT_TIMESTAMP uses the current time"},
+		},
+		{
+			sql: `INSERT INTO counted(id) VALUES(1)`,
+		},
+		{
+			sql:  `INSERT INTO stamped(name) VALUES('fiona')`,
+			time: 1,
+		},
+		{
+			sql:   `INSERT INTO stamped(name) VALUES('fiona')`,
+			probs: []string{"default of column ts: CURRENT_TIMESTAMP uses the current time"},
+		},
+		{
+			sql:   `INSERT INTO stamped(name) SELECT name FROM bar`,
+			time:  1,
+			probs: []string{"default of column ts: CURRENT_TIMESTAMP uses the current time"},
+		},
+		{
+			sql: `INSERT INTO stamped(name, ts) SELECT name, ts FROM bar`,
+		},
+		{
+			sql:  `INSERT INTO keyed DEFAULT VALUES`,
+			seed: 1,
+		},
+		{
+			sql:   `INSERT INTO keyed DEFAULT VALUES`,
+			probs: []string{"default of column uuid: randomblob() is not deterministic"},
+		},
+		{
+			sql: `INSERT INTO foo(id) VALUES(1); UPDATE foo SET ts = date(); DELETE FROM foo WHERE id = random()`,
+			probs: []string{
+				"date() uses the current time",
+				"random() is not deterministic",
+			},
+		},
+	} {
+		probs, err := l.Lint(&proto.Statement{Sql: tt.sql, DefaultTime: tt.time, DefaultSeed: tt.seed})
+		if err != nil {
+			t.Fatalf("test %d: failed to lint: %s", i, err)
+		}
+		if strings.Join(probs, "|") != strings.Join(tt.probs, "|") {
+			t.Fatalf("test %d: wrong problems for %q, exp %q, got %q", i, tt.sql, tt.probs, probs)
+		}
+	}
+}
+
+func Test_Linter_Check(t *testing.T) {
+	stmts := []*proto.Statement{
+		{Sql: `INSERT INTO foo(id) VALUES(1)`},
+		{Sql: `UPDATE foo SET id = random()`},
+		{Name: "bad"},
+		{Name: "missing"},
+	}
+	lookupFn := func(name string) (string, error) {
+		if name == "bad" {
+			return `DELETE FROM foo WHERE ts < date()`, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	var l *Linter
+	if err := l.Check(stmts, lookupFn); err != nil {
+		t.Fatalf("nil linter returned error: %s", err)
+	}
+	if err := NewLinter(LintOff, newMockCatalog()).Check(stmts, lookupFn); err != nil {
+		t.Fatalf("off linter returned error: %s", err)
+	}
+	if err := NewLinter(LintWarn, newMockCatalog()).Check(stmts, lookupFn); err != nil {
+		t.Fatalf("warn linter returned error: %s", err)
+	}
+
+	err := NewLinter(LintReject, newMockCatalog()).Check(stmts, lookupFn)
+	var lerr *LintError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected LintError, got %v", err)
+	}
+	exp := "non-deterministic write: statement 1: random() is not deterministic; statement 2: date() uses the current time"
+	if lerr.Error() != exp {
+		t.Fatalf("wrong error, exp %q, got %q", exp, lerr.Error())
+	}
+
+	if err := NewLinter(LintReject, newMockCatalog()).Check(stmts[:1], nil); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+}
+
+func Test_Linter_CatalogError(t *testing.T) {
+	c := newMockCatalog()
+	c.err = errors.New("catalog unavailable")
+	err := NewLinter(LintReject, c).Check([]*proto.Statement{{Sql: `INSERT INTO foo(id) VALUES(1)`}}, nil)
+	if err == nil || err.Error() != "catalog unavailable" {
+		t.Fatalf("expected catalog error, got %v", err)
+	}
+}
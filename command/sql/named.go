@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// StatementsTable is the table which holds the registry of named statements.
+const StatementsTable = "_rqlite_statements"
+
+// Analysis is the result of analysing a statement, recording what
+// processing the statement requires before it can be executed.
+type Analysis struct {
+	// RewriteTime is whether the statement contains time functions which
+	// must be rewritten for the statement to be deterministic.
+	RewriteTime bool
+
+	// RewriteRandom is whether the statement contains random functions
+	// which must be rewritten for the statement to be deterministic.
+	RewriteRandom bool
+
+	// ForceQuery is whether the statement returns rows, even though it
+	// modifies the database.
+	ForceQuery bool
+
+	// Explain is whether the statement is an EXPLAIN statement.
+	Explain bool
+}
+
+// Deterministic returns whether the statement may be executed, without
+// rewriting, on every node with the same result.
+func (a *Analysis) Deterministic() bool {
+	return !a.RewriteTime && !a.RewriteRandom
+}
+
+// Analyze analyzes the given statement.
+func Analyze(sql string) (*Analysis, error) {
+	plain := &proto.Statement{Sql: sql}
+	rwTime := &proto.Statement{Sql: sql}
+	rwRand := &proto.Statement{Sql: sql}
+	for _, p := range []struct {
+		stmt           *proto.Statement
+		rwrand, rwtime bool
+	}{
+		{plain, false, false},
+		{rwTime, false, true},
+		{rwRand, true, false},
+	} {
+		if err := Process([]*proto.Statement{p.stmt}, p.rwrand, p.rwtime); err != nil {
+			return nil, err
+		}
+	}
+	return &Analysis{
+		RewriteTime:   rwTime.Sql != sql,
+		RewriteRandom: rwRand.Sql != sql,
+		ForceQuery:    plain.ForceQuery,
+		Explain:       plain.SqlExplain,
+	}, nil
+}
+
+// AnalysisCache caches the analysis of statements, keyed by SQL text. It
+// is safe for concurrent use.
+type AnalysisCache struct {
+	max int
+
+	mu sync.Mutex
+	m  map[string]*Analysis
+}
+
+// NewAnalysisCache returns a cache holding the analysis of at most max
+// statements.
+func NewAnalysisCache(max int) *AnalysisCache {
+	return &AnalysisCache{
+		max: max,
+		m:   make(map[string]*Analysis),
+	}
+}
+
+// Analyze returns the analysis of the given statement, analysing it only
+// if it is not already cached.
+func (c *AnalysisCache) Analyze(sql string) (*Analysis, error) {
+	c.mu.Lock()
+	a, ok := c.m[sql]
+	c.mu.Unlock()
+	if ok {
+		stats.Add(numAnalysisCacheHits, 1)
+		return a, nil
+	}
+
+	a, err := Analyze(sql)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.m) >= c.max {
+		// Registries are small, so a full cache is unusual. Just start again.
+		clear(c.m)
+	}
+	c.m[sql] = a
+	return a, nil
+}
+
+// Len returns the number of statements in the cache.
+func (c *AnalysisCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.m)
+}
+
+// ExpandNamed prepares any named statements in stmts for execution, and must
+// be called before Process. lookupFn returns the SQL registered under a name.
+// A named statement which is not deterministic is replaced by its SQL, so that
+// it can be rewritten by Process, but keeps its name, so that the Leader can
+// tell it was named. Any other named statement is sent by name, and is
+// resolved by each node as it is executed. An error is returned for any named
+// statement which lookupFn cannot find.
+func ExpandNamed(stmts []*proto.Statement, lookupFn func(name string) (string, error), c *AnalysisCache) error {
+	for _, stmt := range stmts {
+		if stmt.Name == "" || stmt.Sql != "" {
+			continue
+		}
+		sql, err := lookupFn(stmt.Name)
+		if err != nil {
+			return fmt.Errorf("statement %s: %w", stmt.Name, err)
+		}
+		a, err := c.Analyze(sql)
+		if err != nil {
+			return err
+		}
+		if !a.Deterministic() {
+			stmt.Sql = sql
+			continue
+		}
+		stmt.ForceQuery = a.ForceQuery
+		stmt.SqlExplain = a.Explain
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_Analyze(t *testing.T) {
+	tests := []struct {
+		sql string
+		exp Analysis
+	}{
+		{"INSERT INTO foo(name) VALUES(?)", Analysis{}},
+		{"INSERT INTO foo(t) VALUES(datetime('now'))", Analysis{RewriteTime: true}},
+		{"INSERT INTO foo(r) VALUES(random())", Analysis{RewriteRandom: true}},
+		{"SELECT * FROM foo ORDER BY random()", Analysis{}},
+		{"INSERT INTO foo(name) VALUES(?) RETURNING id", Analysis{ForceQuery: true}},
+		{"EXPLAIN SELECT * FROM foo", Analysis{Explain: true}},
+		{"INSERT INTO foo(p) VALUES(:$0.last_insert_id) RETURNING id", Analysis{ForceQuery: true}},
+	}
+	for _, tt := range tests {
+		a, err := Analyze(tt.sql)
+		if err != nil {
+			t.Fatalf("failed to analyze %q: %s", tt.sql, err)
+		}
+		if *a != tt.exp {
+			t.Fatalf("wrong analysis of %q, got %+v, exp %+v", tt.sql, *a, tt.exp)
+		}
+		if a.Deterministic() != (!tt.exp.RewriteTime && !tt.exp.RewriteRandom) {
+			t.Fatalf("wrong determinism for %q", tt.sql)
+		}
+	}
+}
+
+func Test_AnalysisCache(t *testing.T) {
+	ResetStats()
+	c := NewAnalysisCache(2)
+	for _, s := range []string{"SELECT 1", "SELECT 1", "SELECT 2"} {
+		if _, err := c.Analyze(s); err != nil {
+			t.Fatalf("failed to analyze: %s", err)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Fatalf("wrong cache size, got %d", got)
+	}
+	if got := stats.Get(numAnalysisCacheHits).String(); got != "1" {
+		t.Fatalf("wrong number of cache hits, got %s", got)
+	}
+
+	// A full cache starts again.
+	if _, err := c.Analyze("SELECT 3"); err != nil {
+		t.Fatalf("failed to analyze: %s", err)
+	}
+	if got := c.Len(); got != 1 {
+		t.Fatalf("wrong cache size, got %d", got)
+	}
+}
+
+func Test_ExpandNamed(t *testing.T) {
+	registry := map[string]string{
+		"add":     "INSERT INTO foo(name) VALUES(?)",
+		"add_ret": "INSERT INTO foo(name) VALUES(?) RETURNING id",
+		"add_now": "INSERT INTO foo(t) VALUES(datetime('now'))",
+	}
+	lookupFn := func(name string) (string, error) {
+		if s, ok := registry[name]; ok {
+			return s, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	stmts := []*proto.Statement{
+		{Name: "add"},
+		{Name: "add_ret"},
+		{Name: "add_now"},
+		{Sql: "SELECT 1"},
+	}
+	if err := ExpandNamed(stmts, lookupFn, NewAnalysisCache(10)); err != nil {
+		t.Fatalf("failed to expand: %s", err)
+	}
+	if stmts[0].Name != "add" || stmts[0].Sql != "" || stmts[0].ForceQuery {
+		t.Fatalf("deterministic statement expanded: %v", stmts[0])
+	}
+	if stmts[1].Name != "add_ret" || stmts[1].Sql != "" || !stmts[1].ForceQuery {
+		t.Fatalf("RETURNING statement not marked as query: %v", stmts[1])
+	}
+	if stmts[2].Name != "add_now" || stmts[2].Sql != registry["add_now"] {
+		t.Fatalf("non-deterministic statement not expanded: %v", stmts[2])
+	}
+	if stmts[3].Sql != "SELECT 1" {
+		t.Fatalf("SQL statement changed: %v", stmts[3])
+	}
+
+	if err := ExpandNamed([]*proto.Statement{{Name: "missing"}}, lookupFn, NewAnalysisCache(10)); err == nil {
+		t.Fatalf("expected error expanding missing statement")
+	}
+}
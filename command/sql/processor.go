@@ -18,6 +18,12 @@ import (
 const (
 	numRewrittenStmts = "num_rewritten_stmts"
 	numParserPanics   = "num_parser_panics"
+	numInjectedStmts  = "num_defaults_injected_stmts"
+	numLintProblems   = "num_lint_problems"
+	numLintRejections = "num_lint_rejections"
+	numACLRejections  = "num_acl_rejections"
+
+	numAnalysisCacheHits = "num_analysis_cache_hits"
 )
 
 // stats captures stats for the SQL processor.
@@ -33,29 +39,74 @@ func ResetStats() {
 	stats.Init()
 	stats.Add(numRewrittenStmts, 0)
 	stats.Add(numParserPanics, 0)
+	stats.Add(numInjectedStmts, 0)
+	stats.Add(numLintProblems, 0)
+	stats.Add(numLintRejections, 0)
+	stats.Add(numACLRejections, 0)
+	stats.Add(numAnalysisCacheHits, 0)
 }
 
 // Process processes the given SQL statements, rewriting them if necessary. If
 // random-rewriting is enabled, calls to the RANDOM() function are replaced with
 // an actual random value. If a statement contains a RETURNING clause, the
 // statement is marked as a query, so that the result set can be returned to the
-// client.
-func Process(stmts []*proto.Statement, rwrand, rwtime bool) (retErr error) {
+// client. An INSERT statement which does not give every column a value records
+// the time and random seed to be used for any non-deterministic column
+// defaults. See InjectDefaults.
+func Process(stmts []*proto.Statement, rwrand, rwtime bool) error {
+	return ProcessWithCatalog(stmts, rwrand, rwtime, nil)
+}
+
+// ProcessWithCatalog is like Process, but an INSERT statement only records a
+// time and random seed if the catalog shows that a column it does not give a
+// value has a non-deterministic default, or does not show the table at all.
+// The catalog is not consulted if any statement may change the schema, and
+// every such INSERT records them if catalog is nil.
+//
+// The catalog is this node's view of the database, which may lag the view of
+// the node which executes the statements. An INSERT into a table which has
+// just been given non-deterministic defaults may therefore not record the
+// values they need, and leave them to be computed by each node.
+func ProcessWithCatalog(stmts []*proto.Statement, rwrand, rwtime bool, catalog Catalog) (retErr error) {
 	defer func() {
 		if r := recover(); r != nil {
 			stats.Add(numParserPanics, 1)
 			retErr = fmt.Errorf("panic during SQL processing: %v", r)
 		}
 	}()
+	if rwrand || rwtime {
+		for i := range stmts {
+			if MayChangeSchema(stmts[i].Sql) {
+				catalog = nil
+				break
+			}
+		}
+	}
 	for i := range stmts {
 		lowered := strings.ToLower(stmts[i].Sql)
+		if (rwrand || rwtime) && mayTakeDefaults(stmts[i], lowered, catalog) {
+			// Which columns take their defaults is only known once the statement
+			// is executed, so choose the values they use now.
+			if rwtime {
+				stmts[i].DefaultTime = time.Now().UnixNano()
+			}
+			if rwrand {
+				stmts[i].DefaultSeed = rand.Int64()
+			}
+		}
 		if (!rwtime || !ContainsTime(lowered)) &&
 			(!rwrand || !ContainsRandom(lowered)) &&
 			!ContainsReturning(lowered) &&
 			!ContainsExplain(lowered) {
 			continue
 		}
-		parsed, err := rsql.NewParser(strings.NewReader(stmts[i].Sql)).ParseStatement()
+		// References to earlier results are not valid SQL, so must be masked
+		// while the statement is parsed and rewritten.
+		masked, unmask, err := maskReferences(stmts[i].Sql)
+		if err != nil {
+			continue
+		}
+		parsed, err := rsql.NewParser(strings.NewReader(masked)).ParseStatement()
 		if err != nil {
 			continue
 		}
@@ -70,7 +121,7 @@ func Process(stmts []*proto.Statement, rwrand, rwtime bool) (retErr error) {
 
 		if rewritten {
 			stats.Add(numRewrittenStmts, 1)
-			stmts[i].Sql = rwStmt.String()
+			stmts[i].Sql = unmask(rwStmt.String())
 		}
 		stmts[i].ForceQuery = ret
 	}
@@ -81,9 +132,10 @@ func Process(stmts []*proto.Statement, rwrand, rwtime bool) (retErr error) {
 // The function performs a lower-case comparison so it is up to the caller to
 // ensure the statement is lower-cased.
 func ContainsTime(stmt string) bool {
-	// Since this is a simple substring search, it also matches datetime(
-	// and strftime(.
-	targets := []string{"time(", "date(", "julianday(", "unixepoch(", "timediff("}
+	// Since this is a simple substring search, it also matches datetime(,
+	// strftime( and current_timestamp.
+	targets := []string{"time(", "date(", "julianday(", "unixepoch(", "timediff(",
+		"current_time", "current_date"}
 	for _, target := range targets {
 		if strings.Contains(stmt, target) {
 			return true
@@ -105,6 +157,95 @@ func ContainsRandom(stmt string) bool {
 	return false
 }
 
+// ContainsInsert returns true if the statement may insert rows into a table.
+// The function performs a lower-case comparison so it is up to the caller to
+// ensure the statement is lower-cased.
+func ContainsInsert(stmt string) bool {
+	if strings.Contains(stmt, "insert") {
+		return true
+	}
+	// REPLACE is also a function, which does not insert anything.
+	for {
+		i := strings.Index(stmt, "replace")
+		if i < 0 {
+			return false
+		}
+		stmt = stmt[i+len("replace"):]
+		if !strings.HasPrefix(strings.TrimLeft(stmt, " \t\r\n"), "(") {
+			return true
+		}
+	}
+}
+
+// MayChangeSchema returns true if the statement may change the schema, or roll
+// back a change to it. The check is deliberately coarse, and is not limited to
+// lower-cased statements.
+func MayChangeSchema(stmt string) bool {
+	lowered := strings.ToLower(stmt)
+	for _, kw := range []string{"create", "alter", "drop", "rollback", "pragma", "attach", "detach"} {
+		if strings.Contains(lowered, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayTakeDefaults returns true if the statement may insert rows which take
+// non-deterministic column defaults. If catalog is nil, any INSERT statement
+// which does not give every column a value may.
+func mayTakeDefaults(stmt *proto.Statement, lowered string, catalog Catalog) bool {
+	if stmt.Sql == "" {
+		// A named statement, whose SQL is only known once it is executed.
+		return true
+	}
+	if !ContainsInsert(lowered) {
+		return false
+	}
+	masked, _, err := maskReferences(stmt.Sql)
+	if err != nil {
+		return false
+	}
+	parsed, err := rsql.NewParser(strings.NewReader(masked)).ParseStatement()
+	if err != nil {
+		// Defaults cannot be injected into it either.
+		return false
+	}
+	ins, ok := parsed.(*rsql.InsertStatement)
+	if !ok || ins.Select != nil {
+		return false
+	}
+	if len(ins.Columns) == 0 && !ins.DefaultValues.IsValid() {
+		// Every column is given a value.
+		return false
+	}
+	if catalog == nil {
+		return true
+	}
+
+	var schema string
+	if ins.Schema != nil {
+		schema = ins.Schema.Name
+	}
+	defaults, err := catalog.ColumnDefaults(schema, ins.Table.Name)
+	if err != nil {
+		return true
+	}
+	given := make(map[string]bool, len(ins.Columns))
+	for _, c := range ins.Columns {
+		given[strings.ToLower(c.Name)] = true
+	}
+	for _, d := range defaults {
+		if given[strings.ToLower(d.Column)] {
+			continue
+		}
+		expr := strings.ToLower(d.Expr)
+		if ContainsTime(expr) || ContainsRandom(expr) {
+			return true
+		}
+	}
+	return false
+}
+
 // ContainsReturning returns true if the statement contains a RETURNING clause.
 // The function performs a lower-case comparison so it is up to the caller to
 // ensure the statement is lower-cased.
@@ -124,8 +265,9 @@ type Rewriter struct {
 	RewriteRand bool
 	RewriteTime bool
 
-	randFn func() int64
-	nowFn  func() time.Time
+	randFn  func() int64
+	bytesFn func(n int) []byte
+	nowFn   func() time.Time
 
 	orderedBy bool
 	modified  bool
@@ -139,8 +281,9 @@ func NewRewriter() *Rewriter {
 		RewriteRand: true,
 		RewriteTime: true,
 
-		randFn: rand.Int64,
-		nowFn:  time.Now,
+		randFn:  rand.Int64,
+		bytesFn: random.Bytes,
+		nowFn:   time.Now,
 	}
 }
 
@@ -158,6 +301,35 @@ func (rw *Rewriter) Visit(node sql.Node) (w sql.Visitor, n sql.Node, err error)
 	retNode := node
 
 	switch n := retNode.(type) {
+	case *sql.CreateTableStatement:
+		// Unlike the column definitions, a table's SELECT is only evaluated
+		// when the table is created.
+		if n.Select == nil {
+			return nil, node, nil
+		}
+	case *sql.CreateViewStatement, *sql.CreateTriggerStatement, *sql.AlterTableStatement:
+		// Definitions are evaluated each time they are used, not when they
+		// are created, so must be left as they are.
+		return nil, node, nil
+	case *sql.Ident:
+		// The parser treats time keywords as identifiers, which are quoted
+		// when rendered, so must be converted back to keywords if they are
+		// not rewritten.
+		if !n.Quoted {
+			if v, ok := timeKeyword(n.Name, rw.nowFn()); ok && rw.RewriteTime {
+				retNode = &sql.StringLit{Value: v}
+				rw.modified = true
+			} else if ok {
+				retNode = &sql.TimestampLit{Value: strings.ToUpper(n.Name)}
+			}
+		}
+	case *sql.TimestampLit:
+		if rw.RewriteTime {
+			if v, ok := timeKeyword(n.Value, rw.nowFn()); ok {
+				retNode = &sql.StringLit{Value: v}
+				rw.modified = true
+			}
+		}
 	case *sql.ReturningClause:
 		rw.returning = true
 	case *sql.OrderingTerm:
@@ -206,7 +378,7 @@ func (rw *Rewriter) Visit(node sql.Node) (w sql.Visitor, n sql.Node, err error)
 				if err != nil {
 					break
 				}
-				retNode = &sql.BlobLit{Value: fmt.Sprintf(`%X`, random.Bytes(max(n, 1)))}
+				retNode = &sql.BlobLit{Value: fmt.Sprintf(`%X`, rw.bytesFn(max(n, 1)))}
 				rw.modified = true
 			}
 		}
@@ -231,6 +403,21 @@ func isNow(e sql.Expr) bool {
 	return false
 }
 
+// timeKeyword returns the value SQLite gives the keyword name at time t, and
+// whether name is such a keyword.
+func timeKeyword(name string, t time.Time) (string, bool) {
+	t = t.UTC()
+	switch strings.ToUpper(name) {
+	case "CURRENT_TIMESTAMP":
+		return t.Format(time.DateTime), true
+	case "CURRENT_DATE":
+		return t.Format(time.DateOnly), true
+	case "CURRENT_TIME":
+		return t.Format(time.TimeOnly), true
+	}
+	return "", false
+}
+
 func julianDayAsNumberLit(t time.Time) *sql.NumberLit {
 	return &sql.NumberLit{Value: fmt.Sprintf("%f", julianDay(t))}
 }
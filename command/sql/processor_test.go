@@ -49,6 +49,21 @@ func Test_ContainsTime(t *testing.T) {
 			stmt:     "select strftime('2023-01-01', '2022-01-01')",
 			expected: true,
 		},
+		{
+			name:     "Contains time keyword - current_timestamp",
+			stmt:     "insert into foo values(current_timestamp)",
+			expected: true,
+		},
+		{
+			name:     "Contains time keyword - current_date",
+			stmt:     "insert into foo values(current_date)",
+			expected: true,
+		},
+		{
+			name:     "Contains time keyword - current_time",
+			stmt:     "insert into foo values(current_time)",
+			expected: true,
+		},
 
 		// Test cases where no time-related function is present
 		{
@@ -148,6 +163,49 @@ func Test_ContainsRandom(t *testing.T) {
 	}
 }
 
+func Test_ContainsInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		stmt     string
+		expected bool
+	}{
+		{
+			name:     "INSERT",
+			stmt:     "insert into foo(name) values('fiona')",
+			expected: true,
+		},
+		{
+			name:     "INSERT OR REPLACE",
+			stmt:     "insert or replace into foo(name) values('fiona')",
+			expected: true,
+		},
+		{
+			name:     "REPLACE",
+			stmt:     "replace\n  into foo(name) values('fiona')",
+			expected: true,
+		},
+		{
+			name:     "UPDATE with replace function",
+			stmt:     "update foo set name = replace (name, 'a', 'b')",
+			expected: false,
+		},
+		{
+			name:     "SELECT",
+			stmt:     "select * from foo",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ContainsInsert(tt.stmt)
+			if result != tt.expected {
+				t.Errorf("ContainsInsert(%q) = %v; want %v", tt.stmt, result, tt.expected)
+			}
+		})
+	}
+}
+
 func Test_ContainsReturning(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -325,6 +383,14 @@ func Test_Time_Rewrites(t *testing.T) {
 		`INSERT INTO "values" VALUES (julianday("now"))`, `INSERT INTO "values" VALUES \(julianday\([0-9]+\.[0-9]+\)\)`,
 		`INSERT INTO "values" VALUES (unixepoch("now"))`, `INSERT INTO "values" VALUES \(unixepoch\([0-9]+\.[0-9]+\)\)`,
 		`INSERT INTO "values" VALUES (strftime("%F", "now"))`, `INSERT INTO "values" VALUES \(strftime\("%F", [0-9]+\.[0-9]+\)\)`,
+		`INSERT INTO "values" VALUES (CURRENT_TIMESTAMP)`, `INSERT INTO "values" VALUES \('[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}'\)`,
+		`UPDATE "values" SET d = current_date`, `UPDATE "values" SET "d" = '[0-9]{4}-[0-9]{2}-[0-9]{2}'`,
+		`SELECT CURRENT_TIME, "CURRENT_TIME" FROM "values"`, `SELECT '[0-9]{2}:[0-9]{2}:[0-9]{2}', "CURRENT_TIME" FROM "values"`,
+		`CREATE TABLE tbl (ts DATETIME DEFAULT CURRENT_TIMESTAMP)`, `CREATE TABLE tbl \(ts DATETIME DEFAULT CURRENT_TIMESTAMP\)`,
+		`CREATE TABLE tbl (ts DATETIME DEFAULT (datetime('now')))`, `CREATE TABLE tbl \(ts DATETIME DEFAULT \(datetime\('now'\)\)\)`,
+		`CREATE VIEW v AS SELECT CURRENT_DATE`, `CREATE VIEW v AS SELECT CURRENT_DATE`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET ts = CURRENT_TIMESTAMP; END`, `CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET ts = CURRENT_TIMESTAMP; END`,
+		`ALTER TABLE t ADD COLUMN d TEXT DEFAULT (date('now'))`, `ALTER TABLE t ADD COLUMN d TEXT DEFAULT \(date\('now'\)\)`,
 	}
 	for i := 0; i < len(testSQLs)-1; i += 2 {
 		stmts := []*proto.Statement{
@@ -343,6 +409,85 @@ func Test_Time_Rewrites(t *testing.T) {
 	}
 }
 
+func Test_Process_Defaults(t *testing.T) {
+	stmts := []*proto.Statement{
+		{Sql: `INSERT INTO foo(name) VALUES('fiona')`},
+		{Sql: `REPLACE INTO foo(name) VALUES('fiona')`},
+		{Sql: `SELECT * FROM foo`},
+		{Name: "add"},
+		{Sql: `UPDATE foo SET name = replace(name, 'a', 'b')`},
+		{Sql: `INSERT INTO foo VALUES(1, 'fiona')`},
+		{Sql: `INSERT INTO foo(name) SELECT name FROM bar`},
+		{Sql: `INSERT INTO foo DEFAULT VALUES`},
+	}
+	if err := Process(stmts, true, true); err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	for i, exp := range []bool{true, true, false, true, false, false, false, true} {
+		if got := stmts[i].DefaultTime != 0 && stmts[i].DefaultSeed != 0; got != exp {
+			t.Fatalf("statement %d has wrong defaults, exp %t, got time %d, seed %d",
+				i, exp, stmts[i].DefaultTime, stmts[i].DefaultSeed)
+		}
+	}
+
+	stmts = []*proto.Statement{{Sql: `INSERT INTO foo(name) VALUES('fiona')`}}
+	if err := Process(stmts, false, false); err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	if stmts[0].DefaultTime != 0 || stmts[0].DefaultSeed != 0 {
+		t.Fatalf("defaults recorded without rewriting")
+	}
+}
+
+func Test_ProcessWithCatalog_Defaults(t *testing.T) {
+	catalog := newMockCatalog()
+	catalog.defaults = map[string][]ColumnDefault{
+		"plain": {{Column: "id", Expr: ""}, {Column: "name", Expr: "'unknown'"}},
+		"stamped": {
+			{Column: "name", Expr: "'unknown'"},
+			{Column: "ts", Expr: "CURRENT_TIMESTAMP"},
+		},
+	}
+
+	stmts := []*proto.Statement{
+		{Sql: `INSERT INTO plain(id) VALUES(1)`},
+		{Sql: `INSERT INTO stamped(ts, name) VALUES(1, 'fiona')`},
+		{Sql: `INSERT INTO stamped(name) VALUES('fiona')`},
+		{Sql: `INSERT INTO main.stamped DEFAULT VALUES`},
+	}
+	if err := ProcessWithCatalog(stmts, true, true, catalog); err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	for i, exp := range []bool{false, false, true, true} {
+		if got := stmts[i].DefaultTime != 0 && stmts[i].DefaultSeed != 0; got != exp {
+			t.Fatalf("statement %d has wrong defaults, exp %t, got time %d, seed %d",
+				i, exp, stmts[i].DefaultTime, stmts[i].DefaultSeed)
+		}
+	}
+
+	// A table the catalog does not show, and a schema change in the same
+	// request, are both treated as needing the values.
+	catalog.err = ErrNoSuchTable
+	stmts = []*proto.Statement{{Sql: `INSERT INTO plain(id) VALUES(1)`}}
+	if err := ProcessWithCatalog(stmts, true, true, catalog); err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	if stmts[0].DefaultTime == 0 || stmts[0].DefaultSeed == 0 {
+		t.Fatalf("defaults not recorded for missing table")
+	}
+	catalog.err = nil
+	stmts = []*proto.Statement{
+		{Sql: `ALTER TABLE plain ADD COLUMN ts DEFAULT CURRENT_TIMESTAMP`},
+		{Sql: `INSERT INTO plain(id) VALUES(1)`},
+	}
+	if err := ProcessWithCatalog(stmts, true, true, catalog); err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	if stmts[1].DefaultTime == 0 || stmts[1].DefaultSeed == 0 {
+		t.Fatalf("defaults not recorded after schema change")
+	}
+}
+
 func Test_RETURNING_None(t *testing.T) {
 	for _, str := range []string{
 		`INSERT INTO "names" VALUES (1, 'bob', '123-45-678')`,
@@ -0,0 +1,314 @@
+package sql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// ReferenceField is the part of an earlier statement's result that a
+// Reference refers to.
+type ReferenceField string
+
+const (
+	// RefLastInsertID refers to the last insert ID of an execute result.
+	RefLastInsertID ReferenceField = "last_insert_id"
+
+	// RefRowsAffected refers to the rows affected by an execute result.
+	RefRowsAffected ReferenceField = "rows_affected"
+
+	// RefRows refers to a column value of a row returned by a query, or by
+	// a statement with a RETURNING clause.
+	RefRows ReferenceField = "rows"
+)
+
+// referencePrefix starts every reference. Since $ is a valid identifier
+// character in SQLite, :$ also starts a legal named parameter. Any such
+// parameter is taken to be a reference, so parameters named with a leading
+// $ cannot be used in statements containing references.
+const referencePrefix = ":$"
+
+// Reference is a reference, within a statement, to the result of an earlier
+// statement in the same request. References take one of the forms
+//
+//	:$N.last_insert_id
+//	:$N.rows_affected
+//	:$N.rows[R].column
+//
+// where N is the zero-based index of the statement in the request, and R is
+// the zero-based index of a row in that statement's result.
+type Reference struct {
+	// Start and End are the byte offsets of the reference in the statement.
+	Start, End int
+
+	Stmt   int
+	Field  ReferenceField
+	Row    int
+	Column string
+}
+
+// String returns the reference as it appears in a statement.
+func (r Reference) String() string {
+	if r.Field == RefRows {
+		return fmt.Sprintf("%s%d.rows[%d].%s", referencePrefix, r.Stmt, r.Row, r.Column)
+	}
+	return fmt.Sprintf("%s%d.%s", referencePrefix, r.Stmt, r.Field)
+}
+
+// ContainsReference returns true if the statement may contain a reference to
+// the result of an earlier statement.
+func ContainsReference(stmt string) bool {
+	return strings.Contains(stmt, referencePrefix)
+}
+
+// FindReferences returns the references in the given statement, in the order
+// they appear. References inside string literals, quoted identifiers and
+// comments are ignored. An error is returned if a reference is malformed.
+func FindReferences(stmt string) ([]Reference, error) {
+	if !ContainsReference(stmt) {
+		return nil, nil
+	}
+
+	var refs []Reference
+	for i := 0; i < len(stmt); {
+		switch c := stmt[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipUntil(stmt, i+1, string(c))
+		case c == '[':
+			i = skipUntil(stmt, i+1, "]")
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i+2, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipUntil(stmt, i+2, "*/")
+		case strings.HasPrefix(stmt[i:], referencePrefix):
+			ref, err := parseReference(stmt, i)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+			i = ref.End
+		default:
+			i++
+		}
+	}
+	return refs, nil
+}
+
+// ResolveReferences returns the statement with each reference replaced by the
+// SQL literal of the value it refers to. resultFn is called to retrieve the
+// result of the referenced statement, and returns nil if no such result exists.
+func ResolveReferences(stmt string, resultFn func(n int) *proto.ExecuteQueryResponse) (string, error) {
+	refs, err := FindReferences(stmt)
+	if err != nil || len(refs) == 0 {
+		return stmt, err
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, ref := range refs {
+		v, err := ref.value(resultFn(ref.Stmt))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(stmt[last:ref.Start])
+		b.WriteString(literal(v))
+		last = ref.End
+	}
+	b.WriteString(stmt[last:])
+	return b.String(), nil
+}
+
+// value returns the value referred to within the given result.
+func (r Reference) value(res *proto.ExecuteQueryResponse) (*proto.Parameter, error) {
+	if res == nil {
+		return nil, fmt.Errorf("%s: statement %d has not been executed", r, r.Stmt)
+	}
+	if e := res.GetError(); e != "" {
+		return nil, fmt.Errorf("%s: statement %d failed", r, r.Stmt)
+	}
+
+	switch r.Field {
+	case RefLastInsertID, RefRowsAffected:
+		e := res.GetE()
+		if e == nil {
+			return nil, fmt.Errorf("%s: statement %d did not return an execute result", r, r.Stmt)
+		}
+		if r.Field == RefLastInsertID {
+			return &proto.Parameter{Value: &proto.Parameter_I{I: e.LastInsertId}}, nil
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: e.RowsAffected}}, nil
+	}
+
+	q := res.GetQ()
+	if q == nil {
+		return nil, fmt.Errorf("%s: statement %d did not return rows", r, r.Stmt)
+	}
+	if q.Error != "" {
+		return nil, fmt.Errorf("%s: statement %d failed", r, r.Stmt)
+	}
+	if r.Row >= len(q.Values) {
+		return nil, fmt.Errorf("%s: statement %d returned %d rows", r, r.Stmt, len(q.Values))
+	}
+	for i, c := range q.Columns {
+		if strings.EqualFold(c, r.Column) {
+			return q.Values[r.Row].Parameters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: statement %d has no column %s", r, r.Stmt, r.Column)
+}
+
+// parseReference parses the reference starting at offset start of stmt.
+func parseReference(stmt string, start int) (Reference, error) {
+	ref := Reference{Start: start}
+	i := start + len(referencePrefix)
+	malformed := func() (Reference, error) {
+		end := i
+		for end < len(stmt) && isReferenceChar(stmt[end]) {
+			end++
+		}
+		return Reference{}, fmt.Errorf("malformed statement reference %q, parameters named with a leading $ are not supported", stmt[start:end])
+	}
+
+	n, i, ok := scanDigits(stmt, i)
+	if !ok || i >= len(stmt) || stmt[i] != '.' {
+		return malformed()
+	}
+	ref.Stmt = n
+	i++
+
+	switch {
+	case strings.HasPrefix(stmt[i:], string(RefLastInsertID)):
+		ref.Field = RefLastInsertID
+		i += len(RefLastInsertID)
+	case strings.HasPrefix(stmt[i:], string(RefRowsAffected)):
+		ref.Field = RefRowsAffected
+		i += len(RefRowsAffected)
+	case strings.HasPrefix(stmt[i:], string(RefRows)+"["):
+		ref.Field = RefRows
+		i += len(RefRows) + 1
+		if ref.Row, i, ok = scanDigits(stmt, i); !ok {
+			return malformed()
+		}
+		if !strings.HasPrefix(stmt[i:], "].") {
+			return malformed()
+		}
+		i += 2
+		j := i
+		for j < len(stmt) && isIdentChar(stmt[j]) {
+			j++
+		}
+		if j == i || isDigit(stmt[i]) {
+			return malformed()
+		}
+		ref.Column = stmt[i:j]
+		i = j
+	default:
+		return malformed()
+	}
+	if i < len(stmt) && isIdentChar(stmt[i]) {
+		return malformed()
+	}
+	ref.End = i
+	return ref, nil
+}
+
+// maskReferences replaces each reference in stmt with a named bind parameter,
+// so the statement can be parsed. The returned function reverses the change.
+func maskReferences(stmt string) (string, func(string) string, error) {
+	refs, err := FindReferences(stmt)
+	if err != nil || len(refs) == 0 {
+		return stmt, func(s string) string { return s }, err
+	}
+
+	var b strings.Builder
+	names := make([]string, len(refs))
+	last := 0
+	for i, ref := range refs {
+		names[i] = fmt.Sprintf(":rqlite_ref_%d", i)
+		b.WriteString(stmt[last:ref.Start])
+		b.WriteString(names[i])
+		last = ref.End
+	}
+	b.WriteString(stmt[last:])
+
+	unmask := func(s string) string {
+		// Replace in reverse so :rqlite_ref_1 does not match :rqlite_ref_10.
+		for i := len(refs) - 1; i >= 0; i-- {
+			s = strings.ReplaceAll(s, names[i], stmt[refs[i].Start:refs[i].End])
+		}
+		return s
+	}
+	return b.String(), unmask, nil
+}
+
+// literal returns the SQL literal for the given value.
+func literal(p *proto.Parameter) string {
+	switch v := p.GetValue().(type) {
+	case *proto.Parameter_I:
+		return strconv.FormatInt(v.I, 10)
+	case *proto.Parameter_D:
+		switch {
+		case math.IsNaN(v.D):
+			return "NULL"
+		case math.IsInf(v.D, 1):
+			return "9e999"
+		case math.IsInf(v.D, -1):
+			return "-9e999"
+		}
+		s := strconv.FormatFloat(v.D, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case *proto.Parameter_B:
+		if v.B {
+			return "1"
+		}
+		return "0"
+	case *proto.Parameter_Y:
+		return "X'" + hex.EncodeToString(v.Y) + "'"
+	case *proto.Parameter_S:
+		return "'" + strings.ReplaceAll(v.S, "'", "''") + "'"
+	}
+	return "NULL"
+}
+
+// skipUntil returns the offset just past the next occurrence of end in s,
+// starting at offset i, or len(s) if there is none.
+func skipUntil(s string, i int, end string) int {
+	if j := strings.Index(s[i:], end); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(s)
+}
+
+func scanDigits(s string, i int) (int, int, bool) {
+	j := i
+	for j < len(s) && isDigit(s[j]) {
+		j++
+	}
+	if j == i {
+		return 0, i, false
+	}
+	n, err := strconv.Atoi(s[i:j])
+	if err != nil {
+		return 0, i, false
+	}
+	return n, j, true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isReferenceChar(c byte) bool {
+	return isIdentChar(c) || c == '.' || c == '[' || c == ']' || c == '$'
+}
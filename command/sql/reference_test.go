@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_FindReferences(t *testing.T) {
+	tests := []struct {
+		stmt string
+		exp  []string
+		err  bool
+	}{
+		{stmt: "INSERT INTO foo VALUES(1)"},
+		{stmt: "INSERT INTO foo VALUES(:$0.last_insert_id)", exp: []string{":$0.last_insert_id"}},
+		{stmt: "UPDATE foo SET n=:$12.rows_affected WHERE id=:$3.rows[2].id", exp: []string{":$12.rows_affected", ":$3.rows[2].id"}},
+		{stmt: "INSERT INTO foo VALUES(:$1.rows[0].parent_id,:$0.last_insert_id)", exp: []string{":$1.rows[0].parent_id", ":$0.last_insert_id"}},
+		{stmt: `SELECT ':$0.last_insert_id', ":$0.rows_affected" -- :$0.x`},
+		{stmt: "SELECT [:$0.last_insert_id] /* :$0 */ FROM foo"},
+		{stmt: "SELECT :$0.last_insert_idx", err: true},
+		{stmt: "SELECT :$0.foo", err: true},
+		{stmt: "SELECT :$x.last_insert_id", err: true},
+		{stmt: "SELECT :$0.rows[a].id", err: true},
+		{stmt: "SELECT :$0.rows[0]", err: true},
+		{stmt: "SELECT :$0.rows[0].1d", err: true},
+		{stmt: "SELECT * FROM foo WHERE name = :$name", err: true},
+	}
+	for _, tt := range tests {
+		refs, err := FindReferences(tt.stmt)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("expected error for %q", tt.stmt)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", tt.stmt, err)
+		}
+		if len(refs) != len(tt.exp) {
+			t.Fatalf("wrong number of references for %q, got %d", tt.stmt, len(refs))
+		}
+		for i, ref := range refs {
+			if got := tt.stmt[ref.Start:ref.End]; got != tt.exp[i] {
+				t.Fatalf("wrong reference for %q, got %q, exp %q", tt.stmt, got, tt.exp[i])
+			}
+			if got := ref.String(); got != tt.exp[i] {
+				t.Fatalf("wrong reference string for %q, got %q, exp %q", tt.stmt, got, tt.exp[i])
+			}
+		}
+	}
+}
+
+func Test_ResolveReferences(t *testing.T) {
+	results := []*proto.ExecuteQueryResponse{
+		{Result: &proto.ExecuteQueryResponse_E{E: &proto.ExecuteResult{LastInsertId: 7, RowsAffected: 1}}},
+		{Result: &proto.ExecuteQueryResponse_Q{Q: &proto.QueryRows{
+			Columns: []string{"id", "name", "score", "data", "nothing", "ok"},
+			Values: []*proto.Values{
+				{Parameters: []*proto.Parameter{
+					{Value: &proto.Parameter_I{I: 3}},
+					{Value: &proto.Parameter_S{S: "o'brien"}},
+					{Value: &proto.Parameter_D{D: 2}},
+					{Value: &proto.Parameter_Y{Y: []byte{0xde, 0xad}}},
+					{},
+					{Value: &proto.Parameter_B{B: true}},
+				}},
+			},
+		}}},
+		{Result: &proto.ExecuteQueryResponse_Error{Error: "no such table: bar"}},
+	}
+	resultFn := func(n int) *proto.ExecuteQueryResponse {
+		if n >= len(results) {
+			return nil
+		}
+		return results[n]
+	}
+
+	tests := []struct {
+		stmt string
+		exp  string
+		err  string
+	}{
+		{
+			stmt: "INSERT INTO foo VALUES(:$0.last_insert_id, :$0.rows_affected)",
+			exp:  "INSERT INTO foo VALUES(7, 1)",
+		},
+		{
+			stmt: "INSERT INTO foo VALUES(:$1.rows[0].ID, :$1.rows[0].name, :$1.rows[0].score, :$1.rows[0].data, :$1.rows[0].nothing, :$1.rows[0].ok)",
+			exp:  "INSERT INTO foo VALUES(3, 'o''brien', 2.0, X'dead', NULL, 1)",
+		},
+		{
+			stmt: "SELECT ':$0.last_insert_id'",
+			exp:  "SELECT ':$0.last_insert_id'",
+		},
+		{stmt: "SELECT :$1.last_insert_id", err: "did not return an execute result"},
+		{stmt: "SELECT :$0.rows[0].id", err: "did not return rows"},
+		{stmt: "SELECT :$1.rows[1].id", err: "returned 1 rows"},
+		{stmt: "SELECT :$1.rows[0].missing", err: "has no column missing"},
+		{stmt: "SELECT :$2.last_insert_id", err: "statement 2 failed"},
+		{stmt: "SELECT :$3.last_insert_id", err: "has not been executed"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveReferences(tt.stmt, resultFn)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q for %q, got %v", tt.err, tt.stmt, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", tt.stmt, err)
+		}
+		if got != tt.exp {
+			t.Fatalf("wrong resolved statement, got %q, exp %q", got, tt.exp)
+		}
+	}
+}
+
+func Test_Literal(t *testing.T) {
+	tests := []struct {
+		p   *proto.Parameter
+		exp string
+	}{
+		{&proto.Parameter{Value: &proto.Parameter_I{I: -5}}, "-5"},
+		{&proto.Parameter{Value: &proto.Parameter_D{D: 1.5}}, "1.5"},
+		{&proto.Parameter{Value: &proto.Parameter_D{D: 1e100}}, "1e+100"},
+		{&proto.Parameter{Value: &proto.Parameter_D{D: math.Inf(-1)}}, "-9e999"},
+		{&proto.Parameter{Value: &proto.Parameter_B{B: false}}, "0"},
+		{&proto.Parameter{Value: &proto.Parameter_S{S: "a'b"}}, "'a''b'"},
+		{&proto.Parameter{}, "NULL"},
+	}
+	for _, tt := range tests {
+		if got := literal(tt.p); got != tt.exp {
+			t.Fatalf("wrong literal, got %s, exp %s", got, tt.exp)
+		}
+	}
+}
+
+func Test_ProcessReferences(t *testing.T) {
+	stmts := []*proto.Statement{
+		{Sql: "INSERT INTO foo(t, p) VALUES(datetime('now'), :$0.last_insert_id) RETURNING id, :$1.rows[10].x"},
+	}
+	if err := Process(stmts, true, true); err != nil {
+		t.Fatalf("failed to process statements: %s", err)
+	}
+	if strings.Contains(stmts[0].Sql, "'now'") {
+		t.Fatalf("time function not rewritten: %s", stmts[0].Sql)
+	}
+	if !strings.Contains(stmts[0].Sql, ":$0.last_insert_id") || !strings.Contains(stmts[0].Sql, ":$1.rows[10].x") {
+		t.Fatalf("references not preserved: %s", stmts[0].Sql)
+	}
+	if !stmts[0].ForceQuery {
+		t.Fatalf("RETURNING statement not marked as query")
+	}
+}
@@ -157,6 +157,30 @@ func Test_Rewriter_Time(t *testing.T) {
 			rewriteTime: true,
 			modified:    true,
 		},
+		{
+			in:          `INSERT INTO foo(col1, col2, col3) VALUES (CURRENT_TIMESTAMP, current_date, Current_Time)`,
+			exp:         `INSERT INTO "foo" ("col1", "col2", "col3") VALUES ('1969-07-20 00:00:00', '1969-07-20', '00:00:00')`,
+			rewriteTime: true,
+			modified:    true,
+		},
+		{
+			in:          `INSERT INTO foo(col1) VALUES (CURRENT_TIMESTAMP)`,
+			exp:         `INSERT INTO "foo" ("col1") VALUES (CURRENT_TIMESTAMP)`,
+			rewriteTime: false,
+			modified:    false,
+		},
+		{
+			in:          `SELECT "current_timestamp" FROM foo`,
+			exp:         `SELECT "current_timestamp" FROM "foo"`,
+			rewriteTime: true,
+			modified:    false,
+		},
+		{
+			in:          `CREATE TABLE foo (col1 TEXT DEFAULT CURRENT_TIMESTAMP, col2 TEXT DEFAULT (time('now')))`,
+			exp:         `CREATE TABLE "foo" ("col1" TEXT DEFAULT CURRENT_TIMESTAMP, "col2" TEXT DEFAULT (time('now')))`,
+			rewriteTime: true,
+			modified:    false,
+		},
 	} {
 		rw := NewRewriter()
 		rw.RewriteTime = tt.rewriteTime
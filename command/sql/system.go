@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// The statements with which the endpoints managing the tables of rqlite change
+// them. No other statement may change those tables.
+const (
+	// CreateUsersTableSQL creates the table of users, if it does not already
+	// exist. The credential of each user, less its username, is stored as JSON.
+	CreateUsersTableSQL = `CREATE TABLE IF NOT EXISTS ` + auth.UsersTable +
+		` (username TEXT NOT NULL PRIMARY KEY, credential TEXT NOT NULL)`
+
+	// PutUserSQL creates or replaces a user, given its username and credential.
+	PutUserSQL = `INSERT OR REPLACE INTO ` + auth.UsersTable + `(username, credential) VALUES(?, ?)`
+
+	// UpdateUserSQL is like PutUserSQL, but an existing user keeps its password.
+	UpdateUserSQL = `INSERT INTO ` + auth.UsersTable + `(username, credential) VALUES(?, ?) ` +
+		`ON CONFLICT(username) DO UPDATE SET credential = ` +
+		`json_set(excluded.credential, '$.password', json_extract(credential, '$.password'))`
+
+	// DeleteUserSQL removes a user, given its username.
+	DeleteUserSQL = `DELETE FROM ` + auth.UsersTable + ` WHERE username = ?`
+
+	// CreateStatementsTableSQL creates the registry of named statements,
+	// if it does not already exist.
+	CreateStatementsTableSQL = `CREATE TABLE IF NOT EXISTS ` + StatementsTable +
+		` (name TEXT NOT NULL PRIMARY KEY, sql TEXT NOT NULL)`
+
+	// PutStatementSQL registers a statement, given its name and SQL.
+	PutStatementSQL = `INSERT OR REPLACE INTO ` + StatementsTable + `(name, sql) VALUES(?, ?)`
+
+	// DeleteStatementSQL removes a statement, given its name.
+	DeleteStatementSQL = `DELETE FROM ` + StatementsTable + ` WHERE name = ?`
+)
+
+// systemTableChanges maps each statement which may change a table of rqlite
+// to the table it changes.
+var systemTableChanges = map[string]string{
+	CreateUsersTableSQL:      auth.UsersTable,
+	PutUserSQL:               auth.UsersTable,
+	UpdateUserSQL:            auth.UsersTable,
+	DeleteUserSQL:            auth.UsersTable,
+	CreateStatementsTableSQL: StatementsTable,
+	PutStatementSQL:          StatementsTable,
+	DeleteStatementSQL:       StatementsTable,
+}
+
+// SystemTableChange returns the table of rqlite changed by stmt, if stmt is one
+// of the statements with which the endpoint managing the table changes it, with
+// a string for each parameter. Otherwise it returns the empty string. A
+// statement registered with PutStatementSQL may not itself refer to a table
+// of rqlite.
+func SystemTableChange(stmt *proto.Statement) string {
+	t, ok := systemTableChanges[stmt.Sql]
+	if !ok || stmt.Name != "" || stmt.ForceQuery || stmt.SqlExplain ||
+		len(stmt.Parameters) != strings.Count(stmt.Sql, "?") {
+		return ""
+	}
+	for _, p := range stmt.Parameters {
+		if _, ok := p.GetValue().(*proto.Parameter_S); !ok || p.Name != "" {
+			return ""
+		}
+	}
+	if stmt.Sql == PutStatementSQL && ReferencesSystemTable(stmt.Parameters[1].GetS()) != "" {
+		return ""
+	}
+	return t
+}
@@ -0,0 +1,42 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+func Test_SystemTableChange(t *testing.T) {
+	params := func(ss ...string) []*proto.Parameter {
+		var p []*proto.Parameter
+		for _, s := range ss {
+			p = append(p, &proto.Parameter{Value: &proto.Parameter_S{S: s}})
+		}
+		return p
+	}
+	for i, tc := range []struct {
+		stmt *proto.Statement
+		exp  string
+	}{
+		{&proto.Statement{Sql: CreateUsersTableSQL}, auth.UsersTable},
+		{&proto.Statement{Sql: PutUserSQL, Parameters: params("fiona", "{}")}, auth.UsersTable},
+		{&proto.Statement{Sql: UpdateUserSQL, Parameters: params("fiona", "{}")}, auth.UsersTable},
+		{&proto.Statement{Sql: DeleteUserSQL, Parameters: params("fiona")}, auth.UsersTable},
+		{&proto.Statement{Sql: CreateStatementsTableSQL}, StatementsTable},
+		{&proto.Statement{Sql: PutStatementSQL, Parameters: params("all", "SELECT * FROM foo")}, StatementsTable},
+		{&proto.Statement{Sql: DeleteStatementSQL, Parameters: params("all")}, StatementsTable},
+		{&proto.Statement{Sql: "DELETE FROM " + auth.UsersTable}, ""},
+		{&proto.Statement{Sql: "SELECT * FROM foo"}, ""},
+		{&proto.Statement{Sql: DeleteUserSQL}, ""},
+		{&proto.Statement{Sql: DeleteUserSQL, Parameters: params("fiona", "dana")}, ""},
+		{&proto.Statement{Sql: DeleteUserSQL, Parameters: []*proto.Parameter{{Value: &proto.Parameter_I{I: 1}}}}, ""},
+		{&proto.Statement{Sql: DeleteUserSQL, Parameters: []*proto.Parameter{{Value: &proto.Parameter_S{S: "fiona"}, Name: "name"}}}, ""},
+		{&proto.Statement{Sql: DeleteUserSQL, Parameters: params("fiona"), Name: "purge"}, ""},
+		{&proto.Statement{Sql: PutStatementSQL, Parameters: params("users", "SELECT * FROM "+auth.UsersTable)}, ""},
+	} {
+		if got := SystemTableChange(tc.stmt); got != tc.exp {
+			t.Fatalf("test %d: exp %q, got %q", i, tc.exp, got)
+		}
+	}
+}
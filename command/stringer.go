@@ -70,6 +70,46 @@ func BackupFormatFromString(s string) proto.BackupRequest_Format {
 	}
 }
 
+// BackupCompressionToString converts a proto.BackupRequest_Compression to a string.
+func BackupCompressionToString(c proto.BackupRequest_Compression) string {
+	switch c {
+	case proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_NONE:
+		return "none"
+	case proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP:
+		return "gzip"
+	case proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
+// BackupCompressionFromString converts a string to a proto.BackupRequest_Compression.
+func BackupCompressionFromString(s string) proto.BackupRequest_Compression {
+	switch strings.ToLower(s) {
+	case "none":
+		return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_NONE
+	case "gzip":
+		return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP
+	case "zstd":
+		return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD
+	default:
+		return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED
+	}
+}
+
+// BackupCompression returns the compression algorithm to apply to the backup
+// described by br. If no algorithm is specified, Compress selects gzip.
+func BackupCompression(br *proto.BackupRequest) proto.BackupRequest_Compression {
+	if c := br.GetCompression(); c != proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED {
+		return c
+	}
+	if br.GetCompress() {
+		return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP
+	}
+	return proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_NONE
+}
+
 // SuffrageToString converts a proto.Suffrage to a string.
 func SuffrageToString(s proto.Suffrage) string {
 	switch s {
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
+)
+
+// sqliteDeterministic is the flag SQLite reports for functions which always
+// return the same result given the same arguments.
+const sqliteDeterministic = 0x800
+
+// Functions returns the functions the database provides, including those
+// provided by loaded extensions.
+func (db *DB) Functions() ([]cmdsql.FunctionInfo, error) {
+	rows, err := db.roDB.Query(`SELECT name, builtin, type, flags FROM pragma_function_list`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fns []cmdsql.FunctionInfo
+	for rows.Next() {
+		var name, typ string
+		var builtin bool
+		var flags int64
+		if err := rows.Scan(&name, &builtin, &typ, &flags); err != nil {
+			return nil, err
+		}
+		fns = append(fns, cmdsql.FunctionInfo{
+			Name:          name,
+			Builtin:       builtin,
+			Aggregate:     typ == "a" || typ == "w",
+			Deterministic: flags&sqliteDeterministic != 0,
+		})
+	}
+	return fns, rows.Err()
+}
+
+// Triggers returns the SQL of each trigger on the given table. schema may be
+// empty, in which case the main database is searched.
+func (db *DB) Triggers(schema, table string) ([]string, error) {
+	if schema == "" {
+		schema = "main"
+	}
+	rows, err := db.roDB.Query(fmt.Sprintf(`SELECT sql FROM "%s".sqlite_schema WHERE type = 'trigger' AND tbl_name = ? COLLATE NOCASE`,
+		strings.ReplaceAll(schema, `"`, `""`)), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, s)
+	}
+	return triggers, rows.Err()
+}
+
+// ColumnDefaults returns the declared defaults of the columns of the given
+// table, in column order.
+func (db *DB) ColumnDefaults(schema, table string) ([]cmdsql.ColumnDefault, error) {
+	return columnDefaults(context.Background(), db.roDB, schema, table)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/db/wal"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 // RetryableError is an error that indicates whether the failed operation
@@ -75,7 +76,7 @@ func NewCheckpointManager(db *DB) (*CheckpointManager, error) {
 		dbPath:     db.Path(),
 		resetWatch: &WALResetWatch{},
 		walPath:    db.WALPath(),
-		logger:     log.New(log.Writer(), "[db-checkpoint] ", log.LstdFlags),
+		logger:     logging.New("db-checkpoint", os.Stderr),
 	}, nil
 }
 
@@ -106,6 +107,8 @@ func (m *CheckpointManagerMeta) String() string {
 // the timeout, an error is returned.
 func (cm *CheckpointManager) Checkpoint(w io.Writer, timeout time.Duration) (*CheckpointManagerMeta, int64, error) {
 	stats.Add(numCheckpointTotal, 1)
+	startT := time.Now()
+	defer checkpointHist.RecordSince(startT)
 
 	walSzPre, err := fsutil.FileSize(cm.walPath)
 	if err != nil {
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"strings"
+
+	command "github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// SchemaChecksumTable is the name under which the checksum of the schema
+// itself is returned by Checksum.
+const SchemaChecksumTable = "sqlite_schema"
+
+// Checksum returns checksums of the content of every table in the main
+// database, and of the schema. The checksums depend only on the logical
+// content of the database, not on how it is stored, so they can be compared
+// across nodes. If chunkSize is greater than zero, the rows of each rowid
+// table are checksummed in ranges of chunkSize rowids, and only ranges which
+// contain rows are returned. Otherwise each table has a single checksum.
+func (db *DB) Checksum(chunkSize int64) ([]*command.TableChecksum, error) {
+	cr, err := db.NewChecksumReader(chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return cr.Checksums()
+}
+
+// ChecksumReader computes the checksums returned by Checksum, of the
+// database as it was when the reader was created.
+type ChecksumReader struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	conn      *sql.Conn
+	tx        *sql.Tx
+	chunkSize int64
+	schema    []*command.TableChecksum
+	release   func()
+	closed    bool
+}
+
+// NewChecksumReader starts a read transaction on the database, pinning its
+// current state, and returns a ChecksumReader which checksums that state.
+// The transaction is cheap to start, and writes may continue while it is
+// open, but WAL checkpoints cannot complete until the reader is closed.
+// Checksums or Close must be called on the returned reader.
+func (db *DB) NewChecksumReader(chunkSize int64) (*ChecksumReader, error) {
+	// Canceling the context rolls back the transaction, ending the read.
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := db.roDB.Conn(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Read every table in one transaction, so all checksums are of the same
+	// state of the database.
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		conn.Close()
+		cancel()
+		return nil, err
+	}
+
+	// The transaction only takes its snapshot of the database on its first
+	// read, so read the schema now. Statistics tables are excluded, since
+	// they may be updated by each node independently, for example by PRAGMA
+	// optimize.
+	sums, err := checksumQuery(ctx, tx, SchemaChecksumTable,
+		`SELECT type, name, tbl_name, quote(sql) FROM sqlite_schema WHERE name NOT LIKE 'sqlite_stat%' ORDER BY type, name`,
+		false, 0)
+	if err != nil {
+		tx.Rollback()
+		conn.Close()
+		cancel()
+		return nil, err
+	}
+	return &ChecksumReader{
+		ctx:       ctx,
+		cancel:    cancel,
+		conn:      conn,
+		tx:        tx,
+		chunkSize: chunkSize,
+		schema:    sums,
+	}, nil
+}
+
+// Checksums returns the checksums of the database, and closes the reader.
+func (c *ChecksumReader) Checksums() ([]*command.TableChecksum, error) {
+	defer c.Close()
+	if c.closed {
+		return nil, fmt.Errorf("checksum reader is closed")
+	}
+	ctx := c.ctx
+	tx := c.tx
+	sums := c.schema
+
+	type table struct {
+		name         string
+		withoutRowid bool
+	}
+	var tables []table
+	rows, err := tx.QueryContext(ctx, `SELECT name, wr FROM pragma_table_list WHERE schema = 'main' AND type IN ('table', 'shadow') AND name NOT LIKE 'sqlite_stat%' AND name <> 'sqlite_schema' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.name, &t.withoutRowid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tables {
+		cols, err := columnNames(ctx, tx, `SELECT name FROM pragma_table_info(?) ORDER BY cid`, t.name)
+		if err != nil {
+			return nil, err
+		}
+		quoted := make([]string, len(cols))
+		for i, c := range cols {
+			quoted[i] = fmt.Sprintf("quote(%s)", quoteIdent(c))
+		}
+
+		var query string
+		if t.withoutRowid {
+			pks, err := columnNames(ctx, tx, `SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`, t.name)
+			if err != nil {
+				return nil, err
+			}
+			for i := range pks {
+				pks[i] = quoteIdent(pks[i])
+			}
+			query = fmt.Sprintf(`SELECT %s FROM main.%s ORDER BY %s`,
+				strings.Join(quoted, ", "), quoteIdent(t.name), strings.Join(pks, ", "))
+		} else {
+			query = fmt.Sprintf(`SELECT rowid, %s FROM main.%s ORDER BY rowid`,
+				strings.Join(quoted, ", "), quoteIdent(t.name))
+		}
+		ts, err := checksumQuery(ctx, tx, t.name, query, !t.withoutRowid, c.chunkSize)
+		if err != nil {
+			return nil, fmt.Errorf("checksum of table %s: %w", t.name, err)
+		}
+		sums = append(sums, ts...)
+	}
+	return sums, nil
+}
+
+// Close ends the read transaction of the reader. It is a no-op if the
+// reader is already closed.
+func (c *ChecksumReader) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.tx.Rollback()
+	err := c.conn.Close()
+	c.cancel()
+	if c.release != nil {
+		c.release()
+	}
+	return err
+}
+
+// checksumQuery returns the checksums of the rows returned by query, which
+// must return text values, preceded by the rowid if rowid is true. Rows are
+// checksummed in ranges of chunkSize rowids if chunkSize is greater than zero
+// and rowid is true.
+func checksumQuery(ctx context.Context, q queryer, table, query string, rowid bool, chunkSize int64) ([]*command.TableChecksum, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var rid int64
+	vals := make([]string, len(cols))
+	dest := make([]any, len(cols))
+	first := 0
+	if rowid {
+		dest[0] = &rid
+		first = 1
+	}
+	for i := first; i < len(cols); i++ {
+		dest[i] = &vals[i]
+	}
+
+	var sums []*command.TableChecksum
+	var cur *command.TableChecksum
+	var h hash.Hash
+	var buf []byte
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if rowid && chunkSize > 0 {
+			start := floorDiv(rid, chunkSize) * chunkSize
+			if cur != nil && cur.Start != start {
+				sums = append(sums, finishChecksum(cur, h))
+				cur = nil
+			}
+			if cur == nil {
+				cur = &command.TableChecksum{Table: table, Start: start, End: math.MaxInt64}
+				if start <= math.MaxInt64-(chunkSize-1) {
+					cur.End = start + chunkSize - 1
+				}
+				h = sha256.New()
+			}
+		} else if cur == nil {
+			cur = &command.TableChecksum{Table: table}
+			h = sha256.New()
+		}
+
+		// Length-prefix each value, so that values cannot run into each other.
+		buf = buf[:0]
+		if rowid {
+			buf = binary.AppendVarint(buf, rid)
+		}
+		for _, v := range vals[first:] {
+			buf = binary.AppendUvarint(buf, uint64(len(v)))
+			buf = append(buf, v...)
+		}
+		h.Write(buf)
+		cur.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if cur == nil {
+		// Checksum empty tables too, so that missing tables are detected.
+		cur = &command.TableChecksum{Table: table}
+		h = sha256.New()
+	}
+	return append(sums, finishChecksum(cur, h)), nil
+}
+
+func finishChecksum(ts *command.TableChecksum, h hash.Hash) *command.TableChecksum {
+	ts.Sum = hex.EncodeToString(h.Sum(nil))
+	return ts
+}
+
+func columnNames(ctx context.Context, q queryer, query, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// floorDiv returns a divided by b, rounded towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
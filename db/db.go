@@ -20,11 +20,18 @@ import (
 	"time"
 
 	"github.com/mattn/go-sqlite3"
+	"github.com/rqlite/rqlite/v10/auth"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
 	"github.com/rqlite/rqlite/v10/db/humanize"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/latency"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rsum"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
+	"github.com/rqlite/rqlite/v10/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -70,6 +77,7 @@ const (
 	numUpdateHooksErrors         = "update_hooks_errors"
 	numCommitHooks               = "commit_hooks"
 	cdcDroppedEvents             = "dropped_cdc_events"
+	checkpointLatency            = "checkpoint_latency"
 )
 
 var (
@@ -131,6 +139,13 @@ var DBVersion string
 // stats captures stats for the DB layer.
 var stats *expvar.Map
 
+// checkpointHist is the latency histogram of checkpoints made through a
+// CheckpointManager, served as part of stats.
+var checkpointHist = latency.NewHistogram(0)
+
+// tracer traces the execution of requests by SQLite.
+var tracer = tracing.Tracer("db")
+
 func init() {
 	DBVersion, _, _ = sqlite3.Version()
 	stats = expvar.NewMap("db")
@@ -177,6 +192,8 @@ func ResetStats() {
 	stats.Add(numUpdateHooksErrors, 0)
 	stats.Add(numCommitHooks, 0)
 	stats.Add(cdcDroppedEvents, 0)
+	checkpointHist.Reset()
+	stats.Set(checkpointLatency, checkpointHist)
 }
 
 // DB is the SQL database.
@@ -196,6 +213,9 @@ type DB struct {
 	allOptimized   bool
 	allOptimizedMu sync.Mutex
 
+	namedAnalysis *cmdsql.AnalysisCache // Analysis of named statements as they are executed.
+	defaults      *defaultsCache        // Column defaults, as seen by the read-write connection.
+
 	logger *log.Logger
 }
 
@@ -246,7 +266,7 @@ func Open(dbPath string, fkEnabled, wal bool) (retDB *DB, retErr error) {
 // database is opened in WAL mode, the WAL files will also be created if they
 // do not exist.
 func OpenWithDriver(drv *Driver, dbPath string, fkEnabled, wal bool) (retDB *DB, retErr error) {
-	logger := log.New(log.Writer(), "[db] ", log.LstdFlags)
+	logger := logging.New("db", os.Stderr)
 	startTime := time.Now()
 	defer func() {
 		if retErr != nil {
@@ -346,7 +366,11 @@ func OpenWithDriver(drv *Driver, dbPath string, fkEnabled, wal bool) (retDB *DB,
 		roDB:      roDB,
 		rwDSN:     rwDSN,
 		roDSN:     roDSN,
-		logger:    logger,
+
+		namedAnalysis: cmdsql.NewAnalysisCache(1024),
+		defaults:      newDefaultsCache(),
+
+		logger: logger,
 	}, nil
 }
 
@@ -780,7 +804,7 @@ func (db *DB) CheckpointTruncateWithTimeout(dur time.Duration) (err error) {
 	}
 	defer func() {
 		if err := db.SetBusyTimeout(rwBt, -1); err != nil {
-			db.logger.Printf("failed to reset busy_timeout: %s", err.Error())
+			logging.Warnf(db.logger, "failed to reset busy_timeout: %s", err.Error())
 		}
 	}()
 
@@ -793,7 +817,7 @@ func (db *DB) CheckpointTruncateWithTimeout(dur time.Duration) (err error) {
 	}
 	defer func() {
 		if err := db.SetSynchronousMode(currMode); err != nil {
-			db.logger.Fatalf("failed to reset synchronous mode to %s: %s", currMode, err.Error())
+			logging.Fatalf(db.logger, "failed to reset synchronous mode to %s: %s", currMode, err.Error())
 		}
 	}()
 
@@ -837,7 +861,7 @@ func (db *DB) CheckpointWithTimeout(mode CheckpointMode, dur time.Duration) (met
 		defer func() {
 			// Reset back to default
 			if err := db.SetBusyTimeout(rwBt, -1); err != nil {
-				db.logger.Printf("failed to reset busy_timeout on checkpointing connection: %s", err.Error())
+				logging.Warnf(db.logger, "failed to reset busy_timeout on checkpointing connection: %s", err.Error())
 			}
 		}()
 	}
@@ -852,7 +876,7 @@ func (db *DB) CheckpointWithTimeout(mode CheckpointMode, dur time.Duration) (met
 	}
 	defer func() {
 		if err := db.SetSynchronousMode(currMode); err != nil {
-			db.logger.Fatalf("failed to reset synchronous mode to %s: %s", currMode, err.Error())
+			logging.Fatalf(db.logger, "failed to reset synchronous mode to %s: %s", currMode, err.Error())
 		}
 	}()
 
@@ -980,6 +1004,28 @@ func (db *DB) VerifyIntegrity() (IntegrityResult, error) {
 	return res, nil
 }
 
+// VerifyFileIntegrity checks that the file at path is a SQLite database which
+// passes a quick integrity check. It returns an error describing the first
+// problem found, if any.
+func VerifyFileIntegrity(path string) error {
+	if !IsValidSQLiteFile(path) {
+		return fmt.Errorf("not a valid SQLite file")
+	}
+	db, err := Open(path, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %s", err.Error())
+	}
+	defer db.Close()
+	res, err := db.VerifyIntegrity()
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %s", err.Error())
+	}
+	if !res.OK {
+		return fmt.Errorf("integrity check failed: %s", res.Issues[0])
+	}
+	return nil
+}
+
 // SetSynchronousMode sets the synchronous mode of the database.
 func (db *DB) SetSynchronousMode(mode SynchronousMode) error {
 	if _, err := db.rwDB.Exec(fmt.Sprintf("PRAGMA synchronous=%s", mode)); err != nil {
@@ -1129,13 +1175,15 @@ func (db *DB) Execute(req *command.Request, xTime bool) ([]*command.ExecuteQuery
 // ExecuteWithContext executes queries that modify the database, using the given context.
 // Any timeout set in the request is also applied, so the effective deadline is the
 // earlier of the context's deadline and the request's timeout.
-func (db *DB) ExecuteWithContext(ctx context.Context, req *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
+func (db *DB) ExecuteWithContext(ctx context.Context, req *command.Request, xTime bool) (_ []*command.ExecuteQueryResponse, retErr error) {
 	if req.DbTimeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.DbTimeout))
 		defer cancel()
 	}
 	stats.Add(numExecutions, int64(len(req.Statements)))
+	ctx, span := startSpan(ctx, "db.Execute", req)
+	defer func() { tracing.End(span, retErr) }()
 	conn, err := db.rwDB.Conn(ctx)
 	if err != nil {
 		return nil, err
@@ -1144,6 +1192,12 @@ func (db *DB) ExecuteWithContext(ctx context.Context, req *command.Request, xTim
 	return db.executeWithConn(ctx, req, xTime, conn)
 }
 
+// startSpan starts a span covering the processing of req by SQLite.
+func startSpan(ctx context.Context, name string, req *command.Request) (context.Context, trace.Span) {
+	return tracing.StartChild(ctx, tracer, name,
+		trace.WithAttributes(attribute.Int("rqlite.statements", len(req.Statements))))
+}
+
 type execerQueryer interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
@@ -1194,13 +1248,23 @@ func (db *DB) executeWithConn(ctx context.Context, req *command.Request, xTime b
 	}
 
 	// Execute each statement.
-	for _, stmt := range req.Statements {
-		ss := stmt.Sql
-		if ss == "" {
+	byStmt := make([]*command.ExecuteQueryResponse, len(req.Statements))
+	for i, stmt := range req.Statements {
+		if stmt.Sql == "" && stmt.Name == "" {
 			continue
 		}
 
+		stmt, err := db.resolveStatement(ctx, eqer, stmt, byStmt)
+		if err != nil {
+			db.noteExecuted("", err)
+			if handleError(&command.ExecuteQueryResponse{}, err) {
+				continue
+			}
+			break
+		}
 		result, err := db.executeStmtWithConn(ctx, stmt, xTime, eqer, time.Duration(req.DbTimeout))
+		db.noteExecuted(stmt.Sql, err)
+		byStmt[i] = result
 		if err != nil {
 			if handleError(result, err) {
 				continue
@@ -1212,6 +1276,7 @@ func (db *DB) executeWithConn(ctx context.Context, req *command.Request, xTime b
 
 	if tx != nil {
 		err = tx.Commit()
+		db.noteExecuted("", err)
 	}
 	return allResults, err
 }
@@ -1337,13 +1402,15 @@ func (db *DB) Query(req *command.Request, xTime bool) ([]*command.QueryRows, err
 // QueryWithContext executes queries that return rows, but don't modify the database.
 // Any timeout set in the request is also applied, so the effective deadline is the
 // earlier of the context's deadline and the request's timeout.
-func (db *DB) QueryWithContext(ctx context.Context, req *command.Request, xTime bool) ([]*command.QueryRows, error) {
+func (db *DB) QueryWithContext(ctx context.Context, req *command.Request, xTime bool) (_ []*command.QueryRows, retErr error) {
 	if req.DbTimeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.DbTimeout))
 		defer cancel()
 	}
 	stats.Add(numQueries, int64(len(req.Statements)))
+	ctx, span := startSpan(ctx, "db.Query", req)
+	defer func() { tracing.End(span, retErr) }()
 	conn, err := db.roDB.Conn(ctx)
 	if err != nil {
 		return nil, err
@@ -1373,15 +1440,15 @@ func (db *DB) queryWithConn(ctx context.Context, req *command.Request, xTime boo
 
 	var allRows []*command.QueryRows
 	for _, stmt := range req.Statements {
-		sql := stmt.Sql
-		if sql == "" {
+		if stmt.Sql == "" && stmt.Name == "" {
 			continue
 		}
 
 		var rows *command.QueryRows
-		var err error
-
-		rows, err = db.queryStmtWithConn(ctx, stmt, xTime, queryer)
+		stmt, err := resolveNamed(ctx, queryer, stmt)
+		if err == nil {
+			rows, err = db.queryStmtWithConn(ctx, stmt, xTime, queryer)
+		}
 		if err != nil {
 			// Remap errors if necessary for backwards compatibility reasons.
 			se := NewSQLiteErrorFromError(err)
@@ -1394,7 +1461,7 @@ func (db *DB) queryWithConn(ctx context.Context, req *command.Request, xTime boo
 		}
 		if req.QualifyColumns && rows != nil && rows.Error == "" {
 			if qErr := qualifyRowColumns(conn, stmt.Sql, rows); qErr != nil {
-				db.logger.Printf("qualify columns: %s", qErr.Error())
+				logging.Warnf(db.logger, "qualify columns: %s", qErr.Error())
 			}
 		}
 		allRows = append(allRows, rows)
@@ -1536,13 +1603,15 @@ func (db *DB) Request(req *command.Request, xTime bool) ([]*command.ExecuteQuery
 // RequestWithContext processes a request that can contain both executes and queries,
 // using the given context. Any timeout set in the request is also applied, so the
 // effective deadline is the earlier of the context's deadline and the request's timeout.
-func (db *DB) RequestWithContext(ctx context.Context, req *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
+func (db *DB) RequestWithContext(ctx context.Context, req *command.Request, xTime bool) (_ []*command.ExecuteQueryResponse, retErr error) {
 	if req.DbTimeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.DbTimeout))
 		defer cancel()
 	}
 	stats.Add(numRequests, int64(len(req.Statements)))
+	ctx, span := startSpan(ctx, "db.Request", req)
+	defer func() { tracing.End(span, retErr) }()
 	conn, err := db.rwDB.Conn(ctx)
 	if err != nil {
 		return nil, err
@@ -1573,13 +1642,27 @@ func (db *DB) RequestWithContext(ctx context.Context, req *command.Request, xTim
 	}
 
 	var eqResponse []*command.ExecuteQueryResponse
-	for _, stmt := range req.Statements {
-		ss := stmt.Sql
-		if ss == "" {
+	byStmt := make([]*command.ExecuteQueryResponse, len(req.Statements))
+	for i, stmt := range req.Statements {
+		if stmt.Sql == "" && stmt.Name == "" {
 			continue
 		}
 
-		ro, err := db.StmtReadOnlyWithConn(ss, conn)
+		stmt, err := db.resolveStatement(ctx, eq, stmt, byStmt)
+		if err != nil {
+			db.noteExecuted("", err)
+			eqResponse = append(eqResponse, &command.ExecuteQueryResponse{
+				Result: &command.ExecuteQueryResponse_Error{
+					Error: err.Error(),
+				},
+			})
+			if abortOnError(err) {
+				break
+			}
+			continue
+		}
+
+		ro, err := db.StmtReadOnlyWithConn(stmt.Sql, conn)
 		if err != nil {
 			eqResponse = append(eqResponse, &command.ExecuteQueryResponse{
 				Result: &command.ExecuteQueryResponse_Error{
@@ -1593,15 +1676,19 @@ func (db *DB) RequestWithContext(ctx context.Context, req *command.Request, xTim
 			rows, opErr := db.queryStmtWithConn(ctx, stmt, xTime, eq)
 			if req.QualifyColumns && rows != nil && rows.Error == "" {
 				if qErr := qualifyRowColumns(conn, stmt.Sql, rows); qErr != nil {
-					db.logger.Printf("qualify columns: %s", qErr.Error())
+					logging.Warnf(db.logger, "qualify columns: %s", qErr.Error())
 				}
 			}
-			eqResponse = append(eqResponse, createEQQueryResponse(rows, opErr))
+			db.noteExecuted(stmt.Sql, opErr)
+			byStmt[i] = createEQQueryResponse(rows, opErr)
+			eqResponse = append(eqResponse, byStmt[i])
 			if abortOnError(opErr) {
 				break
 			}
 		} else {
 			result, opErr := db.executeStmtWithConn(ctx, stmt, xTime, eq, time.Duration(req.DbTimeout))
+			db.noteExecuted(stmt.Sql, opErr)
+			byStmt[i] = result
 			eqResponse = append(eqResponse, result)
 			if abortOnError(opErr) {
 				break
@@ -1611,6 +1698,7 @@ func (db *DB) RequestWithContext(ctx context.Context, req *command.Request, xTim
 
 	if tx != nil {
 		err = tx.Commit()
+		db.noteExecuted("", err)
 	}
 	return eqResponse, err
 }
@@ -1729,6 +1817,9 @@ func (db *DB) Dump(w io.Writer, tableNames ...string) error {
 			stmt = `ANALYZE "sqlite_master";`
 		} else if strings.HasPrefix(table, "sqlite_") {
 			continue
+		} else if table == auth.UsersTable {
+			// Holds password hashes, so is never dumped.
+			continue
 		} else {
 			stmt = v.Parameters[2].GetS()
 		}
@@ -2011,6 +2102,189 @@ func copyDatabaseConnection(dst, src *sqlite3.SQLiteConn) error {
 	return bk.Finish()
 }
 
+// resolveStatement returns the statement to execute for stmt, resolving any
+// reference to a named statement, and then any references to the results of
+// earlier statements in the same request.
+func (db *DB) resolveStatement(ctx context.Context, q queryer, stmt *command.Statement,
+	results []*command.ExecuteQueryResponse) (*command.Statement, error) {
+	named := stmt.Name != "" && stmt.Sql == ""
+	stmt, err := resolveNamed(ctx, q, stmt)
+	if err != nil {
+		return nil, err
+	}
+	if named {
+		// A named statement which needed rewriting should have been expanded
+		// before it was sent. If it was not, executing it could leave nodes
+		// with different data, so refuse. Every node refuses alike.
+		a, err := db.namedAnalysis.Analyze(stmt.Sql)
+		if err != nil {
+			return nil, err
+		}
+		if !a.Deterministic() {
+			return nil, fmt.Errorf("%w: %s", ErrNamedNotDeterministic, stmt.Name)
+		}
+	}
+	stmt, err = resolveReferences(stmt, results)
+	if err != nil {
+		return nil, err
+	}
+	return db.injectDefaults(ctx, q, stmt)
+}
+
+// resolveReferences returns the statement with any references to the results of
+// earlier statements in the same request replaced by the values they refer to.
+// results holds the result of each statement executed so far, indexed by the
+// position of the statement in the request. Since every node executes the
+// request identically, every node resolves the references to the same values.
+func resolveReferences(stmt *command.Statement, results []*command.ExecuteQueryResponse) (*command.Statement, error) {
+	if !cmdsql.ContainsReference(stmt.Sql) {
+		return stmt, nil
+	}
+	for _, p := range stmt.Parameters {
+		if strings.HasPrefix(p.GetName(), "$") {
+			return nil, fmt.Errorf("parameter %s may not be used in a statement with references", p.GetName())
+		}
+	}
+	ss, err := cmdsql.ResolveReferences(stmt.Sql, func(n int) *command.ExecuteQueryResponse {
+		if n >= len(results) {
+			return nil
+		}
+		return results[n]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withSQL(stmt, ss), nil
+}
+
+// injectDefaults returns the statement with explicit values given to any
+// non-deterministic column defaults it would otherwise use. The table's
+// defaults are read at the point in the log the statement is applied, so every
+// node injects the same values, even if the table was changed by the same
+// request. Defaults are cached until the schema may have changed.
+func (db *DB) injectDefaults(ctx context.Context, q queryer, stmt *command.Statement) (*command.Statement, error) {
+	if stmt.DefaultTime == 0 && stmt.DefaultSeed == 0 {
+		return stmt, nil
+	}
+	ss, err := cmdsql.InjectDefaults(stmt, func(schema, table string) ([]cmdsql.ColumnDefault, error) {
+		return db.defaults.get(schema, table, func() ([]cmdsql.ColumnDefault, error) {
+			return columnDefaults(ctx, q, schema, table)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ss == stmt.Sql {
+		return stmt, nil
+	}
+	return withSQL(stmt, ss), nil
+}
+
+// columnDefaults returns the declared defaults of the columns of the given
+// table, in column order, or cmdsql.ErrNoSuchTable if the table does not exist.
+func columnDefaults(ctx context.Context, q queryer, schema, table string) ([]cmdsql.ColumnDefault, error) {
+	query := `SELECT name, dflt_value FROM pragma_table_info(?) ORDER BY cid`
+	args := []any{table}
+	if schema != "" {
+		query = `SELECT name, dflt_value FROM pragma_table_info(?, ?) ORDER BY cid`
+		args = append(args, schema)
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var defaults []cmdsql.ColumnDefault
+	found := false
+	for rows.Next() {
+		found = true
+		var name string
+		var expr sql.NullString
+		if err := rows.Scan(&name, &expr); err != nil {
+			return nil, err
+		}
+		if expr.Valid {
+			defaults = append(defaults, cmdsql.ColumnDefault{Column: name, Expr: expr.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cmdsql.ErrNoSuchTable
+	}
+	return defaults, nil
+}
+
+// defaultsCache caches the column defaults of tables. Since it is read within
+// transactions, it must only be filled using the read-write connection, and
+// must be cleared whenever the schema may have changed, or a change to it
+// may have been rolled back. It is safe for concurrent use.
+type defaultsCache struct {
+	mu sync.Mutex
+	m  map[string]defaultsEntry
+}
+
+type defaultsEntry struct {
+	defaults []cmdsql.ColumnDefault
+	err      error
+}
+
+func newDefaultsCache() *defaultsCache {
+	return &defaultsCache{m: make(map[string]defaultsEntry)}
+}
+
+// get returns the column defaults of the given table, calling fn to read them
+// if they are not cached. A table which does not exist is also cached.
+func (c *defaultsCache) get(schema, table string, fn func() ([]cmdsql.ColumnDefault, error)) ([]cmdsql.ColumnDefault, error) {
+	key := strings.ToLower(schema) + "." + strings.ToLower(table)
+	c.mu.Lock()
+	e, ok := c.m[key]
+	c.mu.Unlock()
+	if ok {
+		return e.defaults, e.err
+	}
+	defaults, err := fn()
+	if err != nil && !errors.Is(err, cmdsql.ErrNoSuchTable) {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.m[key] = defaultsEntry{defaults: defaults, err: err}
+	c.mu.Unlock()
+	return defaults, err
+}
+
+// clear empties the cache.
+func (c *defaultsCache) clear() {
+	c.mu.Lock()
+	clear(c.m)
+	c.mu.Unlock()
+}
+
+// noteExecuted clears the cached column defaults if the given statement, just
+// executed on the read-write connection with the given error, may have changed
+// the schema. A failed statement may have rolled back a transaction which
+// changed the schema.
+func (db *DB) noteExecuted(stmt string, err error) {
+	if err != nil || cmdsql.MayChangeSchema(stmt) {
+		db.defaults.clear()
+	}
+}
+
+// withSQL returns a copy of stmt with its SQL replaced by s.
+func withSQL(stmt *command.Statement, s string) *command.Statement {
+	return &command.Statement{
+		Sql:         s,
+		Parameters:  stmt.Parameters,
+		ForceQuery:  stmt.ForceQuery,
+		ForceStall:  stmt.ForceStall,
+		SqlExplain:  stmt.SqlExplain,
+		Name:        stmt.Name,
+		DefaultTime: stmt.DefaultTime,
+		DefaultSeed: stmt.DefaultSeed,
+	}
+}
+
 // parametersToValues maps values in the proto params to SQL driver values.
 func parametersToValues(parameters []*command.Parameter) ([]any, error) {
 	if parameters == nil {
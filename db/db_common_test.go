@@ -1,13 +1,16 @@
 package db
 
 import (
+	"bytes"
 	"errors"
 	"math/rand/v2"
 	"os"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
 	command "github.com/rqlite/rqlite/v10/command/proto"
 )
 
@@ -2488,3 +2491,450 @@ func Test_DB_Backup(t *testing.T) {
 		}
 	}
 }
+
+func Test_DB_ExecuteReferences(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	mustExecute(db, `CREATE TABLE parent (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`)
+	mustExecute(db, `CREATE TABLE child (id INTEGER NOT NULL PRIMARY KEY, parent_id INTEGER, name TEXT)`)
+	mustExecute(db, `INSERT INTO parent(id, name) VALUES(10, "fiona")`)
+
+	request := &command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql: `INSERT INTO parent(name) VALUES("declan")`,
+			},
+			{
+				Sql: `INSERT INTO child(parent_id, name) VALUES(:$0.last_insert_id, "dana")`,
+			},
+			{
+				Sql:        `INSERT INTO child(parent_id, name) VALUES(:$0.last_insert_id, ?) RETURNING id`,
+				Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "dara"}}},
+				ForceQuery: true,
+			},
+			{
+				Sql: `UPDATE child SET name = "dora" WHERE id = :$2.rows[0].id`,
+			},
+		},
+		Transaction: true,
+	}
+	r, err := db.Execute(request, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	if exp, got := `[{"last_insert_id":11,"rows_affected":1},{"last_insert_id":1,"rows_affected":1},{"columns":["id"],"types":["integer"],"values":[[2]]},{"last_insert_id":2,"rows_affected":1}]`, asJSON(r); exp != got {
+		t.Fatalf("unexpected results for execute\nexp: %s\ngot: %s", exp, got)
+	}
+	q, err := db.QueryStringStmt(`SELECT parent_id, name FROM child ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	if exp, got := `[{"columns":["parent_id","name"],"types":["integer","text"],"values":[[11,"dana"],[11,"dora"]]}]`, asJSON(q); exp != got {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", exp, got)
+	}
+
+	// A reference to a statement which failed, or has yet to be executed,
+	// is an error, and rolls back the transaction.
+	request = &command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql: `INSERT INTO child(parent_id, name) VALUES(11, "deirdre")`,
+			},
+			{
+				Sql: `INSERT INTO child(parent_id, name) VALUES(:$2.last_insert_id, "dan")`,
+			},
+		},
+		Transaction: true,
+	}
+	r, err = db.Execute(request, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	if exp, got := `[{"last_insert_id":3,"rows_affected":1},{"error":":$2.last_insert_id: statement 2 has not been executed"}]`, asJSON(r); exp != got {
+		t.Fatalf("unexpected results for execute\nexp: %s\ngot: %s", exp, got)
+	}
+	q, err = db.QueryStringStmt(`SELECT COUNT(*) FROM child`)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	if exp, got := `[{"columns":["COUNT(*)"],"types":["integer"],"values":[[2]]}]`, asJSON(q); exp != got {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", exp, got)
+	}
+
+	// Parameters named with a leading $ would clash with references.
+	request = &command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql: `INSERT INTO child(parent_id, name) VALUES(11, "deirdre")`,
+			},
+			{
+				Sql:        `INSERT INTO child(parent_id, name) VALUES(:$0.last_insert_id, $name)`,
+				Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "dan"}, Name: "$name"}},
+			},
+		},
+	}
+	r, err = db.Execute(request, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	if exp, got := `[{"last_insert_id":3,"rows_affected":1},{"error":"parameter $name may not be used in a statement with references"}]`, asJSON(r); exp != got {
+		t.Fatalf("unexpected results for execute\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
+func Test_DB_RequestReferences(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	mustExecute(db, `CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`)
+	mustExecute(db, `INSERT INTO foo(id, name) VALUES(1, "fiona")`)
+
+	r, err := db.RequestStringStmts([]string{
+		`SELECT id, name FROM foo WHERE name = "fiona"`,
+		`INSERT INTO foo(name) VALUES(:$0.rows[0].name || "-" || :$0.rows[0].id)`,
+		`SELECT name FROM foo WHERE id = :$1.last_insert_id`,
+		`SELECT :$1.rows[0].id`,
+		`SELECT :$9.rows_affected`,
+	})
+	if err != nil {
+		t.Fatalf("failed to request: %s", err.Error())
+	}
+	exp := `[{"columns":["id","name"],"types":["integer","text"],"values":[[1,"fiona"]]},` +
+		`{"last_insert_id":2,"rows_affected":1},` +
+		`{"columns":["name"],"types":["text"],"values":[["fiona-1"]]},` +
+		`{"error":":$1.rows[0].id: statement 1 did not return rows"},` +
+		`{"error":":$9.rows_affected: statement 9 has not been executed"}]`
+	if got := asJSON(r); exp != got {
+		t.Fatalf("unexpected results for request\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
+func Test_DB_NamedStatements(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	if _, err := db.NamedStatement("add"); !errors.Is(err, ErrNoSuchStatement) {
+		t.Fatalf("expected ErrNoSuchStatement before registry exists, got %v", err)
+	}
+	stmts, err := db.NamedStatements()
+	if err != nil {
+		t.Fatalf("failed to list named statements: %s", err.Error())
+	}
+	if len(stmts) != 0 {
+		t.Fatalf("expected no named statements, got %v", stmts)
+	}
+
+	mustExecute(db, `CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT, t TEXT)`)
+	mustExecute(db, CreateStatementsTableSQL)
+	mustExecute(db, `INSERT INTO `+StatementsTable+`(name, sql) VALUES("add", "INSERT INTO foo(name) VALUES(?)")`)
+	mustExecute(db, `INSERT INTO `+StatementsTable+`(name, sql) VALUES("get", "SELECT name FROM foo WHERE id = ?")`)
+	mustExecute(db, `INSERT INTO `+StatementsTable+`(name, sql) VALUES("now", "INSERT INTO foo(t) VALUES(datetime('now'))")`)
+
+	sql, err := db.NamedStatement("add")
+	if err != nil {
+		t.Fatalf("failed to get named statement: %s", err.Error())
+	}
+	if exp, got := "INSERT INTO foo(name) VALUES(?)", sql; exp != got {
+		t.Fatalf("wrong SQL for named statement, exp %s, got %s", exp, got)
+	}
+	stmts, err = db.NamedStatements()
+	if err != nil {
+		t.Fatalf("failed to list named statements: %s", err.Error())
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("expected 3 named statements, got %v", stmts)
+	}
+
+	r, err := db.Execute(&command.Request{
+		Statements: []*command.Statement{
+			{
+				Name: "add",
+				Parameters: []*command.Parameter{
+					{Value: &command.Parameter_S{S: "fiona"}},
+				},
+			},
+			{Name: "missing"},
+			{Name: "now"},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	exp := `[{"last_insert_id":1,"rows_affected":1},` +
+		`{"error":"no such statement: missing"},` +
+		`{"error":"named statement must be rewritten before execution: now"}]`
+	if got := asJSON(r); exp != got {
+		t.Fatalf("unexpected results for execute\nexp: %s\ngot: %s", exp, got)
+	}
+
+	q, err := db.Query(&command.Request{
+		Statements: []*command.Statement{
+			{
+				Name: "get",
+				Parameters: []*command.Parameter{
+					{Value: &command.Parameter_I{I: 1}},
+				},
+			},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	if exp, got := `[{"columns":["name"],"types":["text"],"values":[["fiona"]]}]`, asJSON(q); exp != got {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", exp, got)
+	}
+
+	r, err = db.Request(&command.Request{
+		Statements: []*command.Statement{
+			{
+				Name: "add",
+				Parameters: []*command.Parameter{
+					{Value: &command.Parameter_S{S: "dana"}},
+				},
+			},
+			{
+				Name: "get",
+				Parameters: []*command.Parameter{
+					{Value: &command.Parameter_I{I: 2}},
+				},
+			},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to request: %s", err.Error())
+	}
+	exp = `[{"last_insert_id":2,"rows_affected":1},{"columns":["name"],"types":["text"],"values":[["dana"]]}]`
+	if got := asJSON(r); exp != got {
+		t.Fatalf("unexpected results for request\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
+func Test_DB_ExecuteInjectDefaults(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	defaultTime := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC).UnixNano()
+	r, err := db.Execute(&command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql: `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, ` +
+					`ts TEXT DEFAULT CURRENT_TIMESTAMP, d TEXT DEFAULT (date('now')), ` +
+					`r INTEGER DEFAULT (random()), n TEXT DEFAULT 'none')`,
+			},
+			{
+				Sql:         `INSERT INTO foo(name) VALUES("fiona"), ("declan")`,
+				DefaultTime: defaultTime,
+				DefaultSeed: 1234,
+			},
+			{
+				Sql:         `INSERT INTO foo DEFAULT VALUES`,
+				DefaultTime: defaultTime,
+			},
+		},
+		Transaction: true,
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	if exp, got := `[{},{"last_insert_id":2,"rows_affected":2},{"last_insert_id":3,"rows_affected":1}]`, asJSON(r); exp != got {
+		t.Fatalf("unexpected results for execute\nexp: %s\ngot: %s", exp, got)
+	}
+
+	q, err := db.QueryStringStmt(`SELECT name, ts, d, n FROM foo`)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	exp := `[{"columns":["name","ts","d","n"],"types":["text","text","text","text"],"values":[` +
+		`["fiona","2024-02-29 13:14:15","2024-02-29","none"],` +
+		`["declan","2024-02-29 13:14:15","2024-02-29","none"],` +
+		`[null,"2024-02-29 13:14:15","2024-02-29","none"]]}]`
+	if got := asJSON(q); exp != got {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", exp, got)
+	}
+
+	// Executing the same statement elsewhere must give the same random values.
+	q, err = db.QueryStringStmt(`SELECT r FROM foo WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	first := asJSON(q)
+	mustExecute(db, `DELETE FROM foo`)
+	_, err = db.Execute(&command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql:         `INSERT INTO foo(name) VALUES("fiona"), ("declan")`,
+				DefaultTime: defaultTime,
+				DefaultSeed: 1234,
+			},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	q, err = db.QueryStringStmt(`SELECT r FROM foo WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err.Error())
+	}
+	if got := asJSON(q); got != first {
+		t.Fatalf("random default not deterministic, exp %s, got %s", first, got)
+	}
+}
+
+func Test_DB_ExecuteInjectDefaultsSchemaChange(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	defaultTime := time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC).UnixNano()
+	insert := func(name string) string {
+		t.Helper()
+		r, err := db.Execute(&command.Request{
+			Statements: []*command.Statement{
+				{
+					Sql:         `INSERT INTO foo(name) VALUES(?)`,
+					Parameters:  []*command.Parameter{{Value: &command.Parameter_S{S: name}}},
+					DefaultTime: defaultTime,
+				},
+			},
+		}, false)
+		if err != nil {
+			t.Fatalf("failed to execute: %s", err.Error())
+		}
+		if r[0].GetError() != "" {
+			return r[0].GetError()
+		}
+		q, err := db.QueryStringStmt(`SELECT ts FROM foo WHERE name = '` + name + `'`)
+		if err != nil {
+			t.Fatalf("failed to query: %s", err.Error())
+		}
+		return asJSON(q[0].Values)
+	}
+
+	if exp, got := "no such table: foo", insert("fiona"); exp != got {
+		t.Fatalf("wrong result for missing table, exp %s, got %s", exp, got)
+	}
+	mustExecute(db, `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, ts TEXT DEFAULT 'fixed')`)
+	if exp, got := `[["fixed"]]`, insert("fiona"); exp != got {
+		t.Fatalf("wrong default, exp %s, got %s", exp, got)
+	}
+
+	// Defaults must be reread once the table is changed.
+	_, err := db.Execute(&command.Request{
+		Statements: []*command.Statement{
+			{Sql: `DROP TABLE foo`},
+			{Sql: `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, ts TEXT DEFAULT CURRENT_TIMESTAMP)`},
+		},
+		Transaction: true,
+	}, false)
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err.Error())
+	}
+	if exp, got := `[["2024-02-29 13:14:15"]]`, insert("declan"); exp != got {
+		t.Fatalf("wrong default after schema change, exp %s, got %s", exp, got)
+	}
+
+	// And once a change to the table is rolled back.
+	mustExecute(db, `BEGIN`)
+	mustExecute(db, `DROP TABLE foo`)
+	mustExecute(db, `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, ts TEXT DEFAULT 'fixed')`)
+	insert("sinead")
+	mustExecute(db, `ROLLBACK`)
+	if exp, got := `[["2024-02-29 13:14:15"]]`, insert("aoife"); exp != got {
+		t.Fatalf("wrong default after rollback, exp %s, got %s", exp, got)
+	}
+}
+
+func Test_DB_Users(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	users, err := db.Users()
+	if err != nil {
+		t.Fatalf("failed to list users: %s", err.Error())
+	}
+	if len(users) != 0 {
+		t.Fatalf("expected no users, got %v", users)
+	}
+
+	mustExecute(db, CreateUsersTableSQL)
+	mustExecute(db, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("fiona", '{"password":"secret","perms":["query"]}')`)
+	mustExecute(db, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("dana", '{"perms":["all"]}')`)
+
+	users, err = db.Users()
+	if err != nil {
+		t.Fatalf("failed to list users: %s", err.Error())
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %v", users)
+	}
+	if users[0].Username != "dana" || users[1].Username != "fiona" {
+		t.Fatalf("users not ordered by username: %v", users)
+	}
+	if users[1].Password != "secret" || len(users[1].Perms) != 1 || users[1].Perms[0] != "query" {
+		t.Fatalf("wrong credential for fiona: %v", users[1])
+	}
+
+	mustExecute(db, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("zed", 'not json')`)
+	if _, err := db.Users(); err == nil {
+		t.Fatalf("expected error listing users with invalid credential")
+	}
+}
+
+func Test_DB_UsersNotExported(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer os.Remove(path)
+	defer db.Close()
+
+	mustExecute(db, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(db, CreateUsersTableSQL)
+	mustExecute(db, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("fiona", '{"password":"secret-hash"}')`)
+
+	var buf bytes.Buffer
+	if err := db.Dump(&buf); err != nil {
+		t.Fatalf("failed to dump database: %s", err.Error())
+	}
+	if strings.Contains(buf.String(), auth.UsersTable) || strings.Contains(buf.String(), "secret-hash") {
+		t.Fatalf("dump contains table of users: %s", buf.String())
+	}
+	buf.Reset()
+	if err := db.Dump(&buf, auth.UsersTable); err != nil {
+		t.Fatalf("failed to dump table of users: %s", err.Error())
+	}
+	if strings.Contains(buf.String(), "secret-hash") {
+		t.Fatalf("dump of table of users contains users: %s", buf.String())
+	}
+
+	dstPath := mustTempPath()
+	defer os.Remove(dstPath)
+	if err := db.Backup(dstPath, false); err != nil {
+		t.Fatalf("failed to back up database: %s", err.Error())
+	}
+	if err := RemoveUsersTable(dstPath); err != nil {
+		t.Fatalf("failed to remove table of users: %s", err.Error())
+	}
+	b, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatalf("failed to read backup: %s", err.Error())
+	}
+	if bytes.Contains(b, []byte("secret-hash")) {
+		t.Fatalf("backup contains password hash")
+	}
+	dstDB, err := Open(dstPath, false, false)
+	if err != nil {
+		t.Fatalf("failed to open backup: %s", err.Error())
+	}
+	defer dstDB.Close()
+	if exists, err := dstDB.UsersTableExists(); err != nil || exists {
+		t.Fatalf("table of users exists in backup, err: %v", err)
+	}
+	rows := mustQuery(dstDB, "SELECT COUNT(*) FROM foo")
+	if exp, got := `[{"columns":["COUNT(*)"],"types":["integer"],"values":[[0]]}]`, asJSON(rows); exp != got {
+		t.Fatalf("unexpected results for backup, exp %s, got %s", exp, got)
+	}
+}
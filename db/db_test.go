@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/command/encoding"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
 	"github.com/rqlite/rqlite/v10/internal/random"
 )
@@ -225,6 +227,31 @@ func Test_WALNotCheckpointedOnClose(t *testing.T) {
 	}
 }
 
+func Test_VerifyFileIntegrity(t *testing.T) {
+	db, path := mustCreateOnDiskDatabase()
+	if _, err := db.ExecuteStringStmt("CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %s", err.Error())
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %s", err.Error())
+	}
+	if err := VerifyFileIntegrity(path); err != nil {
+		t.Fatalf("valid database failed integrity verification: %s", err.Error())
+	}
+
+	notDB := filepath.Join(t.TempDir(), "not-a-db")
+	if err := os.WriteFile(notDB, []byte("this is not a SQLite database"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err.Error())
+	}
+	if err := VerifyFileIntegrity(notDB); err == nil {
+		t.Fatalf("expected error verifying non-SQLite file")
+	}
+
+	if err := VerifyFileIntegrity(filepath.Join(t.TempDir(), "nonexistent")); err == nil {
+		t.Fatalf("expected error verifying nonexistent file")
+	}
+}
+
 func Test_RemoveFiles(t *testing.T) {
 	d := t.TempDir()
 	mustCreateClosedFile(fmt.Sprintf("%s/foo", d))
@@ -1517,6 +1544,168 @@ func Test_RequestShouldTimeout(t *testing.T) {
 	}
 }
 
+func Test_DB_Catalog(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer db.Close()
+	defer os.Remove(path)
+
+	fns, err := db.Functions()
+	if err != nil {
+		t.Fatalf("failed to get functions: %s", err.Error())
+	}
+	found := make(map[string]cmdsql.FunctionInfo)
+	for _, fi := range fns {
+		found[fi.Name] = fi
+	}
+	if fi, ok := found["abs"]; !ok || !fi.Builtin || !fi.Deterministic {
+		t.Fatalf("abs not reported as deterministic builtin: %+v", fi)
+	}
+	if fi, ok := found["random"]; !ok || fi.Deterministic {
+		t.Fatalf("random not reported as non-deterministic: %+v", fi)
+	}
+	if fi, ok := found["count"]; !ok || !fi.Aggregate {
+		t.Fatalf("count not reported as aggregate: %+v", fi)
+	}
+
+	mustExecute(db, `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT DEFAULT 'unknown', ts TEXT DEFAULT CURRENT_TIMESTAMP, n INTEGER)`)
+	mustExecute(db, `CREATE TABLE bar (id INTEGER PRIMARY KEY, ts TEXT)`)
+	mustExecute(db, `CREATE TRIGGER foo_insert AFTER INSERT ON foo BEGIN INSERT INTO bar(ts) VALUES(CURRENT_TIMESTAMP); END`)
+
+	triggers, err := db.Triggers("", "FOO")
+	if err != nil {
+		t.Fatalf("failed to get triggers: %s", err.Error())
+	}
+	if len(triggers) != 1 || !strings.HasPrefix(triggers[0], "CREATE TRIGGER foo_insert") {
+		t.Fatalf("wrong triggers: %v", triggers)
+	}
+	triggers, err = db.Triggers("main", "bar")
+	if err != nil {
+		t.Fatalf("failed to get triggers: %s", err.Error())
+	}
+	if len(triggers) != 0 {
+		t.Fatalf("expected no triggers, got %v", triggers)
+	}
+
+	defaults, err := db.ColumnDefaults("", "foo")
+	if err != nil {
+		t.Fatalf("failed to get column defaults: %s", err.Error())
+	}
+	if exp, got := `[{name 'unknown'} {ts CURRENT_TIMESTAMP}]`, fmt.Sprintf("%v", defaults); exp != got {
+		t.Fatalf("wrong column defaults, exp %s, got %s", exp, got)
+	}
+}
+
+func Test_DB_Checksum(t *testing.T) {
+	db1, path1 := mustCreateOnDiskDatabaseWAL()
+	defer db1.Close()
+	defer os.Remove(path1)
+	db2, path2 := mustCreateOnDiskDatabaseWAL()
+	defer db2.Close()
+	defer os.Remove(path2)
+
+	for _, db := range []*DB{db1, db2} {
+		mustExecute(db, `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, v REAL, b BLOB)`)
+		mustExecute(db, `CREATE TABLE bar (k TEXT PRIMARY KEY, v INTEGER) WITHOUT ROWID`)
+		mustExecute(db, `CREATE TABLE empty (id INTEGER PRIMARY KEY)`)
+	}
+
+	// Write the same content in a different order, and with different
+	// history, to each database.
+	mustExecute(db1, `INSERT INTO foo VALUES(1, 'fiona', 1.5, x'00ff'), (150, 'declan', NULL, NULL), (2, '', 0.1, x'')`)
+	mustExecute(db1, `INSERT INTO bar VALUES('a', 1), ('b', 2)`)
+	mustExecute(db2, `INSERT INTO foo VALUES(150, 'declan', NULL, NULL), (2, '', 0.1, x''), (3, 'x', 1, 1)`)
+	mustExecute(db2, `DELETE FROM foo WHERE id = 3`)
+	mustExecute(db2, `INSERT INTO foo VALUES(1, 'fiona', 1.5, x'00ff')`)
+	mustExecute(db2, `INSERT INTO bar VALUES('b', 2), ('a', 1)`)
+	mustExecute(db2, `ANALYZE`)
+
+	sums1, err := db1.Checksum(0)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	sums2, err := db2.Checksum(0)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	if exp, got := asJSON(sums1), asJSON(sums2); exp != got {
+		t.Fatalf("checksums of identical content differ:\n%s\n%s", exp, got)
+	}
+	var tables []string
+	for _, ts := range sums1 {
+		tables = append(tables, fmt.Sprintf("%s:%d", ts.Table, ts.Rows))
+	}
+	if exp, got := "sqlite_schema:3,bar:2,empty:0,foo:3", strings.Join(tables, ","); exp != got {
+		t.Fatalf("wrong tables checksummed, exp %s, got %s", exp, got)
+	}
+
+	// Checksum in chunks, and check that only the chunk which differs has
+	// a different checksum.
+	mustExecute(db2, `UPDATE foo SET v = 0.1000000001 WHERE id = 2`)
+	sums1, err = db1.Checksum(100)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	sums2, err = db2.Checksum(100)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	if len(sums1) != 5 || len(sums2) != 5 {
+		t.Fatalf("wrong number of checksums, got %d and %d", len(sums1), len(sums2))
+	}
+	for i := range sums1 {
+		a, b := sums1[i], sums2[i]
+		if a.Table != b.Table || a.Start != b.Start || a.End != b.End || a.Rows != b.Rows {
+			t.Fatalf("checksums do not match: %v, %v", a, b)
+		}
+		differ := a.Table == "foo" && a.Start == 0
+		if (a.Sum != b.Sum) != differ {
+			t.Fatalf("unexpected checksum comparison for %s %d-%d", a.Table, a.Start, a.End)
+		}
+	}
+	if ts := sums1[4]; ts.Table != "foo" || ts.Start != 100 || ts.End != 199 || ts.Rows != 1 {
+		t.Fatalf("wrong chunk: %v", ts)
+	}
+}
+
+func Test_DB_ChecksumReader(t *testing.T) {
+	db, path := mustCreateOnDiskDatabaseWAL()
+	defer db.Close()
+	defer os.Remove(path)
+	mustExecute(db, `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT)`)
+	mustExecute(db, `INSERT INTO foo VALUES(1, 'fiona')`)
+
+	exp, err := db.Checksum(0)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	cr, err := db.NewChecksumReader(0)
+	if err != nil {
+		t.Fatalf("failed to create checksum reader: %s", err.Error())
+	}
+
+	// Writes after the reader is created must not be seen by it.
+	mustExecute(db, `INSERT INTO foo VALUES(2, 'declan')`)
+	mustExecute(db, `CREATE TABLE bar (id INTEGER PRIMARY KEY)`)
+	got, err := cr.Checksums()
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	if asJSON(exp) != asJSON(got) {
+		t.Fatalf("checksums include later writes:\n%s\n%s", asJSON(exp), asJSON(got))
+	}
+	if _, err := cr.Checksums(); err == nil {
+		t.Fatalf("expected error using closed checksum reader")
+	}
+
+	now, err := db.Checksum(0)
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err.Error())
+	}
+	if asJSON(exp) == asJSON(now) {
+		t.Fatalf("checksums do not include later writes")
+	}
+}
+
 func mustCreateOnDiskDatabase() (*DB, string) {
 	var err error
 	f := mustTempFile()
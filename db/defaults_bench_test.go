@@ -0,0 +1,53 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	command "github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// BenchmarkExecuteInsertDefaults measures the rate at which INSERT statements
+// are applied, when not stamped for non-deterministic column defaults, and when
+// stamped, for a table which has no such defaults and for one which does.
+//
+// Usage:
+//
+//	go test -run=^$ -bench=BenchmarkExecuteInsertDefaults -benchmem ./db
+func BenchmarkExecuteInsertDefaults(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		create string
+		stamp  bool
+	}{
+		{"unstamped", `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, n INTEGER DEFAULT 0)`, false},
+		{"deterministic", `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, n INTEGER DEFAULT 0)`, true},
+		{"time", `CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, ts TEXT DEFAULT CURRENT_TIMESTAMP)`, true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			db, path := mustCreateOnDiskDatabaseWAL()
+			defer os.Remove(path)
+			defer db.Close()
+			mustExecute(db, bm.create)
+
+			stmt := &command.Statement{
+				Sql: `INSERT INTO foo(name) VALUES(?)`,
+				Parameters: []*command.Parameter{
+					{Value: &command.Parameter_S{S: "fiona"}},
+				},
+			}
+			if bm.stamp {
+				stmt.DefaultTime = 1700000000000000000
+				stmt.DefaultSeed = 42
+			}
+			req := &command.Request{Statements: []*command.Statement{stmt}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := db.Execute(req, false); err != nil {
+					b.Fatalf("failed to execute: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	command "github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
+)
+
+const (
+	// StatementsTable is the table which holds the registry of named
+	// statements. Since the registry is stored in the database, it is
+	// replicated, snapshotted and backed up like any other table.
+	StatementsTable = cmdsql.StatementsTable
+
+	// CreateStatementsTableSQL creates the registry of named statements,
+	// if it does not already exist.
+	CreateStatementsTableSQL = cmdsql.CreateStatementsTableSQL
+)
+
+var (
+	// ErrNoSuchStatement is returned when a named statement is not registered.
+	ErrNoSuchStatement = errors.New("no such statement")
+
+	// ErrNamedNotDeterministic is returned when a named statement which must
+	// be rewritten before execution is executed by name.
+	ErrNamedNotDeterministic = errors.New("named statement must be rewritten before execution")
+)
+
+// NamedStatement returns the SQL registered under the given name.
+func (db *DB) NamedStatement(name string) (string, error) {
+	return namedStatementWithConn(context.Background(), db.roDB, name)
+}
+
+// NamedStatements returns all registered statements, keyed by name.
+func (db *DB) NamedStatements() (map[string]string, error) {
+	rows, err := db.roDB.Query(`SELECT name, sql FROM ` + StatementsTable)
+	if err != nil {
+		if isNoSuchTable(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	stmts := make(map[string]string)
+	for rows.Next() {
+		var name, s string
+		if err := rows.Scan(&name, &s); err != nil {
+			return nil, err
+		}
+		stmts[name] = s
+	}
+	return stmts, rows.Err()
+}
+
+// namedStatementWithConn returns the SQL registered under the given name,
+// reading the registry through q so that changes made earlier in the same
+// transaction are seen.
+func namedStatementWithConn(ctx context.Context, q queryer, name string) (string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT sql FROM `+StatementsTable+` WHERE name = ?`, name)
+	if err != nil {
+		if isNoSuchTable(err) {
+			return "", fmt.Errorf("%w: %s", ErrNoSuchStatement, name)
+		}
+		return "", err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s", ErrNoSuchStatement, name)
+	}
+	var s string
+	if err := rows.Scan(&s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// resolveNamed returns the statement with its SQL set to that of the named
+// statement it refers to, if any. Every node resolves the name against its
+// copy of the registry at the same point in the log, so every node executes
+// the same SQL.
+func resolveNamed(ctx context.Context, q queryer, stmt *command.Statement) (*command.Statement, error) {
+	if stmt.Name == "" || stmt.Sql != "" {
+		return stmt, nil
+	}
+	s, err := namedStatementWithConn(ctx, q, stmt.Name)
+	if err != nil {
+		return nil, err
+	}
+	return withSQL(stmt, s), nil
+}
+
+// isNoSuchTable returns whether err indicates that no statements have ever
+// been registered.
+func isNoSuchTable(err error) bool {
+	return strings.Contains(err.Error(), "no such table: "+StatementsTable)
+}
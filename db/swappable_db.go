@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
 )
 
 // SwappableDB is a wrapper around DB that allows the underlying database to be swapped out
@@ -19,6 +23,14 @@ type SwappableDB struct {
 	drv           *Driver
 	checkpointMgr *CheckpointManager
 	dbMu          sync.RWMutex
+
+	// readers are the open checksum readers, which are canceled if the
+	// database is swapped.
+	readersMu sync.Mutex
+	readers   map[*ChecksumReader]chan struct{}
+
+	// usersVersion changes whenever the table of users may have changed.
+	usersVersion atomic.Uint64
 }
 
 // OpenSwappable returns a new SwappableDB instance, which opens the database at the given path,
@@ -38,11 +50,14 @@ func OpenSwappable(dbPath string, drv *Driver, fkEnabled, wal bool, maxROConns i
 	if err != nil {
 		return nil, fmt.Errorf("failed to create checkpoint manager: %s", err)
 	}
-	return &SwappableDB{
+	sdb := &SwappableDB{
 		db:            db,
 		drv:           drv,
 		checkpointMgr: mgr,
-	}, nil
+		readers:       make(map[*ChecksumReader]chan struct{}),
+	}
+	sdb.usersVersion.Store(1)
+	return sdb, nil
 }
 
 // Swap swaps the underlying database with that at the given path. The Swap operation
@@ -55,6 +70,7 @@ func (s *SwappableDB) Swap(path string, fkConstraints, walEnabled bool) error {
 
 	s.dbMu.Lock()
 	defer s.dbMu.Unlock()
+	s.cancelReaders()
 	if err := s.db.Close(); err != nil {
 		return fmt.Errorf("failed to close: %s", err)
 	}
@@ -78,9 +94,44 @@ func (s *SwappableDB) Swap(path string, fkConstraints, walEnabled bool) error {
 		return fmt.Errorf("failed to recreate checkpoint manager: %s", err)
 	}
 	s.checkpointMgr = mgr
+	s.usersVersion.Add(1)
 	return nil
 }
 
+// Load swaps the underlying database with that at the given path, like Swap,
+// but first replaces the table of users in the file at path with that of the
+// current database. Loading data therefore never changes the users.
+func (s *SwappableDB) Load(path string, fkConstraints, walEnabled bool) error {
+	if !IsValidSQLiteFile(path) {
+		return fmt.Errorf("invalid SQLite data")
+	}
+	s.dbMu.RLock()
+	err := s.db.copyUsersTo(path)
+	s.dbMu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to keep users: %s", err)
+	}
+	return s.Swap(path, fkConstraints, walEnabled)
+}
+
+// UsersVersion returns a value which changes whenever the table of users may
+// have changed. It changes when the database is swapped, and when a request
+// containing a statement which refers to the table is executed.
+func (s *SwappableDB) UsersVersion() uint64 {
+	return s.usersVersion.Load()
+}
+
+// noteUsersChange changes the users version if req may have changed the table
+// of users.
+func (s *SwappableDB) noteUsersChange(req *command.Request) {
+	for _, stmt := range req.GetStatements() {
+		if containsFold(stmt.Sql, auth.UsersTable) {
+			s.usersVersion.Add(1)
+			return
+		}
+	}
+}
+
 // Close closes the underlying database.
 func (s *SwappableDB) Close() error {
 	s.dbMu.RLock()
@@ -99,6 +150,7 @@ func (s *SwappableDB) Stats() (map[string]any, error) {
 func (s *SwappableDB) Request(req *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
 	s.dbMu.RLock()
 	defer s.dbMu.RUnlock()
+	defer s.noteUsersChange(req)
 	return s.db.Request(req, xTime)
 }
 
@@ -106,6 +158,7 @@ func (s *SwappableDB) Request(req *command.Request, xTime bool) ([]*command.Exec
 func (s *SwappableDB) RequestWithContext(ctx context.Context, req *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
 	s.dbMu.RLock()
 	defer s.dbMu.RUnlock()
+	defer s.noteUsersChange(req)
 	return s.db.RequestWithContext(ctx, req, xTime)
 }
 
@@ -113,6 +166,7 @@ func (s *SwappableDB) RequestWithContext(ctx context.Context, req *command.Reque
 func (s *SwappableDB) Execute(ex *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
 	s.dbMu.RLock()
 	defer s.dbMu.RUnlock()
+	defer s.noteUsersChange(ex)
 	return s.db.Execute(ex, xTime)
 }
 
@@ -120,6 +174,7 @@ func (s *SwappableDB) Execute(ex *command.Request, xTime bool) ([]*command.Execu
 func (s *SwappableDB) ExecuteWithContext(ctx context.Context, ex *command.Request, xTime bool) ([]*command.ExecuteQueryResponse, error) {
 	s.dbMu.RLock()
 	defer s.dbMu.RUnlock()
+	defer s.noteUsersChange(ex)
 	return s.db.ExecuteWithContext(ctx, ex, xTime)
 }
 
@@ -172,6 +227,91 @@ func (s *SwappableDB) StmtReadOnly(sql string) (bool, error) {
 	return s.db.StmtReadOnly(sql)
 }
 
+// NamedStatement calls NamedStatement on the underlying database.
+func (s *SwappableDB) NamedStatement(name string) (string, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.NamedStatement(name)
+}
+
+// NamedStatements calls NamedStatements on the underlying database.
+func (s *SwappableDB) NamedStatements() (map[string]string, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.NamedStatements()
+}
+
+// Functions calls Functions on the underlying database.
+func (s *SwappableDB) Functions() ([]cmdsql.FunctionInfo, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.Functions()
+}
+
+// Triggers calls Triggers on the underlying database.
+func (s *SwappableDB) Triggers(schema, table string) ([]string, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.Triggers(schema, table)
+}
+
+// ColumnDefaults calls ColumnDefaults on the underlying database.
+func (s *SwappableDB) ColumnDefaults(schema, table string) ([]cmdsql.ColumnDefault, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.ColumnDefaults(schema, table)
+}
+
+// Checksum calls Checksum on the underlying database.
+func (s *SwappableDB) Checksum(chunkSize int64) ([]*command.TableChecksum, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.Checksum(chunkSize)
+}
+
+// NewChecksumReader calls NewChecksumReader on the underlying database. If
+// the database is swapped before the returned reader is closed, the reader
+// is canceled, and its checksums fail.
+func (s *SwappableDB) NewChecksumReader(chunkSize int64) (*ChecksumReader, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	cr, err := s.db.NewChecksumReader(chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	closed := make(chan struct{})
+	s.readersMu.Lock()
+	s.readers[cr] = closed
+	s.readersMu.Unlock()
+	cr.release = func() {
+		s.readersMu.Lock()
+		delete(s.readers, cr)
+		s.readersMu.Unlock()
+		close(closed)
+	}
+	return cr, nil
+}
+
+// cancelReaders cancels the open checksum readers, and waits for them to be
+// closed. It must be called with dbMu held for writing.
+func (s *SwappableDB) cancelReaders() {
+	s.readersMu.Lock()
+	readers := make(map[*ChecksumReader]chan struct{}, len(s.readers))
+	maps.Copy(readers, s.readers)
+	s.readersMu.Unlock()
+	for cr, closed := range readers {
+		cr.cancel()
+		<-closed
+	}
+}
+
+// Users calls Users on the underlying database.
+func (s *SwappableDB) Users() ([]auth.Credential, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.Users()
+}
+
 // Optimize calls Optimize on the underlying database.
 func (s *SwappableDB) Optimize() error {
 	s.dbMu.RLock()
@@ -193,6 +333,13 @@ func (s *SwappableDB) Path() string {
 	return s.db.Path()
 }
 
+// UsersTableExists calls UsersTableExists on the underlying database.
+func (s *SwappableDB) UsersTableExists() (bool, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.UsersTableExists()
+}
+
 // Dump calls Dump on the underlying database.
 func (s *SwappableDB) Dump(w io.Writer, tableNames ...string) error {
 	s.dbMu.RLock()
@@ -3,7 +3,10 @@ package db
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
+	command "github.com/rqlite/rqlite/v10/command/proto"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
 )
 
@@ -94,6 +97,56 @@ func Test_SwapSuccess(t *testing.T) {
 	}
 }
 
+func Test_SwapCancelsChecksumReader(t *testing.T) {
+	srcPath := mustTempPath()
+	defer os.Remove(srcPath)
+	srcDB, err := Open(srcPath, false, false)
+	if err != nil {
+		t.Fatalf("failed to open source database: %s", err)
+	}
+	mustExecute(srcDB, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	if err := srcDB.Close(); err != nil {
+		t.Fatalf("failed to close source database pre-swap: %s", err)
+	}
+
+	swappablePath := mustTempPath()
+	defer os.Remove(swappablePath)
+	swappableDB, err := OpenSwappable(swappablePath, nil, false, false, 0)
+	if err != nil {
+		t.Fatalf("failed to open swappable database: %s", err)
+	}
+	defer swappableDB.Close()
+
+	cr, err := swappableDB.NewChecksumReader(0)
+	if err != nil {
+		t.Fatalf("failed to create checksum reader: %s", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- swappableDB.Swap(srcPath, false, false)
+	}()
+
+	// The swap cancels the open reader, rather than waiting for it.
+	deadline := time.Now().Add(5 * time.Second)
+	for cr.ctx.Err() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("checksum reader not canceled by swap")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := cr.Checksums(); err == nil {
+		t.Fatalf("expected error from canceled checksum reader")
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("failed to swap database: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("swap blocked by checksum reader")
+	}
+}
+
 func Test_SwapSuccess_Driver(t *testing.T) {
 	// Create a new database and confirm foreign key support is enabled
 	srcPath := mustTempPath()
@@ -166,3 +219,86 @@ func Test_SwapInvalidSQLiteFile(t *testing.T) {
 		t.Fatalf("expected an error when swapping with an invalid SQLite file, got nil")
 	}
 }
+
+// Test_LoadKeepsUsers tests that loading a database does not change the users.
+func Test_LoadKeepsUsers(t *testing.T) {
+	srcPath := mustTempPath()
+	defer os.Remove(srcPath)
+	srcDB, err := Open(srcPath, false, false)
+	if err != nil {
+		t.Fatalf("failed to open source database: %s", err)
+	}
+	defer srcDB.Close()
+	mustExecute(srcDB, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(srcDB, CreateUsersTableSQL)
+	mustExecute(srcDB, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("mallory", '{"perms":["all"]}')`)
+	if err := srcDB.Close(); err != nil {
+		t.Fatalf("failed to close source database: %s", err)
+	}
+
+	swappablePath := mustTempPath()
+	defer os.Remove(swappablePath)
+	swappableDB, err := OpenSwappable(swappablePath, nil, false, false, 0)
+	if err != nil {
+		t.Fatalf("failed to open swappable database: %s", err)
+	}
+	defer swappableDB.Close()
+	if _, err := executeSwappable(swappableDB, CreateUsersTableSQL); err != nil {
+		t.Fatalf("failed to create table of users: %s", err)
+	}
+	if _, err := executeSwappable(swappableDB, `INSERT INTO `+auth.UsersTable+`(username, credential) VALUES("fiona", '{"perms":["query"]}')`); err != nil {
+		t.Fatalf("failed to insert user: %s", err)
+	}
+
+	v := swappableDB.UsersVersion()
+	if err := swappableDB.Load(srcPath, false, false); err != nil {
+		t.Fatalf("failed to load database: %s", err)
+	}
+	if swappableDB.UsersVersion() == v {
+		t.Fatalf("users version unchanged by load")
+	}
+	rows, err := swappableDB.QueryStringStmt("SELECT COUNT(*) FROM foo")
+	if err != nil {
+		t.Fatalf("failed to query loaded database: %s", err)
+	}
+	if exp, got := `[{"columns":["COUNT(*)"],"types":["integer"],"values":[[0]]}]`, asJSON(rows); exp != got {
+		t.Fatalf("unexpected results after load, expected %s, got %s", exp, got)
+	}
+	users, err := swappableDB.Users()
+	if err != nil {
+		t.Fatalf("failed to read users: %s", err)
+	}
+	if len(users) != 1 || users[0].Username != "fiona" {
+		t.Fatalf("users changed by load: %v", users)
+	}
+}
+
+// Test_UsersVersion tests that the users version changes only when the table
+// of users may have changed.
+func Test_UsersVersion(t *testing.T) {
+	path := mustTempPath()
+	defer os.Remove(path)
+	swappableDB, err := OpenSwappable(path, nil, false, false, 0)
+	if err != nil {
+		t.Fatalf("failed to open swappable database: %s", err)
+	}
+	defer swappableDB.Close()
+
+	v := swappableDB.UsersVersion()
+	if _, err := executeSwappable(swappableDB, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %s", err)
+	}
+	if swappableDB.UsersVersion() != v {
+		t.Fatalf("users version changed by unrelated statement")
+	}
+	if _, err := executeSwappable(swappableDB, CreateUsersTableSQL); err != nil {
+		t.Fatalf("failed to create table of users: %s", err)
+	}
+	if swappableDB.UsersVersion() == v {
+		t.Fatalf("users version unchanged by change to table of users")
+	}
+}
+
+func executeSwappable(db *SwappableDB, stmt string) ([]*command.ExecuteQueryResponse, error) {
+	return db.Execute(&command.Request{Statements: []*command.Statement{{Sql: stmt}}}, false)
+}
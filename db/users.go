@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
+)
+
+// CreateUsersTableSQL creates the table of users, if it does not already
+// exist. The credential of each user, less its username, is stored as JSON.
+const CreateUsersTableSQL = cmdsql.CreateUsersTableSQL
+
+// Users returns the users in the table of users, ordered by username.
+func (db *DB) Users() ([]auth.Credential, error) {
+	rows, err := db.roDB.Query(`SELECT username, credential FROM ` + auth.UsersTable + ` ORDER BY username`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table: "+auth.UsersTable) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []auth.Credential
+	for rows.Next() {
+		var username, cred string
+		if err := rows.Scan(&username, &cred); err != nil {
+			return nil, err
+		}
+		var c auth.Credential
+		if err := json.Unmarshal([]byte(cred), &c); err != nil {
+			return nil, fmt.Errorf("invalid credential of user %q: %s", username, err)
+		}
+		c.Username = username
+		users = append(users, c)
+	}
+	return users, rows.Err()
+}
+
+// UsersTableExists returns whether the table of users exists.
+func (db *DB) UsersTableExists() (bool, error) {
+	var n int
+	err := db.roDB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`,
+		auth.UsersTable).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// copyUsersTo replaces the table of users in the SQLite file at path with a
+// copy of the table of users in db. If db has no table of users, the file is
+// left with none.
+func (db *DB) copyUsersTo(path string) error {
+	exists, err := db.UsersTableExists()
+	if err != nil {
+		return err
+	}
+	type user struct{ username, credential string }
+	var users []user
+	if exists {
+		rows, err := db.roDB.Query(`SELECT username, credential FROM ` + auth.UsersTable)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var u user
+			if err := rows.Scan(&u.username, &u.credential); err != nil {
+				rows.Close()
+				return err
+			}
+			users = append(users, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	dstDB, err := OpenWithDriver(db.drv, path, false, false)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+	tx, err := dstDB.rwDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS ` + auth.UsersTable); err != nil {
+		return err
+	}
+	if exists {
+		if _, err := tx.Exec(CreateUsersTableSQL); err != nil {
+			return err
+		}
+		for _, u := range users {
+			if _, err := tx.Exec(`INSERT INTO `+auth.UsersTable+`(username, credential) VALUES(?, ?)`,
+				u.username, u.credential); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return dstDB.Close()
+}
+
+// RemoveUsersTable removes the table of users, if any, from the SQLite file
+// at path. The pages the table occupied are overwritten, so that no password
+// hash remains in the file.
+func RemoveUsersTable(path string) error {
+	dstDB, err := Open(path, false, false)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+	conn, err := dstDB.rwDB.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), `PRAGMA secure_delete=ON`); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(context.Background(), `DROP TABLE IF EXISTS `+auth.UsersTable); err != nil {
+		return err
+	}
+	conn.Close()
+	return dstDB.Close()
+}
+
+// containsFold returns whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
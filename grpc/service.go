@@ -0,0 +1,658 @@
+// Package grpc provides a gRPC API to the database. It exposes Execute, Query,
+// Request, Backup and Load, using the command Protobuf messages directly.
+//
+// Requests have the same authentication, consistency and forwarding semantics
+// as the HTTP API. Credentials are passed using Basic auth in the
+// "authorization" metadata, and requests are forwarded to the Leader unless
+// the "rqlite-redirect" metadata is set, in which case a request which must
+// be served by the Leader fails with FailedPrecondition, and the Leader's
+// HTTP API address is returned in the "rqlite-leader" trailer.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/db"
+	pb "github.com/rqlite/rqlite/v10/grpc/proto"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
+	"github.com/rqlite/rqlite/v10/internal/rsync"
+	"github.com/rqlite/rqlite/v10/proxy"
+	"github.com/rqlite/rqlite/v10/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// stats captures stats for the gRPC service.
+	stats *expvar.Map
+
+	// ErrServiceOpen is returned when the service is already open.
+	ErrServiceOpen = errors.New("service already open")
+)
+
+const (
+	numExecutions     = "executions"
+	numQueries        = "queries"
+	numRequests       = "requests"
+	numBackups        = "backups"
+	numLoads          = "loads"
+	numStatements     = "statements"
+	numAuthOK         = "authOK"
+	numAuthFail       = "authFail"
+	numLeaderNotFound = "leader_not_found"
+	numRedirects      = "redirects"
+)
+
+const (
+	// DefaultTimeout is the default time allowed for each request sent to
+	// the cluster, if the client does not set a deadline.
+	DefaultTimeout = 30 * time.Second
+
+	// defaultLinearTimeout is the default time allowed for a linearizable
+	// read to confirm Leadership.
+	defaultLinearTimeout = 10 * time.Second
+
+	// backupChunkSize is the maximum size of each chunk sent in response
+	// to a Backup request.
+	backupChunkSize = 64 * 1024
+
+	// loadChunkSize is the size of the chunks in which SQLite files are
+	// sent through the Raft log when loaded.
+	loadChunkSize = 16 * 1024 * 1024
+
+	// DefaultMaxLoadSize is the default maximum size of the data loaded by
+	// a Load request, after any decompression.
+	DefaultMaxLoadSize = 1 << 30
+)
+
+// Metadata keys understood, or set, by the service.
+const (
+	// MetadataAuthorization carries Basic auth credentials.
+	MetadataAuthorization = "authorization"
+
+	// MetadataRedirect, if "true", disables forwarding of requests to the Leader.
+	MetadataRedirect = "rqlite-redirect"
+
+	// MetadataRetries sets the number of times a forwarded request is retried.
+	MetadataRetries = "rqlite-retries"
+
+	// MetadataNoParse, if "true", disables parsing and rewriting of SQL.
+	MetadataNoParse = "rqlite-noparse"
+
+	// MetadataServedBy is set to the Raft address of the node which served
+	// the request. For Backup it is sent as a trailer.
+	MetadataServedBy = "rqlite-served-by"
+
+	// MetadataLeader is set, in the trailer, to the HTTP API address of the
+	// Leader when a request fails because redirection was requested.
+	MetadataLeader = "rqlite-leader"
+)
+
+func init() {
+	stats = expvar.NewMap("grpc")
+	ResetStats()
+}
+
+// ResetStats resets the expvar stats for this module. Mostly for test purposes.
+func ResetStats() {
+	stats.Init()
+	stats.Add(numExecutions, 0)
+	stats.Add(numQueries, 0)
+	stats.Add(numRequests, 0)
+	stats.Add(numBackups, 0)
+	stats.Add(numLoads, 0)
+	stats.Add(numStatements, 0)
+	stats.Add(numAuthOK, 0)
+	stats.Add(numAuthFail, 0)
+	stats.Add(numLeaderNotFound, 0)
+	stats.Add(numRedirects, 0)
+}
+
+// Proxy is the interface the service uses to send requests to the cluster.
+// Requests are forwarded to the Leader if this node is not the Leader.
+type Proxy interface {
+	Execute(ctx context.Context, er *proto.ExecuteRequest, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error)
+	Query(ctx context.Context, qr *proto.QueryRequest, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) ([]*proto.QueryRows, uint64, string, error)
+	Request(ctx context.Context, eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error)
+	Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writer, creds *clstrPB.Credentials,
+		timeout time.Duration, noForward bool) (string, error)
+	LoadFrom(ctx context.Context, r io.Reader, chunkSize int64, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) (string, error)
+}
+
+// Leader is the interface the service uses to find the HTTP API address
+// of the Leader, returned to clients which request redirection.
+type Leader interface {
+	LeaderAPIAddr(ctx context.Context) string
+}
+
+// CredentialStore is the interface credential stores must support.
+type CredentialStore interface {
+	// AA authenticates and checks authorization for the given perm.
+	AA(username, password, perm string) bool
+
+	// AAToken authenticates the bearer token and checks authorization for
+	// the given perm.
+	AAToken(token, perm string) bool
+}
+
+// Service provides a gRPC API to the database.
+type Service struct {
+	pb.UnimplementedDatabaseServer
+
+	ln   net.Listener // Incoming connections to the service
+	addr net.Addr     // Address on which this service is listening
+
+	proxy           Proxy
+	leader          Leader
+	credentialStore CredentialStore
+
+	// TLSConfig, if set, is used to serve the API over TLS. Must be set
+	// before Start is called.
+	TLSConfig *tls.Config
+
+	// Linter, if set, checks writes for non-determinism before they are
+	// sent to the Leader. Must be set before Start is called.
+	Linter *sql.Linter
+
+	// Catalog, if set, is consulted so that only INSERT statements which
+	// need them record values for non-deterministic column defaults. Must
+	// be set before Start is called.
+	Catalog sql.Catalog
+
+	// Authorizer, if set, checks that statements only access the tables
+	// permitted to the user. Must be set before Start is called.
+	Authorizer *sql.Authorizer
+
+	// NamedStatement, if set, returns the SQL registered under the given
+	// name, so that named statements are linted and authorized by their SQL.
+	// Must be set before Start is called.
+	NamedStatement func(name string) (string, error)
+
+	// MaxLoadSize is the maximum size of the data loaded by a Load request,
+	// after any decompression. Zero means no limit.
+	MaxLoadSize int64
+
+	server *grpc.Server
+	open   *rsync.AtomicBool
+
+	logger *log.Logger
+}
+
+// New returns a new instance of the gRPC service. If credentialStore is nil,
+// clients are not required to authenticate.
+func New(ln net.Listener, p Proxy, l Leader, credentialStore CredentialStore) *Service {
+	return &Service{
+		ln:              ln,
+		addr:            ln.Addr(),
+		proxy:           p,
+		leader:          l,
+		credentialStore: credentialStore,
+		MaxLoadSize:     DefaultMaxLoadSize,
+		open:            rsync.NewAtomicBool(),
+		logger:          logging.New("grpc", os.Stderr),
+	}
+}
+
+// Start starts the service.
+func (s *Service) Start() error {
+	if s.open.Is() {
+		return ErrServiceOpen
+	}
+	var opts []grpc.ServerOption
+	if s.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.TLSConfig)))
+	}
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterDatabaseServer(s.server, s)
+	s.open.Set()
+
+	go func() {
+		if err := s.server.Serve(s.ln); err != nil && s.open.Is() {
+			s.logger.Printf("serve: %s", err)
+		}
+	}()
+	s.logger.Println("service listening on", s.addr)
+	return nil
+}
+
+// Close closes the service.
+func (s *Service) Close() error {
+	if !s.open.Is() {
+		return nil
+	}
+	s.open.Unset()
+	s.server.Stop()
+	return nil
+}
+
+// Addr returns the address on which the service is listening.
+func (s *Service) Addr() net.Addr {
+	return s.addr
+}
+
+// Stats returns status of the service.
+func (s *Service) Stats() (map[string]any, error) {
+	return map[string]any{
+		"addr": s.addr.String(),
+		"auth": strconv.FormatBool(s.credentialStore != nil),
+		"tls":  strconv.FormatBool(s.TLSConfig != nil),
+	}, nil
+}
+
+// Execute executes the statements in the request. Statements are
+// executed on the Leader.
+func (s *Service) Execute(ctx context.Context, er *proto.ExecuteRequest) (*pb.ExecuteResponse, error) {
+	if err := s.checkPerm(ctx, auth.PermExecute); err != nil {
+		return nil, err
+	}
+	stats.Add(numExecutions, 1)
+	if err := s.process(ctx, er.GetRequest(), true); err != nil {
+		return nil, err
+	}
+
+	results, raftIndex, addr, err := s.proxy.Execute(ctx, er, makeCredentials(ctx),
+		timeout(ctx), retries(ctx), redirect(ctx))
+	if err != nil {
+		return nil, s.proxyError(ctx, "execute", err)
+	}
+	setServedBy(ctx, addr)
+	return &pb.ExecuteResponse{
+		Results:   results,
+		RaftIndex: raftIndex,
+	}, nil
+}
+
+// Query executes the queries in the request, at the requested read
+// consistency level.
+func (s *Service) Query(ctx context.Context, qr *proto.QueryRequest) (*pb.QueryResponse, error) {
+	if err := s.checkPerm(ctx, auth.PermQuery); err != nil {
+		return nil, err
+	}
+	stats.Add(numQueries, 1)
+	if err := s.process(ctx, qr.GetRequest(), qr.Level == proto.ConsistencyLevel_STRONG); err != nil {
+		return nil, err
+	}
+	if qr.LinearizableTimeout == 0 {
+		qr.LinearizableTimeout = defaultLinearTimeout.Nanoseconds()
+	}
+
+	results, raftIndex, addr, err := s.proxy.Query(ctx, qr, makeCredentials(ctx),
+		timeout(ctx), retries(ctx), redirect(ctx))
+	if err != nil {
+		return nil, s.proxyError(ctx, "query", err)
+	}
+	setServedBy(ctx, addr)
+	return &pb.QueryResponse{
+		Results:   results,
+		RaftIndex: raftIndex,
+	}, nil
+}
+
+// Request executes the statements in the request, each of which may be a
+// read or a write.
+func (s *Service) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest) (*pb.RequestResponse, error) {
+	if err := s.checkPerm(ctx, auth.PermQuery, auth.PermExecute); err != nil {
+		return nil, err
+	}
+	stats.Add(numRequests, 1)
+	if err := s.process(ctx, eqr.GetRequest(), true); err != nil {
+		return nil, err
+	}
+	if eqr.LinearizableTimeout == 0 {
+		eqr.LinearizableTimeout = defaultLinearTimeout.Nanoseconds()
+	}
+
+	results, seq, raftIndex, addr, err := s.proxy.Request(ctx, eqr, makeCredentials(ctx),
+		timeout(ctx), retries(ctx), redirect(ctx))
+	if err != nil {
+		return nil, s.proxyError(ctx, "request", err)
+	}
+	setServedBy(ctx, addr)
+	return &pb.RequestResponse{
+		Results:        results,
+		RaftIndex:      raftIndex,
+		SequenceNumber: seq,
+	}, nil
+}
+
+// Backup streams a backup of the database to the client.
+func (s *Service) Backup(br *proto.BackupRequest, stream grpc.ServerStreamingServer[pb.BackupChunk]) error {
+	ctx := stream.Context()
+	if err := s.checkPerm(ctx, auth.PermBackup); err != nil {
+		return err
+	}
+	stats.Add(numBackups, 1)
+
+	addr, err := s.proxy.Backup(ctx, br, &chunkWriter{stream: stream}, makeCredentials(ctx),
+		timeout(ctx), redirect(ctx))
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidVacuum) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return s.proxyError(ctx, "backup", err)
+	}
+	stream.SetTrailer(metadata.Pairs(MetadataServedBy, addr))
+	return nil
+}
+
+// Load loads the database from the data streamed by the client, which may
+// be a SQLite database file or a SQL dump, optionally compressed. The data is
+// spooled to a temporary file, and a SQLite file is sent to the Leader in
+// chunks, so the load is never held in memory.
+func (s *Service) Load(stream grpc.ClientStreamingServer[pb.LoadChunk, pb.LoadResponse]) error {
+	ctx := stream.Context()
+	if err := s.checkPerm(ctx, auth.PermLoad); err != nil {
+		return err
+	}
+	stats.Add(numLoads, 1)
+
+	cr := &chunkReader{stream: stream}
+	f, compression, err := rarchive.SpoolDecompressed(cr, s.MaxLoadSize)
+	if err != nil {
+		if cr.err != nil {
+			return cr.err
+		}
+		if errors.Is(err, rarchive.ErrTooLarge) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if compression != rarchive.CompressionNone {
+		s.logger.Printf("%s-compressed load data detected", compression)
+	}
+
+	// The header is enough to tell a SQLite file from SQL.
+	hdr := make([]byte, 16)
+	n, err := io.ReadFull(f, hdr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.LoadResponse{}
+	var addr string
+	if db.IsValidSQLiteData(hdr[:n]) {
+		s.logger.Printf("SQLite database file detected as load data")
+		addr, err = s.proxy.LoadFrom(ctx, f, loadChunkSize, makeCredentials(ctx),
+			timeout(ctx), retries(ctx), redirect(ctx))
+	} else {
+		// A SQL dump is executed in a single request, so must be read into
+		// memory.
+		b, readErr := io.ReadAll(f)
+		if readErr != nil {
+			return status.Error(codes.Internal, readErr.Error())
+		}
+		er := &proto.ExecuteRequest{
+			Request: &proto.Request{
+				Statements:      []*proto.Statement{{Sql: string(b)}},
+				RollbackOnError: true,
+			},
+		}
+		if t := sql.ReferencesSystemTable(string(b)); t != "" {
+			return status.Errorf(codes.PermissionDenied, "load of table %s not permitted", t)
+		}
+		if err := s.authorize(ctx, er.Request.Statements); err != nil {
+			return err
+		}
+		resp.Results, _, addr, err = s.proxy.Execute(ctx, er, makeCredentials(ctx),
+			timeout(ctx), retries(ctx), redirect(ctx))
+	}
+	if err != nil {
+		return s.proxyError(ctx, "load", err)
+	}
+	setServedBy(ctx, addr)
+	return stream.SendAndClose(resp)
+}
+
+// checkPerm checks that the client is authenticated and authorized with
+// all the given perms.
+func (s *Service) checkPerm(ctx context.Context, perms ...string) (err error) {
+	defer func() {
+		if err == nil {
+			stats.Add(numAuthOK, 1)
+		} else {
+			stats.Add(numAuthFail, 1)
+		}
+	}()
+
+	// No auth store set, so no checking required.
+	if s.credentialStore == nil {
+		return nil
+	}
+
+	creds := makeCredentials(ctx)
+	for _, perm := range perms {
+		var ok bool
+		if creds.GetToken() != "" {
+			ok = s.credentialStore.AAToken(creds.GetToken(), perm)
+		} else {
+			ok = s.credentialStore.AA(creds.GetUsername(), creds.GetPassword(), perm)
+		}
+		if !ok {
+			if creds.GetUsername() == "" && creds.GetToken() == "" {
+				return status.Error(codes.Unauthenticated, "authentication required")
+			}
+			return status.Errorf(codes.PermissionDenied, "user not authorized for %s", perm)
+		}
+	}
+	return nil
+}
+
+// process parses and rewrites the statements in req, unless the client
+// disabled parsing, and then checks them with the Linter and the Authorizer.
+// Random and time functions are only rewritten if rewrite is true.
+func (s *Service) process(ctx context.Context, req *proto.Request, rewrite bool) error {
+	stmts := req.GetStatements()
+	stats.Add(numStatements, int64(len(stmts)))
+	if !mdBool(ctx, MetadataNoParse) {
+		if err := sql.ProcessWithCatalog(stmts, rewrite, rewrite, s.Catalog); err != nil {
+			return status.Errorf(codes.InvalidArgument, "SQL rewrite: %s", err.Error())
+		}
+	}
+	if err := s.Linter.Check(stmts, s.NamedStatement); err != nil {
+		var lintErr *sql.LintError
+		if errors.As(err, &lintErr) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return status.Errorf(codes.Internal, "SQL lint: %s", err.Error())
+	}
+	return s.authorize(ctx, stmts)
+}
+
+// authorize checks with the Authorizer that the client may access the tables
+// the given statements access.
+func (s *Service) authorize(ctx context.Context, stmts []*proto.Statement) error {
+	var err error
+	if creds := makeCredentials(ctx); creds.GetToken() != "" {
+		err = s.Authorizer.CheckToken(creds.GetToken(), stmts, s.NamedStatement)
+	} else {
+		err = s.Authorizer.Check(creds.GetUsername(), creds.GetPassword(), stmts, s.NamedStatement)
+	}
+	if err != nil {
+		var aclErr *sql.ACLError
+		if errors.As(err, &aclErr) {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return status.Errorf(codes.Internal, "SQL authorization: %s", err.Error())
+	}
+	return nil
+}
+
+// proxyError converts an error returned by the proxy into a gRPC status.
+func (s *Service) proxyError(ctx context.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, proxy.ErrNotLeader):
+		stats.Add(numRedirects, 1)
+		if s.leader != nil {
+			if addr := s.leader.LeaderAPIAddr(ctx); addr != "" {
+				grpc.SetTrailer(ctx, metadata.Pairs(MetadataLeader, addr))
+			}
+		}
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, proxy.ErrLeaderNotFound):
+		stats.Add(numLeaderNotFound, 1)
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, proxy.ErrUnauthorized):
+		return status.Errorf(codes.PermissionDenied, "remote %s not authorized", op)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// chunkWriter sends everything written to it to the client as a series
+// of BackupChunks.
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[pb.BackupChunk]
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		sz := min(len(p), backupChunkSize)
+		if err := c.stream.Send(&pb.BackupChunk{Data: p[:sz]}); err != nil {
+			return n, err
+		}
+		n += sz
+		p = p[sz:]
+	}
+	return n, nil
+}
+
+// chunkReader reads the data of the LoadChunks sent by the client. Any error
+// receiving a chunk is recorded, so it can be returned to the client as is.
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[pb.LoadChunk, pb.LoadResponse]
+	buf    []byte
+	err    error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		chunk, err := c.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		c.buf = chunk.Data
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// makeCredentials returns the bearer token or Basic auth credentials sent by
+// the client, or nil if none were sent.
+func makeCredentials(ctx context.Context) *clstrPB.Credentials {
+	v := mdValue(ctx, MetadataAuthorization)
+	const bearer = "Bearer "
+	if len(v) > len(bearer) && strings.EqualFold(v[:len(bearer)], bearer) {
+		return &clstrPB.Credentials{
+			Token: strings.TrimSpace(v[len(bearer):]),
+		}
+	}
+	const prefix = "Basic "
+	if len(v) < len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return nil
+	}
+	c, err := base64.StdEncoding.DecodeString(v[len(prefix):])
+	if err != nil {
+		return nil
+	}
+	username, password, ok := strings.Cut(string(c), ":")
+	if !ok {
+		return nil
+	}
+	return &clstrPB.Credentials{
+		Username: username,
+		Password: password,
+	}
+}
+
+// timeout returns the time remaining before the request deadline, or the
+// default timeout if the client did not set a deadline.
+func timeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Deadline(); ok {
+		return time.Until(d)
+	}
+	return DefaultTimeout
+}
+
+func retries(ctx context.Context) int {
+	n, err := strconv.Atoi(mdValue(ctx, MetadataRetries))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func redirect(ctx context.Context) bool {
+	return mdBool(ctx, MetadataRedirect)
+}
+
+func setServedBy(ctx context.Context, addr string) {
+	if addr != "" {
+		grpc.SetHeader(ctx, metadata.Pairs(MetadataServedBy, addr))
+	}
+}
+
+func mdBool(ctx context.Context, key string) bool {
+	b, _ := strconv.ParseBool(mdValue(ctx, key))
+	return b
+}
+
+func mdValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// BasicAuth returns the value of the authorization metadata for the given
+// username and password.
+func BasicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+}
+
+// BearerAuth returns the value of the authorization metadata for the given
+// bearer token.
+func BearerAuth(token string) string {
+	return "Bearer " + token
+}
@@ -0,0 +1,636 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	pb "github.com/rqlite/rqlite/v10/grpc/proto"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
+	"github.com/rqlite/rqlite/v10/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_NewService(t *testing.T) {
+	s := New(mustListen(t), &mockProxy{}, nil, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service: %s", err)
+	}
+	defer s.Close()
+	if err := s.Start(); err != ErrServiceOpen {
+		t.Fatalf("expected ErrServiceOpen, got %v", err)
+	}
+	if s.Addr() == nil {
+		t.Fatalf("expected non-nil address")
+	}
+}
+
+func Test_Execute(t *testing.T) {
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			if exp, got := "INSERT INTO foo(t) VALUES(datetime('now'))", er.Request.Statements[0].Sql; got == exp {
+				t.Fatalf("time function not rewritten")
+			}
+			if noForward {
+				t.Fatalf("expected request to be forwarded")
+			}
+			return []*proto.ExecuteQueryResponse{
+				{Result: &proto.ExecuteQueryResponse_E{E: &proto.ExecuteResult{LastInsertId: 5, RowsAffected: 1}}},
+			}, 7, "node1", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	var hdr metadata.MD
+	resp, err := c.Execute(context.Background(), &proto.ExecuteRequest{
+		Request: &proto.Request{
+			Statements: []*proto.Statement{{Sql: "INSERT INTO foo(t) VALUES(datetime('now'))"}},
+		},
+	}, grpc.Header(&hdr))
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err)
+	}
+	if resp.RaftIndex != 7 {
+		t.Fatalf("wrong raft index, got %d", resp.RaftIndex)
+	}
+	if got := resp.Results[0].GetE().LastInsertId; got != 5 {
+		t.Fatalf("wrong last insert ID, got %d", got)
+	}
+	if got := hdr.Get(MetadataServedBy); len(got) != 1 || got[0] != "node1" {
+		t.Fatalf("wrong served-by header, got %v", got)
+	}
+}
+
+func Test_Query(t *testing.T) {
+	m := &mockProxy{
+		queryFn: func(qr *proto.QueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.QueryRows, uint64, string, error) {
+			if qr.Level != proto.ConsistencyLevel_NONE {
+				t.Fatalf("wrong consistency level, got %s", qr.Level)
+			}
+			if exp, got := "SELECT random()", qr.Request.Statements[0].Sql; got != exp {
+				t.Fatalf("statement rewritten at level NONE, got %s", got)
+			}
+			if qr.LinearizableTimeout != defaultLinearTimeout.Nanoseconds() {
+				t.Fatalf("wrong linearizable timeout, got %d", qr.LinearizableTimeout)
+			}
+			return []*proto.QueryRows{
+				{
+					Columns: []string{"random()"},
+					Types:   []string{"integer"},
+					Values:  []*proto.Values{{Parameters: []*proto.Parameter{{Value: &proto.Parameter_I{I: 4}}}}},
+				},
+			}, 0, "node1", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	resp, err := c.Query(context.Background(), &proto.QueryRequest{
+		Request: &proto.Request{
+			Statements: []*proto.Statement{{Sql: "SELECT random()"}},
+		},
+		Level: proto.ConsistencyLevel_NONE,
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	if got := resp.Results[0].Values[0].Parameters[0].GetI(); got != 4 {
+		t.Fatalf("wrong value, got %d", got)
+	}
+}
+
+func Test_Request(t *testing.T) {
+	m := &mockProxy{
+		requestFn: func(eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error) {
+			return []*proto.ExecuteQueryResponse{
+				{Result: &proto.ExecuteQueryResponse_E{E: &proto.ExecuteResult{RowsAffected: 1}}},
+			}, 3, 9, "node1", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	resp, err := c.Request(context.Background(), &proto.ExecuteQueryRequest{
+		Request: &proto.Request{
+			Statements: []*proto.Statement{{Sql: "DELETE FROM foo"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to request: %s", err)
+	}
+	if resp.SequenceNumber != 3 || resp.RaftIndex != 9 {
+		t.Fatalf("wrong response, got %v", resp)
+	}
+}
+
+func Test_Auth(t *testing.T) {
+	m := &mockProxy{
+		requestFn: func(eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error) {
+			if creds == nil || creds.Username != "alice" || creds.Password != "secret" {
+				t.Fatalf("wrong credentials forwarded, got %v", creds)
+			}
+			return nil, 0, 0, "node1", nil
+		},
+	}
+	cs := &mockCredentialStore{
+		perms: map[string][]string{"alice": {"query", "execute"}, "bob": {"query"}},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, cs))
+	eqr := &proto.ExecuteQueryRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Sql: "SELECT 1"}}},
+	}
+
+	_, err := c.Request(context.Background(), eqr)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BasicAuth("bob", "secret"))
+	_, err = c.Request(ctx, eqr)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BasicAuth("alice", "secret"))
+	if _, err = c.Request(ctx, eqr); err != nil {
+		t.Fatalf("failed to request: %s", err)
+	}
+}
+
+func Test_AuthBearerToken(t *testing.T) {
+	m := &mockProxy{
+		requestFn: func(eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error) {
+			if creds.GetToken() != "token1" || creds.GetUsername() != "" {
+				t.Fatalf("wrong credentials forwarded, got %v", creds)
+			}
+			return nil, 0, 0, "node1", nil
+		},
+	}
+	cs := &mockCredentialStore{
+		tokens: map[string][]string{"token1": {"query", "execute"}, "token2": {"query"}},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, cs))
+	eqr := &proto.ExecuteQueryRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Sql: "SELECT 1"}}},
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BearerAuth("token2"))
+	if _, err := c.Request(ctx, eqr); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BearerAuth("token1"))
+	if _, err := c.Request(ctx, eqr); err != nil {
+		t.Fatalf("failed to request: %s", err)
+	}
+}
+
+func Test_AuthACL(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "alice", "password": "secret", "perms": ["query", "execute"], "acl": [
+			{"tables": ["foo"], "ops": ["select", "insert"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	var executed bool
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			executed = true
+			return nil, 0, "node1", nil
+		},
+	}
+	s := New(mustListen(t), m, nil, cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	c := mustStartClient(t, s)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BasicAuth("alice", "secret"))
+	_, err := c.Execute(ctx, &proto.ExecuteRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Sql: "DELETE FROM foo"}}},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "delete on table foo not permitted") {
+		t.Fatalf("wrong error for denied execute: %s", err)
+	}
+	if executed {
+		t.Fatalf("denied statement was executed")
+	}
+
+	if _, err := c.Execute(ctx, &proto.ExecuteRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Sql: "INSERT INTO foo(id) VALUES(1)"}}},
+	}); err != nil {
+		t.Fatalf("failed to execute: %s", err)
+	}
+	if !executed {
+		t.Fatalf("permitted statement was not executed")
+	}
+}
+
+func Test_AuthACLNamed(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "alice", "password": "secret", "perms": ["query", "execute"], "acl": [
+			{"tables": ["foo"], "ops": ["select", "insert"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	var executed bool
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			executed = true
+			return nil, 0, "node1", nil
+		},
+	}
+	s := New(mustListen(t), m, nil, cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	s.NamedStatement = func(name string) (string, error) {
+		if name == "purge" {
+			return "DELETE FROM foo", nil
+		}
+		return "", fmt.Errorf("no such statement: %s", name)
+	}
+	c := mustStartClient(t, s)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BasicAuth("alice", "secret"))
+	_, err := c.Execute(ctx, &proto.ExecuteRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Name: "purge"}}},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	if executed {
+		t.Fatalf("denied named statement was executed")
+	}
+}
+
+func Test_LoadSQLACL(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "alice", "password": "secret", "perms": ["load"], "acl": [
+			{"tables": ["foo"], "ops": ["ddl", "insert"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			t.Fatalf("denied SQL dump was executed")
+			return nil, 0, "", nil
+		},
+	}
+	s := New(mustListen(t), m, nil, cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	c := mustStartClient(t, s)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, BasicAuth("alice", "secret"))
+	stream, err := c.Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to start load: %s", err)
+	}
+	if err := stream.Send(&pb.LoadChunk{Data: []byte("INSERT INTO foo VALUES(1);\nDROP TABLE bar;\n")}); err != nil {
+		t.Fatalf("failed to send chunk: %s", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func Test_Redirect(t *testing.T) {
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			if !noForward {
+				t.Fatalf("expected forwarding to be disabled")
+			}
+			return nil, 0, "", proxy.ErrNotLeader
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, mockLeader("http://leader:4001"), nil))
+
+	var trl metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataRedirect, "true")
+	_, err := c.Execute(ctx, &proto.ExecuteRequest{
+		Request: &proto.Request{Statements: []*proto.Statement{{Sql: "DELETE FROM foo"}}},
+	}, grpc.Trailer(&trl))
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition, got %v", err)
+	}
+	if got := trl.Get(MetadataLeader); len(got) != 1 || got[0] != "http://leader:4001" {
+		t.Fatalf("wrong leader trailer, got %v", got)
+	}
+}
+
+func Test_ProxyErrors(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{proxy.ErrLeaderNotFound, codes.Unavailable},
+		{proxy.ErrUnauthorized, codes.PermissionDenied},
+		{io.ErrUnexpectedEOF, codes.Internal},
+	} {
+		m := &mockProxy{
+			queryFn: func(qr *proto.QueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.QueryRows, uint64, string, error) {
+				return nil, 0, "", tt.err
+			},
+		}
+		c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+		_, err := c.Query(context.Background(), &proto.QueryRequest{
+			Request: &proto.Request{Statements: []*proto.Statement{{Sql: "SELECT 1"}}},
+		})
+		if status.Code(err) != tt.code {
+			t.Fatalf("expected %s for %s, got %v", tt.code, tt.err, err)
+		}
+	}
+}
+
+func Test_Backup(t *testing.T) {
+	data := bytes.Repeat([]byte("rqlite"), backupChunkSize)
+	m := &mockProxy{
+		backupFn: func(br *proto.BackupRequest, dst io.Writer) (string, error) {
+			if br.Format != proto.BackupRequest_BACKUP_REQUEST_FORMAT_SQL {
+				t.Fatalf("wrong backup format, got %s", br.Format)
+			}
+			_, err := dst.Write(data)
+			return "node1", err
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	stream, err := c.Backup(context.Background(), &proto.BackupRequest{
+		Format: proto.BackupRequest_BACKUP_REQUEST_FORMAT_SQL,
+	})
+	if err != nil {
+		t.Fatalf("failed to start backup: %s", err)
+	}
+	var buf bytes.Buffer
+	n := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to receive chunk: %s", err)
+		}
+		buf.Write(chunk.Data)
+		n++
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("backup data does not match")
+	}
+	if n != 6 {
+		t.Fatalf("wrong number of chunks, got %d", n)
+	}
+	if got := stream.Trailer().Get(MetadataServedBy); len(got) != 1 || got[0] != "node1" {
+		t.Fatalf("wrong served-by trailer, got %v", got)
+	}
+}
+
+func Test_LoadSQL(t *testing.T) {
+	dump := "CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT);\nINSERT INTO foo VALUES(1, 'fiona');\n"
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			if !er.Request.RollbackOnError {
+				t.Fatalf("expected rollback on error")
+			}
+			if got := er.Request.Statements[0].Sql; got != dump {
+				t.Fatalf("wrong SQL, got %s", got)
+			}
+			return []*proto.ExecuteQueryResponse{
+				{Result: &proto.ExecuteQueryResponse_E{E: &proto.ExecuteResult{RowsAffected: 1}}},
+			}, 0, "node1", nil
+		},
+		loadFromFn: func(r io.Reader, chunkSize int64) (string, error) {
+			t.Fatalf("SQL dump loaded as database file")
+			return "", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	stream, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to start load: %s", err)
+	}
+	for _, s := range []string{dump[:10], dump[10:]} {
+		if err := stream.Send(&pb.LoadChunk{Data: []byte(s)}); err != nil {
+			t.Fatalf("failed to send chunk: %s", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("wrong number of results, got %d", len(resp.Results))
+	}
+}
+
+func Test_LoadSQLSystemTables(t *testing.T) {
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			t.Fatalf("load of system table executed")
+			return nil, 0, "", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	stream, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to start load: %s", err)
+	}
+	if err := stream.Send(&pb.LoadChunk{Data: []byte("INSERT INTO _rqlite_users VALUES('mallory', '{}');\n")}); err != nil {
+		t.Fatalf("failed to send chunk: %s", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func Test_LoadSQLite(t *testing.T) {
+	data := append([]byte("SQLite format 3\x00"), bytes.Repeat([]byte("rqlite"), 1024)...)
+	m := &mockProxy{
+		loadFromFn: func(r io.Reader, chunkSize int64) (string, error) {
+			if chunkSize != loadChunkSize {
+				t.Fatalf("wrong chunk size, got %d", chunkSize)
+			}
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to read load data: %s", err)
+			}
+			if !bytes.Equal(b, data) {
+				t.Fatalf("load data does not match")
+			}
+			return "node1", nil
+		},
+	}
+	c := mustStartClient(t, New(mustListen(t), m, nil, nil))
+
+	var buf bytes.Buffer
+	cw, err := rarchive.NewCompressWriter(&buf, rarchive.CompressionGzip)
+	if err != nil {
+		t.Fatalf("failed to create gzip writer: %s", err)
+	}
+	if _, err := cw.Write(data); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+
+	stream, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to start load: %s", err)
+	}
+	b := buf.Bytes()
+	for len(b) > 0 {
+		sz := min(len(b), 100)
+		if err := stream.Send(&pb.LoadChunk{Data: b[:sz]}); err != nil {
+			t.Fatalf("failed to send chunk: %s", err)
+		}
+		b = b[sz:]
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+}
+
+func Test_LoadTooLarge(t *testing.T) {
+	m := &mockProxy{
+		executeFn: func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+			t.Fatalf("oversized data executed")
+			return nil, 0, "", nil
+		},
+	}
+	s := New(mustListen(t), m, nil, nil)
+	s.MaxLoadSize = 1024
+	c := mustStartClient(t, s)
+
+	// Data which compresses well must be limited by its decompressed size.
+	var buf bytes.Buffer
+	cw, err := rarchive.NewCompressWriter(&buf, rarchive.CompressionZstd)
+	if err != nil {
+		t.Fatalf("failed to create zstd writer: %s", err)
+	}
+	if _, err := cw.Write(bytes.Repeat([]byte("SELECT 1;"), 1024)); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+	if buf.Len() > int(s.MaxLoadSize) {
+		t.Fatalf("compressed test data is %d bytes, exp less than limit", buf.Len())
+	}
+
+	stream, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to start load: %s", err)
+	}
+	if err := stream.Send(&pb.LoadChunk{Data: buf.Bytes()}); err != nil {
+		t.Fatalf("failed to send chunk: %s", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
+
+func mustListen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	return ln
+}
+
+func mustStartClient(t *testing.T, s *Service) pb.DatabaseClient {
+	t.Helper()
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service: %s", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	conn, err := grpc.NewClient(s.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewDatabaseClient(conn)
+}
+
+type mockProxy struct {
+	executeFn  func(er *proto.ExecuteRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error)
+	queryFn    func(qr *proto.QueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.QueryRows, uint64, string, error)
+	requestFn  func(eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error)
+	backupFn   func(br *proto.BackupRequest, dst io.Writer) (string, error)
+	loadFromFn func(r io.Reader, chunkSize int64) (string, error)
+}
+
+func (m *mockProxy) Execute(ctx context.Context, er *proto.ExecuteRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+	return m.executeFn(er, creds, noForward)
+}
+
+func (m *mockProxy) Query(ctx context.Context, qr *proto.QueryRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) ([]*proto.QueryRows, uint64, string, error) {
+	return m.queryFn(qr, creds, noForward)
+}
+
+func (m *mockProxy) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, uint64, string, error) {
+	return m.requestFn(eqr, creds, noForward)
+}
+
+func (m *mockProxy) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writer, creds *clstrPB.Credentials,
+	timeout time.Duration, noForward bool) (string, error) {
+	return m.backupFn(br, dst)
+}
+
+func (m *mockProxy) LoadFrom(ctx context.Context, r io.Reader, chunkSize int64, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) (string, error) {
+	return m.loadFromFn(r, chunkSize)
+}
+
+type mockLeader string
+
+func (m mockLeader) LeaderAPIAddr(ctx context.Context) string {
+	return string(m)
+}
+
+type mockCredentialStore struct {
+	perms  map[string][]string
+	tokens map[string][]string
+}
+
+func (m *mockCredentialStore) AA(username, password, perm string) bool {
+	if password != "secret" {
+		return false
+	}
+	for _, p := range m.perms[username] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockCredentialStore) AAToken(token, perm string) bool {
+	for _, p := range m.tokens[token] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
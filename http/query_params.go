@@ -41,7 +41,7 @@ func NewQueryParams(r *http.Request) (QueryParams, error) {
 			}
 		}
 	}
-	for _, k := range []string{"retries", "trailing_logs"} {
+	for _, k := range []string{"retries", "trailing_logs", "batch_size", "chunk_size"} {
 		r, ok := qp[k]
 		if ok {
 			_, err := strconv.Atoi(r)
@@ -50,6 +50,11 @@ func NewQueryParams(r *http.Request) (QueryParams, error) {
 			}
 		}
 	}
+	if c, ok := qp["compression"]; ok {
+		if command.BackupCompressionFromString(c) == proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED {
+			return nil, fmt.Errorf("%s is not a valid compression algorithm", c)
+		}
+	}
 	q, ok := qp["q"]
 	if ok {
 		if q == "" {
@@ -149,6 +154,80 @@ func (qp QueryParams) Key() string {
 	return qp["key"]
 }
 
+// Name returns the value of the key named "name".
+func (qp QueryParams) Name() string {
+	return qp["name"]
+}
+
+// Suffix returns the value of the key named "suffix".
+func (qp QueryParams) Suffix() string {
+	return qp["suffix"]
+}
+
+// Table returns the value of the key named "table".
+func (qp QueryParams) Table() string {
+	return qp["table"]
+}
+
+// ImportFormat returns the requested import data format.
+func (qp QueryParams) ImportFormat() string {
+	return qp["format"]
+}
+
+// OnConflict returns the requested behavior on constraint violations.
+func (qp QueryParams) OnConflict() string {
+	return qp["on_conflict"]
+}
+
+// Create returns true if the query parameters request creation of the table.
+func (qp QueryParams) Create() bool {
+	return qp.HasKey("create")
+}
+
+// Progress returns true if the query parameters request progress reports.
+func (qp QueryParams) Progress() bool {
+	return qp.HasKey("progress")
+}
+
+// Mapping returns the requested field-to-column mapping, parsed from
+// comma-separated src:dst pairs. An empty dst means the field is skipped.
+func (qp QueryParams) Mapping() map[string]string {
+	m := qp["map"]
+	if m == "" {
+		return nil
+	}
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(m, ",") {
+		src, dst, _ := strings.Cut(pair, ":")
+		src = strings.TrimSpace(src)
+		if src == "" {
+			continue
+		}
+		mapping[src] = strings.TrimSpace(dst)
+	}
+	return mapping
+}
+
+// BatchSize returns the requested number of rows per batch.
+func (qp QueryParams) BatchSize(def int) int {
+	i, ok := qp["batch_size"]
+	if !ok {
+		return def
+	}
+	r, _ := strconv.Atoi(i)
+	return r
+}
+
+// ChunkSize returns the value of the key named "chunk_size".
+func (qp QueryParams) ChunkSize(def int) int {
+	i, ok := qp["chunk_size"]
+	if !ok {
+		return def
+	}
+	r, _ := strconv.Atoi(i)
+	return r
+}
+
 // DBTimeout returns the value of the key named "db_timeout".
 func (qp QueryParams) DBTimeout(def time.Duration) time.Duration {
 	t, ok := qp["db_timeout"]
@@ -180,6 +259,11 @@ func (qp QueryParams) BackupFormat() proto.BackupRequest_Format {
 	return command.BackupFormatFromString(qp["fmt"])
 }
 
+// BackupCompression returns the requested backup compression algorithm.
+func (qp QueryParams) BackupCompression() proto.BackupRequest_Compression {
+	return command.BackupCompressionFromString(qp["compression"])
+}
+
 // Query returns the requested query.
 func (qp QueryParams) Query() string {
 	return qp["q"]
@@ -202,6 +286,11 @@ func (qp QueryParams) Sync() bool {
 	return qp.HasKey("sync")
 }
 
+// Writable returns whether the writable flag is set.
+func (qp QueryParams) Writable() bool {
+	return qp.HasKey("writable")
+}
+
 // RaftIndex returns true if the query parameters request the Raft index
 // to be included in the response.
 func (qp QueryParams) RaftIndex() bool {
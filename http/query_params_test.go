@@ -41,6 +41,10 @@ func Test_NewQueryParams(t *testing.T) {
 		{"Byte array with associative", "byte_array&associative", QueryParams{"byte_array": "", "associative": ""}, false},
 		{"Requesting Raft Index", "raft_index", QueryParams{"raft_index": ""}, false},
 		{"Qualify columns", "qualify_columns", QueryParams{"qualify_columns": ""}, false},
+		{"Valid compression", "compression=zstd", QueryParams{"compression": "zstd"}, false},
+		{"Invalid compression", "compression=lz4", nil, true},
+		{"Valid batch size", "batch_size=500", QueryParams{"batch_size": "500"}, false},
+		{"Invalid batch size", "batch_size=many", nil, true},
 	}
 
 	for _, tc := range testCases {
@@ -60,6 +64,36 @@ func Test_NewQueryParams(t *testing.T) {
 	}
 }
 
+func Test_QueryParams_BackupCompression(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rawQuery string
+		expected proto.BackupRequest_Compression
+	}{
+		{"No compression parameter", "", proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED},
+		{"Compress flag only", "compress", proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_UNSPECIFIED},
+		{"None", "compression=none", proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_NONE},
+		{"Gzip", "compression=gzip", proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_GZIP},
+		{"Zstd", "compression=ZSTD", proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{
+				URL: &url.URL{
+					RawQuery: tc.rawQuery,
+				},
+			}
+			qp, err := NewQueryParams(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result := qp.BackupCompression(); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
 // Test_NewQueryParamsTimes tests that looking up unset timings values does
 // not result in a panic, and that zero values are returned.
 func Test_NewQueryParamsTimes(t *testing.T) {
@@ -106,6 +140,39 @@ func Test_QueryParams_Tables(t *testing.T) {
 	}
 }
 
+func Test_QueryParams_Mapping(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rawQuery string
+		expected map[string]string
+	}{
+		{"No map parameter", "", nil},
+		{"Single pair", "map=a:b", map[string]string{"a": "b"}},
+		{"Multiple pairs", "map=a:b, c : d", map[string]string{"a": "b", "c": "d"}},
+		{"Skipped field", "map=a:,b", map[string]string{"a": "", "b": ""}},
+		{"Empty source", "map=:b,c:d", map[string]string{"c": "d"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{
+				URL: &url.URL{
+					RawQuery: tc.rawQuery,
+				},
+			}
+			qp, err := NewQueryParams(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result := qp.Mapping()
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
 func Test_QueryParams_Level(t *testing.T) {
 	testCases := []struct {
 		name     string
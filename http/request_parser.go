@@ -111,6 +111,13 @@ func ParseRequest(r io.Reader) ([]*command.Statement, error) {
 				}
 			}
 			stmts = append(stmts, stmt)
+		} else if t == json.Delim('{') {
+			// It's a named statement.
+			stmt, err := parseNamedStatement(dec)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
 		} else {
 			return nil, ErrInvalidRequest
 		}
@@ -129,6 +136,66 @@ func ParseRequest(r io.Reader) ([]*command.Statement, error) {
 	return stmts, nil
 }
 
+// parseNamedStatement parses a reference to a named statement, of the form
+// {"name": "...", "params": [...]}, where params may instead be an object of
+// named parameters. The opening brace must already have been consumed.
+func parseNamedStatement(dec *json.Decoder) (*command.Statement, error) {
+	stmt := &command.Statement{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, ErrInvalidJSON
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, ErrInvalidRequest
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, ErrInvalidJSON
+		}
+		switch key {
+		case "name":
+			if stmt.Name, ok = v.(string); !ok {
+				return nil, ErrInvalidRequest
+			}
+		case "params":
+			switch p := v.(type) {
+			case []any:
+				for i := range p {
+					param, err := makeParameter("", p[i])
+					if err != nil {
+						return nil, err
+					}
+					stmt.Parameters = append(stmt.Parameters, param)
+				}
+			case map[string]any:
+				for k, v := range p {
+					param, err := makeParameter(k, v)
+					if err != nil {
+						return nil, err
+					}
+					stmt.Parameters = append(stmt.Parameters, param)
+				}
+			default:
+				return nil, ErrInvalidRequest
+			}
+		default:
+			return nil, ErrInvalidRequest
+		}
+	}
+	// Consume the closing brace.
+	if t, err := dec.Token(); err != nil {
+		return nil, ErrInvalidJSON
+	} else if t != json.Delim('}') {
+		return nil, ErrInvalidRequest
+	}
+	if stmt.Name == "" {
+		return nil, ErrInvalidRequest
+	}
+	return stmt, nil
+}
+
 func makeParameter(name string, i any) (*command.Parameter, error) {
 	// Check if the value is a JSON number, and if so, convert it to an int64 or float64.
 	// Then let the switch statement below handle it.
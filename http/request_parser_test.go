@@ -488,6 +488,42 @@ func Test_SingleInvalidTypeRequests(t *testing.T) {
 	}
 }
 
+func Test_NamedStatementRequest(t *testing.T) {
+	b := []byte(`[{"name": "add", "params": ["fiona", 20]}, "SELECT * FROM foo", {"name": "get", "params": {"id": 1}}]`)
+	stmts, err := ParseRequest(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to parse request: %s", err.Error())
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("incorrect number of statements returned: %d", len(stmts))
+	}
+	if stmts[0].Name != "add" || stmts[0].Sql != "" {
+		t.Fatalf("incorrect statement parsed: %v", stmts[0])
+	}
+	if len(stmts[0].Parameters) != 2 {
+		t.Fatalf("incorrect number of parameters returned: %d", len(stmts[0].Parameters))
+	}
+	if got := stmts[0].Parameters[0].GetS(); got != "fiona" {
+		t.Fatalf("incorrect parameter, exp fiona, got %s", got)
+	}
+	if stmts[1].Sql != "SELECT * FROM foo" {
+		t.Fatalf("incorrect statement parsed: %v", stmts[1])
+	}
+	if stmts[2].Name != "get" || len(stmts[2].Parameters) != 1 || stmts[2].Parameters[0].Name != "id" {
+		t.Fatalf("incorrect statement parsed: %v", stmts[2])
+	}
+
+	for _, s := range []string{
+		`[{"params": [1]}]`,
+		`[{"name": 1}]`,
+		`[{"name": "add", "params": 1}]`,
+	} {
+		if _, err := ParseRequest(bytes.NewReader([]byte(s))); err != ErrInvalidRequest {
+			t.Fatalf("got unexpected error for invalid request %s: %v", s, err)
+		}
+	}
+}
+
 func byteSliceToStringArray(b []byte) string {
 	var buffer bytes.Buffer
 	buffer.WriteString("[")
@@ -3,37 +3,51 @@ package http
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/auto/backup"
 	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command"
 	"github.com/rqlite/rqlite/v10/command/encoding"
+	"github.com/rqlite/rqlite/v10/command/importer"
 	"github.com/rqlite/rqlite/v10/command/proto"
 	"github.com/rqlite/rqlite/v10/command/sql"
 	"github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/http/console"
 	"github.com/rqlite/rqlite/v10/http/licenses"
+	"github.com/rqlite/rqlite/v10/internal/latency"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/random"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
+	"github.com/rqlite/rqlite/v10/otlp"
 	"github.com/rqlite/rqlite/v10/proxy"
 	"github.com/rqlite/rqlite/v10/queue"
 	"github.com/rqlite/rqlite/v10/store"
 	rsql "github.com/rqlite/sql"
+	pb "google.golang.org/protobuf/proto"
 )
 
 const kubernetesServiceHostEnv = "KUBERNETES_SERVICE_HOST"
@@ -72,6 +86,19 @@ type Store interface {
 	// the Raft system. It then triggers a Raft snapshot, which will then make
 	// Raft aware of the new data.
 	ReadFrom(r io.Reader) (int64, error)
+
+	// NamedStatement returns the SQL registered under the given name.
+	NamedStatement(name string) (string, error)
+
+	// NamedStatements returns all registered statements, keyed by name.
+	NamedStatements() (map[string]string, error)
+
+	// Users returns the users stored in the database.
+	Users() ([]auth.Credential, error)
+
+	// RORWCount returns the number of read-only and read-write statements
+	// in the given request.
+	RORWCount(eqr *proto.ExecuteQueryRequest) (nRW, nRO int)
 }
 
 // GetNodeMetaer is the interface that wraps the GetNodeMeta method.
@@ -84,6 +111,10 @@ type GetNodeMetaer interface {
 type Cluster interface {
 	GetNodeMetaer
 
+	// GetChecksum returns the checksums the node at the given Raft address
+	// computed at a log index.
+	GetChecksum(ctx context.Context, gr *proto.ChecksumResultRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) (*proto.ChecksumResult, error)
+
 	// Stats returns stats on the Cluster.
 	Stats() (map[string]any, error)
 }
@@ -92,6 +123,17 @@ type Cluster interface {
 type CredentialStore interface {
 	// AA authenticates and checks authorization for the given perm.
 	AA(username, password, perm string) bool
+
+	// AAToken authenticates the bearer token and checks authorization for
+	// the given perm.
+	AAToken(token, perm string) bool
+
+	// AACert checks authorization for the given perm of the user mapped to
+	// the verified client certificate.
+	AACert(cert *x509.Certificate, perm string) bool
+
+	// CertUser returns the user mapped to the verified client certificate.
+	CertUser(cert *x509.Certificate) (string, bool)
 }
 
 // StatusReporter is the interface status providers must implement.
@@ -99,6 +141,13 @@ type StatusReporter interface {
 	Stats() (map[string]any, error)
 }
 
+// BackupUploader is the interface on-demand backup uploaders must implement.
+type BackupUploader interface {
+	// UploadNow uploads a backup to the configured storage service
+	// immediately. name and suffix, if non-empty, override the object name.
+	UploadNow(ctx context.Context, name, suffix string) (*backup.UploadResult, error)
+}
+
 // DBResults stores either an Execute result, a Query result, or
 // an ExecuteQuery result.
 type DBResults struct {
@@ -180,12 +229,22 @@ const (
 	numRequestStmtsRx                 = "request_stmts_rx"
 	numReadyz                         = "num_readyz"
 	numStatus                         = "num_status"
+	numMetrics                        = "num_metrics"
+	numLogLevels                      = "log_levels"
 	numBackups                        = "backups"
+	numBackupUploads                  = "backup_uploads"
 	numLoad                           = "loads"
+	numImports                        = "imports"
+	numImportRows                     = "import_rows"
 	numBoot                           = "boot"
 	numSnapshots                      = "user_snapshots"
 	numReaps                          = "user_reaps"
 	numSQLAnalyze                     = "sql_analyze"
+	numVerify                         = "verify"
+	numStatements                     = "statements"
+	numUsers                          = "users"
+	numRateLimited                    = "rate_limited"
+	numWritesThrottled                = "writes_throttled"
 	numAuthOK                         = "auth_ok"
 	numAuthFail                       = "auth_fail"
 	numTLSCertFetched                 = "tls_cert_fetched"
@@ -196,6 +255,18 @@ const (
 	// Default timeout for linearizable reads.
 	defaultLinearTimeout = 10 * time.Second
 
+	// loadChunkSize is the size of the chunks in which SQLite files are
+	// sent through the Raft log when loaded.
+	loadChunkSize = 16 * 1024 * 1024
+
+	// maxAnalysisCacheSize is the maximum number of named statements whose
+	// analysis is cached.
+	maxAnalysisCacheSize = 1024
+
+	// DefaultMaxLoadSize is the default maximum size of the data loaded by
+	// a request to /db/load, after any decompression.
+	DefaultMaxLoadSize = 1 << 30
+
 	// VersionHTTPHeader is the HTTP header key for the version.
 	VersionHTTPHeader = "X-RQLITE-VERSION"
 
@@ -204,6 +275,27 @@ const (
 	// it wasn't served by this node.
 	ServedByHTTPHeader = "X-RQLITE-SERVED-BY"
 
+	// RequestIDHTTPHeader is the HTTP header carrying the ID of a request,
+	// which is logged with any messages about the request. If a client
+	// does not set it, the node generates an ID, and returns it in the
+	// response.
+	RequestIDHTTPHeader = "X-Request-ID"
+
+	// maxRequestIDLen is the maximum length of a request ID set by a client.
+	maxRequestIDLen = 128
+
+	// ContentTypeJSON is the media type of rqlite's JSON responses.
+	ContentTypeJSON = "application/json"
+
+	// ContentTypeCSV is the media type of CSV query responses.
+	ContentTypeCSV = "text/csv"
+
+	// ContentTypeNDJSON is the media type of newline-delimited JSON query responses.
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// ContentTypeArrowStream is the media type of Arrow IPC stream query responses.
+	ContentTypeArrowStream = "application/vnd.apache.arrow.stream"
+
 	// AllowOriginHeader is the HTTP header for allowing CORS compliant access from certain origins
 	AllowOriginHeader = "Access-Control-Allow-Origin"
 
@@ -217,6 +309,22 @@ const (
 	AllowCredentialsHeader = "Access-Control-Allow-Credentials"
 )
 
+// routeHists are the latency histograms of requests to each route, as
+// returned by httpRoute, served as part of stats with keys such as
+// db_execute_latency.
+var routeHists = func() map[string]*latency.Histogram {
+	m := make(map[string]*latency.Histogram)
+	for _, route := range []string{
+		"/db/execute", "/db/query", "/db/request", "/db/backup", "/db/load",
+		"/db/import", "/db/statements", "/db/sql", "/db/verify", "/auth/users",
+		"/boot", "/snapshot", "/reap", "/remove", "/status", "/nodes",
+		"/leader", "/readyz", "/metrics",
+	} {
+		m[route] = latency.NewHistogram(0)
+	}
+	return m
+}()
+
 func init() {
 	stats = expvar.NewMap("http")
 	ResetStats()
@@ -246,15 +354,29 @@ func ResetStats() {
 	stats.Add(numRequestStmtsRx, 0)
 	stats.Add(numReadyz, 0)
 	stats.Add(numStatus, 0)
+	stats.Add(numMetrics, 0)
+	stats.Add(numLogLevels, 0)
 	stats.Add(numBackups, 0)
+	stats.Add(numBackupUploads, 0)
 	stats.Add(numLoad, 0)
+	stats.Add(numImports, 0)
+	stats.Add(numImportRows, 0)
 	stats.Add(numBoot, 0)
 	stats.Add(numSnapshots, 0)
 	stats.Add(numReaps, 0)
 	stats.Add(numSQLAnalyze, 0)
+	stats.Add(numVerify, 0)
+	stats.Add(numStatements, 0)
+	stats.Add(numUsers, 0)
+	stats.Add(numRateLimited, 0)
+	stats.Add(numWritesThrottled, 0)
 	stats.Add(numAuthOK, 0)
 	stats.Add(numAuthFail, 0)
 	stats.Add(numTLSCertFetched, 0)
+	for route, h := range routeHists {
+		h.Reset()
+		stats.Set(strings.ReplaceAll(strings.TrimPrefix(route, "/"), "/", "_")+"_latency", h)
+	}
 }
 
 // Service provides HTTP service.
@@ -269,6 +391,8 @@ type Service struct {
 	store Store // The Raft-backed database store.
 	proxy *proxy.Proxy
 
+	analysisCache *sql.AnalysisCache // Analysis of named statements.
+
 	queueDone chan struct{}
 	stmtQueue *queue.Queue[*proto.Statement] // Queue for queued executes
 
@@ -280,6 +404,9 @@ type Service struct {
 	statusMu sync.RWMutex
 	statuses map[string]StatusReporter
 
+	uploaderMu     sync.RWMutex
+	backupUploader BackupUploader
+
 	CACertFile             string // Path to x509 CA certificate used to verify certificates.
 	CertFile               string // Path to server's own x509 certificate.
 	KeyFile                string // Path to server's own x509 private key.
@@ -302,7 +429,38 @@ type Service struct {
 
 	BuildInfo map[string]any
 
+	// Linter, if set, checks writes for non-determinism before they are
+	// sent to the Leader.
+	Linter *sql.Linter
+
+	// Catalog, if set, is consulted so that only INSERT statements which
+	// need them record values for non-deterministic column defaults.
+	Catalog sql.Catalog
+
+	// Authorizer, if set, checks that statements only access the tables
+	// permitted to the user.
+	Authorizer *sql.Authorizer
+
+	// UserManagement enables management of the users stored in the
+	// database, via /auth/users.
+	UserManagement bool
+
+	// RateLimiter, if set, limits the rate at which each user sends
+	// statements.
+	RateLimiter *auth.RateLimiter
+
+	// Metrics writes the metrics served at /metrics, in the Prometheus
+	// text exposition format.
+	Metrics *otlp.Prometheus
+
+	// MaxLoadSize is the maximum size of the data loaded by a request to
+	// /db/load, after any decompression. Zero means no limit.
+	MaxLoadSize int64
+
 	logger *log.Logger
+
+	// reqLogger logs messages about individual requests, with their IDs.
+	reqLogger *slog.Logger
 }
 
 // New returns an uninitialized HTTP service. If credentials is nil, then
@@ -319,7 +477,11 @@ func New(addr string, store Store, cluster Cluster, pxy *proxy.Proxy, credential
 		start:               time.Now(),
 		statuses:            make(map[string]StatusReporter),
 		credentialStore:     credentials,
-		logger:              log.New(os.Stderr, "[http] ", log.LstdFlags),
+		analysisCache:       sql.NewAnalysisCache(maxAnalysisCacheSize),
+		Metrics:             otlp.NewPrometheus(nil),
+		MaxLoadSize:         DefaultMaxLoadSize,
+		logger:              logging.New("http", os.Stderr),
+		reqLogger:           logging.Logger("http"),
 	}
 	s.uiHandler = http.StripPrefix("/console/", http.FileServerFS(console.Assets))
 	return s
@@ -390,7 +552,7 @@ func (s *Service) Start() error {
 	go func() {
 		err := s.httpServer.Serve(s.ln)
 		if err != nil {
-			s.logger.Printf("HTTP service on %s stopped: %s", s.ln.Addr().String(), err.Error())
+			logging.Errorf(s.logger, "HTTP service on %s stopped: %s", s.ln.Addr().String(), err.Error())
 		}
 	}()
 	s.logger.Println("service listening on", s.Addr())
@@ -402,7 +564,7 @@ func (s *Service) Start() error {
 func (s *Service) Close() {
 	s.logger.Println("closing HTTP service on", s.ln.Addr().String())
 	if err := s.httpServer.Shutdown(context.Background()); err != nil {
-		s.logger.Println("HTTP service shutdown error:", err.Error())
+		logging.Warnf(s.logger, "HTTP service shutdown error: %s", err.Error())
 	}
 
 	s.stmtQueue.Close()
@@ -446,6 +608,14 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w, r, endSpan := startSpan(w, r)
+	defer endSpan()
+	r = withRequestID(w, r)
+	if h, ok := routeHists[httpRoute(r.URL.Path)]; ok {
+		startT := time.Now()
+		defer h.RecordSince(startT)
+	}
+
 	params, err := NewQueryParams(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -471,15 +641,30 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(r.URL.Path, "/db/request"):
 		stats.Add(numRequests, 1)
 		s.handleRequest(w, r, params)
+	case strings.HasPrefix(r.URL.Path, "/db/backup/upload"):
+		stats.Add(numBackupUploads, 1)
+		s.handleBackupUpload(w, r, params)
 	case strings.HasPrefix(r.URL.Path, "/db/backup"):
 		stats.Add(numBackups, 1)
 		s.handleBackup(w, r, params)
 	case strings.HasPrefix(r.URL.Path, "/db/load"):
 		stats.Add(numLoad, 1)
 		s.handleLoad(w, r, params)
+	case strings.HasPrefix(r.URL.Path, "/db/import"):
+		stats.Add(numImports, 1)
+		s.handleImport(w, r, params)
+	case strings.HasPrefix(r.URL.Path, "/db/statements"):
+		stats.Add(numStatements, 1)
+		s.handleStatements(w, r, params)
+	case strings.HasPrefix(r.URL.Path, "/auth/users"):
+		stats.Add(numUsers, 1)
+		s.handleUsers(w, r, params)
 	case strings.HasPrefix(r.URL.Path, "/db/sql"):
 		stats.Add(numSQLAnalyze, 1)
 		s.handleSQLAnalyze(w, r, params)
+	case strings.HasPrefix(r.URL.Path, "/db/verify"):
+		stats.Add(numVerify, 1)
+		s.handleVerify(w, r, params)
 	case r.URL.Path == "/boot":
 		stats.Add(numBoot, 1)
 		s.handleBoot(w, r)
@@ -503,6 +688,12 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleReadyz(w, r, params)
 	case r.URL.Path == "/licenses":
 		s.handleLicenses(w, r, params)
+	case r.URL.Path == "/metrics":
+		stats.Add(numMetrics, 1)
+		s.handleMetrics(w, r)
+	case r.URL.Path == "/admin/log-levels":
+		stats.Add(numLogLevels, 1)
+		s.handleLogLevels(w, r, params)
 	case r.URL.Path == "/debug/vars":
 		s.handleExpvar(w, r, params)
 	case strings.HasPrefix(r.URL.Path, "/debug/pprof"):
@@ -512,6 +703,17 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// withRequestID returns r, with the ID set by the client, or a new one, in
+// its context. The ID is returned to the client.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(RequestIDHTTPHeader)
+	if id == "" || len(id) > maxRequestIDLen {
+		id = random.StringN(16)
+	}
+	w.Header().Set(RequestIDHTTPHeader, id)
+	return r.WithContext(logging.WithRequestID(r.Context(), id))
+}
+
 // RegisterStatus allows other modules to register status for serving over HTTP.
 func (s *Service) RegisterStatus(key string, stat StatusReporter) error {
 	s.statusMu.Lock()
@@ -524,6 +726,14 @@ func (s *Service) RegisterStatus(key string, stat StatusReporter) error {
 	return nil
 }
 
+// SetBackupUploader sets the uploader used to service on-demand backup
+// uploads. If no uploader is set, such requests are rejected.
+func (s *Service) SetBackupUploader(u BackupUploader) {
+	s.uploaderMu.Lock()
+	defer s.uploaderMu.Unlock()
+	s.backupUploader = u
+}
+
 // handleRemove handles cluster-remove requests.
 func (s *Service) handleRemove(w http.ResponseWriter, r *http.Request, qp QueryParams) {
 	if !s.CheckRequestPerm(r, auth.PermRemove) {
@@ -562,7 +772,7 @@ func (s *Service) handleRemove(w http.ResponseWriter, r *http.Request, qp QueryP
 		Id: remoteID,
 	}
 
-	addr, err := s.proxy.Remove(r.Context(), rn, makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
+	addr, err := s.proxy.Remove(r.Context(), rn, s.makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
 	if err != nil {
 		if errors.Is(err, proxy.ErrNotLeader) {
 			s.DoRedirect(w, r, qp)
@@ -667,15 +877,16 @@ func (s *Service) handleBackup(w http.ResponseWriter, r *http.Request, qp QueryP
 	}
 
 	br := &proto.BackupRequest{
-		Format:   qp.BackupFormat(),
-		Leader:   !qp.NoLeader(),
-		Vacuum:   qp.Vacuum(),
-		Compress: qp.Compress(),
-		Tables:   qp.Tables(),
+		Format:      qp.BackupFormat(),
+		Leader:      !qp.NoLeader(),
+		Vacuum:      qp.Vacuum(),
+		Compress:    qp.Compress(),
+		Compression: qp.BackupCompression(),
+		Tables:      qp.Tables(),
 	}
 	addBackupFormatHeader(w, qp)
 
-	addr, err := s.proxy.Backup(r.Context(), br, w, makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
+	addr, err := s.proxy.Backup(r.Context(), br, w, s.makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
 	if err != nil {
 		if errors.Is(err, proxy.ErrNotLeader) {
 			s.DoRedirect(w, r, qp)
@@ -702,6 +913,53 @@ func (s *Service) handleBackup(w http.ResponseWriter, r *http.Request, qp QueryP
 	s.lastBackup = time.Now()
 }
 
+// handleBackupUpload uploads a backup to the storage service configured for
+// automatic backups, and returns the key and manifest of the upload.
+func (s *Service) handleBackupUpload(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	if !s.CheckRequestPerm(r, auth.PermBackup) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.uploaderMu.RLock()
+	u := s.backupUploader
+	s.uploaderMu.RUnlock()
+	if u == nil {
+		http.Error(w, "automatic backups not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), qp.Timeout(defaultTimeout))
+	defer cancel()
+	res, err := u.UploadNow(ctx, qp.Name(), qp.Suffix())
+	if err != nil {
+		if errors.Is(err, backup.ErrUploadDisabled) {
+			if s.DoRedirect(w, r, qp) {
+				return
+			}
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, backup.ErrInvalidName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.lastBackup = time.Now()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // handleLoad loads the database from the given SQLite database file or SQLite dump.
 func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request, qp QueryParams) {
 	if !s.CheckRequestPerm(r, auth.PermLoad) {
@@ -732,20 +990,38 @@ func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request, qp QueryPar
 	}
 
 	resp := NewResponse()
-	b, err := io.ReadAll(r.Body)
+	f, compression, err := rarchive.SpoolDecompressed(r.Body, s.MaxLoadSize)
 	if err != nil {
+		if errors.Is(err, rarchive.ErrTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	defer os.Remove(f.Name())
+	defer f.Close()
 	r.Body.Close()
+	if compression != rarchive.CompressionNone {
+		s.reqLogger.InfoContext(r.Context(), "compressed load data detected", "compression", compression)
+	}
 
-	if db.IsValidSQLiteData(b) {
-		s.logger.Printf("SQLite database file detected as load data")
-		lr := &proto.LoadRequest{
-			Data: b,
-		}
+	// The header is enough to tell a SQLite file from SQL.
+	hdr := make([]byte, 16)
+	n, err := io.ReadFull(f, hdr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		addr, err := s.proxy.Load(r.Context(), lr, makeCredentials(r), qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
+	if db.IsValidSQLiteData(hdr[:n]) {
+		s.reqLogger.InfoContext(r.Context(), "SQLite database file detected as load data")
+		addr, err := s.proxy.LoadFrom(r.Context(), f, loadChunkSize, s.makeCredentials(r),
+			qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
 		if err != nil {
 			if handleProxyErr(err) {
 				return
@@ -756,11 +1032,24 @@ func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request, qp QueryPar
 		w.Header().Set(ServedByHTTPHeader, addr)
 	} else {
 		// No JSON structure expected for this API, just a bunch of SQL statements.
+		// They are executed in a single request, so must be read into memory.
+		b, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		queries := []string{string(b)}
 		er := executeRequestFromStrings(queries, qp.Timings(), false)
 		er.Request.RollbackOnError = true
+		if t := sql.ReferencesSystemTable(string(b)); t != "" {
+			http.Error(w, fmt.Sprintf("load of table %s not permitted", t), http.StatusForbidden)
+			return
+		}
+		if !s.authorize(w, r, er.Request.Statements) {
+			return
+		}
 
-		response, _, addr, resultsErr := s.proxy.Execute(r.Context(), er, makeCredentials(r),
+		response, _, addr, resultsErr := s.proxy.Execute(r.Context(), er, s.makeCredentials(r),
 			qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
 		if resultsErr != nil {
 			if handleProxyErr(resultsErr) {
@@ -776,6 +1065,240 @@ func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request, qp QueryPar
 	s.writeResponse(w, qp, resp)
 }
 
+// ImportResponse is the response to an import request. When progress is
+// requested, a response with Progress set is also written after each batch.
+type ImportResponse struct {
+	Table    string  `json:"table"`
+	Created  bool    `json:"created,omitempty"`
+	Rows     int64   `json:"rows"`
+	Batches  int64   `json:"batches"`
+	Progress bool    `json:"progress,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Time     float64 `json:"time"`
+}
+
+// handleImport imports CSV or NDJSON data into a table. The data is converted
+// into batches of INSERT statements, each batch sent to the Leader in chunks,
+// using command/chunking, and executed as a transaction once its last chunk is
+// applied. An import is therefore not atomic: if it fails part way through, the
+// batches already executed remain committed, and the response reports how
+// many rows were imported. If progress is requested, the response is NDJSON,
+// with a progress line after each batch, and the final line reporting the
+// result. Since the status is then sent with the first line, a failure after
+// the first batch is reported only by the final line. The tables managed by
+// rqlite may not be imported into, and the generated statements are subject
+// to the same authorization as statements sent to /db/execute.
+func (s *Service) handleImport(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	if !s.CheckRequestPermAll(r, auth.PermExecute, auth.PermQuery) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	handleProxyErr := func(err error) bool {
+		if errors.Is(err, proxy.ErrNotLeader) {
+			s.DoRedirect(w, r, qp)
+			return true
+		}
+		if errors.Is(err, proxy.ErrLeaderNotFound) {
+			stats.Add(numLeaderNotFound, 1)
+			http.Error(w, proxy.ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return true
+		}
+		if errors.Is(err, proxy.ErrUnauthorized) {
+			http.Error(w, "remote import not authorized", http.StatusUnauthorized)
+			return true
+		}
+		return false
+	}
+
+	start := time.Now()
+	resp := &ImportResponse{Table: qp.Table()}
+	streaming := false
+	writeImportResponse := func(code int) {
+		resp.Time = time.Since(start).Seconds()
+		if !streaming {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(code)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.reqLogger.ErrorContext(r.Context(), "failed to write import response", "error", err)
+		}
+	}
+	writeImportProgress := func() {
+		if !streaming {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			streaming = true
+		}
+		p := *resp
+		p.Progress = true
+		p.Time = time.Since(start).Seconds()
+		if err := json.NewEncoder(w).Encode(&p); err != nil {
+			s.reqLogger.ErrorContext(r.Context(), "failed to write import progress", "error", err)
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	if t := sql.ReferencesSystemTable(qp.Table()); t != "" {
+		http.Error(w, fmt.Sprintf("import into table %s not permitted", t), http.StatusForbidden)
+		return
+	}
+
+	rc, _, err := rarchive.NewDecompressReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer rc.Close()
+
+	imp, err := importer.New(rc, &importer.Config{
+		Table:      qp.Table(),
+		Format:     qp.ImportFormat(),
+		Mapping:    qp.Mapping(),
+		OnConflict: qp.OnConflict(),
+		BatchRows:  qp.BatchSize(importer.DefaultBatchRows),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Check the client may insert into the destination table before revealing
+	// anything about it.
+	if !s.authorize(w, r, []*proto.Statement{imp.AccessStatement()}) {
+		return
+	}
+
+	// Learn the columns of the destination table, creating it if requested.
+	qr := &proto.QueryRequest{
+		Request: &proto.Request{
+			Statements: []*proto.Statement{
+				{
+					Sql: "SELECT name, type FROM pragma_table_info(?)",
+					Parameters: []*proto.Parameter{
+						{Value: &proto.Parameter_S{S: qp.Table()}},
+					},
+				},
+			},
+		},
+		Level: proto.ConsistencyLevel_WEAK,
+	}
+	rows, _, _, err := s.proxy.Query(r.Context(), qr, s.makeCredentials(r),
+		qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
+	if err != nil {
+		if handleProxyErr(err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(rows) != 1 || rows[0].Error != "" {
+		http.Error(w, "failed to read table columns", http.StatusInternalServerError)
+		return
+	}
+	var cols []importer.Column
+	for _, v := range rows[0].Values {
+		if len(v.Parameters) != 2 {
+			continue
+		}
+		cols = append(cols, importer.Column{
+			Name: v.Parameters[0].GetS(),
+			Type: v.Parameters[1].GetS(),
+		})
+	}
+
+	if len(cols) == 0 {
+		if !qp.Create() {
+			http.Error(w, fmt.Sprintf("table %s does not exist", qp.Table()), http.StatusBadRequest)
+			return
+		}
+		cols, err = imp.InferColumns()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		er := &proto.ExecuteRequest{
+			Request: &proto.Request{
+				Statements: []*proto.Statement{imp.CreateTableStatement(cols)},
+			},
+		}
+		if !s.authorize(w, r, er.Request.Statements) {
+			return
+		}
+		results, _, _, err := s.proxy.Execute(r.Context(), er, s.makeCredentials(r),
+			qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
+		if err != nil {
+			if handleProxyErr(err) {
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if errMsg := command.ExecuteQueryResponsesError(results); errMsg != "" {
+			http.Error(w, errMsg, http.StatusInternalServerError)
+			return
+		}
+		resp.Created = true
+	}
+	if err := imp.SetColumns(cols); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastProgress := time.Now()
+	for {
+		er, err := imp.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			writeImportResponse(http.StatusBadRequest)
+			return
+		}
+		if code, err := s.checkAuthorized(r, er.Request.Statements); err != nil {
+			resp.Error = err.Error()
+			writeImportResponse(code)
+			return
+		}
+
+		addr, err := s.proxy.Import(r.Context(), imp.AccessStatement(), er.Request, loadChunkSize,
+			s.makeCredentials(r), qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
+		if err != nil {
+			if resp.Batches == 0 && handleProxyErr(err) {
+				return
+			}
+			resp.Error = err.Error()
+			writeImportResponse(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(ServedByHTTPHeader, addr)
+
+		n := int64(len(er.Request.Statements))
+		resp.Rows += n
+		resp.Batches++
+		stats.Add(numImportRows, n)
+		if qp.Progress() {
+			writeImportProgress()
+		}
+		if time.Since(lastProgress) >= 10*time.Second {
+			s.reqLogger.InfoContext(r.Context(), "import in progress",
+				"table", qp.Table(), "rows", resp.Rows, "batches", resp.Batches)
+			lastProgress = time.Now()
+		}
+	}
+	s.reqLogger.InfoContext(r.Context(), "import complete",
+		"table", qp.Table(), "rows", resp.Rows, "batches", resp.Batches)
+	writeImportResponse(http.StatusOK)
+}
+
 // handleBoot handles booting this node using a SQLite file.
 func (s *Service) handleBoot(w http.ResponseWriter, r *http.Request) {
 	if !s.CheckRequestPerm(r, auth.PermLoad) {
@@ -799,7 +1322,7 @@ func (s *Service) handleBoot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.logger.Printf("starting boot process")
+	s.reqLogger.InfoContext(r.Context(), "starting boot process")
 	_, err = s.store.ReadFrom(bufReader)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -945,6 +1468,7 @@ func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request, qp QueryP
 		"store":   storeStatus,
 		"http":    httpStatus,
 		"node":    nodeStatus,
+		"latency": latency.Stats(),
 	}
 	if !s.lastBackup.IsZero() {
 		status["last_backup_time"] = s.lastBackup
@@ -960,7 +1484,7 @@ func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request, qp QueryP
 		for k, v := range s.statuses {
 			stat, err := v.Stats()
 			if err != nil {
-				s.logger.Printf("failed to retrieve stats for registered reporter %s: %s", k, err.Error())
+				logging.Warnf(s.logger, "failed to retrieve stats for registered reporter %s: %s", k, err.Error())
 				stat = map[string]any{"error": err.Error()}
 			}
 			status[k] = stat
@@ -988,7 +1512,7 @@ func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request, qp QueryP
 
 	_, err = w.Write(b)
 	if err != nil {
-		s.logger.Println("failed to write status to client", err.Error())
+		logging.Warnf(s.logger, "failed to write status to client: %s", err.Error())
 		return
 	}
 }
@@ -1091,7 +1615,7 @@ func (s *Service) handleLeader(w http.ResponseWriter, r *http.Request, qp QueryP
 		}
 		_, err = w.Write(b)
 		if err != nil {
-			s.logger.Println("failed to write leader node to client", err.Error())
+			logging.Warnf(s.logger, "failed to write leader node to client: %s", err.Error())
 			return
 		}
 
@@ -1112,7 +1636,7 @@ func (s *Service) handleLeader(w http.ResponseWriter, r *http.Request, qp QueryP
 			nodeID = reqBody.ID
 		}
 
-		addr, err := s.proxy.Stepdown(r.Context(), wait, nodeID, makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
+		addr, err := s.proxy.Stepdown(r.Context(), wait, nodeID, s.makeCredentials(r), qp.Timeout(defaultTimeout), qp.Redirect())
 		if err != nil {
 			if errors.Is(err, proxy.ErrNotLeader) {
 				s.DoRedirect(w, r, qp)
@@ -1173,7 +1697,7 @@ func (s *Service) handleReadyz(w http.ResponseWriter, r *http.Request, qp QueryP
 		return
 	}
 
-	_, err = s.cluster.GetNodeMeta(r.Context(), lAddr, qp.Retries(0), qp.Timeout(defaultTimeout))
+	meta, err := s.cluster.GetNodeMeta(r.Context(), lAddr, qp.Retries(0), qp.Timeout(defaultTimeout))
 	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		w.Write(fmt.Appendf(nil, "[+]node ok\n[+]leader not contactable: %s", err.Error()))
@@ -1196,6 +1720,17 @@ func (s *Service) handleReadyz(w http.ResponseWriter, r *http.Request, qp QueryP
 		okMsg += "\n[+]sync ok"
 	}
 
+	if qp.Writable() {
+		// Writes are throttled by the Leader, so report its throttle.
+		if meta.GetWriteRetryAfter() > 0 {
+			writeRetryAfterHeader(w, time.Duration(meta.GetWriteRetryAfter()))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(fmt.Appendf(nil, "%s\n[+]writes throttled at level %d", okMsg, meta.GetWriteThrottleLevel()))
+			return
+		}
+		okMsg += fmt.Sprintf("\n[+]writable ok, throttle level %d", meta.GetWriteThrottleLevel())
+	}
+
 	qr := &proto.QueryRequest{
 		Request: &proto.Request{
 			DbTimeout:  qp.Timeout(defaultTimeout).Nanoseconds(),
@@ -1203,7 +1738,7 @@ func (s *Service) handleReadyz(w http.ResponseWriter, r *http.Request, qp QueryP
 		},
 		Level: proto.ConsistencyLevel_NONE,
 	}
-	results, _, _, err := s.proxy.Query(r.Context(), qr, makeCredentials(r),
+	results, _, _, err := s.proxy.Query(r.Context(), qr, s.makeCredentials(r),
 		qp.Timeout(defaultTimeout), 0, false)
 	if err == nil && len(results) == 1 && len(results[0].Values) == 1 &&
 		len(results[0].Values[0].GetParameters()) == 1 && results[0].Values[0].GetParameters()[0].GetI() == 1 {
@@ -1253,7 +1788,8 @@ func (s *Service) handleUI(w http.ResponseWriter, r *http.Request) {
 func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request, qp QueryParams) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	if !s.CheckRequestPerm(r, auth.PermExecute) {
+	ok, namedOnly := s.CheckRequestPermOrNamed(r, auth.PermExecute)
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1265,14 +1801,14 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request, qp Query
 
 	if qp.Queue() {
 		stats.Add(numQueuedExecutions, 1)
-		s.queuedExecute(w, r, qp)
+		s.queuedExecute(w, r, qp, namedOnly)
 	} else {
-		s.execute(w, r, qp)
+		s.execute(w, r, qp, namedOnly)
 	}
 }
 
 // queuedExecute handles queued queries that modify the database.
-func (s *Service) queuedExecute(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+func (s *Service) queuedExecute(w http.ResponseWriter, r *http.Request, qp QueryParams, namedOnly bool) {
 	resp := NewResponse()
 
 	// Perform a leader check, unless disabled. This prevents generating queued writes on
@@ -1297,10 +1833,20 @@ func (s *Service) queuedExecute(w http.ResponseWriter, r *http.Request, qp Query
 			return
 		}
 	}
-	if err := sql.Process(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime()); err != nil {
+	if namedOnly && !allNamed(stmts) {
+		http.Error(w, errNamedOnly.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.expandNamed(w, stmts) {
+		return
+	}
+	if err := sql.ProcessWithCatalog(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime(), s.Catalog); err != nil {
 		http.Error(w, fmt.Sprintf("SQL rewrite: %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
+	if !s.lint(w, stmts) || !s.authorize(w, r, stmts) || !s.rateLimit(w, r, stmts, true) {
+		return
+	}
 
 	var fc queue.FlushChannel
 	if qp.Wait() {
@@ -1332,7 +1878,7 @@ func (s *Service) queuedExecute(w http.ResponseWriter, r *http.Request, qp Query
 }
 
 // execute handles queries that modify the database.
-func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams, namedOnly bool) {
 	resp := NewResponse()
 	resp.Results.AssociativeJSON = qp.Associative()
 	resp.Results.BlobsAsArrays = qp.BlobArray()
@@ -1358,12 +1904,22 @@ func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams
 		}
 	}
 	stats.Add(numExecuteStmtsRx, int64(len(stmts)))
+	if namedOnly && !allNamed(stmts) {
+		http.Error(w, errNamedOnly.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.expandNamed(w, stmts) {
+		return
+	}
 	if !qp.NoParse() {
-		if err := sql.Process(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime()); err != nil {
+		if err := sql.ProcessWithCatalog(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime(), s.Catalog); err != nil {
 			http.Error(w, fmt.Sprintf("SQL rewrite: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
 	}
+	if !s.lint(w, stmts) || !s.authorize(w, r, stmts) || !s.rateLimit(w, r, stmts, true) {
+		return
+	}
 
 	er := &proto.ExecuteRequest{
 		Request: &proto.Request{
@@ -1374,7 +1930,7 @@ func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams
 		Timings: qp.Timings(),
 	}
 
-	results, raftIndex, addr, resultsErr := s.proxy.Execute(r.Context(), er, makeCredentials(r),
+	results, raftIndex, addr, resultsErr := s.proxy.Execute(r.Context(), er, s.makeCredentials(r),
 		qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
 	if resultsErr != nil {
 		if errors.Is(resultsErr, proxy.ErrNotLeader) {
@@ -1390,6 +1946,10 @@ func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams
 			http.Error(w, "remote Execute not authorized", http.StatusUnauthorized)
 			return
 		}
+		if d, ok := retryAfter(resultsErr); ok {
+			writeTooManyRequests(w, d, resultsErr.Error())
+			return
+		}
 	}
 
 	if resultsErr != nil {
@@ -1409,7 +1969,8 @@ func (s *Service) execute(w http.ResponseWriter, r *http.Request, qp QueryParams
 func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request, qp QueryParams) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	if !s.CheckRequestPerm(r, auth.PermQuery) {
+	ok, namedOnly := s.CheckRequestPermOrNamed(r, auth.PermQuery)
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1441,14 +2002,29 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request, qp QueryPa
 		}
 	}
 	stats.Add(numQueryStmtsRx, int64(len(queries)))
+	if namedOnly && !allNamed(queries) {
+		http.Error(w, errNamedOnly.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.expandNamed(w, queries) {
+		return
+	}
 
 	if !qp.NoParse() {
 		rLvl := qp.Level() == proto.ConsistencyLevel_STRONG
-		if err := sql.Process(queries, !qp.NoRewriteRandom() && rLvl, !qp.NoRewriteTime() && rLvl); err != nil {
+		if err := sql.ProcessWithCatalog(queries, !qp.NoRewriteRandom() && rLvl, !qp.NoRewriteTime() && rLvl, s.Catalog); err != nil {
 			http.Error(w, fmt.Sprintf("SQL rewrite: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
 	}
+	ct := negotiateContentType(r)
+	if ct != ContentTypeJSON && len(queries) != 1 {
+		http.Error(w, fmt.Sprintf("%s responses require exactly one query", ct), http.StatusBadRequest)
+		return
+	}
+	if !s.authorize(w, r, queries) || !s.rateLimit(w, r, queries, false) {
+		return
+	}
 
 	resp := NewResponse()
 	resp.Results.AssociativeJSON = qp.Associative()
@@ -1468,7 +2044,7 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request, qp QueryPa
 		LinearizableTimeout: qp.LinearizableTimeout(defaultLinearTimeout).Nanoseconds(),
 	}
 
-	results, raftIndex, addr, resultsErr := s.proxy.Query(r.Context(), qr, makeCredentials(r),
+	results, raftIndex, addr, resultsErr := s.proxy.Query(r.Context(), qr, s.makeCredentials(r),
 		qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
 	if resultsErr != nil {
 		if errors.Is(resultsErr, proxy.ErrNotLeader) {
@@ -1484,6 +2060,24 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request, qp QueryPa
 			http.Error(w, "remote query not authorized", http.StatusUnauthorized)
 			return
 		}
+		if d, ok := retryAfter(resultsErr); ok {
+			writeTooManyRequests(w, d, resultsErr.Error())
+			return
+		}
+	}
+
+	if ct != ContentTypeJSON {
+		if resultsErr != nil {
+			http.Error(w, resultsErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(ServedByHTTPHeader, addr)
+		if len(results) != 1 {
+			http.Error(w, "query returned no results", http.StatusInternalServerError)
+			return
+		}
+		s.writeRowsResponse(w, ct, qp, results[0])
+		return
 	}
 
 	if resultsErr != nil {
@@ -1502,7 +2096,8 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request, qp QueryPa
 func (s *Service) handleRequest(w http.ResponseWriter, r *http.Request, qp QueryParams) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	if !s.CheckRequestPermAll(r, auth.PermQuery, auth.PermExecute) {
+	ok, namedOnly := s.CheckRequestPermOrNamed(r, auth.PermQuery, auth.PermExecute)
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1518,18 +2113,21 @@ func (s *Service) handleRequest(w http.ResponseWriter, r *http.Request, qp Query
 		return
 	}
 	stats.Add(numRequestStmtsRx, int64(len(stmts)))
+	if namedOnly && !allNamed(stmts) {
+		http.Error(w, errNamedOnly.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.expandNamed(w, stmts) {
+		return
+	}
 
 	if !qp.NoParse() {
-		if err := sql.Process(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime()); err != nil {
+		if err := sql.ProcessWithCatalog(stmts, !qp.NoRewriteRandom(), !qp.NoRewriteTime(), s.Catalog); err != nil {
 			http.Error(w, fmt.Sprintf("SQL rewrite: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
 	}
 
-	resp := NewResponse()
-	resp.Results.AssociativeJSON = qp.Associative()
-	resp.Results.BlobsAsArrays = qp.BlobArray()
-
 	eqr := &proto.ExecuteQueryRequest{
 		Request: &proto.Request{
 			Transaction:    qp.Tx(),
@@ -1543,7 +2141,28 @@ func (s *Service) handleRequest(w http.ResponseWriter, r *http.Request, qp Query
 		FreshnessStrict: qp.FreshnessStrict(),
 	}
 
-	results, _, raftIndex, addr, resultsErr := s.proxy.Request(r.Context(), eqr, makeCredentials(r),
+	// Formats other than JSON hold the rows of a single statement, so check
+	// the request can be answered before anything is executed.
+	ct := negotiateContentType(r)
+	if ct != ContentTypeJSON {
+		if len(stmts) != 1 {
+			http.Error(w, fmt.Sprintf("%s responses require exactly one statement", ct), http.StatusBadRequest)
+			return
+		}
+		if _, nRO := s.store.RORWCount(eqr); nRO != 1 && !stmts[0].ForceQuery {
+			http.Error(w, fmt.Sprintf("%s responses require a statement which returns rows", ct), http.StatusBadRequest)
+			return
+		}
+	}
+	if !s.lint(w, stmts) || !s.authorize(w, r, stmts) || !s.rateLimit(w, r, stmts, true) {
+		return
+	}
+
+	resp := NewResponse()
+	resp.Results.AssociativeJSON = qp.Associative()
+	resp.Results.BlobsAsArrays = qp.BlobArray()
+
+	results, _, raftIndex, addr, resultsErr := s.proxy.Request(r.Context(), eqr, s.makeCredentials(r),
 		qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
 	if resultsErr != nil {
 		if errors.Is(resultsErr, proxy.ErrNotLeader) {
@@ -1559,6 +2178,33 @@ func (s *Service) handleRequest(w http.ResponseWriter, r *http.Request, qp Query
 			http.Error(w, "remote Request not authorized", http.StatusUnauthorized)
 			return
 		}
+		if d, ok := retryAfter(resultsErr); ok {
+			writeTooManyRequests(w, d, resultsErr.Error())
+			return
+		}
+	}
+
+	if ct != ContentTypeJSON {
+		if resultsErr != nil {
+			http.Error(w, resultsErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(ServedByHTTPHeader, addr)
+		if len(results) != 1 {
+			http.Error(w, "request returned no results", http.StatusInternalServerError)
+			return
+		}
+		if e := results[0].GetError(); e != "" {
+			http.Error(w, e, http.StatusBadRequest)
+			return
+		}
+		rows := results[0].GetQ()
+		if rows == nil {
+			http.Error(w, "statement returned no rows", http.StatusInternalServerError)
+			return
+		}
+		s.writeRowsResponse(w, ct, qp, rows)
+		return
 	}
 
 	if resultsErr != nil {
@@ -1597,6 +2243,87 @@ func (s *Service) handleExpvar(w http.ResponseWriter, r *http.Request, qp QueryP
 	fmt.Fprintf(w, "\n}\n")
 }
 
+// handleMetrics serves all metrics in the Prometheus text exposition format.
+func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, auth.PermStatus) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", otlp.PrometheusContentType)
+	if err := s.Metrics.Write(w); err != nil {
+		s.reqLogger.ErrorContext(r.Context(), "failed to write metrics", "error", err)
+	}
+}
+
+// handleLogLevels serves the log levels of the node's subsystems, and
+// changes them if the request is a PUT or POST of subsystem-level pairs.
+// An empty level removes the level of a subsystem, so that it logs at the
+// default level.
+func (s *Service) handleLogLevels(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	switch r.Method {
+	case http.MethodGet:
+		if !s.CheckRequestPerm(r, auth.PermStatus) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case http.MethodPut, http.MethodPost:
+		if !s.CheckRequestPerm(r, auth.PermAll) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid log levels: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		levels := make(map[string]slog.Level, len(req))
+		for sub, lvl := range req {
+			if lvl == "" {
+				continue
+			}
+			l, err := logging.ParseLevel(lvl)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid level for subsystem %s: %s", sub, err.Error()),
+					http.StatusBadRequest)
+				return
+			}
+			levels[sub] = l
+		}
+		for sub, lvl := range req {
+			if l, ok := levels[sub]; ok {
+				logging.SetLevel(sub, l)
+			} else if sub != logging.DefaultSubsystem {
+				logging.ClearLevel(sub)
+			}
+			s.reqLogger.InfoContext(r.Context(), "log level changed", "target", sub, "level", lvl)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var b []byte
+	var err error
+	if qp.Pretty() {
+		b, err = json.MarshalIndent(logging.Levels(), "", "    ")
+	} else {
+		b, err = json.Marshal(logging.Levels())
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("JSON marshal: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
 // handlePprof serves pprof information over HTTP.
 func (s *Service) handlePprof(w http.ResponseWriter, r *http.Request) {
 	if !s.CheckRequestPerm(r, auth.PermStatus) {
@@ -1653,6 +2380,110 @@ func (s *Service) FormRedirect(r *http.Request) (string, error) {
 	return fmt.Sprintf("%s%s%s", leaderAPIAddr, r.URL.Path, rq), nil
 }
 
+// lint checks the given statements with the Linter. If the statements are
+// rejected, an error is written to w and false is returned.
+func (s *Service) lint(w http.ResponseWriter, stmts []*proto.Statement) bool {
+	err := s.Linter.Check(stmts, s.store.NamedStatement)
+	if err == nil {
+		return true
+	}
+	var lintErr *sql.LintError
+	if errors.As(err, &lintErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	http.Error(w, fmt.Sprintf("SQL lint: %s", err.Error()), http.StatusInternalServerError)
+	return false
+}
+
+// rateLimit charges the given statements to the rate limit of the user making
+// the request. The size of the statements is charged as bytes written if
+// write is true. If the limit is exceeded, 429 Too Many Requests is written
+// to w and false is returned.
+func (s *Service) rateLimit(w http.ResponseWriter, r *http.Request, stmts []*proto.Statement, write bool) bool {
+	if s.RateLimiter == nil {
+		return true
+	}
+	var n int
+	if write {
+		n = pb.Size(&proto.Request{Statements: stmts})
+	}
+	err := s.RateLimiter.Allow(requestClient(r), len(stmts), n)
+	if err == nil {
+		return true
+	}
+	var rlErr *auth.RateLimitError
+	if errors.As(err, &rlErr) {
+		stats.Add(numRateLimited, 1)
+		writeTooManyRequests(w, rlErr.RetryAfter, rlErr.Error())
+		return false
+	}
+	http.Error(w, fmt.Sprintf("rate limit: %s", err.Error()), http.StatusInternalServerError)
+	return false
+}
+
+// retryAfter returns how long the client should wait before retrying, if err
+// refuses the request because writes are being throttled.
+func retryAfter(err error) (time.Duration, bool) {
+	var thrErr *store.ThrottledError
+	if errors.As(err, &thrErr) {
+		stats.Add(numWritesThrottled, 1)
+		return thrErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// writeTooManyRequests writes 429 Too Many Requests to w, with a Retry-After
+// header telling the client how long to wait, in whole seconds.
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	writeRetryAfterHeader(w, retryAfter)
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
+
+// writeRetryAfterHeader sets the Retry-After header of w, in whole seconds.
+func writeRetryAfterHeader(w http.ResponseWriter, retryAfter time.Duration) {
+	secs := int64(math.Ceil(retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// authorize checks the given statements with the Authorizer, for the user
+// making the request. If any are denied, an error is written to w and false
+// is returned.
+func (s *Service) authorize(w http.ResponseWriter, r *http.Request, stmts []*proto.Statement) bool {
+	code, err := s.checkAuthorized(r, stmts)
+	if err == nil {
+		return true
+	}
+	http.Error(w, err.Error(), code)
+	return false
+}
+
+// checkAuthorized checks that the client making the request may access the
+// tables the given statements access. If it may not, it returns an error, and
+// the status with which the request should be refused.
+func (s *Service) checkAuthorized(r *http.Request, stmts []*proto.Statement) (int, error) {
+	var err error
+	username, password, basic := r.BasicAuth()
+	if token := bearerToken(r); token != "" {
+		err = s.Authorizer.CheckToken(token, stmts, s.store.NamedStatement)
+	} else if cert := clientCert(r); !basic && cert != nil {
+		err = s.Authorizer.CheckCert(cert, stmts, s.store.NamedStatement)
+	} else {
+		err = s.Authorizer.Check(username, password, stmts, s.store.NamedStatement)
+	}
+	if err == nil {
+		return http.StatusOK, nil
+	}
+	var aclErr *sql.ACLError
+	if errors.As(err, &aclErr) {
+		return http.StatusForbidden, err
+	}
+	return http.StatusInternalServerError, fmt.Errorf("SQL authorization: %w", err)
+}
+
 // CheckRequestPerm checks if the request is authenticated and authorized
 // with the given Perm.
 func (s *Service) CheckRequestPerm(r *http.Request, perm string) (b bool) {
@@ -1669,12 +2500,7 @@ func (s *Service) CheckRequestPerm(r *http.Request, perm string) (b bool) {
 		return true
 	}
 
-	username, password, ok := r.BasicAuth()
-	if !ok {
-		username = ""
-	}
-
-	return s.credentialStore.AA(username, password, perm)
+	return s.aa(r, perm)
 }
 
 // CheckRequestPermAll checks if the request is authenticated and authorized
@@ -1693,19 +2519,33 @@ func (s *Service) CheckRequestPermAll(r *http.Request, perms ...string) (b bool)
 		return true
 	}
 
-	username, password, ok := r.BasicAuth()
-	if !ok {
-		username = ""
-	}
-
 	for _, perm := range perms {
-		if !s.credentialStore.AA(username, password, perm) {
+		if !s.aa(r, perm) {
 			return false
 		}
 	}
 	return true
 }
 
+// aa authenticates the request and checks authorization for the given perm.
+// A bearer token takes precedence over Basic auth, which takes precedence
+// over a verified client certificate.
+func (s *Service) aa(r *http.Request, perm string) bool {
+	if token := bearerToken(r); token != "" {
+		return s.credentialStore.AAToken(token, perm)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		if cert := clientCert(r); cert != nil {
+			return s.credentialStore.AACert(cert, perm)
+		}
+		username = ""
+	}
+
+	return s.credentialStore.AA(username, password, perm)
+}
+
 // LeaderAddr returns the Raft address of the leader, as known by this node.
 func (s *Service) LeaderAddr(ctx context.Context) (string, error) {
 	ldr, err := s.store.Leader()
@@ -1864,10 +2704,85 @@ func (s *Service) writeResponse(w http.ResponseWriter, qp QueryParams, j Respons
 	}
 	_, err = w.Write(b)
 	if err != nil {
-		s.logger.Println("writing response failed:", err.Error())
+		logging.Warnf(s.logger, "writing response failed: %s", err.Error())
 	}
 }
 
+// writeRowsResponse writes a single set of query results in a non-JSON format.
+// These formats have no way to carry an error alongside the rows, so a query
+// error is reported through the status code instead.
+func (s *Service) writeRowsResponse(w http.ResponseWriter, contentType string, qp QueryParams, rows *proto.QueryRows) {
+	if rows.Error != "" {
+		http.Error(w, rows.Error, http.StatusBadRequest)
+		return
+	}
+
+	var write func(io.Writer, *proto.QueryRows, bool) error
+	switch contentType {
+	case ContentTypeCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		write = encoding.WriteCSV
+	case ContentTypeNDJSON:
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+		write = encoding.WriteNDJSON
+	case ContentTypeArrowStream:
+		w.Header().Set("Content-Type", ContentTypeArrowStream)
+		write = encoding.WriteArrow
+	}
+
+	// Encode in full before writing, so that an encoding failure can still
+	// be reported with an error status.
+	var buf bytes.Buffer
+	if err := write(&buf, rows, qp.BlobArray()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.Warnf(s.logger, "writing response failed: %s", err.Error())
+	}
+}
+
+// negotiateContentType returns the media type in which query results should
+// be returned, based on the Accept header of the request. JSON is returned
+// unless the client prefers one of the other supported formats.
+func negotiateContentType(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return ContentTypeJSON
+	}
+
+	best := ContentTypeJSON
+	bestQ := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		switch mediaType {
+		case ContentTypeJSON, ContentTypeCSV, ContentTypeNDJSON, ContentTypeArrowStream:
+		case "*/*", "application/*":
+			mediaType = ContentTypeJSON
+		default:
+			continue
+		}
+
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	if bestQ <= 0 {
+		return ContentTypeJSON
+	}
+	return best
+}
+
 func requestQueries(r *http.Request, qp QueryParams) ([]*proto.Statement, error) {
 	if r.Method == "GET" {
 		return []*proto.Statement{
@@ -1963,9 +2878,25 @@ func executeRequestFromStrings(s []string, timings, tx bool) *proto.ExecuteReque
 	}
 }
 
-func makeCredentials(r *http.Request) *clstrPB.Credentials {
+// makeCredentials returns the credentials to send with requests forwarded to
+// other nodes. A verified client certificate is sent as the user it maps to,
+// since other nodes must not derive identities from certificates they did not
+// verify themselves.
+func (s *Service) makeCredentials(r *http.Request) *clstrPB.Credentials {
+	if token := bearerToken(r); token != "" {
+		return &clstrPB.Credentials{
+			Token: token,
+		}
+	}
 	username, password, ok := r.BasicAuth()
 	if !ok {
+		if cert := clientCert(r); cert != nil && s.credentialStore != nil {
+			if u, ok := s.credentialStore.CertUser(cert); ok {
+				return &clstrPB.Credentials{
+					CertUser: u,
+				}
+			}
+		}
 		return nil
 	}
 	return &clstrPB.Credentials{
@@ -1973,3 +2904,39 @@ func makeCredentials(r *http.Request) *clstrPB.Credentials {
 		Password: password,
 	}
 }
+
+// requestClient returns the credentials the client presented with the
+// request. As when authorizing the request, a bearer token takes precedence
+// over a username and password, which take precedence over a client
+// certificate.
+func requestClient(r *http.Request) auth.Client {
+	username, password, basic := r.BasicAuth()
+	c := auth.Client{
+		Username: username,
+		Password: password,
+		Token:    bearerToken(r),
+	}
+	if c.Token == "" && !basic {
+		c.Cert = clientCert(r)
+	}
+	return c
+}
+
+// bearerToken returns the bearer token of the request, if any.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	v := r.Header.Get("Authorization")
+	if len(v) < len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(v[len(prefix):])
+}
+
+// clientCert returns the client certificate of the request, if the
+// certificate was verified.
+func clientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
@@ -3,10 +3,15 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -18,8 +23,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/auto"
+	"github.com/rqlite/rqlite/v10/auto/backup"
 	cluster "github.com/rqlite/rqlite/v10/cluster/proto"
+	rcommand "github.com/rqlite/rqlite/v10/command"
+	"github.com/rqlite/rqlite/v10/command/chunking"
 	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
+	"github.com/rqlite/rqlite/v10/otlp"
 	"github.com/rqlite/rqlite/v10/proxy"
 	"github.com/rqlite/rqlite/v10/store"
 )
@@ -337,6 +352,8 @@ func Test_405Routes(t *testing.T) {
 		{method: "POST", path: "/remove"},
 		{method: "GET", path: "/snapshot"},
 		{method: "POST", path: "/db/backup"},
+		{method: "GET", path: "/db/backup/upload"},
+		{method: "GET", path: "/db/import"},
 		{method: "POST", path: "/status"},
 		{method: "POST", path: "/nodes"},
 		{method: "POST", path: "/licenses"},
@@ -414,7 +431,9 @@ func Test_401Routes_NoBasicAuth(t *testing.T) {
 		"/db/query",
 		"/db/request",
 		"/db/backup",
+		"/db/backup/upload",
 		"/db/load",
+		"/db/import",
 		"/boot",
 		"/remove",
 		"/status",
@@ -424,6 +443,8 @@ func Test_401Routes_NoBasicAuth(t *testing.T) {
 		"/reap",
 		"/readyz",
 		"/licenses",
+		"/metrics",
+		"/admin/log-levels",
 		"/debug/vars",
 		"/debug/pprof/cmdline",
 		"/debug/pprof/profile",
@@ -465,6 +486,8 @@ func Test_401Routes_BasicAuthBadPassword(t *testing.T) {
 		"/snapshot",
 		"/reap",
 		"/readyz",
+		"/metrics",
+		"/admin/log-levels",
 		"/debug/vars",
 		"/debug/pprof/cmdline",
 		"/debug/pprof/profile",
@@ -504,9 +527,12 @@ func Test_401Routes_BasicAuthBadPerm(t *testing.T) {
 		"/db/execute",
 		"/db/query",
 		"/db/backup",
+		"/db/backup/upload",
 		"/db/request",
 		"/db/load",
+		"/db/import",
 		"/db/sql",
+		"/db/statements",
 		"/boot",
 		"/snapshot",
 		"/status",
@@ -515,6 +541,8 @@ func Test_401Routes_BasicAuthBadPerm(t *testing.T) {
 		"/snapshot",
 		"/reap",
 		"/readyz",
+		"/metrics",
+		"/admin/log-levels",
 		"/debug/vars",
 		"/debug/pprof/cmdline",
 		"/debug/pprof/profile",
@@ -536,6 +564,188 @@ func Test_401Routes_BasicAuthBadPerm(t *testing.T) {
 	}
 }
 
+func Test_Metrics(t *testing.T) {
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	s.Metrics = otlp.NewPrometheus(map[string]string{"node_id": "node1"})
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+
+	resp, err := http.Get(host + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to make metrics request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for metrics, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != otlp.PrometheusContentType {
+		t.Fatalf("unexpected Content-Type, got %s", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics response: %s", err)
+	}
+	for _, want := range []string{
+		"# TYPE rqlite_http_num_metrics_total counter\n",
+		`rqlite_http_num_metrics_total{node_id="node1"} `,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+
+	resp, err = http.Post(host+"/metrics", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("failed to make metrics request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("failed to get expected StatusMethodNotAllowed for POST, got %d", resp.StatusCode)
+	}
+}
+
+func Test_LogLevels(t *testing.T) {
+	t.Cleanup(func() {
+		logging.SetLevel(logging.DefaultSubsystem, slog.LevelInfo)
+		logging.ClearLevel("store")
+	})
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	client := &http.Client{}
+
+	do := func(method, body string) (int, map[string]string) {
+		req, err := http.NewRequest(method, host+"/admin/log-levels", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make log levels request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var levels map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&levels); err != nil {
+			t.Fatalf("failed to decode log levels: %s", err)
+		}
+		return resp.StatusCode, levels
+	}
+
+	code, levels := do("GET", "")
+	if code != http.StatusOK || levels["default"] != "INFO" {
+		t.Fatalf("unexpected response to GET: %d %v", code, levels)
+	}
+
+	code, levels = do("PUT", `{"store": "debug", "default": "warn"}`)
+	if code != http.StatusOK || levels["store"] != "DEBUG" || levels["default"] != "WARN" {
+		t.Fatalf("unexpected response to PUT: %d %v", code, levels)
+	}
+
+	code, levels = do("PUT", `{"store": ""}`)
+	if _, ok := levels["store"]; code != http.StatusOK || ok {
+		t.Fatalf("store level not cleared: %d %v", code, levels)
+	}
+
+	if code, _ := do("PUT", `{"store": "loud"}`); code != http.StatusBadRequest {
+		t.Fatalf("exp StatusBadRequest for invalid level, got %d", code)
+	}
+	if code, _ := do("DELETE", ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("exp StatusMethodNotAllowed for DELETE, got %d", code)
+	}
+}
+
+func Test_RequestID(t *testing.T) {
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+
+	resp, err := http.Get(host + "/status")
+	if err != nil {
+		t.Fatalf("failed to make status request: %s", err)
+	}
+	resp.Body.Close()
+	if id := resp.Header.Get(RequestIDHTTPHeader); id == "" {
+		t.Fatalf("no request ID returned")
+	}
+
+	req, err := http.NewRequest("GET", host+"/status", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	req.Header.Set(RequestIDHTTPHeader, "abc123")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make status request: %s", err)
+	}
+	resp.Body.Close()
+	if id := resp.Header.Get(RequestIDHTTPHeader); id != "abc123" {
+		t.Fatalf("exp request ID abc123, got %s", id)
+	}
+}
+
+func Test_StatusLatency(t *testing.T) {
+	ResetStats()
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+
+	for range 3 {
+		resp, err := http.Get(host + "/nodes")
+		if err != nil {
+			t.Fatalf("failed to make nodes request: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(host + "/status")
+	if err != nil {
+		t.Fatalf("failed to make status request: %s", err)
+	}
+	defer resp.Body.Close()
+	var status struct {
+		Latency map[string]map[string]map[string]any `json:"latency"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %s", err)
+	}
+	nodes, ok := status.Latency["http"]["nodes_latency"]
+	if !ok {
+		t.Fatalf("status does not contain latency of /nodes: %v", status.Latency)
+	}
+	if nodes["count"] != 3.0 || nodes["window_count"] != 3.0 {
+		t.Fatalf("unexpected latency of /nodes: %v", nodes)
+	}
+	if _, ok := nodes["p99_ms"]; !ok {
+		t.Fatalf("latency of /nodes has no p99: %v", nodes)
+	}
+	if _, ok := status.Latency["store"]["raft_apply_latency"]; !ok {
+		t.Fatalf("status does not contain latency of Raft apply: %v", status.Latency)
+	}
+}
+
 func Test_BackupOK(t *testing.T) {
 	m := &MockStore{}
 	c := &mockClusterService{}
@@ -668,6 +878,104 @@ func Test_ReapOK(t *testing.T) {
 	}
 }
 
+func Test_BackupUploadOK(t *testing.T) {
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	u := &mockBackupUploader{
+		uploadNowFn: func(ctx context.Context, name, suffix string) (*backup.UploadResult, error) {
+			if name != "pre.sqlite" || suffix != ".bak" {
+				t.Fatalf("unexpected name %q or suffix %q", name, suffix)
+			}
+			return &backup.UploadResult{
+				Key:      "dir/pre.sqlite.bak",
+				Index:    5,
+				Manifest: &auto.Manifest{Key: "dir/pre.sqlite.bak", Index: 5},
+			}, nil
+		},
+	}
+	s.SetBackupUploader(u)
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Post(host+"/db/backup/upload?name=pre.sqlite&suffix=.bak", "", nil)
+	if err != nil {
+		t.Fatalf("failed to make backup upload request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for backup upload, got %d", resp.StatusCode)
+	}
+
+	var res backup.UploadResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode backup upload response: %s", err)
+	}
+	if res.Key != "dir/pre.sqlite.bak" {
+		t.Fatalf("unexpected key: %s", res.Key)
+	}
+	if res.Manifest == nil || res.Manifest.Index != 5 {
+		t.Fatalf("unexpected manifest: %+v", res.Manifest)
+	}
+}
+
+func Test_BackupUploadErrors(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{
+		apiAddr: "http://1.2.3.4:999",
+	}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	client := &http.Client{}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+
+	resp, err := client.Post(host+"/db/backup/upload", "", nil)
+	if err != nil {
+		t.Fatalf("failed to make backup upload request: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected StatusServiceUnavailable with no uploader, got %d", resp.StatusCode)
+	}
+
+	u := &mockBackupUploader{}
+	s.SetBackupUploader(u)
+	for _, tc := range []struct {
+		err  error
+		path string
+		exp  int
+	}{
+		{err: backup.ErrInvalidName, path: "/db/backup/upload?name=a/b", exp: http.StatusBadRequest},
+		{err: backup.ErrUploadDisabled, path: "/db/backup/upload", exp: http.StatusServiceUnavailable},
+		{err: backup.ErrUploadDisabled, path: "/db/backup/upload?redirect", exp: http.StatusMovedPermanently},
+		{err: errors.New("upload failed"), path: "/db/backup/upload", exp: http.StatusInternalServerError},
+	} {
+		u.uploadNowFn = func(ctx context.Context, name, suffix string) (*backup.UploadResult, error) {
+			return nil, tc.err
+		}
+		resp, err := client.Post(host+tc.path, "", nil)
+		if err != nil {
+			t.Fatalf("failed to make backup upload request: %s", err.Error())
+		}
+		if resp.StatusCode != tc.exp {
+			t.Fatalf("expected %d for %s with error %v, got %d", tc.exp, tc.path, tc.err, resp.StatusCode)
+		}
+	}
+}
+
 func Test_BackupFlagsNoLeaderRedirect(t *testing.T) {
 	m := &MockStore{
 		leaderAddr: "foo:1234",
@@ -887,6 +1195,38 @@ func Test_BackupTablesOK(t *testing.T) {
 	}
 }
 
+func Test_BackupCompression(t *testing.T) {
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var capturedRequest *command.BackupRequest
+	m.backupFn = func(br *command.BackupRequest, dst io.Writer) error {
+		capturedRequest = br
+		return nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Get(host + "/db/backup?fmt=sql&tables=users,products&compression=zstd")
+	if err != nil {
+		t.Fatalf("failed to make backup request")
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for backup, got %d", resp.StatusCode)
+	}
+	if capturedRequest == nil {
+		t.Fatalf("backup function was not called")
+	}
+	if capturedRequest.Compression != command.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD {
+		t.Fatalf("expected zstd compression, got %v", capturedRequest.Compression)
+	}
+}
+
 func Test_LoadOK(t *testing.T) {
 	m := &MockStore{
 		leaderAddr: "foo:1234",
@@ -913,69 +1253,101 @@ func Test_LoadOK(t *testing.T) {
 	}
 }
 
-func Test_LoadFlagsNoLeader(t *testing.T) {
+func Test_LoadACL(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "tenant1", "password": "pw", "perms": ["load"], "acl": [
+			{"tables": ["t1_*"], "ops": ["ddl", "insert"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
 	m := &MockStore{
 		leaderAddr: "foo:1234",
 	}
-	c := &mockClusterService{
-		apiAddr: "http://1.2.3.4:999",
-	}
-
-	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
-
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
 	if err := s.Start(); err != nil {
 		t.Fatalf("failed to start service")
 	}
 	defer s.Close()
 
-	testData, err := os.ReadFile("testdata/load.db")
-	if err != nil {
-		t.Fatalf("failed to load test SQLite data")
-	}
-
-	m.loadFn = func(lr *command.LoadRequest) error {
-		return store.ErrNotLeader
+	var executed bool
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = true
+		return nil, 0, nil
 	}
 
-	clusterLoadCalled := false
-	c.loadFn = func(lc *command.LoadRequest, nodeAddr string, timeout time.Duration) error {
-		clusterLoadCalled = true
-		if !bytes.Equal(lc.Data, testData) {
-			t.Fatalf("wrong data passed to cluster load")
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, tc := range []struct {
+		dump string
+		code int
+	}{
+		{"CREATE TABLE t1_foo (id INTEGER);\nINSERT INTO t1_foo VALUES(1);\n", http.StatusOK},
+		{"CREATE TABLE t1_foo (id INTEGER);\nDROP TABLE t2_foo;\n", http.StatusForbidden},
+		{"INSERT INTO t1_foo VALUES(1);\nINSERT INTO t2_foo VALUES(1);\n", http.StatusForbidden},
+	} {
+		executed = false
+		req, err := http.NewRequest("POST", host+"/db/load", strings.NewReader(tc.dump))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.SetBasicAuth("tenant1", "pw")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make load request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Fatalf("wrong status code for %q, exp %d, got %d", tc.dump, tc.code, resp.StatusCode)
+		}
+		if executed != (tc.code == http.StatusOK) {
+			t.Fatalf("wrong execution state for %q, got %t", tc.dump, executed)
 		}
-		return nil
 	}
+}
 
-	client := &http.Client{}
-	host := fmt.Sprintf("http://%s", s.Addr().String())
-	resp, err := client.Post(host+"/db/load", "application/octet-stream", bytes.NewReader(testData))
-	if err != nil {
-		t.Fatalf("failed to make load request")
+func Test_LoadSystemTables(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("failed to get expected StatusOK for load, got %d", resp.StatusCode)
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
 	}
+	defer s.Close()
 
-	if !clusterLoadCalled {
-		t.Fatalf("cluster load was not called")
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		t.Fatalf("load of system table executed")
+		return nil, 0, nil
 	}
 
-	if exp, got := `{"results":[]}`, mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got %s", exp, got)
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, dump := range []string{
+		"CREATE TABLE foo (id INTEGER);\nINSERT INTO _rqlite_users VALUES('mallory', '{}');\n",
+		"INSERT INTO \"_RQLITE_STATEMENTS\" VALUES('purge', 'DELETE FROM foo');\n",
+	} {
+		resp, err := client.Post(host+"/db/load", "application/octet-stream", strings.NewReader(dump))
+		if err != nil {
+			t.Fatalf("failed to make load request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("load of %q: exp status 403, got %d", dump, resp.StatusCode)
+		}
 	}
 }
 
-func Test_LoadRemoteError(t *testing.T) {
+func Test_LoadCompressed(t *testing.T) {
 	m := &MockStore{
 		leaderAddr: "foo:1234",
 	}
-	c := &mockClusterService{
-		apiAddr: "http://1.2.3.4:999",
-	}
-
+	c := &mockClusterService{}
 	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
-
 	if err := s.Start(); err != nil {
 		t.Fatalf("failed to start service")
 	}
@@ -985,13 +1357,1029 @@ func Test_LoadRemoteError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to load test SQLite data")
 	}
-
-	m.loadFn = func(br *command.LoadRequest) error {
-		return store.ErrNotLeader
-	}
-	clusterLoadCalled := false
-	c.loadFn = func(lr *command.LoadRequest, addr string, t time.Duration) error {
-		clusterLoadCalled = true
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, algo := range []string{rarchive.CompressionGzip, rarchive.CompressionZstd} {
+		d := newLoadDechunker(t)
+		m.loadChunkFn = d.WriteChunk
+		var buf bytes.Buffer
+		cw, err := rarchive.NewCompressWriter(&buf, algo)
+		if err != nil {
+			t.Fatalf("failed to create %s writer: %s", algo, err)
+		}
+		if _, err := cw.Write(testData); err != nil {
+			t.Fatalf("failed to compress test data: %s", err)
+		}
+		if err := cw.Close(); err != nil {
+			t.Fatalf("failed to compress test data: %s", err)
+		}
+
+		resp, err := client.Post(host+"/db/load", "application/octet-stream", &buf)
+		if err != nil {
+			t.Fatalf("failed to make load request")
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("failed to get expected StatusOK for %s load, got %d", algo, resp.StatusCode)
+		}
+		if !bytes.Equal(d.data, testData) {
+			t.Fatalf("wrong data passed to store load")
+		}
+	}
+}
+
+func Test_LoadTooLarge(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	s.MaxLoadSize = 1024
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+	m.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		t.Fatalf("oversized data passed to store load")
+		return nil
+	}
+
+	// Data which compresses well must be limited by its decompressed size.
+	var buf bytes.Buffer
+	cw, err := rarchive.NewCompressWriter(&buf, rarchive.CompressionZstd)
+	if err != nil {
+		t.Fatalf("failed to create zstd writer: %s", err)
+	}
+	if _, err := cw.Write(bytes.Repeat([]byte("SELECT 1;"), 1024)); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("failed to compress test data: %s", err)
+	}
+	if buf.Len() > int(s.MaxLoadSize) {
+		t.Fatalf("compressed test data is %d bytes, exp less than limit", buf.Len())
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Post(host+"/db/load", "application/octet-stream", &buf)
+	if err != nil {
+		t.Fatalf("failed to make load request")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("failed to get expected StatusRequestEntityTooLarge, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Post(host+"/db/load", "application/octet-stream", strings.NewReader("SELECT"))
+	if err != nil {
+		t.Fatalf("failed to make load request")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for small load, got %d", resp.StatusCode)
+	}
+}
+
+func Test_QueryFormats(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	rows := &command.QueryRows{
+		Columns: []string{"id", "data"},
+		Types:   []string{"integer", "blob"},
+		Values: []*command.Values{
+			{Parameters: []*command.Parameter{{Value: &command.Parameter_I{I: 1}}, {Value: &command.Parameter_Y{Y: []byte{1, 2}}}}},
+		},
+	}
+	// Requests which cannot be answered in the format asked for must be
+	// refused before anything is executed.
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		if len(qr.Request.Statements) != 1 {
+			t.Errorf("query of %d statements executed", len(qr.Request.Statements))
+		}
+		return []*command.QueryRows{rows}, 0, nil
+	}
+	m.requestFn = func(er *command.ExecuteQueryRequest) ([]*command.ExecuteQueryResponse, uint64, uint64, error) {
+		if len(er.Request.Statements) != 1 || strings.HasPrefix(er.Request.Statements[0].Sql, "INSERT") {
+			t.Errorf("request %v executed", er.Request.Statements)
+		}
+		return []*command.ExecuteQueryResponse{{Result: &command.ExecuteQueryResponse_Q{Q: rows}}}, 0, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		accept string
+		code   int
+		ct     string
+		exp    string
+	}{
+		{"query JSON default", "GET", "/db/query?q=SELECT", "", "", 200, "application/json; charset=utf-8", ""},
+		{"query wildcard", "GET", "/db/query?q=SELECT", "", "*/*", 200, "application/json; charset=utf-8", ""},
+		{"query CSV", "GET", "/db/query?q=SELECT", "", "text/csv", 200, "text/csv; charset=utf-8", "id,data\n1,AQI=\n"},
+		{"query CSV blob array", "GET", "/db/query?q=SELECT&blob_array", "", "text/csv", 200, "text/csv; charset=utf-8", "id,data\n1,\"[1,2]\"\n"},
+		{"query NDJSON", "GET", "/db/query?q=SELECT", "", "application/x-ndjson", 200, "application/x-ndjson", `{"id":1,"data":"AQI="}` + "\n"},
+		{"query preference", "GET", "/db/query?q=SELECT", "", "application/json;q=0.5, text/csv", 200, "text/csv; charset=utf-8", "id,data\n1,AQI=\n"},
+		{"query Arrow", "GET", "/db/query?q=SELECT", "", "application/vnd.apache.arrow.stream", 200, "application/vnd.apache.arrow.stream", ""},
+		{"query CSV multiple", "POST", "/db/query", `["SELECT 1", "SELECT 2"]`, "text/csv", 400, "", ""},
+		{"request NDJSON", "POST", "/db/request", `["SELECT 1"]`, "application/x-ndjson", 200, "application/x-ndjson", `{"id":1,"data":"AQI="}` + "\n"},
+		{"request CSV execute", "POST", "/db/request", `["INSERT INTO foo VALUES(1)"]`, "text/csv", 400, "", ""},
+		{"request CSV multiple", "POST", "/db/request", `["SELECT 1", "SELECT 2"]`, "text/csv", 400, "", ""},
+		{"request CSV returning", "POST", "/db/request", `["DELETE FROM foo RETURNING *"]`, "text/csv", 200, "text/csv; charset=utf-8", "id,data\n1,AQI=\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, host+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("failed to make request: %s", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.code {
+				t.Fatalf("exp status %d, got %d", tc.code, resp.StatusCode)
+			}
+			if tc.code != 200 {
+				return
+			}
+			if got := resp.Header.Get("Content-Type"); got != tc.ct {
+				t.Fatalf("exp Content-Type %s, got %s", tc.ct, got)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %s", err)
+			}
+			if tc.exp != "" && string(body) != tc.exp {
+				t.Fatalf("exp body %q, got %q", tc.exp, string(body))
+			}
+		})
+	}
+}
+
+func Test_NegotiateContentType(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		exp    string
+	}{
+		{"", ContentTypeJSON},
+		{"application/json", ContentTypeJSON},
+		{"text/html", ContentTypeJSON},
+		{"*/*", ContentTypeJSON},
+		{"TEXT/CSV", ContentTypeCSV},
+		{"text/csv;q=0.5, application/x-ndjson", ContentTypeNDJSON},
+		{"text/csv;q=0.9, application/vnd.apache.arrow.stream;q=0.1", ContentTypeCSV},
+		{"text/csv, application/x-ndjson", ContentTypeCSV},
+		{"text/csv;q=0", ContentTypeJSON},
+		{"text/html, application/vnd.apache.arrow.stream, */*;q=0.8", ContentTypeArrowStream},
+	} {
+		r := &http.Request{Header: http.Header{}}
+		r.Header.Set("Accept", tc.accept)
+		if got := negotiateContentType(r); got != tc.exp {
+			t.Fatalf("Accept %q: exp %s, got %s", tc.accept, tc.exp, got)
+		}
+	}
+}
+
+func Test_ImportOK(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		if got, exp := qr.Request.Statements[0].Parameters[0].GetS(), "foo"; got != exp {
+			t.Fatalf("wrong table queried, exp %s, got %s", exp, got)
+		}
+		return []*command.QueryRows{
+			{
+				Columns: []string{"name", "type"},
+				Values: []*command.Values{
+					{Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "id"}}, {Value: &command.Parameter_S{S: "INTEGER"}}}},
+					{Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "name"}}, {Value: &command.Parameter_S{S: "TEXT"}}}},
+				},
+			},
+		}, 0, nil
+	}
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		t.Fatalf("import batch executed rather than sent in chunks")
+		return nil, 0, nil
+	}
+	var nStmts int
+	var batches int
+	m.loadChunkFn = importBatches(t, func(access string, req *command.Request) error {
+		if exp, got := `INSERT OR IGNORE INTO "foo" DEFAULT VALUES`, access; exp != got {
+			t.Fatalf("wrong access statement, exp %s, got %s", exp, got)
+		}
+		if !req.Transaction {
+			t.Fatalf("import batch not executed in a transaction")
+		}
+		batches++
+		nStmts += len(req.Statements)
+		if exp, got := `INSERT OR IGNORE INTO "foo" ("id", "name") VALUES (?, ?)`, req.Statements[0].Sql; exp != got {
+			t.Fatalf("wrong SQL, exp %s, got %s", exp, got)
+		}
+		return nil
+	})
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	data := "id,fullname\n1,fiona\n2,declan\n3,sinead\n"
+	resp, err := client.Post(host+"/db/import?table=foo&format=csv&on_conflict=ignore&map=fullname:name&batch_size=2",
+		"text/csv", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to make import request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for import, got %d", resp.StatusCode)
+	}
+	var ir ImportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ir); err != nil {
+		t.Fatalf("failed to decode import response: %s", err)
+	}
+	if ir.Rows != 3 || ir.Batches != 2 || ir.Created || ir.Error != "" {
+		t.Fatalf("unexpected import response: %+v", ir)
+	}
+	if nStmts != 3 || batches != 2 {
+		t.Fatalf("unexpected executions, got %d statements in %d batches", nStmts, batches)
+	}
+}
+
+func Test_ImportProgress(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return []*command.QueryRows{
+			{
+				Columns: []string{"name", "type"},
+				Values: []*command.Values{
+					{Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "id"}}, {Value: &command.Parameter_S{S: "INTEGER"}}}},
+				},
+			},
+		}, 0, nil
+	}
+	var batches int
+	m.loadChunkFn = importBatches(t, func(access string, req *command.Request) error {
+		batches++
+		if batches == 3 {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	data := "id\n1\n2\n3\n4\n5\n"
+	resp, err := client.Post(host+"/db/import?table=foo&format=csv&batch_size=2&progress",
+		"text/csv", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to make import request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for import, got %d", resp.StatusCode)
+	}
+
+	// Two batches succeed, then the import fails with the first two
+	// batches committed.
+	dec := json.NewDecoder(resp.Body)
+	var lines []ImportResponse
+	for dec.More() {
+		var ir ImportResponse
+		if err := dec.Decode(&ir); err != nil {
+			t.Fatalf("failed to decode import response: %s", err)
+		}
+		lines = append(lines, ir)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("exp 3 response lines, got %d: %+v", len(lines), lines)
+	}
+	for i, ir := range lines[:2] {
+		if !ir.Progress || ir.Rows != int64(2*(i+1)) || ir.Batches != int64(i+1) {
+			t.Fatalf("unexpected progress line %d: %+v", i, ir)
+		}
+	}
+	if ir := lines[2]; ir.Progress || ir.Rows != 4 || ir.Error != "disk full" {
+		t.Fatalf("unexpected final line: %+v", ir)
+	}
+}
+
+func Test_ImportCreate(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return []*command.QueryRows{{Columns: []string{"name", "type"}}}, 0, nil
+	}
+	var sqls []string
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		for _, stmt := range er.Request.Statements {
+			sqls = append(sqls, stmt.Sql)
+		}
+		return nil, 0, nil
+	}
+	m.loadChunkFn = importBatches(t, func(access string, req *command.Request) error {
+		for _, stmt := range req.Statements {
+			sqls = append(sqls, stmt.Sql)
+		}
+		return nil
+	})
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	data := `{"id":1,"name":"fiona","score":1.5}` + "\n" + `{"id":2,"name":"declan"}` + "\n"
+
+	// Without create, a missing table is an error.
+	resp, err := client.Post(host+"/db/import?table=foo&format=ndjson", "application/x-ndjson", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to make import request")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("failed to get expected StatusBadRequest for import, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Post(host+"/db/import?table=foo&format=ndjson&create", "application/x-ndjson", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to make import request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for import, got %d", resp.StatusCode)
+	}
+	var ir ImportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ir); err != nil {
+		t.Fatalf("failed to decode import response: %s", err)
+	}
+	if !ir.Created || ir.Rows != 2 {
+		t.Fatalf("unexpected import response: %+v", ir)
+	}
+	if len(sqls) != 3 {
+		t.Fatalf("expected 3 statements executed, got %d", len(sqls))
+	}
+	if exp, got := `CREATE TABLE IF NOT EXISTS "foo" ("id" INTEGER, "name" TEXT, "score" REAL)`, sqls[0]; exp != got {
+		t.Fatalf("wrong create statement, exp %s, got %s", exp, got)
+	}
+}
+
+func Test_ImportErrors(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return []*command.QueryRows{
+			{
+				Columns: []string{"name", "type"},
+				Values: []*command.Values{
+					{Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "id"}}, {Value: &command.Parameter_S{S: "INTEGER"}}}},
+				},
+			},
+		}, 0, nil
+	}
+	m.loadChunkFn = importBatches(t, func(access string, req *command.Request) error {
+		return errors.New("UNIQUE constraint failed: foo.id")
+	})
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, tc := range []struct {
+		query string
+		data  string
+		code  int
+	}{
+		{query: "format=csv", data: "id\n1\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=xml", data: "id\n1\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=csv&on_conflict=update", data: "id\n1\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=csv&batch_size=x", data: "id\n1\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=csv&map=id:bar", data: "id\n1\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=csv", data: "id\nabc\n", code: http.StatusBadRequest},
+		{query: "table=foo&format=csv", data: "id\n1\n", code: http.StatusInternalServerError},
+	} {
+		resp, err := client.Post(host+"/db/import?"+tc.query, "text/csv", strings.NewReader(tc.data))
+		if err != nil {
+			t.Fatalf("failed to make import request")
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Fatalf("wrong status code for %s, exp %d, got %d", tc.query, tc.code, resp.StatusCode)
+		}
+	}
+}
+
+func Test_ImportSystemTables(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[{"username": "admin", "password": "pw", "perms": ["all"]}]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		t.Fatalf("columns of system table queried")
+		return nil, 0, nil
+	}
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		t.Fatalf("import into system table executed")
+		return nil, 0, nil
+	}
+	m.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		t.Fatalf("import into system table executed")
+		return nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, table := range []string{"_rqlite_users", "_RQLITE_USERS", "_rqlite_statements"} {
+		req, err := http.NewRequest("POST", host+"/db/import?format=csv&create&table="+table,
+			strings.NewReader("username,credential\nmallory,{}\n"))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.SetBasicAuth("admin", "pw")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make import request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("import into %s: exp status 403, got %d", table, resp.StatusCode)
+		}
+	}
+}
+
+func Test_ImportACL(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "tenant1", "password": "pw", "perms": ["execute", "query"], "acl": [
+			{"tables": ["t1_*"], "ops": ["select", "insert"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var queried, executed bool
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		queried = true
+		return []*command.QueryRows{
+			{
+				Columns: []string{"name", "type"},
+				Values: []*command.Values{
+					{Parameters: []*command.Parameter{{Value: &command.Parameter_S{S: "id"}}, {Value: &command.Parameter_S{S: "INTEGER"}}}},
+				},
+			},
+		}, 0, nil
+	}
+	m.loadChunkFn = importBatches(t, func(access string, req *command.Request) error {
+		executed = true
+		return nil
+	})
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	do := func(table string) int {
+		req, err := http.NewRequest("POST", host+"/db/import?format=csv&table="+table, strings.NewReader("id\n1\n"))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.SetBasicAuth("tenant1", "pw")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make import request: %s", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do("t2_orders"); code != http.StatusForbidden {
+		t.Fatalf("import into denied table: exp status 403, got %d", code)
+	}
+	if queried || executed {
+		t.Fatalf("denied table accessed by import")
+	}
+
+	if code := do("t1_orders"); code != http.StatusOK {
+		t.Fatalf("import into permitted table: exp status 200, got %d", code)
+	}
+	if !queried || !executed {
+		t.Fatalf("permitted import not performed")
+	}
+}
+
+func Test_StatementsOK(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+		statements: map[string]string{"get": "SELECT * FROM foo WHERE id = ?"},
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var executed []*command.Statement
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		if !er.Request.Transaction {
+			t.Fatalf("statements update not executed in a transaction")
+		}
+		executed = er.Request.Statements
+		return []*command.ExecuteQueryResponse{
+			{Result: &command.ExecuteQueryResponse_E{E: &command.ExecuteResult{}}},
+			{Result: &command.ExecuteQueryResponse_E{E: &command.ExecuteResult{RowsAffected: 1}}},
+		}, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+
+	resp, err := client.Get(host + "/db/statements/get")
+	if err != nil {
+		t.Fatalf("failed to make statements request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for statement, got %d", resp.StatusCode)
+	}
+	var ns NamedStatement
+	if err := json.NewDecoder(resp.Body).Decode(&ns); err != nil {
+		t.Fatalf("failed to decode statement: %s", err)
+	}
+	if ns.Name != "get" || ns.SQL != "SELECT * FROM foo WHERE id = ?" {
+		t.Fatalf("unexpected statement: %+v", ns)
+	}
+
+	resp, err = client.Get(host + "/db/statements/missing")
+	if err != nil {
+		t.Fatalf("failed to make statements request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("failed to get expected StatusNotFound for missing statement, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get(host + "/db/statements")
+	if err != nil {
+		t.Fatalf("failed to make statements request: %s", err)
+	}
+	defer resp.Body.Close()
+	var list []NamedStatement
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode statements: %s", err)
+	}
+	if len(list) != 1 || list[0].Name != "get" {
+		t.Fatalf("unexpected statements: %+v", list)
+	}
+
+	req, err := http.NewRequest("PUT", host+"/db/statements/add", strings.NewReader("INSERT INTO foo(name) VALUES(?)"))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make statements request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for registration, got %d", resp.StatusCode)
+	}
+	if len(executed) != 2 || executed[0].Sql != db.CreateStatementsTableSQL {
+		t.Fatalf("unexpected statements executed: %v", executed)
+	}
+	if got := executed[1].Parameters[1].GetS(); got != "INSERT INTO foo(name) VALUES(?)" {
+		t.Fatalf("wrong SQL registered: %s", got)
+	}
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		exp    int
+	}{
+		{"PUT", "/db/statements/add", "", http.StatusBadRequest},
+		{"PUT", "/db/statements", "SELECT 1", http.StatusBadRequest},
+		{"PUT", "/db/statements/bad%20name", "SELECT 1", http.StatusBadRequest},
+		{"PUT", "/db/statements/add", "PRAGMA journal_mode=DELETE", http.StatusBadRequest},
+		{"PUT", "/db/statements/add", "SELECT * FROM _rqlite_users", http.StatusBadRequest},
+		{"DELETE", "/db/statements", "", http.StatusBadRequest},
+		{"DELETE", "/db/statements/add", "", http.StatusOK},
+		{"PATCH", "/db/statements/add", "", http.StatusMethodNotAllowed},
+	} {
+		req, err := http.NewRequest(tc.method, host+tc.path, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make statements request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.exp {
+			t.Fatalf("%s %s: exp status %d, got %d", tc.method, tc.path, tc.exp, resp.StatusCode)
+		}
+	}
+}
+
+func Test_UsersOK(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+		users: []auth.Credential{
+			{Username: "fiona", Password: "$2a$10$hash", Perms: []string{"query"}},
+		},
+	}
+	c := &mockClusterService{}
+	disabled := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := disabled.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer disabled.Close()
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	s.UserManagement = true
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var executed []*command.Statement
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = er.Request.Statements
+		return []*command.ExecuteQueryResponse{
+			{Result: &command.ExecuteQueryResponse_E{E: &command.ExecuteResult{}}},
+			{Result: &command.ExecuteQueryResponse_E{E: &command.ExecuteResult{}}},
+		}, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, host+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make users request: %s", err)
+		}
+		return resp
+	}
+
+	resp, err := client.Get(fmt.Sprintf("http://%s/auth/users", disabled.Addr().String()))
+	if err != nil {
+		t.Fatalf("failed to make users request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected StatusNotFound when user management disabled, got %d", resp.StatusCode)
+	}
+
+	resp = do("GET", "/auth/users", "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for users, got %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %s", err)
+	}
+	if exp, got := `[{"username":"fiona","perms":["query"]}]`, strings.TrimSpace(string(b)); exp != got {
+		t.Fatalf("wrong users, exp %s, got %s", exp, got)
+	}
+
+	resp = do("GET", "/auth/users/missing", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("failed to get expected StatusNotFound for missing user, got %d", resp.StatusCode)
+	}
+
+	resp = do("PUT", "/auth/users/dana", `{"password": "secret", "perms": ["all"]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for user creation, got %d", resp.StatusCode)
+	}
+	if len(executed) != 2 || executed[0].Sql != db.CreateUsersTableSQL {
+		t.Fatalf("unexpected statements executed: %v", executed)
+	}
+	var cred auth.Credential
+	if err := json.Unmarshal([]byte(executed[1].Parameters[1].GetS()), &cred); err != nil {
+		t.Fatalf("failed to decode stored credential: %s", err)
+	}
+	if !auth.IsHashed(cred.Password) || cred.Username != "" || len(cred.Perms) != 1 {
+		t.Fatalf("unexpected stored credential: %+v", cred)
+	}
+
+	h, err := auth.HashPassword("secret", auth.HashArgon2id, 0)
+	if err != nil {
+		t.Fatalf("failed to hash password: %s", err)
+	}
+	resp = do("PUT", "/auth/users/dana", `{"password": "`+h+`"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for user with hashed password, got %d", resp.StatusCode)
+	}
+
+	resp = do("PUT", "/auth/users/fiona", `{"perms": ["query"]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for user update, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(executed[1].Sql, "ON CONFLICT") {
+		t.Fatalf("update without password does not keep password: %s", executed[1].Sql)
+	}
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		exp    int
+	}{
+		{"PUT", "/auth/users", `{}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/*", `{}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/bad%20name", `{}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"username": "fiona"}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"passwd": "secret"}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"perms": ["query"]}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"password": "", "perms": ["query"]}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"acl": [{"tables": ["foo"], "ops": ["drop"]}]}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"password": "$argon2id$v=19$m=65536,t=3,p=0$c2FsdHNhbHQ$a2V5"}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"password": "$argon2id$v=19$m=4294967295,t=3,p=4$c2FsdHNhbHQ$a2V5"}`, http.StatusBadRequest},
+		{"PUT", "/auth/users/dana", `{"password": "$2a$10$notavalidhash"}`, http.StatusBadRequest},
+		{"DELETE", "/auth/users", "", http.StatusBadRequest},
+		{"DELETE", "/auth/users/dana", "", http.StatusNotFound},
+		{"PATCH", "/auth/users/dana", "", http.StatusMethodNotAllowed},
+	} {
+		resp := do(tc.method, tc.path, tc.body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.exp {
+			t.Fatalf("%s %s: exp status %d, got %d", tc.method, tc.path, tc.exp, resp.StatusCode)
+		}
+	}
+}
+
+func Test_UsersGrantHeldPerms(t *testing.T) {
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return perm == auth.PermUsers || perm == auth.PermQuery
+		},
+	}
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	n := &mockClusterService{}
+	s := New("127.0.0.1:0", m, n, proxy.New(m, n), c)
+	s.UserManagement = true
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var executed bool
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = true
+		return nil, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"password": "secret", "perms": ["query"]}`, http.StatusOK},
+		{`{"password": "secret", "perms": ["query", "execute"]}`, http.StatusForbidden},
+		{`{"password": "secret", "perms": ["all"]}`, http.StatusForbidden},
+	} {
+		executed = false
+		req, err := http.NewRequest("PUT", host+"/auth/users/dana", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make users request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Fatalf("wrong status code for %s, exp %d, got %d", tc.body, tc.code, resp.StatusCode)
+		}
+		if executed != (tc.code == http.StatusOK) {
+			t.Fatalf("wrong execution state for %s, got %t", tc.body, executed)
+		}
+	}
+}
+
+func Test_NamedOnlyPerm(t *testing.T) {
+	c := &mockCredentialStore{
+		aaFunc: func(username, password, perm string) bool {
+			return perm == auth.PermNamedStatements
+		},
+	}
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+		statements: map[string]string{"add": "INSERT INTO foo(name) VALUES(?)"},
+	}
+	n := &mockClusterService{}
+	s := New("127.0.0.1:0", m, n, proxy.New(m, n), c)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var executed []*command.Statement
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = er.Request.Statements
+		return nil, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	for _, tc := range []struct {
+		body string
+		exp  int
+	}{
+		{`[{"name": "add", "params": ["fiona"]}]`, http.StatusOK},
+		{`["INSERT INTO foo(name) VALUES('fiona')"]`, http.StatusUnauthorized},
+		{`[{"name": "add", "params": ["fiona"]}, "DELETE FROM foo"]`, http.StatusUnauthorized},
+		{`[{"name": "missing", "params": ["fiona"]}]`, http.StatusBadRequest},
+	} {
+		executed = nil
+		req, err := http.NewRequest("POST", host+"/db/execute", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.SetBasicAuth("username1", "password1")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make execute request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.exp {
+			t.Fatalf("%s: exp status %d, got %d", tc.body, tc.exp, resp.StatusCode)
+		}
+		if tc.exp == http.StatusOK && (len(executed) != 1 || executed[0].Name != "add") {
+			t.Fatalf("%s: unexpected statements executed: %v", tc.body, executed)
+		}
+		if tc.exp != http.StatusOK && executed != nil {
+			t.Fatalf("%s: statements executed without permission", tc.body)
+		}
+	}
+
+	// Users permitted only named statements cannot change the registry.
+	req, err := http.NewRequest("PUT", host+"/db/statements/del", strings.NewReader("DELETE FROM foo"))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	req.SetBasicAuth("username1", "password1")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make statements request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("exp status 401, got %d", resp.StatusCode)
+	}
+}
+
+func Test_LoadFlagsNoLeader(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{
+		apiAddr: "http://1.2.3.4:999",
+	}
+
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	testData, err := os.ReadFile("testdata/load.db")
+	if err != nil {
+		t.Fatalf("failed to load test SQLite data")
+	}
+
+	m.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		return store.ErrNotLeader
+	}
+
+	clusterLoadCalled := false
+	d := newLoadDechunker(t)
+	c.loadChunkFn = func(lcr *command.LoadChunkRequest, nodeAddr string, timeout time.Duration) error {
+		clusterLoadCalled = true
+		return d.WriteChunk(lcr)
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Post(host+"/db/load", "application/octet-stream", bytes.NewReader(testData))
+	if err != nil {
+		t.Fatalf("failed to make load request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for load, got %d", resp.StatusCode)
+	}
+
+	if !clusterLoadCalled {
+		t.Fatalf("cluster load was not called")
+	}
+	if !bytes.Equal(d.data, testData) {
+		t.Fatalf("wrong data passed to cluster load")
+	}
+
+	if exp, got := `{"results":[]}`, mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got %s", exp, got)
+	}
+}
+
+func Test_LoadRemoteError(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{
+		apiAddr: "http://1.2.3.4:999",
+	}
+
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	testData, err := os.ReadFile("testdata/load.db")
+	if err != nil {
+		t.Fatalf("failed to load test SQLite data")
+	}
+
+	m.loadChunkFn = func(lcr *command.LoadChunkRequest) error {
+		return store.ErrNotLeader
+	}
+	clusterLoadCalled := false
+	c.loadChunkFn = func(lcr *command.LoadChunkRequest, addr string, t time.Duration) error {
+		clusterLoadCalled = true
 		return fmt.Errorf("the load failed")
 	}
 
@@ -1015,6 +2403,67 @@ func Test_LoadRemoteError(t *testing.T) {
 	}
 }
 
+// loadDechunker reassembles the chunks of a SQLite file passed to a load.
+type loadDechunker struct {
+	d    *chunking.Dechunker
+	data []byte
+}
+
+func newLoadDechunker(t *testing.T) *loadDechunker {
+	t.Helper()
+	d, err := chunking.NewDechunker(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create dechunker: %s", err)
+	}
+	return &loadDechunker{d: d}
+}
+
+// WriteChunk writes the chunk, reading the reassembled file once the last
+// chunk is written.
+func (l *loadDechunker) WriteChunk(lcr *command.LoadChunkRequest) error {
+	last, err := l.d.WriteChunk(lcr)
+	if err != nil || !last {
+		return err
+	}
+	path, err := l.d.Close()
+	if err != nil {
+		return err
+	}
+	l.data, err = os.ReadFile(path)
+	return err
+}
+
+// importBatches returns a function, for use as the loadChunkFn of a store,
+// which reassembles the batches of an import from their chunks, and passes
+// each, with the access statement of the import, to fn.
+func importBatches(t *testing.T, fn func(access string, req *command.Request) error) func(lcr *command.LoadChunkRequest) error {
+	t.Helper()
+	streams := make(map[string]*loadDechunker)
+	return func(lcr *command.LoadChunkRequest) error {
+		if lcr.ImportAccess == "" {
+			t.Fatalf("import chunk carries no access statement")
+		}
+		if lcr.Abort {
+			delete(streams, lcr.StreamId)
+			return nil
+		}
+		ld, ok := streams[lcr.StreamId]
+		if !ok {
+			ld = newLoadDechunker(t)
+			streams[lcr.StreamId] = ld
+		}
+		if err := ld.WriteChunk(lcr); err != nil || !lcr.IsLast {
+			return err
+		}
+		delete(streams, lcr.StreamId)
+		var req command.Request
+		if err := rcommand.UnmarshalImportBatch(ld.data, &req); err != nil {
+			t.Fatalf("failed to unmarshal import batch: %s", err)
+		}
+		return fn(lcr.ImportAccess, &req)
+	}
+}
+
 func Test_Boot(t *testing.T) {
 	m := &MockStore{
 		leaderAddr: "foo:1234",
@@ -1212,145 +2661,588 @@ func Test_RootRedirectToUI(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to make root request")
 	}
-	if resp.StatusCode != http.StatusFound {
-		t.Fatalf("failed to get expected StatusFound for root, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("failed to get expected StatusFound for root, got %d", resp.StatusCode)
+	}
+	if resp.Header["Location"][0] != "/console/" {
+		t.Fatalf("received incorrect redirect path")
+	}
+
+	resp, err = client.Get(fmt.Sprintf("http://%s", s.Addr().String()))
+	if err != nil {
+		t.Fatalf("failed to make root request")
+	}
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("failed to get expected StatusFound for root, got %d", resp.StatusCode)
+	}
+	if resp.Header["Location"][0] != "/console/" {
+		t.Fatalf("received incorrect redirect path")
+	}
+}
+
+func Test_Readyz(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{
+		apiAddr: "https://bar:5678",
+	}
+
+	// Simulate database responding correctly.
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		rows := &command.QueryRows{
+			Columns: []string{"value"},
+			Types:   []string{"integer"},
+			Values: []*command.Values{
+				{
+					Parameters: []*command.Parameter{
+						{
+							Value: &command.Parameter_I{
+								I: 1,
+							},
+						},
+					},
+				},
+			},
+		}
+		return []*command.QueryRows{rows}, 0, nil
+	}
+
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Get(host + "/readyz")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for node, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]db ok", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+
+	resp, err = client.Get(host + "/readyz?noleader")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+
+	m.notReady = true
+	m.committedFn = func(timeout time.Duration) (uint64, error) {
+		t.Fatal("committedFn should not have been called")
+		return 0, nil
+	}
+	resp, err = client.Get(host + "/readyz")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store not ready", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+
+	cnt := &atomic.Uint32{}
+	m.notReady = false
+	m.committedFn = func(timeout time.Duration) (uint64, error) {
+		cnt.Store(1)
+		return 0, fmt.Errorf("timeout")
+	}
+	resp, err = client.Get(host + "/readyz?sync")
+	if err != nil {
+		t.Fatalf("failed to make readyz request with sync set")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]sync timeout", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+	if cnt.Load() != 1 {
+		t.Fatalf("failed to call committedFn")
+	}
+	m.notReady = false
+	m.committedFn = func(timeout time.Duration) (uint64, error) {
+		cnt.Store(2)
+		return 0, nil
+	}
+	resp, err = client.Get(host + "/readyz?sync")
+	if err != nil {
+		t.Fatalf("failed to make readyz request with sync set")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]sync ok\n[+]db ok", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+	if cnt.Load() != 2 {
+		t.Fatalf("failed to call committedFn")
+	}
+}
+
+func Test_ReadyzWritable(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{
+		getNodeMetaFn: func(addr string) (*cluster.NodeMeta, error) {
+			return &cluster.NodeMeta{
+				Url:                "http://foo:4001",
+				WriteThrottleLevel: 3,
+				WriteRetryAfter:    int64(1500 * time.Millisecond),
+			}, nil
+		},
+	}
+	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
+		return []*command.QueryRows{{
+			Columns: []string{"value"},
+			Types:   []string{"integer"},
+			Values:  []*command.Values{{Parameters: []*command.Parameter{{Value: &command.Parameter_I{I: 1}}}}},
+		}}, 0, nil
+	}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Get(host + "/readyz?writable")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	}
+	if exp, got := "2", resp.Header.Get("Retry-After"); exp != got {
+		t.Fatalf("wrong Retry-After, exp %s, got %s", exp, got)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]writes throttled at level 3", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+
+	// Without writable, the throttle is not checked.
+	resp, err = client.Get(host + "/readyz")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	}
+
+	c.getNodeMetaFn = nil
+	resp, err = client.Get(host + "/readyz?writable")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]writable ok, throttle level 0\n[+]db ok", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+}
+
+func Test_ExecuteThrottled(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, &store.ThrottledError{RetryAfter: time.Second}
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Post(host+"/db/execute", "application/json", strings.NewReader(`["INSERT INTO foo(id) VALUES(1)"]`))
+	if err != nil {
+		t.Fatalf("failed to make execute request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("failed to get expected StatusTooManyRequests, got %d", resp.StatusCode)
+	}
+	if exp, got := "1", resp.Header.Get("Retry-After"); exp != got {
+		t.Fatalf("wrong Retry-After, exp %s, got %s", exp, got)
+	}
+
+	// A write throttled by the Leader is also refused.
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, store.ErrNotLeader
+	}
+	c.executeFn = func(er *command.ExecuteRequest, addr string, t time.Duration) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, errors.New((&store.ThrottledError{RetryAfter: 5 * time.Second}).Error())
+	}
+	resp, err = client.Post(host+"/db/execute", "application/json", strings.NewReader(`["INSERT INTO foo(id) VALUES(1)"]`))
+	if err != nil {
+		t.Fatalf("failed to make execute request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("failed to get expected StatusTooManyRequests for forwarded execute, got %d", resp.StatusCode)
+	}
+	if exp, got := "5", resp.Header.Get("Retry-After"); exp != got {
+		t.Fatalf("wrong Retry-After, exp %s, got %s", exp, got)
+	}
+}
+
+func Test_ReadyzNoLeader(t *testing.T) {
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Get(host + "/readyz")
+	if err != nil {
+		t.Fatalf("failed to make readyz request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	}
+	if exp, got := "[+]node ok\n[+]leader does not exist", mustReadBody(t, resp); exp != got {
+		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	}
+}
+
+func Test_ExecuteLint(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	s.Linter = sql.NewLinter(sql.LintReject, &mockCatalog{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	var executed bool
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = true
+		return nil, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	resp, err := client.Post(host+"/db/execute", "application/json",
+		strings.NewReader(`["INSERT INTO foo(id) SELECT id FROM bar ORDER BY random() LIMIT 1"]`))
+	if err != nil {
+		t.Fatalf("failed to make execute request: %s", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("failed to get expected StatusBadRequest for execute, got %d", resp.StatusCode)
 	}
-	if resp.Header["Location"][0] != "/console/" {
-		t.Fatalf("received incorrect redirect path")
+	if !strings.Contains(string(b), "random() is not deterministic") {
+		t.Fatalf("wrong error for rejected execute: %s", b)
+	}
+	if executed {
+		t.Fatalf("rejected statement was executed")
 	}
 
-	resp, err = client.Get(fmt.Sprintf("http://%s", s.Addr().String()))
+	resp, err = client.Post(host+"/db/execute", "application/json",
+		strings.NewReader(`["INSERT INTO foo(id) VALUES(random())"]`))
 	if err != nil {
-		t.Fatalf("failed to make root request")
+		t.Fatalf("failed to make execute request: %s", err)
 	}
-	if resp.StatusCode != http.StatusFound {
-		t.Fatalf("failed to get expected StatusFound for root, got %d", resp.StatusCode)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for execute, got %d", resp.StatusCode)
 	}
-	if resp.Header["Location"][0] != "/console/" {
-		t.Fatalf("received incorrect redirect path")
+	if !executed {
+		t.Fatalf("rewritten statement was not executed")
 	}
 }
 
-func Test_Readyz(t *testing.T) {
+func Test_ExecuteQueryACL(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "tenant1", "password": "pw", "perms": ["execute", "query"], "acl": [
+			{"tables": ["t1_*"], "ops": ["select", "insert", "update", "delete"]}
+		]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
 	m := &MockStore{
 		leaderAddr: "foo:1234",
 	}
-	c := &mockClusterService{
-		apiAddr: "https://bar:5678",
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
 	}
+	defer s.Close()
 
-	// Simulate database responding correctly.
+	var executed, queried bool
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed = true
+		return nil, 0, nil
+	}
 	m.queryFn = func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error) {
-		rows := &command.QueryRows{
-			Columns: []string{"value"},
-			Types:   []string{"integer"},
-			Values: []*command.Values{
-				{
-					Parameters: []*command.Parameter{
-						{
-							Value: &command.Parameter_I{
-								I: 1,
-							},
-						},
-					},
-				},
-			},
+		queried = true
+		return nil, 0, nil
+	}
+
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	do := func(path, body string) (int, string) {
+		req, err := http.NewRequest("POST", host+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
 		}
-		return []*command.QueryRows{rows}, 0, nil
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("tenant1", "pw")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
 	}
 
-	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	code, body := do("/db/execute", `["DROP TABLE t2_orders"]`)
+	if code != http.StatusForbidden {
+		t.Fatalf("failed to get expected StatusForbidden for execute, got %d", code)
+	}
+	if !strings.Contains(body, "ddl on table t2_orders not permitted") {
+		t.Fatalf("wrong error for denied execute: %s", body)
+	}
+	if executed {
+		t.Fatalf("denied statement was executed")
+	}
+
+	code, body = do("/db/query", `["SELECT * FROM t1_orders JOIN t2_orders"]`)
+	if code != http.StatusForbidden {
+		t.Fatalf("failed to get expected StatusForbidden for query, got %d", code)
+	}
+	if !strings.Contains(body, "select on table t2_orders not permitted") {
+		t.Fatalf("wrong error for denied query: %s", body)
+	}
+	if queried {
+		t.Fatalf("denied query was executed")
+	}
+
+	if code, body := do("/db/execute", `["INSERT INTO t1_orders(id) VALUES(1)"]`); code != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for execute, got %d: %s", code, body)
+	}
+	if !executed {
+		t.Fatalf("permitted statement was not executed")
+	}
+	if code, body := do("/db/query", `["SELECT * FROM t1_orders"]`); code != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for query, got %d: %s", code, body)
+	}
+	if !queried {
+		t.Fatalf("permitted query was not executed")
+	}
+}
+
+func Test_RateLimit(t *testing.T) {
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "tenant1", "password": "pw", "perms": ["execute", "query"], "rate_limit": {"statements_per_second": 2}},
+		{"username": "tenant2", "password": "pw", "perms": ["execute", "query"]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.RateLimiter = auth.NewRateLimiter(cs)
 	if err := s.Start(); err != nil {
 		t.Fatalf("failed to start service")
 	}
 	defer s.Close()
 
+	executed := 0
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		executed++
+		return nil, 0, nil
+	}
+
 	client := &http.Client{}
 	host := fmt.Sprintf("http://%s", s.Addr().String())
-	resp, err := client.Get(host + "/readyz")
-	if err != nil {
-		t.Fatalf("failed to make readyz request")
+	do := func(user, path, body string) *http.Response {
+		req, err := http.NewRequest("POST", host+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(user, "pw")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		resp.Body.Close()
+		return resp
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("failed to get expected StatusOK for node, got %d", resp.StatusCode)
+
+	if resp := do("tenant1", "/db/execute", `["INSERT INTO foo(id) VALUES(1)", "INSERT INTO foo(id) VALUES(2)"]`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for execute, got %d", resp.StatusCode)
 	}
-	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]db ok", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	resp := do("tenant1", "/db/query", `["SELECT * FROM foo"]`)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("failed to get expected StatusTooManyRequests for query, got %d", resp.StatusCode)
+	}
+	if exp, got := "1", resp.Header.Get("Retry-After"); exp != got {
+		t.Fatalf("wrong Retry-After, exp %s, got %s", exp, got)
+	}
+	if executed != 1 {
+		t.Fatalf("wrong number of executions, exp 1, got %d", executed)
 	}
 
-	resp, err = client.Get(host + "/readyz?noleader")
-	if err != nil {
-		t.Fatalf("failed to make readyz request")
+	// Requests forwarded to the Leader are charged only here, so tenant2,
+	// which has no rate limit, is unaffected by tenant1.
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, store.ErrNotLeader
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	c.executeFn = func(er *command.ExecuteRequest, addr string, t time.Duration) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
 	}
-	if exp, got := "[+]node ok", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	if resp := do("tenant2", "/db/execute", `["INSERT INTO foo(id) VALUES(3)"]`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for forwarded execute, got %d", resp.StatusCode)
 	}
-
-	m.notReady = true
-	m.committedFn = func(timeout time.Duration) (uint64, error) {
-		t.Fatal("committedFn should not have been called")
-		return 0, nil
+	if resp := do("tenant1", "/db/execute", `["INSERT INTO foo(id) VALUES(3)"]`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("failed to get expected StatusTooManyRequests for forwarded execute, got %d", resp.StatusCode)
 	}
-	resp, err = client.Get(host + "/readyz")
+}
+
+func Test_BearerToken(t *testing.T) {
+	tv, err := auth.NewTokenVerifier(auth.TokenConfig{
+		Issuer:   "idp",
+		Audience: "rqlite",
+		Keys:     []auth.JSONWebKey{{Kty: "oct", K: base64.RawURLEncoding.EncodeToString([]byte("secret"))}},
+	})
 	if err != nil {
-		t.Fatalf("failed to make readyz request")
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusServiceUnavailable {
-		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+		t.Fatalf("failed to create token verifier: %s", err)
 	}
-	if exp, got := "[+]node ok\n[+]leader ok\n[+]store not ready", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "svc", "acl": [{"tables": ["orders"], "ops": ["insert"]}]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
 	}
+	cs.SetTokenVerifier(tv)
 
-	cnt := &atomic.Uint32{}
-	m.notReady = false
-	m.committedFn = func(timeout time.Duration) (uint64, error) {
-		cnt.Store(1)
-		return 0, fmt.Errorf("timeout")
+	m := &MockStore{
+		leaderAddr: "foo:1234",
 	}
-	resp, err = client.Get(host + "/readyz?sync")
-	if err != nil {
-		t.Fatalf("failed to make readyz request with sync set")
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.Authorizer = sql.NewAuthorizer(cs)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusServiceUnavailable {
-		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	defer s.Close()
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, nil
 	}
-	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]sync timeout", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+
+	token := signHS256(t, []byte("secret"), map[string]any{
+		"iss":   "idp",
+		"aud":   "rqlite",
+		"sub":   "svc",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"perms": []string{"execute"},
+	})
+	client := &http.Client{}
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	do := func(path, token, body string) int {
+		req, err := http.NewRequest("POST", host+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
 	}
-	if cnt.Load() != 1 {
-		t.Fatalf("failed to call committedFn")
+
+	if code := do("/db/execute", token, `["INSERT INTO orders(id) VALUES(1)"]`); code != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for execute, got %d", code)
 	}
-	m.notReady = false
-	m.committedFn = func(timeout time.Duration) (uint64, error) {
-		cnt.Store(2)
-		return 0, nil
+	if code := do("/db/execute", token, `["DELETE FROM orders"]`); code != http.StatusForbidden {
+		t.Fatalf("failed to get expected StatusForbidden for execute, got %d", code)
 	}
-	resp, err = client.Get(host + "/readyz?sync")
-	if err != nil {
-		t.Fatalf("failed to make readyz request with sync set")
+	if code := do("/db/query", token, `["SELECT * FROM orders"]`); code != http.StatusUnauthorized {
+		t.Fatalf("failed to get expected StatusUnauthorized for query, got %d", code)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	if code := do("/db/execute", token+"x", `["INSERT INTO orders(id) VALUES(1)"]`); code != http.StatusUnauthorized {
+		t.Fatalf("failed to get expected StatusUnauthorized for bad token, got %d", code)
 	}
-	if exp, got := "[+]node ok\n[+]leader ok\n[+]store ok\n[+]sync ok\n[+]db ok", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+
+	// The token is sent with requests forwarded to other nodes.
+	req := httptest.NewRequest("POST", "/db/execute", nil)
+	req.Header.Set("Authorization", "bearer "+token)
+	if creds := s.makeCredentials(req); creds.GetToken() != token || creds.GetUsername() != "" {
+		t.Fatalf("wrong credentials for bearer token: %v", creds)
 	}
-	if cnt.Load() != 2 {
-		t.Fatalf("failed to call committedFn")
+}
+
+// signHS256 returns a token containing claims, signed with secret.
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	b, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %s", err)
 	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
 
-func Test_ReadyzNoLeader(t *testing.T) {
-	m := &MockStore{}
+func Test_Verify(t *testing.T) {
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+		nodes: []*store.Server{
+			{ID: "1", Addr: "node1:4002"},
+			{ID: "2", Addr: "node2:4002"},
+			{ID: "3", Addr: "node3:4002"},
+		},
+	}
 	c := &mockClusterService{}
 	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
 	if err := s.Start(); err != nil {
@@ -1358,18 +3250,73 @@ func Test_ReadyzNoLeader(t *testing.T) {
 	}
 	defer s.Close()
 
+	sums := func(foo string) []*command.TableChecksum {
+		return []*command.TableChecksum{
+			{Table: "sqlite_schema", Rows: 2, Sum: "s"},
+			{Table: "bar", Rows: 0, Sum: "e"},
+			{Table: "foo", Start: 0, End: 99, Rows: 10, Sum: "a"},
+			{Table: "foo", Start: 100, End: 199, Rows: 5, Sum: foo},
+		}
+	}
+	m.checksumFn = func(cr *command.ChecksumRequest) (*command.ChecksumResult, error) {
+		if cr.ChunkSize != 100 {
+			t.Fatalf("wrong chunk size, exp 100, got %d", cr.ChunkSize)
+		}
+		return &command.ChecksumResult{Index: 9, ChunkSize: cr.ChunkSize, Checksums: sums("b")}, nil
+	}
+	c.getChecksumFn = func(gr *command.ChecksumResultRequest, addr string, t time.Duration) (*command.ChecksumResult, error) {
+		switch addr {
+		case "node1:4002":
+			return &command.ChecksumResult{Index: gr.Index, ChunkSize: 100, Checksums: sums("b")}, nil
+		case "node2:4002":
+			return &command.ChecksumResult{Index: gr.Index, ChunkSize: 100, Checksums: sums("c")[1:]}, nil
+		}
+		return nil, fmt.Errorf("index %d: checksum not found", gr.Index)
+	}
+
 	client := &http.Client{}
 	host := fmt.Sprintf("http://%s", s.Addr().String())
-	resp, err := client.Get(host + "/readyz")
+	resp, err := client.Post(host+"/db/verify?chunk_size=100", "", nil)
 	if err != nil {
-		t.Fatalf("failed to make readyz request")
+		t.Fatalf("failed to make verify request: %s", err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusServiceUnavailable {
-		t.Fatalf("failed to get expected StatusServiceUnavailable, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK for verify, got %d", resp.StatusCode)
 	}
-	if exp, got := "[+]node ok\n[+]leader does not exist", mustReadBody(t, resp); exp != got {
-		t.Fatalf("incorrect response body, exp: %s, got: %s", exp, got)
+	var vr VerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&vr); err != nil {
+		t.Fatalf("failed to decode verify response: %s", err)
+	}
+	if vr.Index != 9 || vr.ChunkSize != 100 || vr.Consistent {
+		t.Fatalf("unexpected verify response: %+v", vr)
+	}
+	if len(vr.Nodes) != 3 || vr.Nodes[0].Rows != 15 || vr.Nodes[0].Error != "" ||
+		vr.Nodes[2].Error != "index 9: checksum not found" {
+		t.Fatalf("unexpected nodes in verify response: %s", asJSON(vr.Nodes))
+	}
+	if exp, got := `[{"table":"foo","start":100,"end":199,"rows":{"1":5,"2":5},"checksums":{"1":"b","2":"c"}},`+
+		`{"table":"sqlite_schema","rows":{"1":2,"2":0},"checksums":{"1":"s"}}]`, asJSON(vr.Differences); exp != got {
+		t.Fatalf("unexpected differences, exp %s, got %s", exp, got)
+	}
+
+	resp, err = client.Post(host+"/db/verify?chunk_size=-1", "", nil)
+	if err != nil {
+		t.Fatalf("failed to make verify request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("failed to get expected StatusBadRequest for verify, got %d", resp.StatusCode)
+	}
+
+	// Verification is expensive, so must not be triggered by a GET.
+	resp, err = client.Get(host + "/db/verify")
+	if err != nil {
+		t.Fatalf("failed to make verify request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("failed to get expected StatusMethodNotAllowed for verify, got %d", resp.StatusCode)
 	}
 }
 
@@ -2583,14 +4530,18 @@ type MockStore struct {
 	queryFn     func(qr *command.QueryRequest) ([]*command.QueryRows, uint64, error)
 	requestFn   func(eqr *command.ExecuteQueryRequest) ([]*command.ExecuteQueryResponse, uint64, uint64, error)
 	backupFn    func(br *command.BackupRequest, dst io.Writer) error
-	loadFn      func(lr *command.LoadRequest) error
+	loadChunkFn func(lcr *command.LoadChunkRequest) error
 	snapshotFn  func(n uint64) error
 	reapFn      func() (int, int, error)
 	readFromFn  func(r io.Reader) (int64, error)
 	committedFn func(timeout time.Duration) (uint64, error)
 	stepdownFn  func(wait bool, id string) error
+	checksumFn  func(cr *command.ChecksumRequest) (*command.ChecksumResult, error)
+	nodes       []*store.Server
 	leaderAddr  string
 	notReady    bool // Default value is true, easier to test.
+	statements  map[string]string
+	users       []auth.Credential
 }
 
 func (m *MockStore) Execute(ctx context.Context, er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
@@ -2615,6 +4566,17 @@ func (m *MockStore) Request(ctx context.Context, eqr *command.ExecuteQueryReques
 	return nil, 0, 0, nil
 }
 
+func (m *MockStore) RORWCount(eqr *command.ExecuteQueryRequest) (nRW, nRO int) {
+	for _, stmt := range eqr.Request.Statements {
+		if stmt.SqlExplain || strings.HasPrefix(strings.ToUpper(stmt.Sql), "SELECT") {
+			nRO++
+		} else {
+			nRW++
+		}
+	}
+	return
+}
+
 func (m *MockStore) Join(jr *command.JoinRequest) error {
 	return nil
 }
@@ -2652,7 +4614,7 @@ func (m *MockStore) Stats() (map[string]any, error) {
 }
 
 func (m *MockStore) Nodes() ([]*store.Server, error) {
-	return nil, nil
+	return m.nodes, nil
 }
 
 func (m *MockStore) Backup(ctx context.Context, br *command.BackupRequest, w io.Writer) error {
@@ -2663,8 +4625,12 @@ func (m *MockStore) Backup(ctx context.Context, br *command.BackupRequest, w io.
 }
 
 func (m *MockStore) Load(ctx context.Context, lr *command.LoadRequest) error {
-	if m.loadFn != nil {
-		return m.loadFn(lr)
+	return nil
+}
+
+func (m *MockStore) LoadChunk(ctx context.Context, lcr *command.LoadChunkRequest) error {
+	if m.loadChunkFn != nil {
+		return m.loadChunkFn(lcr)
 	}
 	return nil
 }
@@ -2690,6 +4656,35 @@ func (m *MockStore) ReadFrom(r io.Reader) (int64, error) {
 	return 0, nil
 }
 
+func (m *MockStore) NamedStatement(name string) (string, error) {
+	if s, ok := m.statements[name]; ok {
+		return s, nil
+	}
+	return "", db.ErrNoSuchStatement
+}
+
+func (m *MockStore) NamedStatements() (map[string]string, error) {
+	return m.statements, nil
+}
+
+func (m *MockStore) Users() ([]auth.Credential, error) {
+	return m.users, nil
+}
+
+type mockCatalog struct{}
+
+func (m *mockCatalog) Functions() ([]sql.FunctionInfo, error) {
+	return []sql.FunctionInfo{{Name: "random", Builtin: true}}, nil
+}
+
+func (m *mockCatalog) Triggers(schema, table string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockCatalog) ColumnDefaults(schema, table string) ([]sql.ColumnDefault, error) {
+	return nil, nil
+}
+
 func (m *MockStore) Stepdown(wait bool, id string) error {
 	if m.stepdownFn != nil {
 		return m.stepdownFn(wait, id)
@@ -2697,6 +4692,13 @@ func (m *MockStore) Stepdown(wait bool, id string) error {
 	return nil
 }
 
+func (m *MockStore) Checksum(ctx context.Context, cr *command.ChecksumRequest) (*command.ChecksumResult, error) {
+	if m.checksumFn != nil {
+		return m.checksumFn(cr)
+	}
+	return &command.ChecksumResult{}, nil
+}
+
 func (m *MockStore) LeaderAddr() (string, error) {
 	return m.leaderAddr, nil
 }
@@ -2707,12 +4709,19 @@ type mockClusterService struct {
 	queryFn      func(qr *command.QueryRequest, addr string, t time.Duration) ([]*command.QueryRows, uint64, error)
 	requestFn    func(eqr *command.ExecuteQueryRequest, nodeAddr string, timeout time.Duration) ([]*command.ExecuteQueryResponse, uint64, uint64, error)
 	backupFn     func(br *command.BackupRequest, addr string, t time.Duration, w io.Writer) error
-	loadFn       func(lr *command.LoadRequest, addr string, t time.Duration) error
+	loadChunkFn  func(lcr *command.LoadChunkRequest, addr string, t time.Duration) error
 	removeNodeFn func(rn *command.RemoveNodeRequest, nodeAddr string, t time.Duration) error
 	stepdownFn   func(sr *command.StepdownRequest, nodeAddr string, t time.Duration) error
+	checksumFn   func(cr *command.ChecksumRequest, nodeAddr string, t time.Duration) (*command.ChecksumResult, error)
+
+	getChecksumFn func(gr *command.ChecksumResultRequest, nodeAddr string, t time.Duration) (*command.ChecksumResult, error)
+	getNodeMetaFn func(addr string) (*cluster.NodeMeta, error)
 }
 
 func (m *mockClusterService) GetNodeMeta(ctx context.Context, a string, r int, t time.Duration) (*cluster.NodeMeta, error) {
+	if m.getNodeMetaFn != nil {
+		return m.getNodeMetaFn(a)
+	}
 	return &cluster.NodeMeta{
 		Url: m.apiAddr,
 	}, nil
@@ -2747,8 +4756,12 @@ func (m *mockClusterService) Backup(ctx context.Context, br *command.BackupReque
 }
 
 func (m *mockClusterService) Load(ctx context.Context, lr *command.LoadRequest, nodeAddr string, creds *cluster.Credentials, timeout time.Duration, r int) error {
-	if m.loadFn != nil {
-		return m.loadFn(lr, nodeAddr, timeout)
+	return nil
+}
+
+func (m *mockClusterService) LoadChunk(ctx context.Context, lcr *command.LoadChunkRequest, nodeAddr string, creds *cluster.Credentials, timeout time.Duration, r int) error {
+	if m.loadChunkFn != nil {
+		return m.loadChunkFn(lcr, nodeAddr, timeout)
 	}
 	return nil
 }
@@ -2767,6 +4780,20 @@ func (m *mockClusterService) Stepdown(ctx context.Context, sr *command.StepdownR
 	return nil
 }
 
+func (m *mockClusterService) Checksum(ctx context.Context, cr *command.ChecksumRequest, addr string, creds *cluster.Credentials, t time.Duration) (*command.ChecksumResult, error) {
+	if m.checksumFn != nil {
+		return m.checksumFn(cr, addr, t)
+	}
+	return &command.ChecksumResult{}, nil
+}
+
+func (m *mockClusterService) GetChecksum(ctx context.Context, gr *command.ChecksumResultRequest, addr string, creds *cluster.Credentials, t time.Duration) (*command.ChecksumResult, error) {
+	if m.getChecksumFn != nil {
+		return m.getChecksumFn(gr, addr, t)
+	}
+	return &command.ChecksumResult{Index: gr.Index}, nil
+}
+
 type mockCredentialStore struct {
 	HasPermOK bool
 	aaFunc    func(username, password, perm string) bool
@@ -2783,6 +4810,27 @@ func (m *mockCredentialStore) AA(username, password, perm string) bool {
 	return m.HasPermOK
 }
 
+func (m *mockCredentialStore) AAToken(token, perm string) bool {
+	if m == nil {
+		return true
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) AACert(cert *x509.Certificate, perm string) bool {
+	if m == nil {
+		return true
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) CertUser(cert *x509.Certificate) (string, bool) {
+	if m == nil || cert == nil {
+		return "", false
+	}
+	return cert.Subject.CommonName, true
+}
+
 func (m *mockClusterService) Stats() (map[string]any, error) {
 	return nil, nil
 }
@@ -2794,6 +4842,17 @@ func (m *mockStatusReporter) Stats() (map[string]any, error) {
 	return nil, nil
 }
 
+type mockBackupUploader struct {
+	uploadNowFn func(ctx context.Context, name, suffix string) (*backup.UploadResult, error)
+}
+
+func (m *mockBackupUploader) UploadNow(ctx context.Context, name, suffix string) (*backup.UploadResult, error) {
+	if m.uploadNowFn == nil {
+		return &backup.UploadResult{}, nil
+	}
+	return m.uploadNowFn(ctx, name, suffix)
+}
+
 func mustNewHTTPRequest(url string) *http.Request {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auth"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
 	"github.com/rqlite/rqlite/v10/proxy"
 	"golang.org/x/net/http2"
@@ -350,6 +353,117 @@ func Test_TLSServiceSecureMutualVerifyCommonName(t *testing.T) {
 	}
 }
 
+func Test_TLSServiceSecureMutualCertUser(t *testing.T) {
+	caCertPEM, caKeyPEM, err := rtls.GenerateCACert(pkix.Name{CommonName: "ca.rqlite.io"}, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate CA cert: %s", err)
+	}
+	caCert, _ := pem.Decode(caCertPEM)
+	caKey, _ := pem.Decode(caKeyPEM)
+	if caCert == nil || caKey == nil {
+		t.Fatal("failed to decode CA certificate or key")
+	}
+	parsedCACert, err := x509.ParseCertificate(caCert.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedCAKey, err := x509.ParsePKCS1PrivateKey(caKey.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certServer, keyServer, err := rtls.GenerateCertIPSAN(pkix.Name{CommonName: "server.rqlite.io"}, time.Hour, 2048, parsedCACert, parsedCAKey, net.ParseIP("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("failed to generate server cert: %s", err)
+	}
+	certSvc, keySvc, err := rtls.GenerateCert(pkix.Name{CommonName: "svc.rqlite.io"}, time.Hour, 2048, parsedCACert, parsedCAKey)
+	if err != nil {
+		t.Fatalf("failed to generate svc client cert: %s", err)
+	}
+	certOther, keyOther, err := rtls.GenerateCert(pkix.Name{CommonName: "other.rqlite.io"}, time.Hour, 2048, parsedCACert, parsedCAKey)
+	if err != nil {
+		t.Fatalf("failed to generate other client cert: %s", err)
+	}
+
+	cs := auth.NewCredentialsStore()
+	if err := cs.Load(strings.NewReader(`[
+		{"username": "svc", "perms": ["status"], "certificates": ["cn:svc.rqlite.io"]},
+		{"username": "admin", "password": "pw", "perms": ["all"]}
+	]`)); err != nil {
+		t.Fatalf("failed to load credentials: %s", err)
+	}
+	m := &MockStore{}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), cs)
+	s.CertFile = mustWriteTempFile(t, certServer)
+	s.KeyFile = mustWriteTempFile(t, keyServer)
+	s.CACertFile = mustWriteTempFile(t, caCertPEM)
+	s.ClientVerify = true
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	get := func(certPEM, keyPEM []byte, path string, basicAuth bool) int {
+		tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM(caCertPEM); !ok {
+			t.Fatalf("failed to parse CA certificate(s)")
+		}
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatalf("failed to set X509 key pair %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		req, err := http.NewRequest("GET", fmt.Sprintf("https://%s%s", s.Addr().String(), path), nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		if basicAuth {
+			req.SetBasicAuth("admin", "pw")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make HTTP request: %s", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get(certSvc, keySvc, "/status", false); code != http.StatusOK {
+		t.Fatalf("certificate user not permitted status, got %d", code)
+	}
+	if code := get(certSvc, keySvc, "/db/backup", false); code != http.StatusUnauthorized {
+		t.Fatalf("certificate user permitted backup, got %d", code)
+	}
+	if code := get(certOther, keyOther, "/status", false); code != http.StatusUnauthorized {
+		t.Fatalf("unmapped certificate permitted status, got %d", code)
+	}
+	if code := get(certOther, keyOther, "/status", true); code != http.StatusOK {
+		t.Fatalf("Basic auth user not permitted status, got %d", code)
+	}
+
+	// The user of the certificate is sent with requests forwarded to other
+	// nodes.
+	block, _ := pem.Decode(certSvc)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse client certificate: %s", err)
+	}
+	req := httptest.NewRequest("GET", "/db/query", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, parsedCACert}}}
+	if creds := s.makeCredentials(req); creds.GetCertUser() != "svc" {
+		t.Fatalf("certificate user not in credentials: %v", creds)
+	}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{parsedCACert}}}
+	if creds := s.makeCredentials(req); creds != nil {
+		t.Fatalf("unmapped client certificate in credentials")
+	}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if creds := s.makeCredentials(req); creds != nil {
+		t.Fatalf("unverified client certificate in credentials")
+	}
+}
+
 // mustWriteTempFile writes the given bytes to a temporary file, and returns the
 // path to the file. If there is an error, it panics. The file will be automatically
 // deleted when the test ends.
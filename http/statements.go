@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/proxy"
+)
+
+// errNamedOnly is returned when a user permitted to run only named statements
+// sends SQL.
+var errNamedOnly = errors.New("only named statements permitted")
+
+// validStatementName matches the names under which statements may be registered.
+var validStatementName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,127}$`)
+
+// NamedStatement is a statement registered by name.
+type NamedStatement struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+// handleStatements manages the registry of named statements. Statements are
+// registered with PUT or POST, the body of the request being the SQL, removed
+// with DELETE, and retrieved with GET. Changes are made via the Leader, so are
+// replicated to every node. Reads are served from this node's copy of the
+// registry.
+func (s *Service) handleStatements(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if !s.CheckRequestPerm(r, auth.PermStatements) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/db/statements"), "/")
+	if name != "" && !validStatementName.MatchString(name) {
+		http.Error(w, fmt.Sprintf("invalid statement name %q", name), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.getStatements(w, name)
+	case "PUT", "POST":
+		if name == "" {
+			http.Error(w, "statement name required", http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stmt := strings.TrimSpace(string(b))
+		if stmt == "" {
+			http.Error(w, "statement SQL required", http.StatusBadRequest)
+			return
+		}
+		if db.IsBreakingPragma(stmt) {
+			http.Error(w, "disallowed pragma", http.StatusBadRequest)
+			return
+		}
+		if _, err := sql.FindReferences(stmt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if t := sql.ReferencesSystemTable(stmt); t != "" {
+			http.Error(w, fmt.Sprintf("statement may not refer to table %s", t), http.StatusBadRequest)
+			return
+		}
+		s.updateSystemTable(w, r, qp, db.CreateStatementsTableSQL, nil, &proto.Statement{
+			Sql: sql.PutStatementSQL,
+			Parameters: []*proto.Parameter{
+				{Value: &proto.Parameter_S{S: name}},
+				{Value: &proto.Parameter_S{S: stmt}},
+			},
+		})
+	case "DELETE":
+		if name == "" {
+			http.Error(w, "statement name required", http.StatusBadRequest)
+			return
+		}
+		s.updateSystemTable(w, r, qp, db.CreateStatementsTableSQL, db.ErrNoSuchStatement, &proto.Statement{
+			Sql: sql.DeleteStatementSQL,
+			Parameters: []*proto.Parameter{
+				{Value: &proto.Parameter_S{S: name}},
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Service) getStatements(w http.ResponseWriter, name string) {
+	var v any
+	if name == "" {
+		stmts, err := s.store.NamedStatements()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		list := make([]NamedStatement, 0, len(stmts))
+		for n, q := range stmts {
+			list = append(list, NamedStatement{Name: n, SQL: q})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		v = list
+	} else {
+		q, err := s.store.NamedStatement(name)
+		if err != nil {
+			if errors.Is(err, db.ErrNoSuchStatement) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		v = NamedStatement{Name: name, SQL: q}
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// updateSystemTable executes the given change to a table managed by rqlite,
+// such as the registry of named statements, creating the table first with
+// createSQL. If errNotFound is not nil, and the change affects no rows,
+// errNotFound is returned to the client with 404 Not Found.
+func (s *Service) updateSystemTable(w http.ResponseWriter, r *http.Request, qp QueryParams, createSQL string,
+	errNotFound error, stmt *proto.Statement) {
+	er := &proto.ExecuteRequest{
+		Request: &proto.Request{
+			Statements:  []*proto.Statement{{Sql: createSQL}, stmt},
+			Transaction: true,
+		},
+	}
+	results, _, addr, err := s.proxy.Execute(r.Context(), er, s.makeCredentials(r),
+		qp.Timeout(defaultTimeout), qp.Retries(0), qp.Redirect())
+	if err != nil {
+		if errors.Is(err, proxy.ErrNotLeader) {
+			s.DoRedirect(w, r, qp)
+			return
+		}
+		if errors.Is(err, proxy.ErrLeaderNotFound) {
+			stats.Add(numLeaderNotFound, 1)
+			http.Error(w, proxy.ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, proxy.ErrUnauthorized) {
+			http.Error(w, "remote update not authorized", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(ServedByHTTPHeader, addr)
+	for _, res := range results {
+		if e := res.GetError(); e != "" {
+			http.Error(w, e, http.StatusInternalServerError)
+			return
+		}
+	}
+	if errNotFound != nil && len(results) == 2 && results[1].GetE().GetRowsAffected() == 0 {
+		http.Error(w, errNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// CheckRequestPermOrNamed checks if the request is authenticated and authorized
+// with all the given Perms. If it is not, but the user may run named statements,
+// the request is authorized but namedOnly is true. The caller must then check
+// that the request contains only named statements.
+func (s *Service) CheckRequestPermOrNamed(r *http.Request, perms ...string) (ok, namedOnly bool) {
+	if s.CheckRequestPermAll(r, perms...) {
+		return true, false
+	}
+	if s.CheckRequestPerm(r, auth.PermNamedStatements) {
+		return true, true
+	}
+	return false, false
+}
+
+// expandNamed prepares any named statements in stmts, and must be called
+// before the statements are processed. It returns false, having written the
+// response, if the statements cannot be prepared.
+func (s *Service) expandNamed(w http.ResponseWriter, stmts []*proto.Statement) bool {
+	err := sql.ExpandNamed(stmts, s.store.NamedStatement, s.analysisCache)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, db.ErrNoSuchStatement) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	http.Error(w, fmt.Sprintf("SQL rewrite: %s", err.Error()), http.StatusInternalServerError)
+	return false
+}
+
+// allNamed returns whether every statement in stmts is a named statement.
+func allNamed(stmts []*proto.Statement) bool {
+	for _, stmt := range stmts {
+		if stmt.Name == "" || stmt.Sql != "" {
+			return false
+		}
+	}
+	return true
+}
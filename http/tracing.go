@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces the requests handled by the HTTP service.
+var tracer = tracing.Tracer("http")
+
+// startSpan starts a span covering the handling of r, continuing any trace
+// started by the client. It returns the writer to which the response must be
+// written, so that the status of the response is recorded, the request, with
+// the span in its context, and a function which ends the span.
+func startSpan(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	route := httpRoute(r.URL.Path)
+	ctx, span := tracer.Start(ctx, r.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+		))
+	if !span.IsRecording() {
+		return w, r.WithContext(ctx), func() { span.End() }
+	}
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	return sw, r.WithContext(ctx), func() {
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+		span.End()
+	}
+}
+
+// httpRoute returns the route of path, which is at most its first two
+// segments, so that paths which name a resource are traced together.
+func httpRoute(path string) string {
+	segs := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(segs) > 2 {
+		segs = segs[:2]
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+// statusWriter is an http.ResponseWriter which records the status of the
+// response written to it.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status, and writes it to the underlying writer.
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes b to the underlying writer.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	command "github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/proxy"
+	"github.com/rqlite/rqlite/v10/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_ExecuteTraced(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	m := &MockStore{
+		leaderAddr: "foo:1234",
+	}
+	m.executeFn = func(er *command.ExecuteRequest) ([]*command.ExecuteQueryResponse, uint64, error) {
+		return nil, 0, store.ErrNotLeader
+	}
+	c := &mockClusterService{}
+	s := New("127.0.0.1:0", m, c, proxy.New(m, c), nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service")
+	}
+	defer s.Close()
+
+	host := fmt.Sprintf("http://%s", s.Addr().String())
+	req, err := http.NewRequest("POST", host+"/db/execute", strings.NewReader(`["INSERT INTO foo(id) VALUES(1)"]`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make execute request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to get expected StatusOK, got %d", resp.StatusCode)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		spans[span.Name()] = span
+	}
+	server, ok := spans["POST /db/execute"]
+	if !ok {
+		t.Fatalf("HTTP span not recorded, got %v", spans)
+	}
+	if exp, got := "0af7651916cd43dd8448eb211c80319c", server.SpanContext().TraceID().String(); exp != got {
+		t.Fatalf("HTTP span does not continue the trace of the client, exp %s, got %s", exp, got)
+	}
+	forward, ok := spans["proxy.forward Execute"]
+	if !ok {
+		t.Fatalf("forwarding span not recorded, got %v", spans)
+	}
+	if forward.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("forwarding span is not a child of the HTTP span")
+	}
+	for _, kv := range server.Attributes() {
+		if kv.Key == "http.response.status_code" && kv.Value.AsInt64() != http.StatusOK {
+			t.Fatalf("wrong status code on HTTP span, got %d", kv.Value.AsInt64())
+		}
+	}
+}
+
+func Test_HTTPRoute(t *testing.T) {
+	for path, exp := range map[string]string{
+		"":                    "/",
+		"/":                   "/",
+		"/status":             "/status",
+		"/db/execute":         "/db/execute",
+		"/auth/users/alice":   "/auth/users",
+		"/db/statements/a/b/": "/db/statements",
+	} {
+		if got := httpRoute(path); exp != got {
+			t.Fatalf("wrong route for %q, exp %s, got %s", path, exp, got)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/db"
+)
+
+// errNoSuchUser is returned when a user is not stored in the database.
+var errNoSuchUser = errors.New("no such user")
+
+// validUsername matches the names under which users may be stored.
+var validUsername = regexp.MustCompile(`^[^\s/]{1,128}$`)
+
+// handleUsers manages the users stored in the database. A user is created or
+// replaced with PUT or POST, the body of the request being the credential of
+// the user, removed with DELETE, and retrieved with GET. Passwords are hashed
+// before they are stored, and are never returned. A password which is already
+// a hash is stored as given, but only if it is well-formed. If a user is replaced
+// without a password, the user keeps their existing password, but a new user
+// requires one. Only perms held by the user making the request may be granted,
+// though table ACLs are not compared, so a user restricted by one may create
+// a user which is not. Changes are made via the Leader, so are replicated to
+// every node.
+func (s *Service) handleUsers(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if !s.UserManagement {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !s.CheckRequestPerm(r, auth.PermUsers) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/auth/users"), "/")
+	if name != "" && (name == auth.AllUsers || !validUsername.MatchString(name)) {
+		http.Error(w, fmt.Sprintf("invalid username %q", name), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.getUsers(w, name)
+	case "PUT", "POST":
+		if name == "" {
+			http.Error(w, "username required", http.StatusBadRequest)
+			return
+		}
+		var cred auth.Credential
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cred); err != nil {
+			http.Error(w, fmt.Sprintf("invalid credential: %s", err), http.StatusBadRequest)
+			return
+		}
+		if cred.Username != "" && cred.Username != name {
+			http.Error(w, "username does not match path", http.StatusBadRequest)
+			return
+		}
+		cred.Username = name
+		if err := cred.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, perm := range cred.Perms {
+			if !s.CheckRequestPerm(r, perm) {
+				http.Error(w, fmt.Sprintf("perm %s not held, so may not be granted", perm), http.StatusForbidden)
+				return
+			}
+		}
+		if auth.IsHashed(cred.Password) {
+			// A stored hash is checked on every login by every node, so it
+			// must be one that can be checked safely.
+			if err := auth.ValidateHash(cred.Password); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else if cred.Password != "" {
+			h, err := auth.HashPassword(cred.Password, auth.HashBcrypt, 0)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cred.Password = h
+		} else if ok, err := s.userExists(name); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if !ok {
+			http.Error(w, "password required for new user", http.StatusBadRequest)
+			return
+		}
+		cred.Username = ""
+		b, err := json.Marshal(cred)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Without a password, the existing password, if any, is kept.
+		q := sql.PutUserSQL
+		if cred.Password == "" {
+			q = sql.UpdateUserSQL
+		}
+		s.updateSystemTable(w, r, qp, db.CreateUsersTableSQL, nil, &proto.Statement{
+			Sql: q,
+			Parameters: []*proto.Parameter{
+				{Value: &proto.Parameter_S{S: name}},
+				{Value: &proto.Parameter_S{S: string(b)}},
+			},
+		})
+	case "DELETE":
+		if name == "" {
+			http.Error(w, "username required", http.StatusBadRequest)
+			return
+		}
+		s.updateSystemTable(w, r, qp, db.CreateUsersTableSQL, errNoSuchUser, &proto.Statement{
+			Sql: sql.DeleteUserSQL,
+			Parameters: []*proto.Parameter{
+				{Value: &proto.Parameter_S{S: name}},
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// userExists returns whether the named user is stored in the database. A user
+// removed after the check is recreated without a password, so cannot log in
+// with one.
+func (s *Service) userExists(name string) (bool, error) {
+	users, err := s.store.Users()
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.Username == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) getUsers(w http.ResponseWriter, name string) {
+	users, err := s.store.Users()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	var v any
+	if name == "" {
+		if users == nil {
+			users = []auth.Credential{}
+		}
+		v = users
+	} else {
+		for _, u := range users {
+			if u.Username == name {
+				v = u
+				break
+			}
+		}
+		if v == nil {
+			http.Error(w, errNoSuchUser.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/rqlite/rqlite/v10/auth"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/proxy"
+	"github.com/rqlite/rqlite/v10/store"
+)
+
+// VerifyNode is the outcome of checksumming the database on a single node.
+type VerifyNode struct {
+	ID    string `json:"id"`
+	Addr  string `json:"addr"`
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyDifference is a table, or a range of rowids in a table, whose content
+// differs between nodes. Rows and Checksums are keyed by node ID. A node which
+// has no rows in the range, or does not have the table, has no checksum.
+type VerifyDifference struct {
+	Table     string            `json:"table"`
+	Start     *int64            `json:"start,omitempty"`
+	End       *int64            `json:"end,omitempty"`
+	Rows      map[string]int64  `json:"rows"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// VerifyResponse is the report of a cluster-wide verification. The database
+// of every node is checksummed at the same log index, so any difference
+// between nodes is a real divergence of their data. The cluster is
+// consistent if every node was checksummed, and no differences were found.
+type VerifyResponse struct {
+	Index       uint64              `json:"index"`
+	ChunkSize   int64               `json:"chunk_size,omitempty"`
+	Consistent  bool                `json:"consistent"`
+	Nodes       []*VerifyNode       `json:"nodes"`
+	Differences []*VerifyDifference `json:"differences,omitempty"`
+}
+
+// handleVerify checksums the database on every node at the same log index,
+// and reports any tables, or ranges of rows, which differ between nodes.
+// Every row of every table is read on every node, so on a large database
+// this is expensive. Writes continue while the checksums are computed, but
+// snapshots are delayed until they are done.
+func (s *Service) handleVerify(w http.ResponseWriter, r *http.Request, qp QueryParams) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if !s.CheckRequestPerm(r, auth.PermStatus) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	chunkSize := qp.ChunkSize(0)
+	if chunkSize < 0 {
+		http.Error(w, "chunk_size must not be negative", http.StatusBadRequest)
+		return
+	}
+	timeout := qp.Timeout(defaultTimeout)
+	creds := s.makeCredentials(r)
+
+	res, addr, err := s.proxy.Checksum(r.Context(), &proto.ChecksumRequest{ChunkSize: int64(chunkSize)},
+		creds, timeout, qp.Redirect())
+	if err != nil {
+		if errors.Is(err, proxy.ErrNotLeader) {
+			s.DoRedirect(w, r, qp)
+			return
+		}
+		if errors.Is(err, proxy.ErrLeaderNotFound) {
+			stats.Add(numLeaderNotFound, 1)
+			http.Error(w, proxy.ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, proxy.ErrUnauthorized) {
+			http.Error(w, "remote checksum not authorized", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	servers, err := s.store.Nodes()
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == store.ErrNotOpen {
+			statusCode = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Sprintf("store nodes: %s", err.Error()), statusCode)
+		return
+	}
+
+	// Retrieve the checksums each node computed at the same index.
+	results := make([]*proto.ChecksumResult, len(servers))
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			results[i], errs[i] = s.cluster.GetChecksum(ctx, &proto.ChecksumResultRequest{Index: res.Index},
+				srv.Addr, creds, timeout)
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set(ServedByHTTPHeader, addr)
+	vr := compareChecksums(res, servers, results, errs)
+	enc := json.NewEncoder(w)
+	if qp.Pretty() {
+		enc.SetIndent("", "    ")
+	}
+	if err := enc.Encode(vr); err != nil {
+		http.Error(w, fmt.Sprintf("JSON marshal: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// compareChecksums compares the checksums computed by each node, and returns
+// the resulting report.
+func compareChecksums(res *proto.ChecksumResult, servers []*store.Server, results []*proto.ChecksumResult, errs []error) *VerifyResponse {
+	vr := &VerifyResponse{
+		Index:      res.Index,
+		ChunkSize:  res.ChunkSize,
+		Consistent: true,
+		Nodes:      make([]*VerifyNode, 0, len(servers)),
+	}
+
+	type key struct {
+		table      string
+		start, end int64
+	}
+	byKey := make(map[key]map[string]*proto.TableChecksum)
+	var ids []string
+	for i, srv := range servers {
+		vn := &VerifyNode{ID: srv.ID, Addr: srv.Addr}
+		vr.Nodes = append(vr.Nodes, vn)
+		if errs[i] != nil {
+			vn.Error = errs[i].Error()
+			vr.Consistent = false
+			continue
+		}
+		ids = append(ids, srv.ID)
+		for _, ts := range results[i].Checksums {
+			k := key{ts.Table, ts.Start, ts.End}
+			if byKey[k] == nil {
+				byKey[k] = make(map[string]*proto.TableChecksum)
+			}
+			byKey[k][srv.ID] = ts
+			if ts.Table != db.SchemaChecksumTable {
+				vn.Rows += ts.Rows
+			}
+		}
+	}
+
+	for k, sums := range byKey {
+		differ := len(sums) != len(ids)
+		var first string
+		for _, ts := range sums {
+			if first == "" {
+				first = ts.Sum
+			} else if ts.Sum != first {
+				differ = true
+			}
+		}
+		if !differ {
+			continue
+		}
+		vd := &VerifyDifference{
+			Table:     k.table,
+			Rows:      make(map[string]int64, len(ids)),
+			Checksums: make(map[string]string, len(ids)),
+		}
+		if k.start != 0 || k.end != 0 {
+			vd.Start, vd.End = &k.start, &k.end
+		}
+		for _, id := range ids {
+			if ts, ok := sums[id]; ok {
+				vd.Rows[id] = ts.Rows
+				vd.Checksums[id] = ts.Sum
+			} else {
+				vd.Rows[id] = 0
+			}
+		}
+		vr.Differences = append(vr.Differences, vd)
+	}
+	sort.Slice(vr.Differences, func(i, j int) bool {
+		a, b := vr.Differences[i], vr.Differences[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Start != nil && b.Start != nil && *a.Start < *b.Start
+	})
+	if len(vr.Differences) > 0 {
+		vr.Consistent = false
+	}
+	return vr
+}
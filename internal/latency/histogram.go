@@ -0,0 +1,294 @@
+// Package latency provides HDR-style histograms of durations, such as the
+// latency of requests. Histograms are expvar Vars, so they are registered
+// in the expvar maps of the packages which record them, and served with
+// the other metrics of those packages.
+package latency
+
+import (
+	"encoding/json"
+	"expvar"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the default window over which a Histogram reports
+// percentiles.
+const DefaultWindow = time.Minute
+
+const (
+	// Values below linearMax microseconds are recorded exactly. Each power
+	// of two above that is divided into subBuckets linear buckets, so that
+	// recorded values are within 1/subBuckets of the actual value.
+	linearBits = 5
+	linearMax  = 1 << linearBits
+	subBits    = 4
+	subBuckets = 1 << subBits
+
+	// maxBits bounds the largest recordable value, about 12 days.
+	maxBits    = 40
+	maxValue   = 1<<maxBits - 1
+	numBuckets = linearMax + (maxBits-linearBits)*subBuckets
+)
+
+// Bounds are the upper bounds, in seconds, of the cumulative buckets
+// exported by Snapshot.
+var Bounds = []float64{
+	0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// boundsUS are Bounds in microseconds.
+var boundsUS = func() []int64 {
+	b := make([]int64, len(Bounds))
+	for i := range Bounds {
+		b[i] = int64(Bounds[i] * 1e6)
+	}
+	return b
+}()
+
+// quantiles are the quantiles reported by Stats.
+var quantiles = []struct {
+	name string
+	q    float64
+}{
+	{"p50", 0.5},
+	{"p90", 0.9},
+	{"p99", 0.99},
+	{"p999", 0.999},
+}
+
+// window holds the values recorded during a window.
+type window struct {
+	counts   [numBuckets]uint64
+	count    uint64
+	min, max int64
+}
+
+func (w *window) reset() {
+	*w = window{}
+}
+
+// Histogram records durations with microsecond resolution, and a relative
+// error of at most 1/16. Percentiles are reported over a sliding window,
+// which covers between one and two windows of recent values, so that they
+// reflect current latency rather than latency since the process started.
+// Counts for export to monitoring systems are cumulative. It is safe for
+// concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	window time.Duration
+
+	// cur holds the values recorded since curStart, and prev those recorded
+	// in the window before.
+	cur, prev *window
+	curStart  time.Time
+
+	// Cumulative counts, in the buckets defined by Bounds.
+	buckets []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+// NewHistogram returns a new Histogram reporting percentiles over the given
+// window. If window is zero, DefaultWindow is used.
+func NewHistogram(window time.Duration) *Histogram {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	h := &Histogram{
+		window: window,
+	}
+	h.Reset()
+	return h
+}
+
+// Record records the duration d.
+func (h *Histogram) Record(d time.Duration) {
+	us := min(max(d.Microseconds(), 0), maxValue)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rotate(time.Now())
+	w := h.cur
+	w.counts[bucketIndex(us)]++
+	if w.count == 0 || us < w.min {
+		w.min = us
+	}
+	if us > w.max {
+		w.max = us
+	}
+	w.count++
+
+	h.buckets[sort.Search(len(boundsUS), func(i int) bool { return boundsUS[i] >= us })]++
+	h.count++
+	h.sum += d
+}
+
+// RecordSince records the time elapsed since t.
+func (h *Histogram) RecordSince(t time.Time) {
+	h.Record(time.Since(t))
+}
+
+// Reset discards all recorded values, and starts a new window.
+func (h *Histogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cur = &window{}
+	h.prev = &window{}
+	h.curStart = time.Now()
+	h.buckets = make([]uint64, len(Bounds)+1)
+	h.count = 0
+	h.sum = 0
+}
+
+// rotate starts a new window, if the current one has ended. It must be
+// called with mu held.
+func (h *Histogram) rotate(now time.Time) {
+	elapsed := now.Sub(h.curStart)
+	if elapsed < h.window {
+		return
+	}
+	if elapsed < 2*h.window {
+		h.prev, h.cur = h.cur, h.prev
+	} else {
+		h.prev.reset()
+	}
+	h.cur.reset()
+	h.curStart = now
+}
+
+// Snapshot is the cumulative state of a Histogram.
+type Snapshot struct {
+	// BucketCounts are the number of values recorded in each bucket. The
+	// upper bounds of the buckets are Bounds, followed by +Inf.
+	BucketCounts []uint64
+
+	// Count is the number of values recorded.
+	Count uint64
+
+	// Sum is the sum of the values recorded.
+	Sum time.Duration
+}
+
+// Snapshot returns the cumulative state of the Histogram.
+func (h *Histogram) Snapshot() Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Snapshot{
+		BucketCounts: append([]uint64(nil), h.buckets...),
+		Count:        h.count,
+		Sum:          h.sum,
+	}
+}
+
+// Stats returns the percentiles of the durations recorded in the current
+// window, in milliseconds, and the cumulative count and sum.
+func (h *Histogram) Stats() map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rotate(time.Now())
+
+	var merged window
+	for i := range merged.counts {
+		merged.counts[i] = h.prev.counts[i] + h.cur.counts[i]
+	}
+	merged.count = h.prev.count + h.cur.count
+	merged.min, merged.max = h.cur.min, h.cur.max
+	if h.prev.count > 0 {
+		if h.cur.count == 0 || h.prev.min < merged.min {
+			merged.min = h.prev.min
+		}
+		merged.max = max(merged.max, h.prev.max)
+	}
+
+	stats := map[string]any{
+		"count":        h.count,
+		"sum_ms":       ms(h.sum.Microseconds()),
+		"window":       h.window.String(),
+		"window_count": merged.count,
+	}
+	if merged.count == 0 {
+		return stats
+	}
+	stats["min_ms"] = ms(merged.min)
+	stats["max_ms"] = ms(merged.max)
+	for _, q := range quantiles {
+		stats[q.name+"_ms"] = ms(merged.quantile(q.q))
+	}
+	return stats
+}
+
+// String returns the Stats of the Histogram as JSON, so that a Histogram
+// may be served as an expvar Var.
+func (h *Histogram) String() string {
+	b, err := json.Marshal(h.Stats())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// quantile returns the value, in microseconds, at quantile q of the values
+// in w. w must not be empty.
+func (w *window) quantile(q float64) int64 {
+	rank := max(uint64(q*float64(w.count)+0.5), 1)
+	var seen uint64
+	for i, c := range w.counts {
+		seen += c
+		if seen >= rank {
+			return min(max(bucketHighest(i), w.min), w.max)
+		}
+	}
+	return w.max
+}
+
+// bucketIndex returns the index of the bucket holding v microseconds.
+func bucketIndex(v int64) int {
+	if v < linearMax {
+		return int(v)
+	}
+	k := bits.Len64(uint64(v)) - 1
+	shift := k - subBits
+	sub := int(v>>shift) - subBuckets
+	return linearMax + (k-linearBits)*subBuckets + sub
+}
+
+// bucketHighest returns the highest value, in microseconds, held by the
+// bucket with index i.
+func bucketHighest(i int) int64 {
+	if i < linearMax {
+		return int64(i)
+	}
+	k := linearBits + (i-linearMax)/subBuckets
+	sub := (i - linearMax) % subBuckets
+	shift := k - subBits
+	return int64(subBuckets+sub+1)<<shift - 1
+}
+
+func ms(us int64) float64 {
+	return float64(us) / 1000
+}
+
+// Stats returns the Stats of every Histogram registered in a top-level
+// expvar map, keyed by the name of the map, then by key.
+func Stats() map[string]any {
+	all := make(map[string]any)
+	expvar.Do(func(kv expvar.KeyValue) {
+		m, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+		hs := make(map[string]any)
+		m.Do(func(mkv expvar.KeyValue) {
+			if h, ok := mkv.Value.(*Histogram); ok {
+				hs[mkv.Key] = h.Stats()
+			}
+		})
+		if len(hs) > 0 {
+			all[kv.Key] = hs
+		}
+	})
+	return all
+}
@@ -0,0 +1,136 @@
+package latency
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func Test_BucketIndex(t *testing.T) {
+	for v := range int64(1 << 20) {
+		i := bucketIndex(v)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("bucket index of %d out of range: %d", v, i)
+		}
+		hi := bucketHighest(i)
+		if hi < v {
+			t.Fatalf("bucket %d holding %d has highest value %d", i, v, hi)
+		}
+		if float64(hi-v) > float64(v)/subBuckets {
+			t.Fatalf("bucket %d holding %d has highest value %d, error too large", i, v, hi)
+		}
+	}
+	if i := bucketIndex(maxValue); i != numBuckets-1 {
+		t.Fatalf("bucket index of max value is %d, exp %d", i, numBuckets-1)
+	}
+}
+
+func Test_HistogramStats(t *testing.T) {
+	h := NewHistogram(0)
+	if s := h.Stats(); s["window_count"] != uint64(0) || s["p99_ms"] != nil {
+		t.Fatalf("unexpected stats for empty histogram: %v", s)
+	}
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	s := h.Stats()
+	if s["count"] != uint64(1000) || s["window_count"] != uint64(1000) {
+		t.Fatalf("unexpected counts: %v", s)
+	}
+	if s["min_ms"] != 1.0 || s["max_ms"] != 1000.0 {
+		t.Fatalf("unexpected min or max: %v", s)
+	}
+	for _, tt := range []struct {
+		name string
+		exp  float64
+	}{
+		{"p50_ms", 500},
+		{"p90_ms", 900},
+		{"p99_ms", 990},
+		{"p999_ms", 999},
+	} {
+		got := s[tt.name].(float64)
+		if got < tt.exp || got > tt.exp*(1+1.0/subBuckets) {
+			t.Fatalf("%s is %f, exp about %f", tt.name, got, tt.exp)
+		}
+	}
+	if s["sum_ms"] != 500500.0 {
+		t.Fatalf("unexpected sum: %v", s["sum_ms"])
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal([]byte(h.String()), &m); err != nil {
+		t.Fatalf("String is not valid JSON: %s", err)
+	}
+	if m["count"] != 1000.0 {
+		t.Fatalf("unexpected count in JSON: %v", m)
+	}
+
+	h.Reset()
+	if s := h.Stats(); s["count"] != uint64(0) || s["window_count"] != uint64(0) {
+		t.Fatalf("unexpected stats after reset: %v", s)
+	}
+}
+
+func Test_HistogramWindow(t *testing.T) {
+	h := NewHistogram(100 * time.Millisecond)
+	h.Record(time.Second)
+	time.Sleep(150 * time.Millisecond)
+
+	// The previous window is still reported.
+	h.Record(time.Millisecond)
+	s := h.Stats()
+	if s["window_count"] != uint64(2) || s["max_ms"] != 1000.0 {
+		t.Fatalf("unexpected stats one window later: %v", s)
+	}
+
+	// Values recorded more than two windows ago are not.
+	time.Sleep(250 * time.Millisecond)
+	s = h.Stats()
+	if s["window_count"] != uint64(0) || s["count"] != uint64(2) {
+		t.Fatalf("unexpected stats two windows later: %v", s)
+	}
+}
+
+func Test_HistogramSnapshot(t *testing.T) {
+	h := NewHistogram(0)
+	h.Record(50 * time.Microsecond)
+	h.Record(100 * time.Microsecond)
+	h.Record(3 * time.Millisecond)
+	h.Record(2 * time.Minute)
+
+	snap := h.Snapshot()
+	if len(snap.BucketCounts) != len(Bounds)+1 {
+		t.Fatalf("exp %d buckets, got %d", len(Bounds)+1, len(snap.BucketCounts))
+	}
+	if snap.Count != 4 || snap.Sum != 2*time.Minute+3150*time.Microsecond {
+		t.Fatalf("unexpected count or sum: %v", snap)
+	}
+	exp := map[int]uint64{0: 2, 4: 1, len(Bounds): 1}
+	for i, c := range snap.BucketCounts {
+		if c != exp[i] {
+			t.Fatalf("bucket %d has count %d, exp %d", i, c, exp[i])
+		}
+	}
+}
+
+func Test_Stats(t *testing.T) {
+	m := expvar.NewMap("latency_test")
+	m.Add("not_a_histogram", 1)
+	h := NewHistogram(0)
+	h.Record(time.Millisecond)
+	m.Set("op_latency", h)
+
+	stats, ok := Stats()["latency_test"].(map[string]any)
+	if !ok {
+		t.Fatalf("histograms of expvar map not returned")
+	}
+	if len(stats) != 1 {
+		t.Fatalf("exp 1 histogram, got %d", len(stats))
+	}
+	if s := stats["op_latency"].(map[string]any); s["count"] != uint64(1) {
+		t.Fatalf("unexpected stats: %v", s)
+	}
+}
@@ -0,0 +1,357 @@
+// Package logging provides the loggers of rqlite's subsystems. Each
+// subsystem, such as the store or the HTTP service, logs with its own
+// level, which may be changed at any time. Logs are written either as
+// text, prefixed by the subsystem in brackets, or as JSON, with the fields
+// time, level, msg, node_id and subsystem, and where known raft_index and
+// request_id.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FormatText writes logs as text lines, as the standard log package does.
+	FormatText = "text"
+
+	// FormatJSON writes logs as JSON objects, one per line.
+	FormatJSON = "json"
+)
+
+// DefaultSubsystem is the name under which the default level, used by
+// subsystems without a level of their own, is reported and set.
+const DefaultSubsystem = "default"
+
+// LevelTrace is the level of Raft's trace logs.
+const LevelTrace = slog.LevelDebug - 4
+
+// Names of the fields of JSON logs.
+const (
+	NodeIDKey    = "node_id"
+	SubsystemKey = "subsystem"
+	RaftIndexKey = "raft_index"
+	RequestIDKey = "request_id"
+)
+
+var (
+	mu       sync.RWMutex
+	jsonOut  slog.Handler // nil unless logs are written as JSON.
+	nodeID   string
+	defLevel = slog.LevelInfo
+	levels   = make(map[string]slog.Level)
+)
+
+// Configure sets the format of all logs, which are written to w if the
+// format is JSON, and the ID of the node, which is included in JSON logs.
+func Configure(format string, w io.Writer, id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch format {
+	case FormatText:
+		jsonOut = nil
+	case FormatJSON:
+		jsonOut = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: LevelTrace})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	nodeID = id
+	return nil
+}
+
+// SetLevel sets the level of the given subsystem, or the default level if
+// subsystem is DefaultSubsystem.
+func SetLevel(subsystem string, level slog.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	if subsystem == DefaultSubsystem {
+		defLevel = level
+		return
+	}
+	levels[subsystem] = level
+}
+
+// ClearLevel removes the level of the given subsystem, which then logs at
+// the default level.
+func ClearLevel(subsystem string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(levels, subsystem)
+}
+
+// Levels returns the default level, and the levels of all subsystems which
+// have their own.
+func Levels() map[string]string {
+	mu.RLock()
+	defer mu.RUnlock()
+	m := map[string]string{DefaultSubsystem: defLevel.String()}
+	for k, l := range levels {
+		m[k] = l.String()
+	}
+	return m
+}
+
+// ParseLevel parses a level such as "debug" or "WARN". "trace" is also
+// accepted, for Raft.
+func ParseLevel(s string) (slog.Level, error) {
+	if strings.EqualFold(s, "trace") {
+		return LevelTrace, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, err
+	}
+	return l, nil
+}
+
+// ParseLevels parses a comma-delimited list of subsystem=level pairs, such
+// as "store=debug,raft=warn".
+func ParseLevels(s string) (map[string]slog.Level, error) {
+	m := make(map[string]slog.Level)
+	for pair := range strings.SplitSeq(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		sub, lvl, ok := strings.Cut(pair, "=")
+		if !ok || sub == "" {
+			return nil, fmt.Errorf("invalid subsystem level %q, must be subsystem=level", pair)
+		}
+		l, err := ParseLevel(lvl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for subsystem %s: %s", sub, err)
+		}
+		m[sub] = l
+	}
+	return m, nil
+}
+
+func enabled(subsystem string, level slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	threshold, ok := levels[subsystem]
+	if !ok {
+		threshold = defLevel
+	}
+	return level >= threshold
+}
+
+func jsonHandler() (slog.Handler, string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return jsonOut, nodeID
+}
+
+type ctxKey int
+
+const (
+	raftIndexCtxKey ctxKey = iota
+	requestIDCtxKey
+)
+
+// WithRaftIndex returns ctx carrying the given Raft index, which is logged
+// by loggers returned by Logger.
+func WithRaftIndex(ctx context.Context, idx uint64) context.Context {
+	return context.WithValue(ctx, raftIndexCtxKey, idx)
+}
+
+// WithRequestID returns ctx carrying the given request ID, which is logged
+// by loggers returned by Logger.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
+}
+
+// New returns a standard logger for the given subsystem. Text logs are
+// written to w. Messages are logged at Info, unless logged with Warnf,
+// Errorf or Fatalf.
+func New(subsystem string, w io.Writer) *log.Logger {
+	return log.New(&lineWriter{subsystem: subsystem, w: w}, "", 0)
+}
+
+// NewTagged returns a standard logger for a subsystem, such as Raft, whose
+// messages begin with a level tag, such as "[WARN]". Messages are logged at
+// the level of their tag, or at Info if they have none.
+func NewTagged(subsystem string, w io.Writer) *log.Logger {
+	return log.New(&lineWriter{subsystem: subsystem, w: w, tagged: true}, "", 0)
+}
+
+// Warnf logs a message at Warn using l, which should be a logger returned
+// by New. Other loggers log the message as Printf does.
+func Warnf(l *log.Logger, format string, v ...any) {
+	logf(l, slog.LevelWarn, format, v...)
+}
+
+// Errorf logs a message at Error using l, which should be a logger returned
+// by New. Other loggers log the message as Printf does.
+func Errorf(l *log.Logger, format string, v ...any) {
+	logf(l, slog.LevelError, format, v...)
+}
+
+// Fatalf logs a message at Error, as Errorf does, and then exits the
+// process, as log.Fatalf does.
+func Fatalf(l *log.Logger, format string, v ...any) {
+	logf(l, slog.LevelError, format, v...)
+	os.Exit(1)
+}
+
+func logf(l *log.Logger, level slog.Level, format string, v ...any) {
+	msg := fmt.Sprintf(format, v...)
+	if lw, ok := l.Writer().(*lineWriter); ok {
+		lw.write(level, msg)
+		return
+	}
+	l.Output(3, msg)
+}
+
+// Logger returns a structured logger for the given subsystem. Text logs
+// are written to os.Stderr.
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem, w: os.Stderr})
+}
+
+// lineWriter writes the lines of a standard logger.
+type lineWriter struct {
+	subsystem string
+	w         io.Writer
+	tagged    bool // Do lines begin with a level tag?
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := slog.LevelInfo
+	if lw.tagged {
+		level, msg = tagLevel(msg)
+	}
+	if err := lw.write(level, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write logs msg at the given level.
+func (lw *lineWriter) write(level slog.Level, msg string) error {
+	if !enabled(lw.subsystem, level) {
+		return nil
+	}
+	if h, id := jsonHandler(); h != nil {
+		r := slog.NewRecord(time.Now(), level, msg, 0)
+		r.AddAttrs(slog.String(NodeIDKey, id), slog.String(SubsystemKey, lw.subsystem))
+		return h.Handle(context.Background(), r)
+	}
+	_, err := fmt.Fprintf(lw.w, "[%s] %s%s\n", lw.subsystem, timestamp(), msg)
+	return err
+}
+
+// raftLevels are the level tags of Raft's log lines.
+var raftLevels = []struct {
+	tag   string
+	level slog.Level
+}{
+	{"[TRACE]", LevelTrace},
+	{"[DEBUG]", slog.LevelDebug},
+	{"[INFO]", slog.LevelInfo},
+	{"[WARN]", slog.LevelWarn},
+	{"[ERROR]", slog.LevelError},
+}
+
+// tagLevel returns the level of a line which begins with a level tag, or
+// Info if it does not. The tag is removed from JSON logs, which have a
+// level field of their own.
+func tagLevel(msg string) (slog.Level, string) {
+	for _, rl := range raftLevels {
+		if rest, ok := strings.CutPrefix(msg, rl.tag); ok {
+			if j, _ := jsonHandler(); j != nil {
+				msg = strings.TrimLeft(rest, " ")
+			}
+			return rl.level, msg
+		}
+	}
+	return slog.LevelInfo, msg
+}
+
+func timestamp() string {
+	return time.Now().Format("2006/01/02 15:04:05 ")
+}
+
+// handler is the slog.Handler of the loggers returned by Logger.
+type handler struct {
+	subsystem string
+	w         io.Writer
+	attrs     []slog.Attr
+	group     string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabled(h.subsystem, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := []slog.Attr{slog.String(SubsystemKey, h.subsystem)}
+	if idx, ok := ctx.Value(raftIndexCtxKey).(uint64); ok {
+		attrs = append(attrs, slog.Uint64(RaftIndexKey, idx))
+	}
+	if id := RequestID(ctx); id != "" {
+		attrs = append(attrs, slog.String(RequestIDKey, id))
+	}
+	attrs = append(attrs, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, h.qualify(a))
+		return true
+	})
+
+	if j, id := jsonHandler(); j != nil {
+		out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		out.AddAttrs(slog.String(NodeIDKey, id))
+		out.AddAttrs(attrs...)
+		return j.Handle(ctx, out)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[%s] %s%s", h.subsystem, timestamp(), r.Message)
+	for _, a := range attrs[1:] {
+		fmt.Fprintf(&buf, " %s=%v", a.Key, a.Value)
+	}
+	buf.WriteByte('\n')
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = slices.Clone(h.attrs)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, h.qualify(a))
+	}
+	return &h2
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.qualify(slog.String(name, "")).Key
+	return &h2
+}
+
+// qualify prefixes the key of a with the group of the handler.
+func (h *handler) qualify(a slog.Attr) slog.Attr {
+	if h.group != "" {
+		a.Key = h.group + "." + a.Key
+	}
+	return a
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// reset restores the default configuration once the test completes.
+func reset(t *testing.T) {
+	t.Cleanup(func() {
+		Configure(FormatText, nil, "")
+		SetLevel(DefaultSubsystem, slog.LevelInfo)
+		for k := range Levels() {
+			ClearLevel(k)
+		}
+	})
+}
+
+func Test_NewText(t *testing.T) {
+	reset(t)
+	var buf bytes.Buffer
+	l := New("store", &buf)
+	l.Printf("opened store at %s", "/tmp")
+	Errorf(l, "failed to open store: %s", "disk full")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("exp 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "[store] ") || !strings.HasSuffix(lines[0], " opened store at /tmp") {
+		t.Fatalf("unexpected text line: %s", lines[0])
+	}
+
+	buf.Reset()
+	SetLevel("store", slog.LevelWarn)
+	l.Printf("opened store at %s", "/tmp")
+	l.Printf("failed to open store: %s", "disk full")
+	Warnf(l, "store is slow")
+	Errorf(l, "failed to open store: %s", "disk full")
+	if exp, got := []string{"store is slow", "failed to open store: disk full"}, strings.Split(strings.TrimSpace(buf.String()), "\n"); len(got) != len(exp) ||
+		!strings.HasSuffix(got[0], exp[0]) || !strings.HasSuffix(got[1], exp[1]) {
+		t.Fatalf("store level not applied: %s", buf.String())
+	}
+
+	ClearLevel("store")
+	buf.Reset()
+	l.Printf("opened store at %s", "/tmp")
+	if buf.Len() == 0 {
+		t.Fatalf("default level not applied after clearing store level")
+	}
+}
+
+func Test_NewJSON(t *testing.T) {
+	reset(t)
+	var out bytes.Buffer
+	if err := Configure(FormatJSON, &out, "node1"); err != nil {
+		t.Fatalf("failed to configure: %s", err)
+	}
+	SetLevel("raft", slog.LevelWarn)
+
+	var text bytes.Buffer
+	NewTagged("raft", &text).Print("[WARN]  heartbeat timeout reached, starting election")
+	NewTagged("raft", &text).Print("[INFO]  entering follower state")
+	New("http", &text).Print("service listening on 127.0.0.1:4001")
+	SetLevel("http", slog.LevelWarn)
+	New("http", &text).Print("[WARN] error reading request")
+	if text.Len() != 0 {
+		t.Fatalf("text written in JSON format: %s", text.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("exp 2 JSON lines, got %d: %s", len(lines), out.String())
+	}
+	for i, exp := range []map[string]string{
+		{"level": "WARN", "msg": "heartbeat timeout reached, starting election", "node_id": "node1", "subsystem": "raft"},
+		{"level": "INFO", "msg": "service listening on 127.0.0.1:4001", "node_id": "node1", "subsystem": "http"},
+	} {
+		var got map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("invalid JSON line %s: %s", lines[i], err)
+		}
+		for k, v := range exp {
+			if got[k] != v {
+				t.Fatalf("line %d has %s=%v, exp %s", i, k, got[k], v)
+			}
+		}
+		if _, ok := got["time"]; !ok {
+			t.Fatalf("line %d has no time", i)
+		}
+	}
+}
+
+func Test_LoggerContext(t *testing.T) {
+	reset(t)
+	var out bytes.Buffer
+	if err := Configure(FormatJSON, &out, "node1"); err != nil {
+		t.Fatalf("failed to configure: %s", err)
+	}
+
+	ctx := WithRequestID(WithRaftIndex(context.Background(), 42), "abc")
+	Logger("store").With("table", "foo").InfoContext(ctx, "applied", "rows", 3)
+	Logger("store").DebugContext(ctx, "not logged")
+
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %s: %s", out.String(), err)
+	}
+	for k, v := range map[string]any{
+		"msg": "applied", "node_id": "node1", "subsystem": "store",
+		"raft_index": 42.0, "request_id": "abc", "table": "foo", "rows": 3.0,
+	} {
+		if got[k] != v {
+			t.Fatalf("exp %s=%v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func Test_ParseLevels(t *testing.T) {
+	m, err := ParseLevels("store=debug, raft=trace,http=WARN")
+	if err != nil {
+		t.Fatalf("failed to parse levels: %s", err)
+	}
+	exp := map[string]slog.Level{"store": slog.LevelDebug, "raft": LevelTrace, "http": slog.LevelWarn}
+	if len(m) != len(exp) {
+		t.Fatalf("exp %v, got %v", exp, m)
+	}
+	for k, v := range exp {
+		if m[k] != v {
+			t.Fatalf("exp %s=%s, got %s", k, v, m[k])
+		}
+	}
+
+	for _, s := range []string{"store", "=debug", "store=loud"} {
+		if _, err := ParseLevels(s); err == nil {
+			t.Fatalf("exp error parsing %q", s)
+		}
+	}
+	if m, err := ParseLevels(""); err != nil || len(m) != 0 {
+		t.Fatalf("exp no levels from empty string, got %v, %v", m, err)
+	}
+}
@@ -0,0 +1,97 @@
+package rarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionNone indicates data is not compressed.
+	CompressionNone = "none"
+
+	// CompressionGzip indicates data is gzip-compressed.
+	CompressionGzip = "gzip"
+
+	// CompressionZstd indicates data is zstd-compressed.
+	CompressionZstd = "zstd"
+)
+
+var (
+	// ErrUnsupportedCompression is returned when an unknown compression
+	// algorithm is requested.
+	ErrUnsupportedCompression = errors.New("unsupported compression algorithm")
+
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectCompression returns the compression algorithm used to produce the
+// data starting with b, as indicated by its magic bytes.
+func DetectCompression(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(b, zstdMagic):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// NewCompressWriter returns a WriteCloser which compresses all data written
+// to it using the given algorithm, and writes the compressed data to w. Close
+// must be called to flush any buffered data, but does not close w.
+func NewCompressWriter(w io.Writer, algo string) (io.WriteCloser, error) {
+	switch algo {
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, algo)
+	}
+}
+
+// NewDecompressReader returns a ReadCloser which decompresses the data read
+// from r. The compression algorithm is detected from the magic bytes at the
+// start of the data, and is also returned. If the data is not compressed it
+// is returned unchanged. Closing the returned reader does not close r.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, string, error) {
+	br := bufio.NewReader(r)
+	b, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+
+	algo := DetectCompression(b)
+	switch algo {
+	case CompressionGzip:
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", err
+		}
+		return gzr, algo, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", err
+		}
+		return zr.IOReadCloser(), algo, nil
+	default:
+		return io.NopCloser(br), algo, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
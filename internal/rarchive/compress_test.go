@@ -0,0 +1,70 @@
+package rarchive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func Test_CompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("rqlite compression test data "), 1000)
+	for _, algo := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		var buf bytes.Buffer
+		w, err := NewCompressWriter(&buf, algo)
+		if err != nil {
+			t.Fatalf("%s: failed to create compress writer: %s", algo, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: failed to write data: %s", algo, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: failed to close writer: %s", algo, err)
+		}
+		if algo != CompressionNone && buf.Len() >= len(data) {
+			t.Fatalf("%s: data was not compressed", algo)
+		}
+
+		if got := DetectCompression(buf.Bytes()); got != algo {
+			t.Fatalf("%s: detected compression %s", algo, got)
+		}
+
+		r, got, err := NewDecompressReader(&buf)
+		if err != nil {
+			t.Fatalf("%s: failed to create decompress reader: %s", algo, err)
+		}
+		if got != algo {
+			t.Fatalf("%s: reader detected compression %s", algo, got)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: failed to read data: %s", algo, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("%s: failed to close reader: %s", algo, err)
+		}
+		if !bytes.Equal(b, data) {
+			t.Fatalf("%s: decompressed data does not match", algo)
+		}
+	}
+}
+
+func Test_DecompressReaderShort(t *testing.T) {
+	r, algo, err := NewDecompressReader(bytes.NewReader([]byte("ab")))
+	if err != nil {
+		t.Fatalf("failed to create decompress reader: %s", err)
+	}
+	if algo != CompressionNone {
+		t.Fatalf("expected no compression, got %s", algo)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "ab" {
+		t.Fatalf("unexpected data %q, err %v", b, err)
+	}
+}
+
+func Test_NewCompressWriterUnsupported(t *testing.T) {
+	if _, err := NewCompressWriter(io.Discard, "lz4"); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Fatalf("expected ErrUnsupportedCompression, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package rarchive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrTooLarge is returned when spooled data exceeds the maximum size.
+var ErrTooLarge = errors.New("data too large")
+
+// SpoolDecompressed decompresses the data read from r, if it is compressed,
+// into a temporary file, and returns that file positioned at its start, along
+// with the compression detected. If the decompressed data is larger than
+// limit bytes ErrTooLarge is returned, so that highly compressed data cannot
+// exhaust the node's resources. A limit of zero means no limit. The caller
+// must remove the file.
+func SpoolDecompressed(r io.Reader, limit int64) (*os.File, string, error) {
+	rc, compression, err := NewDecompressReader(r)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	f, err := os.CreateTemp("", "rqlite-spool")
+	if err != nil {
+		return nil, "", err
+	}
+	if err := copyLimited(f, rc, limit); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	return f, compression, nil
+}
+
+// copyLimited copies src to f, and seeks f back to its start. An error is
+// returned if src is larger than limit bytes, unless limit is zero.
+func copyLimited(f *os.File, src io.Reader, limit int64) error {
+	if limit > 0 {
+		src = io.LimitReader(src, limit+1)
+	}
+	n, err := io.Copy(f, src)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && n > limit {
+		return fmt.Errorf("%w, limit is %d bytes", ErrTooLarge, limit)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
@@ -0,0 +1,48 @@
+package rarchive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func Test_SpoolDecompressed(t *testing.T) {
+	data := bytes.Repeat([]byte("rqlite spool test data "), 100)
+	var buf bytes.Buffer
+	w, err := NewCompressWriter(&buf, CompressionGzip)
+	if err != nil {
+		t.Fatalf("failed to create compress writer: %s", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("failed to write data: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %s", err)
+	}
+	compressed := buf.Bytes()
+
+	f, algo, err := SpoolDecompressed(bytes.NewReader(compressed), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to spool data: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if algo != CompressionGzip {
+		t.Fatalf("expected gzip compression, got %s", algo)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("failed to read spooled data: %s", err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatalf("spooled data does not match")
+	}
+
+	// The limit applies to the decompressed data.
+	_, _, err = SpoolDecompressed(bytes.NewReader(compressed), int64(len(data)-1))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"github.com/rqlite/rqlite/v10/internal/logging"
 )
 
 // CertReloader is a simple TLS certificate reloader. It loads the certificate
@@ -25,7 +27,7 @@ func NewCertReloader(cert, key string) (*CertReloader, error) {
 	cr := &CertReloader{
 		certPath: cert,
 		keyPath:  key,
-		logger:   log.New(os.Stderr, "[cert-reloader] ", log.LstdFlags),
+		logger:   logging.New("cert-reloader", os.Stderr),
 	}
 	pair, err := loadKeyPair(cr.certPath, cr.keyPath)
 	if err != nil {
@@ -50,7 +52,7 @@ func (cr *CertReloader) GetCertificate() (*tls.Certificate, error) {
 	if err != nil || !latestTime.After(cr.modTime) {
 		defer cr.mu.RUnlock()
 		if err != nil {
-			cr.logger.Printf("failed to get latest modification time (%s), returning prior cert", err)
+			logging.Warnf(cr.logger, "failed to get latest modification time (%s), returning prior cert", err)
 		}
 		return cr.cert, nil
 	}
@@ -66,14 +68,14 @@ func (cr *CertReloader) GetCertificate() (*tls.Certificate, error) {
 	latestTime, err = latestModTime(cr.certPath, cr.keyPath)
 	if err != nil || !latestTime.After(cr.modTime) {
 		if err != nil {
-			cr.logger.Printf("failed to get latest modification time (%s), returning prior cert", err)
+			logging.Warnf(cr.logger, "failed to get latest modification time (%s), returning prior cert", err)
 		}
 		return cr.cert, nil
 	}
 
 	pair, err := loadKeyPair(cr.certPath, cr.keyPath)
 	if err != nil {
-		cr.logger.Printf("failed to reload certificate (%s), returning prior cert", err)
+		logging.Warnf(cr.logger, "failed to reload certificate (%s), returning prior cert", err)
 		return cr.cert, nil
 	}
 	cr.logger.Printf("reloading certificate at %s and key at %s", cr.certPath, cr.keyPath)
@@ -0,0 +1,102 @@
+// Package tracing provides the helpers rqlite uses to trace requests with
+// OpenTelemetry. Spans are created with the global TracerProvider, so they
+// cost almost nothing unless trace export has been started, for example by
+// the otlp package.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// Tracer returns the tracer for the given package. Unlike the tracers of
+// otel.Tracer, it starts each span with the global TracerProvider at the
+// time, so that trace export may be stopped, and started again.
+func Tracer(pkg string) trace.Tracer {
+	return tracer{name: "github.com/rqlite/rqlite/v10/" + pkg}
+}
+
+type tracer struct {
+	embedded.Tracer
+	name string
+}
+
+// Start starts a span with the global TracerProvider.
+func (t tracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.GetTracerProvider().Tracer(t.name).Start(ctx, spanName, opts...)
+}
+
+// StartChild starts a span with the given tracer, as a child of the span in
+// ctx. If ctx is not part of a trace, it starts nothing, and returns the
+// non-recording span of ctx, so that background work does not start traces
+// of its own.
+func StartChild(ctx context.Context, tracer trace.Tracer, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// End records err, if not nil, on the span, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx, for propagation to another
+// node. It returns nil if ctx is not part of a sampled trace.
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsSampled() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx, continuing the trace context propagated by Inject.
+func Extract(ctx context.Context, tc map[string]string) context.Context {
+	if len(tc) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(tc))
+}
+
+// Marshal returns the trace context of ctx in a form which may be stored
+// with a Raft log entry, or nil if ctx is not part of a sampled trace.
+func Marshal(ctx context.Context) []byte {
+	tc := Inject(ctx)
+	if tc == nil {
+		return nil
+	}
+	b, err := json.Marshal(tc)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// Unmarshal returns ctx, continuing the trace context returned by Marshal.
+// Anything which is not a trace context is ignored.
+func Unmarshal(ctx context.Context, b []byte) context.Context {
+	if len(b) == 0 {
+		return ctx
+	}
+	var tc map[string]string
+	if err := json.Unmarshal(b, &tc); err != nil {
+		return ctx
+	}
+	return Extract(ctx, tc)
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func Test_Propagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	defer tp.Shutdown(context.Background())
+
+	if tc := Inject(context.Background()); tc != nil {
+		t.Fatalf("expected no trace context outside a trace, got %v", tc)
+	}
+	if b := Marshal(context.Background()); b != nil {
+		t.Fatalf("expected no trace context outside a trace, got %s", b)
+	}
+
+	if _, span := StartChild(context.Background(), tp.Tracer("test"), "orphan"); span.IsRecording() {
+		t.Fatalf("expected no span to be started outside a trace")
+	}
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	want := span.SpanContext()
+	if _, child := StartChild(ctx, tp.Tracer("test"), "child"); child.SpanContext().TraceID() != want.TraceID() {
+		t.Fatalf("child span not part of parent trace")
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), Inject(ctx)))
+	if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() || !got.IsRemote() {
+		t.Fatalf("trace context not propagated, exp %v, got %v", want, got)
+	}
+	got = trace.SpanContextFromContext(Unmarshal(context.Background(), Marshal(ctx)))
+	if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Fatalf("trace context not marshaled, exp %v, got %v", want, got)
+	}
+	if got := trace.SpanContextFromContext(Unmarshal(context.Background(), []byte("garbage"))); got.IsValid() {
+		t.Fatalf("expected no trace context from garbage, got %v", got)
+	}
+
+	End(span, errors.New("failed"))
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "failed" {
+		t.Fatalf("error not recorded on span, got %v", spans[0].Status())
+	}
+}
+
+func Test_TracerFollowsGlobalProvider(t *testing.T) {
+	tr := Tracer("test")
+	for range 2 {
+		sr := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+		otel.SetTracerProvider(tp)
+		_, span := tr.Start(context.Background(), "span")
+		span.End()
+		if len(sr.Ended()) != 1 {
+			t.Fatalf("span not recorded by global provider")
+		}
+
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		if _, span := tr.Start(context.Background(), "span"); span.IsRecording() {
+			t.Fatalf("span recorded after global provider removed")
+		}
+		tp.Shutdown(context.Background())
+	}
+}
@@ -3,9 +3,11 @@ package otlp
 import (
 	"context"
 	"expvar"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/internal/latency"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
@@ -55,7 +57,8 @@ func NewBridge() *Bridge {
 
 // Produce returns the current state of all expvar metrics in OpenTelemetry
 // form. Each key in each top-level expvar map is emitted as a metric named
-// rqlite.<map>.<key>. The expvar registry is walked on every call, so maps
+// rqlite.<map>.<key>, latency histograms as histograms in seconds, and
+// other numbers as gauges or counters. The expvar registry is walked on every call, so maps
 // and keys registered after the Bridge is created are picked up
 // automatically. It never returns an error, since doing so would suppress
 // the export of all other metrics gathered in the same collection cycle.
@@ -78,6 +81,8 @@ func (b *Bridge) Produce(_ context.Context) ([]metricdata.ScopeMetrics, error) {
 				metrics = append(metrics, newMetric(name, unit, gauge, v.Value(), b.startTime, now))
 			case *expvar.Float:
 				metrics = append(metrics, newMetric(name, unit, gauge, v.Value(), b.startTime, now))
+			case *latency.Histogram:
+				metrics = append(metrics, newHistogram(name, v.Snapshot(), b.startTime, now))
 			}
 		})
 	})
@@ -118,6 +123,26 @@ func newMetric[N int64 | float64](name, unit string, gauge bool, value N, startT
 	return m
 }
 
+// newHistogram returns a cumulative histogram, in seconds, of the given
+// latency snapshot.
+func newHistogram(name string, snap latency.Snapshot, startTime, now time.Time) metricdata.Metrics {
+	return metricdata.Metrics{
+		Name: name,
+		Unit: "s",
+		Data: metricdata.Histogram[float64]{
+			Temporality: metricdata.CumulativeTemporality,
+			DataPoints: []metricdata.HistogramDataPoint[float64]{{
+				StartTime:    startTime,
+				Time:         now,
+				Count:        snap.Count,
+				Sum:          snap.Sum.Seconds(),
+				Bounds:       slices.Clone(latency.Bounds),
+				BucketCounts: snap.BucketCounts,
+			}},
+		},
+	}
+}
+
 // classify determines whether the given expvar metric is a gauge, and what
 // its unit is, based on its name. Metrics not identified as gauges are
 // monotonic counters. rqlite's expvar metrics are counters unless they are
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/rqlite/rqlite/v10/internal/latency"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
@@ -153,6 +155,44 @@ func Test_BridgeProduce(t *testing.T) {
 	}
 }
 
+func Test_BridgeProduceHistogram(t *testing.T) {
+	m, name := newTestMap()
+	h := latency.NewHistogram(0)
+	h.Record(2 * time.Millisecond)
+	h.Record(3 * time.Second)
+	m.Set("op_latency", h)
+
+	sms, err := NewBridge().Produce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	metric := findMetric(t, sms, "rqlite."+name+".op_latency")
+	if metric.Unit != "s" {
+		t.Fatalf("op_latency unit = %q, want s", metric.Unit)
+	}
+	data, ok := metric.Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("op_latency is %T, want Histogram[float64]", metric.Data)
+	}
+	if data.Temporality != metricdata.CumulativeTemporality {
+		t.Fatalf("op_latency temporality = %v, want cumulative", data.Temporality)
+	}
+	dp := data.DataPoints[0]
+	if dp.Count != 2 || dp.Sum != 3.002 {
+		t.Fatalf("op_latency count = %d, sum = %f, want 2 and 3.002", dp.Count, dp.Sum)
+	}
+	if len(dp.BucketCounts) != len(dp.Bounds)+1 {
+		t.Fatalf("op_latency has %d buckets for %d bounds", len(dp.BucketCounts), len(dp.Bounds))
+	}
+	var total uint64
+	for _, c := range dp.BucketCounts {
+		total += c
+	}
+	if total != 2 {
+		t.Fatalf("op_latency buckets hold %d values, want 2", total)
+	}
+}
+
 func Test_BridgeManualReader(t *testing.T) {
 	m, name := newTestMap()
 	m.Add("num_reads", 42)
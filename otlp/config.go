@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/rqlite/rqlite/v10/internal/rtls"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 )
 
 // DefaultInterval is the default period between metric exports.
@@ -48,6 +50,11 @@ type Config struct {
 	// Version is the rqlite version, and is set as the service.version
 	// resource attribute on all exported metrics.
 	Version string
+
+	// TraceSampleRatio is the fraction of traces started by this node which
+	// are sampled, and so exported, by a TraceService. Traces continued from
+	// another node, or from a client, are sampled if their parent was.
+	TraceSampleRatio float64
 }
 
 // Validate checks the configuration for validity.
@@ -67,6 +74,9 @@ func (c *Config) Validate() error {
 	if (c.CertFile == "") != (c.KeyFile == "") {
 		return errors.New("either both cert and key must be set, or neither")
 	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return errors.New("trace sample ratio must be between 0 and 1")
+	}
 	if c.Insecure && (c.InsecureSkipVerify || c.CACertFile != "" || c.CertFile != "") {
 		return errors.New("insecure connections cannot use TLS options")
 	}
@@ -82,3 +92,17 @@ func (c *Config) TLSConfig() (*tls.Config, error) {
 	return rtls.CreateClientConfig(c.CertFile, c.KeyFile, c.CACertFile, rtls.NoServerName,
 		c.InsecureSkipVerify)
 }
+
+// resource returns the resource describing this node, which is attached to
+// all exported telemetry.
+func (c *Config) resource() (*resource.Resource, error) {
+	return resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("rqlite"),
+			semconv.ServiceVersion(c.Version),
+			semconv.ServiceInstanceID(c.NodeID),
+		),
+	)
+}
@@ -54,6 +54,20 @@ func Test_ConfigValidate(t *testing.T) {
 			cfg:     Config{Endpoint: "localhost:4317", Interval: -time.Second},
 			wantErr: true,
 		},
+		{
+			name: "valid trace sample ratio",
+			cfg:  Config{Endpoint: "localhost:4317", Interval: DefaultInterval, TraceSampleRatio: 0.5},
+		},
+		{
+			name:    "trace sample ratio too large",
+			cfg:     Config{Endpoint: "localhost:4317", Interval: DefaultInterval, TraceSampleRatio: 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "negative trace sample ratio",
+			cfg:     Config{Endpoint: "localhost:4317", Interval: DefaultInterval, TraceSampleRatio: -0.1},
+			wantErr: true,
+		},
 		{
 			name:    "cert without key",
 			cfg:     Config{Endpoint: "localhost:4317", Interval: DefaultInterval, CertFile: "cert.pem"},
@@ -0,0 +1,222 @@
+package otlp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// PrometheusContentType is the content type of the Prometheus text
+// exposition format written by Prometheus.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promUnits maps the units of the metrics produced by the Bridge to the
+// suffixes Prometheus uses for them. Names already ending with an
+// abbreviation of the unit have the abbreviation replaced.
+var promUnits = map[string]struct{ suffix, abbrev string }{
+	"ms": {"_milliseconds", "_ms"},
+	"us": {"_microseconds", "_us"},
+	"By": {"_bytes", "_bytes"},
+	"1":  {"_ratio", "_ratio"},
+	"s":  {"_seconds", "_seconds"},
+}
+
+// Prometheus writes the metrics produced by a Bridge in the Prometheus text
+// exposition format, so that they may be scraped without an OpenTelemetry
+// Collector. Metric names follow the OpenTelemetry conventions for
+// Prometheus: rqlite.store.num_snapshots becomes
+// rqlite_store_num_snapshots_total, and units become suffixes.
+type Prometheus struct {
+	bridge     *Bridge
+	labels     string
+	labelNames map[string]bool
+}
+
+// NewPrometheus returns a new Prometheus. The given labels, such as the ID
+// of the node, are added to every sample.
+func NewPrometheus(labels map[string]string) *Prometheus {
+	pairs := make([]string, 0, len(labels))
+	names := map[string]bool{"le": true}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, sanitizeName(k), labelEscaper.Replace(labels[k])))
+		names[sanitizeName(k)] = true
+	}
+	return &Prometheus{
+		bridge:     NewBridge(),
+		labels:     strings.Join(pairs, ","),
+		labelNames: names,
+	}
+}
+
+// Write writes the current state of all metrics to w. A metric whose name,
+// once sanitized, is the same as that of a metric already written is
+// skipped, since Prometheus requires each name be described only once.
+func (p *Prometheus) Write(w io.Writer) error {
+	sms, err := p.bridge.Produce(context.Background())
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	written := make(map[string]bool)
+	for _, sm := range sms {
+		for _, m := range sm.Metrics {
+			typ := promType(m.Data)
+			if typ == "" {
+				continue
+			}
+			name := promName(m.Name, m.Unit, typ == "counter")
+			if written[name] {
+				continue
+			}
+			written[name] = true
+
+			switch d := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				writeSamples(bw, p, name, m.Name, typ, d.DataPoints)
+			case metricdata.Gauge[float64]:
+				writeSamples(bw, p, name, m.Name, typ, d.DataPoints)
+			case metricdata.Sum[int64]:
+				writeSamples(bw, p, name, m.Name, typ, d.DataPoints)
+			case metricdata.Sum[float64]:
+				writeSamples(bw, p, name, m.Name, typ, d.DataPoints)
+			case metricdata.Histogram[int64]:
+				writeHistogram(bw, p, name, m.Name, d.DataPoints)
+			case metricdata.Histogram[float64]:
+				writeHistogram(bw, p, name, m.Name, d.DataPoints)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// promType returns the Prometheus type of the given metric data, or the
+// empty string if the data cannot be written.
+func promType(data metricdata.Aggregation) string {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64], metricdata.Gauge[float64]:
+		return "gauge"
+	case metricdata.Sum[int64]:
+		return sumType(d.IsMonotonic)
+	case metricdata.Sum[float64]:
+		return sumType(d.IsMonotonic)
+	case metricdata.Histogram[int64], metricdata.Histogram[float64]:
+		return "histogram"
+	}
+	return ""
+}
+
+func sumType(monotonic bool) string {
+	if monotonic {
+		return "counter"
+	}
+	return "gauge"
+}
+
+func writeSamples[N int64 | float64](w *bufio.Writer, p *Prometheus, name, otelName, typ string, dps []metricdata.DataPoint[N]) {
+	writeHeader(w, name, otelName, typ)
+	for _, dp := range dps {
+		fmt.Fprintf(w, "%s%s %s\n", name, braces(p.labelsOf(dp.Attributes)), formatValue(float64(dp.Value)))
+	}
+}
+
+func writeHistogram[N int64 | float64](w *bufio.Writer, p *Prometheus, name, otelName string, dps []metricdata.HistogramDataPoint[N]) {
+	writeHeader(w, name, otelName, "histogram")
+	for _, dp := range dps {
+		labels := p.labelsOf(dp.Attributes)
+		sep := ""
+		if labels != "" {
+			sep = ","
+		}
+		var cumulative uint64
+		for i, bound := range dp.Bounds {
+			cumulative += dp.BucketCounts[i]
+			fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", name, labels, sep, formatValue(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, dp.Count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(labels), formatValue(float64(dp.Sum)))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, braces(labels), dp.Count)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, otelName, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, otelName)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// promName returns the Prometheus name of the metric with the given
+// OpenTelemetry name and unit.
+func promName(name, unit string, counter bool) string {
+	name = sanitizeName(name)
+	if u, ok := promUnits[unit]; ok {
+		name = strings.TrimSuffix(name, u.abbrev) + u.suffix
+	}
+	if counter {
+		name += "_total"
+	}
+	return name
+}
+
+// labelsOf returns the labels of a sample with the given attributes: the
+// labels of every sample, followed by the attributes. An attribute with the
+// same name as one of those labels, or as the "le" label of histogram
+// buckets, is prefixed with "exported_", as Prometheus does.
+func (p *Prometheus) labelsOf(attrs attribute.Set) string {
+	if attrs.Len() == 0 {
+		return p.labels
+	}
+	pairs := make([]string, 0, attrs.Len()+1)
+	if p.labels != "" {
+		pairs = append(pairs, p.labels)
+	}
+	for iter := attrs.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		k := sanitizeName(string(kv.Key))
+		if p.labelNames[k] {
+			k = "exported_" + k
+		}
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelEscaper.Replace(kv.Value.Emit())))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// sanitizeName replaces any characters not permitted in Prometheus metric
+// and label names with underscores.
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// labelEscaper escapes label values as the text exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,151 @@
+package otlp
+
+import (
+	"bufio"
+	"bytes"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func Test_PromName(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		unit    string
+		counter bool
+		want    string
+	}{
+		{"rqlite.store.num_snapshots", "", true, "rqlite_store_num_snapshots_total"},
+		{"rqlite.uploader.total_upload_bytes", "By", true, "rqlite_uploader_total_upload_bytes_total"},
+		{"rqlite.db.checkpoint_duration_ms", "ms", false, "rqlite_db_checkpoint_duration_milliseconds"},
+		{"rqlite.store.fsm_apply_duration_us", "us", false, "rqlite_store_fsm_apply_duration_microseconds"},
+		{"rqlite.store.auto_vacuum_duration", "ms", false, "rqlite_store_auto_vacuum_duration_milliseconds"},
+		{"rqlite.db.wal.compact_frames_ratio", "1", false, "rqlite_db_wal_compact_frames_ratio"},
+		{"rqlite.snapshot.persist_size", "By", false, "rqlite_snapshot_persist_size_bytes"},
+		{"rqlite.cdc.service.fifo_size", "", false, "rqlite_cdc_service_fifo_size"},
+		{"rqlite.http.queries-5xx", "", true, "rqlite_http_queries_5xx_total"},
+		{"rqlite.store.raft_apply_latency", "s", false, "rqlite_store_raft_apply_latency_seconds"},
+	} {
+		if got := promName(tt.name, tt.unit, tt.counter); got != tt.want {
+			t.Fatalf("promName(%s, %s, %v) = %s, want %s", tt.name, tt.unit, tt.counter, got, tt.want)
+		}
+	}
+}
+
+func Test_PrometheusWrite(t *testing.T) {
+	m, name := newTestMap()
+	m.Add("num_things", 5)
+	m.Add("open_duration_ms", 0)
+	m.Get("open_duration_ms").(*expvar.Int).Set(123)
+	m.AddFloat("hit_ratio", 0.75)
+
+	p := NewPrometheus(map[string]string{"node_id": `node "1"`})
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("failed to write metrics: %s", err)
+	}
+	out := buf.String()
+
+	prefix := "rqlite_" + name + "_"
+	for _, want := range []string{
+		"# HELP " + prefix + "num_things_total rqlite." + name + ".num_things\n",
+		"# TYPE " + prefix + "num_things_total counter\n",
+		prefix + `num_things_total{node_id="node \"1\""} 5` + "\n",
+		"# TYPE " + prefix + "open_duration_milliseconds gauge\n",
+		prefix + `open_duration_milliseconds{node_id="node \"1\""} 123` + "\n",
+		"# TYPE " + prefix + "hit_ratio gauge\n",
+		prefix + `hit_ratio{node_id="node \"1\""} 0.75` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "memstats") || strings.Contains(out, "cmdline") {
+		t.Fatalf("built-in expvars should not be written:\n%s", out)
+	}
+}
+
+func Test_PrometheusWriteDuplicateNames(t *testing.T) {
+	m, name := newTestMap()
+	m.Add("num-things", 1)
+	m.Add("num_things", 2)
+
+	p := NewPrometheus(nil)
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("failed to write metrics: %s", err)
+	}
+	out := buf.String()
+
+	full := "rqlite_" + name + "_num_things_total"
+	if n := strings.Count(out, "# TYPE "+full+" "); n != 1 {
+		t.Fatalf("exp 1 TYPE line for %s, got %d:\n%s", full, n, out)
+	}
+	if n := strings.Count(out, "\n"+full+" "); n != 1 {
+		t.Fatalf("exp 1 sample for %s, got %d:\n%s", full, n, out)
+	}
+}
+
+func Test_PrometheusWriteAttributes(t *testing.T) {
+	p := NewPrometheus(map[string]string{"node_id": "1"})
+	attrs := attribute.NewSet(attribute.String("node_id", "2"), attribute.String("op.type", `a"b`), attribute.Int("le", 3))
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeSamples(w, p, "rqlite_ops_total", "rqlite.ops", "counter",
+		[]metricdata.DataPoint[int64]{{Attributes: attrs, Value: 7}})
+	writeHistogram(w, p, "rqlite_latency_seconds", "rqlite.latency",
+		[]metricdata.HistogramDataPoint[float64]{{
+			Attributes:   attrs,
+			Bounds:       []float64{0.5},
+			BucketCounts: []uint64{1, 0},
+			Count:        1,
+			Sum:          0.25,
+		}})
+	w.Flush()
+
+	labels := `node_id="1",exported_le="3",exported_node_id="2",op_type="a\"b"`
+	exp := `# HELP rqlite_ops_total rqlite.ops
+# TYPE rqlite_ops_total counter
+rqlite_ops_total{` + labels + `} 7
+# HELP rqlite_latency_seconds rqlite.latency
+# TYPE rqlite_latency_seconds histogram
+rqlite_latency_seconds_bucket{` + labels + `,le="0.5"} 1
+rqlite_latency_seconds_bucket{` + labels + `,le="+Inf"} 1
+rqlite_latency_seconds_sum{` + labels + `} 0.25
+rqlite_latency_seconds_count{` + labels + `} 1
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("unexpected output, exp:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+func Test_PrometheusWriteHistogram(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeHistogram(w, NewPrometheus(map[string]string{"node_id": "1"}), "rqlite_store_raft_apply_latency_seconds", "rqlite.store.raft_apply_latency",
+		[]metricdata.HistogramDataPoint[float64]{{
+			Time:         time.Now(),
+			Bounds:       []float64{0.001, 0.01},
+			BucketCounts: []uint64{2, 3, 1},
+			Count:        6,
+			Sum:          0.0425,
+		}})
+	w.Flush()
+
+	exp := `# HELP rqlite_store_raft_apply_latency_seconds rqlite.store.raft_apply_latency
+# TYPE rqlite_store_raft_apply_latency_seconds histogram
+rqlite_store_raft_apply_latency_seconds_bucket{node_id="1",le="0.001"} 2
+rqlite_store_raft_apply_latency_seconds_bucket{node_id="1",le="0.01"} 5
+rqlite_store_raft_apply_latency_seconds_bucket{node_id="1",le="+Inf"} 6
+rqlite_store_raft_apply_latency_seconds_sum{node_id="1"} 0.0425
+rqlite_store_raft_apply_latency_seconds_count{node_id="1"} 6
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("unexpected histogram output, exp:\n%s\ngot:\n%s", exp, got)
+	}
+}
@@ -1,8 +1,9 @@
-// Package otlp provides OpenTelemetry metrics reporting for rqlite. It
-// bridges rqlite's expvar metrics to the OpenTelemetry metrics data model,
-// and periodically pushes them, along with Go runtime metrics, in OTLP
-// format over gRPC to an OpenTelemetry Collector. The expvar metrics
-// themselves are not modified in any way by this package.
+// Package otlp provides OpenTelemetry metrics reporting and trace export
+// for rqlite. It bridges rqlite's expvar metrics to the OpenTelemetry metrics
+// data model, and periodically pushes them, along with Go runtime metrics, in
+// OTLP format over gRPC to an OpenTelemetry Collector. The expvar metrics
+// themselves are not modified in any way by this package. It also exports,
+// in the same way, the spans of sampled requests.
 package otlp
 
 import (
@@ -12,18 +13,30 @@ import (
 	"os"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
-	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"google.golang.org/grpc/credentials"
 )
 
 const stopTimeout = 5 * time.Second
 
+// errLogger logs the errors encountered while exporting to the Collector.
+var errLogger = logging.New("otlp", os.Stderr)
+
+// setErrorHandler routes any errors encountered by OpenTelemetry to
+// errLogger. Export happens on background goroutines, and OpenTelemetry has
+// a single, global, error handler, so it is shared by metrics and traces,
+// whose errors are told apart by their messages.
+func setErrorHandler() {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logging.Warnf(errLogger, "error exporting to Collector: %s", err)
+	}))
+}
+
 // Service periodically pushes rqlite metrics, in OTLP format, to an
 // OpenTelemetry Collector.
 type Service struct {
@@ -40,7 +53,7 @@ func NewService(cfg Config) *Service {
 	return &Service{
 		cfg:     cfg,
 		running: rsync.NewAtomicBool(),
-		logger:  log.New(os.Stderr, "[otlp-metrics] ", log.LstdFlags),
+		logger:  logging.New("otlp-metrics", os.Stderr),
 	}
 }
 
@@ -55,15 +68,7 @@ func (s *Service) Start() error {
 		return err
 	}
 
-	res, err := resource.Merge(
-		resource.Default(),
-		resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName("rqlite"),
-			semconv.ServiceVersion(s.cfg.Version),
-			semconv.ServiceInstanceID(s.cfg.NodeID),
-		),
-	)
+	res, err := s.cfg.resource()
 	if err != nil {
 		return fmt.Errorf("failed to create resource: %s", err)
 	}
@@ -98,11 +103,7 @@ func (s *Service) Start() error {
 			sdkmetric.WithProducer(NewBridge()))),
 	)
 
-	// Export happens on a background goroutine, so route any errors it
-	// encounters to this service's logger.
-	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
-		s.logger.Printf("error exporting metrics: %s", err)
-	}))
+	setErrorHandler()
 
 	if err := runtime.Start(runtime.WithMeterProvider(s.mp)); err != nil {
 		s.shutdownProvider()
@@ -139,6 +140,6 @@ func (s *Service) shutdownProvider() {
 	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
 	defer cancel()
 	if err := s.mp.Shutdown(ctx); err != nil {
-		s.logger.Printf("error shutting down metrics provider: %s", err)
+		logging.Warnf(s.logger, "error shutting down metrics provider: %s", err)
 	}
 }
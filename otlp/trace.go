@@ -0,0 +1,119 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/rsync"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/credentials"
+)
+
+// TraceService exports the spans of sampled requests, in OTLP format, to an
+// OpenTelemetry Collector. While it is running it is the global OpenTelemetry
+// TracerProvider, and the W3C Trace Context is the global propagator, so that
+// traces continue across nodes.
+type TraceService struct {
+	cfg     Config
+	tp      *sdktrace.TracerProvider
+	running *rsync.AtomicBool
+
+	logger *log.Logger
+}
+
+// NewTraceService returns a new TraceService with the given configuration.
+// Export does not begin until Start is called.
+func NewTraceService(cfg Config) *TraceService {
+	return &TraceService{
+		cfg:     cfg,
+		running: rsync.NewAtomicBool(),
+		logger:  logging.New("otlp-traces", os.Stderr),
+	}
+}
+
+// Start starts exporting spans to the Collector. The connection to the
+// Collector is established lazily, so Start succeeds even if the Collector
+// is not yet reachable.
+func (s *TraceService) Start() error {
+	if s.running.Is() {
+		return nil
+	}
+	if err := s.cfg.Validate(); err != nil {
+		return err
+	}
+	if s.cfg.TraceSampleRatio <= 0 {
+		return errors.New("trace sample ratio must be greater than zero")
+	}
+
+	res, err := s.cfg.resource()
+	if err != nil {
+		return fmt.Errorf("failed to create resource: %s", err)
+	}
+
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(s.cfg.Endpoint),
+		// As with metrics, retrying failed exports could delay node shutdown
+		// until stopTimeout expires, so spans which fail to export are dropped.
+		otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: false}),
+	}
+	if s.cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	} else {
+		tlsCfg, err := s.cfg.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to create TLS config: %s", err)
+		}
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+	}
+	exp, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %s", err)
+	}
+
+	s.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(s.cfg.TraceSampleRatio))),
+	)
+	setErrorHandler()
+	otel.SetTracerProvider(s.tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	s.running.Set()
+	s.logger.Printf("exporting traces to %s, sampling %g of traces", s.cfg.Endpoint, s.cfg.TraceSampleRatio)
+	return nil
+}
+
+// Stop stops the service, exporting any remaining spans to the Collector
+// before returning.
+func (s *TraceService) Stop() {
+	if s.running.IsNot() {
+		return
+	}
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := s.tp.Shutdown(ctx); err != nil {
+		logging.Warnf(s.logger, "error shutting down trace provider: %s", err)
+	}
+	s.running.Unset()
+}
+
+// Stats returns the status of the TraceService.
+func (s *TraceService) Stats() (map[string]any, error) {
+	return map[string]any{
+		"endpoint":     s.cfg.Endpoint,
+		"sample_ratio": s.cfg.TraceSampleRatio,
+		"insecure":     s.cfg.Insecure,
+		"no_verify":    s.cfg.InsecureSkipVerify,
+		"running":      s.running.Is(),
+	}, nil
+}
@@ -0,0 +1,119 @@
+package otlp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeTraceCollector is an in-process OTLP gRPC trace collector.
+type fakeTraceCollector struct {
+	collectortracepb.UnimplementedTraceServiceServer
+
+	mu   sync.Mutex
+	reqs []*collectortracepb.ExportTraceServiceRequest
+}
+
+func (f *fakeTraceCollector) Export(ctx context.Context, req *collectortracepb.ExportTraceServiceRequest) (*collectortracepb.ExportTraceServiceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reqs = append(f.reqs, req)
+	return &collectortracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (f *fakeTraceCollector) requests() []*collectortracepb.ExportTraceServiceRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*collectortracepb.ExportTraceServiceRequest(nil), f.reqs...)
+}
+
+// startFakeTraceCollector starts an OTLP gRPC trace collector on a random
+// local port, returning it along with its address.
+func startFakeTraceCollector(t *testing.T) (*fakeTraceCollector, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	fc := &fakeTraceCollector{}
+	srv := grpc.NewServer()
+	collectortracepb.RegisterTraceServiceServer(srv, fc)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return fc, ln.Addr().String()
+}
+
+func Test_TraceServiceExportsSpans(t *testing.T) {
+	fc, addr := startFakeTraceCollector(t)
+	srv := NewTraceService(Config{
+		Endpoint:         addr,
+		Interval:         DefaultInterval,
+		Insecure:         true,
+		NodeID:           "node1",
+		Version:          "v10.0.0",
+		TraceSampleRatio: 1,
+	})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start service: %s", err)
+	}
+	if stats, err := srv.Stats(); err != nil || stats["running"] != true {
+		t.Fatalf("expected service to report running, got %v (err=%v)", stats, err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	if !span.SpanContext().IsSampled() {
+		t.Fatalf("expected span to be sampled")
+	}
+	span.End()
+
+	// Stopping the service exports any remaining spans.
+	srv.Stop()
+	var gotSpan, gotInstanceID bool
+	for _, req := range fc.requests() {
+		for _, rs := range req.GetResourceSpans() {
+			for _, attr := range rs.GetResource().GetAttributes() {
+				if attr.GetKey() == "service.instance.id" && attr.GetValue().GetStringValue() == "node1" {
+					gotInstanceID = true
+				}
+			}
+			for _, ss := range rs.GetScopeSpans() {
+				for _, s := range ss.GetSpans() {
+					if s.GetName() == "test-span" {
+						gotSpan = true
+					}
+				}
+			}
+		}
+	}
+	if !gotSpan || !gotInstanceID {
+		t.Fatalf("span not exported (span=%v, instanceID=%v)", gotSpan, gotInstanceID)
+	}
+
+	// Once stopped, spans are no longer recorded.
+	_, span = otel.Tracer("test").Start(context.Background(), "after-stop")
+	if span.IsRecording() {
+		t.Fatalf("expected span not to be recorded after stop")
+	}
+	span.End()
+	if stats, err := srv.Stats(); err != nil || stats["running"] != false {
+		t.Fatalf("expected service to report not running, got %v (err=%v)", stats, err)
+	}
+	srv.Stop() // Stopping a stopped service must be a no-op.
+}
+
+func Test_TraceServiceStartNoSampling(t *testing.T) {
+	srv := NewTraceService(Config{
+		Endpoint: "localhost:4317",
+		Interval: time.Second,
+		Insecure: true,
+	})
+	if err := srv.Start(); err == nil {
+		t.Fatalf("expected error starting service without a sample ratio")
+	}
+}
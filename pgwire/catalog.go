@@ -0,0 +1,278 @@
+package pgwire
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/rqlite/rqlite/v10/command"
+	"github.com/rqlite/rqlite/v10/command/proto"
+)
+
+// Session parameters which control how rqlite executes statements.
+const (
+	paramConsistency     = "rqlite.consistency"
+	paramFreshness       = "rqlite.freshness"
+	paramFreshnessStrict = "rqlite.freshness_strict"
+)
+
+// listTablesSQL lists the tables and views in the database, in the form
+// expected by psql's \dt and \d commands.
+const listTablesSQL = `SELECT 'main' AS "Schema", name AS "Name", type AS "Type", '' AS "Owner" ` +
+	`FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`
+
+// defaultParams returns the initial settings of a session's parameters.
+func defaultParams() map[string]string {
+	return map[string]string{
+		"server_version":              serverVersion,
+		"server_encoding":             "UTF8",
+		"client_encoding":             "UTF8",
+		"datestyle":                   "ISO, MDY",
+		"intervalstyle":               "postgres",
+		"timezone":                    "UTC",
+		"integer_datetimes":           "on",
+		"standard_conforming_strings": "on",
+		"application_name":            "",
+		"transaction_isolation":       "serializable",
+	}
+}
+
+// intercept handles statements which are answered by the session itself,
+// rather than by the database. These are session commands such as SET and
+// SHOW, and the catalog queries commonly made by clients. It returns false
+// if stmt is not such a statement. If describe is set, the statement is not
+// executed, and only the columns of the result are set.
+func (s *session) intercept(stmt string, describe bool) (*result, bool, error) {
+	kws := keywords(stmt, 3)
+	if len(kws) == 0 {
+		return nil, false, nil
+	}
+
+	switch kws[0] {
+	case "SET":
+		if describe {
+			return &result{tag: "SET"}, true, nil
+		}
+		return s.set(stmt)
+	case "RESET":
+		if describe {
+			return &result{tag: "RESET"}, true, nil
+		}
+		name := argument(stmt)
+		if name == "" {
+			return nil, true, newError("42601", "syntax error at end of input")
+		}
+		s.reset(name)
+		return &result{tag: "RESET"}, true, nil
+	case "SHOW":
+		return s.show(stmt, describe)
+	case "DISCARD":
+		if !describe {
+			clear(s.stmts)
+			clear(s.portals)
+			s.params = defaultParams()
+			s.level, s.freshness, s.freshnessStrict = proto.ConsistencyLevel_WEAK, 0, false
+		}
+		return &result{tag: "DISCARD ALL"}, true, nil
+	case "SELECT":
+	default:
+		return nil, false, nil
+	}
+
+	n := normalize(stmt)
+	var name, value string
+	switch n {
+	case "select version()", "select pg_catalog.version()":
+		name, value = "version", "PostgreSQL "+serverVersion+" (rqlite "+s.svc.Version+")"
+	case "select current_database()", "select pg_catalog.current_database()":
+		name, value = "current_database", "main"
+	case "select current_schema()", "select current_schema", "select pg_catalog.current_schema()":
+		name, value = "current_schema", "main"
+	case "select current_user", "select user":
+		name, value = "current_user", s.user
+	case "select session_user":
+		name, value = "session_user", s.user
+	default:
+		if strings.Contains(n, "pg_catalog.pg_class") && strings.Contains(n, "relkind") &&
+			!strings.Contains(n, "pg_catalog.pg_attribute") {
+			return s.listTables(describe)
+		}
+		return nil, false, nil
+	}
+	return textResult(name, [][]string{{value}}), true, nil
+}
+
+// set handles a SET statement.
+func (s *session) set(stmt string) (*result, bool, error) {
+	name, value, ok := parseSet(stmt)
+	if !ok {
+		if arg := argument(stmt); strings.HasPrefix(arg, "transaction ") ||
+			strings.HasPrefix(arg, "session characteristics ") {
+			// Transactions are always serializable, so transaction
+			// characteristics are accepted but have no effect.
+			return &result{tag: "SET"}, true, nil
+		}
+		return nil, true, newError("42601", "syntax error in SET statement")
+	}
+	switch name {
+	case paramConsistency:
+		v := strings.ToLower(value)
+		if command.LevelToString(command.LevelFromString(v)) != v {
+			return nil, true, newError("22023", "invalid value for parameter %q: %q", name, value)
+		}
+		s.level = command.LevelFromString(v)
+	case paramFreshness:
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, true, newError("22023", "invalid value for parameter %q: %q", name, value)
+		}
+		s.freshness = d
+	case paramFreshnessStrict:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, true, newError("22023", "invalid value for parameter %q: %q", name, value)
+		}
+		s.freshnessStrict = b
+	default:
+		if strings.EqualFold(value, "DEFAULT") {
+			s.reset(name)
+			break
+		}
+		// Other parameters are accepted, so that clients which set them on
+		// connection work, but they have no effect.
+		s.params[name] = value
+	}
+	return &result{tag: "SET"}, true, nil
+}
+
+// reset returns the named parameter, or all parameters, to the default.
+func (s *session) reset(name string) {
+	defaults := defaultParams()
+	switch name {
+	case "all":
+		s.params = defaults
+		s.level, s.freshness, s.freshnessStrict = proto.ConsistencyLevel_WEAK, 0, false
+	case paramConsistency:
+		s.level = proto.ConsistencyLevel_WEAK
+	case paramFreshness:
+		s.freshness = 0
+	case paramFreshnessStrict:
+		s.freshnessStrict = false
+	default:
+		if v, ok := defaults[name]; ok {
+			s.params[name] = v
+		} else {
+			delete(s.params, name)
+		}
+	}
+}
+
+// show handles a SHOW statement.
+func (s *session) show(stmt string, describe bool) (*result, bool, error) {
+	name := argument(stmt)
+	if name == "transaction isolation level" {
+		name = "transaction_isolation"
+	}
+	if describe {
+		return textResult(name, nil), true, nil
+	}
+
+	var value string
+	switch name {
+	case paramConsistency:
+		value = command.LevelToString(s.level)
+	case paramFreshness:
+		value = s.freshness.String()
+	case paramFreshnessStrict:
+		value = strconv.FormatBool(s.freshnessStrict)
+	default:
+		v, ok := s.params[name]
+		if !ok {
+			return nil, true, newError("42704", "unrecognized configuration parameter %q", name)
+		}
+		value = v
+	}
+	return textResult(name, [][]string{{value}}), true, nil
+}
+
+// listTables answers the catalog query psql uses to list tables.
+func (s *session) listTables(describe bool) (*result, bool, error) {
+	if describe {
+		return &result{fields: textFields("Schema", "Name", "Type", "Owner")}, true, nil
+	}
+	rows, err := s.query(listTablesSQL, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	return &result{
+		fields: textFields(rows.Columns...),
+		rows:   rows,
+		tag:    "SELECT " + strconv.Itoa(len(rows.Values)),
+	}, true, nil
+}
+
+// textResult returns a result with a single text column, with the given rows.
+func textResult(column string, values [][]string) *result {
+	rows := &proto.QueryRows{Columns: []string{column}, Types: []string{"text"}}
+	for _, row := range values {
+		params := make([]*proto.Parameter, len(row))
+		for i, v := range row {
+			params[i] = &proto.Parameter{Value: &proto.Parameter_S{S: v}}
+		}
+		rows.Values = append(rows.Values, &proto.Values{Parameters: params})
+	}
+	return &result{
+		fields: []pgproto3.FieldDescription{newField(column, oidText)},
+		rows:   rows,
+		tag:    "SELECT " + strconv.Itoa(len(values)),
+	}
+}
+
+// argument returns the text of stmt following its first keyword, lower-cased
+// and with runs of whitespace collapsed.
+func argument(stmt string) string {
+	n := normalize(stmt)
+	if i := strings.IndexByte(n, ' '); i >= 0 {
+		return n[i+1:]
+	}
+	return ""
+}
+
+// parseSet parses a statement of the form SET [SESSION | LOCAL] name
+// { TO | = } value, returning the lower-cased name and the unquoted value.
+func parseSet(stmt string) (string, string, bool) {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(stmt), ";"))
+	s = strings.TrimSpace(s[len("SET"):])
+	for _, mod := range []string{"SESSION ", "LOCAL "} {
+		if len(s) > len(mod) && strings.EqualFold(s[:len(mod)], mod) {
+			s = strings.TrimSpace(s[len(mod):])
+		}
+	}
+
+	var name, value string
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		name, value = s[:i], s[i+1:]
+	} else {
+		f := strings.Fields(s)
+		switch {
+		case len(f) >= 3 && strings.EqualFold(f[1], "TO"):
+			name, value = f[0], strings.Join(f[2:], " ")
+		case len(f) >= 2 && strings.EqualFold(f[0], "TIME") && strings.EqualFold(f[1], "ZONE"):
+			name, value = "timezone", strings.Join(f[2:], " ")
+		case len(f) >= 2 && strings.EqualFold(f[0], "NAMES"):
+			name, value = "client_encoding", strings.Join(f[1:], " ")
+		default:
+			return "", "", false
+		}
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	if name == "" || value == "" {
+		return "", "", false
+	}
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = strings.ReplaceAll(value[1:len(value)-1], string(value[0])+string(value[0]), string(value[0]))
+	}
+	return name, value, true
+}
@@ -0,0 +1,276 @@
+// Package pgwire provides a listener which speaks the PostgreSQL v3
+// frontend/backend protocol, allowing PostgreSQL clients and drivers to
+// access the distributed database.
+//
+// Statements are sent to the cluster exactly as they would be by the HTTP
+// API, so writes are forwarded to the Leader. Within a transaction block,
+// writes are buffered by the session and sent to the cluster as a single
+// transactional request on COMMIT. Reads within a transaction block are
+// executed immediately, and so do not see the buffered writes.
+package pgwire
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/internal/logging"
+	"github.com/rqlite/rqlite/v10/internal/rsync"
+)
+
+var (
+	// stats captures stats for the PostgreSQL wire service.
+	stats *expvar.Map
+
+	// ErrServiceOpen is returned when the service is already open.
+	ErrServiceOpen = errors.New("service already open")
+)
+
+const (
+	numConnections      = "connections"
+	numConnRejected     = "conn_rejected"
+	numAuthFailures     = "auth_failures"
+	numAuthNoTLS        = "auth_no_tls"
+	numSimpleQueries    = "simple_queries"
+	numExtendedQueries  = "extended_queries"
+	numStatements       = "statements"
+	numStatementErrors  = "statement_errors"
+	numTLSConnections   = "tls_connections"
+	numCancelRequests   = "cancel_requests"
+	numTransactions     = "transactions"
+	numTransactionsFail = "transactions_failed"
+)
+
+const (
+	// DefaultTimeout is the default time allowed for each request sent to
+	// the cluster.
+	DefaultTimeout = 30 * time.Second
+
+	// maxConcurrentConns bounds the number of client connections the
+	// service handles concurrently.
+	maxConcurrentConns = 512
+
+	// serverVersion is the PostgreSQL version reported to clients. Clients
+	// use it to decide which features and catalog queries to use.
+	serverVersion = "14.0"
+)
+
+func init() {
+	stats = expvar.NewMap("pgwire")
+	ResetStats()
+}
+
+// ResetStats resets the expvar stats for this module. Mostly for test purposes.
+func ResetStats() {
+	stats.Init()
+	stats.Add(numConnections, 0)
+	stats.Add(numConnRejected, 0)
+	stats.Add(numAuthFailures, 0)
+	stats.Add(numAuthNoTLS, 0)
+	stats.Add(numSimpleQueries, 0)
+	stats.Add(numExtendedQueries, 0)
+	stats.Add(numStatements, 0)
+	stats.Add(numStatementErrors, 0)
+	stats.Add(numTLSConnections, 0)
+	stats.Add(numCancelRequests, 0)
+	stats.Add(numTransactions, 0)
+	stats.Add(numTransactionsFail, 0)
+}
+
+// Proxy is the interface the service uses to send requests to the cluster.
+// Requests are forwarded to the Leader if this node is not the Leader.
+type Proxy interface {
+	Execute(ctx context.Context, er *proto.ExecuteRequest, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error)
+	Query(ctx context.Context, qr *proto.QueryRequest, creds *clstrPB.Credentials,
+		timeout time.Duration, retries int, noForward bool) ([]*proto.QueryRows, uint64, string, error)
+}
+
+// CredentialStore is the interface credential stores must support.
+type CredentialStore interface {
+	// AA authenticates and checks authorization for the given perm.
+	AA(username, password, perm string) bool
+}
+
+// Service provides a PostgreSQL wire protocol front end to the database.
+type Service struct {
+	ln   net.Listener // Incoming connections to the service
+	addr net.Addr     // Address on which this service is listening
+
+	proxy           Proxy
+	credentialStore CredentialStore
+
+	// TLSConfig, if set, allows clients to upgrade their connections to TLS.
+	// Must be set before Start is called.
+	TLSConfig *tls.Config
+
+	// InsecureAuth, if set, allows clients to send their passwords, which
+	// are sent in cleartext, over connections not upgraded to TLS. Otherwise
+	// such clients are refused if authentication is required.
+	InsecureAuth bool
+
+	// Timeout is the time allowed for each request sent to the cluster.
+	Timeout time.Duration
+
+	// Retries is the number of times a forwarded request is retried.
+	Retries int
+
+	// Version is the rqlite version reported by version().
+	Version string
+
+	// Linter, if set, checks writes for non-determinism before they are
+	// sent to the Leader. Must be set before Start is called.
+	Linter *sql.Linter
+
+	// Catalog, if set, is consulted so that only INSERT statements which
+	// need them record values for non-deterministic column defaults. Must
+	// be set before Start is called.
+	Catalog sql.Catalog
+
+	// Authorizer, if set, checks that statements only access the tables
+	// permitted to the user. Must be set before Start is called.
+	Authorizer *sql.Authorizer
+
+	open   *rsync.AtomicBool
+	nextID atomic.Uint32
+
+	mu       sync.Mutex
+	sessions map[uint32]*session
+	wg       sync.WaitGroup
+
+	connLimiterCh chan struct{}
+
+	logger *log.Logger
+}
+
+// New returns a new instance of the PostgreSQL wire service. If credentialStore
+// is nil, clients are not required to authenticate.
+func New(ln net.Listener, p Proxy, credentialStore CredentialStore) *Service {
+	return &Service{
+		ln:              ln,
+		addr:            ln.Addr(),
+		proxy:           p,
+		credentialStore: credentialStore,
+		Timeout:         DefaultTimeout,
+		open:            rsync.NewAtomicBool(),
+		sessions:        make(map[uint32]*session),
+		logger:          logging.New("pgwire", os.Stderr),
+	}
+}
+
+// Start starts the service.
+func (s *Service) Start() error {
+	if s.open.Is() {
+		return ErrServiceOpen
+	}
+	s.connLimiterCh = make(chan struct{}, maxConcurrentConns)
+	s.open.Set()
+
+	go s.serve()
+	s.logger.Println("service listening on", s.addr)
+	return nil
+}
+
+// Close closes the service, and all client connections.
+func (s *Service) Close() error {
+	s.open.Unset()
+	err := s.ln.Close()
+	s.mu.Lock()
+	for _, sess := range s.sessions {
+		sess.raw.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Addr returns the address on which the service is listening.
+func (s *Service) Addr() net.Addr {
+	return s.addr
+}
+
+// Stats returns status of the service.
+func (s *Service) Stats() (map[string]any, error) {
+	s.mu.Lock()
+	n := len(s.sessions)
+	s.mu.Unlock()
+	return map[string]any{
+		"addr":          s.addr.String(),
+		"auth":          strconv.FormatBool(s.credentialStore != nil),
+		"tls":           strconv.FormatBool(s.TLSConfig != nil),
+		"insecure_auth": strconv.FormatBool(s.InsecureAuth),
+		"timeout":       s.Timeout.String(),
+		"sessions":      n,
+		"pg_version":    serverVersion,
+	}, nil
+}
+
+func (s *Service) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		select {
+		case s.connLimiterCh <- struct{}{}:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer func() { <-s.connLimiterCh }()
+				s.handleConn(conn)
+			}()
+		default:
+			_ = conn.Close()
+			stats.Add(numConnRejected, 1)
+		}
+	}
+}
+
+func (s *Service) handleConn(conn net.Conn) {
+	stats.Add(numConnections, 1)
+	sess := newSession(s, conn, s.nextID.Add(1))
+	s.mu.Lock()
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.sessions, sess.id)
+		s.mu.Unlock()
+		sess.raw.Close()
+	}()
+
+	if err := sess.run(); err != nil && s.open.Is() && !isClosedErr(err) {
+		s.logger.Printf("connection from %s closed: %s", conn.RemoteAddr(), err)
+	}
+}
+
+// cancel cancels the request in progress, if any, on the session with
+// the given ID and secret key.
+func (s *Service) cancel(id uint32, key []byte) {
+	stats.Add(numCancelRequests, 1)
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	s.mu.Unlock()
+	if ok && bytes.Equal(sess.key, key) {
+		sess.cancelRequest()
+	}
+}
+
+func isClosedErr(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, errTerminated) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,565 @@
+package pgwire
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/db"
+	"github.com/rqlite/rqlite/v10/testdata/x509"
+)
+
+func Test_NewService(t *testing.T) {
+	s := mustNewService(t, nil)
+	if s.Addr() == nil {
+		t.Fatalf("service has no address")
+	}
+	if err := s.Start(); err != ErrServiceOpen {
+		t.Fatalf("expected ErrServiceOpen, got %v", err)
+	}
+	st, err := s.Stats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %s", err)
+	}
+	if st["addr"] != s.Addr().String() {
+		t.Fatalf("wrong address in stats: %v", st["addr"])
+	}
+}
+
+func Test_SimpleQuery(t *testing.T) {
+	s := mustNewService(t, nil)
+	conn := mustConnect(t, s, "")
+
+	// Simple protocol, multiple statements.
+	mrr := conn.PgConn().Exec(context.Background(),
+		"CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, age INT); "+
+			"INSERT INTO foo(name, age) VALUES('fiona', 20); INSERT INTO foo(name, age) VALUES('declan', 30)")
+	results, err := mrr.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to execute: %s", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if exp, got := "CREATE TABLE", results[0].CommandTag.String(); exp != got {
+		t.Fatalf("exp tag %q, got %q", exp, got)
+	}
+	if exp, got := "INSERT 0 1", results[2].CommandTag.String(); exp != got {
+		t.Fatalf("exp tag %q, got %q", exp, got)
+	}
+
+	rr := conn.PgConn().ExecParams(context.Background(), "SELECT id, name FROM foo ORDER BY id", nil, nil, nil, nil)
+	res := rr.Read()
+	if res.Err != nil {
+		t.Fatalf("failed to query: %s", res.Err)
+	}
+	if exp, got := "SELECT 2", res.CommandTag.String(); exp != got {
+		t.Fatalf("exp tag %q, got %q", exp, got)
+	}
+	if exp, got := "[[1 fiona] [2 declan]]", fmt.Sprintf("%s", res.Rows); exp != got {
+		t.Fatalf("exp rows %s, got %s", exp, got)
+	}
+
+	// An error in one statement stops the rest.
+	_, err = conn.PgConn().Exec(context.Background(), "SELECT * FROM nonexistent; SELECT 1").ReadAll()
+	if code := pgCode(err); code != "42P01" {
+		t.Fatalf("expected error code 42P01, got %v", err)
+	}
+}
+
+func Test_ExtendedQuery(t *testing.T) {
+	s := mustNewService(t, nil)
+	conn := mustConnect(t, s, "")
+	ctx := context.Background()
+
+	mustExec(t, conn, "CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, score REAL, ok BOOLEAN, data BLOB)")
+	tag, err := conn.Exec(ctx, "INSERT INTO foo(name, score, ok, data) VALUES($1, $2, $3, $4)",
+		"fiona", 1.5, true, []byte{1, 2, 3})
+	if err != nil {
+		t.Fatalf("failed to insert: %s", err)
+	}
+	if exp, got := "INSERT 0 1", tag.String(); exp != got {
+		t.Fatalf("exp tag %q, got %q", exp, got)
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO foo(name, score) VALUES($1, $2)", "declan", 7); err != nil {
+		t.Fatalf("failed to insert: %s", err)
+	}
+
+	var id int64
+	var name string
+	var score float64
+	var ok bool
+	var data []byte
+	err = conn.QueryRow(ctx, "SELECT id, name, score, ok, data FROM foo WHERE name = $1", "fiona").
+		Scan(&id, &name, &score, &ok, &data)
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	if id != 1 || name != "fiona" || score != 1.5 || !ok || string(data) != "\x01\x02\x03" {
+		t.Fatalf("wrong row: %d %s %f %v %v", id, name, score, ok, data)
+	}
+
+	var cnt int64
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo WHERE id > $1", 0).Scan(&cnt); err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	if cnt != 2 {
+		t.Fatalf("expected count 2, got %d", cnt)
+	}
+
+	// Results of RETURNING.
+	var newID int64
+	if err := conn.QueryRow(ctx, "INSERT INTO foo(name) VALUES($1) RETURNING id", "tom").Scan(&newID); err != nil {
+		t.Fatalf("failed to insert with RETURNING: %s", err)
+	}
+	if newID != 3 {
+		t.Fatalf("expected id 3, got %d", newID)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT name FROM foo ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("failed to collect rows: %s", err)
+	}
+	if exp, got := "[fiona declan tom]", fmt.Sprint(names); exp != got {
+		t.Fatalf("exp %s, got %s", exp, got)
+	}
+
+	// Constraint violations are reported with the right code.
+	_, err = conn.Exec(ctx, "INSERT INTO foo(id, name) VALUES($1, $2)", 1, "dup")
+	if code := pgCode(err); code != "23505" {
+		t.Fatalf("expected error code 23505, got %v", err)
+	}
+
+	// The connection remains usable after an error.
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&cnt); err != nil || cnt != 3 {
+		t.Fatalf("failed to query after error, count %d: %v", cnt, err)
+	}
+}
+
+func Test_Transaction(t *testing.T) {
+	s := mustNewService(t, nil)
+	conn := mustConnect(t, s, "")
+	ctx := context.Background()
+	mustExec(t, conn, "CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT UNIQUE)")
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %s", err)
+	}
+	for _, n := range []string{"fiona", "declan"} {
+		if _, err := tx.Exec(ctx, "INSERT INTO foo(name) VALUES($1)", n); err != nil {
+			t.Fatalf("failed to insert in transaction: %s", err)
+		}
+	}
+	if n := mustCount(t, conn); n != 0 {
+		t.Fatalf("expected no rows before commit, got %d", n)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+	if n := mustCount(t, conn); n != 2 {
+		t.Fatalf("expected 2 rows after commit, got %d", n)
+	}
+
+	// Rolled back transactions have no effect.
+	tx, err = conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %s", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO foo(name) VALUES($1)", "tom"); err != nil {
+		t.Fatalf("failed to insert in transaction: %s", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("failed to roll back: %s", err)
+	}
+	if n := mustCount(t, conn); n != 2 {
+		t.Fatalf("expected 2 rows after rollback, got %d", n)
+	}
+
+	// A failure at commit applies none of the transaction.
+	tx, err = conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %s", err)
+	}
+	for _, n := range []string{"tom", "fiona"} {
+		if _, err := tx.Exec(ctx, "INSERT INTO foo(name) VALUES($1)", n); err != nil {
+			t.Fatalf("failed to insert in transaction: %s", err)
+		}
+	}
+	if err := tx.Commit(ctx); pgCode(err) != "23505" {
+		t.Fatalf("expected error code 23505 on commit, got %v", err)
+	}
+	if n := mustCount(t, conn); n != 2 {
+		t.Fatalf("expected 2 rows after failed commit, got %d", n)
+	}
+}
+
+func Test_SessionSettings(t *testing.T) {
+	mp := mustNewMockProxy(t)
+	s := mustNewServiceWithProxy(t, mp, nil)
+	conn := mustConnect(t, s, "")
+	ctx := context.Background()
+	mustExec(t, conn, "CREATE TABLE foo (id INTEGER PRIMARY KEY)")
+
+	var level string
+	if err := conn.QueryRow(ctx, "SHOW rqlite.consistency").Scan(&level); err != nil {
+		t.Fatalf("failed to show consistency: %s", err)
+	}
+	if level != "weak" {
+		t.Fatalf("expected weak consistency, got %s", level)
+	}
+
+	mustExec(t, conn, "SET rqlite.consistency = 'strong'")
+	mustExec(t, conn, "SET rqlite.freshness TO '1s'")
+	mustCount(t, conn)
+	if l, f := mp.last(); l != proto.ConsistencyLevel_STRONG || f != time.Second.Nanoseconds() {
+		t.Fatalf("expected strong consistency with freshness of 1s, got %s, %d", l, f)
+	}
+
+	if _, err := conn.Exec(ctx, "SET rqlite.consistency = 'bogus'"); pgCode(err) != "22023" {
+		t.Fatalf("expected error code 22023, got %v", err)
+	}
+
+	mustExec(t, conn, "RESET rqlite.consistency")
+	mustCount(t, conn)
+	if l, _ := mp.last(); l != proto.ConsistencyLevel_WEAK {
+		t.Fatalf("expected weak consistency after reset, got %s", l)
+	}
+
+	var version string
+	if err := conn.QueryRow(ctx, "SELECT version()").Scan(&version); err != nil {
+		t.Fatalf("failed to query version: %s", err)
+	}
+	if version == "" {
+		t.Fatalf("empty version")
+	}
+}
+
+func Test_ListTables(t *testing.T) {
+	s := mustNewService(t, nil)
+	conn := mustConnect(t, s, "")
+	mustExec(t, conn, "CREATE TABLE foo (id INTEGER PRIMARY KEY)")
+	mustExec(t, conn, "CREATE VIEW bar AS SELECT * FROM foo")
+
+	// The query psql sends for \dt.
+	q := `SELECT n.nspname as "Schema", c.relname as "Name",
+  CASE c.relkind WHEN 'r' THEN 'table' WHEN 'v' THEN 'view' END as "Type",
+  pg_catalog.pg_get_userbyid(c.relowner) as "Owner"
+FROM pg_catalog.pg_class c
+     LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r','p','')
+ORDER BY 1,2;`
+	res, err := conn.PgConn().Exec(context.Background(), q).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to list tables: %s", err)
+	}
+	if exp, got := "[[main bar view ] [main foo table ]]", fmt.Sprintf("%s", res[0].Rows); exp != got {
+		t.Fatalf("exp %s, got %s", exp, got)
+	}
+}
+
+func Test_Auth(t *testing.T) {
+	cs := &mockCredentialStore{
+		users: map[string]string{"alice": "secret", "bob": "secret"},
+		perms: map[string][]string{"alice": {"query", "execute"}, "bob": {"query"}},
+	}
+	s := mustNewServiceWithConfig(t, mustNewMockProxy(t), cs, func(s *Service) {
+		s.TLSConfig = mustTLSConfig(t)
+	})
+
+	if _, err := connectWithSSLMode(s, "alice:wrong@", "require"); pgCode(err) != "28P01" {
+		t.Fatalf("expected error code 28P01, got %v", err)
+	}
+
+	// Passwords are sent in cleartext, so must not be sent without TLS.
+	if _, err := connect(s, "alice:secret@"); pgCode(err) != "28000" {
+		t.Fatalf("expected error code 28000, got %v", err)
+	}
+
+	alice := mustConnectWithSSLMode(t, s, "alice:secret@", "require")
+	mustExec(t, alice, "CREATE TABLE foo (id INTEGER PRIMARY KEY)")
+
+	bob := mustConnectWithSSLMode(t, s, "bob:secret@", "require")
+	if n := mustCount(t, bob); n != 0 {
+		t.Fatalf("expected no rows, got %d", n)
+	}
+	if _, err := bob.Exec(context.Background(), "INSERT INTO foo(id) VALUES(1)"); pgCode(err) != "42501" {
+		t.Fatalf("expected error code 42501, got %v", err)
+	}
+}
+
+// Test_ReadsAndWrites checks reads are sent as queries, and writes for
+// execution, so each needs only the matching permission when forwarded.
+func Test_ReadsAndWrites(t *testing.T) {
+	p := mustNewMockProxy(t)
+	s := mustNewServiceWithProxy(t, p, nil)
+	conn := mustConnect(t, s, "")
+
+	mustExec(t, conn, "CREATE TABLE foo (id INTEGER PRIMARY KEY)")
+	mustExec(t, conn, "INSERT INTO foo(id) VALUES(1)")
+	if exp, got := int32(2), p.executes.Load(); exp != got {
+		t.Fatalf("expected %d executes, got %d", exp, got)
+	}
+	if p.queries.Load() != 0 {
+		t.Fatalf("expected no queries, got %d", p.queries.Load())
+	}
+
+	if n := mustCount(t, conn); n != 1 {
+		t.Fatalf("expected 1 row, got %d", n)
+	}
+	var id int64
+	if err := conn.QueryRow(context.Background(), "INSERT INTO foo(id) VALUES(2) RETURNING id").Scan(&id); err != nil {
+		t.Fatalf("failed to insert with RETURNING: %s", err)
+	}
+	if id != 2 {
+		t.Fatalf("expected id 2, got %d", id)
+	}
+	if exp, got := int32(3), p.executes.Load(); exp != got {
+		t.Fatalf("expected %d executes, got %d", exp, got)
+	}
+	if p.queries.Load() == 0 {
+		t.Fatalf("expected reads to be sent as queries")
+	}
+}
+
+func Test_AuthInsecure(t *testing.T) {
+	cs := &mockCredentialStore{
+		users: map[string]string{"alice": "secret"},
+		perms: map[string][]string{"alice": {"query", "execute"}},
+	}
+	s := mustNewServiceWithConfig(t, mustNewMockProxy(t), cs, func(s *Service) {
+		s.InsecureAuth = true
+	})
+	if _, err := connect(s, "alice:wrong@"); pgCode(err) != "28P01" {
+		t.Fatalf("expected error code 28P01, got %v", err)
+	}
+	alice := mustConnect(t, s, "alice:secret@")
+	mustExec(t, alice, "CREATE TABLE foo (id INTEGER PRIMARY KEY)")
+}
+
+func Test_Cancel(t *testing.T) {
+	mp := mustNewMockProxy(t)
+	mp.block = make(chan struct{})
+	s := mustNewServiceWithProxy(t, mp, nil)
+	conn := mustConnect(t, s, "")
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := conn.Exec(context.Background(), "SELECT 1")
+		errCh <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mp.blocked.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("request never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := conn.PgConn().CancelRequest(context.Background()); err != nil {
+		t.Fatalf("failed to cancel request: %s", err)
+	}
+	select {
+	case err := <-errCh:
+		if pgCode(err) != "57014" {
+			t.Fatalf("expected error code 57014, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request was not canceled")
+	}
+}
+
+func mustNewService(t *testing.T, cs CredentialStore) *Service {
+	t.Helper()
+	return mustNewServiceWithProxy(t, mustNewMockProxy(t), cs)
+}
+
+func mustNewServiceWithProxy(t *testing.T, p Proxy, cs CredentialStore) *Service {
+	t.Helper()
+	return mustNewServiceWithConfig(t, p, cs, nil)
+}
+
+// mustNewServiceWithConfig returns a started service, calling configure, if
+// not nil, on the service before it is started.
+func mustNewServiceWithConfig(t *testing.T, p Proxy, cs CredentialStore, configure func(*Service)) *Service {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	s := New(ln, p, cs)
+	s.Version = "test"
+	if configure != nil {
+		configure(s)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service: %s", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func mustTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	dir := t.TempDir()
+	cert, err := tls.LoadX509KeyPair(x509.CertExampleDotComFile(dir), x509.KeyExampleDotComFile(dir))
+	if err != nil {
+		t.Fatalf("failed to load key pair: %s", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
+func connect(s *Service, userinfo string) (*pgx.Conn, error) {
+	return connectWithSSLMode(s, userinfo, "disable")
+}
+
+func connectWithSSLMode(s *Service, userinfo, sslmode string) (*pgx.Conn, error) {
+	if userinfo == "" {
+		userinfo = "rqlite@"
+	}
+	url := fmt.Sprintf("postgres://%s%s/main?sslmode=%s", userinfo, s.Addr().String(), sslmode)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return pgx.Connect(ctx, url)
+}
+
+func mustConnect(t *testing.T, s *Service, userinfo string) *pgx.Conn {
+	t.Helper()
+	return mustConnectWithSSLMode(t, s, userinfo, "disable")
+}
+
+func mustConnectWithSSLMode(t *testing.T, s *Service, userinfo, sslmode string) *pgx.Conn {
+	t.Helper()
+	conn, err := connectWithSSLMode(s, userinfo, sslmode)
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	t.Cleanup(func() { conn.Close(context.Background()) })
+	return conn
+}
+
+func mustExec(t *testing.T, conn *pgx.Conn, stmt string) {
+	t.Helper()
+	if _, err := conn.Exec(context.Background(), stmt); err != nil {
+		t.Fatalf("failed to execute %q: %s", stmt, err)
+	}
+}
+
+func mustCount(t *testing.T, conn *pgx.Conn) int64 {
+	t.Helper()
+	var n int64
+	if err := conn.QueryRow(context.Background(), "SELECT COUNT(*) FROM foo").Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %s", err)
+	}
+	return n
+}
+
+func pgCode(err error) string {
+	var pe *pgconn.PgError
+	if errors.As(err, &pe) {
+		return pe.Code
+	}
+	return ""
+}
+
+// mockProxy executes requests against a local database.
+type mockProxy struct {
+	db *db.DB
+
+	block   chan struct{}
+	blocked atomic.Int32
+
+	executes atomic.Int32
+	queries  atomic.Int32
+
+	mu sync.Mutex
+
+	lastLevel     proto.ConsistencyLevel
+	lastFreshness int64
+}
+
+func mustNewMockProxy(t *testing.T) *mockProxy {
+	t.Helper()
+	d, err := db.Open(filepath.Join(t.TempDir(), "db.sqlite"), false, true)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return &mockProxy{db: d}
+}
+
+func (m *mockProxy) Execute(ctx context.Context, er *proto.ExecuteRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) ([]*proto.ExecuteQueryResponse, uint64, string, error) {
+	if err := m.wait(ctx); err != nil {
+		return nil, 0, "", err
+	}
+	m.executes.Add(1)
+	resps, err := m.db.ExecuteWithContext(ctx, er.Request, false)
+	return resps, 0, "", err
+}
+
+func (m *mockProxy) Query(ctx context.Context, qr *proto.QueryRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) ([]*proto.QueryRows, uint64, string, error) {
+	if err := m.wait(ctx); err != nil {
+		return nil, 0, "", err
+	}
+	m.queries.Add(1)
+	m.mu.Lock()
+	m.lastLevel = qr.Level
+	m.lastFreshness = qr.Freshness
+	m.mu.Unlock()
+	rows, err := m.db.QueryWithContext(ctx, qr.Request, false)
+	return rows, 0, "", err
+}
+
+// wait blocks until the proxy is unblocked, if it was created blocked.
+func (m *mockProxy) wait(ctx context.Context) error {
+	if m.block == nil {
+		return nil
+	}
+	m.blocked.Add(1)
+	select {
+	case <-m.block:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *mockProxy) last() (proto.ConsistencyLevel, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastLevel, m.lastFreshness
+}
+
+type mockCredentialStore struct {
+	users map[string]string
+	perms map[string][]string
+}
+
+func (m *mockCredentialStore) AA(username, password, perm string) bool {
+	if p, ok := m.users[username]; !ok || p != password {
+		return false
+	}
+	for _, p := range m.perms[username] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,927 @@
+package pgwire
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/rqlite/rqlite/v10/auth"
+	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/command/sql"
+	"github.com/rqlite/rqlite/v10/proxy"
+)
+
+// errTerminated is returned when the client ends the session.
+var errTerminated = errors.New("session terminated by client")
+
+// Transaction status indicators, as sent in ReadyForQuery messages.
+const (
+	txIdle   = 'I'
+	txActive = 'T'
+	txFailed = 'E'
+)
+
+// pgError is an error reported to the client with a SQLSTATE code.
+type pgError struct {
+	severity string
+	code     string
+	msg      string
+}
+
+func (e *pgError) Error() string {
+	return e.msg
+}
+
+func newError(code, format string, args ...any) *pgError {
+	return &pgError{severity: "ERROR", code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// preparedStatement is a statement created by a Parse message.
+type preparedStatement struct {
+	query     string   // SQL as sent by the client.
+	sql       string   // SQL with placeholders rewritten for SQLite.
+	paramOIDs []uint32 // Type of each parameter, 0 if not yet known.
+
+	described bool
+	fields    []pgproto3.FieldDescription // Nil if the statement returns no rows.
+}
+
+// portal is a prepared statement bound to parameters by a Bind message.
+type portal struct {
+	stmt    *preparedStatement
+	params  []*proto.Parameter
+	formats []int16
+
+	res *result // Result of execution, nil until executed.
+	pos int     // Index of the next row to send.
+}
+
+// result is the outcome of executing a single statement.
+type result struct {
+	fields []pgproto3.FieldDescription // Nil if the statement returns no rows.
+	rows   *proto.QueryRows
+	tag    string
+}
+
+// session is a single client connection.
+type session struct {
+	svc  *Service
+	raw  net.Conn // Underlying connection, closed when the service closes.
+	conn net.Conn // Connection in use, which may be a TLS connection over raw.
+	be   *pgproto3.Backend
+
+	id  uint32
+	key []byte
+
+	user     string
+	password string
+	params   map[string]string
+
+	level           proto.ConsistencyLevel
+	freshness       time.Duration
+	freshnessStrict bool
+
+	txStatus byte
+	txStmts  []*proto.Statement
+
+	stmts   map[string]*preparedStatement
+	portals map[string]*portal
+
+	// ignoring is set after an error in the extended query protocol, and
+	// causes messages to be discarded until the next Sync.
+	ignoring bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newSession(svc *Service, conn net.Conn, id uint32) *session {
+	key := make([]byte, 4)
+	rand.Read(key)
+	return &session{
+		svc:      svc,
+		raw:      conn,
+		conn:     conn,
+		be:       pgproto3.NewBackend(conn, conn),
+		id:       id,
+		key:      key,
+		params:   defaultParams(),
+		level:    proto.ConsistencyLevel_WEAK,
+		txStatus: txIdle,
+		stmts:    make(map[string]*preparedStatement),
+		portals:  make(map[string]*portal),
+	}
+}
+
+// run handles the session until the client disconnects or an error occurs.
+func (s *session) run() error {
+	ok, err := s.startup()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		msg, err := s.be.Receive()
+		if err != nil {
+			return err
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// startup handles the startup phase of the session, including any TLS
+// negotiation and authentication. It returns false if the session should
+// end without error.
+func (s *session) startup() (bool, error) {
+	for {
+		msg, err := s.be.ReceiveStartupMessage()
+		if err != nil {
+			return false, err
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.SSLRequest:
+			if s.svc.TLSConfig == nil || s.conn != s.raw {
+				if _, err := s.conn.Write([]byte{'N'}); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if _, err := s.conn.Write([]byte{'S'}); err != nil {
+				return false, err
+			}
+			tlsConn := tls.Server(s.conn, s.svc.TLSConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return false, err
+			}
+			stats.Add(numTLSConnections, 1)
+			s.conn = tlsConn
+			s.be = pgproto3.NewBackend(tlsConn, tlsConn)
+		case *pgproto3.GSSEncRequest:
+			if _, err := s.conn.Write([]byte{'N'}); err != nil {
+				return false, err
+			}
+		case *pgproto3.CancelRequest:
+			s.svc.cancel(m.ProcessID, m.SecretKey)
+			return false, nil
+		case *pgproto3.StartupMessage:
+			return s.authenticate(m)
+		default:
+			return false, fmt.Errorf("unexpected startup message %T", msg)
+		}
+	}
+}
+
+// authenticate authenticates the client, if required, and completes the
+// startup phase.
+func (s *session) authenticate(m *pgproto3.StartupMessage) (bool, error) {
+	s.user = m.Parameters["user"]
+	for k, v := range m.Parameters {
+		switch k {
+		case "user", "database", "options", "replication":
+		default:
+			s.params[strings.ToLower(k)] = v
+		}
+	}
+
+	if s.svc.credentialStore != nil {
+		// The password would be sent in cleartext, so only ask for it over
+		// TLS, unless explicitly allowed.
+		if s.conn == s.raw && !s.svc.InsecureAuth {
+			stats.Add(numAuthNoTLS, 1)
+			s.be.Send(&pgproto3.ErrorResponse{
+				Severity: "FATAL",
+				Code:     "28000",
+				Message:  "password authentication requires an SSL connection",
+			})
+			return false, s.be.Flush()
+		}
+		if err := s.be.SetAuthType(pgproto3.AuthTypeCleartextPassword); err != nil {
+			return false, err
+		}
+		s.be.Send(&pgproto3.AuthenticationCleartextPassword{})
+		if err := s.be.Flush(); err != nil {
+			return false, err
+		}
+		msg, err := s.be.Receive()
+		if err != nil {
+			return false, err
+		}
+		pm, ok := msg.(*pgproto3.PasswordMessage)
+		if !ok {
+			return false, fmt.Errorf("expected password message, got %T", msg)
+		}
+		s.password = pm.Password
+		if !s.svc.credentialStore.AA(s.user, s.password, auth.PermQuery) &&
+			!s.svc.credentialStore.AA(s.user, s.password, auth.PermExecute) {
+			stats.Add(numAuthFailures, 1)
+			s.be.Send(&pgproto3.ErrorResponse{
+				Severity: "FATAL",
+				Code:     "28P01",
+				Message:  fmt.Sprintf("password authentication failed for user %q", s.user),
+			})
+			return false, s.be.Flush()
+		}
+	}
+
+	s.be.Send(&pgproto3.AuthenticationOk{})
+	for _, k := range []string{"server_version", "server_encoding", "client_encoding",
+		"DateStyle", "IntervalStyle", "TimeZone", "integer_datetimes", "standard_conforming_strings",
+		"application_name"} {
+		s.be.Send(&pgproto3.ParameterStatus{Name: k, Value: s.params[strings.ToLower(k)]})
+	}
+	s.be.Send(&pgproto3.BackendKeyData{ProcessID: s.id, SecretKey: s.key})
+	s.be.Send(&pgproto3.ReadyForQuery{TxStatus: s.txStatus})
+	return true, s.be.Flush()
+}
+
+// handle handles a single message from the client.
+func (s *session) handle(msg pgproto3.FrontendMessage) error {
+	switch m := msg.(type) {
+	case *pgproto3.Query:
+		return s.handleQuery(m.String)
+	case *pgproto3.Terminate:
+		return errTerminated
+	case *pgproto3.Sync:
+		s.ignoring = false
+		if s.txStatus == txIdle {
+			clear(s.portals)
+		}
+		s.be.Send(&pgproto3.ReadyForQuery{TxStatus: s.txStatus})
+		return s.be.Flush()
+	case *pgproto3.Flush:
+		return s.be.Flush()
+	}
+
+	if s.ignoring {
+		return nil
+	}
+	var err error
+	switch m := msg.(type) {
+	case *pgproto3.Parse:
+		err = s.handleParse(m)
+	case *pgproto3.Bind:
+		err = s.handleBind(m)
+	case *pgproto3.Describe:
+		err = s.handleDescribe(m)
+	case *pgproto3.Execute:
+		err = s.handleExecute(m)
+	case *pgproto3.Close:
+		if m.ObjectType == 'S' {
+			delete(s.stmts, m.Name)
+		} else {
+			delete(s.portals, m.Name)
+		}
+		s.be.Send(&pgproto3.CloseComplete{})
+	default:
+		err = newError("08P01", "unsupported message %T", msg)
+	}
+	if err != nil {
+		var pe *pgError
+		if !errors.As(err, &pe) {
+			return err
+		}
+		s.sendError(pe)
+		s.failTx(pe)
+		s.ignoring = true
+	}
+	return nil
+}
+
+// handleQuery handles a query sent using the simple query protocol. The
+// query may contain multiple statements.
+func (s *session) handleQuery(q string) error {
+	stats.Add(numSimpleQueries, 1)
+	stmts := splitStatements(q)
+	if len(stmts) == 0 {
+		s.be.Send(&pgproto3.EmptyQueryResponse{})
+	}
+	for _, stmt := range stmts {
+		res, err := s.execute(stmt, stmt, nil)
+		if err != nil {
+			var pe *pgError
+			if !errors.As(err, &pe) {
+				return err
+			}
+			s.sendError(pe)
+			break
+		}
+		if res.fields != nil {
+			s.be.Send(&pgproto3.RowDescription{Fields: res.fields})
+			if err := s.sendRows(res.fields, res.rows.GetValues()); err != nil {
+				s.sendError(err)
+				break
+			}
+		}
+		s.be.Send(&pgproto3.CommandComplete{CommandTag: []byte(res.tag)})
+	}
+	s.be.Send(&pgproto3.ReadyForQuery{TxStatus: s.txStatus})
+	return s.be.Flush()
+}
+
+func (s *session) handleParse(m *pgproto3.Parse) error {
+	stats.Add(numExtendedQueries, 1)
+	stmts := splitStatements(m.Query)
+	if len(stmts) > 1 {
+		return newError("42601", "cannot insert multiple commands into a prepared statement")
+	}
+	if _, ok := s.stmts[m.Name]; ok && m.Name != "" {
+		return newError("42P05", "prepared statement %q already exists", m.Name)
+	}
+
+	ps := &preparedStatement{}
+	if len(stmts) == 1 {
+		ps.query = stmts[0]
+	}
+	var n int
+	ps.sql, n = rewritePlaceholders(ps.query)
+	ps.paramOIDs = make([]uint32, max(n, len(m.ParameterOIDs)))
+	for i := range ps.paramOIDs {
+		if i < len(m.ParameterOIDs) && m.ParameterOIDs[i] != 0 {
+			ps.paramOIDs[i] = m.ParameterOIDs[i]
+		} else {
+			ps.paramOIDs[i] = 0
+		}
+	}
+	s.stmts[m.Name] = ps
+	s.be.Send(&pgproto3.ParseComplete{})
+	return nil
+}
+
+func (s *session) handleBind(m *pgproto3.Bind) error {
+	ps, ok := s.stmts[m.PreparedStatement]
+	if !ok {
+		return newError("26000", "prepared statement %q does not exist", m.PreparedStatement)
+	}
+	if len(m.Parameters) != len(ps.paramOIDs) {
+		return newError("08P01", "bind message supplies %d parameters, but prepared statement requires %d",
+			len(m.Parameters), len(ps.paramOIDs))
+	}
+
+	params := make([]*proto.Parameter, len(m.Parameters))
+	for i, b := range m.Parameters {
+		p, err := decodeParameter(b, ps.paramOIDs[i], formatCode(m.ParameterFormatCodes, i))
+		if err != nil {
+			return newError("22P02", "%s", err.Error())
+		}
+		params[i] = p
+	}
+	s.portals[m.DestinationPortal] = &portal{
+		stmt:    ps,
+		params:  params,
+		formats: m.ResultFormatCodes,
+	}
+	s.be.Send(&pgproto3.BindComplete{})
+	return nil
+}
+
+func (s *session) handleDescribe(m *pgproto3.Describe) error {
+	if m.ObjectType == 'S' {
+		ps, ok := s.stmts[m.Name]
+		if !ok {
+			return newError("26000", "prepared statement %q does not exist", m.Name)
+		}
+		if err := s.describe(ps); err != nil {
+			return err
+		}
+		s.be.Send(&pgproto3.ParameterDescription{ParameterOIDs: ps.paramOIDs})
+		s.sendRowDescription(ps.fields, nil)
+		return nil
+	}
+
+	p, ok := s.portals[m.Name]
+	if !ok {
+		return newError("34000", "portal %q does not exist", m.Name)
+	}
+	if p.stmt.described {
+		s.sendRowDescription(p.stmt.fields, p.formats)
+		return nil
+	}
+	if !s.returnsRows(p.stmt.query) {
+		s.be.Send(&pgproto3.NoData{})
+		return nil
+	}
+	// The only way to know the columns is to execute the portal.
+	if err := s.executePortal(p); err != nil {
+		return err
+	}
+	s.sendRowDescription(p.res.fields, p.formats)
+	return nil
+}
+
+func (s *session) handleExecute(m *pgproto3.Execute) error {
+	p, ok := s.portals[m.Portal]
+	if !ok {
+		return newError("34000", "portal %q does not exist", m.Portal)
+	}
+	if p.stmt.query == "" {
+		s.be.Send(&pgproto3.EmptyQueryResponse{})
+		return nil
+	}
+	if p.res == nil {
+		if err := s.executePortal(p); err != nil {
+			return err
+		}
+	}
+
+	if p.res.fields != nil {
+		values := p.res.rows.GetValues()[p.pos:]
+		if m.MaxRows > 0 && len(values) > int(m.MaxRows) {
+			values = values[:m.MaxRows]
+		}
+		if err := s.sendRows(withFormats(p.res.fields, p.formats), values); err != nil {
+			return err
+		}
+		p.pos += len(values)
+		if p.pos < len(p.res.rows.GetValues()) {
+			s.be.Send(&pgproto3.PortalSuspended{})
+			return nil
+		}
+	}
+	s.be.Send(&pgproto3.CommandComplete{CommandTag: []byte(p.res.tag)})
+	return nil
+}
+
+// executePortal executes the statement of the portal, and stores the result.
+// If the statement was described the columns sent to the client are the
+// described columns, regardless of the result.
+func (s *session) executePortal(p *portal) error {
+	res, err := s.execute(p.stmt.query, p.stmt.sql, p.params)
+	if err != nil {
+		return err
+	}
+	if p.stmt.described && res.fields != nil {
+		if len(p.stmt.fields) != len(res.fields) {
+			return newError("XX000", "statement returned %d columns, but %d were described",
+				len(res.fields), len(p.stmt.fields))
+		}
+		res.fields = p.stmt.fields
+	}
+	p.res = res
+	return nil
+}
+
+// describe determines the columns returned by the prepared statement,
+// without executing it.
+func (s *session) describe(ps *preparedStatement) error {
+	if ps.described {
+		return nil
+	}
+	if err := s.inferParamTypes(ps); err != nil {
+		return err
+	}
+	if res, ok, err := s.intercept(ps.query, true); err != nil {
+		return err
+	} else if ok {
+		ps.fields = res.fields
+		ps.described = true
+		return nil
+	}
+
+	var probe string
+	switch firstKeyword(ps.query) {
+	case "SELECT", "VALUES", "WITH":
+		probe = "SELECT * FROM (" + ps.sql + ") LIMIT 0"
+	case "PRAGMA", "EXPLAIN":
+		if !isReadOnly(ps.query) {
+			ps.described = true
+			return nil
+		}
+		probe = ps.sql
+	default:
+		probe = returningProbe(ps.sql)
+		if probe == "" {
+			ps.described = true
+			return nil
+		}
+	}
+
+	nulls := make([]*proto.Parameter, len(ps.paramOIDs))
+	for i := range nulls {
+		nulls[i] = &proto.Parameter{}
+	}
+	rows, err := s.query(probe, nulls)
+	if err != nil {
+		return err
+	}
+	ps.fields = fieldDescriptions(rows)
+	if hints := resultOIDs(ps.sql); len(hints) == len(ps.fields) {
+		for i, oid := range hints {
+			if oid != 0 && i < len(rows.Types) && oidOfDeclType(rows.Types[i]) == 0 {
+				ps.fields[i] = newField(rows.Columns[i], oid)
+			}
+		}
+	}
+	ps.described = true
+	return nil
+}
+
+// inferParamTypes sets the type of each parameter of ps not specified by the
+// client, where it can be inferred from the declared type of the column the
+// parameter is compared with or assigned to. Parameters of unknown type are
+// left as 0, which leaves the choice of type to the client.
+func (s *session) inferParamTypes(ps *preparedStatement) error {
+	if !slices.Contains(ps.paramOIDs, 0) {
+		return nil
+	}
+	uses := findParamUses(ps.sql)
+	if uses == nil {
+		return nil
+	}
+
+	declTypes := make(map[string]string)
+	if len(uses.columns) > 0 {
+		for _, t := range uses.tables {
+			rows, err := s.query("SELECT name, type FROM pragma_table_info(?)",
+				[]*proto.Parameter{{Value: &proto.Parameter_S{S: t}}})
+			if err != nil {
+				return err
+			}
+			for _, v := range rows.Values {
+				p := v.GetParameters()
+				if len(p) != 2 {
+					continue
+				}
+				name, typ := strings.ToLower(p[0].GetS()), p[1].GetS()
+				if prev, ok := declTypes[name]; ok && oidOfDeclType(prev) != oidOfDeclType(typ) {
+					// Ambiguous column, so its type is unknown.
+					typ = ""
+				}
+				declTypes[name] = typ
+			}
+		}
+	}
+
+	for i := range ps.paramOIDs {
+		if ps.paramOIDs[i] != 0 {
+			continue
+		}
+		if oid, ok := uses.oids[i+1]; ok {
+			ps.paramOIDs[i] = oid
+		} else if col, ok := uses.columns[i+1]; ok {
+			ps.paramOIDs[i] = oidOfDeclType(declTypes[col])
+		}
+	}
+	return nil
+}
+
+// returnsRows returns whether executing the statement may return rows.
+func (s *session) returnsRows(stmt string) bool {
+	if res, ok, _ := s.intercept(stmt, true); ok {
+		return res.fields != nil
+	}
+	return mayReturnRows(stmt)
+}
+
+// execute executes a single statement. query is the statement as sent by
+// the client, and stmt is the statement to send to the database.
+func (s *session) execute(query, stmt string, params []*proto.Parameter) (*result, error) {
+	stats.Add(numStatements, 1)
+
+	if res, err, ok := s.transactionControl(query); ok {
+		return res, err
+	}
+	if s.txStatus == txFailed {
+		return nil, newError("25P02", "current transaction is aborted, commands ignored until end of transaction block")
+	}
+
+	res, ok, err := s.intercept(query, false)
+	if err != nil || ok {
+		s.failTx(err)
+		return res, err
+	}
+
+	readOnly := isReadOnly(query)
+	if err := s.checkPerm(readOnly); err != nil {
+		s.failTx(err)
+		return nil, err
+	}
+
+	st := &proto.Statement{Sql: stmt, Parameters: params}
+	if s.txStatus == txActive && !mayReturnRows(query) {
+		// Writes within a transaction are sent to the cluster together
+		// when the transaction is committed.
+		s.txStmts = append(s.txStmts, st)
+		return &result{tag: commandTag(query, 0)}, nil
+	}
+	if s.txStatus == txActive && hasReturning(query) {
+		err := newError("0A000", "RETURNING is not supported within a transaction")
+		s.failTx(err)
+		return nil, err
+	}
+
+	resps, err := s.request([]*proto.Statement{st}, false, readOnly)
+	if err != nil {
+		s.failTx(err)
+		return nil, err
+	}
+	res, err = toResult(query, resps)
+	s.failTx(err)
+	return res, err
+}
+
+// transactionControl handles transaction control statements. It returns
+// false if stmt is not such a statement.
+func (s *session) transactionControl(stmt string) (*result, error, bool) {
+	kws := keywords(stmt, 2)
+	if len(kws) == 0 {
+		return nil, nil, false
+	}
+	switch kws[0] {
+	case "BEGIN", "START":
+		if s.txStatus == txIdle {
+			s.txStatus = txActive
+			s.txStmts = nil
+		}
+		return &result{tag: "BEGIN"}, nil, true
+	case "COMMIT", "END":
+		if s.txStatus == txFailed {
+			s.endTx()
+			return &result{tag: "ROLLBACK"}, nil, true
+		}
+		stmts := s.txStmts
+		s.endTx()
+		if len(stmts) == 0 {
+			return &result{tag: "COMMIT"}, nil, true
+		}
+		stats.Add(numTransactions, 1)
+		resps, err := s.request(stmts, true, false)
+		if err == nil {
+			_, err = toResult("", resps)
+		}
+		if err != nil {
+			stats.Add(numTransactionsFail, 1)
+			return nil, err, true
+		}
+		return &result{tag: "COMMIT"}, nil, true
+	case "ROLLBACK", "ABORT":
+		if len(kws) > 1 && kws[1] == "TO" {
+			if s.txStatus == txIdle {
+				return nil, newError("25P01", "ROLLBACK TO SAVEPOINT can only be used in transaction blocks"), true
+			}
+			s.txStatus = txActive
+			s.txStmts = append(s.txStmts, &proto.Statement{Sql: stmt})
+			return &result{tag: "ROLLBACK"}, nil, true
+		}
+		s.endTx()
+		return &result{tag: "ROLLBACK"}, nil, true
+	case "SAVEPOINT", "RELEASE":
+		if s.txStatus == txIdle {
+			return nil, newError("25P01", "%s can only be used in transaction blocks", kws[0]), true
+		}
+		if s.txStatus == txActive {
+			s.txStmts = append(s.txStmts, &proto.Statement{Sql: stmt})
+		}
+		return &result{tag: kws[0]}, nil, true
+	}
+	return nil, nil, false
+}
+
+func (s *session) endTx() {
+	s.txStatus = txIdle
+	s.txStmts = nil
+}
+
+// failTx marks any transaction in progress as failed, if err is not nil.
+func (s *session) failTx(err error) {
+	if err != nil && s.txStatus == txActive {
+		s.txStatus = txFailed
+		s.txStmts = nil
+	}
+}
+
+// checkPerm checks the session user has permission to run a statement.
+func (s *session) checkPerm(readOnly bool) error {
+	if s.svc.credentialStore == nil {
+		return nil
+	}
+	perm := auth.PermExecute
+	if readOnly {
+		perm = auth.PermQuery
+	}
+	if !s.svc.credentialStore.AA(s.user, s.password, perm) {
+		return newError("42501", "permission denied: user %q does not have %s permission", s.user, perm)
+	}
+	return nil
+}
+
+// query runs a read-only statement, without consistency checks, and returns
+// the rows.
+func (s *session) query(stmt string, params []*proto.Parameter) (*proto.QueryRows, error) {
+	if err := s.checkPerm(true); err != nil {
+		return nil, err
+	}
+	level, freshness := s.level, s.freshness
+	s.level, s.freshness = proto.ConsistencyLevel_NONE, 0
+	resps, err := s.request([]*proto.Statement{{Sql: stmt, Parameters: params}}, false, true)
+	s.level, s.freshness = level, freshness
+	if err != nil {
+		return nil, err
+	}
+	res, err := toResult(stmt, resps)
+	if err != nil {
+		return nil, err
+	}
+	if res.rows == nil {
+		return &proto.QueryRows{}, nil
+	}
+	return res.rows, nil
+}
+
+// request sends the statements to the cluster, as a query if readOnly is
+// true, and otherwise for execution.
+func (s *session) request(stmts []*proto.Statement, tx, readOnly bool) ([]*proto.ExecuteQueryResponse, error) {
+	if err := sql.ProcessWithCatalog(stmts, true, true, s.svc.Catalog); err != nil {
+		return nil, newError("42601", "%s", err.Error())
+	}
+	if err := s.svc.Linter.Check(stmts, nil); err != nil {
+		var lintErr *sql.LintError
+		if errors.As(err, &lintErr) {
+			return nil, newError("0A000", "%s", err.Error())
+		}
+		return nil, newError("XX000", "SQL lint: %s", err.Error())
+	}
+	if err := s.svc.Authorizer.Check(s.user, s.password, stmts, nil); err != nil {
+		var aclErr *sql.ACLError
+		if errors.As(err, &aclErr) {
+			return nil, newError("42501", "%s", err.Error())
+		}
+		return nil, newError("XX000", "SQL authorization: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.svc.Timeout)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.cancel = nil
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	req := &proto.Request{
+		Transaction: tx,
+		Statements:  stmts,
+	}
+	var creds *clstrPB.Credentials
+	if s.svc.credentialStore != nil {
+		creds = &clstrPB.Credentials{Username: s.user, Password: s.password}
+	}
+	var resps []*proto.ExecuteQueryResponse
+	var err error
+	if readOnly {
+		qr := &proto.QueryRequest{
+			Request:         req,
+			Level:           s.level,
+			Freshness:       s.freshness.Nanoseconds(),
+			FreshnessStrict: s.freshnessStrict,
+		}
+		var rows []*proto.QueryRows
+		rows, _, _, err = s.svc.proxy.Query(ctx, qr, creds, s.svc.Timeout, s.svc.Retries, false)
+		for _, r := range rows {
+			resps = append(resps, &proto.ExecuteQueryResponse{Result: &proto.ExecuteQueryResponse_Q{Q: r}})
+		}
+	} else {
+		er := &proto.ExecuteRequest{Request: req}
+		resps, _, _, err = s.svc.proxy.Execute(ctx, er, creds, s.svc.Timeout, s.svc.Retries, false)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return nil, newError("57014", "canceling statement due to user request")
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, newError("57014", "canceling statement due to statement timeout")
+		case errors.Is(err, proxy.ErrLeaderNotFound):
+			return nil, newError("57P03", "%s", err.Error())
+		case errors.Is(err, proxy.ErrUnauthorized):
+			return nil, newError("28000", "remote request not authorized")
+		default:
+			return nil, newError("XX000", "%s", err.Error())
+		}
+	}
+	return resps, nil
+}
+
+// cancelRequest cancels the request to the cluster in progress, if any.
+func (s *session) cancelRequest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// toResult converts the responses to a request into the result of stmt. If
+// any response is an error, that error is returned.
+func toResult(stmt string, resps []*proto.ExecuteQueryResponse) (*result, error) {
+	res := &result{}
+	for _, r := range resps {
+		switch v := r.GetResult().(type) {
+		case *proto.ExecuteQueryResponse_Error:
+			return nil, sqliteError(v.Error)
+		case *proto.ExecuteQueryResponse_E:
+			if v.E.Error != "" {
+				return nil, sqliteError(v.E.Error)
+			}
+			res.tag = commandTag(stmt, v.E.RowsAffected)
+		case *proto.ExecuteQueryResponse_Q:
+			if v.Q.Error != "" {
+				return nil, sqliteError(v.Q.Error)
+			}
+			res.rows = v.Q
+			res.fields = fieldDescriptions(v.Q)
+			if hasReturning(stmt) {
+				res.tag = commandTag(stmt, int64(len(v.Q.Values)))
+			} else {
+				res.tag = "SELECT " + strconv.Itoa(len(v.Q.Values))
+			}
+		}
+	}
+	return res, nil
+}
+
+// sqliteError converts an error message from SQLite into an error with the
+// closest matching SQLSTATE code.
+func sqliteError(msg string) *pgError {
+	code := "XX000"
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"), strings.Contains(msg, "PRIMARY KEY constraint failed"):
+		code = "23505"
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		code = "23503"
+	case strings.Contains(msg, "NOT NULL constraint failed"):
+		code = "23502"
+	case strings.Contains(msg, "CHECK constraint failed"):
+		code = "23514"
+	case strings.Contains(msg, "no such table"):
+		code = "42P01"
+	case strings.Contains(msg, "no such column"):
+		code = "42703"
+	case strings.Contains(msg, "no such function"):
+		code = "42883"
+	case strings.Contains(msg, "already exists"):
+		code = "42P07"
+	case strings.Contains(msg, "syntax error"), strings.Contains(msg, "incomplete input"):
+		code = "42601"
+	case strings.Contains(msg, "attempt to write a readonly database"):
+		code = "25006"
+	}
+	return &pgError{severity: "ERROR", code: code, msg: msg}
+}
+
+// sendRows sends a DataRow message for each of values.
+func (s *session) sendRows(fields []pgproto3.FieldDescription, values []*proto.Values) error {
+	row := make([][]byte, len(fields))
+	for _, v := range values {
+		params := v.GetParameters()
+		for i := range row {
+			row[i] = nil
+			if i < len(params) {
+				b, err := encodeValue(fields[i], params[i])
+				if err != nil {
+					return newError("22P03", "column %q: %s", fields[i].Name, err.Error())
+				}
+				row[i] = b
+			}
+		}
+		s.be.Send(&pgproto3.DataRow{Values: row})
+	}
+	return nil
+}
+
+// sendRowDescription sends a RowDescription message for fields, or a NoData
+// message if fields is nil.
+func (s *session) sendRowDescription(fields []pgproto3.FieldDescription, formats []int16) {
+	if fields == nil {
+		s.be.Send(&pgproto3.NoData{})
+		return
+	}
+	s.be.Send(&pgproto3.RowDescription{Fields: withFormats(fields, formats)})
+}
+
+func (s *session) sendError(err error) {
+	stats.Add(numStatementErrors, 1)
+	pe, ok := err.(*pgError)
+	if !ok {
+		pe = newError("XX000", "%s", err.Error())
+	}
+	s.be.Send(&pgproto3.ErrorResponse{
+		Severity:            pe.severity,
+		SeverityUnlocalized: pe.severity,
+		Code:                pe.code,
+		Message:             pe.msg,
+	})
+}
@@ -0,0 +1,444 @@
+package pgwire
+
+import (
+	"strconv"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// scanner walks SQL text, tracking whether the current position is inside a
+// string literal, quoted identifier or comment, so that callers only act on
+// characters which are part of the SQL itself.
+type scanner struct {
+	s string
+	i int
+}
+
+// next advances past the token at the current position, and returns whether
+// that token was plain SQL (as opposed to a literal, identifier or comment).
+func (sc *scanner) next() (start int, plain bool) {
+	start = sc.i
+	c := sc.s[sc.i]
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		sc.skipQuoted(c, c)
+		return start, false
+	case c == '[':
+		sc.skipQuoted('[', ']')
+		return start, false
+	case c == '-' && strings.HasPrefix(sc.s[sc.i:], "--"):
+		if n := strings.IndexByte(sc.s[sc.i:], '\n'); n >= 0 {
+			sc.i += n + 1
+		} else {
+			sc.i = len(sc.s)
+		}
+		return start, false
+	case c == '/' && strings.HasPrefix(sc.s[sc.i:], "/*"):
+		if n := strings.Index(sc.s[sc.i+2:], "*/"); n >= 0 {
+			sc.i += n + 4
+		} else {
+			sc.i = len(sc.s)
+		}
+		return start, false
+	}
+	sc.i++
+	return start, true
+}
+
+// skipQuoted advances past a quoted section which ends with close. A doubled
+// closing quote is an escaped quote, not the end of the section.
+func (sc *scanner) skipQuoted(open, close byte) {
+	sc.i++
+	for sc.i < len(sc.s) {
+		if sc.s[sc.i] == close {
+			if close == open && sc.i+1 < len(sc.s) && sc.s[sc.i+1] == close {
+				sc.i += 2
+				continue
+			}
+			sc.i++
+			return
+		}
+		sc.i++
+	}
+}
+
+func (sc *scanner) done() bool {
+	return sc.i >= len(sc.s)
+}
+
+// splitStatements splits the SQL text of a simple query into its statements.
+// Semicolons within the body of a CREATE TRIGGER statement do not end the
+// statement. Empty statements are dropped.
+func splitStatements(q string) []string {
+	var stmts []string
+	sc := &scanner{s: q}
+	begin := 0
+	for !sc.done() {
+		start, plain := sc.next()
+		if !plain || q[start] != ';' {
+			continue
+		}
+		stmt := q[begin:start]
+		if isCreateTrigger(stmt) && !endsWithEnd(stmt) {
+			continue
+		}
+		if strings.TrimSpace(stmt) != "" {
+			stmts = append(stmts, strings.TrimSpace(stmt))
+		}
+		begin = sc.i
+	}
+	if stmt := strings.TrimSpace(q[begin:]); stmt != "" && !isComment(stmt) {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+// isCreateTrigger returns whether stmt is a CREATE TRIGGER statement.
+func isCreateTrigger(stmt string) bool {
+	kws := keywords(stmt, 3)
+	if len(kws) < 2 || kws[0] != "CREATE" {
+		return false
+	}
+	return kws[1] == "TRIGGER" || (len(kws) > 2 && kws[2] == "TRIGGER")
+}
+
+// endsWithEnd returns whether the last keyword of stmt is END.
+func endsWithEnd(stmt string) bool {
+	s := strings.TrimRightFunc(stmt, isSpace)
+	return len(s) >= 3 && strings.EqualFold(s[len(s)-3:], "END") &&
+		(len(s) == 3 || !isIdentChar(s[len(s)-4]))
+}
+
+// isComment returns whether s consists only of comments and whitespace.
+func isComment(s string) bool {
+	sc := &scanner{s: s}
+	for !sc.done() {
+		start, plain := sc.next()
+		if plain && !isSpace(rune(s[start])) {
+			return false
+		}
+		if !plain && s[start] != '-' && s[start] != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// rewritePlaceholders rewrites the PostgreSQL-style $N placeholders in q to
+// the equivalent SQLite ?N placeholders, and returns the rewritten SQL along
+// with the highest placeholder number found.
+func rewritePlaceholders(q string) (string, int) {
+	var b strings.Builder
+	sc := &scanner{s: q}
+	n := 0
+	last := 0
+	for !sc.done() {
+		start, plain := sc.next()
+		if !plain || q[start] != '$' {
+			continue
+		}
+		if start > 0 && isIdentChar(q[start-1]) {
+			continue
+		}
+		end := start + 1
+		for end < len(q) && q[end] >= '0' && q[end] <= '9' {
+			end++
+		}
+		if end == start+1 {
+			continue
+		}
+		idx, err := strconv.Atoi(q[start+1 : end])
+		if err != nil {
+			continue
+		}
+		n = max(n, idx)
+		b.WriteString(q[last:start])
+		b.WriteByte('?')
+		b.WriteString(q[start+1 : end])
+		last = end
+		sc.i = end
+	}
+	if last == 0 {
+		return q, n
+	}
+	b.WriteString(q[last:])
+	return b.String(), n
+}
+
+// keywords returns up to n leading words of stmt, upper-cased, skipping any
+// leading comments.
+func keywords(stmt string, n int) []string {
+	var kws []string
+	sc := &scanner{s: stmt}
+	for !sc.done() && len(kws) < n {
+		start, plain := sc.next()
+		if !plain {
+			if stmt[start] == '-' || stmt[start] == '/' {
+				continue
+			}
+			return kws
+		}
+		c := stmt[start]
+		if isSpace(rune(c)) {
+			continue
+		}
+		if !isIdentChar(c) {
+			return kws
+		}
+		end := start + 1
+		for end < len(stmt) && isIdentChar(stmt[end]) {
+			end++
+		}
+		kws = append(kws, strings.ToUpper(stmt[start:end]))
+		sc.i = end
+	}
+	return kws
+}
+
+// firstKeyword returns the first word of stmt, upper-cased.
+func firstKeyword(stmt string) string {
+	if kws := keywords(stmt, 1); len(kws) > 0 {
+		return kws[0]
+	}
+	return ""
+}
+
+// isReadOnly returns whether stmt can be treated as read-only for the purposes
+// of authorization. It errs on the side of caution, since statements such as
+// WITH ... INSERT can modify the database.
+func isReadOnly(stmt string) bool {
+	switch firstKeyword(stmt) {
+	case "SELECT", "VALUES", "EXPLAIN":
+		return true
+	case "WITH":
+		parsed, err := rsql.NewParser(strings.NewReader(stmt)).ParseStatement()
+		if err != nil {
+			return false
+		}
+		_, ok := parsed.(*rsql.SelectStatement)
+		return ok
+	case "PRAGMA":
+		return isReadOnlyPragma(stmt)
+	default:
+		return false
+	}
+}
+
+// readOnlyPragmas are the PRAGMAs which only report a value when run without
+// an argument. PRAGMAs such as optimize or wal_checkpoint act even without an
+// argument, so are not listed.
+var readOnlyPragmas = map[string]bool{
+	"application_id":            true,
+	"auto_vacuum":               true,
+	"automatic_index":           true,
+	"busy_timeout":              true,
+	"cache_size":                true,
+	"cache_spill":               true,
+	"cell_size_check":           true,
+	"checkpoint_fullfsync":      true,
+	"collation_list":            true,
+	"compile_options":           true,
+	"data_version":              true,
+	"database_list":             true,
+	"defer_foreign_keys":        true,
+	"encoding":                  true,
+	"foreign_key_check":         true,
+	"foreign_keys":              true,
+	"freelist_count":            true,
+	"fullfsync":                 true,
+	"function_list":             true,
+	"ignore_check_constraints":  true,
+	"integrity_check":           true,
+	"journal_mode":              true,
+	"journal_size_limit":        true,
+	"legacy_alter_table":        true,
+	"locking_mode":              true,
+	"max_page_count":            true,
+	"mmap_size":                 true,
+	"module_list":               true,
+	"page_count":                true,
+	"page_size":                 true,
+	"pragma_list":               true,
+	"query_only":                true,
+	"quick_check":               true,
+	"read_uncommitted":          true,
+	"recursive_triggers":        true,
+	"reverse_unordered_selects": true,
+	"schema_version":            true,
+	"secure_delete":             true,
+	"synchronous":               true,
+	"table_list":                true,
+	"temp_store":                true,
+	"trusted_schema":            true,
+	"user_version":              true,
+	"wal_autocheckpoint":        true,
+}
+
+// readOnlyPragmaFuncs are the PRAGMAs whose argument names what to report on,
+// rather than setting a value.
+var readOnlyPragmaFuncs = map[string]bool{
+	"foreign_key_check": true,
+	"foreign_key_list":  true,
+	"index_info":        true,
+	"index_list":        true,
+	"index_xinfo":       true,
+	"integrity_check":   true,
+	"quick_check":       true,
+	"table_info":        true,
+	"table_list":        true,
+	"table_xinfo":       true,
+}
+
+// isReadOnlyPragma returns whether the PRAGMA statement stmt only reports
+// values, and so cannot modify the database.
+func isReadOnlyPragma(stmt string) bool {
+	name, rest := pragmaParts(stmt)
+	switch {
+	case rest == "" || isComment(rest):
+		return readOnlyPragmas[name]
+	case rest[0] == '(':
+		return readOnlyPragmaFuncs[name]
+	default:
+		return false
+	}
+}
+
+// pragmaParts returns the lower-cased name of the PRAGMA statement stmt,
+// without any schema, and the trimmed text which follows the name.
+func pragmaParts(stmt string) (name, rest string) {
+	sc := &scanner{s: stmt}
+	word := func() string {
+		for !sc.done() {
+			start, plain := sc.next()
+			if !plain && (stmt[start] == '-' || stmt[start] == '/') {
+				continue
+			}
+			if plain && isSpace(rune(stmt[start])) {
+				continue
+			}
+			if !plain || !isIdentChar(stmt[start]) {
+				sc.i = start
+				return ""
+			}
+			end := start + 1
+			for end < len(stmt) && isIdentChar(stmt[end]) {
+				end++
+			}
+			sc.i = end
+			return stmt[start:end]
+		}
+		return ""
+	}
+	if !strings.EqualFold(word(), "PRAGMA") {
+		return "", stmt
+	}
+	name = word()
+	if r := strings.TrimLeftFunc(stmt[sc.i:], isSpace); strings.HasPrefix(r, ".") {
+		sc.i = len(stmt) - len(r) + 1
+		name = word()
+	}
+	return strings.ToLower(name), strings.TrimSpace(stmt[sc.i:])
+}
+
+// mayReturnRows returns whether executing stmt may return rows.
+func mayReturnRows(stmt string) bool {
+	switch firstKeyword(stmt) {
+	case "SELECT", "VALUES", "WITH", "EXPLAIN", "PRAGMA":
+		return true
+	}
+	return hasReturning(stmt)
+}
+
+// hasReturning returns whether stmt has a RETURNING clause.
+func hasReturning(stmt string) bool {
+	return returningIndex(stmt) >= 0
+}
+
+// returningIndex returns the index of the RETURNING keyword in stmt, or -1
+// if stmt has no RETURNING clause.
+func returningIndex(stmt string) int {
+	sc := &scanner{s: stmt}
+	for !sc.done() {
+		start, plain := sc.next()
+		if !plain || (start > 0 && isIdentChar(stmt[start-1])) {
+			continue
+		}
+		if len(stmt)-start >= 9 && strings.EqualFold(stmt[start:start+9], "RETURNING") &&
+			(len(stmt) == start+9 || !isIdentChar(stmt[start+9])) {
+			return start
+		}
+	}
+	return -1
+}
+
+// returningProbe returns a query which selects no rows, but has the same
+// columns as those returned by the RETURNING clause of stmt. It returns the
+// empty string if stmt has no RETURNING clause, or no such query can be built.
+func returningProbe(stmt string) string {
+	i := returningIndex(stmt)
+	if i < 0 {
+		return ""
+	}
+	kws := keywords(stmt, 6)
+	var table string
+	for j := 0; j < len(kws)-1; j++ {
+		switch kws[j] {
+		case "INTO", "FROM":
+			table = kws[j+1]
+		case "UPDATE":
+			table = kws[j+1]
+			if table == "OR" && j+3 < len(kws) {
+				table = kws[j+3]
+			}
+		}
+		if table != "" {
+			break
+		}
+	}
+	if table == "" {
+		return ""
+	}
+	return "SELECT " + strings.TrimSpace(stmt[i+len("RETURNING"):]) + " FROM " + table + " LIMIT 0"
+}
+
+// commandTag returns the tag reported to the client on completion of stmt.
+func commandTag(stmt string, rowsAffected int64) string {
+	kws := keywords(stmt, 4)
+	if len(kws) == 0 {
+		return ""
+	}
+	switch kws[0] {
+	case "INSERT", "REPLACE":
+		return "INSERT 0 " + strconv.FormatInt(rowsAffected, 10)
+	case "UPDATE", "DELETE":
+		return kws[0] + " " + strconv.FormatInt(rowsAffected, 10)
+	case "CREATE", "DROP", "ALTER":
+		for _, kw := range kws[1:] {
+			switch kw {
+			case "UNIQUE", "TEMP", "TEMPORARY", "VIRTUAL":
+				continue
+			}
+			return kws[0] + " " + kw
+		}
+	case "WITH":
+		return "WITH"
+	}
+	return kws[0]
+}
+
+// normalize returns stmt lower-cased, with runs of whitespace collapsed and
+// any trailing semicolon removed, for matching against known statements.
+func normalize(stmt string) string {
+	stmt = strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+	return strings.ToLower(strings.Join(strings.Fields(stmt), " "))
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || c >= 0x80
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\v'
+}
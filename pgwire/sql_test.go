@@ -0,0 +1,188 @@
+package pgwire
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		exp  []string
+	}{
+		{
+			name: "empty",
+			q:    "",
+			exp:  nil,
+		},
+		{
+			name: "only semicolons and comments",
+			q:    " ; ; -- nothing here",
+			exp:  nil,
+		},
+		{
+			name: "single",
+			q:    "SELECT 1",
+			exp:  []string{"SELECT 1"},
+		},
+		{
+			name: "multiple",
+			q:    "SELECT 1; SELECT 2;",
+			exp:  []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name: "semicolons in literals and comments",
+			q:    `SELECT 'a;b', "c;d" /* ; */; -- x;y` + "\nSELECT 2",
+			exp:  []string{`SELECT 'a;b', "c;d" /* ; */`, "-- x;y\nSELECT 2"},
+		},
+		{
+			name: "escaped quote",
+			q:    `SELECT 'it''s; fine'; SELECT 2`,
+			exp:  []string{`SELECT 'it''s; fine'`, "SELECT 2"},
+		},
+		{
+			name: "trigger",
+			q: "CREATE TRIGGER t AFTER INSERT ON foo BEGIN UPDATE foo SET x=1; DELETE FROM bar; END; " +
+				"SELECT 1",
+			exp: []string{
+				"CREATE TRIGGER t AFTER INSERT ON foo BEGIN UPDATE foo SET x=1; DELETE FROM bar; END",
+				"SELECT 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.q); !reflect.DeepEqual(got, tt.exp) {
+				t.Fatalf("got %q, exp %q", got, tt.exp)
+			}
+		})
+	}
+}
+
+func Test_RewritePlaceholders(t *testing.T) {
+	tests := []struct {
+		q   string
+		exp string
+		n   int
+	}{
+		{"SELECT 1", "SELECT 1", 0},
+		{"SELECT * FROM foo WHERE id = $1", "SELECT * FROM foo WHERE id = ?1", 1},
+		{"INSERT INTO foo VALUES($1, $2, $10)", "INSERT INTO foo VALUES(?1, ?2, ?10)", 10},
+		{"SELECT '$1', \"$2\", $3 -- $4", "SELECT '$1', \"$2\", ?3 -- $4", 3},
+		{"SELECT a$1 FROM foo", "SELECT a$1 FROM foo", 0},
+		{"SELECT $ FROM foo", "SELECT $ FROM foo", 0},
+	}
+	for _, tt := range tests {
+		got, n := rewritePlaceholders(tt.q)
+		if got != tt.exp || n != tt.n {
+			t.Fatalf("rewritePlaceholders(%q) got %q, %d, exp %q, %d", tt.q, got, n, tt.exp, tt.n)
+		}
+	}
+}
+
+func Test_Classify(t *testing.T) {
+	tests := []struct {
+		stmt      string
+		readOnly  bool
+		rows      bool
+		returning bool
+	}{
+		{"SELECT * FROM foo", true, true, false},
+		{"  /* comment */ select 1", true, true, false},
+		{"VALUES(1)", true, true, false},
+		{"PRAGMA table_info(foo)", true, true, false},
+		{"PRAGMA main.table_info(foo)", true, true, false},
+		{"PRAGMA foreign_keys", true, true, false},
+		{"pragma foreign_keys -- comment", true, true, false},
+		{"PRAGMA foreign_keys=ON", false, true, false},
+		{"PRAGMA journal_mode(WAL)", false, true, false},
+		{"PRAGMA optimize", false, true, false},
+		{"PRAGMA incremental_vacuum", false, true, false},
+		{"PRAGMA wal_checkpoint(TRUNCATE)", false, true, false},
+		{"PRAGMA main.wal_checkpoint", false, true, false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true, true, false},
+		{"WITH x AS (SELECT 1) INSERT INTO foo SELECT * FROM x", false, true, false},
+		{"INSERT INTO foo VALUES(1)", false, false, false},
+		{"INSERT INTO foo VALUES(1) RETURNING id", false, true, true},
+		{"INSERT INTO foo VALUES('returning')", false, false, false},
+		{"UPDATE foo SET returning_x=1", false, false, false},
+		{"CREATE TABLE foo (id INTEGER)", false, false, false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnly(tt.stmt); got != tt.readOnly {
+			t.Fatalf("isReadOnly(%q) got %v", tt.stmt, got)
+		}
+		if got := mayReturnRows(tt.stmt); got != tt.rows {
+			t.Fatalf("mayReturnRows(%q) got %v", tt.stmt, got)
+		}
+		if got := hasReturning(tt.stmt); got != tt.returning {
+			t.Fatalf("hasReturning(%q) got %v", tt.stmt, got)
+		}
+	}
+}
+
+func Test_CommandTag(t *testing.T) {
+	tests := []struct {
+		stmt string
+		n    int64
+		exp  string
+	}{
+		{"INSERT INTO foo VALUES(1)", 1, "INSERT 0 1"},
+		{"REPLACE INTO foo VALUES(1)", 2, "INSERT 0 2"},
+		{"update foo SET x=1", 3, "UPDATE 3"},
+		{"DELETE FROM foo", 0, "DELETE 0"},
+		{"CREATE TABLE foo (id INTEGER)", 0, "CREATE TABLE"},
+		{"CREATE UNIQUE INDEX i ON foo(id)", 0, "CREATE INDEX"},
+		{"CREATE VIRTUAL TABLE v USING fts5(x)", 0, "CREATE TABLE"},
+		{"DROP VIEW v", 0, "DROP VIEW"},
+		{"VACUUM", 0, "VACUUM"},
+	}
+	for _, tt := range tests {
+		if got := commandTag(tt.stmt, tt.n); got != tt.exp {
+			t.Fatalf("commandTag(%q) got %q, exp %q", tt.stmt, got, tt.exp)
+		}
+	}
+}
+
+func Test_ReturningProbe(t *testing.T) {
+	tests := []struct {
+		stmt string
+		exp  string
+	}{
+		{"INSERT INTO foo(name) VALUES(?1)", ""},
+		{"INSERT INTO foo(name) VALUES(?1) RETURNING id, name", "SELECT id, name FROM FOO LIMIT 0"},
+		{"INSERT OR REPLACE INTO foo VALUES(1) RETURNING *", "SELECT * FROM FOO LIMIT 0"},
+		{"UPDATE foo SET x=1 RETURNING id", "SELECT id FROM FOO LIMIT 0"},
+		{"UPDATE OR IGNORE foo SET x=1 RETURNING id", "SELECT id FROM FOO LIMIT 0"},
+		{"DELETE FROM foo RETURNING id", "SELECT id FROM FOO LIMIT 0"},
+	}
+	for _, tt := range tests {
+		if got := returningProbe(tt.stmt); got != tt.exp {
+			t.Fatalf("returningProbe(%q) got %q, exp %q", tt.stmt, got, tt.exp)
+		}
+	}
+}
+
+func Test_ParseSet(t *testing.T) {
+	tests := []struct {
+		stmt  string
+		name  string
+		value string
+		ok    bool
+	}{
+		{"SET rqlite.consistency = strong", "rqlite.consistency", "strong", true},
+		{"SET SESSION rqlite.consistency TO 'none'", "rqlite.consistency", "none", true},
+		{"set application_name = 'it''s'", "application_name", "it's", true},
+		{"SET TIME ZONE 'UTC'", "timezone", "UTC", true},
+		{"SET NAMES 'UTF8';", "client_encoding", "UTF8", true},
+		{"SET foo", "", "", false},
+		{"SET foo =", "", "", false},
+	}
+	for _, tt := range tests {
+		name, value, ok := parseSet(tt.stmt)
+		if name != tt.name || value != tt.value || ok != tt.ok {
+			t.Fatalf("parseSet(%q) got %q, %q, %v", tt.stmt, name, value, ok)
+		}
+	}
+}
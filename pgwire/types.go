@@ -0,0 +1,562 @@
+package pgwire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/rqlite/rqlite/v10/command/proto"
+	rsql "github.com/rqlite/sql"
+)
+
+// PostgreSQL type OIDs used by this package.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidJSON        = 114
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidUnknown     = 705
+	oidBpchar      = 1042
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTimestamp   = 1114
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+	oidJSONB       = 3802
+)
+
+// Format codes for parameters and results.
+const (
+	formatText   = 0
+	formatBinary = 1
+)
+
+// oidOfDeclType returns the type OID implied by the given SQLite declared
+// column type, or 0 if the declared type does not imply one.
+func oidOfDeclType(declType string) uint32 {
+	t := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(t, "BOOL"):
+		return oidBool
+	case strings.Contains(t, "INT"):
+		return oidInt8
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return oidText
+	case strings.Contains(t, "BLOB"):
+		return oidBytea
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return oidFloat8
+	default:
+		return 0
+	}
+}
+
+// oidOfValue returns the type OID of the given value, or 0 for NULL.
+func oidOfValue(p *proto.Parameter) uint32 {
+	switch p.GetValue().(type) {
+	case *proto.Parameter_B:
+		return oidBool
+	case *proto.Parameter_I:
+		return oidInt8
+	case *proto.Parameter_D:
+		return oidFloat8
+	case *proto.Parameter_Y:
+		return oidBytea
+	case *proto.Parameter_S:
+		return oidText
+	default:
+		return 0
+	}
+}
+
+// fits returns whether the value p can be sent as a value of type oid.
+func fits(oid uint32, p *proto.Parameter) bool {
+	switch p.GetValue().(type) {
+	case nil:
+		return true
+	case *proto.Parameter_B:
+		return oid == oidBool || oid == oidInt8 || oid == oidText
+	case *proto.Parameter_I:
+		return oid == oidInt8 || oid == oidFloat8 || oid == oidText
+	case *proto.Parameter_D:
+		return oid == oidFloat8 || oid == oidText
+	case *proto.Parameter_Y:
+		return oid == oidBytea || oid == oidText
+	default:
+		return oid == oidText
+	}
+}
+
+// columnOID returns the type OID for column i of rows. The declared type of
+// the column is used if all its values fit that type, otherwise the type is
+// inferred from the values themselves.
+func columnOID(rows *proto.QueryRows, i int) uint32 {
+	var declType string
+	if i < len(rows.Types) {
+		declType = rows.Types[i]
+	}
+	if oid := oidOfDeclType(declType); oid != 0 {
+		ok := true
+		for _, v := range rows.Values {
+			if p := v.GetParameters(); i < len(p) && !fits(oid, p[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return oid
+		}
+	}
+
+	var oid uint32
+	for _, v := range rows.Values {
+		p := v.GetParameters()
+		if i >= len(p) {
+			continue
+		}
+		vo := oidOfValue(p[i])
+		switch {
+		case vo == 0 || vo == oid:
+		case oid == 0:
+			oid = vo
+		case (oid == oidInt8 && vo == oidFloat8) || (oid == oidFloat8 && vo == oidInt8):
+			oid = oidFloat8
+		case (oid == oidBool && vo == oidInt8) || (oid == oidInt8 && vo == oidBool):
+			oid = oidInt8
+		default:
+			return oidText
+		}
+	}
+	if oid == 0 {
+		return oidText
+	}
+	return oid
+}
+
+// resultOIDs returns the type OID of each column of the SELECT statement
+// stmt, as far as it can be determined from the statement alone. This is
+// used for columns which are expressions, and so have no declared type. An
+// element is 0 if the type is not known, and nil is returned if the columns
+// can't be determined at all.
+func resultOIDs(stmt string) []uint32 {
+	parsed, err := rsql.NewParser(strings.NewReader(stmt)).ParseStatement()
+	if err != nil {
+		return nil
+	}
+	sel, ok := parsed.(*rsql.SelectStatement)
+	if !ok || len(sel.ValueLists) > 0 {
+		return nil
+	}
+	oids := make([]uint32, len(sel.Columns))
+	for i, c := range sel.Columns {
+		if c.Star.IsValid() {
+			return nil
+		}
+		if ref, ok := c.Expr.(*rsql.QualifiedRef); ok && ref.Star.IsValid() {
+			return nil
+		}
+		oids[i] = exprOID(c.Expr)
+	}
+	return oids
+}
+
+// paramUses describes how the parameters of a statement are used, so that
+// their types can be inferred.
+type paramUses struct {
+	tables  []string       // Tables referenced by the statement.
+	columns map[int]string // Column each parameter is compared with or assigned to.
+	oids    map[int]uint32 // Parameters whose type is implied by the statement alone.
+}
+
+// findParamUses parses stmt, which uses SQLite ?N placeholders, and returns
+// how its parameters are used. It returns nil if stmt can't be parsed.
+func findParamUses(stmt string) *paramUses {
+	parsed, err := rsql.NewParser(strings.NewReader(stmt)).ParseStatement()
+	if err != nil {
+		return nil
+	}
+	u := &paramUses{
+		columns: make(map[int]string),
+		oids:    make(map[int]uint32),
+	}
+	if _, err := rsql.Walk(u, parsed); err != nil {
+		return nil
+	}
+	return u
+}
+
+// Visit implements rsql.Visitor.
+func (u *paramUses) Visit(n rsql.Node) (rsql.Visitor, rsql.Node, error) {
+	switch n := n.(type) {
+	case *rsql.QualifiedTableName:
+		u.tables = append(u.tables, n.Name.Name)
+	case *rsql.InsertStatement:
+		u.tables = append(u.tables, n.Table.Name)
+		for _, vl := range n.ValueLists {
+			for i, e := range vl.Exprs {
+				if i < len(n.Columns) {
+					u.bind(e, n.Columns[i].Name)
+				}
+			}
+		}
+	case *rsql.Assignment:
+		if len(n.Columns) == 1 {
+			u.bind(n.Expr, n.Columns[0].Name)
+		}
+	case *rsql.BinaryExpr:
+		if col := columnName(n.X); col != "" {
+			u.bind(n.Y, col)
+		} else if col := columnName(n.Y); col != "" {
+			u.bind(n.X, col)
+		}
+	case *rsql.SelectStatement:
+		u.bindOID(n.LimitExpr, oidInt8)
+		u.bindOID(n.OffsetExpr, oidInt8)
+	case *rsql.DeleteStatement:
+		u.bindOID(n.LimitExpr, oidInt8)
+		u.bindOID(n.OffsetExpr, oidInt8)
+	}
+	return u, n, nil
+}
+
+// VisitEnd implements rsql.Visitor.
+func (u *paramUses) VisitEnd(n rsql.Node) (rsql.Node, error) {
+	return n, nil
+}
+
+func (u *paramUses) bind(e rsql.Expr, column string) {
+	if i := paramIndex(e); i > 0 {
+		u.columns[i] = strings.ToLower(column)
+	}
+}
+
+func (u *paramUses) bindOID(e rsql.Expr, oid uint32) {
+	if i := paramIndex(e); i > 0 {
+		u.oids[i] = oid
+	}
+}
+
+// paramIndex returns the number of the ?N parameter e, or 0 if e is not a
+// numbered parameter.
+func paramIndex(e rsql.Expr) int {
+	b, ok := e.(*rsql.BindExpr)
+	if !ok || !strings.HasPrefix(b.Name, "?") {
+		return 0
+	}
+	i, err := strconv.Atoi(b.Name[1:])
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// columnName returns the name of the column referenced by e, or the empty
+// string if e is not a column reference.
+func columnName(e rsql.Expr) string {
+	switch e := e.(type) {
+	case *rsql.Ident:
+		return e.Name
+	case *rsql.QualifiedRef:
+		if e.Column != nil {
+			return e.Column.Name
+		}
+	}
+	return ""
+}
+
+// exprOID returns the type OID of the result of expr, or 0 if not known.
+func exprOID(expr rsql.Expr) uint32 {
+	switch e := expr.(type) {
+	case *rsql.ParenExpr:
+		return exprOID(e.X)
+	case *rsql.NumberLit:
+		if strings.ContainsAny(e.Value, ".eE") && !strings.HasPrefix(strings.ToLower(e.Value), "0x") {
+			return oidFloat8
+		}
+		return oidInt8
+	case *rsql.StringLit:
+		return oidText
+	case *rsql.CastExpr:
+		return oidOfDeclType(e.Type.Name.Name)
+	case *rsql.Call:
+		switch strings.ToLower(e.Name.Name) {
+		case "count", "length", "octet_length", "instr", "unicode", "changes", "total_changes",
+			"last_insert_rowid", "random", "sign", "row_number", "rank", "dense_rank", "ntile":
+			return oidInt8
+		case "avg", "total", "round", "julianday", "percent_rank", "cume_dist":
+			return oidFloat8
+		case "lower", "upper", "trim", "ltrim", "rtrim", "substr", "substring", "replace", "hex",
+			"quote", "typeof", "printf", "format", "group_concat", "string_agg", "char",
+			"date", "time", "datetime", "strftime", "sqlite_version":
+			return oidText
+		case "randomblob", "zeroblob", "unhex":
+			return oidBytea
+		}
+	}
+	return 0
+}
+
+// fieldDescriptions returns the descriptions of the columns of rows.
+func fieldDescriptions(rows *proto.QueryRows) []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(rows.Columns))
+	for i, c := range rows.Columns {
+		fields[i] = newField(c, columnOID(rows, i))
+	}
+	return fields
+}
+
+// textFields returns descriptions of the given columns, all of type text.
+func textFields(names ...string) []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(names))
+	for i, n := range names {
+		fields[i] = newField(n, oidText)
+	}
+	return fields
+}
+
+func newField(name string, oid uint32) pgproto3.FieldDescription {
+	size := int16(-1)
+	switch oid {
+	case oidBool:
+		size = 1
+	case oidInt8, oidFloat8:
+		size = 8
+	}
+	return pgproto3.FieldDescription{
+		Name:         []byte(name),
+		DataTypeOID:  oid,
+		DataTypeSize: size,
+		TypeModifier: -1,
+		Format:       formatText,
+	}
+}
+
+// withFormats returns a copy of fields with the format of each set as
+// requested by the given result format codes. No codes means all text, one
+// code applies to every field, otherwise there is one code per field.
+func withFormats(fields []pgproto3.FieldDescription, codes []int16) []pgproto3.FieldDescription {
+	out := make([]pgproto3.FieldDescription, len(fields))
+	copy(out, fields)
+	for i := range out {
+		out[i].Format = formatCode(codes, i)
+	}
+	return out
+}
+
+func formatCode(codes []int16, i int) int16 {
+	switch {
+	case len(codes) == 0:
+		return formatText
+	case len(codes) == 1:
+		return codes[0]
+	case i < len(codes):
+		return codes[i]
+	default:
+		return formatText
+	}
+}
+
+// encodeValue encodes p as a value of the given field. A nil return means NULL.
+func encodeValue(f pgproto3.FieldDescription, p *proto.Parameter) ([]byte, error) {
+	if p.GetValue() == nil {
+		return nil, nil
+	}
+	if f.Format == formatBinary {
+		return encodeBinary(f.DataTypeOID, p)
+	}
+	return encodeText(f.DataTypeOID, p), nil
+}
+
+// encodeText returns the text format encoding of p, as a value of type oid.
+func encodeText(oid uint32, p *proto.Parameter) []byte {
+	switch v := p.GetValue().(type) {
+	case *proto.Parameter_I:
+		if oid == oidBool {
+			return []byte(formatBool(v.I != 0))
+		}
+		return strconv.AppendInt(nil, v.I, 10)
+	case *proto.Parameter_D:
+		return strconv.AppendFloat(nil, v.D, 'g', -1, 64)
+	case *proto.Parameter_B:
+		if oid == oidBool {
+			return []byte(formatBool(v.B))
+		}
+		if v.B {
+			return []byte("1")
+		}
+		return []byte("0")
+	case *proto.Parameter_Y:
+		b := make([]byte, 2+hex.EncodedLen(len(v.Y)))
+		copy(b, `\x`)
+		hex.Encode(b[2:], v.Y)
+		return b
+	case *proto.Parameter_S:
+		return []byte(v.S)
+	default:
+		return nil
+	}
+}
+
+// encodeBinary returns the binary format encoding of p, as a value of type oid.
+func encodeBinary(oid uint32, p *proto.Parameter) ([]byte, error) {
+	switch oid {
+	case oidInt8:
+		var i int64
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_I:
+			i = v.I
+		case *proto.Parameter_B:
+			if v.B {
+				i = 1
+			}
+		default:
+			return nil, fmt.Errorf("cannot encode %T as int8", v)
+		}
+		return binary.BigEndian.AppendUint64(nil, uint64(i)), nil
+	case oidFloat8:
+		var f float64
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_D:
+			f = v.D
+		case *proto.Parameter_I:
+			f = float64(v.I)
+		default:
+			return nil, fmt.Errorf("cannot encode %T as float8", v)
+		}
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+	case oidBool:
+		var b bool
+		switch v := p.GetValue().(type) {
+		case *proto.Parameter_B:
+			b = v.B
+		case *proto.Parameter_I:
+			b = v.I != 0
+		default:
+			return nil, fmt.Errorf("cannot encode %T as bool", v)
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case oidBytea:
+		if v, ok := p.GetValue().(*proto.Parameter_Y); ok {
+			return v.Y, nil
+		}
+		return nil, fmt.Errorf("cannot encode %T as bytea", p.GetValue())
+	default:
+		// The binary format of text is the same as the text format.
+		return encodeText(oid, p), nil
+	}
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "t"
+	}
+	return "f"
+}
+
+// decodeParameter converts a bound parameter value, of the given type and
+// format, into a parameter for rqlite. A nil value is NULL.
+func decodeParameter(b []byte, oid uint32, format int16) (*proto.Parameter, error) {
+	if b == nil {
+		return &proto.Parameter{}, nil
+	}
+	if format == formatBinary {
+		return decodeBinary(b, oid)
+	}
+
+	s := string(b)
+	switch oid {
+	case oidInt2, oidInt4, oidInt8:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer parameter %q", s)
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: i}}, nil
+	case oidFloat4, oidFloat8, oidNumeric:
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil && oid == oidNumeric {
+			return &proto.Parameter{Value: &proto.Parameter_I{I: i}}, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid numeric parameter %q", s)
+		}
+		return &proto.Parameter{Value: &proto.Parameter_D{D: f}}, nil
+	case oidBool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return &proto.Parameter{Value: &proto.Parameter_I{I: 1}}, nil
+		case "f", "false", "n", "no", "off", "0":
+			return &proto.Parameter{Value: &proto.Parameter_I{I: 0}}, nil
+		}
+		return nil, fmt.Errorf("invalid boolean parameter %q", s)
+	case oidBytea:
+		if strings.HasPrefix(s, `\x`) {
+			y, err := hex.DecodeString(s[2:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid bytea parameter: %s", err)
+			}
+			return &proto.Parameter{Value: &proto.Parameter_Y{Y: y}}, nil
+		}
+		return &proto.Parameter{Value: &proto.Parameter_Y{Y: b}}, nil
+	default:
+		return &proto.Parameter{Value: &proto.Parameter_S{S: s}}, nil
+	}
+}
+
+// decodeBinary converts a binary format parameter value of the given type.
+func decodeBinary(b []byte, oid uint32) (*proto.Parameter, error) {
+	switch oid {
+	case oidInt2:
+		if len(b) != 2 {
+			return nil, fmt.Errorf("invalid int2 parameter length %d", len(b))
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: int64(int16(binary.BigEndian.Uint16(b)))}}, nil
+	case oidInt4:
+		if len(b) != 4 {
+			return nil, fmt.Errorf("invalid int4 parameter length %d", len(b))
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: int64(int32(binary.BigEndian.Uint32(b)))}}, nil
+	case oidInt8:
+		if len(b) != 8 {
+			return nil, fmt.Errorf("invalid int8 parameter length %d", len(b))
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: int64(binary.BigEndian.Uint64(b))}}, nil
+	case oidFloat4:
+		if len(b) != 4 {
+			return nil, fmt.Errorf("invalid float4 parameter length %d", len(b))
+		}
+		return &proto.Parameter{Value: &proto.Parameter_D{D: float64(math.Float32frombits(binary.BigEndian.Uint32(b)))}}, nil
+	case oidFloat8:
+		if len(b) != 8 {
+			return nil, fmt.Errorf("invalid float8 parameter length %d", len(b))
+		}
+		return &proto.Parameter{Value: &proto.Parameter_D{D: math.Float64frombits(binary.BigEndian.Uint64(b))}}, nil
+	case oidBool:
+		if len(b) != 1 {
+			return nil, fmt.Errorf("invalid bool parameter length %d", len(b))
+		}
+		var i int64
+		if b[0] != 0 {
+			i = 1
+		}
+		return &proto.Parameter{Value: &proto.Parameter_I{I: i}}, nil
+	case oidBytea:
+		return &proto.Parameter{Value: &proto.Parameter_Y{Y: b}}, nil
+	case oidText, oidVarchar, oidBpchar, oidUnknown, oidJSON, 0:
+		return &proto.Parameter{Value: &proto.Parameter_S{S: string(b)}}, nil
+	default:
+		return nil, fmt.Errorf("binary format not supported for parameters of type %d", oid)
+	}
+}
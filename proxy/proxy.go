@@ -5,6 +5,7 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"expvar"
@@ -13,8 +14,13 @@ import (
 	"time"
 
 	clstrPB "github.com/rqlite/rqlite/v10/cluster/proto"
+	"github.com/rqlite/rqlite/v10/command"
+	"github.com/rqlite/rqlite/v10/command/chunking"
 	"github.com/rqlite/rqlite/v10/command/proto"
+	"github.com/rqlite/rqlite/v10/internal/tracing"
 	"github.com/rqlite/rqlite/v10/store"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -34,11 +40,16 @@ var (
 // stats captures stats for the HTTP service.
 var stats *expvar.Map
 
+// tracer traces the forwarding of requests to the leader.
+var tracer = tracing.Tracer("proxy")
+
 const (
 	numRemoteRemoveNode       = "remote_remove_node"
 	numRemoteBackups          = "remote_backups"
 	numRemoteLoads            = "remote_loads"
+	numRemoteImports          = "remote_imports"
 	numRemoteStepdowns        = "remote_stepdowns"
+	numRemoteChecksums        = "remote_checksums"
 	numRemoteExecutions       = "remote_executions"
 	numRemoteExecutionsFailed = "remote_executions_failed"
 	numRemoteQueries          = "remote_queries"
@@ -58,7 +69,9 @@ func ResetStats() {
 	stats.Add(numRemoteRemoveNode, 0)
 	stats.Add(numRemoteBackups, 0)
 	stats.Add(numRemoteLoads, 0)
+	stats.Add(numRemoteImports, 0)
 	stats.Add(numRemoteStepdowns, 0)
+	stats.Add(numRemoteChecksums, 0)
 	stats.Add(numRemoteExecutions, 0)
 	stats.Add(numRemoteExecutionsFailed, 0)
 	stats.Add(numRemoteQueries, 0)
@@ -73,9 +86,11 @@ type Store interface {
 	Query(ctx context.Context, qr *proto.QueryRequest) ([]*proto.QueryRows, proto.ConsistencyLevel, uint64, error)
 	Request(ctx context.Context, eqr *proto.ExecuteQueryRequest) ([]*proto.ExecuteQueryResponse, uint64, uint64, error)
 	Load(ctx context.Context, lr *proto.LoadRequest) error
+	LoadChunk(ctx context.Context, lcr *proto.LoadChunkRequest) error
 	Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writer) error
 	Remove(ctx context.Context, rn *proto.RemoveNodeRequest) error
 	Stepdown(wait bool, id string) error
+	Checksum(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error)
 	LeaderAddr() (string, error)
 }
 
@@ -86,8 +101,10 @@ type Cluster interface {
 	Request(ctx context.Context, eqr *proto.ExecuteQueryRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) ([]*proto.ExecuteQueryResponse, uint64, uint64, error)
 	Backup(ctx context.Context, br *proto.BackupRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, w io.Writer) error
 	Load(ctx context.Context, lr *proto.LoadRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error
+	LoadChunk(ctx context.Context, lcr *proto.LoadChunkRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error
 	RemoveNode(ctx context.Context, rn *proto.RemoveNodeRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) error
 	Stepdown(ctx context.Context, sr *proto.StepdownRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) error
+	Checksum(ctx context.Context, cr *proto.ChecksumRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) (*proto.ChecksumResult, error)
 }
 
 // Proxy handles try-local-then-forward-to-leader logic.
@@ -136,10 +153,12 @@ func (p *Proxy) Execute(ctx context.Context, er *proto.ExecuteRequest, creds *cl
 		if addrErr != nil {
 			return nil, 0, "", addrErr
 		}
-		results, raftIndex, err = p.cluster.Execute(ctx, er, addr, creds, timeout, retries)
+		fctx, span := startForward(ctx, "Execute", addr)
+		results, raftIndex, err = p.cluster.Execute(fctx, er, addr, creds, timeout, retries)
+		tracing.End(span, err)
 		if err != nil {
 			stats.Add(numRemoteExecutionsFailed, 1)
-			return nil, 0, "", wrapIfUnauthorized(err)
+			return nil, 0, "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteExecutions, 1)
 		return results, raftIndex, addr, nil
@@ -162,10 +181,12 @@ func (p *Proxy) Query(ctx context.Context, qr *proto.QueryRequest, creds *clstrP
 		if addrErr != nil {
 			return nil, 0, "", addrErr
 		}
-		results, raftIndex, err = p.cluster.Query(ctx, qr, addr, creds, timeout, retries)
+		fctx, span := startForward(ctx, "Query", addr)
+		results, raftIndex, err = p.cluster.Query(fctx, qr, addr, creds, timeout, retries)
+		tracing.End(span, err)
 		if err != nil {
 			stats.Add(numRemoteQueriesFailed, 1)
-			return nil, 0, "", wrapIfUnauthorized(err)
+			return nil, 0, "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteQueries, 1)
 		return results, raftIndex, addr, nil
@@ -188,10 +209,12 @@ func (p *Proxy) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest, cre
 		if addrErr != nil {
 			return nil, 0, 0, "", addrErr
 		}
-		results, seq, raftIndex, err = p.cluster.Request(ctx, eqr, addr, creds, timeout, retries)
+		fctx, span := startForward(ctx, "Request", addr)
+		results, seq, raftIndex, err = p.cluster.Request(fctx, eqr, addr, creds, timeout, retries)
+		tracing.End(span, err)
 		if err != nil {
 			stats.Add(numRemoteRequestsFailed, 1)
-			return nil, 0, 0, "", wrapIfUnauthorized(err)
+			return nil, 0, 0, "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteRequests, 1)
 		return results, seq, raftIndex, addr, nil
@@ -216,7 +239,7 @@ func (p *Proxy) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 		}
 		err = p.cluster.Backup(ctx, br, addr, creds, timeout, dst)
 		if err != nil {
-			return "", wrapIfUnauthorized(err)
+			return "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteBackups, 1)
 		return addr, nil
@@ -241,7 +264,7 @@ func (p *Proxy) Load(ctx context.Context, lr *proto.LoadRequest, creds *clstrPB.
 		}
 		err = p.cluster.Load(ctx, lr, addr, creds, timeout, retries)
 		if err != nil {
-			return "", wrapIfUnauthorized(err)
+			return "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteLoads, 1)
 		return addr, nil
@@ -249,6 +272,84 @@ func (p *Proxy) Load(ctx context.Context, lr *proto.LoadRequest, creds *clstrPB.
 	return p.GetAPIAddr(), err
 }
 
+// LoadFrom loads the SQLite file read from r into the cluster, sending it in
+// chunks of at most chunkSize bytes, so the file is never held in memory. If
+// the local store returns ErrNotLeader for the first chunk and noForward is
+// false, every chunk is forwarded to the current leader. If any chunk fails,
+// the load is aborted.
+func (p *Proxy) LoadFrom(ctx context.Context, r io.Reader, chunkSize int64, creds *clstrPB.Credentials,
+	timeout time.Duration, retries int, noForward bool) (string, error) {
+	addr, remote, err := p.sendChunks(ctx, r, chunkSize, "", creds, timeout, retries, noForward)
+	if remote {
+		stats.Add(numRemoteLoads, 1)
+	}
+	return addr, err
+}
+
+// Import executes a batch of an import as a single transaction. The batch is
+// sent through the Raft log in chunks of at most chunkSize bytes, so it need
+// not fit in a single log entry. access is a statement, already authorized,
+// which accesses tables in every way the statements of the batch may. Chunks
+// are forwarded to the current leader as LoadFrom forwards them.
+func (p *Proxy) Import(ctx context.Context, access *proto.Statement, req *proto.Request, chunkSize int64,
+	creds *clstrPB.Credentials, timeout time.Duration, retries int, noForward bool) (string, error) {
+	b, err := command.MarshalImportBatch(req)
+	if err != nil {
+		return "", err
+	}
+	addr, remote, err := p.sendChunks(ctx, bytes.NewReader(b), chunkSize, access.Sql, creds, timeout, retries, noForward)
+	if remote {
+		stats.Add(numRemoteImports, 1)
+	}
+	return addr, err
+}
+
+// sendChunks sends the data read from r through the Raft log, using a
+// chunking.Chunker. If importAccess is set the chunks carry a batch of an
+// import, rather than a SQLite file. It also returns whether the chunks were
+// successfully forwarded to the leader.
+func (p *Proxy) sendChunks(ctx context.Context, r io.Reader, chunkSize int64, importAccess string,
+	creds *clstrPB.Credentials, timeout time.Duration, retries int, noForward bool) (string, bool, error) {
+
+	chunker := chunking.NewChunker(r, chunkSize)
+	addr := "" // Address of the leader, if chunks are forwarded.
+	send := func(lcr *proto.LoadChunkRequest) error {
+		lcr.ImportAccess = importAccess
+		if addr == "" {
+			return p.store.LoadChunk(ctx, lcr)
+		}
+		return wrapRemoteError(p.cluster.LoadChunk(ctx, lcr, addr, creds, timeout, retries))
+	}
+	for first := true; ; first = false {
+		lcr, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = send(lcr)
+			if first && errors.Is(err, store.ErrNotLeader) {
+				if noForward {
+					return "", false, ErrNotLeader
+				}
+				if addr, err = p.leaderAddr(); err != nil {
+					return "", false, err
+				}
+				err = send(lcr)
+			}
+		}
+		if err != nil {
+			// Discard any chunks already applied. The load has failed
+			// whether or not this succeeds.
+			send(chunker.Abort())
+			return "", false, err
+		}
+	}
+	if addr == "" {
+		return p.GetAPIAddr(), false, nil
+	}
+	return addr, true, nil
+}
+
 // Remove removes a node from the cluster. If the local store returns
 // ErrNotLeader and noForward is false, the request is forwarded to
 // the current leader.
@@ -266,7 +367,7 @@ func (p *Proxy) Remove(ctx context.Context, rn *proto.RemoveNodeRequest, creds *
 		}
 		err = p.cluster.RemoveNode(ctx, rn, addr, creds, timeout)
 		if err != nil {
-			return "", wrapIfUnauthorized(err)
+			return "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteRemoveNode, 1)
 		return addr, nil
@@ -295,7 +396,7 @@ func (p *Proxy) Stepdown(ctx context.Context, wait bool, id string, creds *clstr
 		}
 		err = p.cluster.Stepdown(ctx, sr, addr, creds, timeout)
 		if err != nil {
-			return "", wrapIfUnauthorized(err)
+			return "", wrapRemoteError(err)
 		}
 		stats.Add(numRemoteStepdowns, 1)
 		return addr, nil
@@ -303,6 +404,39 @@ func (p *Proxy) Stepdown(ctx context.Context, wait bool, id string, creds *clstr
 	return p.GetAPIAddr(), err
 }
 
+// Checksum checksums the database on every node, at the same log index,
+// returning the checksums computed by the leader. If the local store returns
+// ErrNotLeader and noForward is false, the request is forwarded to the
+// current leader.
+func (p *Proxy) Checksum(ctx context.Context, cr *proto.ChecksumRequest, creds *clstrPB.Credentials,
+	timeout time.Duration, noForward bool) (*proto.ChecksumResult, string, error) {
+
+	res, err := p.store.Checksum(ctx, cr)
+	if errors.Is(err, store.ErrNotLeader) {
+		if noForward {
+			return nil, "", ErrNotLeader
+		}
+		addr, addrErr := p.leaderAddr()
+		if addrErr != nil {
+			return nil, "", addrErr
+		}
+		res, err = p.cluster.Checksum(ctx, cr, addr, creds, timeout)
+		if err != nil {
+			return nil, "", wrapRemoteError(err)
+		}
+		stats.Add(numRemoteChecksums, 1)
+		return res, addr, nil
+	}
+	return res, p.GetAPIAddr(), err
+}
+
+// startForward starts a span covering the forwarding of a request to the
+// leader at addr.
+func startForward(ctx context.Context, op, addr string) (context.Context, trace.Span) {
+	return tracing.StartChild(ctx, tracer, "proxy.forward "+op,
+		trace.WithAttributes(attribute.String("rqlite.leader_addr", addr)))
+}
+
 // leaderAddr returns the Raft address of the current leader. Returns
 // ErrLeaderNotFound if the address is empty.
 func (p *Proxy) leaderAddr() (string, error) {
@@ -316,14 +450,19 @@ func (p *Proxy) leaderAddr() (string, error) {
 	return addr, nil
 }
 
-// wrapIfUnauthorized wraps an error as ErrUnauthorized if the error
-// message is "unauthorized", allowing callers to use errors.Is().
-func wrapIfUnauthorized(err error) error {
+// wrapRemoteError wraps an error returned by a remote node as ErrUnauthorized
+// if the error message is "unauthorized", or as a *store.ThrottledError if the
+// remote node refused a write because it is under pressure, allowing callers
+// to use errors.Is() and errors.As().
+func wrapRemoteError(err error) error {
 	if err == nil {
 		return nil
 	}
 	if err.Error() == "unauthorized" {
 		return ErrUnauthorized
 	}
+	if thrErr, ok := store.ParseThrottledError(err.Error()); ok {
+		return thrErr
+	}
 	return err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,9 +19,11 @@ type mockStore struct {
 	queryFn      func(ctx context.Context, qr *proto.QueryRequest) ([]*proto.QueryRows, proto.ConsistencyLevel, uint64, error)
 	requestFn    func(ctx context.Context, eqr *proto.ExecuteQueryRequest) ([]*proto.ExecuteQueryResponse, uint64, uint64, error)
 	loadFn       func(ctx context.Context, lr *proto.LoadRequest) error
+	loadChunkFn  func(ctx context.Context, lcr *proto.LoadChunkRequest) error
 	backupFn     func(ctx context.Context, br *proto.BackupRequest, dst io.Writer) error
 	removeFn     func(ctx context.Context, rn *proto.RemoveNodeRequest) error
 	stepdownFn   func(wait bool, id string) error
+	checksumFn   func(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error)
 	leaderAddrFn func() (string, error)
 }
 
@@ -52,6 +55,13 @@ func (m *mockStore) Load(ctx context.Context, lr *proto.LoadRequest) error {
 	return nil
 }
 
+func (m *mockStore) LoadChunk(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+	if m.loadChunkFn != nil {
+		return m.loadChunkFn(ctx, lcr)
+	}
+	return nil
+}
+
 func (m *mockStore) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writer) error {
 	if m.backupFn != nil {
 		return m.backupFn(ctx, br, dst)
@@ -73,6 +83,13 @@ func (m *mockStore) Stepdown(wait bool, id string) error {
 	return nil
 }
 
+func (m *mockStore) Checksum(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error) {
+	if m.checksumFn != nil {
+		return m.checksumFn(ctx, cr)
+	}
+	return &proto.ChecksumResult{}, nil
+}
+
 func (m *mockStore) LeaderAddr() (string, error) {
 	if m.leaderAddrFn != nil {
 		return m.leaderAddrFn()
@@ -87,8 +104,10 @@ type mockCluster struct {
 	requestFn    func(ctx context.Context, eqr *proto.ExecuteQueryRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) ([]*proto.ExecuteQueryResponse, uint64, uint64, error)
 	backupFn     func(ctx context.Context, br *proto.BackupRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, w io.Writer) error
 	loadFn       func(ctx context.Context, lr *proto.LoadRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error
+	loadChunkFn  func(ctx context.Context, lcr *proto.LoadChunkRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error
 	removeNodeFn func(ctx context.Context, rn *proto.RemoveNodeRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) error
 	stepdownFn   func(ctx context.Context, sr *proto.StepdownRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) error
+	checksumFn   func(ctx context.Context, cr *proto.ChecksumRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) (*proto.ChecksumResult, error)
 }
 
 func (m *mockCluster) Execute(ctx context.Context, er *proto.ExecuteRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) ([]*proto.ExecuteQueryResponse, uint64, error) {
@@ -126,6 +145,13 @@ func (m *mockCluster) Load(ctx context.Context, lr *proto.LoadRequest, nodeAddr
 	return nil
 }
 
+func (m *mockCluster) LoadChunk(ctx context.Context, lcr *proto.LoadChunkRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error {
+	if m.loadChunkFn != nil {
+		return m.loadChunkFn(ctx, lcr, nodeAddr, creds, timeout, retries)
+	}
+	return nil
+}
+
 func (m *mockCluster) RemoveNode(ctx context.Context, rn *proto.RemoveNodeRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) error {
 	if m.removeNodeFn != nil {
 		return m.removeNodeFn(ctx, rn, nodeAddr, creds, timeout)
@@ -140,6 +166,13 @@ func (m *mockCluster) Stepdown(ctx context.Context, sr *proto.StepdownRequest, n
 	return nil
 }
 
+func (m *mockCluster) Checksum(ctx context.Context, cr *proto.ChecksumRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) (*proto.ChecksumResult, error) {
+	if m.checksumFn != nil {
+		return m.checksumFn(ctx, cr, nodeAddr, creds, timeout)
+	}
+	return &proto.ChecksumResult{}, nil
+}
+
 func newTestProxy(s Store, c Cluster) *Proxy {
 	return New(s, c)
 }
@@ -261,6 +294,33 @@ func Test_Execute_Unauthorized(t *testing.T) {
 	}
 }
 
+func Test_Execute_Throttled(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		executeFn: func(ctx context.Context, er *proto.ExecuteRequest) ([]*proto.ExecuteQueryResponse, uint64, error) {
+			return nil, 0, store.ErrNotLeader
+		},
+		leaderAddrFn: func() (string, error) {
+			return "leader:4002", nil
+		},
+	}
+	c := &mockCluster{
+		executeFn: func(ctx context.Context, er *proto.ExecuteRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) ([]*proto.ExecuteQueryResponse, uint64, error) {
+			return nil, 0, errors.New((&store.ThrottledError{RetryAfter: 5 * time.Second}).Error())
+		},
+	}
+	p := newTestProxy(s, c)
+
+	_, _, _, err := p.Execute(context.Background(), &proto.ExecuteRequest{}, nil, time.Second, 0, false)
+	var tErr *store.ThrottledError
+	if !errors.As(err, &tErr) {
+		t.Fatalf("expected ThrottledError, got %v", err)
+	}
+	if tErr.RetryAfter != 5*time.Second {
+		t.Fatalf("wrong retry after, got %s", tErr.RetryAfter)
+	}
+}
+
 func Test_Execute_ClusterOtherError(t *testing.T) {
 	t.Parallel()
 	s := &mockStore{
@@ -671,6 +731,144 @@ func Test_Load_Unauthorized(t *testing.T) {
 	}
 }
 
+func Test_LoadFrom_LocalSuccess(t *testing.T) {
+	t.Parallel()
+	var chunks []*proto.LoadChunkRequest
+	s := &mockStore{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+			chunks = append(chunks, lcr)
+			return nil
+		},
+	}
+	p := newTestProxy(s, &mockCluster{})
+
+	addr, err := p.LoadFrom(context.Background(), strings.NewReader("0123456789"), 4, nil, time.Second, 0, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if addr != "" {
+		t.Fatalf("expected empty addr, got %s", addr)
+	}
+	if len(chunks) != 3 || !chunks[2].IsLast {
+		t.Fatalf("expected 3 chunks, the last marked as such, got %v", chunks)
+	}
+}
+
+func Test_LoadFrom_NotLeader_NoForward(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+			return store.ErrNotLeader
+		},
+	}
+	p := newTestProxy(s, &mockCluster{})
+
+	_, err := p.LoadFrom(context.Background(), strings.NewReader("0123456789"), 4, nil, time.Second, 0, true)
+	if !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected ErrNotLeader, got %v", err)
+	}
+}
+
+func Test_LoadFrom_NotLeader_Forward(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+			return store.ErrNotLeader
+		},
+		leaderAddrFn: func() (string, error) {
+			return "leader:4002", nil
+		},
+	}
+	var chunks []*proto.LoadChunkRequest
+	c := &mockCluster{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error {
+			if nodeAddr != "leader:4002" {
+				t.Fatalf("expected forwarding to leader:4002, got %s", nodeAddr)
+			}
+			chunks = append(chunks, lcr)
+			return nil
+		},
+	}
+	p := newTestProxy(s, c)
+
+	addr, err := p.LoadFrom(context.Background(), strings.NewReader("0123456789"), 4, nil, time.Second, 0, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if addr != "leader:4002" {
+		t.Fatalf("expected addr leader:4002, got %s", addr)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks forwarded, got %d", len(chunks))
+	}
+}
+
+func Test_LoadFrom_Abort(t *testing.T) {
+	t.Parallel()
+	var aborted bool
+	s := &mockStore{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+			if lcr.Abort {
+				aborted = true
+				return nil
+			}
+			if lcr.SequenceNum == 2 {
+				return errors.New("chunk failed")
+			}
+			return nil
+		},
+	}
+	p := newTestProxy(s, &mockCluster{})
+
+	if _, err := p.LoadFrom(context.Background(), strings.NewReader("0123456789"), 4, nil, time.Second, 0, false); err == nil {
+		t.Fatalf("expected error")
+	}
+	if !aborted {
+		t.Fatalf("expected load to be aborted")
+	}
+}
+
+func Test_Import_NotLeader_Forward(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+			return store.ErrNotLeader
+		},
+		leaderAddrFn: func() (string, error) {
+			return "leader:4002", nil
+		},
+	}
+	var chunks []*proto.LoadChunkRequest
+	c := &mockCluster{
+		loadChunkFn: func(ctx context.Context, lcr *proto.LoadChunkRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration, retries int) error {
+			chunks = append(chunks, lcr)
+			return nil
+		},
+	}
+	p := newTestProxy(s, c)
+
+	access := &proto.Statement{Sql: `INSERT INTO "foo" DEFAULT VALUES`}
+	req := &proto.Request{
+		Transaction: true,
+		Statements:  []*proto.Statement{{Sql: `INSERT INTO "foo" ("id") VALUES (1)`}},
+	}
+	addr, err := p.Import(context.Background(), access, req, 4, nil, time.Second, 0, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if addr != "leader:4002" {
+		t.Fatalf("expected addr leader:4002, got %s", addr)
+	}
+	if len(chunks) < 2 || !chunks[len(chunks)-1].IsLast {
+		t.Fatalf("expected batch forwarded in chunks, got %v", chunks)
+	}
+	for _, lcr := range chunks {
+		if lcr.ImportAccess != access.Sql {
+			t.Fatalf("chunk carries wrong access statement: %s", lcr.ImportAccess)
+		}
+	}
+}
+
 func Test_Remove_LocalSuccess(t *testing.T) {
 	t.Parallel()
 	s := &mockStore{}
@@ -834,3 +1032,53 @@ func Test_LeaderAddrError(t *testing.T) {
 		t.Fatalf("expected leader error, got %v", err)
 	}
 }
+
+func Test_Checksum_NotLeader_NoForward(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		checksumFn: func(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error) {
+			return nil, store.ErrNotLeader
+		},
+	}
+	p := newTestProxy(s, &mockCluster{})
+
+	_, _, err := p.Checksum(context.Background(), &proto.ChecksumRequest{}, nil, time.Second, true)
+	if !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected ErrNotLeader, got %v", err)
+	}
+}
+
+func Test_Checksum_NotLeader_Forward(t *testing.T) {
+	t.Parallel()
+	s := &mockStore{
+		checksumFn: func(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error) {
+			return nil, store.ErrNotLeader
+		},
+		leaderAddrFn: func() (string, error) {
+			return "leader:4002", nil
+		},
+	}
+	c := &mockCluster{
+		checksumFn: func(ctx context.Context, cr *proto.ChecksumRequest, nodeAddr string, creds *clstrPB.Credentials, timeout time.Duration) (*proto.ChecksumResult, error) {
+			if nodeAddr != "leader:4002" {
+				t.Fatalf("expected forwarding to leader:4002, got %s", nodeAddr)
+			}
+			if cr.ChunkSize != 1000 {
+				t.Fatalf("expected chunk size 1000, got %d", cr.ChunkSize)
+			}
+			return &proto.ChecksumResult{Index: 7}, nil
+		},
+	}
+	p := newTestProxy(s, c)
+
+	res, addr, err := p.Checksum(context.Background(), &proto.ChecksumRequest{ChunkSize: 1000}, nil, time.Second, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if addr != "leader:4002" {
+		t.Fatalf("expected addr leader:4002, got %s", addr)
+	}
+	if res.Index != 7 {
+		t.Fatalf("expected index 7, got %d", res.Index)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hashicorp/raft"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/snapshot/proto"
 	pb "google.golang.org/protobuf/proto"
 )
@@ -65,7 +66,7 @@ type Sink struct {
 // and the snapshot metadata. closeCh, if non-nil, will receive a non-blocking
 // signal after a successful Close.
 func NewSink(dir string, meta *raft.SnapshotMeta, stc snapshotTypeController, closeCh chan<- struct{}) *Sink {
-	logger := log.New(log.Writer(), "[snapshot-sink] ", log.LstdFlags)
+	logger := logging.New("snapshot-sink", os.Stderr)
 	return &Sink{
 		dir:     dir,
 		meta:    meta,
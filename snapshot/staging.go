@@ -10,6 +10,7 @@ import (
 
 	"github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rsum"
 	"github.com/rqlite/rqlite/v10/snapshot/sidecar"
 )
@@ -37,7 +38,7 @@ type StagingDir struct {
 func NewStagingDir(dir string) *StagingDir {
 	return &StagingDir{
 		dir:    dir,
-		logger: log.New(log.Writer(), "[snapshot-staging] ", log.LstdFlags),
+		logger: logging.New("snapshot-staging", os.Stderr),
 	}
 }
 
@@ -4,9 +4,11 @@ import (
 	"expvar"
 	"io"
 	"log"
+	"os"
 	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/progress"
 )
 
@@ -35,7 +37,7 @@ type StateReader struct {
 func NewStateReader(rc io.ReadCloser) *StateReader {
 	return &StateReader{
 		rc:     rc,
-		logger: log.New(log.Writer(), "[snapshot] ", log.LstdFlags),
+		logger: logging.New("snapshot", os.Stderr),
 	}
 }
 
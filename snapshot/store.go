@@ -15,6 +15,8 @@ import (
 
 	"github.com/hashicorp/raft"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/latency"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
 	"github.com/rqlite/rqlite/v10/snapshot/plan"
 )
@@ -51,6 +53,8 @@ const (
 
 	upgradeOk   = "upgrade_ok"
 	upgradeFail = "upgrade_fail"
+
+	reapLatency = "reap_latency"
 )
 
 var (
@@ -61,6 +65,9 @@ var (
 // stats captures stats for the Store.
 var stats *expvar.Map
 
+// reapHist is the latency histogram of reaps, served as part of stats.
+var reapHist = latency.NewHistogram(0)
+
 func init() {
 	stats = expvar.NewMap("snapshot")
 	ResetStats()
@@ -89,6 +96,8 @@ func ResetStats() {
 	stats.Add(readTimeoutTotal, 0)
 	stats.Add(upgradeOk, 0)
 	stats.Add(upgradeFail, 0)
+	reapHist.Reset()
+	stats.Set(reapLatency, reapHist)
 }
 
 // ErrSnapshotReaderTimeout is returned by Read after the LockingStreamer has
@@ -182,7 +191,7 @@ func (l *LockingStreamer) checkIdle() {
 	stats.Add(readTimeoutTotal, 1)
 	l.str.logger.Printf("snapshot reader idle for %s, forcing close", idle)
 	if closeErr := l.ReadCloser.Close(); closeErr != nil {
-		l.str.logger.Printf("error closing idle snapshot reader: %s", closeErr)
+		logging.Warnf(l.str.logger, "error closing idle snapshot reader: %s", closeErr)
 	}
 	l.str.mrsw.EndRead()
 }
@@ -238,7 +247,7 @@ func NewStore(dir string) (*Store, error) {
 		return nil, err
 	}
 
-	logger := log.New(os.Stderr, "[snapshot-store] ", log.LstdFlags)
+	logger := logging.New("snapshot-store", os.Stderr)
 	str := &Store{
 		dir:            dir,
 		fullNeededPath: filepath.Join(dir, fullNeededFile),
@@ -254,7 +263,7 @@ func NewStore(dir string) (*Store, error) {
 		observers:      newObserverSet(),
 		logger:         logger,
 		fatalFn: func(err error) {
-			logger.Fatalf("fatal snapshot integrity error, exiting process: %s", err)
+			logging.Fatalf(logger, "fatal snapshot integrity error, exiting process: %s", err)
 		},
 	}
 	str.logger.Printf("store initialized using %s", dir)
@@ -509,6 +518,7 @@ func (s *Store) Reap() (int, int, error) {
 func (s *Store) reap() (int, int, error) {
 	startTime := time.Now()
 	n, c, err := s.reapInternal()
+	reapHist.RecordSince(startTime)
 	if err != nil {
 		return n, c, err
 	}
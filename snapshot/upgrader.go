@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/raft"
 	"github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/snapshot/plan"
 )
 
@@ -35,7 +36,7 @@ func Upgrade7To8(old, new string, logger *log.Logger) (retErr error) {
 	defer func() {
 		if retErr != nil {
 			if err := os.RemoveAll(newTmpDir); err != nil && !os.IsNotExist(err) {
-				logger.Printf("failed to remove temporary upgraded snapshot directory at %s due to outer error (%s) cleanup: %s",
+				logging.Warnf(logger, "failed to remove temporary upgraded snapshot directory at %s due to outer error (%s) cleanup: %s",
 					newTmpDir, retErr, err)
 			}
 		}
@@ -282,7 +283,7 @@ func Upgrade8To10(old, new string, logger *log.Logger) (retErr error) {
 
 	// Clean up the plan file.
 	if err := os.Remove(planPath); err != nil {
-		logger.Printf("failed to remove upgrade plan file %s: %v", planPath, err)
+		logging.Warnf(logger, "failed to remove upgrade plan file %s: %v", planPath, err)
 	}
 	logger.Printf("upgraded v8 snapshot directory %s to %s", old, new)
 	stats.Add(upgradeOk, 1)
@@ -1,14 +1,18 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/rqlite/rqlite/v10/command"
 	"github.com/rqlite/rqlite/v10/command/chunking"
 	"github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
 	sql "github.com/rqlite/rqlite/v10/db"
 )
 
@@ -41,8 +45,9 @@ func NewCommandProcessor(logger *log.Logger, dm *chunking.DechunkerManager) *Com
 		decMgmr: dm}
 }
 
-// Process processes the given command against the given database.
-func (c *CommandProcessor) Process(data []byte, db *sql.SwappableDB) (*proto.Command, bool, any) {
+// Process processes the given command against the given database. Any
+// trace in ctx is continued by the database.
+func (c *CommandProcessor) Process(ctx context.Context, data []byte, db *sql.SwappableDB) (*proto.Command, bool, any) {
 	cmd := &proto.Command{}
 	if err := command.Unmarshal(data, cmd); err != nil {
 		panic(fmt.Sprintf("failed to unmarshal cluster command: %s", err.Error()))
@@ -54,21 +59,21 @@ func (c *CommandProcessor) Process(data []byte, db *sql.SwappableDB) (*proto.Com
 		if err := command.UnmarshalSubCommand(cmd, &qr); err != nil {
 			panic(fmt.Sprintf("failed to unmarshal query subcommand: %s", err.Error()))
 		}
-		r, err := db.Query(qr.Request, qr.Timings)
+		r, err := db.QueryWithContext(ctx, qr.Request, qr.Timings)
 		return cmd, false, &fsmQueryResponse{rows: r, error: err}
 	case proto.Command_COMMAND_TYPE_EXECUTE:
 		var er proto.ExecuteRequest
 		if err := command.UnmarshalSubCommand(cmd, &er); err != nil {
 			panic(fmt.Sprintf("failed to unmarshal execute subcommand: %s", err.Error()))
 		}
-		r, err := db.Execute(er.Request, er.Timings)
+		r, err := db.ExecuteWithContext(ctx, er.Request, er.Timings)
 		return cmd, true, &fsmExecuteQueryResponse{results: r, error: err}
 	case proto.Command_COMMAND_TYPE_EXECUTE_QUERY:
 		var eqr proto.ExecuteQueryRequest
 		if err := command.UnmarshalSubCommand(cmd, &eqr); err != nil {
 			panic(fmt.Sprintf("failed to unmarshal execute-query subcommand: %s", err.Error()))
 		}
-		r, err := db.Request(eqr.Request, eqr.Timings)
+		r, err := db.RequestWithContext(ctx, eqr.Request, eqr.Timings)
 		return cmd, ExecuteQueryResponses(r).Mutation(), &fsmExecuteQueryResponse{results: r, error: err}
 	case proto.Command_COMMAND_TYPE_LOAD:
 		var lr proto.LoadRequest
@@ -89,8 +94,8 @@ func (c *CommandProcessor) Process(data []byte, db *sql.SwappableDB) (*proto.Com
 		}
 		fd.Close()
 
-		// Swap the underlying database to the new one.
-		if err := db.Swap(fd.Name(), db.FKEnabled(), db.WALEnabled()); err != nil {
+		// Swap the underlying database to the new one, keeping the users.
+		if err := db.Load(fd.Name(), db.FKEnabled(), db.WALEnabled()); err != nil {
 			return cmd, false, &fsmGenericResponse{error: fmt.Errorf("error swapping databases: %s", err)}
 		}
 		return cmd, true, &fsmGenericResponse{}
@@ -124,6 +129,10 @@ func (c *CommandProcessor) Process(data []byte, db *sql.SwappableDB) (*proto.Com
 				c.decMgmr.Delete(lcr.StreamId)
 				defer os.Remove(path)
 
+				if lcr.ImportAccess != "" {
+					return c.applyImport(ctx, cmd, path, lcr.ImportAccess, db)
+				}
+
 				// Check if reassembled database is valid. If not, do not perform the load. This could
 				// happen a snapshot truncated earlier parts of the log which contained the earlier parts
 				// of a database load. If that happened then the database has already been loaded, and
@@ -132,15 +141,72 @@ func (c *CommandProcessor) Process(data []byte, db *sql.SwappableDB) (*proto.Com
 					c.logger.Printf("invalid chunked database file - ignoring")
 					return cmd, false, &fsmGenericResponse{error: fmt.Errorf("invalid chunked database file - ignoring")}
 				}
-				if err := db.Swap(path, db.FKEnabled(), db.WALEnabled()); err != nil {
+				if err := db.Load(path, db.FKEnabled(), db.WALEnabled()); err != nil {
 					return cmd, false, &fsmGenericResponse{error: fmt.Errorf("error swapping databases: %s", err)}
 				}
+				return cmd, true, &fsmGenericResponse{}
 			}
 		}
-		return cmd, true, &fsmGenericResponse{}
+		// Only the last chunk changes the database.
+		return cmd, false, &fsmGenericResponse{}
+	case proto.Command_COMMAND_TYPE_CHECKSUM:
+		var cr proto.ChecksumRequest
+		if err := command.UnmarshalChecksumRequest(cmd.SubCommand, &cr); err != nil {
+			panic(fmt.Sprintf("failed to unmarshal checksum subcommand: %s", err.Error()))
+		}
+		// Only pin the state of the database here. Hashing every table is
+		// left to the Store, so that it does not block the application of
+		// later commands.
+		rdr, err := db.NewChecksumReader(cr.ChunkSize)
+		if err != nil {
+			return cmd, false, &fsmChecksumResponse{error: err}
+		}
+		return cmd, false, &fsmChecksumResponse{reader: rdr, chunkSize: cr.ChunkSize}
 	case proto.Command_COMMAND_TYPE_NOOP:
 		return cmd, false, &fsmGenericResponse{}
 	default:
 		return cmd, false, &fsmGenericResponse{error: fmt.Errorf("unhandled command: %v", cmd.Type)}
 	}
 }
+
+// applyImport executes the batch of an import, reassembled from its chunks at
+// path, as a single transaction. The batch may only access tables as the
+// access statement, which was authorized when the chunks were sent, does.
+func (c *CommandProcessor) applyImport(ctx context.Context, cmd *proto.Command, path, access string, db *sql.SwappableDB) (*proto.Command, bool, any) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cmd, false, &fsmGenericResponse{error: fmt.Errorf("failed to read import batch: %s", err)}
+	}
+	var req proto.Request
+	if err := command.UnmarshalImportBatch(b, &req); err != nil {
+		return cmd, false, &fsmGenericResponse{error: fmt.Errorf("invalid import batch: %s", err)}
+	}
+
+	permitted, err := cmdsql.TableAccesses(access)
+	if err != nil {
+		return cmd, false, &fsmGenericResponse{error: fmt.Errorf("invalid import access: %s", err)}
+	}
+	for _, stmt := range req.Statements {
+		if stmt.Name != "" {
+			return cmd, false, &fsmGenericResponse{error: fmt.Errorf("named statement %s not permitted in import", stmt.Name)}
+		}
+		accesses, err := cmdsql.TableAccesses(stmt.Sql)
+		if err != nil {
+			return cmd, false, &fsmGenericResponse{error: fmt.Errorf("invalid import statement: %s", err)}
+		}
+		for _, a := range accesses {
+			if !slices.Contains(permitted, a) {
+				return cmd, false, &fsmGenericResponse{error: fmt.Errorf("import statement not permitted: %s", stmt.Sql)}
+			}
+		}
+	}
+
+	req.Transaction = true
+	r, err := db.ExecuteWithContext(ctx, &req, false)
+	if err == nil {
+		if e := command.ExecuteQueryResponsesError(r); e != "" {
+			err = errors.New(e)
+		}
+	}
+	return cmd, true, &fsmGenericResponse{error: err}
+}
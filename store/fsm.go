@@ -8,10 +8,11 @@ import (
 	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/snapshot"
 )
 
-var fsmSnapshotErrLogger = log.New(os.Stderr, "[fsm-snapshot] ", log.LstdFlags)
+var fsmSnapshotErrLogger = logging.New("fsm-snapshot", os.Stderr)
 
 // FSM is a wrapper around the Store which implements raft.FSM.
 type FSM struct {
@@ -70,7 +71,7 @@ func (f *FSMSnapshot) Persist(sink raft.SnapshotSink) (retError error) {
 		}
 	}()
 	if err := f.FSMSnapshot.Persist(sink); err != nil {
-		fsmSnapshotErrLogger.Printf("failed to persist %s snapshot %s: %v", f.Type, sink.ID(), err)
+		logging.Errorf(fsmSnapshotErrLogger, "failed to persist %s snapshot %s: %v", f.Type, sink.ID(), err)
 		return err
 	}
 	if f.Finalizer != nil {
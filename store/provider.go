@@ -2,9 +2,13 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auto"
 	"github.com/rqlite/rqlite/v10/command/proto"
 )
 
@@ -15,8 +19,15 @@ type Provider struct {
 	vacuum   bool
 	compress bool
 
+	// Compression, if set, selects the compression algorithm applied to the
+	// provided data, taking precedence over the compression flag passed to
+	// NewProvider.
+	Compression proto.BackupRequest_Compression
+
 	nRetries      int
 	retryInterval time.Duration
+
+	lastTerm uint64 // Term of the most recently applied log entry when data was last provided.
 }
 
 // NewProvider returns a new instance of Provider. If v is true, the
@@ -50,10 +61,12 @@ func (p *Provider) Provide(w io.WriteSeeker) (retErr error) {
 	}()
 
 	br := &proto.BackupRequest{
-		Format:   proto.BackupRequest_BACKUP_REQUEST_FORMAT_BINARY,
-		Vacuum:   p.vacuum,
-		Compress: p.compress,
+		Format:      proto.BackupRequest_BACKUP_REQUEST_FORMAT_BINARY,
+		Vacuum:      p.vacuum,
+		Compress:    p.compress,
+		Compression: p.Compression,
 	}
+	p.lastTerm = p.str.fsmTerm.Load()
 	nRetries := 0
 	for {
 		if _, err := w.Seek(0, io.SeekStart); err != nil {
@@ -71,3 +84,27 @@ func (p *Provider) Provide(w io.WriteSeeker) (retErr error) {
 	}
 	return nil
 }
+
+// ManifestDetails sets the Term, NodeID and SchemaHash fields of m, so they
+// describe the data most recently provided.
+func (p *Provider) ManifestDetails(m *auto.Manifest) error {
+	rows, err := p.str.db.QueryStringStmt("SELECT type, name, tbl_name, sql FROM sqlite_master ORDER BY type, name")
+	if err != nil {
+		return err
+	}
+	if len(rows) != 1 || rows[0].Error != "" {
+		return fmt.Errorf("failed to query schema: %v", rows)
+	}
+	h := sha256.New()
+	for _, v := range rows[0].Values {
+		for _, param := range v.Parameters {
+			fmt.Fprintf(h, "%s\x00", param.GetS())
+		}
+		h.Write([]byte{'\n'})
+	}
+
+	m.Term = p.lastTerm
+	m.NodeID = p.str.ID()
+	m.SchemaHash = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/auto"
 	command "github.com/rqlite/rqlite/v10/command/proto"
 	"github.com/rqlite/rqlite/v10/internal/rarchive"
 )
@@ -284,3 +285,80 @@ func Test_SingleNodeProvideLastIndex_Restart(t *testing.T) {
 		return lm == newLI
 	}, 100*time.Millisecond, 5*time.Second)
 }
+
+// Test_SingleNodeProvideManifestDetails tests that the Provider correctly
+// describes provided data for backup manifests.
+func Test_SingleNodeProvideManifestDetails(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	er := executeRequestFromStrings([]string{
+		`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`,
+	}, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+
+	tmpFd := mustCreateTempFD()
+	defer os.Remove(tmpFd.Name())
+	defer tmpFd.Close()
+	provider := NewProvider(s, false, false)
+	if err := provider.Provide(tmpFd); err != nil {
+		t.Fatalf("failed to provide SQLite data: %s", err.Error())
+	}
+
+	var m1 auto.Manifest
+	if err := provider.ManifestDetails(&m1); err != nil {
+		t.Fatalf("failed to get manifest details: %s", err.Error())
+	}
+	if m1.NodeID != s.ID() {
+		t.Fatalf("wrong node ID, exp %s, got %s", s.ID(), m1.NodeID)
+	}
+	if m1.Term == 0 {
+		t.Fatalf("term should be non-zero")
+	}
+	if m1.SchemaHash == "" {
+		t.Fatalf("schema hash should be set")
+	}
+
+	// Inserting data does not change the schema hash.
+	er = executeRequestFromStrings([]string{
+		`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+	}, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+	var m2 auto.Manifest
+	if err := provider.ManifestDetails(&m2); err != nil {
+		t.Fatalf("failed to get manifest details: %s", err.Error())
+	}
+	if m1.SchemaHash != m2.SchemaHash {
+		t.Fatalf("schema hash changed after insert")
+	}
+
+	// Changing the schema does.
+	er = executeRequestFromStrings([]string{
+		`CREATE TABLE bar (id INTEGER NOT NULL PRIMARY KEY)`,
+	}, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+	var m3 auto.Manifest
+	if err := provider.ManifestDetails(&m3); err != nil {
+		t.Fatalf("failed to get manifest details: %s", err.Error())
+	}
+	if m1.SchemaHash == m3.SchemaHash {
+		t.Fatalf("schema hash did not change after schema change")
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -236,7 +237,7 @@ func RecoverNode(dataDir string, extensions []string, logger *log.Logger, logs r
 			return fmt.Errorf("failed to get log at index %d: %v", index, err)
 		}
 		if entry.Type == raft.LogCommand {
-			cmdProc.Process(entry.Data, db)
+			cmdProc.Process(context.Background(), entry.Data, db)
 		}
 		lastIndex = entry.Index
 		lastTerm = entry.Term
@@ -5,7 +5,6 @@ package store
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/binary"
 	"errors"
@@ -13,6 +12,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -26,19 +26,27 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
+	"github.com/rqlite/rqlite/v10/auth"
 	"github.com/rqlite/rqlite/v10/command"
 	"github.com/rqlite/rqlite/v10/command/chunking"
 	"github.com/rqlite/rqlite/v10/command/proto"
+	cmdsql "github.com/rqlite/rqlite/v10/command/sql"
 	sql "github.com/rqlite/rqlite/v10/db"
 	"github.com/rqlite/rqlite/v10/db/humanize"
 	"github.com/rqlite/rqlite/v10/internal/fsutil"
+	"github.com/rqlite/rqlite/v10/internal/latency"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/progress"
 	"github.com/rqlite/rqlite/v10/internal/random"
+	"github.com/rqlite/rqlite/v10/internal/rarchive"
 	"github.com/rqlite/rqlite/v10/internal/rsum"
 	"github.com/rqlite/rqlite/v10/internal/rsync"
+	"github.com/rqlite/rqlite/v10/internal/tracing"
 	"github.com/rqlite/rqlite/v10/snapshot"
 	rlog "github.com/rqlite/rqlite/v10/store/log"
 	"github.com/rqlite/rqlite/v10/store/throttler"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -118,6 +126,14 @@ var (
 	// WAL data to snapshot. This can happen when the Raft log contains only entries
 	// that don't modify the database (e.g. cluster membership changes).
 	ErrNoWALToSnapshot = errors.New("no WAL data available for snapshot")
+
+	// ErrChecksumNotFound is returned when a node has no checksum of the
+	// database at a given index.
+	ErrChecksumNotFound = errors.New("checksum not found")
+
+	// ErrChecksumInProgress is returned when a checksum command is applied
+	// while the checksum of an earlier command is still being computed.
+	ErrChecksumInProgress = errors.New("checksum already in progress")
 )
 
 const (
@@ -144,6 +160,7 @@ const (
 	raftLogCacheSize       = 512
 	trailingScale          = 1.25
 	observerChanLen        = 50
+	checksumRetain         = 16
 
 	baseVacuumTimeKey   = "rqlite_base_vacuum"
 	lastVacuumTimeKey   = "rqlite_last_vacuum"
@@ -201,11 +218,26 @@ const (
 	failedHeartbeatObserved     = "failed_heartbeat_observed"
 	nodesReapedOK               = "nodes_reaped_ok"
 	nodesReapedFailed           = "nodes_reaped_failed"
+	numWritesThrottled          = "num_writes_throttled"
+	raftApplyLatency            = "raft_apply_latency"
+	fsmApplyLatency             = "fsm_apply_latency"
+	snapshotCreateLatency       = "snapshot_create_latency"
 )
 
 // stats captures stats for the Store.
 var stats *expvar.Map
 
+// Latency histograms, served as part of stats.
+var (
+	raftApplyHist      = latency.NewHistogram(0)
+	fsmApplyHist       = latency.NewHistogram(0)
+	snapshotCreateHist = latency.NewHistogram(0)
+)
+
+// tracer traces the requests made of the Store, and their application to
+// the database.
+var tracer = tracing.Tracer("store")
+
 func init() {
 	stats = expvar.NewMap("store")
 	ResetStats()
@@ -267,6 +299,13 @@ func ResetStats() {
 	stats.Add(failedHeartbeatObserved, 0)
 	stats.Add(nodesReapedOK, 0)
 	stats.Add(nodesReapedFailed, 0)
+	stats.Add(numWritesThrottled, 0)
+	raftApplyHist.Reset()
+	stats.Set(raftApplyLatency, raftApplyHist)
+	fsmApplyHist.Reset()
+	stats.Set(fsmApplyLatency, fsmApplyHist)
+	snapshotCreateHist.Reset()
+	stats.Set(snapshotCreateLatency, snapshotCreateHist)
 }
 
 // SnapshotStore is the interface Snapshot stores must implement.
@@ -347,6 +386,16 @@ type Store struct {
 	dechunkManager *chunking.DechunkerManager
 	cmdProc        *CommandProcessor
 
+	// Checksums of the database computed at recent log indexes. Only one
+	// checksum is computed at a time.
+	checksumsMu  sync.Mutex
+	checksums    []*pendingChecksum
+	checksumBusy atomic.Bool
+
+	// lastLogIdxOnOpen is the index of the last entry in the Raft log when
+	// the Store was opened. Entries up to it are replayed on open.
+	lastLogIdxOnOpen uint64
+
 	// Channels that must be closed for the Store to be considered ready.
 	readyChans *rsync.ReadyChannels
 
@@ -402,6 +451,10 @@ type Store struct {
 
 	logger *log.Logger
 
+	// slogger logs messages about the application of individual commands,
+	// with their Raft indexes and request IDs.
+	slogger *slog.Logger
+
 	notifyMu        sync.Mutex
 	BootstrapExpect int
 	bootstrapped    bool
@@ -430,6 +483,11 @@ type Store struct {
 	ReapTimeout         time.Duration
 	ReapReadOnlyTimeout time.Duration
 
+	// WriteThrottleRejectLevel is the throttle level at which writes are
+	// refused with a ThrottledError, rather than delayed. If zero, writes
+	// are always delayed.
+	WriteThrottleRejectLevel int
+
 	numTrailingLogs uint64
 
 	// For whitebox testing
@@ -460,7 +518,7 @@ type Config struct {
 func New(c *Config, ly Layer) *Store {
 	logger := c.Logger
 	if logger == nil {
-		logger = log.New(os.Stderr, "[store] ", log.LstdFlags)
+		logger = logging.New("store", os.Stderr)
 	}
 
 	dbPath := filepath.Join(c.Dir, sqliteFile)
@@ -486,6 +544,7 @@ func New(c *Config, ly Layer) *Store {
 		reqMarshaller:     command.NewRequestMarshaler(),
 		throttler:         throttler.DefaultThrottler(),
 		logger:            logger,
+		slogger:           logging.Logger("store"),
 		notifyingNodes:    make(map[string]*Server),
 		ApplyTimeout:      applyTimeout,
 		snapshotSync:      rsync.NewSyncChannels(),
@@ -575,7 +634,8 @@ func (s *Store) Open() (retErr error) {
 	}
 
 	// Create Raft-compatible network layer.
-	nt := raft.NewNetworkTransport(NewTransport(s.ly), connectionPoolCount, connectionTimeout, nil)
+	nt := raft.NewNetworkTransportWithLogger(NewTransport(s.ly), connectionPoolCount, connectionTimeout,
+		raftLogger("raft-net"))
 	s.raftTn = NewNodeTransport(nt, s.CompressSnapTransport)
 
 	// Don't allow control over trailing logs directly, just implement a policy.
@@ -629,7 +689,7 @@ func (s *Store) Open() (retErr error) {
 				stats.Add(numRestoresStart, 1)
 				s.numSnapshotsStart.Add(1)
 				if err := fsutil.RemoveFile(s.cleanSnapshotPath); err != nil {
-					s.logger.Printf("warning: failed to remove clean snapshot marker file: %s", err)
+					logging.Warnf(s.logger, "failed to remove clean snapshot marker file: %s", err)
 				}
 			}
 		}()
@@ -639,12 +699,12 @@ func (s *Store) Open() (retErr error) {
 		}
 		fp := &FileFingerprint{}
 		if err := fp.ReadFromFile(s.cleanSnapshotPath); err != nil {
-			s.logger.Printf("failed to read clean snapshot (%s), performing full restore", err)
+			logging.Warnf(s.logger, "failed to read clean snapshot (%s), performing full restore", err)
 			return nil
 		}
 		mt, sz, err := fsutil.ModTimeSize(s.dbPath)
 		if err != nil {
-			s.logger.Printf("failed to read mod time from clean snapshot (%s), performing full restore", err)
+			logging.Warnf(s.logger, "failed to read mod time from clean snapshot (%s), performing full restore", err)
 			return nil
 		}
 
@@ -708,6 +768,7 @@ func (s *Store) Open() (retErr error) {
 			return fmt.Errorf("failed to retrieve latest snapshot index/term: %s", err)
 		}
 		s.fsmIdx.Store(li)
+		s.fsmTarget.Signal(li)
 		s.fsmTerm.Store(tm)
 		s.dbAppliedIdx.Store(li)
 		stats.Add(numRestoresStartSkipped, 1)
@@ -731,6 +792,7 @@ func (s *Store) Open() (retErr error) {
 		return fmt.Errorf("failed to retrieve log store indexes: %s", err)
 	}
 	s.logger.Println(raftLogInfoMessage(raftDBSize, fi, li))
+	s.lastLogIdxOnOpen = li
 	if fi != 0 && li != 0 {
 		err = s.boltStore.GetLog(fi, &raft.Log{})
 		if err != nil {
@@ -975,7 +1037,7 @@ func (s *Store) Close(wait bool) (retErr error) {
 		if err := s.Snapshot(0); err != nil {
 			if !strings.Contains(err.Error(), "nothing new to snapshot") &&
 				!strings.Contains(err.Error(), "wait until the configuration entry at") {
-				s.logger.Printf("pre-close snapshot failed: %s", err.Error())
+				logging.Warnf(s.logger, "pre-close snapshot failed: %s", err.Error())
 			}
 		}
 		s.logger.Println("snapshot-on-close took ", time.Since(startT))
@@ -1338,7 +1400,7 @@ func (s *Store) Stats() (map[string]any, error) {
 	dbStatus, err := s.db.Stats()
 	if err != nil {
 		stats.Add(numDBStatsErrors, 1)
-		s.logger.Printf("failed to get database stats: %s", err.Error())
+		logging.Warnf(s.logger, "failed to get database stats: %s", err.Error())
 	}
 
 	nodes, err := s.Nodes()
@@ -1399,6 +1461,11 @@ func (s *Store) Stats() (map[string]any, error) {
 			"enabled":    s.cdcEnabled.Is(),
 			"registered": s.cdcRegistered.Is(),
 		},
+		"write_throttle": map[string]any{
+			"level":        s.throttler.Level(),
+			"delay":        s.throttler.GetDelay().String(),
+			"reject_level": s.WriteThrottleRejectLevel,
+		},
 		"apply_timeout":          s.ApplyTimeout.String(),
 		"heartbeat_timeout":      s.HeartbeatTimeout.String(),
 		"election_timeout":       s.ElectionTimeout.String(),
@@ -1467,10 +1534,16 @@ func (s *Store) Execute(ctx context.Context, ex *proto.ExecuteRequest) ([]*proto
 	if !s.Ready() {
 		return nil, 0, ErrNotReady
 	}
-	return s.execute(ex)
+
+	// Only refuse writes this node would apply. Those it would not are
+	// forwarded to the Leader, which applies its own throttle.
+	if err := s.checkWriteThrottle(); err != nil {
+		return nil, 0, err
+	}
+	return s.execute(ctx, ex)
 }
 
-func (s *Store) execute(ex *proto.ExecuteRequest) ([]*proto.ExecuteQueryResponse, uint64, error) {
+func (s *Store) execute(ctx context.Context, ex *proto.ExecuteRequest) ([]*proto.ExecuteQueryResponse, uint64, error) {
 	b, compressed, err := s.tryCompress(ex)
 	if err != nil {
 		return nil, 0, err
@@ -1487,7 +1560,7 @@ func (s *Store) execute(ex *proto.ExecuteRequest) ([]*proto.ExecuteQueryResponse
 		return nil, 0, err
 	}
 
-	af := s.raft.Apply(b, s.ApplyTimeout)
+	af := s.applyCommand(ctx, b)
 	if af.Error() != nil {
 		if af.Error() == raft.ErrNotLeader {
 			return nil, 0, ErrNotLeader
@@ -1498,6 +1571,26 @@ func (s *Store) execute(ex *proto.ExecuteRequest) ([]*proto.ExecuteQueryResponse
 	return r.results, af.Index(), r.error
 }
 
+// applyCommand applies b through Raft, waiting until it has been applied.
+// If ctx is part of a trace, the trace context is stored with the log entry,
+// so that the application of the entry by each node is also traced.
+func (s *Store) applyCommand(ctx context.Context, b []byte) raft.ApplyFuture {
+	ctx, span := tracing.StartChild(ctx, tracer, "store.raftApply")
+	startT := time.Now()
+	af := s.raft.ApplyLog(raft.Log{Data: b, Extensions: tracing.Marshal(ctx)}, s.ApplyTimeout)
+	err := af.Error()
+	raftApplyHist.RecordSince(startT)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("rqlite.raft_index", int64(af.Index())))
+		s.slogger.DebugContext(logging.WithRaftIndex(ctx, af.Index()), "command applied through Raft",
+			"duration", time.Since(startT))
+	} else {
+		s.slogger.DebugContext(ctx, "failed to apply command through Raft", "error", err)
+	}
+	tracing.End(span, err)
+	return af
+}
+
 // Query executes queries that return rows, and do not modify the database.
 // If the request read consistency level is LINEARIZABLE, that level may be
 // upgraded to STRONG if the Store determines that is necessary to guarantee
@@ -1566,7 +1659,7 @@ func (s *Store) Query(ctx context.Context, qr *proto.QueryRequest) (rows []*prot
 			return nil, 0, 0, err
 		}
 
-		af := s.raft.Apply(b, s.ApplyTimeout)
+		af := s.applyCommand(ctx, b)
 		if af.Error() != nil {
 			if af.Error() == raft.ErrNotLeader || af.Error() == raft.ErrLeadershipLost {
 				return nil, 0, 0, ErrNotLeader
@@ -1684,6 +1777,9 @@ func (s *Store) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest) ([]
 	}
 
 	// Enforce throttling since we're probably about to make changes to the database.
+	if err := s.checkWriteThrottle(); err != nil {
+		return nil, 0, 0, err
+	}
 	if err := s.throttler.Delay(ctx); err != nil {
 		return nil, 0, 0, err
 	}
@@ -1703,7 +1799,7 @@ func (s *Store) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest) ([]
 		return nil, 0, 0, err
 	}
 
-	af := s.raft.Apply(b, s.ApplyTimeout)
+	af := s.applyCommand(ctx, b)
 	if af.Error() != nil {
 		if af.Error() == raft.ErrNotLeader {
 			return nil, 0, 0, ErrNotLeader
@@ -1725,13 +1821,17 @@ func (s *Store) Request(ctx context.Context, eqr *proto.ExecuteQueryRequest) ([]
 // can be called while writes are being made to the system. The backup may fail
 // if the system is actively snapshotting. The client can just retry in this case.
 //
-// If vacuum is not true the copy is written directly to dst, optionally in compressed
-// form, without any intermediate temporary files.
+// No backup contains the table of users, since it holds password hashes.
+//
+// If vacuum is not true, and there is no table of users, the copy is written
+// directly to dst, optionally in compressed form, without any intermediate
+// temporary files.
 //
 // If vacuum is true, then a VACUUM is performed on the database before the backup
 // is made. If compression false, and dst is an os.File, then the vacuumed copy
 // will be written directly to that file. Otherwise a temporary file will be created,
-// and that temporary file copied to dst.
+// and that temporary file copied to dst. A copy is made in the same way if there
+// is a table of users, so that it can be removed.
 func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writer) (retErr error) {
 	if !s.open.Is() {
 		return ErrNotOpen
@@ -1762,15 +1862,42 @@ func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 		return ErrNotLeader
 	}
 
+	switch br.Format {
+	case proto.BackupRequest_BACKUP_REQUEST_FORMAT_BINARY,
+		proto.BackupRequest_BACKUP_REQUEST_FORMAT_SQL,
+		proto.BackupRequest_BACKUP_REQUEST_FORMAT_DELETE:
+	default:
+		return ErrInvalidBackupFormat
+	}
+
+	// All formats stream through the requested compression algorithm, which
+	// must be closed to flush any compressed data still buffered.
+	compression := command.BackupCompressionToString(command.BackupCompression(br))
+	cw, err := rarchive.NewCompressWriter(dst, compression)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cw.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
 	switch br.Format {
 	case proto.BackupRequest_BACKUP_REQUEST_FORMAT_BINARY:
+		// The table of users holds password hashes, so must be removed from
+		// a copy of the database file rather than the file copied directly.
+		hasUsers, err := s.db.UsersTableExists()
+		if err != nil {
+			return err
+		}
+
 		var srcFD *os.File
-		var err error
-		if br.Vacuum {
-			if !br.Compress {
+		if br.Vacuum || hasUsers {
+			if compression == rarchive.CompressionNone {
 				if f, ok := dst.(*os.File); ok {
-					// Fast path, just vacuum directly to the destination.
-					return s.db.Backup(f.Name(), br.Vacuum)
+					// Fast path, just back up directly to the destination.
+					return s.backupDatabase(f.Name(), br.Vacuum)
 				}
 			}
 
@@ -1780,7 +1907,7 @@ func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 			}
 			defer os.Remove(srcFD.Name())
 			defer srcFD.Close()
-			if err := s.db.Backup(srcFD.Name(), br.Vacuum); err != nil {
+			if err := s.backupDatabase(srcFD.Name(), br.Vacuum); err != nil {
 				return err
 			}
 		} else {
@@ -1816,39 +1943,10 @@ func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 			defer srcFD.Close()
 		}
 
-		if br.Compress {
-			var dstGz *gzip.Writer
-			dstGz, err = gzip.NewWriterLevel(dst, gzip.BestSpeed)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				err := dstGz.Close()
-				if err != nil && retErr == nil {
-					retErr = err
-				}
-			}()
-			_, err = io.Copy(dstGz, srcFD)
-		} else {
-			_, err = io.Copy(dst, srcFD)
-		}
+		_, err = io.Copy(cw, srcFD)
 		return err
 	case proto.BackupRequest_BACKUP_REQUEST_FORMAT_SQL:
-		ww := dst
-		if br.Compress {
-			dstGz, err := gzip.NewWriterLevel(dst, gzip.BestSpeed)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				err := dstGz.Close()
-				if err != nil && retErr == nil {
-					retErr = err
-				}
-			}()
-			ww = dstGz
-		}
-		return s.db.Dump(ww, br.Tables...)
+		return s.db.Dump(cw, br.Tables...)
 	case proto.BackupRequest_BACKUP_REQUEST_FORMAT_DELETE:
 		// Create a temporary database file in DELETE mode
 		tmpFD, err := createTemp(s.dbDir, backupScratchPattern)
@@ -1859,7 +1957,7 @@ func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 		defer tmpFD.Close()
 
 		// Copy the current database to the temporary file and convert to DELETE mode
-		if err := s.db.Backup(tmpFD.Name(), br.Vacuum); err != nil {
+		if err := s.backupDatabase(tmpFD.Name(), br.Vacuum); err != nil {
 			return err
 		}
 
@@ -1871,26 +1969,21 @@ func (s *Store) Backup(ctx context.Context, br *proto.BackupRequest, dst io.Writ
 		defer tmpReadFD.Close()
 
 		// Stream the DELETE mode database to the destination
-		if br.Compress {
-			dstGz, err := gzip.NewWriterLevel(dst, gzip.BestSpeed)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				err := dstGz.Close()
-				if err != nil && retErr == nil {
-					retErr = err
-				}
-			}()
-			_, err = io.Copy(dstGz, tmpReadFD)
-		} else {
-			_, err = io.Copy(dst, tmpReadFD)
-		}
+		_, err = io.Copy(cw, tmpReadFD)
 		return err
 	}
 	return ErrInvalidBackupFormat
 }
 
+// backupDatabase writes a copy of the database, in DELETE mode, to the file
+// at path. The table of users is removed from the copy.
+func (s *Store) backupDatabase(path string, vacuum bool) error {
+	if err := s.db.Backup(path, vacuum); err != nil {
+		return err
+	}
+	return sql.RemoveUsersTable(path)
+}
+
 // Load loads an entire SQLite file into the database, sending the request
 // through the Raft log.
 func (s *Store) Load(ctx context.Context, lr *proto.LoadRequest) error {
@@ -1921,7 +2014,7 @@ func (s *Store) load(lr *proto.LoadRequest) (retErr error) {
 
 	b, err := command.MarshalLoadRequest(lr)
 	if err != nil {
-		s.logger.Printf("load failed during load-request marshalling %s", err.Error())
+		logging.Errorf(s.logger, "load failed during load-request marshalling %s", err.Error())
 		return err
 	}
 
@@ -1945,13 +2038,58 @@ func (s *Store) load(lr *proto.LoadRequest) (retErr error) {
 		if af.Error() == raft.ErrNotLeader {
 			return ErrNotLeader
 		}
-		s.logger.Printf("load failed during Apply: %s", af.Error())
+		logging.Errorf(s.logger, "load failed during Apply: %s", af.Error())
 		return af.Error()
 	}
 	r := af.Response().(*fsmGenericResponse)
 	return r.error
 }
 
+// LoadChunk sends one chunk of a SQLite file, or of a batch of an import,
+// through the Raft log. Once the last chunk of a stream is applied, the
+// reassembled file is loaded into the database, or the reassembled batch is
+// executed as a single transaction. A chunk with Abort set discards the chunks
+// of the stream applied so far.
+func (s *Store) LoadChunk(ctx context.Context, lcr *proto.LoadChunkRequest) error {
+	if !s.open.Is() {
+		return ErrNotOpen
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.Ready() {
+		return ErrNotReady
+	}
+
+	b, err := command.MarshalLoadChunkRequest(lcr)
+	if err != nil {
+		return err
+	}
+	b, err = command.Marshal(&proto.Command{
+		Type:       proto.Command_COMMAND_TYPE_LOAD_CHUNK,
+		SubCommand: b,
+	})
+	if err != nil {
+		return err
+	}
+
+	af := s.raft.Apply(b, s.ApplyTimeout)
+	if af.Error() != nil {
+		if af.Error() == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		logging.Errorf(s.logger, "load chunk failed during Apply: %s", af.Error())
+		return af.Error()
+	}
+	if r := af.Response().(*fsmGenericResponse); r.error != nil {
+		return r.error
+	}
+	if lcr.IsLast && lcr.ImportAccess == "" {
+		stats.Add(numLoads, 1)
+	}
+	return nil
+}
+
 // ReadFrom reads data from r, and loads it into the database, bypassing Raft consensus.
 // Once the data is loaded, a snapshot is triggered, which then results in a system as
 // if the data had been loaded through Raft consensus.
@@ -2002,8 +2140,8 @@ func (s *Store) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	// Swap in new database file.
-	if err := s.db.Swap(f.Name(), s.dbConf.FKConstraints, true); err != nil {
+	// Swap in new database file, keeping the users.
+	if err := s.db.Load(f.Name(), s.dbConf.FKConstraints, true); err != nil {
 		return n, fmt.Errorf("error swapping database file: %v", err)
 	}
 
@@ -2013,7 +2151,7 @@ func (s *Store) ReadFrom(r io.Reader) (int64, error) {
 
 	// Snapshot, so we load the new database into the Raft system.
 	if err := s.snapshotStore.SetDueNext(snapshot.Full); err != nil {
-		s.logger.Fatalf("failed to set full snapshot needed: %s", err)
+		logging.Fatalf(s.logger, "failed to set full snapshot needed: %s", err)
 	}
 	if err := s.Snapshot(1); err != nil {
 		return n, err
@@ -2143,7 +2281,7 @@ func (s *Store) Notify(nr *proto.NotifyRequest) error {
 		Servers: raftServers,
 	})
 	if bf.Error() != nil {
-		s.logger.Printf("cluster bootstrap failed: %s", bf.Error())
+		logging.Errorf(s.logger, "cluster bootstrap failed: %s", bf.Error())
 	} else {
 		s.logger.Printf("cluster bootstrap successful, servers: %s", raftServers)
 	}
@@ -2176,7 +2314,7 @@ func (s *Store) Join(jr *proto.JoinRequest) error {
 
 	configFuture := s.raft.GetConfiguration()
 	if err := configFuture.Error(); err != nil {
-		s.logger.Printf("failed to get raft configuration: %v", err)
+		logging.Errorf(s.logger, "failed to get raft configuration: %v", err)
 		return err
 	}
 
@@ -2194,7 +2332,7 @@ func (s *Store) Join(jr *proto.JoinRequest) error {
 			}
 
 			if err := s.remove(id); err != nil {
-				s.logger.Printf("failed to remove node %s: %v", id, err)
+				logging.Errorf(s.logger, "failed to remove node %s: %v", id, err)
 				return err
 			}
 			stats.Add(numRemovedBeforeJoins, 1)
@@ -2266,11 +2404,159 @@ func (s *Store) Noop(id string) (raft.ApplyFuture, error) {
 	return s.raft.Apply(bc, s.ApplyTimeout), nil
 }
 
+// Checksum writes a checksum command to the Raft log. When the command is
+// applied every node pins the state of its database with a read transaction,
+// and then checksums that state in the background, so the checksums of all
+// nodes are of the database at the same log index without blocking later
+// writes. The checksums computed by this node are returned, and include that
+// index. The checksums computed by each node can then be retrieved using
+// GetChecksum.
+func (s *Store) Checksum(ctx context.Context, cr *proto.ChecksumRequest) (*proto.ChecksumResult, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.raft.State() != raft.Leader {
+		return nil, ErrNotLeader
+	}
+
+	b, err := command.MarshalChecksumRequest(cr)
+	if err != nil {
+		return nil, err
+	}
+	c := &proto.Command{
+		Type:       proto.Command_COMMAND_TYPE_CHECKSUM,
+		SubCommand: b,
+	}
+	bc, err := command.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	af := s.raft.Apply(bc, s.ApplyTimeout)
+	if af.Error() != nil {
+		if af.Error() == raft.ErrNotLeader {
+			return nil, ErrNotLeader
+		}
+		return nil, af.Error()
+	}
+	r := af.Response().(*fsmChecksumResponse)
+	if r.error != nil {
+		return nil, r.error
+	}
+	if r.pending == nil {
+		return nil, ErrChecksumNotFound
+	}
+	return r.pending.wait(ctx)
+}
+
+// GetChecksum returns the checksums this node computed when it applied the
+// checksum command at the given index, waiting for the command to be applied
+// and the checksums to be computed if necessary. Only the checksums of recent
+// commands are retained.
+func (s *Store) GetChecksum(ctx context.Context, gr *proto.ChecksumResultRequest) (*proto.ChecksumResult, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	ch := s.fsmTarget.Subscribe(gr.Index)
+	defer s.fsmTarget.Unsubscribe(ch)
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("index %d: %w", gr.Index, ErrWaitForFSMTimeout)
+	}
+
+	var p *pendingChecksum
+	s.checksumsMu.Lock()
+	for _, c := range s.checksums {
+		if c.index == gr.Index {
+			p = c
+		}
+	}
+	s.checksumsMu.Unlock()
+	if p == nil {
+		return nil, fmt.Errorf("index %d: %w", gr.Index, ErrChecksumNotFound)
+	}
+	return p.wait(ctx)
+}
+
+// NamedStatement returns the SQL registered under the given name, as known
+// by this node.
+func (s *Store) NamedStatement(name string) (string, error) {
+	if !s.open.Is() {
+		return "", ErrNotOpen
+	}
+	return s.db.NamedStatement(name)
+}
+
+// NamedStatements returns all registered statements, keyed by name, as known
+// by this node.
+func (s *Store) NamedStatements() (map[string]string, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	return s.db.NamedStatements()
+}
+
+// Functions returns the functions provided by this node's database.
+func (s *Store) Functions() ([]cmdsql.FunctionInfo, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	return s.db.Functions()
+}
+
+// Triggers returns the SQL of each trigger on the given table, as known by
+// this node.
+func (s *Store) Triggers(schema, table string) ([]string, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	return s.db.Triggers(schema, table)
+}
+
+// ColumnDefaults returns the declared defaults of the columns of the given
+// table, as known by this node.
+func (s *Store) ColumnDefaults(schema, table string) ([]cmdsql.ColumnDefault, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	return s.db.ColumnDefaults(schema, table)
+}
+
+// Users returns the users in the table of users, as known by this node.
+func (s *Store) Users() ([]auth.Credential, error) {
+	if !s.open.Is() {
+		return nil, ErrNotOpen
+	}
+	return s.db.Users()
+}
+
+// UsersVersion returns a value which changes whenever the users in the table
+// of users may have changed on this node. It is zero if the Store is not open.
+func (s *Store) UsersVersion() uint64 {
+	if !s.open.Is() {
+		return 0
+	}
+	return s.db.UsersVersion()
+}
+
 // RORWCount returns the number of read-only and read-write statements in the
 // given ExecuteQueryRequest. EXPLAIN statements are always considered read-only.
 func (s *Store) RORWCount(eqr *proto.ExecuteQueryRequest) (nRW, nRO int) {
 	for _, stmt := range eqr.Request.Statements {
 		sql := stmt.Sql
+		if sql == "" && stmt.Name != "" {
+			// A named statement which cannot be found locally is treated
+			// as read-write, so it is resolved on every node via the log.
+			var err error
+			if sql, err = s.db.NamedStatement(stmt.Name); err != nil {
+				nRW++
+				continue
+			}
+		}
 		if sql == "" {
 			continue
 		}
@@ -2402,13 +2688,20 @@ func (s *Store) raftConfig() *raft.Config {
 	if s.CommitTimeout != 0 {
 		config.CommitTimeout = s.CommitTimeout
 	}
-	opts := hclog.DefaultOptions
-	opts.Name = ""
-	opts.Level = s.hcLogLevel()
-	config.Logger = hclog.FromStandardLogger(log.New(os.Stderr, "[raft] ", log.LstdFlags), opts)
+	config.Logger = raftLogger("")
 	return config
 }
 
+// raftLogger returns a logger, with the given name, for Raft. It logs at
+// every level, and the level of the raft subsystem is applied by the
+// underlying logger, so that it may be changed at runtime.
+func raftLogger(name string) hclog.Logger {
+	opts := hclog.DefaultOptions
+	opts.Name = name
+	opts.Level = hclog.Trace
+	return hclog.FromStandardLogger(logging.NewTagged("raft", os.Stderr), opts)
+}
+
 // waitForLinearizableRead performs the preprocessing needed for a
 // linearizable read, or timeouts. Once this function returns without
 // error subsequent reads on the Leader will be linearizable.
@@ -2487,21 +2780,82 @@ type fsmGenericResponse struct {
 	error error
 }
 
+type fsmChecksumResponse struct {
+	reader    *sql.ChecksumReader
+	chunkSize int64
+	pending   *pendingChecksum
+	error     error
+}
+
+// pendingChecksum is a checksum of the database at a log index, which is
+// computed in the background once the checksum command has been applied.
+type pendingChecksum struct {
+	index  uint64
+	done   chan struct{}
+	result *proto.ChecksumResult
+	err    error
+}
+
+// newPendingChecksum starts computing the checksum of the state pinned by rdr,
+// unless an earlier checksum is still being computed.
+func (s *Store) newPendingChecksum(index uint64, chunkSize int64, rdr *sql.ChecksumReader) *pendingChecksum {
+	p := &pendingChecksum{
+		index: index,
+		done:  make(chan struct{}),
+	}
+	if !s.checksumBusy.CompareAndSwap(false, true) {
+		rdr.Close()
+		p.err = ErrChecksumInProgress
+		close(p.done)
+		return p
+	}
+	go func() {
+		defer close(p.done)
+		defer s.checksumBusy.Store(false)
+		sums, err := rdr.Checksums()
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.result = &proto.ChecksumResult{
+			Index:     index,
+			ChunkSize: chunkSize,
+			Checksums: sums,
+		}
+	}()
+	return p
+}
+
+// wait waits for the checksum to be computed, or for ctx to be done.
+func (p *pendingChecksum) wait(ctx context.Context) (*proto.ChecksumResult, error) {
+	select {
+	case <-p.done:
+		return p.result, p.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("index %d: %w", p.index, ctx.Err())
+	}
+}
+
 // fsmApply applies a Raft log entry to the database.
 func (s *Store) fsmApply(l *raft.Log) (e any) {
 	startT := time.Now()
+	ctx, span := tracing.StartChild(tracing.Unmarshal(context.Background(), l.Extensions), tracer,
+		"store.fsmApply", trace.WithAttributes(attribute.Int64("rqlite.raft_index", int64(l.Index))))
+	defer span.End()
 	defer func() {
 		s.fsmIdx.Store(l.Index)
 		s.fsmTarget.Signal(l.Index)
 		s.fsmTerm.Store(l.Term)
 		s.fsmUpdateTime.Store(time.Now())
 		s.appendedAtTime.Store(l.AppendedAt)
-		stats.Get(fsmApplyDuration).(*expvar.Int).Set(time.Since(startT).Microseconds())
+		dur := time.Since(startT)
+		stats.Get(fsmApplyDuration).(*expvar.Int).Set(dur.Microseconds())
+		fsmApplyHist.Record(dur)
 	}()
 
 	if s.firstLogAppliedT.IsZero() {
 		s.firstLogAppliedT = time.Now()
-		s.logger.Printf("first log applied since node %s started, log at index %d", s.raftID, l.Index)
+		s.slogger.InfoContext(logging.WithRaftIndex(ctx, l.Index), "first log applied since node started")
 	}
 
 	cmd, mutated, r := func() (*proto.Command, bool, any) {
@@ -2513,7 +2867,7 @@ func (s *Store) fsmApply(l *raft.Log) (e any) {
 				var err error
 				s.cdcStreamer, err = sql.NewCDCStreamer(s.cdcOutCh, s.db)
 				if err != nil {
-					s.logger.Fatalf("failed to create CDC streamer: %s", err)
+					logging.Fatalf(s.logger, "failed to create CDC streamer: %s", err)
 				}
 			}
 
@@ -2521,18 +2875,22 @@ func (s *Store) fsmApply(l *raft.Log) (e any) {
 			// CDC registration in a single place in the code.
 			if s.cdcRegistered.IsNot() {
 				if err := s.db.RegisterPreUpdateHook(s.cdcStreamer.PreupdateHook, s.cdcTableRe, s.cdcIDsOnly); err != nil {
-					s.logger.Fatalf("failed to register preupdate hook for CDC: %s", err)
+					logging.Fatalf(s.logger, "failed to register preupdate hook for CDC: %s", err)
 				}
 				if err := s.db.RegisterCommitHook(s.cdcStreamer.CommitHook); err != nil {
-					s.logger.Fatalf("failed to register commit hook for CDC: %s", err)
+					logging.Fatalf(s.logger, "failed to register commit hook for CDC: %s", err)
 				}
 				s.cdcRegistered.Set()
 			}
 			s.cdcStreamer.Reset(l.Index)
 		}
-		return s.cmdProc.Process(l.Data, s.db)
+		return s.cmdProc.Process(ctx, l.Data, s.db)
 	}()
 
+	if s.slogger.Enabled(ctx, slog.LevelDebug) {
+		s.slogger.DebugContext(logging.WithRaftIndex(ctx, l.Index), "log applied to FSM",
+			"type", cmd.Type.String(), "mutated", mutated)
+	}
 	if mutated {
 		s.dbAppliedIdx.Store(l.Index)
 		s.appliedTarget.Signal(l.Index)
@@ -2540,10 +2898,29 @@ func (s *Store) fsmApply(l *raft.Log) (e any) {
 	switch cmd.Type {
 	case proto.Command_COMMAND_TYPE_NOOP:
 		s.numNoops.Add(1)
-	case proto.Command_COMMAND_TYPE_LOAD:
+	case proto.Command_COMMAND_TYPE_CHECKSUM:
+		if cr, ok := r.(*fsmChecksumResponse); ok && cr.error == nil {
+			if l.Index <= s.lastLogIdxOnOpen {
+				// No one is waiting for the checksums of replayed entries.
+				cr.reader.Close()
+				break
+			}
+			cr.pending = s.newPendingChecksum(l.Index, cr.chunkSize, cr.reader)
+			s.checksumsMu.Lock()
+			s.checksums = append(s.checksums, cr.pending)
+			if len(s.checksums) > checksumRetain {
+				s.checksums = s.checksums[len(s.checksums)-checksumRetain:]
+			}
+			s.checksumsMu.Unlock()
+		}
+	case proto.Command_COMMAND_TYPE_LOAD, proto.Command_COMMAND_TYPE_LOAD_CHUNK:
+		if !mutated {
+			// Chunks before the last do not swap in a new database.
+			break
+		}
 		// Swapping in a new database invalidates any existing snapshot.
 		if err := s.snapshotStore.SetDueNext(snapshot.Full); err != nil {
-			s.logger.Fatalf("failed to set full snapshot needed: %s", err)
+			logging.Fatalf(s.logger, "failed to set full snapshot needed: %s", err)
 		}
 		// Swapping in a new database deactivates the CDC hooks, so signal that it
 		// needs to be reregistered on the next commit.
@@ -2583,7 +2960,7 @@ func (s *Store) fsmSnapshot() (fSnap raft.FSMSnapshot, retErr error) {
 	}
 	defer func() {
 		if err := s.db.SetSynchronousMode(sql.SynchronousOff); err != nil {
-			s.logger.Fatalf("failed to set synchronous mode to OFF after snapshot: %s", err.Error())
+			logging.Fatalf(s.logger, "failed to set synchronous mode to OFF after snapshot: %s", err.Error())
 		}
 	}()
 
@@ -2594,7 +2971,7 @@ func (s *Store) fsmSnapshot() (fSnap raft.FSMSnapshot, retErr error) {
 		}
 		lt, err := s.db.DBLastModified()
 		if err != nil {
-			s.logger.Printf("failed to get last modified time: %s", err)
+			logging.Warnf(s.logger, "failed to get last modified time: %s", err)
 			s.snapshotStore.SetDueNext(snapshot.Full)
 		} else {
 			s.dbModifiedTime.Store(lt)
@@ -2687,7 +3064,7 @@ func (s *Store) fsmSnapshot() (fSnap raft.FSMSnapshot, retErr error) {
 				// that further incremental snapshots won't handle. We could revert to full but
 				// a nonretryable error shouldn't actually happen. Something bad has gone wrong.
 				walWriter.Cancel()
-				s.logger.Fatalf("failed to checkpoint and truncate database for incremental snapshot: %s (meta: %s)",
+				logging.Fatalf(s.logger, "failed to checkpoint and truncate database for incremental snapshot: %s (meta: %s)",
 					err.Error(), meta)
 			}
 			s.numIncSnapshotsRetryable.Add(1)
@@ -2721,6 +3098,7 @@ func (s *Store) fsmSnapshot() (fSnap raft.FSMSnapshot, retErr error) {
 	stats.Add(numSnapshots, 1)
 	dur := time.Since(startT)
 	stats.Get(snapshotCreateDuration).(*expvar.Int).Set(dur.Milliseconds())
+	snapshotCreateHist.Record(dur)
 	fs := FSMSnapshot{
 		Type:        dueNext,
 		FSMSnapshot: fsmSnapshot,
@@ -2741,7 +3119,7 @@ func (s *Store) fsmSnapshot() (fSnap raft.FSMSnapshot, retErr error) {
 				// again next time. Otherwise the chain has been broken and we must fall back to full.
 				if !fsutil.DirExists(s.walStagingDir) {
 					if err := s.snapshotStore.SetDueNext(snapshot.Full); err != nil {
-						s.logger.Fatalf("failed to set full needed after snapshot processing failure: %s", err)
+						logging.Fatalf(s.logger, "failed to set full needed after snapshot processing failure: %s", err)
 					}
 				}
 			}
@@ -2785,7 +3163,7 @@ func (s *Store) fsmRestore(rc io.ReadCloser) (retErr error) {
 	// immediately. Do this so we're not holding onto the underlying LockingStreamer
 	// while we calculate fingerprint (which can take measurable time for large databases).
 	if err := rc.Close(); err != nil {
-		s.logger.Printf("error closing snapshot reader after restore: %s", err)
+		logging.Warnf(s.logger, "error closing snapshot reader after restore: %s", err)
 	}
 
 	// Any existing SQLite file is about to be invalid, so mark that we can't
@@ -2878,7 +3256,7 @@ func (s *Store) observe() (closeCh, doneCh chan struct{}) {
 
 					nodes, err := s.Nodes()
 					if err != nil {
-						s.logger.Printf("failed to get nodes configuration during reap check: %s", err.Error())
+						logging.Warnf(s.logger, "failed to get nodes configuration during reap check: %s", err.Error())
 					}
 					servers := Servers(nodes)
 					id := string(signal.PeerID)
@@ -2898,7 +3276,7 @@ func (s *Store) observe() (closeCh, doneCh chan struct{}) {
 						}
 						if err := s.remove(id); err != nil {
 							stats.Add(nodesReapedFailed, 1)
-							s.logger.Printf("failed to reap %s %s: %s", pn, id, err.Error())
+							logging.Warnf(s.logger, "failed to reap %s %s: %s", pn, id, err.Error())
 						} else {
 							stats.Add(nodesReapedOK, 1)
 							s.logger.Printf("successfully reaped %s %s", pn, id)
@@ -2946,7 +3324,7 @@ func (s *Store) Snapshot(n uint64) (retError error) {
 	defer func() {
 		if retError != nil && retError != ErrNothingNewToSnapshot && retError != ErrNoWALToSnapshot {
 			stats.Add(numUserSnapshotsFailed, 1)
-			s.logger.Printf("failed to generate application-level snapshot: %s", retError.Error())
+			logging.Errorf(s.logger, "failed to generate application-level snapshot: %s", retError.Error())
 		}
 	}()
 
@@ -2955,7 +3333,7 @@ func (s *Store) Snapshot(n uint64) (retError error) {
 		defer func() {
 			cfg.TrailingLogs = s.numTrailingLogs
 			if err := s.raft.ReloadConfig(cfg); err != nil {
-				s.logger.Printf("failed to reload Raft config: %s", err.Error())
+				logging.Warnf(s.logger, "failed to reload Raft config: %s", err.Error())
 			}
 		}()
 		cfg.TrailingLogs = n
@@ -3002,13 +3380,13 @@ func (s *Store) runWALSnapshotting() (closeCh, doneCh chan struct{}) {
 			case <-ticker.C:
 				sz, err := fsutil.FileSizeExists(s.walPath)
 				if err != nil {
-					s.logger.Printf("failed to check WAL size: %s", err.Error())
+					logging.Warnf(s.logger, "failed to check WAL size: %s", err.Error())
 					continue
 				}
 				if uint64(sz) >= s.SnapshotThresholdWALSize {
 					if err := s.Snapshot(0); err != nil {
 						stats.Add(numWALSnapshotsFailed, 1)
-						s.logger.Printf("failed to snapshot due to WAL threshold: %s", err.Error())
+						logging.Warnf(s.logger, "failed to snapshot due to WAL threshold: %s", err.Error())
 					} else {
 						stats.Add(numWALSnapshots, 1)
 					}
@@ -3029,7 +3407,7 @@ func (s *Store) selfLeaderChange(leader bool) {
 			// Whatever happens, this is a one-shot attempt to perform a restore
 			err := os.Remove(s.restorePath)
 			if err != nil {
-				s.logger.Printf("failed to remove restore path after restore %s: %s",
+				logging.Warnf(s.logger, "failed to remove restore path after restore %s: %s",
 					s.restorePath, err.Error())
 			}
 			s.restorePath = ""
@@ -3042,7 +3420,7 @@ func (s *Store) selfLeaderChange(leader bool) {
 		} else {
 			s.logger.Printf("this node is now leader, auto-restoring from %s", s.restorePath)
 			if err := s.installRestore(); err != nil {
-				s.logger.Printf("failed to auto-restore from %s: %s", s.restorePath, err.Error())
+				logging.Errorf(s.logger, "failed to auto-restore from %s: %s", s.restorePath, err.Error())
 				stats.Add(numAutoRestoresFailed, 1)
 				return
 			}
@@ -1279,6 +1279,76 @@ func Test_MultiNodeJoinNonVoterRemove(t *testing.T) {
 	}
 }
 
+func Test_MultiNodeChecksum(t *testing.T) {
+	s0, ln0 := mustNewStore(t)
+	defer ln0.Close()
+	if err := s0.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	defer s0.Close(true)
+	if err := s0.Bootstrap(NewServer(s0.ID(), s0.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	if _, err := s0.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	s1, ln1 := mustNewStore(t)
+	defer ln1.Close()
+	if err := s1.Open(); err != nil {
+		t.Fatalf("failed to open node for multi-node test: %s", err.Error())
+	}
+	defer s1.Close(true)
+	if err := s0.Join(joinRequest(s1.ID(), s1.Addr(), true)); err != nil {
+		t.Fatalf("failed to join to node at %s: %s", s0.Addr(), err.Error())
+	}
+	if _, err := s1.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	er := executeRequestFromStrings([]string{
+		`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`,
+		`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+	}, false, false)
+	if _, _, err := s0.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on leader: %s", err.Error())
+	}
+
+	if _, err := s1.Checksum(context.Background(), &proto.ChecksumRequest{}); err != ErrNotLeader {
+		t.Fatalf("expected ErrNotLeader checksumming on follower, got %v", err)
+	}
+	res, err := s0.Checksum(context.Background(), &proto.ChecksumRequest{ChunkSize: 10})
+	if err != nil {
+		t.Fatalf("failed to checksum on leader: %s", err.Error())
+	}
+	if res.Index == 0 || res.ChunkSize != 10 || len(res.Checksums) != 2 {
+		t.Fatalf("unexpected checksum result: %v", res)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, s := range []*Store{s0, s1} {
+		r, err := s.GetChecksum(ctx, &proto.ChecksumResultRequest{Index: res.Index})
+		if err != nil {
+			t.Fatalf("failed to get checksum from node %s: %s", s.ID(), err.Error())
+		}
+		if exp, got := asJSON(res), asJSON(r); exp != got {
+			t.Fatalf("checksums of node %s differ from leader, exp %s, got %s", s.ID(), exp, got)
+		}
+	}
+
+	_, err = s1.GetChecksum(ctx, &proto.ChecksumResultRequest{Index: res.Index - 1})
+	if !errors.Is(err, ErrChecksumNotFound) {
+		t.Fatalf("expected ErrChecksumNotFound, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = s1.GetChecksum(ctx, &proto.ChecksumResultRequest{Index: res.Index + 100})
+	if !errors.Is(err, ErrWaitForFSMTimeout) {
+		t.Fatalf("expected ErrWaitForFSMTimeout, got %v", err)
+	}
+}
+
 func Test_MultiNodeExecuteQuery(t *testing.T) {
 	s0, ln0 := mustNewStore(t)
 	defer ln0.Close()
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -13,6 +14,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/command"
+	"github.com/rqlite/rqlite/v10/command/chunking"
 	"github.com/rqlite/rqlite/v10/command/encoding"
 	"github.com/rqlite/rqlite/v10/command/proto"
 	"github.com/rqlite/rqlite/v10/db"
@@ -20,7 +23,13 @@ import (
 	"github.com/rqlite/rqlite/v10/internal/random"
 	"github.com/rqlite/rqlite/v10/internal/rarchive"
 	"github.com/rqlite/rqlite/v10/snapshot"
+	"github.com/rqlite/rqlite/v10/store/throttler"
 	"github.com/rqlite/rqlite/v10/testdata/chinook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // Test_NonOpenStore tests that a non-open Store handles public methods correctly.
@@ -382,6 +391,33 @@ COMMIT;
 	if !filesIdentical(guzf, s.dbPath) {
 		t.Fatalf("backup file not identical to database file")
 	}
+
+	/////////////////////////////////////////////////////////////////////
+	// zstd-compressed backup, which takes precedence over Compress.
+	var zbuf bytes.Buffer
+	br := backupRequestBinary(true, false, true)
+	br.Compression = proto.BackupRequest_BACKUP_REQUEST_COMPRESSION_ZSTD
+	if err := s.Backup(context.Background(), br, &zbuf); err != nil {
+		t.Fatalf("zstd backup failed %s", err.Error())
+	}
+	rc, compression, err := rarchive.NewDecompressReader(&zbuf)
+	if err != nil {
+		t.Fatalf("failed to create decompress reader: %s", err.Error())
+	}
+	if compression != rarchive.CompressionZstd {
+		t.Fatalf("expected zstd compression, got %s", compression)
+	}
+	zb, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to decompress zstd backup: %s", err.Error())
+	}
+	dbb, err := os.ReadFile(s.dbPath)
+	if err != nil {
+		t.Fatalf("failed to read database file: %s", err.Error())
+	}
+	if !bytes.Equal(zb, dbb) {
+		t.Fatalf("zstd backup not identical to database file")
+	}
 }
 
 // Test_StoreSingleNodeNotOpen tests that various methods called on a
@@ -638,6 +674,179 @@ func Test_SingleNodeExecuteQuery(t *testing.T) {
 	}
 }
 
+// Test_SingleNodeExecute_Throttled tests that writes are refused, rather than
+// delayed, once the throttle level reaches WriteThrottleRejectLevel.
+func Test_SingleNodeExecute_Throttled(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	s.throttler = throttler.New([]time.Duration{0, time.Millisecond, 2 * time.Millisecond}, 1, 0)
+	s.WriteThrottleRejectLevel = 2
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	er := executeRequestFromString(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`, false, false)
+	s.throttler.Signal()
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute below reject level: %s", err.Error())
+	}
+	if level, retryAfter := s.WriteThrottle(); level != 1 || retryAfter != 0 {
+		t.Fatalf("wrong write throttle, got level %d, retry after %s", level, retryAfter)
+	}
+
+	s.throttler.Signal()
+	er = executeRequestFromString(`INSERT INTO foo(id, name) VALUES(1, "fiona")`, false, false)
+	_, _, err := s.Execute(context.Background(), er)
+	var tErr *ThrottledError
+	if !errors.As(err, &tErr) {
+		t.Fatalf("expected ThrottledError, got %v", err)
+	}
+	if exp, got := minThrottleRetryAfter, tErr.RetryAfter; exp != got {
+		t.Fatalf("wrong retry after, exp %s, got %s", exp, got)
+	}
+	if _, _, _, err := s.Request(context.Background(), executeQueryRequestFromString(`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+		proto.ConsistencyLevel_WEAK, false, false, false)); !errors.As(err, &tErr) {
+		t.Fatalf("expected ThrottledError from Request, got %v", err)
+	}
+	if got, ok := ParseThrottledError(tErr.Error()); !ok || got.RetryAfter != tErr.RetryAfter {
+		t.Fatalf("failed to parse %q", tErr.Error())
+	}
+
+	s.throttler.Release()
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute after throttle released: %s", err.Error())
+	}
+}
+
+// Test_StoreExecute_ThrottledNotLeader tests that a node which is not the
+// Leader does not refuse writes it is throttling, so that they are forwarded.
+func Test_StoreExecute_ThrottledNotLeader(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	s.throttler = throttler.New([]time.Duration{0, time.Millisecond}, 1, 0)
+	s.WriteThrottleRejectLevel = 1
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	s.throttler.Signal()
+	if _, retryAfter := s.WriteThrottle(); retryAfter == 0 {
+		t.Fatalf("writes not throttled")
+	}
+
+	er := executeRequestFromString(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != ErrNotLeader {
+		t.Fatalf("expected ErrNotLeader from Execute, got %v", err)
+	}
+	eqr := executeQueryRequestFromString(`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+		proto.ConsistencyLevel_WEAK, false, false, false)
+	if _, _, _, err := s.Request(context.Background(), eqr); err != ErrNotLeader {
+		t.Fatalf("expected ErrNotLeader from Request, got %v", err)
+	}
+}
+
+// Test_SingleNodeExecute_Latency tests that the latencies of applying a
+// write to the Raft log, and to the FSM, are recorded.
+func Test_SingleNodeExecute_Latency(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	nApply, nFSM := raftApplyHist.Snapshot().Count, fsmApplyHist.Snapshot().Count
+	er := executeRequestFromString(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+	if n := raftApplyHist.Snapshot().Count; n != nApply+1 {
+		t.Fatalf("Raft apply latency not recorded, exp count %d, got %d", nApply+1, n)
+	}
+	if n := fsmApplyHist.Snapshot().Count; n <= nFSM {
+		t.Fatalf("FSM apply latency not recorded, exp count greater than %d, got %d", nFSM, n)
+	}
+}
+
+// Test_SingleNodeExecute_Traced tests that the trace of a write continues
+// through Raft to the application of the write to the database.
+func Test_SingleNodeExecute_Traced(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	er := executeRequestFromString(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`, false, false)
+	_, idx, err := s.Execute(ctx, er)
+	if err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		if span.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+			spans[span.Name()] = span
+		}
+	}
+	apply, ok := spans["store.raftApply"]
+	if !ok {
+		t.Fatalf("raft apply span not recorded, got %v", spans)
+	}
+	fsm, ok := spans["store.fsmApply"]
+	if !ok {
+		t.Fatalf("FSM apply span not recorded, got %v", spans)
+	}
+	exec, ok := spans["db.Execute"]
+	if !ok {
+		t.Fatalf("database span not recorded, got %v", spans)
+	}
+	if apply.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("raft apply span is not a child of the parent span")
+	}
+	if fsm.Parent().SpanID() != apply.SpanContext().SpanID() {
+		t.Fatalf("FSM apply span is not a child of the raft apply span")
+	}
+	if exec.Parent().SpanID() != fsm.SpanContext().SpanID() {
+		t.Fatalf("database span is not a child of the FSM apply span")
+	}
+	for _, kv := range fsm.Attributes() {
+		if kv.Key == "rqlite.raft_index" && kv.Value.AsInt64() != int64(idx) {
+			t.Fatalf("wrong raft index on FSM apply span, exp %d, got %d", idx, kv.Value.AsInt64())
+		}
+	}
+}
+
 // Test_SingleNodeExecuteQuery_Linearizable tests that a Store correctly responds to a
 // simple Query request with Linearizable consistency level.
 func Test_SingleNodeExecuteQuery_Linearizable(t *testing.T) {
@@ -2159,6 +2368,309 @@ COMMIT;
 	}
 }
 
+// Test_SingleNodeLoadChunks tests that a SQLite file loaded in chunks replaces
+// the database once the last chunk is applied, and not before.
+func Test_SingleNodeLoadChunks(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "load.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open SQLite file: %s", err.Error())
+	}
+	defer f.Close()
+	chunker := chunking.NewChunker(f, 1024)
+	query := func() string {
+		qr := queryRequestFromString("SELECT count(*) FROM foo", false, true, false)
+		qr.Level = proto.ConsistencyLevel_STRONG
+		r, _, _, err := s.Query(context.Background(), qr)
+		if err != nil {
+			t.Fatalf("failed to query single node: %s", err.Error())
+		}
+		return asJSON(r[0])
+	}
+	for n := 0; ; n++ {
+		lcr, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read chunk: %s", err.Error())
+		}
+		if n == 1 {
+			if exp, got := `{"error":"no such table: foo"}`, query(); exp != got {
+				t.Fatalf("database changed before last chunk\nexp: %s\ngot: %s", exp, got)
+			}
+		}
+		if err := s.LoadChunk(context.Background(), lcr); err != nil {
+			t.Fatalf("failed to load chunk: %s", err.Error())
+		}
+	}
+	if exp, got := `{"columns":["count(*)"],"types":["integer"],"values":[[3]]}`, query(); exp != got {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", exp, got)
+	}
+	dn, err := s.snapshotStore.DueNext()
+	if err != nil {
+		t.Fatalf("failed to check snapshot due next: %s", err.Error())
+	}
+	if dn != snapshot.Full {
+		t.Fatalf("expected full snapshot due next, got %s", dn)
+	}
+}
+
+// Test_SingleNodeImportChunks tests that a batch of an import sent in chunks
+// is executed as a single transaction once the last chunk is applied, and
+// only if its statements access tables as the import's access statement does.
+// Test_SingleNodeChecksum_InProgress tests that a checksum is refused while
+// an earlier one is still being computed.
+func Test_SingleNodeChecksum_InProgress(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	s.checksumBusy.Store(true)
+	if _, err := s.Checksum(context.Background(), &proto.ChecksumRequest{}); !errors.Is(err, ErrChecksumInProgress) {
+		t.Fatalf("expected ErrChecksumInProgress, got %v", err)
+	}
+	s.checksumBusy.Store(false)
+	if _, err := s.Checksum(context.Background(), &proto.ChecksumRequest{}); err != nil {
+		t.Fatalf("failed to checksum: %s", err.Error())
+	}
+	if s.checksumBusy.Load() {
+		t.Fatalf("checksum still marked as in progress")
+	}
+}
+
+// Test_SingleNodeChecksum_Replay tests that checksum commands replayed when
+// the store is opened are not computed again.
+func Test_SingleNodeChecksum_Replay(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	res, err := s.Checksum(context.Background(), &proto.ChecksumRequest{})
+	if err != nil {
+		t.Fatalf("failed to checksum: %s", err.Error())
+	}
+	// Don't snapshot, so the checksum command is replayed from the log.
+	s.NoSnapshotOnClose = true
+	if err := s.Close(true); err != nil {
+		t.Fatalf("failed to close store: %s", err.Error())
+	}
+	s.checksums = nil
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+	testPoll(t, func() bool {
+		return s.fsmIdx.Load() >= res.Index
+	}, 100*time.Millisecond, 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.GetChecksum(ctx, &proto.ChecksumResultRequest{Index: res.Index}); !errors.Is(err, ErrChecksumNotFound) {
+		t.Fatalf("expected ErrChecksumNotFound for replayed checksum, got %v", err)
+	}
+}
+
+func Test_SingleNodeImportChunks(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	er := executeRequestFromString(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+
+	const access = `INSERT INTO "foo" DEFAULT VALUES`
+	importBatch := func(access string, stmts ...string) error {
+		b, err := command.MarshalImportBatch(executeRequestFromStrings(stmts, false, false).Request)
+		if err != nil {
+			t.Fatalf("failed to marshal import batch: %s", err.Error())
+		}
+		chunker := chunking.NewChunker(bytes.NewReader(b), 16)
+		for {
+			lcr, err := chunker.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				t.Fatalf("failed to read chunk: %s", err.Error())
+			}
+			lcr.ImportAccess = access
+			if err := s.LoadChunk(context.Background(), lcr); err != nil {
+				return err
+			}
+		}
+	}
+	query := func() string {
+		qr := queryRequestFromString("SELECT * FROM foo", false, true, false)
+		qr.Level = proto.ConsistencyLevel_STRONG
+		r, _, _, err := s.Query(context.Background(), qr)
+		if err != nil {
+			t.Fatalf("failed to query single node: %s", err.Error())
+		}
+		return asJSON(r[0])
+	}
+	imported := `{"columns":["id","name"],"types":["integer","text"],"values":[[1,"fiona"],[2,"declan"]]}`
+
+	if err := importBatch(access, `INSERT INTO "foo" ("id", "name") VALUES (1, 'fiona')`,
+		`INSERT INTO "foo" ("id", "name") VALUES (2, 'declan')`); err != nil {
+		t.Fatalf("failed to import batch: %s", err.Error())
+	}
+	if got := query(); got != imported {
+		t.Fatalf("unexpected results for query\nexp: %s\ngot: %s", imported, got)
+	}
+
+	// A failing batch is rolled back.
+	if err := importBatch(access, `INSERT INTO "foo" ("id", "name") VALUES (3, 'sinead')`,
+		`INSERT INTO "foo" ("id", "name") VALUES (1, 'fiona')`); err == nil || !strings.Contains(err.Error(), "UNIQUE") {
+		t.Fatalf("expected constraint violation importing batch, got %v", err)
+	}
+	if got := query(); got != imported {
+		t.Fatalf("failed batch not rolled back\nexp: %s\ngot: %s", imported, got)
+	}
+
+	// Statements may not go beyond the access statement.
+	for _, stmt := range []string{
+		`DELETE FROM foo`,
+		`INSERT OR REPLACE INTO "foo" ("id", "name") VALUES (1, 'mallory')`,
+		`INSERT INTO "bar" ("id") VALUES (1)`,
+	} {
+		if err := importBatch(access, stmt); err == nil || !strings.Contains(err.Error(), "not permitted") {
+			t.Fatalf("expected %s to be refused, got %v", stmt, err)
+		}
+	}
+	if got := query(); got != imported {
+		t.Fatalf("refused batch changed database\nexp: %s\ngot: %s", imported, got)
+	}
+}
+
+// Test_SingleNodeUsersTable tests that the table of users is never backed
+// up, and that loading a database does not change the users.
+func Test_SingleNodeUsersTable(t *testing.T) {
+	s, ln := mustNewStore(t)
+	defer ln.Close()
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open single-node store: %s", err.Error())
+	}
+	if err := s.Bootstrap(NewServer(s.ID(), s.Addr(), true)); err != nil {
+		t.Fatalf("failed to bootstrap single-node store: %s", err.Error())
+	}
+	defer s.Close(true)
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("Error waiting for leader: %s", err)
+	}
+
+	v := s.UsersVersion()
+	er := executeRequestFromStrings([]string{
+		`CREATE TABLE foo (id integer not null primary key, name text)`,
+		`INSERT INTO foo(name) VALUES('fiona')`,
+		db.CreateUsersTableSQL,
+		`INSERT INTO _rqlite_users(username, credential) VALUES('fiona', '{"password":"secret-hash"}')`,
+	}, false, false)
+	if _, _, err := s.Execute(context.Background(), er); err != nil {
+		t.Fatalf("failed to execute on single node: %s", err.Error())
+	}
+	if s.UsersVersion() == v {
+		t.Fatalf("users version unchanged by change to table of users")
+	}
+
+	for _, br := range []*proto.BackupRequest{
+		backupRequestBinary(true, false, false),
+		backupRequestBinary(true, true, false),
+		backupRequestDelete(true, false, false),
+		backupRequestSQL(true),
+		backupRequestSQLWithTables(true, []string{"_rqlite_users"}),
+	} {
+		var buf bytes.Buffer
+		if err := s.Backup(context.Background(), br, &buf); err != nil {
+			t.Fatalf("backup failed: %s", err.Error())
+		}
+		if bytes.Contains(buf.Bytes(), []byte("secret-hash")) {
+			t.Fatalf("backup %v contains password hash", br)
+		}
+	}
+
+	// Load a database which has its own table of users.
+	f, err := os.CreateTemp("", "rqlite-users-")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err.Error())
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	ldb, err := db.Open(f.Name(), false, false)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err.Error())
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE bar (id integer not null primary key, name text)`,
+		db.CreateUsersTableSQL,
+		`INSERT INTO _rqlite_users(username, credential) VALUES('mallory', '{"perms":["all"]}')`,
+	} {
+		if _, err := ldb.ExecuteStringStmt(stmt); err != nil {
+			t.Fatalf("failed to execute on database: %s", err.Error())
+		}
+	}
+	if err := ldb.Close(); err != nil {
+		t.Fatalf("failed to close database: %s", err.Error())
+	}
+	if err := s.Load(context.Background(), loadRequestFromFile(f.Name())); err != nil {
+		t.Fatalf("failed to load SQLite file: %s", err.Error())
+	}
+	users, err := s.Users()
+	if err != nil {
+		t.Fatalf("failed to read users: %s", err.Error())
+	}
+	if len(users) != 1 || users[0].Username != "fiona" {
+		t.Fatalf("users changed by load: %v", users)
+	}
+}
+
 func Test_SingleNodeLoad_SQLFail(t *testing.T) {
 	s, ln := mustNewStore(t)
 	defer ln.Close()
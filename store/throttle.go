@@ -0,0 +1,60 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// throttledErrorPrefix begins the message of every ThrottledError, so that
+// the error can be recognized when returned by another node.
+const throttledErrorPrefix = "write throttled, retry after "
+
+// minThrottleRetryAfter is the shortest time clients are asked to wait
+// before retrying a write refused by the throttler.
+const minThrottleRetryAfter = time.Second
+
+// ThrottledError is returned when a write is refused, rather than delayed,
+// because the Store is under pressure. See WriteThrottleRejectLevel.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e *ThrottledError) Error() string {
+	return throttledErrorPrefix + e.RetryAfter.String()
+}
+
+// ParseThrottledError returns the ThrottledError with the given message, if
+// the message is that of a ThrottledError.
+func ParseThrottledError(msg string) (*ThrottledError, bool) {
+	s, ok := strings.CutPrefix(msg, throttledErrorPrefix)
+	if !ok {
+		return nil, false
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, false
+	}
+	return &ThrottledError{RetryAfter: d}, true
+}
+
+// WriteThrottle returns the current throttle level of writes. If writes are
+// being refused at that level, it also returns how long clients should wait
+// before retrying, otherwise retryAfter is zero.
+func (s *Store) WriteThrottle() (level int, retryAfter time.Duration) {
+	level = s.throttler.Level()
+	if s.WriteThrottleRejectLevel <= 0 || level < s.WriteThrottleRejectLevel {
+		return level, 0
+	}
+	// Ask clients to wait at least as long as writes are being delayed.
+	return level, max(s.throttler.GetDelay(), minThrottleRetryAfter)
+}
+
+// checkWriteThrottle returns a *ThrottledError if writes are being refused.
+func (s *Store) checkWriteThrottle() error {
+	if _, retryAfter := s.WriteThrottle(); retryAfter > 0 {
+		stats.Add(numWritesThrottled, 1)
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rarchive/zstd"
 )
 
@@ -77,7 +78,7 @@ func NewNodeTransport(transport *raft.NetworkTransport, compressSnap bool) *Node
 		commandCommitIndex: &atomic.Uint64{},
 		leaderCommitIndex:  &atomic.Uint64{},
 		done:               make(chan struct{}),
-		logger:             log.New(os.Stderr, "[transport] ", log.LstdFlags),
+		logger:             logging.New("transport", os.Stderr),
 	}
 }
 
@@ -174,7 +175,7 @@ func (n *NodeTransport) Consumer() <-chan raft.RPC {
 					n.aeMu.RUnlock()
 					if handler != nil {
 						if err := handler(cmd); err != nil {
-							n.logger.Printf("AppendEntriesRxHandler error: %v", err)
+							logging.Errorf(n.logger, "AppendEntriesRxHandler error: %v", err)
 						}
 					}
 					for _, e := range cmd.Entries {
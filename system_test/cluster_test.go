@@ -1495,6 +1495,41 @@ func Test_MultiNodeCluster_FollowerLoad_SQL(t *testing.T) {
 	}
 }
 
+func Test_MultiNodeCluster_FollowerLoad_SQLite(t *testing.T) {
+	node1 := mustNewLeaderNode("leader1")
+	defer node1.Deprovision()
+
+	node2 := mustNewNode("node2", false)
+	defer node2.Deprovision()
+	if err := node2.Join(node1); err != nil {
+		t.Fatalf("node failed to join leader: %s", err.Error())
+	}
+	_, err := node2.WaitForLeader()
+	if err != nil {
+		t.Fatalf("failed waiting for leader: %s", err.Error())
+	}
+
+	// Get a follower, make sure a SQLite file is forwarded to the Leader.
+	c := Cluster{node1, node2}
+	followers, err := c.Followers()
+	if err != nil {
+		t.Fatalf("failed to get followers: %s", err.Error())
+	}
+	if _, err := followers[0].Load(filepath.Join("testdata", "auto-restore.sqlite")); err != nil {
+		t.Fatalf("failed to load via follower: %s", err.Error())
+	}
+
+	for _, n := range c {
+		r, err := n.QueryStrongConsistency("SELECT * FROM foo WHERE id=2")
+		if err != nil {
+			t.Fatalf("failed to execute query: %s", err.Error())
+		}
+		if r != `{"results":[{"columns":["id","name"],"types":["integer","text"],"values":[[2,"fiona"]]}]}` {
+			t.Fatalf("test received wrong result got %s", r)
+		}
+	}
+}
+
 // Test_MultiNodeClusterWithNonVoter tests formation of a 4-node cluster, one of which is
 // a non-voter. This test also checks that if the Leader changes the non-voter is still in
 // the cluster and gets updates from the new leader.
@@ -1178,6 +1178,34 @@ func (m *mockCredentialStore) AA(username, password, perm string) bool {
 	return m.HasPermOK
 }
 
+func (m *mockCredentialStore) AAToken(token, perm string) bool {
+	if m == nil {
+		return true
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) AACert(cert *x509.Certificate, perm string) bool {
+	if m == nil {
+		return true
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) AACertUser(username, perm string) bool {
+	if m == nil {
+		return true
+	}
+	return m.HasPermOK
+}
+
+func (m *mockCredentialStore) CertUser(cert *x509.Certificate) (string, bool) {
+	if m == nil || cert == nil {
+		return "", false
+	}
+	return cert.Subject.CommonName, true
+}
+
 func mustNewMockCredentialStore() *mockCredentialStore {
 	return &mockCredentialStore{HasPermOK: true}
 }
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rqlite/rqlite/v10/internal/logging"
 	"github.com/rqlite/rqlite/v10/internal/rtls"
 )
 
@@ -108,7 +109,7 @@ func NewMux(ln net.Listener, adv net.Addr) (*Mux, error) {
 		m:       make(map[byte]*listener),
 		conns:   make(map[*trackedConn]struct{}),
 		Timeout: DefaultTimeout,
-		Logger:  log.New(os.Stderr, "[mux] ", log.LstdFlags),
+		Logger:  logging.New("mux", os.Stderr),
 	}, nil
 }
 
@@ -296,6 +297,15 @@ func (c *trackedConn) Close() error {
 	return err
 }
 
+// ConnectionState returns the TLS state of the underlying connection, which is
+// the zero value if the connection does not use TLS.
+func (c *trackedConn) ConnectionState() tls.ConnectionState {
+	if tc, ok := c.Conn.(*tls.Conn); ok {
+		return tc.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
 func (mux *Mux) handleConn(conn net.Conn) {
 	stats.Add(numConnectionsHandled, 1)
 
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -379,6 +380,78 @@ func TestMutualTLSMuxVerifyCommonName(t *testing.T) {
 	}
 }
 
+// Test_MutualTLSMuxConnectionState verifies that connections accepted by the
+// Mux expose the TLS state of the underlying connection, including the
+// verified chains of the client certificate.
+func Test_MutualTLSMuxConnectionState(t *testing.T) {
+	caCertPEM, caKeyPEM, err := rtls.GenerateCACert(pkix.Name{CommonName: "ca.rqlite.io"}, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate CA cert: %s", err)
+	}
+	caCertBlock, _ := pem.Decode(caCertPEM)
+	caKeyBlock, _ := pem.Decode(caKeyPEM)
+	parsedCACert, err := cryptox509.ParseCertificate(caCertBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA cert: %s", err)
+	}
+	parsedCAKey, err := cryptox509.ParsePKCS1PrivateKey(caKeyBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CA key: %s", err)
+	}
+	serverIP := net.ParseIP("127.0.0.1")
+	certPEM, keyPEM, err := rtls.GenerateCertIPSAN(pkix.Name{CommonName: "node.rqlite.io"}, time.Hour, 2048, parsedCACert, parsedCAKey, serverIP)
+	if err != nil {
+		t.Fatalf("failed to generate cert: %s", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	for f, b := range map[string][]byte{certFile: certPEM, keyFile: keyPEM, caFile: caCertPEM} {
+		if err := os.WriteFile(f, b, 0600); err != nil {
+			t.Fatalf("failed to write %s: %s", f, err)
+		}
+	}
+
+	tcpListener := mustTCPListener("127.0.0.1:0")
+	defer tcpListener.Close()
+	mux, err := NewMutualTLSMux(tcpListener, nil, certFile, keyFile, caFile, rtls.NoVerifyCN)
+	if err != nil {
+		t.Fatalf("failed to create mutual TLS mux: %s", err.Error())
+	}
+	defer mux.Close()
+	ln := mux.Listen(1)
+	go mux.Serve()
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to build keypair: %s", err)
+	}
+	client, err := tls.Dial("tcp", tcpListener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{pair},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial mux: %s", err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte{1}); err != nil {
+		t.Fatalf("failed to write header byte: %s", err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept connection: %s", err)
+	}
+	defer conn.Close()
+	cs, ok := conn.(interface{ ConnectionState() tls.ConnectionState })
+	if !ok {
+		t.Fatalf("accepted connection does not expose its TLS state")
+	}
+	state := cs.ConnectionState()
+	if len(state.VerifiedChains) == 0 || state.VerifiedChains[0][0].Subject.CommonName != "node.rqlite.io" {
+		t.Fatalf("verified client certificate not in TLS state")
+	}
+}
+
 type mockAddr struct {
 	Nwk  string
 	Addr string